@@ -44,6 +44,8 @@ func cmdAdd(args *skel.CmdArgs) error {
 	if netConf.Provider == "" && netConf.Type == util.CniTypeName && args.IfName == "eth0" {
 		netConf.Provider = util.OvnProvider
 	}
+	// the name of the interface inside the pod is optional and defaults to the one passed by the runtime
+	podIfName, _ := parseValueFromArgs("POD_IFNAME", args.Args)
 
 	client := request.NewCniServerClient(netConf.ServerSocket)
 	response, err := client.Add(request.CniRequest{
@@ -53,6 +55,7 @@ func cmdAdd(args *skel.CmdArgs) error {
 		ContainerID:               args.ContainerID,
 		NetNs:                     args.Netns,
 		IfName:                    args.IfName,
+		PodIfName:                 podIfName,
 		Provider:                  netConf.Provider,
 		Routes:                    netConf.Routes,
 		DNS:                       netConf.DNS,
@@ -66,9 +69,40 @@ func cmdAdd(args *skel.CmdArgs) error {
 	}
 
 	result := generateCNIResult(response, args.Netns)
+	if netConf.PrevResult != nil {
+		prevResult, err := mergePrevResult(netConf.PrevResult, &result)
+		if err != nil {
+			return err
+		}
+		return types.PrintResult(prevResult, cniVersion)
+	}
 	return types.PrintResult(&result, cniVersion)
 }
 
+// mergePrevResult appends the interfaces, addresses and routes of the nic to the result of the previous plugin in the
+// chain, since the CNI spec requires the plugins to pass the previous result through to the next plugins
+func mergePrevResult(prev types.Result, result *current.Result) (*current.Result, error) {
+	prevResult, err := current.NewResultFromResult(prev)
+	if err != nil {
+		return nil, types.NewError(types.ErrDecodingFailure, "failed to convert prevResult", err.Error())
+	}
+
+	// the addresses refer to the interfaces by the index in the merged result
+	offset := len(prevResult.Interfaces)
+	for _, ip := range result.IPs {
+		if ip.Interface != nil {
+			ip.Interface = current.Int(*ip.Interface + offset)
+		}
+	}
+	prevResult.Interfaces = append(prevResult.Interfaces, result.Interfaces...)
+	prevResult.IPs = append(prevResult.IPs, result.IPs...)
+	prevResult.Routes = append(prevResult.Routes, result.Routes...)
+	if len(result.DNS.Nameservers) != 0 {
+		prevResult.DNS = result.DNS
+	}
+	return prevResult, nil
+}
+
 func generateCNIResult(cniResponse *request.CniResponse, netns string) current.Result {
 	result := current.Result{
 		CNIVersion: current.ImplementedSpecVersion,
@@ -80,6 +114,12 @@ func generateCNIResult(cniResponse *request.CniResponse, netns string) current.R
 		Mac:     cniResponse.MacAddress,
 		Sandbox: netns,
 	}
+	// the pod interface is always the first one which the addresses refer to by index 0
+	result.Interfaces = []*current.Interface{&podIface}
+	if cniResponse.HostNicName != "" {
+		// the chained plugins like bandwidth look for the host end of the veth pair in the result
+		result.Interfaces = append(result.Interfaces, &current.Interface{Name: cniResponse.HostNicName})
+	}
 	switch cniResponse.Protocol {
 	case kubeovnv1.ProtocolIPv4:
 		ip, route := assignV4Address(cniResponse.IpAddress, cniResponse.Gateway, mask)
@@ -87,14 +127,12 @@ func generateCNIResult(cniResponse *request.CniResponse, netns string) current.R
 		if route != nil {
 			result.Routes = []*types.Route{route}
 		}
-		result.Interfaces = []*current.Interface{&podIface}
 	case kubeovnv1.ProtocolIPv6:
 		ip, route := assignV6Address(cniResponse.IpAddress, cniResponse.Gateway, mask)
 		result.IPs = []*current.IPConfig{ip}
 		if route != nil {
 			result.Routes = []*types.Route{route}
 		}
-		result.Interfaces = []*current.Interface{&podIface}
 	case kubeovnv1.ProtocolDual:
 		var netMask *net.IPNet
 		var gwStr string
@@ -125,7 +163,13 @@ func generateCNIResult(cniResponse *request.CniResponse, netns string) current.R
 				}
 			}
 		}
-		result.Interfaces = []*current.Interface{&podIface}
+		// the first ip is taken as the primary ip of the pod
+		if cniResponse.PreferredIPFamily == kubeovnv1.ProtocolIPv6 && len(result.IPs) == 2 {
+			result.IPs[0], result.IPs[1] = result.IPs[1], result.IPs[0]
+			if len(result.Routes) == 2 {
+				result.Routes[0], result.Routes[1] = result.Routes[1], result.Routes[0]
+			}
+		}
 	}
 
 	return result
@@ -191,7 +235,9 @@ func loadNetConf(bytes []byte) (*netConf, string, error) {
 		n.Provider = util.OvnProvider
 	}
 
-	n.postLoad()
+	if err := n.postLoad(); err != nil {
+		return nil, "", types.NewError(types.ErrDecodingFailure, "failed to load prevResult", err.Error())
+	}
 	return n, n.CNIVersion, nil
 }
 
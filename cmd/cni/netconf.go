@@ -5,6 +5,7 @@ package cni
 
 import (
 	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/version"
 
 	"github.com/kubeovn/kube-ovn/pkg/request"
 )
@@ -23,6 +24,6 @@ type netConf struct {
 	VhostUserSocketName       string `json:"vhost_user_socket_name"`
 }
 
-func (n *netConf) postLoad() {
-	// nothing to do on linux
+func (n *netConf) postLoad() error {
+	return version.ParsePrevResult(&n.NetConf)
 }
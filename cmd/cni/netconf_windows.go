@@ -1,6 +1,7 @@
 package cni
 
 import (
+	"github.com/containernetworking/cni/pkg/version"
 	"github.com/containernetworking/plugins/pkg/hns"
 
 	"github.com/kubeovn/kube-ovn/pkg/request"
@@ -20,11 +21,12 @@ type netConf struct {
 	VhostUserSocketName       string `json:"vhost_user_socket_name"`
 }
 
-func (n *netConf) postLoad() {
+func (n *netConf) postLoad() error {
 	if len(n.DNS.Nameservers) == 0 {
 		n.DNS.Nameservers = n.RuntimeConfig.DNS.Nameservers
 	}
 	if len(n.DNS.Search) == 0 {
 		n.DNS.Search = n.RuntimeConfig.DNS.Search
 	}
+	return version.ParsePrevResult(&n.NetConf.NetConf)
 }
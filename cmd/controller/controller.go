@@ -38,10 +38,13 @@ func CmdMain() {
 		util.LogFatalAndExit(err, "failed to check permission")
 	}
 
+	ctl := controller.NewController(config)
+
 	go loopOvnNbctlDaemon(config)
 	go func() {
 		mux := http.NewServeMux()
 		mux.Handle("/metrics", promhttp.Handler())
+		mux.Handle("/api/", ctl.ApiHandler())
 		if config.EnablePprof {
 			mux.HandleFunc("/debug/pprof/", pprof.Index)
 			mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
@@ -60,7 +63,6 @@ func CmdMain() {
 		klog.Fatal(server.ListenAndServe())
 	}()
 
-	ctl := controller.NewController(config)
 	ctl.Run(stopCh)
 }
 
@@ -70,7 +72,7 @@ func loopOvnNbctlDaemon(config *controller.Configuration) {
 		time.Sleep(5 * time.Second)
 
 		if _, err := os.Stat(daemonSocket); os.IsNotExist(err) || daemonSocket == "" {
-			if err := ovs.StartOvnNbctlDaemon(config.OvnNbAddr); err != nil {
+			if err := ovs.StartOvnNbctlDaemon(config.OvnNbAddr, config.OvnInactivityProbe, config.OvnSSLFiles()); err != nil {
 				klog.Errorf("failed to start ovn-nbctl daemon %v", err)
 			}
 		}
@@ -79,7 +81,7 @@ func loopOvnNbctlDaemon(config *controller.Configuration) {
 		// In case of that, we need to start a new daemon.
 		if err := ovs.CheckAlive(); err != nil {
 			klog.Warningf("ovn-nbctl daemon doesn't return, start a new daemon")
-			if err := ovs.StartOvnNbctlDaemon(config.OvnNbAddr); err != nil {
+			if err := ovs.StartOvnNbctlDaemon(config.OvnNbAddr, config.OvnInactivityProbe, config.OvnSSLFiles()); err != nil {
 				klog.Errorf("failed to start ovn-nbctl daemon %v", err)
 			}
 		}
@@ -37,7 +37,7 @@ func CmdMain() {
 		klog.Fatal(err)
 	}
 
-	nicBridgeMappings, err := daemon.InitOVSBridges()
+	nicBridgeMappings, err := daemon.InitOVSBridges(config)
 	if err != nil {
 		util.LogFatalAndExit(err, "failed to initialize OVS bridges")
 	}
@@ -27,6 +27,10 @@ IFACE=${IFACE:-}
 # Specifies the name of the dpdk tunnel iface.
 # Note that the dpdk tunnel iface and tunnel ip cidr should be diffierent with Kubernetes api cidr,otherwise the route will be a problem.
 DPDK_TUNNEL_IFACE=${DPDK_TUNNEL_IFACE:-br-phy}
+# Hugepage memory in MB ovs-dpdk allocates on each numa socket, e.g. 1024,1024
+DPDK_SOCKET_MEM=${DPDK_SOCKET_MEM:-1024}
+# Hexadecimal mask of the cores running pmd threads, empty to let ovs-dpdk decide
+DPDK_PMD_CPU_MASK=${DPDK_PMD_CPU_MASK:-}
 
 CNI_CONF_DIR="/etc/cni/net.d"
 CNI_BIN_DIR="/opt/cni/bin"
@@ -253,6 +257,10 @@ spec:
                   type: string
                 subnet:
                   type: string
+                ips:
+                  type: array
+                  items:
+                    type: string
             status:
               type: object
               properties:
@@ -376,13 +384,38 @@ spec:
         - jsonPath: .spec.lanIp
           name: LanIP
           type: string
+        - jsonPath: .status.conditions[?(@.type=="Healthy")].status
+          name: Healthy
+          type: string
       name: v1
       served: true
       storage: true
+      subresources:
+        status: {}
       schema:
         openAPIV3Schema:
           type: object
           properties:
+            status:
+              type: object
+              properties:
+                conditions:
+                  type: array
+                  items:
+                    type: object
+                    properties:
+                      type:
+                        type: string
+                      status:
+                        type: string
+                      reason:
+                        type: string
+                      message:
+                        type: string
+                      lastUpdateTime:
+                        type: string
+                      lastTransitionTime:
+                        type: string
             spec:
               type: object
               properties:
@@ -396,6 +429,12 @@ spec:
                   type: array
                   items:
                     type: string
+                nodes:
+                  type: array
+                  items:
+                    type: string
+                externalProvider:
+                  type: string
                 tolerations:
                   type: array
                   items:
@@ -972,6 +1011,103 @@ spec:
 ---
 apiVersion: apiextensions.k8s.io/v1
 kind: CustomResourceDefinition
+metadata:
+  name: egress-ip-pools.kubeovn.io
+spec:
+  group: kubeovn.io
+  names:
+    plural: egress-ip-pools
+    singular: egress-ip-pool
+    shortNames:
+      - eipp
+    kind: EgressIPPool
+    listKind: EgressIPPoolList
+  scope: Cluster
+  versions:
+    - name: v1
+      served: true
+      storage: true
+      subresources:
+        status: {}
+      additionalPrinterColumns:
+      - jsonPath: .spec.ips
+        name: IPs
+        type: string
+      - jsonPath: .status.assignedPods
+        name: Assigned
+        type: integer
+      - jsonPath: .status.pendingPods
+        name: Pending
+        type: integer
+      - jsonPath: .status.exhausted
+        name: Exhausted
+        type: boolean
+      schema:
+        openAPIV3Schema:
+          type: object
+          properties:
+            status:
+              type: object
+              properties:
+                assignedPods:
+                  type: integer
+                pendingPods:
+                  type: integer
+                exhausted:
+                  type: boolean
+            spec:
+              type: object
+              properties:
+                ips:
+                  type: array
+                  items:
+                    type: string
+                maxPodsPerIP:
+                  type: integer
+                  minimum: 0
+                namespaceSelector:
+                  type: object
+                  properties:
+                    matchLabels:
+                      type: object
+                      additionalProperties:
+                        type: string
+                    matchExpressions:
+                      type: array
+                      items:
+                        type: object
+                        properties:
+                          key:
+                            type: string
+                          operator:
+                            type: string
+                          values:
+                            type: array
+                            items:
+                              type: string
+                podSelector:
+                  type: object
+                  properties:
+                    matchLabels:
+                      type: object
+                      additionalProperties:
+                        type: string
+                    matchExpressions:
+                      type: array
+                      items:
+                        type: object
+                        properties:
+                          key:
+                            type: string
+                          operator:
+                            type: string
+                          values:
+                            type: array
+                            items:
+                              type: string
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
 metadata:
   name: vpcs.kubeovn.io
 spec:
@@ -1011,6 +1147,15 @@ spec:
                         type: string
                       nextHopIP:
                         type: string
+                      bfd:
+                        properties:
+                          minTx:
+                            type: integer
+                          minRx:
+                            type: integer
+                          detectMult:
+                            type: integer
+                        type: object
                     type: object
                   type: array
                 policyRoutes:
@@ -1073,6 +1218,21 @@ spec:
                   items:
                     type: string
                   type: array
+                staticRoutes:
+                  items:
+                    properties:
+                      policy:
+                        type: string
+                      cidr:
+                        type: string
+                      nextHopIP:
+                        type: string
+                      bfdStatus:
+                        type: string
+                      withdrawn:
+                        type: boolean
+                    type: object
+                  type: array
                 tcpLoadBalancer:
                   type: string
                 tcpSessionLoadBalancer:
@@ -1311,6 +1471,9 @@ spec:
       - name: GatewayType
         type: string
         jsonPath: .spec.gatewayType
+      - name: GatewayMode
+        type: string
+        jsonPath: .status.gatewayMode
       - name: V4Used
         type: number
         jsonPath: .status.v4usingIPs
@@ -1343,6 +1506,10 @@ spec:
                   type: number
                 activateGateway:
                   type: string
+                gatewayMode:
+                  type: string
+                lastGatewayReachableTime:
+                  type: string
                 dhcpV4OptionsUUID:
                   type: string
                 dhcpV6OptionsUUID:
@@ -1379,6 +1546,10 @@ spec:
                     - Dual
                 cidrBlock:
                   type: string
+                extraCIDRBlocks:
+                  type: array
+                  items:
+                    type: string
                 namespaces:
                   type: array
                   items:
@@ -1403,6 +1574,16 @@ spec:
                     type: string
                 gatewayNode:
                   type: string
+                gatewayUnavailablePolicy:
+                  type: string
+                  enum:
+                    - allow
+                    - fail
+                gatewayMode:
+                  type: string
+                  enum:
+                    - L2
+                    - L3
                 natOutgoing:
                   type: boolean
                 u2oRouting:
@@ -1431,12 +1612,80 @@ spec:
                   type: boolean
                 disableGatewayCheck:
                   type: boolean
+                gatewayCheckPort:
+                  type: integer
+                  minimum: 1
+                  maximum: 65535
+                gatewayCheckMode:
+                  type: string
+                  enum:
+                    - auto
+                    - ping
+                    - arping
+                    - tcp
+                    - disabled
+                arpResponder:
+                  type: string
+                  enum:
+                    - distributed
+                    - flood
+                    - router
+                preferredIPFamily:
+                  type: string
+                  enum:
+                    - IPv4
+                    - IPv6
+                broadcastRateLimit:
+                  type: integer
+                  minimum: 0
+                enableMulticastSnoop:
+                  type: boolean
+                enableMulticastQuerier:
+                  type: boolean
+                multicastQuerierIP:
+                  type: string
+                logicalSwitchOptions:
+                  type: object
+                  additionalProperties:
+                    type: string
+                externalIPAM:
+                  type: string
+                macOUI:
+                  type: string
+                suppressGatewayArp:
+                  type: boolean
+                dhcpLeaseTime:
+                  type: integer
+                  minimum: 0
                 disableInterConnection:
                   type: boolean
+                disableTxChecksum:
+                  type: boolean
+                allowGatewayPing:
+                  type: boolean
+                gatewayMac:
+                  type: string
                 htbqos:
                   type: string
+                defaultIngressRate:
+                  type: string
+                defaultEgressRate:
+                  type: string
+                qosType:
+                  type: string
+                  enum:
+                    - linux-htb
+                    - linux-hfsc
+                aggregateEgressRate:
+                  type: string
                 enableDHCP:
                   type: boolean
+                disableDHCP:
+                  type: boolean
+                disableDHCPv4:
+                  type: boolean
+                disableDHCPv6:
+                  type: boolean
                 dhcpV4Options:
                   type: string
                 dhcpV6Options:
@@ -1445,6 +1694,10 @@ spec:
                   type: boolean
                 ipv6RAConfigs:
                   type: string
+                podIfName:
+                  type: string
+                enableReverseDns:
+                  type: boolean
                 acls:
                   type: array
                   items:
@@ -1580,6 +1833,23 @@ spec:
                           type: string
                 exchangeLinkName:
                   type: boolean
+                bridgeName:
+                  type: string
+                  maxLength: 15
+                  pattern: '^[^/\s]+$'
+                vlanRanges:
+                  type: array
+                  items:
+                    type: string
+                    pattern: '^[0-9]+(\.\.[0-9]+)?$'
+                gatewayCheckMode:
+                  type: string
+                  enum:
+                    - auto
+                    - ping
+                    - arping
+                    - tcp
+                    - disabled
                 excludeNodes:
                   type: array
                   items:
@@ -1780,6 +2050,7 @@ rules:
       - vpcs
       - vpcs/status
       - vpc-nat-gateways
+      - vpc-nat-gateways/status
       - subnets
       - subnets/status
       - ips
@@ -1810,6 +2081,8 @@ rules:
       - switch-lb-rules/status
       - vpc-dnses
       - vpc-dnses/status
+      - egress-ip-pools
+      - egress-ip-pools/status
     verbs:
       - "*"
   - apiGroups:
@@ -1817,6 +2090,7 @@ rules:
     resources:
       - pods
       - pods/exec
+      - pods/status
       - namespaces
       - nodes
       - configmaps
@@ -1881,6 +2155,18 @@ rules:
     verbs:
       - get
       - list
+  - apiGroups:
+      - authentication.k8s.io
+    resources:
+      - tokenreviews
+    verbs:
+      - create
+  - apiGroups:
+      - authorization.k8s.io
+    resources:
+      - subjectaccessreviews
+    verbs:
+      - create
 ---
 apiVersion: rbac.authorization.k8s.io/v1
 kind: ClusterRoleBinding
@@ -2137,6 +2423,10 @@ spec:
             runAsUser: 0
             privileged: true
           env:
+            - name: DPDK_SOCKET_MEM
+              value: "$DPDK_SOCKET_MEM"
+            - name: DPDK_PMD_CPU_MASK
+              value: "$DPDK_PMD_CPU_MASK"
             - name: ENABLE_SSL
               value: "$ENABLE_SSL"
             - name: POD_IP
@@ -2277,6 +2567,7 @@ rules:
       - vpcs
       - vpcs/status
       - vpc-nat-gateways
+      - vpc-nat-gateways/status
       - subnets
       - subnets/status
       - ips
@@ -2305,6 +2596,8 @@ rules:
       - ovn-snat-rules/status
       - vpc-dnses
       - vpc-dnses/status
+      - egress-ip-pools
+      - egress-ip-pools/status
       - switch-lb-rules
       - switch-lb-rules/status
     verbs:
@@ -2314,6 +2607,7 @@ rules:
     resources:
       - pods
       - pods/exec
+      - pods/status
       - namespaces
       - nodes
       - configmaps
@@ -2800,6 +3094,10 @@ spec:
               value: "$TUNNEL_TYPE"
             - name: DPDK_TUNNEL_IFACE
               value: "$DPDK_TUNNEL_IFACE"
+            - name: DPDK_SOCKET_MEM
+              value: "$DPDK_SOCKET_MEM"
+            - name: DPDK_PMD_CPU_MASK
+              value: "$DPDK_PMD_CPU_MASK"
             - name: KUBE_NODE_NAME
               valueFrom:
                 fieldRef:
@@ -34,11 +34,16 @@ function quit {
 trap quit EXIT
 
 CONFIG_FILE=/opt/ovs-config/config.cfg
+DPDK_SOCKET_MEM=${DPDK_SOCKET_MEM:-1024}
+DPDK_PMD_CPU_MASK=${DPDK_PMD_CPU_MASK:-}
 
 # Check if config file exists, create default one if not
 if ! test -f "$CONFIG_FILE"; then
 	mkdir -p $(dirname ${CONFIG_FILE})
-	printf %s\\n {dpdk-socket-mem=\"1024\",dpdk-init=true,dpdk-hugepage-dir=/dev/hugepages} > $CONFIG_FILE
+	printf %s\\n {dpdk-socket-mem=\"${DPDK_SOCKET_MEM}\",dpdk-init=true,dpdk-hugepage-dir=/dev/hugepages} > $CONFIG_FILE
+	if [ -n "$DPDK_PMD_CPU_MASK" ]; then
+		echo "pmd-cpu-mask=${DPDK_PMD_CPU_MASK}" >> $CONFIG_FILE
+	fi
 fi
 
 # Start ovsdb
@@ -11,13 +11,13 @@ function exec_cmd() {
 }
 
 function init() {
+    ip link set net1 up
+    ip link set dev net1 arp off
     # run once is enough
     iptables-save | grep DNAT_FILTER && exit 0
     # add static chain
     # this also a flag to make sure init once
     iptables -t nat -N DNAT_FILTER
-    ip link set net1 up
-    ip link set dev net1 arp off
 
     # add static chain
     iptables -t nat -N SNAT_FILTER
@@ -36,6 +36,20 @@ function init() {
 }
 
 
+function health_check() {
+    # read only checks, never changes the dataplane
+    for nic in eth0 net1
+    do
+        ip link show dev $nic | grep -q ",UP" || { echo "interface $nic is down" >&2; exit 1; }
+    done
+    ip -4 route show dev eth0 | grep -q . || { echo "no route via eth0" >&2; exit 1; }
+    defaultRoute=$(ip -4 route show default)
+    if [ -n "$defaultRoute" ]; then
+        echo "$defaultRoute" | grep -q "dev net1" || { echo "default route is not via net1: $defaultRoute" >&2; exit 1; }
+    fi
+    iptables-save -t nat | grep -q SNAT_FILTER || { echo "nat chains are not initialized" >&2; exit 1; }
+}
+
 function get_iptables_version() {
   exec_cmd "iptables --version"
 }
@@ -136,6 +150,20 @@ function del_eip() {
     done
 }
 
+function announce_eip() {
+    # send gratuitous arp on the external interface so that the external gateway relearns the eips
+    ret=0
+    for rule in $@
+    do
+        arr=(${rule//,/ })
+        eip=(${arr[0]//\// })
+        # skip the eips which are not configured on net1 any more
+        ip -4 addr show dev net1 | grep -qwF "inet $eip" || continue
+        arping -U -I net1 -c 1 $eip >/dev/null || { echo "failed to announce eip $eip" >&2; ret=1; }
+    done
+    exit $ret
+}
+
 function add_floating_ip() {
     # make sure inited
     iptables-save -t nat | grep  SNAT_FILTER | grep SHARED_SNAT
@@ -271,6 +299,9 @@ case $opt in
         echo "eip-del $rules"
         del_eip $rules
         ;;
+ eip-arp)
+        announce_eip $rules
+        ;;
  dnat-add)
         echo "dnat-add $rules"
         add_dnat $rules
@@ -299,8 +330,11 @@ case $opt in
         echo "get-iptables-version $rules"
         get_iptables_version $rules
         ;;
+ health-check)
+        health_check
+        ;;
  *)
-        echo "Usage: $0 [init|subnet-route-add|subnet-route-del|eip-add|eip-del|floating-ip-add|floating-ip-del|dnat-add|dnat-del|snat-add|snat-del] ..."
+        echo "Usage: $0 [init|subnet-route-add|subnet-route-del|eip-add|eip-del|eip-arp|floating-ip-add|floating-ip-del|dnat-add|dnat-del|snat-add|snat-del|health-check] ..."
         exit 1
         ;;
 esac
@@ -170,6 +170,21 @@ func (v *VlanStatus) SetVlanCondition(ctype ConditionType, reason, message strin
 	v.setVlanConditionValue(ctype, corev1.ConditionTrue, reason, message)
 }
 
+// GetVlanCondition returns the condition of the type
+func (v *VlanStatus) GetVlanCondition(ctype ConditionType) *VlanCondition {
+	for i := range v.Conditions {
+		if v.Conditions[i].Type == ctype {
+			return &v.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// ClearVlanCondition updates or creates a new condition with status false
+func (v *VlanStatus) ClearVlanCondition(ctype ConditionType, reason, message string) {
+	v.setVlanConditionValue(ctype, corev1.ConditionFalse, reason, message)
+}
+
 func (v *VlanStatus) setVlanConditionValue(ctype ConditionType, status corev1.ConditionStatus, reason, message string) {
 	var c *VlanCondition
 	for i := range v.Conditions {
@@ -297,6 +312,19 @@ func (s *ProviderNetworkStatus) IsReady() bool {
 	return true
 }
 
+// ProviderNetworkNodeSteps are the conditions of the steps initializing the provider network on a node, in order
+var ProviderNetworkNodeSteps = []ConditionType{BridgeReady, RoutesMigrated, NicAttached}
+
+// NodeStepsReady returns true if all the steps initializing the provider network on the node are completed
+func (s *ProviderNetworkStatus) NodeStepsReady(node string) bool {
+	for _, step := range ProviderNetworkNodeSteps {
+		if !s.IsNodeConditionTrue(node, step) {
+			return false
+		}
+	}
+	return true
+}
+
 // ConditionReason - return condition reason
 func (s *ProviderNetworkStatus) ConditionReason(node string, ctype ConditionType) string {
 	if c := s.GetNodeCondition(node, ctype); c != nil {
@@ -339,6 +367,11 @@ func (s *ProviderNetworkStatus) SetNodeCondition(node string, ctype ConditionTyp
 	s.setNodeConditionValue(node, ctype, corev1.ConditionTrue, reason, message)
 }
 
+// SetNodeConditionUnknown updates or creates a condition with unknown status
+func (s *ProviderNetworkStatus) SetNodeConditionUnknown(node string, ctype ConditionType, reason, message string) {
+	s.setNodeConditionValue(node, ctype, corev1.ConditionUnknown, reason, message)
+}
+
 // RemoveNodeConditions updates or creates a new condition
 func (s *ProviderNetworkStatus) RemoveNodeConditions(node string) bool {
 	var changed bool
@@ -352,3 +385,46 @@ func (s *ProviderNetworkStatus) RemoveNodeConditions(node string) bool {
 	}
 	return changed
 }
+
+// SetVpcNatCondition updates or creates a new condition
+func (s *VpcNatStatus) SetVpcNatCondition(ctype ConditionType, status corev1.ConditionStatus, reason, message string) {
+	var c *VpcNatCondition
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == ctype {
+			c = &s.Conditions[i]
+		}
+	}
+	if c == nil {
+		now := metav1.Now()
+		s.Conditions = append(s.Conditions, VpcNatCondition{
+			Type:               ctype,
+			LastUpdateTime:     now,
+			LastTransitionTime: now,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+		})
+		return
+	}
+	if c.Status == status && c.Reason == reason && c.Message == message {
+		return
+	}
+	now := metav1.Now()
+	c.LastUpdateTime = now
+	if c.Status != status {
+		c.LastTransitionTime = now
+	}
+	c.Status = status
+	c.Reason = reason
+	c.Message = message
+}
+
+// GetVpcNatCondition returns the condition of the given type
+func (s *VpcNatStatus) GetVpcNatCondition(ctype ConditionType) *VpcNatCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == ctype {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
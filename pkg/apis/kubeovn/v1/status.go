@@ -117,3 +117,23 @@ func (osrs *OvnSnatRuleStatus) Bytes() ([]byte, error) {
 	klog.V(5).Info("status body", newStr)
 	return []byte(newStr), nil
 }
+
+func (eipps *EgressIPPoolStatus) Bytes() ([]byte, error) {
+	bytes, err := json.Marshal(eipps)
+	if err != nil {
+		return nil, err
+	}
+	newStr := fmt.Sprintf(`{"status": %s}`, string(bytes))
+	klog.V(5).Info("status body", newStr)
+	return []byte(newStr), nil
+}
+
+func (vngs *VpcNatStatus) Bytes() ([]byte, error) {
+	bytes, err := json.Marshal(vngs)
+	if err != nil {
+		return nil, err
+	}
+	newStr := fmt.Sprintf(`{"status": %s}`, string(bytes))
+	klog.V(5).Info("status body", newStr)
+	return []byte(newStr), nil
+}
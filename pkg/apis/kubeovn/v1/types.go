@@ -14,6 +14,31 @@ const (
 
 	GWDistributedType = "distributed"
 	GWCentralizedType = "centralized"
+
+	// GatewayUnavailablePolicyAllow routes the new pods with a warning when all gateways of the centralized subnet are not ready
+	GatewayUnavailablePolicyAllow = "allow"
+	// GatewayUnavailablePolicyFail keeps the new pods pending until any gateway of the centralized subnet is ready
+	GatewayUnavailablePolicyFail = "fail"
+
+	// GatewayModeL2 forwards the traffic of the underlay subnet by the external gateway of the physical network
+	GatewayModeL2 = "L2"
+	// GatewayModeL3 forwards the traffic of the subnet by the logical router of ovn
+	GatewayModeL3 = "L3"
+
+	// GatewayCheckModeAuto checks the gateway by tcp if the subnet has gatewayCheckPort, by arping for underlay subnets
+	// without gatewayMac and by ping for the others
+	GatewayCheckModeAuto     = "auto"
+	GatewayCheckModePing     = "ping"
+	GatewayCheckModeArping   = "arping"
+	GatewayCheckModeTCP      = "tcp"
+	GatewayCheckModeDisabled = "disabled"
+
+	// ArpResponderDistributed answers the arp/nd requests for every port of the subnet in the logical switch
+	ArpResponderDistributed = "distributed"
+	// ArpResponderFlood disables the arp/nd responder of the ports and floods the requests to the ports
+	ArpResponderFlood = "flood"
+	// ArpResponderRouter disables the arp/nd responder of the ports and answers the requests by proxy arp of the router port
+	ArpResponderRouter = "router"
 )
 
 type SgRemoteType string
@@ -47,6 +72,20 @@ const (
 	Validated = "Validated"
 	// Error => last recorded error
 	Error = "Error"
+	// Healthy => dataplane probe passed
+	Healthy = "Healthy"
+	// Maintenance => reconciliation is paused by the user
+	Maintenance = "Maintenance"
+	// ArpResponder => the arp/nd responder mode applied to the logical switch
+	ArpResponder = "ArpResponder"
+	// BridgeReady => the external bridge of the provider network is created and configured on the node
+	BridgeReady = "BridgeReady"
+	// RoutesMigrated => the addresses and routes of the provider nic are transferred to the external bridge
+	RoutesMigrated = "RoutesMigrated"
+	// NicAttached => the provider nic is added to the external bridge
+	NicAttached = "NicAttached"
+	// GatewayReachable => the gateways forwarding the egress traffic of the subnet are reachable
+	GatewayReachable = "GatewayReachable"
 
 	ReasonInit = "Init"
 )
@@ -110,10 +149,19 @@ type SubnetSpec struct {
 	ExcludeIps []string `json:"excludeIps,omitempty"`
 	Provider   string   `json:"provider,omitempty"`
 
+	// ExtraCIDRBlocks are additional IPv4 CIDRs sharing the broadcast domain and the gateway of CIDRBlock,
+	// addresses are allocated from all of them as a single pool
+	ExtraCIDRBlocks []string `json:"extraCIDRBlocks,omitempty"`
+
 	GatewayType string `json:"gatewayType,omitempty"`
 	GatewayNode string `json:"gatewayNode"`
 	NatOutgoing bool   `json:"natOutgoing"`
 	U2oRouting  bool   `json:"u2oRouting,omitempty"`
+	// GatewayUnavailablePolicy handles the new pods when all gateways of the centralized subnet are not ready, allow or fail
+	GatewayUnavailablePolicy string `json:"gatewayUnavailablePolicy,omitempty"`
+	// GatewayMode is the gateway mode of the subnet, L2 or L3. It's derived from vlan and logicalGateway if not set,
+	// L3 for overlay subnets and underlay subnets with logicalGateway, and L2 for the other underlay subnets
+	GatewayMode string `json:"gatewayMode,omitempty"`
 
 	ExternalEgressGateway string `json:"externalEgressGateway,omitempty"`
 	PolicyRoutingPriority uint32 `json:"policyRoutingPriority,omitempty"`
@@ -125,20 +173,90 @@ type SubnetSpec struct {
 	Vlan   string `json:"vlan,omitempty"`
 	HtbQos string `json:"htbqos,omitempty"`
 
+	// DefaultIngressRate and DefaultEgressRate are the rate limits in Mbit/s of pods without
+	// their own ingress_rate/egress_rate annotations
+	DefaultIngressRate string `json:"defaultIngressRate,omitempty"`
+	DefaultEgressRate  string `json:"defaultEgressRate,omitempty"`
+	// QosType is the ovs qos type of the pods limiting egress bandwidth, linux-htb or linux-hfsc
+	QosType string `json:"qosType,omitempty"`
+	// AggregateEgressRate is the rate limit in Mbit/s shared by the egress traffic of all pods in the subnet
+	AggregateEgressRate string `json:"aggregateEgressRate,omitempty"`
+
 	Vips []string `json:"vips,omitempty"`
 
 	LogicalGateway         bool `json:"logicalGateway,omitempty"`
 	DisableGatewayCheck    bool `json:"disableGatewayCheck,omitempty"`
 	DisableInterConnection bool `json:"disableInterConnection,omitempty"`
+	DisableTxChecksum      bool `json:"disableTxChecksum,omitempty"`
+	AllowGatewayPing       bool `json:"allowGatewayPing,omitempty"`
+
+	// GatewayMac is the static mac of the gateway of underlay pods, resolved by arp/ndp if not set
+	GatewayMac string `json:"gatewayMac,omitempty"`
+	// GatewayCheckPort checks the gateway by tcp connection to the port instead of ping/arping
+	GatewayCheckPort int `json:"gatewayCheckPort,omitempty"`
+	// GatewayCheckMode overrides the gateway check mode of the provider network and the cluster for the pods of the subnet,
+	// auto, ping, arping, tcp or disabled. DisableGatewayCheck is the same as disabled if it is not set
+	GatewayCheckMode string `json:"gatewayCheckMode,omitempty"`
+
+	// ArpResponder is the arp/nd responder mode of the subnet, distributed, flood or router.
+	// flood and router reduce the flows of large subnets at the cost of broadcast traffic
+	ArpResponder string `json:"arpResponder,omitempty"`
+
+	// PreferredIPFamily is the primary ip family of the dual stack pods in the subnet, IPv4 or IPv6,
+	// which decides the order of the pod ips and the default routes
+	PreferredIPFamily string `json:"preferredIPFamily,omitempty"`
+
+	// BroadcastRateLimit limits the arp/nd/broadcast rate in kbps sent by each pod port of the subnet,
+	// the excess packets are dropped by ovn meters, dhcp requests are not limited, 0 to disable
+	BroadcastRateLimit int `json:"broadcastRateLimit,omitempty"`
+
+	// EnableMulticastSnoop enables igmp/mld snooping of the logical switch, so that the multicast traffic is only
+	// forwarded to the ports joining the groups instead of flooded
+	EnableMulticastSnoop bool `json:"enableMulticastSnoop,omitempty"`
+	// EnableMulticastQuerier sends igmp/mld general queries from the logical switch, which requires EnableMulticastSnoop
+	EnableMulticastQuerier bool `json:"enableMulticastQuerier,omitempty"`
+	// MulticastQuerierIP is the comma separated source ips of the queries in the subnet, one per protocol,
+	// defaults to the gateway
+	MulticastQuerierIP string `json:"multicastQuerierIP,omitempty"`
+
+	// LogicalSwitchOptions are the other_config options of the logical switch, only the options in an allowlist
+	// such as mcast_snoop and mcast_querier are supported
+	LogicalSwitchOptions map[string]string `json:"logicalSwitchOptions,omitempty"`
+
+	// ExternalIPAM is the url of the webhook which reserves and releases the pod addresses of the subnet
+	// instead of the built-in ipam
+	ExternalIPAM string `json:"externalIPAM,omitempty"`
+
+	// MacOUI is the 3-byte prefix of the macs generated for the pods in the subnet, such as 02:1a:2b,
+	// the macs specified by annotations are not restricted
+	MacOUI string `json:"macOUI,omitempty"`
+
+	// SuppressGatewayArp drops the arp/nd replies for the gateway ips from the logical router port of the underlay
+	// subnet, so that the physical gateway answers them and the pods send the traffic to the physical gateway
+	SuppressGatewayArp bool `json:"suppressGatewayArp,omitempty"`
 
 	EnableDHCP    bool   `json:"enableDHCP,omitempty"`
 	DHCPv4Options string `json:"dhcpV4Options,omitempty"`
 	DHCPv6Options string `json:"dhcpV6Options,omitempty"`
+	// DHCPLeaseTime is the lease time in seconds of the addresses offered by the ovn dhcpv4 server, defaults to 3600.
+	// The ovn dhcpv6 server always offers addresses with infinite lifetimes, so it does not apply to dhcpv6
+	DHCPLeaseTime int `json:"dhcpLeaseTime,omitempty"`
+	// DisableDHCP turns off the ovn dhcp of the subnet for an external dhcp server, and takes precedence over EnableDHCP.
+	// DisableDHCPv4 and DisableDHCPv6 turn off the ovn dhcp of one protocol of a dual stack subnet
+	DisableDHCP   bool `json:"disableDHCP,omitempty"`
+	DisableDHCPv4 bool `json:"disableDHCPv4,omitempty"`
+	DisableDHCPv6 bool `json:"disableDHCPv6,omitempty"`
 
 	EnableIPv6RA  bool   `json:"enableIPv6RA,omitempty"`
 	IPv6RAConfigs string `json:"ipv6RAConfigs,omitempty"`
 
 	Acls []Acl `json:"acls,omitempty"`
+
+	// PodIfName is the name of the primary interface inside the pods, defaults to eth0
+	PodIfName string `json:"podIfName,omitempty"`
+
+	// EnableReverseDNS serves the reverse records of the pods in the subnet by the vpc-dns of the vpc
+	EnableReverseDNS bool `json:"enableReverseDns,omitempty"`
 }
 
 type Acl struct {
@@ -188,6 +306,13 @@ type SubnetStatus struct {
 	ActivateGateway   string  `json:"activateGateway"`
 	DHCPv4OptionsUUID string  `json:"dhcpV4OptionsUUID"`
 	DHCPv6OptionsUUID string  `json:"dhcpV6OptionsUUID"`
+
+	// GatewayMode is the effective gateway mode of the subnet, L2 or L3
+	GatewayMode string `json:"gatewayMode,omitempty"`
+
+	// LastGatewayReachableTime is the time of the last check finding the gateways of the subnet reachable,
+	// it is updated at most once a minute
+	LastGatewayReachableTime metav1.Time `json:"lastGatewayReachableTime,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -282,6 +407,14 @@ type ProviderNetworkSpec struct {
 	CustomInterfaces []CustomInterface `json:"customInterfaces,omitempty"`
 	ExcludeNodes     []string          `json:"excludeNodes,omitempty"`
 	ExchangeLinkName bool              `json:"exchangeLinkName,omitempty"`
+	// BridgeName is the name of the external OVS bridge, defaults to br-<name>
+	BridgeName string `json:"bridgeName,omitempty"`
+	// VlanRanges are the allowed vlan ids of the provider network in the format of "id" or "start..end",
+	// all vlan ids from 1 to 4094 are allowed if empty, vlan 0 is untagged and always allowed
+	VlanRanges []string `json:"vlanRanges,omitempty"`
+	// GatewayCheckMode overrides the gateway check mode of the cluster for the pods of the underlay subnets
+	// in the provider network, auto, ping, arping, tcp or disabled
+	GatewayCheckMode string `json:"gatewayCheckMode,omitempty"`
 }
 
 type ProviderNetworkStatus struct {
@@ -367,6 +500,24 @@ type StaticRoute struct {
 	Policy    RoutePolicy `json:"policy,omitempty"`
 	CIDR      string      `json:"cidr"`
 	NextHopIP string      `json:"nextHopIP"`
+	BFD       *BFDConfig  `json:"bfd,omitempty"`
+}
+
+// BFDConfig enables bfd towards the next hop of a static route,
+// zero values fall back to the defaults of kube-ovn-controller
+type BFDConfig struct {
+	MinTx      int `json:"minTx,omitempty"`
+	MinRx      int `json:"minRx,omitempty"`
+	DetectMult int `json:"detectMult,omitempty"`
+}
+
+type StaticRouteStatus struct {
+	Policy    RoutePolicy `json:"policy,omitempty"`
+	CIDR      string      `json:"cidr"`
+	NextHopIP string      `json:"nextHopIP"`
+	BFDStatus string      `json:"bfdStatus,omitempty"`
+	// Withdrawn is true when ovn skips the route as the bfd session to the next hop is down
+	Withdrawn bool `json:"withdrawn"`
 }
 
 type PolicyRouteAction string
@@ -393,17 +544,18 @@ type VpcStatus struct {
 	// +patchStrategy=merge
 	Conditions []VpcCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 
-	Standby                bool     `json:"standby"`
-	Default                bool     `json:"default"`
-	DefaultLogicalSwitch   string   `json:"defaultLogicalSwitch"`
-	Router                 string   `json:"router"`
-	TcpLoadBalancer        string   `json:"tcpLoadBalancer"`
-	UdpLoadBalancer        string   `json:"udpLoadBalancer"`
-	TcpSessionLoadBalancer string   `json:"tcpSessionLoadBalancer"`
-	UdpSessionLoadBalancer string   `json:"udpSessionLoadBalancer"`
-	Subnets                []string `json:"subnets"`
-	VpcPeerings            []string `json:"vpcPeerings"`
-	EnableExternal         bool     `json:"enableExternal"`
+	Standby                bool                 `json:"standby"`
+	Default                bool                 `json:"default"`
+	DefaultLogicalSwitch   string               `json:"defaultLogicalSwitch"`
+	Router                 string               `json:"router"`
+	TcpLoadBalancer        string               `json:"tcpLoadBalancer"`
+	UdpLoadBalancer        string               `json:"udpLoadBalancer"`
+	TcpSessionLoadBalancer string               `json:"tcpSessionLoadBalancer"`
+	UdpSessionLoadBalancer string               `json:"udpSessionLoadBalancer"`
+	Subnets                []string             `json:"subnets"`
+	VpcPeerings            []string             `json:"vpcPeerings"`
+	EnableExternal         bool                 `json:"enableExternal"`
+	StaticRoutes           []*StaticRouteStatus `json:"staticRoutes"`
 }
 
 // Condition describes the state of an object at a certain point.
@@ -445,7 +597,8 @@ type VpcNatGateway struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec VpcNatSpec `json:"spec"`
+	Spec   VpcNatSpec   `json:"spec"`
+	Status VpcNatStatus `json:"status,omitempty"`
 }
 
 type VpcNatSpec struct {
@@ -454,6 +607,39 @@ type VpcNatSpec struct {
 	LanIp       string             `json:"lanIp"`
 	Selector    []string           `json:"selector"`
 	Tolerations []VpcNatToleration `json:"tolerations"`
+	// Nodes are the candidate nodes of the gateway pod in order of preference
+	Nodes []string `json:"nodes,omitempty"`
+	// ExternalProvider is the provider network which must be ready on the node of the gateway pod
+	ExternalProvider string `json:"externalProvider,omitempty"`
+}
+
+// Condition describes the state of an object at a certain point.
+// +k8s:deepcopy-gen=true
+type VpcNatCondition struct {
+	// Type of condition.
+	Type ConditionType `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+	// The reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// A human readable message indicating details about the transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Last time the condition was probed
+	// +optional
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+	// Last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+type VpcNatStatus struct {
+	// Conditions represents the latest state of the object
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []VpcNatCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
 type VpcNatToleration struct {
@@ -889,6 +1075,8 @@ type VpcDns struct {
 type VpcDnsSpec struct {
 	Vpc    string `json:"vpc"`
 	Subnet string `json:"subnet"`
+	// IPs are the static addresses in the subnet for the vpc dns pods, one for each replica
+	IPs []string `json:"ips,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -1183,3 +1371,43 @@ type OvnSnatRuleList struct {
 
 	Items []OvnSnatRule `json:"items"`
 }
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient:nonNamespaced
+
+type EgressIPPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EgressIPPoolSpec   `json:"spec"`
+	Status EgressIPPoolStatus `json:"status,omitempty"`
+}
+
+type EgressIPPoolSpec struct {
+	// IPs are the external addresses the egress traffic of matching pods is SNATed to
+	IPs []string `json:"ips"`
+	// NamespaceSelector selects the namespaces of the pods, all namespaces if not set
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// PodSelector selects the pods in the matching namespaces, all pods if not set
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// MaxPodsPerIP limits the number of pods sharing one address, 0 means no limit
+	MaxPodsPerIP int `json:"maxPodsPerIP,omitempty"`
+}
+
+type EgressIPPoolStatus struct {
+	// +optional
+	// +patchStrategy=merge
+	AssignedPods int  `json:"assignedPods" patchStrategy:"merge"`
+	PendingPods  int  `json:"pendingPods" patchStrategy:"merge"`
+	Exhausted    bool `json:"exhausted" patchStrategy:"merge"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type EgressIPPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []EgressIPPool `json:"items"`
+}
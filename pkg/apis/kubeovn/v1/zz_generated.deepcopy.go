@@ -22,6 +22,7 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -41,6 +42,22 @@ func (in *Acl) DeepCopy() *Acl {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BFDConfig) DeepCopyInto(out *BFDConfig) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BFDConfig.
+func (in *BFDConfig) DeepCopy() *BFDConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BFDConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CustomInterface) DeepCopyInto(out *CustomInterface) {
 	*out = *in
@@ -62,6 +79,114 @@ func (in *CustomInterface) DeepCopy() *CustomInterface {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressIPPool) DeepCopyInto(out *EgressIPPool) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressIPPool.
+func (in *EgressIPPool) DeepCopy() *EgressIPPool {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressIPPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EgressIPPool) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressIPPoolList) DeepCopyInto(out *EgressIPPoolList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]EgressIPPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressIPPoolList.
+func (in *EgressIPPoolList) DeepCopy() *EgressIPPoolList {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressIPPoolList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EgressIPPoolList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressIPPoolSpec) DeepCopyInto(out *EgressIPPoolSpec) {
+	*out = *in
+	if in.IPs != nil {
+		in, out := &in.IPs, &out.IPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressIPPoolSpec.
+func (in *EgressIPPoolSpec) DeepCopy() *EgressIPPoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressIPPoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressIPPoolStatus) DeepCopyInto(out *EgressIPPoolStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressIPPoolStatus.
+func (in *EgressIPPoolStatus) DeepCopy() *EgressIPPoolStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressIPPoolStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HtbQos) DeepCopyInto(out *HtbQos) {
 	*out = *in
@@ -1165,6 +1290,11 @@ func (in *ProviderNetworkSpec) DeepCopyInto(out *ProviderNetworkSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.VlanRanges != nil {
+		in, out := &in.VlanRanges, &out.VlanRanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -1361,6 +1491,11 @@ func (in *SlrPort) DeepCopy() *SlrPort {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StaticRoute) DeepCopyInto(out *StaticRoute) {
 	*out = *in
+	if in.BFD != nil {
+		in, out := &in.BFD, &out.BFD
+		*out = new(BFDConfig)
+		**out = **in
+	}
 	return
 }
 
@@ -1374,6 +1509,22 @@ func (in *StaticRoute) DeepCopy() *StaticRoute {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticRouteStatus) DeepCopyInto(out *StaticRouteStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticRouteStatus.
+func (in *StaticRouteStatus) DeepCopy() *StaticRouteStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticRouteStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Subnet) DeepCopyInto(out *Subnet) {
 	*out = *in
@@ -1466,6 +1617,11 @@ func (in *SubnetSpec) DeepCopyInto(out *SubnetSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExtraCIDRBlocks != nil {
+		in, out := &in.ExtraCIDRBlocks, &out.ExtraCIDRBlocks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.AllowSubnets != nil {
 		in, out := &in.AllowSubnets, &out.AllowSubnets
 		*out = make([]string, len(*in))
@@ -1476,6 +1632,13 @@ func (in *SubnetSpec) DeepCopyInto(out *SubnetSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.LogicalSwitchOptions != nil {
+		in, out := &in.LogicalSwitchOptions, &out.LogicalSwitchOptions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Acls != nil {
 		in, out := &in.Acls, &out.Acls
 		*out = make([]Acl, len(*in))
@@ -1504,6 +1667,7 @@ func (in *SubnetStatus) DeepCopyInto(out *SubnetStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	in.LastGatewayReachableTime.DeepCopyInto(&out.LastGatewayReachableTime)
 	return
 }
 
@@ -1942,7 +2106,7 @@ func (in *VpcDns) DeepCopyInto(out *VpcDns) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 	return
 }
@@ -2019,6 +2183,11 @@ func (in *VpcDnsList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VpcDnsSpec) DeepCopyInto(out *VpcDnsSpec) {
 	*out = *in
+	if in.IPs != nil {
+		in, out := &in.IPs, &out.IPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -2088,12 +2257,31 @@ func (in *VpcList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VpcNatCondition) DeepCopyInto(out *VpcNatCondition) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VpcNatCondition.
+func (in *VpcNatCondition) DeepCopy() *VpcNatCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(VpcNatCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VpcNatGateway) DeepCopyInto(out *VpcNatGateway) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -2161,6 +2349,11 @@ func (in *VpcNatSpec) DeepCopyInto(out *VpcNatSpec) {
 		*out = make([]VpcNatToleration, len(*in))
 		copy(*out, *in)
 	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -2174,6 +2367,29 @@ func (in *VpcNatSpec) DeepCopy() *VpcNatSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VpcNatStatus) DeepCopyInto(out *VpcNatStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]VpcNatCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VpcNatStatus.
+func (in *VpcNatStatus) DeepCopy() *VpcNatStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VpcNatStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VpcNatToleration) DeepCopyInto(out *VpcNatToleration) {
 	*out = *in
@@ -2221,7 +2437,7 @@ func (in *VpcSpec) DeepCopyInto(out *VpcSpec) {
 			if (*in)[i] != nil {
 				in, out := &(*in)[i], &(*out)[i]
 				*out = new(StaticRoute)
-				**out = **in
+				(*in).DeepCopyInto(*out)
 			}
 		}
 	}
@@ -2280,6 +2496,17 @@ func (in *VpcStatus) DeepCopyInto(out *VpcStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.StaticRoutes != nil {
+		in, out := &in.StaticRoutes, &out.StaticRoutes
+		*out = make([]*StaticRouteStatus, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(StaticRouteStatus)
+				**out = **in
+			}
+		}
+	}
 	return
 }
 
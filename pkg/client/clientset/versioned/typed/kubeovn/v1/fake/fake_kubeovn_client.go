@@ -28,6 +28,10 @@ type FakeKubeovnV1 struct {
 	*testing.Fake
 }
 
+func (c *FakeKubeovnV1) EgressIPPools() v1.EgressIPPoolInterface {
+	return &FakeEgressIPPools{c}
+}
+
 func (c *FakeKubeovnV1) HtbQoses() v1.HtbQosInterface {
 	return &FakeHtbQoses{c}
 }
@@ -18,6 +18,8 @@ limitations under the License.
 
 package v1
 
+type EgressIPPoolExpansion interface{}
+
 type HtbQosExpansion interface{}
 
 type IPExpansion interface{}
@@ -28,6 +28,7 @@ import (
 
 type KubeovnV1Interface interface {
 	RESTClient() rest.Interface
+	EgressIPPoolsGetter
 	HtbQosesGetter
 	IPsGetter
 	IptablesDnatRulesGetter
@@ -53,6 +54,10 @@ type KubeovnV1Client struct {
 	restClient rest.Interface
 }
 
+func (c *KubeovnV1Client) EgressIPPools() EgressIPPoolInterface {
+	return newEgressIPPools(c)
+}
+
 func (c *KubeovnV1Client) HtbQoses() HtbQosInterface {
 	return newHtbQoses(c)
 }
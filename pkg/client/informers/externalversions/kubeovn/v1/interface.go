@@ -24,6 +24,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// EgressIPPools returns a EgressIPPoolInformer.
+	EgressIPPools() EgressIPPoolInformer
 	// HtbQoses returns a HtbQosInformer.
 	HtbQoses() HtbQosInformer
 	// IPs returns a IPInformer.
@@ -73,6 +75,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// EgressIPPools returns a EgressIPPoolInformer.
+func (v *version) EgressIPPools() EgressIPPoolInformer {
+	return &egressIPPoolInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // HtbQoses returns a HtbQosInformer.
 func (v *version) HtbQoses() HtbQosInformer {
 	return &htbQosInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
@@ -18,6 +18,10 @@ limitations under the License.
 
 package v1
 
+// EgressIPPoolListerExpansion allows custom methods to be added to
+// EgressIPPoolLister.
+type EgressIPPoolListerExpansion interface{}
+
 // HtbQosListerExpansion allows custom methods to be added to
 // HtbQosLister.
 type HtbQosListerExpansion interface{}
@@ -0,0 +1,293 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emicklei/go-restful/v3"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	ipUsageTypePod      = "pod"
+	ipUsageTypeNode     = "node"
+	ipUsageTypeReserved = "reserved"
+
+	defaultApiPageLimit = 500
+	maxApiPageLimit     = 5000
+)
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type ipUsage struct {
+	IP    string `json:"ip"`
+	Type  string `json:"type"`
+	Owner string `json:"owner,omitempty"`
+	Nic   string `json:"nic,omitempty"`
+}
+
+type aclSource struct {
+	UUID      string `json:"uuid"`
+	Direction string `json:"direction"`
+	Priority  string `json:"priority"`
+	Action    string `json:"action"`
+	Match     string `json:"match"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+type subnetIPUsage struct {
+	Subnet string    `json:"subnet"`
+	Total  int       `json:"total"`
+	Offset int       `json:"offset"`
+	Next   int       `json:"next,omitempty"`
+	Items  []ipUsage `json:"items"`
+}
+
+// ApiHandler returns the handler of the internal read-only api served by the leader
+func (c *Controller) ApiHandler() http.Handler {
+	wsContainer := restful.NewContainer()
+	wsContainer.EnableContentEncoding(true)
+
+	ws := new(restful.WebService)
+	ws.Path("/api/v1").
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+	wsContainer.Add(ws)
+
+	ws.Route(
+		ws.GET("/subnets/{subnet}/ips").
+			To(c.handleListSubnetIPs).
+			Filter(c.apiAuthFilter(subnetApiAccess)).
+			Param(ws.PathParameter("subnet", "name of the subnet")).
+			Param(ws.QueryParameter("limit", "maximum number of addresses to return")).
+			Param(ws.QueryParameter("offset", "index of the first address to return")).
+			Writes(subnetIPUsage{}))
+	ws.Route(
+		ws.GET("/acls").
+			To(c.handleListAclSources).
+			Filter(c.apiAuthFilter(aclApiAccess)).
+			Param(ws.QueryParameter("kind", "kind of the source, NetworkPolicy or SecurityGroup")).
+			Param(ws.QueryParameter("namespace", "namespace of the source network policy")).
+			Param(ws.QueryParameter("name", "name of the source")).
+			Writes([]aclSource{}))
+
+	ws.Filter(c.apiLeaderFilter)
+
+	return wsContainer
+}
+
+func writeApiError(resp *restful.Response, status int, err error) {
+	if err := resp.WriteHeaderAndEntity(status, apiError{Error: err.Error()}); err != nil {
+		klog.Errorf("failed to write response, %v", err)
+	}
+}
+
+func (c *Controller) apiLeaderFilter(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	if !c.isLeader() {
+		writeApiError(resp, http.StatusServiceUnavailable, fmt.Errorf("%s is not the leader", c.config.PodName))
+		return
+	}
+	chain.ProcessFilter(req, resp)
+}
+
+// apiAccess returns the resources the user must be allowed to access for the request
+type apiAccess func(req *restful.Request) []authorizationv1.ResourceAttributes
+
+func subnetApiAccess(req *restful.Request) []authorizationv1.ResourceAttributes {
+	return []authorizationv1.ResourceAttributes{{
+		Verb:     "get",
+		Group:    "kubeovn.io",
+		Resource: "subnets",
+		Name:     req.PathParameter("subnet"),
+	}}
+}
+
+func aclApiAccess(_ *restful.Request) []authorizationv1.ResourceAttributes {
+	return []authorizationv1.ResourceAttributes{{
+		Verb:     "list",
+		Group:    "networking.k8s.io",
+		Resource: "networkpolicies",
+	}, {
+		Verb:     "list",
+		Group:    "kubeovn.io",
+		Resource: "security-groups",
+	}}
+}
+
+// apiAuthFilter authenticates the bearer token of the request and checks
+// whether the user is allowed to access the resources the request refers to
+func (c *Controller) apiAuthFilter(access apiAccess) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		c.apiAuthorize(access(req), req, resp, chain)
+	}
+}
+
+func (c *Controller) apiAuthorize(attributes []authorizationv1.ResourceAttributes, req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	token := strings.TrimSpace(strings.TrimPrefix(req.HeaderParameter("Authorization"), "Bearer "))
+	if token == "" {
+		writeApiError(resp, http.StatusUnauthorized, fmt.Errorf("missing bearer token"))
+		return
+	}
+
+	tr := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	tr, err := c.config.KubeClient.AuthenticationV1().TokenReviews().Create(context.Background(), tr, metav1.CreateOptions{})
+	if err != nil {
+		klog.Errorf("failed to review token: %v", err)
+		writeApiError(resp, http.StatusInternalServerError, err)
+		return
+	}
+	if !tr.Status.Authenticated {
+		writeApiError(resp, http.StatusUnauthorized, fmt.Errorf("invalid bearer token"))
+		return
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(tr.Status.User.Extra))
+	for k, v := range tr.Status.User.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	for i := range attributes {
+		sar := &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes: &attributes[i],
+				User:               tr.Status.User.Username,
+				Groups:             tr.Status.User.Groups,
+				UID:                tr.Status.User.UID,
+				Extra:              extra,
+			},
+		}
+		sar, err = c.config.KubeClient.AuthorizationV1().SubjectAccessReviews().Create(context.Background(), sar, metav1.CreateOptions{})
+		if err != nil {
+			klog.Errorf("failed to review access of user %s: %v", tr.Status.User.Username, err)
+			writeApiError(resp, http.StatusInternalServerError, err)
+			return
+		}
+		if !sar.Status.Allowed {
+			a := attributes[i]
+			target := a.Resource
+			if a.Name != "" {
+				target = fmt.Sprintf("%s %s", a.Resource, a.Name)
+			}
+			writeApiError(resp, http.StatusForbidden, fmt.Errorf("user %s is not allowed to %s %s", tr.Status.User.Username, a.Verb, target))
+			return
+		}
+	}
+	chain.ProcessFilter(req, resp)
+}
+
+func parseApiPage(req *restful.Request) (int, int, error) {
+	limit, offset := defaultApiPageLimit, 0
+	var err error
+	if v := req.QueryParameter("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			return 0, 0, fmt.Errorf("invalid limit %q", v)
+		}
+		if limit > maxApiPageLimit {
+			limit = maxApiPageLimit
+		}
+	}
+	if v := req.QueryParameter("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset %q", v)
+		}
+	}
+	return limit, offset, nil
+}
+
+func (c *Controller) handleListSubnetIPs(req *restful.Request, resp *restful.Response) {
+	subnetName := req.PathParameter("subnet")
+	limit, offset, err := parseApiPage(req)
+	if err != nil {
+		writeApiError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, err = c.subnetsLister.Get(subnetName); err != nil {
+		if k8serrors.IsNotFound(err) {
+			writeApiError(resp, http.StatusNotFound, err)
+			return
+		}
+		klog.Errorf("failed to get subnet %s: %v", subnetName, err)
+		writeApiError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	allocations, err := c.ipam.ListSubnetAllocations(subnetName)
+	if err != nil {
+		writeApiError(resp, http.StatusNotFound, fmt.Errorf("subnet %s has not been initialized in ipam", subnetName))
+		return
+	}
+
+	result := subnetIPUsage{Subnet: subnetName, Total: len(allocations), Offset: offset, Items: []ipUsage{}}
+	for i := offset; i < len(allocations) && i < offset+limit; i++ {
+		a := allocations[i]
+		usage := ipUsage{IP: a.IP, Owner: a.Owner, Nic: a.Nic}
+		switch {
+		case subnetName == c.config.NodeSwitch && strings.HasPrefix(a.Owner, "node-"):
+			usage.Type = ipUsageTypeNode
+		case strings.Contains(a.Owner, "/"):
+			usage.Type = ipUsageTypePod
+		default:
+			// excluded addresses, vips and eips
+			usage.Type = ipUsageTypeReserved
+		}
+		result.Items = append(result.Items, usage)
+	}
+	if offset+limit < len(allocations) {
+		result.Next = offset + limit
+	}
+
+	if err = resp.WriteHeaderAndEntity(http.StatusOK, result); err != nil {
+		klog.Errorf("failed to write response, %v", err)
+	}
+}
+
+func (c *Controller) handleListAclSources(req *restful.Request, resp *restful.Response) {
+	kind, namespace, name := req.QueryParameter("kind"), req.QueryParameter("namespace"), req.QueryParameter("name")
+	acls, err := c.ovnLegacyClient.ListAclSources()
+	if err != nil {
+		writeApiError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	result := make([]aclSource, 0, len(acls))
+	for _, acl := range acls {
+		if (kind != "" && acl.Kind != kind) || (namespace != "" && acl.Namespace != namespace) || (name != "" && acl.Name != name) {
+			continue
+		}
+		result = append(result, aclSource{
+			UUID:      acl.UUID,
+			Direction: acl.Direction,
+			Priority:  acl.Priority,
+			Action:    acl.Action,
+			Match:     acl.Match,
+			Kind:      acl.Kind,
+			Namespace: acl.Namespace,
+			Name:      acl.Name,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	if err = resp.WriteHeaderAndEntity(http.StatusOK, result); err != nil {
+		klog.Errorf("failed to write response, %v", err)
+	}
+}
@@ -4,16 +4,21 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	attachnetclientset "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/clientset/versioned"
 	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/klog/v2"
 
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
 	clientset "github.com/kubeovn/kube-ovn/pkg/client/clientset/versioned"
+	ovsclient "github.com/kubeovn/kube-ovn/pkg/ovsdb/client"
 	"github.com/kubeovn/kube-ovn/pkg/util"
 	"kubevirt.io/client-go/kubecli"
 )
@@ -24,6 +29,11 @@ type Configuration struct {
 	OvnNbAddr            string
 	OvnSbAddr            string
 	OvnTimeout           int
+	OvnInactivityProbe   int
+	OvnReconnectTimeout  int
+	OvnSSLKey            string
+	OvnSSLCert           string
+	OvnSSLCACert         string
 	CustCrdRetryMaxDelay int
 	CustCrdRetryMinDelay int
 	KubeConfigFile       string
@@ -44,6 +54,8 @@ type Configuration struct {
 	DefaultExcludeIps     string
 	DefaultGatewayCheck   bool
 	DefaultLogicalGateway bool
+	// DisableNsDefaultSubnet leaves namespaces without explicit subnet binding unbound
+	DisableNsDefaultSubnet bool
 
 	ClusterRouter     string
 	NodeSwitch        string
@@ -84,13 +96,76 @@ type Configuration struct {
 	EnableKeepVmIP    bool
 	EnableLbSvc       bool
 
+	NodeGwNextHops  string
+	EnableNodeGwBfd bool
+	BfdMinTx        int
+	BfdMinRx        int
+	BfdDetectMult   int
+
 	ExternalGatewaySwitch   string
 	ExternalGatewayConfigNS string
 	ExternalGatewayNet      string
 	ExternalGatewayVlanID   int
 
+	NatGwEipArpInterval int
+	// NatGwFailoverGracePeriod is the duration the node of a vpc nat gateway pod must stay ineligible before the pod
+	// is failed over
+	NatGwFailoverGracePeriod time.Duration
+
+	IPReleaseDelay time.Duration
+
+	// ExternalIPAMTimeout is the timeout of the requests to the external ipam webhooks of subnets
+	ExternalIPAMTimeout time.Duration
+
+	AutoCorrectLspAddress bool
+
+	// NamespaceSelector scopes the pods and namespaces managed by the controller, nil for all of them
+	NamespaceSelector labels.Selector
+
+	PodGatewayNodeFailurePolicy string
+
+	// DefaultGatewayCheckMode is the gateway check mode of the pods not overridden by the provider network, subnet or pod
+	DefaultGatewayCheckMode string
+
+	// PodSubnetResolutionOrder is the precedence of the sources resolving the subnet of the pods without
+	// the logical switch annotation
+	PodSubnetResolutionOrder []string
+
+	StaticIPConflictPolicy     string
+	StaticIPReclaimGracePeriod time.Duration
+
 	GCInterval      int
 	InspectInterval int
+	// GCStabilizationDelay defers the gc after startup until the caches are stable, 0 to gc on startup
+	GCStabilizationDelay time.Duration
+	// GCMaxDeletePercent blocks the gc deleting more than the percentage of any kind of objects until it's
+	// confirmed, 100 to disable
+	GCMaxDeletePercent int
+	// OvnObjectMetricsInterval is the interval in seconds to scrape the counts of ovn objects, 0 to disable
+	OvnObjectMetricsInterval int
+	// PodPortStatusInterval is the interval in seconds to sync the up status of the pod ports to the pods, 0 to disable
+	PodPortStatusInterval int
+	// PodPortUpTimeout is the duration after the pod creation to report the port not up as a metric
+	PodPortUpTimeout time.Duration
+	// IPAMDivergenceCheckInterval is the interval in seconds to compare the ipam with the ip CRs, 0 to disable
+	IPAMDivergenceCheckInterval int
+	AutoCorrectIPAMDivergence   bool
+	// NodeInitWaitTimeout is the duration to wait for all the nodes to be initialized before starting the other
+	// workers, the nodes not initialized in time are left to the node workers, 0 to wait indefinitely
+	NodeInitWaitTimeout time.Duration
+	// TerminatingEndpointGrace is the duration to keep the terminating but still ready pods as the backends of the
+	// services after their deletion, 0 to remove them immediately
+	TerminatingEndpointGrace time.Duration
+	// PortRemovalGracePeriod is the duration to keep the lsps of the pods after their containers exit, 0 to remove
+	// them immediately
+	PortRemovalGracePeriod time.Duration
+
+	// MaxPodBandwidth is the max rate in Mbit/s accepted by the ingress and egress rate annotations of pods
+	MaxPodBandwidth int
+
+	LeaderElectLeaseDuration time.Duration
+	LeaderElectRenewDeadline time.Duration
+	LeaderElectRetryPeriod   time.Duration
 }
 
 // ParseFlags parses cmd args then init kubeclient and conf
@@ -100,6 +175,11 @@ func ParseFlags() (*Configuration, error) {
 		argOvnNbAddr            = pflag.String("ovn-nb-addr", "", "ovn-nb address")
 		argOvnSbAddr            = pflag.String("ovn-sb-addr", "", "ovn-sb address")
 		argOvnTimeout           = pflag.Int("ovn-timeout", 60, "")
+		argOvnInactivityProbe   = pflag.Int("ovn-inactivity-probe", 0, "The interval in milliseconds of the inactivity probe of ovn nb and sb connections, at least 1000, 0 to use the default of the clients")
+		argOvnReconnectTimeout  = pflag.Int("ovn-reconnect-timeout", 3, "The timeout in seconds of each connection and reconnection attempt to ovn nb")
+		argOvnSSLKey            = pflag.String("ovn-ssl-key", "/var/run/tls/key", "The private key file of the ssl connections to ovn nb and sb, re-read when it changes")
+		argOvnSSLCert           = pflag.String("ovn-ssl-cert", "/var/run/tls/cert", "The certificate file of the ssl connections to ovn nb and sb, re-read when it changes")
+		argOvnSSLCACert         = pflag.String("ovn-ssl-ca-cert", "/var/run/tls/cacert", "The ca certificate file of the ssl connections to ovn nb and sb")
 		argCustCrdRetryMinDelay = pflag.Int("cust-crd-retry-min-delay", 2, "The min delay seconds between custom crd two retries")
 		argCustCrdRetryMaxDelay = pflag.Int("cust-crd-retry-max-delay", 20, "The max delay seconds between custom crd two retries")
 		argKubeConfigFile       = pflag.String("kubeconfig", "", "Path to kubeconfig file with authorization and master location information. If not set use the inCluster token.")
@@ -111,6 +191,8 @@ func ParseFlags() (*Configuration, error) {
 		argDefaultLogicalGateway = pflag.Bool("default-logical-gateway", false, "Create a logical gateway for the default subnet instead of using underlay gateway. Take effect only when the default subnet is in underlay mode. (default false)")
 		argDefaultExcludeIps     = pflag.String("default-exclude-ips", "", "Exclude ips in default switch (default gateway address)")
 
+		argDisableNsDefaultSubnet = pflag.Bool("disable-namespace-default-subnet", false, "Do not bind namespaces without subnet or vpc binding to the default subnet, system namespaces are always bound")
+
 		argClusterRouter     = pflag.String("cluster-router", util.DefaultVpc, "The router name for cluster router")
 		argNodeSwitch        = pflag.String("node-switch", "join", "The name of node gateway switch which help node to access pod network")
 		argNodeSwitchCIDR    = pflag.String("node-switch-cidr", "100.64.0.0/16", "The cidr for node switch")
@@ -145,13 +227,63 @@ func ParseFlags() (*Configuration, error) {
 		argKeepVmIP                = pflag.Bool("keep-vm-ip", false, "Whether to keep ip for kubevirt pod when pod is rebuild")
 		argEnableLbSvc             = pflag.Bool("enable-lb-svc", false, "Whether to support loadbalancer service")
 
+		argNodeGwNextHops  = pflag.String("node-gw-nexthops", "", "Comma-separated next hops of the default route for node gateway, next hops of the same protocol form an ecmp group (default the node switch gateway)")
+		argEnableNodeGwBfd = pflag.Bool("enable-node-gw-bfd", false, "Enable bfd for the next hops of the default route for node gateway")
+		argBfdMinTx        = pflag.Int("bfd-min-tx", 1000, "The default minimum interval in milliseconds of sending bfd packets")
+		argBfdMinRx        = pflag.Int("bfd-min-rx", 1000, "The default minimum interval in milliseconds of receiving bfd packets")
+		argBfdDetectMult   = pflag.Int("bfd-detect-mult", 3, "The default number of lost bfd packets before a next hop is considered down")
+
 		argExternalGatewayConfigNS = pflag.String("external-gateway-config-ns", "kube-system", "The namespace of configmap external-gateway-config, default: kube-system")
 		argExternalGatewaySwitch   = pflag.String("external-gateway-switch", "external", "The name of the external gateway switch which is a ovs bridge to provide external network, default: external")
 		argExternalGatewayNet      = pflag.String("external-gateway-net", "external", "The name of the external network which mappings with an ovs bridge, default: external")
 		argExternalGatewayVlanID   = pflag.Int("external-gateway-vlanid", 0, "The vlanId of port ln-ovn-external, default: 0")
 
+		argNamespaceSelector = pflag.String("namespace-selector", "", "The label selector of namespaces whose pods are managed by kube-ovn, pods in other namespaces are ignored, cluster scoped resources are not affected (default all namespaces)")
+
+		argPodGatewayNodeFailurePolicy = pflag.String("pod-gateway-node-failure-policy", podGatewayNodeFailurePolicyDrop, "The policy of the egress traffic of the pods pinned to a failed gateway node by annotation "+util.GatewayNodeAnnotation+", drop or fallback to the subnet gateway")
+
+		argDefaultGatewayCheckMode = pflag.String("default-gateway-check-mode", kubeovnv1.GatewayCheckModeAuto, "The gateway check mode of the pods, overridden by the provider network, subnet and pod annotation "+util.GatewayCheckModeAnnotation+", auto, ping, arping or disabled")
+
+		argPodSubnetResolutionOrder = pflag.String("pod-subnet-resolution-order", "namespace,subnet,default", "The comma separated precedence resolving the subnet of the pods without annotation "+util.LogicalSwitchAnnotation+", namespace for the annotation of the namespace, subnet for the subnets with the namespace in the namespaces field, default for the default subnet of the vpc or cluster")
+
+		argStaticIPConflictPolicy     = pflag.String("static-ip-conflict-policy", staticIPConflictPolicyFail, "The policy when the static ip requested by a pod is used by another pod, fail the pod or reclaim the ip after the holder is confirmed deleted")
+		argStaticIPReclaimGracePeriod = pflag.Duration("static-ip-reclaim-grace-period", 30*time.Second, "The duration to wait after the holder of a conflicting static ip is confirmed deleted before reclaiming the ip, only used by the reclaim policy")
+
+		argAutoCorrectLspAddress = pflag.Bool("auto-correct-lsp-address", false, "Reset the addresses of logical switch ports drifted from the ip records to the allocated ones during gc")
+
+		argIPReleaseDelay = pflag.Duration("ip-release-delay", 0, "The duration a released pod ip is kept from reallocation to avoid connection resets by stale conntrack entries of the peers, the delay is bypassed when the subnet is exhausted, 0 to disable")
+
+		argExternalIPAMTimeout = pflag.Duration("external-ipam-timeout", 10*time.Second, "The timeout of the reserve and release requests to the external ipam webhooks of subnets, the pods stay pending and the requests are retried on timeout")
+
+		argNatGwEipArpInterval = pflag.Int("nat-gw-eip-arp-interval", 60, "The interval in seconds between gratuitous arp announcements of the vpc nat gateway eips on the external network, 0 to disable")
+
+		argNatGwFailoverGracePeriod = pflag.Duration("nat-gw-failover-grace-period", time.Minute, "The duration the node of a vpc nat gateway pod must stay not ready or without the external provider network before the pod is deleted to fail over")
+
 		argGCInterval      = pflag.Int("gc-interval", 360, "The interval between GC processes, default 360 seconds")
 		argInspectInterval = pflag.Int("inspect-interval", 20, "The interval between inspect processes, default 20 seconds")
+
+		argGCStabilizationDelay = pflag.Duration("gc-stabilization-delay", 0, "The duration to wait after startup before the first gc, 0 to gc on startup")
+		argGCMaxDeletePercent   = pflag.Int("gc-max-delete-percent", 100, "Refuse to gc more than the percentage of any kind of objects until confirmed by annotation "+util.GCConfirmAnnotation+" on the leader controller pod, 100 to disable")
+
+		argMaxPodBandwidth = pflag.Int("max-pod-bandwidth", util.DefaultMaxPodBandwidth, "The max rate in Mbit/s accepted by the ingress and egress rate annotations of pods, the pods exceeding it are rejected, 0 to disable")
+
+		argPodPortStatusInterval = pflag.Int("pod-port-status-interval", 0, "The interval in seconds to sync the up status of the logical switch ports in the ovn southbound database to the pod condition "+util.PortUpConditionType+", 0 to disable")
+		argPodPortUpTimeout      = pflag.Duration("pod-port-up-timeout", time.Minute, "The duration after the pod creation to report the logical switch port of the pod not up by metric kube_ovn_pod_port_not_up")
+
+		argOvnObjectMetricsInterval = pflag.Int("ovn-object-metrics-interval", 60, "The interval in seconds to scrape the counts of ovn logical routers, switches and switch ports as metrics, 0 to disable")
+
+		argIPAMDivergenceCheckInterval = pflag.Int("ipam-divergence-check-interval", 300, "The interval in seconds to compare the in-memory ipam with the ip CRs and export the mismatches by metric kube_ovn_ipam_crd_divergence, 0 to disable")
+		argAutoCorrectIPAMDivergence   = pflag.Bool("auto-correct-ipam-divergence", false, "Restore the addresses of ip CRs missing in the ipam and release the ipam addresses of deleted pods without ip CRs")
+
+		argTerminatingEndpointGrace = pflag.Duration("terminating-endpoint-grace-period", 0, "The duration to keep the terminating but still ready pods as the backends of the services after their deletion for graceful shutdown, 0 to remove them immediately")
+
+		argPortRemovalGracePeriod = pflag.Duration("port-removal-grace-period", 0, "The duration to keep the logical switch ports and addresses of the pods after their containers exit so that the in-flight connections can be drained, 0 to remove them immediately")
+
+		argNodeInitWaitTimeout = pflag.Duration("node-init-wait-timeout", 0, "The duration to wait for all the nodes to be initialized on startup before starting the other workers, the nodes not initialized in time are initialized asynchronously, 0 to wait indefinitely")
+
+		argLeaderElectLeaseDuration = pflag.Duration("leader-elect-lease-duration", 15*time.Second, "The duration that non-leader candidates will wait after observing a leadership renewal until attempting to acquire leadership")
+		argLeaderElectRenewDeadline = pflag.Duration("leader-elect-renew-deadline", 10*time.Second, "The interval between attempts by the acting leader to renew leadership before it stops leading, must be less than the lease duration")
+		argLeaderElectRetryPeriod   = pflag.Duration("leader-elect-retry-period", 2*time.Second, "The duration the clients should wait between attempting acquisition and renewal of leadership")
 	)
 
 	klogFlags := flag.NewFlagSet("klog", flag.ExitOnError)
@@ -176,6 +308,11 @@ func ParseFlags() (*Configuration, error) {
 		OvnNbAddr:                     *argOvnNbAddr,
 		OvnSbAddr:                     *argOvnSbAddr,
 		OvnTimeout:                    *argOvnTimeout,
+		OvnInactivityProbe:            *argOvnInactivityProbe,
+		OvnReconnectTimeout:           *argOvnReconnectTimeout,
+		OvnSSLKey:                     *argOvnSSLKey,
+		OvnSSLCert:                    *argOvnSSLCert,
+		OvnSSLCACert:                  *argOvnSSLCACert,
 		CustCrdRetryMinDelay:          *argCustCrdRetryMinDelay,
 		CustCrdRetryMaxDelay:          *argCustCrdRetryMaxDelay,
 		KubeConfigFile:                *argKubeConfigFile,
@@ -185,6 +322,7 @@ func ParseFlags() (*Configuration, error) {
 		DefaultGatewayCheck:           *argDefaultGatewayCheck,
 		DefaultLogicalGateway:         *argDefaultLogicalGateway,
 		DefaultExcludeIps:             *argDefaultExcludeIps,
+		DisableNsDefaultSubnet:        *argDisableNsDefaultSubnet,
 		ClusterRouter:                 *argClusterRouter,
 		NodeSwitch:                    *argNodeSwitch,
 		NodeSwitchCIDR:                *argNodeSwitchCIDR,
@@ -216,12 +354,37 @@ func ParseFlags() (*Configuration, error) {
 		ExternalGatewaySwitch:         *argExternalGatewaySwitch,
 		ExternalGatewayNet:            *argExternalGatewayNet,
 		ExternalGatewayVlanID:         *argExternalGatewayVlanID,
+		NatGwEipArpInterval:           *argNatGwEipArpInterval,
+		NatGwFailoverGracePeriod:      *argNatGwFailoverGracePeriod,
+		IPReleaseDelay:                *argIPReleaseDelay,
+		ExternalIPAMTimeout:           *argExternalIPAMTimeout,
+		StaticIPReclaimGracePeriod:    *argStaticIPReclaimGracePeriod,
+		AutoCorrectLspAddress:         *argAutoCorrectLspAddress,
 		EnableEcmp:                    *argEnableEcmp,
 		EnableKeepVmIP:                *argKeepVmIP,
 		NodePgProbeTime:               *argNodePgProbeTime,
 		GCInterval:                    *argGCInterval,
 		InspectInterval:               *argInspectInterval,
+		OvnObjectMetricsInterval:      *argOvnObjectMetricsInterval,
+		PodPortStatusInterval:         *argPodPortStatusInterval,
+		IPAMDivergenceCheckInterval:   *argIPAMDivergenceCheckInterval,
+		AutoCorrectIPAMDivergence:     *argAutoCorrectIPAMDivergence,
+		NodeInitWaitTimeout:           *argNodeInitWaitTimeout,
+		TerminatingEndpointGrace:      *argTerminatingEndpointGrace,
+		PortRemovalGracePeriod:        *argPortRemovalGracePeriod,
+		PodPortUpTimeout:              *argPodPortUpTimeout,
+		MaxPodBandwidth:               *argMaxPodBandwidth,
+		GCStabilizationDelay:          *argGCStabilizationDelay,
+		GCMaxDeletePercent:            *argGCMaxDeletePercent,
+		LeaderElectLeaseDuration:      *argLeaderElectLeaseDuration,
+		LeaderElectRenewDeadline:      *argLeaderElectRenewDeadline,
+		LeaderElectRetryPeriod:        *argLeaderElectRetryPeriod,
 		EnableLbSvc:                   *argEnableLbSvc,
+		NodeGwNextHops:                *argNodeGwNextHops,
+		EnableNodeGwBfd:               *argEnableNodeGwBfd,
+		BfdMinTx:                      *argBfdMinTx,
+		BfdMinRx:                      *argBfdMinRx,
+		BfdDetectMult:                 *argBfdDetectMult,
 	}
 
 	if config.NetworkType == util.NetworkTypeVlan && config.DefaultHostInterface == "" {
@@ -248,6 +411,123 @@ func ParseFlags() (*Configuration, error) {
 		config.NodeSwitchGateway = gw
 	}
 
+	if config.NodeGwNextHops == "" {
+		config.NodeGwNextHops = config.NodeSwitchGateway
+	}
+	for _, nextHop := range strings.Split(config.NodeGwNextHops, ",") {
+		if !util.CIDRContainIP(config.NodeSwitchCIDR, nextHop) {
+			return nil, fmt.Errorf("node gateway next hop %s is not in node switch cidr %s", nextHop, config.NodeSwitchCIDR)
+		}
+	}
+
+	if config.OvnInactivityProbe != 0 && config.OvnInactivityProbe < 1000 {
+		return nil, fmt.Errorf("ovn-inactivity-probe must be 0 or at least 1000 milliseconds")
+	}
+	if config.OvnReconnectTimeout <= 0 {
+		return nil, fmt.Errorf("ovn-reconnect-timeout must be positive")
+	}
+	if os.Getenv("ENABLE_SSL") == "true" {
+		if err := config.OvnSSLFiles().Validate(); err != nil {
+			return nil, fmt.Errorf("invalid ovn ssl files: %v", err)
+		}
+	}
+
+	if config.NatGwEipArpInterval < 0 {
+		return nil, fmt.Errorf("nat-gw-eip-arp-interval must not be negative")
+	}
+	if config.NatGwFailoverGracePeriod < 0 {
+		return nil, fmt.Errorf("nat-gw-failover-grace-period must not be negative")
+	}
+	if config.OvnObjectMetricsInterval < 0 {
+		return nil, fmt.Errorf("ovn-object-metrics-interval must not be negative")
+	}
+	if config.PodPortStatusInterval < 0 {
+		return nil, fmt.Errorf("pod-port-status-interval must not be negative")
+	}
+	if config.IPAMDivergenceCheckInterval < 0 {
+		return nil, fmt.Errorf("ipam-divergence-check-interval must not be negative")
+	}
+	if config.NodeInitWaitTimeout < 0 {
+		return nil, fmt.Errorf("node-init-wait-timeout must not be negative")
+	}
+	if config.TerminatingEndpointGrace < 0 {
+		return nil, fmt.Errorf("terminating-endpoint-grace-period must not be negative")
+	}
+	if config.PortRemovalGracePeriod < 0 {
+		return nil, fmt.Errorf("port-removal-grace-period must not be negative")
+	}
+	if config.PodPortUpTimeout <= 0 {
+		return nil, fmt.Errorf("pod-port-up-timeout must be positive")
+	}
+	if config.GCStabilizationDelay < 0 {
+		return nil, fmt.Errorf("gc-stabilization-delay must not be negative")
+	}
+	if config.GCMaxDeletePercent < 0 || config.GCMaxDeletePercent > 100 {
+		return nil, fmt.Errorf("gc-max-delete-percent must be between 0 and 100")
+	}
+	if config.MaxPodBandwidth < 0 {
+		return nil, fmt.Errorf("max-pod-bandwidth must not be negative")
+	}
+
+	if config.IPReleaseDelay < 0 {
+		return nil, fmt.Errorf("ip-release-delay must not be negative")
+	}
+	if config.ExternalIPAMTimeout <= 0 {
+		return nil, fmt.Errorf("external-ipam-timeout must be positive")
+	}
+
+	config.PodGatewayNodeFailurePolicy = *argPodGatewayNodeFailurePolicy
+	if config.PodGatewayNodeFailurePolicy != podGatewayNodeFailurePolicyDrop && config.PodGatewayNodeFailurePolicy != podGatewayNodeFailurePolicyFallback {
+		return nil, fmt.Errorf("pod-gateway-node-failure-policy must be %s or %s", podGatewayNodeFailurePolicyDrop, podGatewayNodeFailurePolicyFallback)
+	}
+
+	config.DefaultGatewayCheckMode = *argDefaultGatewayCheckMode
+	if config.DefaultGatewayCheckMode == kubeovnv1.GatewayCheckModeTCP {
+		return nil, fmt.Errorf("default-gateway-check-mode tcp is not supported, set gatewayCheckPort of the subnets instead")
+	}
+	if err := util.ValidateGatewayCheckMode(config.DefaultGatewayCheckMode, 0); err != nil {
+		return nil, fmt.Errorf("invalid default-gateway-check-mode: %v", err)
+	}
+
+	order, err := parsePodSubnetResolutionOrder(*argPodSubnetResolutionOrder)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod-subnet-resolution-order: %v", err)
+	}
+	config.PodSubnetResolutionOrder = order
+
+	config.StaticIPConflictPolicy = *argStaticIPConflictPolicy
+	if config.StaticIPConflictPolicy != staticIPConflictPolicyFail && config.StaticIPConflictPolicy != staticIPConflictPolicyReclaim {
+		return nil, fmt.Errorf("static-ip-conflict-policy must be %s or %s", staticIPConflictPolicyFail, staticIPConflictPolicyReclaim)
+	}
+	if config.StaticIPReclaimGracePeriod < 0 {
+		return nil, fmt.Errorf("static-ip-reclaim-grace-period must not be negative")
+	}
+
+	if *argNamespaceSelector != "" {
+		selector, err := labels.Parse(*argNamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace-selector %q: %v", *argNamespaceSelector, err)
+		}
+		if !selector.Empty() {
+			config.NamespaceSelector = selector
+		}
+	}
+
+	if config.BfdMinTx <= 0 || config.BfdMinRx <= 0 || config.BfdDetectMult <= 0 {
+		return nil, fmt.Errorf("bfd-min-tx, bfd-min-rx and bfd-detect-mult must be positive")
+	}
+
+	if config.LeaderElectLeaseDuration <= 0 || config.LeaderElectRenewDeadline <= 0 || config.LeaderElectRetryPeriod <= 0 {
+		return nil, fmt.Errorf("leader-elect-lease-duration, leader-elect-renew-deadline and leader-elect-retry-period must be positive")
+	}
+	if config.LeaderElectRenewDeadline >= config.LeaderElectLeaseDuration {
+		return nil, fmt.Errorf("leader-elect-renew-deadline %v must be less than leader-elect-lease-duration %v", config.LeaderElectRenewDeadline, config.LeaderElectLeaseDuration)
+	}
+	// the same constraint as leaderelection.NewLeaderElector, checked here to fail with a clear message
+	if float64(config.LeaderElectRenewDeadline) <= leaderelection.JitterFactor*float64(config.LeaderElectRetryPeriod) {
+		return nil, fmt.Errorf("leader-elect-renew-deadline %v must be greater than %v times leader-elect-retry-period %v", config.LeaderElectRenewDeadline, leaderelection.JitterFactor, config.LeaderElectRetryPeriod)
+	}
+
 	if err := config.initKubeClient(); err != nil {
 		return nil, err
 	}
@@ -350,3 +630,8 @@ func (config *Configuration) initKubeFactoryClient() error {
 	config.KubeFactoryClient = kubeClient
 	return nil
 }
+
+// OvnSSLFiles returns the ssl files of the connections to ovn nb and sb
+func (config *Configuration) OvnSSLFiles() ovsclient.SSLFiles {
+	return ovsclient.SSLFiles{Key: config.OvnSSLKey, Cert: config.OvnSSLCert, CACert: config.OvnSSLCACert}
+}
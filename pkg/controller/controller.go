@@ -1,7 +1,10 @@
 package controller
 
 import (
+	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/neverlee/keymutex"
@@ -9,6 +12,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	kubeinformers "k8s.io/client-go/informers"
@@ -17,7 +22,6 @@ import (
 	v1 "k8s.io/client-go/listers/core/v1"
 	netv1 "k8s.io/client-go/listers/networking/v1"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
@@ -27,11 +31,15 @@ import (
 	kubeovnlister "github.com/kubeovn/kube-ovn/pkg/client/listers/kubeovn/v1"
 	ovnipam "github.com/kubeovn/kube-ovn/pkg/ipam"
 	"github.com/kubeovn/kube-ovn/pkg/ovs"
+	"github.com/kubeovn/kube-ovn/pkg/request"
 	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
 const controllerAgentName = "kube-ovn-controller"
 
+// number of readiness checks between two reports of the resource blocking workers from starting
+const preWorkerReportRounds = 20
+
 // Controller is kube-ovn main controller that watch ns/pod/node/svc/ep and operate ovn
 type Controller struct {
 	config *Configuration
@@ -39,6 +47,31 @@ type Controller struct {
 	//subnetVpcMap *sync.Map
 	podSubnetMap *sync.Map
 	ipam         *ovnipam.IPAM
+	// staticIPConflicts records when the holders of the conflicting static ips are confirmed deleted
+	staticIPConflicts *sync.Map
+	// subnetsPendingReady records the subnets whose logical switches are created but not yet initialized
+	subnetsPendingReady *sync.Map
+	// subnetGatewaysReady records whether the centralized subnets have any ready gateway
+	subnetGatewaysReady *sync.Map
+	// gwReachableFlips counts the consecutive checks contradicting the GatewayReachable condition of the subnets
+	gwReachableFlips *sync.Map
+	// gatewayNodesReady records the latest ping results of the ovn0 ips of the centralized gateway nodes
+	gatewayNodesReady *sync.Map
+	// gatewayTransitions records when the subnets started switching the gateway type
+	gatewayTransitions *sync.Map
+	// gcConfirm is the confirmation of the gc exceeding the delete threshold in the current gc round
+	gcConfirm *gcConfirmation
+	gcMutex   *sync.Mutex
+	// lspRemovalDeadlines records the deadlines of the lsps of the deleted pods kept for the port removal grace period
+	lspRemovalDeadlines *sync.Map
+	// podPortDownCounts records the consecutive rounds the ports of the pods are observed not up
+	podPortDownCounts map[string]int
+	// ipamDivergences records the mismatches between the ipam and the ip CRs found by the last check
+	ipamDivergences map[ipamDivergence]bool
+	// ovnVersion caches the version of the running ovn once it's fetched
+	ovnVersion *atomic.Value
+	// svcLbSelections records the lb selection algorithms of the services checked last time
+	svcLbSelections *sync.Map
 
 	ovnLegacyClient *ovs.LegacyClient
 	ovnClient       *ovs.OvnClient
@@ -50,6 +83,7 @@ type Controller struct {
 	deletePodQueue         workqueue.RateLimitingInterface
 	updatePodQueue         workqueue.RateLimitingInterface
 	updatePodSecurityQueue workqueue.RateLimitingInterface
+	migratePodQueue        workqueue.RateLimitingInterface
 	podKeyMutex            *keymutex.KeyMutex
 
 	vpcsLister           kubeovnlister.VpcLister
@@ -69,6 +103,9 @@ type Controller struct {
 	updateVpcSnatQueue            workqueue.RateLimitingInterface
 	updateVpcSubnetQueue          workqueue.RateLimitingInterface
 	vpcNatGwKeyMutex              *keymutex.KeyMutex
+	vpcNatGwProbeFailures         map[string]int
+	// vpcNatGwIneligibleSince records since when the node of the pod of each vpc nat gateway is ineligible
+	vpcNatGwIneligibleSince *sync.Map
 
 	switchLBRuleLister      kubeovnlister.SwitchLBRuleLister
 	switchLBRuleSynced      cache.InformerSynced
@@ -80,6 +117,7 @@ type Controller struct {
 	vpcDnsSynced           cache.InformerSynced
 	addOrUpdateVpcDnsQueue workqueue.RateLimitingInterface
 	delVpcDnsQueue         workqueue.RateLimitingInterface
+	syncVpcDnsReverseQueue workqueue.RateLimitingInterface
 
 	subnetsLister           kubeovnlister.SubnetLister
 	subnetSynced            cache.InformerSynced
@@ -88,10 +126,13 @@ type Controller struct {
 	deleteRouteQueue        workqueue.RateLimitingInterface
 	updateSubnetStatusQueue workqueue.RateLimitingInterface
 	syncVirtualPortsQueue   workqueue.RateLimitingInterface
+	rebalanceGatewayQueue   workqueue.RateLimitingInterface
 	subnetStatusKeyMutex    *keymutex.KeyMutex
 
-	ipsLister kubeovnlister.IPLister
-	ipSynced  cache.InformerSynced
+	ipsLister              kubeovnlister.IPLister
+	ipSynced               cache.InformerSynced
+	releaseExternalIPQueue workqueue.RateLimitingInterface
+	externalIPAMClient     *request.ExternalIPAMClient
 
 	virtualIpsLister     kubeovnlister.VipLister
 	virtualIpsSynced     cache.InformerSynced
@@ -155,6 +196,11 @@ type Controller struct {
 	updateOvnSnatRuleQueue workqueue.RateLimitingInterface
 	delOvnSnatRuleQueue    workqueue.RateLimitingInterface
 
+	egressIPPoolsLister     kubeovnlister.EgressIPPoolLister
+	egressIPPoolSynced      cache.InformerSynced
+	updateEgressIPPoolQueue workqueue.RateLimitingInterface
+	egressIPAllocator       *egressIPAllocator
+
 	vlansLister kubeovnlister.VlanLister
 	vlanSynced  cache.InformerSynced
 
@@ -166,15 +212,17 @@ type Controller struct {
 	delVlanQueue    workqueue.RateLimitingInterface
 	updateVlanQueue workqueue.RateLimitingInterface
 
-	namespacesLister  v1.NamespaceLister
-	namespacesSynced  cache.InformerSynced
-	addNamespaceQueue workqueue.RateLimitingInterface
+	namespacesLister      v1.NamespaceLister
+	namespacesSynced      cache.InformerSynced
+	addNamespaceQueue     workqueue.RateLimitingInterface
+	syncNamespaceQosQueue workqueue.RateLimitingInterface
 
 	nodesLister     v1.NodeLister
 	nodesSynced     cache.InformerSynced
 	addNodeQueue    workqueue.RateLimitingInterface
 	updateNodeQueue workqueue.RateLimitingInterface
 	deleteNodeQueue workqueue.RateLimitingInterface
+	nodeKeyMutex    *keymutex.KeyMutex
 
 	servicesLister     v1.ServiceLister
 	serviceSynced      cache.InformerSynced
@@ -205,7 +253,8 @@ type Controller struct {
 	informerFactory        kubeinformers.SharedInformerFactory
 	cmInformerFactory      kubeinformers.SharedInformerFactory
 	kubeovnInformerFactory kubeovninformer.SharedInformerFactory
-	elector                *leaderelection.LeaderElector
+	// elector stores the *leaderelection.LeaderElector once the leader election is set up
+	elector *atomic.Value
 }
 
 // NewController returns a new ovn controller
@@ -256,12 +305,24 @@ func NewController(config *Configuration) *Controller {
 	configMapInformer := cmInformerFactory.Core().V1().ConfigMaps()
 
 	controller := &Controller{
-		config:          config,
-		vpcs:            &sync.Map{},
-		podSubnetMap:    &sync.Map{},
-		ovnLegacyClient: ovs.NewLegacyClient(config.OvnNbAddr, config.OvnTimeout, config.OvnSbAddr, config.ClusterRouter, config.ClusterTcpLoadBalancer, config.ClusterUdpLoadBalancer, config.ClusterTcpSessionLoadBalancer, config.ClusterUdpSessionLoadBalancer, config.NodeSwitch, config.NodeSwitchCIDR),
-		ovnPgKeyMutex:   keymutex.New(97),
-		ipam:            ovnipam.NewIPAM(),
+		config:              config,
+		vpcs:                &sync.Map{},
+		podSubnetMap:        &sync.Map{},
+		staticIPConflicts:   &sync.Map{},
+		subnetsPendingReady: &sync.Map{},
+		subnetGatewaysReady: &sync.Map{},
+		gwReachableFlips:    &sync.Map{},
+		gatewayNodesReady:   &sync.Map{},
+		gatewayTransitions:  &sync.Map{},
+		gcMutex:             &sync.Mutex{},
+		lspRemovalDeadlines: &sync.Map{},
+		podPortDownCounts:   make(map[string]int),
+		ovnVersion:          &atomic.Value{},
+		svcLbSelections:     &sync.Map{},
+		elector:             &atomic.Value{},
+		ovnLegacyClient:     ovs.NewLegacyClient(config.OvnNbAddr, config.OvnTimeout, config.OvnInactivityProbe, config.OvnSbAddr, config.ClusterRouter, config.ClusterTcpLoadBalancer, config.ClusterUdpLoadBalancer, config.ClusterTcpSessionLoadBalancer, config.ClusterUdpSessionLoadBalancer, config.NodeSwitch, config.NodeSwitchCIDR, config.OvnSSLFiles()),
+		ovnPgKeyMutex:       keymutex.New(97),
+		ipam:                ovnipam.NewIPAM(),
 
 		vpcsLister:           vpcInformer.Lister(),
 		vpcSynced:            vpcInformer.Informer().HasSynced,
@@ -280,6 +341,8 @@ func NewController(config *Configuration) *Controller {
 		updateVpcSnatQueue:            workqueue.NewNamedRateLimitingQueue(custCrdRateLimiter, "UpdateVpcSnat"),
 		updateVpcSubnetQueue:          workqueue.NewNamedRateLimitingQueue(custCrdRateLimiter, "UpdateVpcSubnet"),
 		vpcNatGwKeyMutex:              keymutex.New(97),
+		vpcNatGwProbeFailures:         make(map[string]int),
+		vpcNatGwIneligibleSince:       &sync.Map{},
 
 		subnetsLister:           subnetInformer.Lister(),
 		subnetSynced:            subnetInformer.Informer().HasSynced,
@@ -288,10 +351,13 @@ func NewController(config *Configuration) *Controller {
 		deleteRouteQueue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "DeleteRoute"),
 		updateSubnetStatusQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "UpdateSubnetStatus"),
 		syncVirtualPortsQueue:   workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "SyncVirtualPort"),
+		rebalanceGatewayQueue:   workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "RebalanceGateway"),
 		subnetStatusKeyMutex:    keymutex.New(97),
 
-		ipsLister: ipInformer.Lister(),
-		ipSynced:  ipInformer.Informer().HasSynced,
+		ipsLister:              ipInformer.Lister(),
+		ipSynced:               ipInformer.Informer().HasSynced,
+		releaseExternalIPQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ReleaseExternalIP"),
+		externalIPAMClient:     request.NewExternalIPAMClient(config.ExternalIPAMTimeout),
 
 		virtualIpsLister:     virtualIpInformer.Lister(),
 		virtualIpsSynced:     virtualIpInformer.Informer().HasSynced,
@@ -352,17 +418,20 @@ func NewController(config *Configuration) *Controller {
 		deletePodQueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "DeletePod"),
 		updatePodQueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "UpdatePod"),
 		updatePodSecurityQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "UpdatePodSecurity"),
+		migratePodQueue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "MigratePod"),
 		podKeyMutex:            keymutex.New(97),
 
-		namespacesLister:  namespaceInformer.Lister(),
-		namespacesSynced:  namespaceInformer.Informer().HasSynced,
-		addNamespaceQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "AddNamespace"),
+		namespacesLister:      namespaceInformer.Lister(),
+		namespacesSynced:      namespaceInformer.Informer().HasSynced,
+		addNamespaceQueue:     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "AddNamespace"),
+		syncNamespaceQosQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "SyncNamespaceQos"),
 
 		nodesLister:     nodeInformer.Lister(),
 		nodesSynced:     nodeInformer.Informer().HasSynced,
 		addNodeQueue:    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "AddNode"),
 		updateNodeQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "UpdateNode"),
 		deleteNodeQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "DeleteNode"),
+		nodeKeyMutex:    keymutex.New(97),
 
 		servicesLister:     serviceInformer.Lister(),
 		serviceSynced:      serviceInformer.Informer().HasSynced,
@@ -391,15 +460,17 @@ func NewController(config *Configuration) *Controller {
 		kubeovnInformerFactory: kubeovnInformerFactory,
 	}
 
+	controller.ipam.ReleaseDelay = config.IPReleaseDelay
+
 	var err error
-	if controller.ovnClient, err = ovs.NewOvnClient(config.OvnNbAddr, config.OvnTimeout); err != nil {
+	if controller.ovnClient, err = ovs.NewOvnClient(config.OvnNbAddr, config.OvnTimeout, config.OvnInactivityProbe, config.OvnReconnectTimeout, config.OvnSSLFiles()); err != nil {
 		klog.Fatal(err)
 	}
 
 	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    controller.enqueueAddPod,
+		AddFunc:    controller.enqueueAddPodInScope,
 		DeleteFunc: controller.enqueueDeletePod,
-		UpdateFunc: controller.enqueueUpdatePod,
+		UpdateFunc: controller.enqueueUpdatePodInScope,
 	})
 
 	namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -456,6 +527,7 @@ func NewController(config *Configuration) *Controller {
 		controller.vpcDnsSynced = vpcDnsInformer.Informer().HasSynced
 		controller.addOrUpdateVpcDnsQueue = workqueue.NewNamedRateLimitingQueue(custCrdRateLimiter, "AddOrUpdateVpcDns")
 		controller.delVpcDnsQueue = workqueue.NewNamedRateLimitingQueue(custCrdRateLimiter, "DeleteVpcDns")
+		controller.syncVpcDnsReverseQueue = workqueue.NewNamedRateLimitingQueue(custCrdRateLimiter, "SyncVpcDnsReverse")
 		vpcDnsInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 			AddFunc:    controller.enqueueAddVpcDns,
 			UpdateFunc: controller.enqueueUpdateVpcDns,
@@ -570,6 +642,17 @@ func NewController(config *Configuration) *Controller {
 			UpdateFunc: controller.enqueueUpdateOvnSnatRule,
 			DeleteFunc: controller.enqueueDelOvnSnatRule,
 		})
+
+		egressIPPoolInformer := kubeovnInformerFactory.Kubeovn().V1().EgressIPPools()
+		controller.egressIPPoolsLister = egressIPPoolInformer.Lister()
+		controller.egressIPPoolSynced = egressIPPoolInformer.Informer().HasSynced
+		controller.updateEgressIPPoolQueue = workqueue.NewNamedRateLimitingQueue(custCrdRateLimiter, "updateEgressIPPool")
+		controller.egressIPAllocator = newEgressIPAllocator()
+		egressIPPoolInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    controller.enqueueAddEgressIPPool,
+			UpdateFunc: controller.enqueueUpdateEgressIPPool,
+			DeleteFunc: controller.enqueueDelEgressIPPool,
+		})
 	}
 
 	podAnnotatedIptablesEipInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -612,7 +695,7 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 	}
 
 	if c.config.EnableEipSnat {
-		cacheSyncs = append(cacheSyncs, c.ovnEipSynced, c.ovnFipSynced, c.ovnSnatRuleSynced)
+		cacheSyncs = append(cacheSyncs, c.ovnEipSynced, c.ovnFipSynced, c.ovnSnatRuleSynced, c.egressIPPoolSynced)
 	}
 
 	if c.config.EnableNP {
@@ -627,6 +710,10 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 		util.LogFatalAndExit(nil, "failed to wait for caches to sync")
 	}
 
+	if err := c.checkNamespaceScope(); err != nil {
+		util.LogFatalAndExit(err, "failed to check namespace scope")
+	}
+
 	if err := c.ovnLegacyClient.SetLsDnatModDlDst(c.config.LsDnatModDlDst); err != nil {
 		util.LogFatalAndExit(err, "failed to set NB_Global option ls_dnat_mod_dl_dst")
 	}
@@ -651,6 +738,12 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 		util.LogFatalAndExit(err, "failed to initialize ipam")
 	}
 
+	if c.config.EnableEipSnat {
+		if err := c.initEgressIPPools(); err != nil {
+			util.LogFatalAndExit(err, "failed to initialize egress ip pools")
+		}
+	}
+
 	if err := c.initNodeChassis(); err != nil {
 		util.LogFatalAndExit(err, "failed to initialize node chassis")
 	}
@@ -664,8 +757,12 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 	}
 
 	// remove resources in ovndb that not exist any more in kubernetes resources
-	if err := c.gc(); err != nil {
-		util.LogFatalAndExit(err, "failed to run gc")
+	if c.config.GCStabilizationDelay == 0 {
+		if err := c.gc(); err != nil {
+			util.LogFatalAndExit(err, "failed to run gc")
+		}
+	} else {
+		klog.Infof("defer gc for the stabilization delay %s", c.config.GCStabilizationDelay)
 	}
 
 	c.registerSubnetMetrics()
@@ -705,14 +802,18 @@ func (c *Controller) shutdown() {
 	c.deletePodQueue.ShutDown()
 	c.updatePodQueue.ShutDown()
 	c.updatePodSecurityQueue.ShutDown()
+	c.migratePodQueue.ShutDown()
 
 	c.addNamespaceQueue.ShutDown()
+	c.syncNamespaceQosQueue.ShutDown()
 
 	c.addOrUpdateSubnetQueue.ShutDown()
 	c.deleteSubnetQueue.ShutDown()
 	c.deleteRouteQueue.ShutDown()
 	c.updateSubnetStatusQueue.ShutDown()
 	c.syncVirtualPortsQueue.ShutDown()
+	c.rebalanceGatewayQueue.ShutDown()
+	c.releaseExternalIPQueue.ShutDown()
 
 	c.addNodeQueue.ShutDown()
 	c.updateNodeQueue.ShutDown()
@@ -749,6 +850,7 @@ func (c *Controller) shutdown() {
 
 		c.addOrUpdateVpcDnsQueue.ShutDown()
 		c.delVpcDnsQueue.ShutDown()
+		c.syncVpcDnsReverseQueue.ShutDown()
 	}
 
 	c.addVirtualIpQueue.ShutDown()
@@ -785,6 +887,8 @@ func (c *Controller) shutdown() {
 		c.addIptablesSnatRuleQueue.ShutDown()
 		c.updateIptablesSnatRuleQueue.ShutDown()
 		c.delIptablesSnatRuleQueue.ShutDown()
+
+		c.updateEgressIPPoolQueue.ShutDown()
 	}
 
 	if c.config.PodDefaultFipType == util.IptablesFip {
@@ -805,6 +909,36 @@ func (c *Controller) shutdown() {
 	c.syncSgPortsQueue.ShutDown()
 }
 
+// reportPreWorkerBlocked exposes the resource blocking workers from starting,
+// logs and events are only emitted once in preWorkerReportRounds rounds
+func (c *Controller) reportPreWorkerBlocked(round int, stage, resource string, obj runtime.Object, since time.Time) {
+	waited := time.Since(since)
+	metricPreWorkerBlockedSeconds.Reset()
+	metricPreWorkerBlockedSeconds.WithLabelValues(stage, resource).Set(waited.Seconds())
+	if round%preWorkerReportRounds != 0 {
+		return
+	}
+
+	msg := fmt.Sprintf("workers have been waiting for %s %s to be ready for %s", strings.ReplaceAll(stage, "_", " "), resource, waited.Round(time.Second))
+	klog.Warning(msg)
+	if obj != nil {
+		c.recorder.Event(obj, corev1.EventTypeWarning, "WaitingForReady", msg)
+	}
+}
+
+// skipPendingNodes stops waiting for the nodes not initialized in time. They are still initialized by the node workers,
+// which retry them until they succeed, and again when they become ready
+func (c *Controller) skipPendingNodes(nodes []string, waited time.Duration) {
+	for _, node := range nodes {
+		msg := fmt.Sprintf("workers are started without waiting for the node to be initialized after %s, it is initialized asynchronously", waited.Round(time.Second))
+		klog.Warningf("node %s: %s", node, msg)
+		// node events are referenced by name, see kubectl describe node
+		ref := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: node, UID: types.UID(node)}}
+		c.recorder.Event(ref, corev1.EventTypeWarning, "NodeInitTimeout", msg)
+		c.addNodeQueue.Add(node)
+	}
+}
+
 func (c *Controller) startWorkers(stopCh <-chan struct{}) {
 	klog.Info("Starting workers")
 
@@ -823,18 +957,37 @@ func (c *Controller) startWorkers(stopCh <-chan struct{}) {
 	go wait.Until(c.runAddSubnetWorker, time.Second, stopCh)
 	go wait.Until(c.runAddVlanWorker, time.Second, stopCh)
 	go wait.Until(c.runAddNamespaceWorker, time.Second, stopCh)
-	for {
-		klog.Infof("wait for %s and %s ready", c.config.DefaultLogicalSwitch, c.config.NodeSwitch)
+	klog.Infof("wait for %s and %s ready", c.config.DefaultLogicalSwitch, c.config.NodeSwitch)
+	waitStart := time.Now()
+	for round := 0; ; round++ {
 		time.Sleep(3 * time.Second)
 		lss, err := c.ovnLegacyClient.ListLogicalSwitch(c.config.EnableExternalVpc)
 		if err != nil {
 			util.LogFatalAndExit(err, "failed to list logical switch")
 		}
 
-		if util.IsStringIn(c.config.DefaultLogicalSwitch, lss) && util.IsStringIn(c.config.NodeSwitch, lss) && c.addNamespaceQueue.Len() == 0 {
-			break
+		var blocking string
+		for _, ls := range []string{c.config.DefaultLogicalSwitch, c.config.NodeSwitch} {
+			if !util.IsStringIn(ls, lss) {
+				blocking = ls
+				break
+			}
+		}
+		if blocking != "" {
+			var obj runtime.Object
+			if subnet, err := c.subnetsLister.Get(blocking); err == nil {
+				obj = subnet
+			}
+			c.reportPreWorkerBlocked(round, "logical_switch", blocking, obj, waitStart)
+			continue
+		}
+		if c.addNamespaceQueue.Len() != 0 {
+			c.reportPreWorkerBlocked(round, "namespace", "add-namespace-queue", nil, waitStart)
+			continue
 		}
+		break
 	}
+	metricPreWorkerBlockedSeconds.Reset()
 
 	go wait.Until(c.runAddSgWorker, time.Second, stopCh)
 	go wait.Until(c.runDelSgWorker, time.Second, stopCh)
@@ -846,24 +999,31 @@ func (c *Controller) startWorkers(stopCh <-chan struct{}) {
 		go wait.Until(c.runUpdateNodeWorker, time.Second, stopCh)
 		go wait.Until(c.runDeleteNodeWorker, time.Second, stopCh)
 	}
-	for {
-		ready := true
+	waitStart = time.Now()
+	for round := 0; ; round++ {
 		time.Sleep(3 * time.Second)
 		nodes, err := c.nodesLister.List(labels.Everything())
 		if err != nil {
 			util.LogFatalAndExit(err, "failed to list nodes")
 		}
+		var pending []string
 		for _, node := range nodes {
 			if node.Annotations[util.AllocatedAnnotation] != "true" {
-				klog.Infof("wait node %s annotation ready", node.Name)
-				ready = false
-				break
+				pending = append(pending, node.Name)
 			}
 		}
-		if ready {
+		if len(pending) == 0 {
 			break
 		}
+		if c.config.NodeInitWaitTimeout > 0 && time.Since(waitStart) >= c.config.NodeInitWaitTimeout {
+			c.skipPendingNodes(pending, time.Since(waitStart))
+			break
+		}
+		// node events are referenced by name, see kubectl describe node
+		ref := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: pending[0], UID: types.UID(pending[0])}}
+		c.reportPreWorkerBlocked(round, "node_annotation", pending[0], ref, waitStart)
 	}
+	metricPreWorkerBlockedSeconds.Reset()
 
 	go wait.Until(c.runDelVpcWorker, time.Second, stopCh)
 	go wait.Until(c.runUpdateVpcStatusWorker, time.Second, stopCh)
@@ -871,8 +1031,6 @@ func (c *Controller) startWorkers(stopCh <-chan struct{}) {
 
 	if c.config.EnableLb {
 		go wait.Until(c.runAddServiceWorker, time.Second, stopCh)
-		// run in a single worker to avoid delete the last vip, which will lead ovn to delete the loadbalancer
-		go wait.Until(c.runDeleteServiceWorker, time.Second, stopCh)
 
 		go wait.Until(c.runAddSwitchLBRuleWorker, time.Second, stopCh)
 		go wait.Until(c.runDelSwitchLBRuleWorker, time.Second, stopCh)
@@ -880,6 +1038,7 @@ func (c *Controller) startWorkers(stopCh <-chan struct{}) {
 
 		go wait.Until(c.runAddOrUpdateVpcDnsWorker, time.Second, stopCh)
 		go wait.Until(c.runDelVpcDnsWorker, time.Second, stopCh)
+		go wait.Until(c.runSyncVpcDnsReverseWorker, time.Second, stopCh)
 		go wait.Until(func() {
 			c.resyncVpcDnsConfig()
 		}, 5*time.Second, stopCh)
@@ -890,14 +1049,20 @@ func (c *Controller) startWorkers(stopCh <-chan struct{}) {
 		go wait.Until(c.runDeletePodWorker, time.Second, stopCh)
 		go wait.Until(c.runUpdatePodWorker, time.Second, stopCh)
 		go wait.Until(c.runUpdatePodSecurityWorker, time.Second, stopCh)
+		go wait.Until(c.runMigratePodWorker, time.Second, stopCh)
 
 		go wait.Until(c.runDeleteSubnetWorker, time.Second, stopCh)
 		go wait.Until(c.runDeleteRouteWorker, time.Second, stopCh)
 		go wait.Until(c.runUpdateSubnetStatusWorker, time.Second, stopCh)
 		go wait.Until(c.runSyncVirtualPortsWorker, time.Second, stopCh)
+		go wait.Until(c.runRebalanceGatewayWorker, time.Second, stopCh)
+		go wait.Until(c.runReleaseExternalIPWorker, time.Second, stopCh)
+		go wait.Until(c.runSyncNamespaceQosWorker, time.Second, stopCh)
 
 		if c.config.EnableLb {
 			go wait.Until(c.runUpdateServiceWorker, time.Second, stopCh)
+			// deleting the last vip keeps the loadbalancer, so deletions can run in parallel
+			go wait.Until(c.runDeleteServiceWorker, time.Second, stopCh)
 			go wait.Until(c.runUpdateEndpointWorker, time.Second, stopCh)
 		}
 
@@ -918,6 +1083,12 @@ func (c *Controller) startWorkers(stopCh <-chan struct{}) {
 		c.SynRouteToPolicy()
 	}, 5*time.Second, stopCh)
 
+	go wait.Until(func() {
+		c.syncPodGatewayNodePolicy()
+	}, 5*time.Second, stopCh)
+
+	go wait.Until(c.checkNodeRoutes, 30*time.Second, stopCh)
+
 	go wait.Until(func() {
 		c.resyncExternalGateway()
 	}, time.Second, stopCh)
@@ -925,12 +1096,28 @@ func (c *Controller) startWorkers(stopCh <-chan struct{}) {
 	go wait.Until(func() {
 		c.resyncVpcNatGwConfig()
 	}, time.Second, stopCh)
+	go wait.Until(c.resyncVpcNatGwHealth, 30*time.Second, stopCh)
+	if c.config.NatGwEipArpInterval > 0 {
+		go wait.Until(c.resyncVpcNatGwEipArp, time.Duration(c.config.NatGwEipArpInterval)*time.Second, stopCh)
+	}
 
-	go wait.Until(func() {
-		if err := c.markAndCleanLSP(); err != nil {
-			klog.Errorf("gc lsp error: %v", err)
+	go func() {
+		if c.config.GCStabilizationDelay != 0 {
+			select {
+			case <-time.After(c.config.GCStabilizationDelay):
+			case <-stopCh:
+				return
+			}
+			if err := c.gc(); err != nil {
+				klog.Errorf("gc error: %v", err)
+			}
 		}
-	}, time.Duration(c.config.GCInterval)*time.Second, stopCh)
+		wait.Until(func() {
+			if err := c.runGC(c.markAndCleanLSP); err != nil {
+				klog.Errorf("gc lsp error: %v", err)
+			}
+		}, time.Duration(c.config.GCInterval)*time.Second, stopCh)
+	}()
 
 	go wait.Until(func() {
 		if err := c.inspectPod(); err != nil {
@@ -946,7 +1133,17 @@ func (c *Controller) startWorkers(stopCh <-chan struct{}) {
 
 	go wait.Until(c.resyncProviderNetworkStatus, 30*time.Second, stopCh)
 	go wait.Until(c.resyncSubnetMetrics, 30*time.Second, stopCh)
+	if c.config.OvnObjectMetricsInterval > 0 {
+		go wait.Until(c.resyncOvnObjectMetrics, time.Duration(c.config.OvnObjectMetricsInterval)*time.Second, stopCh)
+	}
+	if c.config.PodPortStatusInterval > 0 {
+		go wait.Until(c.resyncPodPortStatus, time.Duration(c.config.PodPortStatusInterval)*time.Second, stopCh)
+	}
+	if c.config.IPAMDivergenceCheckInterval > 0 {
+		go wait.Until(c.checkIPAMDivergence, time.Duration(c.config.IPAMDivergenceCheckInterval)*time.Second, stopCh)
+	}
 	go wait.Until(c.CheckGatewayReady, 5*time.Second, stopCh)
+	go wait.Until(c.syncVpcStaticRouteBFD, 5*time.Second, stopCh)
 
 	if c.config.EnableEipSnat {
 		go wait.Until(c.runAddOvnEipWorker, time.Second, stopCh)
@@ -961,6 +1158,8 @@ func (c *Controller) startWorkers(stopCh <-chan struct{}) {
 		go wait.Until(c.runAddOvnSnatRuleWorker, time.Second, stopCh)
 		go wait.Until(c.runUpdateOvnSnatRuleWorker, time.Second, stopCh)
 		go wait.Until(c.runDelOvnSnatRuleWorker, time.Second, stopCh)
+
+		go wait.Until(c.runUpdateEgressIPPoolWorker, time.Second, stopCh)
 	}
 
 	if c.config.EnableNP {
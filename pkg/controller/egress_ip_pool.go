@@ -0,0 +1,468 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+var errEgressIPPoolExhausted = errors.New("egress ip pool exhausted")
+
+type egressIPAssignment struct {
+	pool string
+	ip   string
+}
+
+// egressIPAllocator records the addresses of egress ip pools assigned to pods.
+// An assignment is kept for the whole lifetime of the pod as long as the address
+// stays in the pool, so that established connections never change source address.
+type egressIPAllocator struct {
+	mutex sync.Mutex
+	// pool name -> ip -> pod keys
+	pools map[string]map[string]map[string]struct{}
+	pods  map[string]egressIPAssignment
+}
+
+func newEgressIPAllocator() *egressIPAllocator {
+	return &egressIPAllocator{
+		pools: make(map[string]map[string]map[string]struct{}),
+		pods:  make(map[string]egressIPAssignment),
+	}
+}
+
+func (a *egressIPAllocator) assign(pool, ip, podKey string) {
+	a.releaseLocked(podKey)
+	if a.pools[pool] == nil {
+		a.pools[pool] = make(map[string]map[string]struct{})
+	}
+	if a.pools[pool][ip] == nil {
+		a.pools[pool][ip] = make(map[string]struct{})
+	}
+	a.pools[pool][ip][podKey] = struct{}{}
+	a.pods[podKey] = egressIPAssignment{pool: pool, ip: ip}
+}
+
+func (a *egressIPAllocator) releaseLocked(podKey string) string {
+	assignment, ok := a.pods[podKey]
+	if !ok {
+		return ""
+	}
+	delete(a.pods, podKey)
+	delete(a.pools[assignment.pool][assignment.ip], podKey)
+	if len(a.pools[assignment.pool][assignment.ip]) == 0 {
+		delete(a.pools[assignment.pool], assignment.ip)
+	}
+	if len(a.pools[assignment.pool]) == 0 {
+		delete(a.pools, assignment.pool)
+	}
+	return assignment.pool
+}
+
+// Restore records an existing assignment, e.g. from pod annotations after restart
+func (a *egressIPAllocator) Restore(pool, ip, podKey string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.assign(pool, ip, podKey)
+}
+
+// Release removes the assignment of the pod and returns the pool it belonged to
+func (a *egressIPAllocator) Release(podKey string) string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.releaseLocked(podKey)
+}
+
+// Get returns the assignment of the pod
+func (a *egressIPAllocator) Get(podKey string) (string, string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	assignment := a.pods[podKey]
+	return assignment.pool, assignment.ip
+}
+
+// Acquire returns the address assigned to the pod, the current one is kept if it is still in the pool,
+// otherwise a random address is chosen from the least used addresses of the pool
+func (a *egressIPAllocator) Acquire(pool *kubeovnv1.EgressIPPool, podKey string) (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if assignment, ok := a.pods[podKey]; ok && assignment.pool == pool.Name && util.ContainsString(pool.Spec.IPs, assignment.ip) {
+		return assignment.ip, nil
+	}
+	a.releaseLocked(podKey)
+
+	var candidates []string
+	least := -1
+	for _, ip := range pool.Spec.IPs {
+		used := len(a.pools[pool.Name][ip])
+		if pool.Spec.MaxPodsPerIP > 0 && used >= pool.Spec.MaxPodsPerIP {
+			continue
+		}
+		switch {
+		case least == -1 || used < least:
+			least, candidates = used, []string{ip}
+		case used == least:
+			candidates = append(candidates, ip)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", errEgressIPPoolExhausted
+	}
+
+	ip := candidates[rand.Intn(len(candidates))]
+	a.assign(pool.Name, ip, podKey)
+	return ip, nil
+}
+
+// Available returns whether the pool has capacity for another pod
+func (a *egressIPAllocator) Available(pool *kubeovnv1.EgressIPPool) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, ip := range pool.Spec.IPs {
+		if pool.Spec.MaxPodsPerIP <= 0 || len(a.pools[pool.Name][ip]) < pool.Spec.MaxPodsPerIP {
+			return true
+		}
+	}
+	return false
+}
+
+func validateEgressIPPool(pool *kubeovnv1.EgressIPPool) error {
+	if pool.Spec.NamespaceSelector == nil && pool.Spec.PodSelector == nil {
+		return fmt.Errorf("at least one of namespaceSelector and podSelector must be set")
+	}
+	if pool.Spec.MaxPodsPerIP < 0 {
+		return fmt.Errorf("maxPodsPerIP %d must not be negative", pool.Spec.MaxPodsPerIP)
+	}
+	seen := make(map[string]struct{}, len(pool.Spec.IPs))
+	for _, ip := range pool.Spec.IPs {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("%s is not a valid ip", ip)
+		}
+		if _, ok := seen[ip]; ok {
+			return fmt.Errorf("duplicate ip %s", ip)
+		}
+		seen[ip] = struct{}{}
+	}
+	if pool.Spec.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(pool.Spec.NamespaceSelector); err != nil {
+			return fmt.Errorf("invalid namespaceSelector: %v", err)
+		}
+	}
+	if pool.Spec.PodSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(pool.Spec.PodSelector); err != nil {
+			return fmt.Errorf("invalid podSelector: %v", err)
+		}
+	}
+	return nil
+}
+
+func isPodMatchEgressIPPool(pod *v1.Pod, ns *v1.Namespace, pool *kubeovnv1.EgressIPPool) bool {
+	if validateEgressIPPool(pool) != nil {
+		return false
+	}
+	if pool.Spec.NamespaceSelector != nil {
+		sel, _ := metav1.LabelSelectorAsSelector(pool.Spec.NamespaceSelector)
+		if !sel.Matches(labels.Set(ns.Labels)) {
+			return false
+		}
+	}
+	if pool.Spec.PodSelector != nil {
+		sel, _ := metav1.LabelSelectorAsSelector(pool.Spec.PodSelector)
+		if !sel.Matches(labels.Set(pod.Labels)) {
+			return false
+		}
+	}
+	return true
+}
+
+// getPodEgressIPPool returns the first pool in name order matching the pod without eip/snat
+func (c *Controller) getPodEgressIPPool(pod *v1.Pod) (*kubeovnv1.EgressIPPool, error) {
+	if pod.Annotations[util.EipAnnotation] != "" || pod.Annotations[util.SnatAnnotation] != "" {
+		// the eip/snat of the pod takes precedence
+		return nil, nil
+	}
+	ns, err := c.namespacesLister.Get(pod.Namespace)
+	if err != nil {
+		klog.Errorf("failed to get namespace %s: %v", pod.Namespace, err)
+		return nil, err
+	}
+	pools, err := c.egressIPPoolsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list egress ip pools: %v", err)
+		return nil, err
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+	for _, pool := range pools {
+		if pool.DeletionTimestamp == nil && isPodMatchEgressIPPool(pod, ns, pool) {
+			return pool, nil
+		}
+	}
+	return nil, nil
+}
+
+// acquirePodEgressIP assigns an address of the matching egress ip pool to the pod and records it
+// in the pod annotations, an empty address is returned if the pod has its own eip/snat, matches no
+// pool or the pool is exhausted
+func (c *Controller) acquirePodEgressIP(pod *v1.Pod, podName string) (string, error) {
+	key := fmt.Sprintf("%s/%s", pod.Namespace, podName)
+	pool, err := c.getPodEgressIPPool(pod)
+	if err != nil {
+		return "", err
+	}
+
+	if pool == nil {
+		if oldPool := c.egressIPAllocator.Release(key); oldPool != "" {
+			c.updateEgressIPPoolQueue.Add(oldPool)
+		}
+		delete(pod.Annotations, util.EgressIPPoolAnnotation)
+		delete(pod.Annotations, util.EgressIPAnnotation)
+		return "", nil
+	}
+
+	oldPool, _ := c.egressIPAllocator.Get(key)
+	ip, err := c.egressIPAllocator.Acquire(pool, key)
+	if oldPool != "" && oldPool != pool.Name {
+		c.updateEgressIPPoolQueue.Add(oldPool)
+	}
+	c.updateEgressIPPoolQueue.Add(pool.Name)
+	if err != nil {
+		if !errors.Is(err, errEgressIPPoolExhausted) {
+			return "", err
+		}
+		klog.Warningf("no address available in egress ip pool %s for pod %s", pool.Name, key)
+		c.recorder.Eventf(pod, v1.EventTypeWarning, "EgressIPPoolExhausted", "no address available in egress ip pool %s", pool.Name)
+		delete(pod.Annotations, util.EgressIPPoolAnnotation)
+		delete(pod.Annotations, util.EgressIPAnnotation)
+		return "", nil
+	}
+
+	if pod.Annotations[util.EgressIPAnnotation] != ip {
+		klog.Infof("assign egress ip %s of pool %s to pod %s", ip, pool.Name, key)
+	}
+	pod.Annotations[util.EgressIPPoolAnnotation] = pool.Name
+	pod.Annotations[util.EgressIPAnnotation] = ip
+	return ip, nil
+}
+
+func (c *Controller) initEgressIPPools() error {
+	pools, err := c.egressIPPoolsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list egress ip pools: %v", err)
+		return err
+	}
+	poolMap := make(map[string]*kubeovnv1.EgressIPPool, len(pools))
+	for _, pool := range pools {
+		poolMap[pool.Name] = pool
+	}
+
+	pods, err := c.podsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list pods: %v", err)
+		return err
+	}
+	for _, pod := range pods {
+		if !isPodAlive(pod) {
+			continue
+		}
+		pool := poolMap[pod.Annotations[util.EgressIPPoolAnnotation]]
+		ip := pod.Annotations[util.EgressIPAnnotation]
+		if pool == nil || !util.ContainsString(pool.Spec.IPs, ip) {
+			continue
+		}
+		c.egressIPAllocator.Restore(pool.Name, ip, fmt.Sprintf("%s/%s", pod.Namespace, c.getNameByPod(pod)))
+	}
+	return nil
+}
+
+func (c *Controller) enqueueAddEgressIPPool(obj interface{}) {
+	if !c.isLeader() {
+		return
+	}
+	var key string
+	var err error
+	if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	klog.V(3).Infof("enqueue add egress ip pool %s", key)
+	c.updateEgressIPPoolQueue.Add(key)
+}
+
+func (c *Controller) enqueueUpdateEgressIPPool(old, new interface{}) {
+	if !c.isLeader() {
+		return
+	}
+	oldPool := old.(*kubeovnv1.EgressIPPool)
+	newPool := new.(*kubeovnv1.EgressIPPool)
+	if oldPool.ResourceVersion == newPool.ResourceVersion ||
+		(reflect.DeepEqual(oldPool.Spec, newPool.Spec) && newPool.DeletionTimestamp == nil) {
+		return
+	}
+	var key string
+	var err error
+	if key, err = cache.MetaNamespaceKeyFunc(new); err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	klog.V(3).Infof("enqueue update egress ip pool %s", key)
+	c.updateEgressIPPoolQueue.Add(key)
+}
+
+func (c *Controller) enqueueDelEgressIPPool(obj interface{}) {
+	if !c.isLeader() {
+		return
+	}
+	var key string
+	var err error
+	if key, err = cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	klog.V(3).Infof("enqueue delete egress ip pool %s", key)
+	c.updateEgressIPPoolQueue.Add(key)
+}
+
+func (c *Controller) runUpdateEgressIPPoolWorker() {
+	for c.processNextUpdateEgressIPPoolWorkItem() {
+	}
+}
+
+func (c *Controller) processNextUpdateEgressIPPoolWorkItem() bool {
+	obj, shutdown := c.updateEgressIPPoolQueue.Get()
+	if shutdown {
+		return false
+	}
+
+	err := func(obj interface{}) error {
+		defer c.updateEgressIPPoolQueue.Done(obj)
+		var key string
+		var ok bool
+		if key, ok = obj.(string); !ok {
+			c.updateEgressIPPoolQueue.Forget(obj)
+			utilruntime.HandleError(fmt.Errorf("expected string in workqueue but got %#v", obj))
+			return nil
+		}
+		if err := c.handleUpdateEgressIPPool(key); err != nil {
+			c.updateEgressIPPoolQueue.AddRateLimited(key)
+			return fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error())
+		}
+		c.updateEgressIPPoolQueue.Forget(obj)
+		return nil
+	}(obj)
+
+	if err != nil {
+		utilruntime.HandleError(err)
+		return true
+	}
+	return true
+}
+
+// handleUpdateEgressIPPool enqueues the pods whose egress ip should be changed and refreshes the pool status
+func (c *Controller) handleUpdateEgressIPPool(key string) error {
+	cachedPool, err := c.egressIPPoolsLister.Get(key)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		klog.Errorf("failed to get egress ip pool %s: %v", key, err)
+		return err
+	}
+	if cachedPool != nil && cachedPool.DeletionTimestamp != nil {
+		cachedPool = nil
+	}
+	if cachedPool != nil {
+		if err = validateEgressIPPool(cachedPool); err != nil {
+			klog.Errorf("invalid egress ip pool %s: %v", key, err)
+			c.recorder.Eventf(cachedPool, v1.EventTypeWarning, "ValidateEgressIPPoolFailed", err.Error())
+			return nil
+		}
+	}
+
+	pods, err := c.podsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list pods: %v", err)
+		return err
+	}
+
+	var assigned, pending int
+	available := cachedPool != nil && c.egressIPAllocator.Available(cachedPool)
+	for _, pod := range pods {
+		if pod.Spec.HostNetwork || !isPodAlive(pod) || pod.Annotations[util.RoutedAnnotation] != "true" {
+			continue
+		}
+		podKey := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+		pool, ip := c.egressIPAllocator.Get(fmt.Sprintf("%s/%s", pod.Namespace, c.getNameByPod(pod)))
+		if cachedPool == nil {
+			if pool == key {
+				c.updatePodQueue.Add(podKey)
+			}
+			continue
+		}
+
+		if subnet, err := c.subnetsLister.Get(pod.Annotations[util.LogicalSwitchAnnotation]); err != nil ||
+			subnet.Spec.Vpc != util.DefaultVpc || subnet.Spec.Vlan != "" {
+			// only pods of overlay subnets in the default vpc egress via the external gateway
+			continue
+		}
+		wanted, err := c.getPodEgressIPPool(pod)
+		if err != nil {
+			return err
+		}
+		matched := wanted != nil && wanted.Name == key
+		switch {
+		case pool == key && (!matched || !util.ContainsString(cachedPool.Spec.IPs, ip)):
+			c.updatePodQueue.Add(podKey)
+		case pool == key:
+			assigned++
+		case matched:
+			pending++
+			if available || pool != "" {
+				c.updatePodQueue.Add(podKey)
+			}
+		}
+	}
+	if cachedPool == nil {
+		return nil
+	}
+
+	pool := cachedPool.DeepCopy()
+	pool.Status.AssignedPods = assigned
+	pool.Status.PendingPods = pending
+	pool.Status.Exhausted = pending != 0 && !available
+	if pool.Status == cachedPool.Status {
+		return nil
+	}
+	if pool.Status.Exhausted && !cachedPool.Status.Exhausted {
+		klog.Warningf("egress ip pool %s is exhausted, %d pods are waiting for an address", key, pending)
+		c.recorder.Eventf(pool, v1.EventTypeWarning, "EgressIPPoolExhausted", "%d pods are waiting for an address", pending)
+	}
+	bytes, err := pool.Status.Bytes()
+	if err != nil {
+		klog.Errorf("failed to marshal egress ip pool status, %v", err)
+		return err
+	}
+	if _, err = c.config.KubeOvnClient.KubeovnV1().EgressIPPools().Patch(context.Background(), pool.Name,
+		types.MergePatchType, bytes, metav1.PatchOptions{}, "status"); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		klog.Errorf("failed to patch status of egress ip pool %s: %v", pool.Name, err)
+		return err
+	}
+	return nil
+}
@@ -28,23 +28,31 @@ type leaderElectionConfig struct {
 	ElectionID string
 	WasLeader  bool
 
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+
 	OnStartedLeading func(chan struct{})
 	OnStoppedLeading func()
 	OnNewLeader      func(identity string)
 }
 
 func (c *Controller) isLeader() bool {
-	return c.elector.IsLeader()
+	elector, _ := c.elector.Load().(*leaderelection.LeaderElector)
+	return elector != nil && elector.IsLeader()
 }
 
 func (c *Controller) leaderElection() {
 	config := &leaderElectionConfig{
-		Client:       c.config.KubeClient,
-		ElectionID:   "kube-ovn-controller",
-		PodName:      c.config.PodName,
-		PodNamespace: c.config.PodNamespace,
+		Client:        c.config.KubeClient,
+		ElectionID:    "kube-ovn-controller",
+		PodName:       c.config.PodName,
+		PodNamespace:  c.config.PodNamespace,
+		LeaseDuration: c.config.LeaderElectLeaseDuration,
+		RenewDeadline: c.config.LeaderElectRenewDeadline,
+		RetryPeriod:   c.config.LeaderElectRetryPeriod,
 	}
-	c.elector = setupLeaderElection(config)
+	c.elector.Store(setupLeaderElection(config))
 
 	var flag bool
 	for {
@@ -108,9 +116,9 @@ func setupLeaderElection(config *leaderElectionConfig) *leaderelection.LeaderEle
 	}
 	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
 		Lock:          lock,
-		LeaseDuration: 15 * time.Second,
-		RenewDeadline: 10 * time.Second,
-		RetryPeriod:   2 * time.Second,
+		LeaseDuration: config.LeaseDuration,
+		RenewDeadline: config.RenewDeadline,
+		RetryPeriod:   config.RetryPeriod,
 		Callbacks:     callbacks,
 	})
 	if err != nil {
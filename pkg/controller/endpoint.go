@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
@@ -133,6 +135,7 @@ func (c *Controller) handleUpdateEndpoint(key string) error {
 		klog.Errorf("failed to get pods for service %s in namespace %s: %v", name, namespace, err)
 		return err
 	}
+	terminatingPods, requeueAfter := c.servingTerminatingPods(svc, pods)
 
 	var vpcName string
 	for _, pod := range pods {
@@ -184,11 +187,17 @@ func (c *Controller) handleUpdateEndpoint(key string) error {
 	if svc.Spec.SessionAffinity == v1.ServiceAffinityClientIP {
 		tcpLb, udpLb = vpc.Status.TcpSessionLoadBalancer, vpc.Status.UdpSessionLoadBalancer
 	}
+	if hasDedicatedLoadBalancers(svc) {
+		if tcpLb, udpLb, err = c.ensureDedicatedLoadBalancers(svc, vpcName); err != nil {
+			klog.Errorf("failed to ensure dedicated lb of service %s/%s, %v", namespace, name, err)
+			return err
+		}
+	}
 
 	for _, settingIP := range LbIPs {
 		for _, port := range svc.Spec.Ports {
 			vip := util.JoinHostPort(settingIP, port.Port)
-			backends := getServicePortBackends(ep, pods, port, settingIP)
+			backends := getServicePortBackends(ep, pods, terminatingPods, port, settingIP)
 			if port.Protocol == v1.ProtocolTCP {
 				// for performance reason delete lb with no backends
 				if len(backends) != 0 {
@@ -222,10 +231,140 @@ func (c *Controller) handleUpdateEndpoint(key string) error {
 		}
 	}
 
+	if requeueAfter > 0 {
+		// remove the terminating backends once their grace period ends
+		c.updateEndpointQueue.AddAfter(key, requeueAfter)
+	}
+
+	c.exportLbBackendCount(svc, LbIPs, tcpLb, udpLb)
 	return nil
 }
 
-func getServicePortBackends(endpoints *v1.Endpoints, pods []*v1.Pod, servicePort v1.ServicePort, serviceIP string) string {
+// exportLbBackendCount exports the num of backends of the service vips according to the vips of the ovn load balancers
+func (c *Controller) exportLbBackendCount(svc *v1.Service, lbIPs []string, tcpLb, udpLb string) {
+	lbVips := make(map[string]map[string]string, 2)
+	for _, port := range svc.Spec.Ports {
+		lb := tcpLb
+		if port.Protocol != v1.ProtocolTCP {
+			lb = udpLb
+		}
+		if _, ok := lbVips[lb]; ok {
+			continue
+		}
+		vips, err := c.ovnLegacyClient.GetLoadBalancerVips(lb)
+		if err != nil {
+			klog.Errorf("failed to get vips of lb %s, %v", lb, err)
+			return
+		}
+		lbVips[lb] = vips
+	}
+
+	deleteLbBackendCount(svc.Namespace, svc.Name)
+	for _, settingIP := range lbIPs {
+		for _, port := range svc.Spec.Ports {
+			lb := tcpLb
+			if port.Protocol != v1.ProtocolTCP {
+				lb = udpLb
+			}
+			vip := util.JoinHostPort(settingIP, port.Port)
+			var count int
+			if backends := lbVips[lb][vip]; backends != "" {
+				count = len(strings.Split(backends, ","))
+			}
+			metricLbBackendCount.WithLabelValues(svc.Namespace, svc.Name, string(port.Protocol), vip).Set(float64(count))
+		}
+	}
+}
+
+// servingTerminatingPods returns the terminating pods of the service which are still ready and within the grace
+// period after their deletion, and the duration until the earliest grace period ends
+func (c *Controller) servingTerminatingPods(svc *v1.Service, pods []*v1.Pod) ([]*v1.Pod, time.Duration) {
+	if c.config.TerminatingEndpointGrace == 0 || len(svc.Spec.Selector) == 0 {
+		return nil, 0
+	}
+
+	var terminatingPods []*v1.Pod
+	var requeueAfter time.Duration
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil || pod.Status.Phase != v1.PodRunning || !isPodReady(pod) {
+			continue
+		}
+		// the deletion timestamp is the time the pod is killed after the termination grace period
+		deletedAt := pod.DeletionTimestamp.Time
+		if pod.DeletionGracePeriodSeconds != nil {
+			deletedAt = deletedAt.Add(-time.Duration(*pod.DeletionGracePeriodSeconds) * time.Second)
+		}
+		remaining := time.Until(deletedAt.Add(c.config.TerminatingEndpointGrace))
+		if remaining <= 0 {
+			continue
+		}
+		terminatingPods = append(terminatingPods, pod)
+		if requeueAfter == 0 || remaining < requeueAfter {
+			requeueAfter = remaining
+		}
+	}
+	return terminatingPods, requeueAfter
+}
+
+// enqueueServiceEndpointsOfPod enqueues the endpoints of the services selecting the pod
+func (c *Controller) enqueueServiceEndpointsOfPod(pod *v1.Pod) {
+	svcs, err := c.servicesLister.Services(pod.Namespace).List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list services in namespace %s, %v", pod.Namespace, err)
+		return
+	}
+	for _, svc := range svcs {
+		if len(svc.Spec.Selector) == 0 || !labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		key := fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)
+		klog.V(3).Infof("enqueue update endpoint %s", key)
+		c.updateEndpointQueue.Add(key)
+	}
+}
+
+func isPodReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podIPByProtocol returns the ip of the pod in the protocol
+func podIPByProtocol(pod *v1.Pod, protocol string) string {
+	podIPs := pod.Status.PodIPs
+	if len(podIPs) == 0 && pod.Status.PodIP != "" {
+		podIPs = []v1.PodIP{{IP: pod.Status.PodIP}}
+	}
+	for _, podIP := range podIPs {
+		if util.CheckProtocol(podIP.IP) == protocol {
+			return podIP.IP
+		}
+	}
+	return ""
+}
+
+// podTargetPort resolves the target port of the service port on the pod, which may refer to a named container port
+func podTargetPort(pod *v1.Pod, servicePort v1.ServicePort) int32 {
+	if servicePort.TargetPort.Type == intstr.Int {
+		if servicePort.TargetPort.IntVal != 0 {
+			return servicePort.TargetPort.IntVal
+		}
+		return servicePort.Port
+	}
+	for _, port := range podContainerPorts(pod) {
+		if port.Name == servicePort.TargetPort.StrVal && port.Protocol == servicePort.Protocol {
+			return port.ContainerPort
+		}
+	}
+	return 0
+}
+
+// getServicePortBackends returns the backends of the service port. The terminating pods are not in the endpoints,
+// and are added as backends
+func getServicePortBackends(endpoints *v1.Endpoints, pods, terminatingPods []*v1.Pod, servicePort v1.ServicePort, serviceIP string) string {
 	backends := []string{}
 	protocol := util.CheckProtocol(serviceIP)
 	for _, subset := range endpoints.Subsets {
@@ -249,16 +388,7 @@ func getServicePortBackends(endpoints *v1.Endpoints, pods []*v1.Pod, servicePort
 			var ip string
 			for _, pod := range pods {
 				if pod.Name == address.TargetRef.Name {
-					podIPs := pod.Status.PodIPs
-					if len(podIPs) == 0 && pod.Status.PodIP != "" {
-						podIPs = []v1.PodIP{{IP: pod.Status.PodIP}}
-					}
-					for _, podIP := range podIPs {
-						if util.CheckProtocol(podIP.IP) == protocol {
-							ip = podIP.IP
-							break
-						}
-					}
+					ip = podIPByProtocol(pod, protocol)
 					break
 				}
 			}
@@ -271,5 +401,15 @@ func getServicePortBackends(endpoints *v1.Endpoints, pods []*v1.Pod, servicePort
 		}
 	}
 
+	for _, pod := range terminatingPods {
+		ip, targetPort := podIPByProtocol(pod, protocol), podTargetPort(pod, servicePort)
+		if ip == "" || targetPort == 0 {
+			continue
+		}
+		if backend := util.JoinHostPort(ip, targetPort); !util.ContainsString(backends, backend) {
+			backends = append(backends, backend)
+		}
+	}
+
 	return strings.Join(backends, ",")
 }
@@ -33,6 +33,7 @@ func (c *Controller) exportSubnetMetrics() bool {
 	for _, subnet := range subnets {
 		c.exportSubnetAvailableIPsGauge(subnet)
 		c.exportSubnetUsedIPsGauge(subnet)
+		c.exportSubnetQuarantinedIPsGauge(subnet)
 	}
 
 	return true
@@ -59,3 +60,38 @@ func (c *Controller) exportSubnetUsedIPsGauge(subnet *kubeovnv1.Subnet) {
 	}
 	metricSubnetUsedIPs.WithLabelValues(subnet.Name, subnet.Spec.Protocol, subnet.Spec.CIDRBlock).Set(usingIPs)
 }
+
+// quarantined addresses are still counted in the available ones as they are available soon
+func (c *Controller) exportSubnetQuarantinedIPsGauge(subnet *kubeovnv1.Subnet) {
+	v4, v6 := c.ipam.GetSubnetQuarantinedIPs(subnet.Name)
+	quarantinedIPs := math.Max(float64(v4), float64(v6))
+	metricSubnetQuarantinedIPs.WithLabelValues(subnet.Name, subnet.Spec.Protocol, subnet.Spec.CIDRBlock).Set(quarantinedIPs)
+}
+
+// resyncOvnObjectMetrics updates the counts of ovn objects, only the leader scrapes them to avoid duplicate series
+func (c *Controller) resyncOvnObjectMetrics() {
+	if !c.isLeader() {
+		return
+	}
+
+	routers, err := c.ovnLegacyClient.ListLogicalRouter(false)
+	if err != nil {
+		klog.Errorf("failed to list logical routers, %v", err)
+	} else {
+		metricLogicalRouterCount.Set(float64(len(routers)))
+	}
+
+	switches, err := c.ovnLegacyClient.ListLogicalSwitch(false)
+	if err != nil {
+		klog.Errorf("failed to list logical switches, %v", err)
+	} else {
+		metricLogicalSwitchCount.Set(float64(len(switches)))
+	}
+
+	ports, err := c.ovnClient.CountLogicalSwitchPorts()
+	if err != nil {
+		klog.Errorf("failed to count logical switch ports, %v", err)
+	} else {
+		metricLogicalSwitchPortCount.Set(float64(ports))
+	}
+}
@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/ovs"
+	"github.com/kubeovn/kube-ovn/pkg/request"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+// acquireExternalAddress reserves the addresses of the pod from the external ipam webhook of the subnet and records
+// them in the built-in ipam to keep the subnet status and conflict checks working. The webhook is called again when
+// the pod is retried, so the reservation must be idempotent for the same port
+func (c *Controller) acquireExternalAddress(pod *v1.Pod, podNet *kubeovnNet, key, portName, mac string) (string, string, string, error) {
+	subnet := podNet.Subnet
+	req := request.ExternalIPAMRequest{
+		Subnet:     subnet.Name,
+		CIDR:       subnet.Spec.CIDRBlock,
+		Namespace:  pod.Namespace,
+		PodName:    c.getNameByPod(pod),
+		Port:       portName,
+		IPAddress:  pod.Annotations[fmt.Sprintf(util.IpAddressAnnotationTemplate, podNet.ProviderName)],
+		MacAddress: mac,
+	}
+	resp, err := c.externalIPAMClient.Reserve(subnet.Spec.ExternalIPAM, req)
+	if err != nil {
+		klog.Errorf("failed to reserve addresses for %s: %v", key, err)
+		return "", "", "", err
+	}
+	if err = checkExternalAddresses(subnet, resp.IPAddress); err != nil {
+		err = fmt.Errorf("invalid addresses reserved by external ipam %s for %s: %v", subnet.Spec.ExternalIPAM, key, err)
+		klog.Error(err)
+		req.IPAddress = resp.IPAddress
+		c.releaseExternalAddress(subnet.Spec.ExternalIPAM, req)
+		return "", "", "", err
+	}
+	if resp.MacAddress != "" {
+		mac = resp.MacAddress
+	}
+	klog.Infof("external ipam %s reserved %s for %s", subnet.Spec.ExternalIPAM, resp.IPAddress, key)
+	v4IP, v6IP, mac, err := c.acquireStaticAddress(key, portName, resp.IPAddress, mac, subnet.Name, podNet.AllowLiveMigration)
+	if err != nil {
+		req.IPAddress = resp.IPAddress
+		c.releaseExternalAddress(subnet.Spec.ExternalIPAM, req)
+		return "", "", "", err
+	}
+	return v4IP, v6IP, mac, nil
+}
+
+// releaseExternalAddressOfPod releases the addresses reserved for the pod whose ip CR is not created, the addresses
+// recorded in an ip CR are released by its finalizer instead
+func (c *Controller) releaseExternalAddressOfPod(pod *v1.Pod, podName, providerName, ipStr, mac string, subnet *kubeovnv1.Subnet) {
+	portName := ovs.PodNameToPortName(podName, pod.Namespace, providerName)
+	if _, err := c.ipsLister.Get(portName); err == nil || !k8serrors.IsNotFound(err) {
+		return
+	}
+	c.releaseExternalAddress(subnet.Spec.ExternalIPAM, request.ExternalIPAMRequest{
+		Subnet:     subnet.Name,
+		CIDR:       subnet.Spec.CIDRBlock,
+		Namespace:  pod.Namespace,
+		PodName:    podName,
+		Port:       portName,
+		IPAddress:  ipStr,
+		MacAddress: mac,
+	})
+}
+
+// releaseExternalAddress releases the addresses on the error paths, the addresses failed to release are left to the
+// gc of the reservations
+func (c *Controller) releaseExternalAddress(url string, req request.ExternalIPAMRequest) {
+	if err := c.externalIPAMClient.Release(url, req); err != nil {
+		klog.Errorf("failed to release addresses %s of port %s: %v", req.IPAddress, req.Port, err)
+		return
+	}
+	klog.Infof("external ipam %s released %s of port %s", url, req.IPAddress, req.Port)
+}
+
+// gcExternalIPAMReservations releases the reservations of the external ipam webhooks which belong to no ip CR, e.g.
+// the pod is deleted or the controller restarts before the ip CR is created. The reservations of the pods still
+// allocating addresses are kept
+func (c *Controller) gcExternalIPAMReservations() error {
+	klog.Infof("start to gc external ipam reservations")
+	subnets, err := c.subnetsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list subnets, %v", err)
+		return err
+	}
+	for _, subnet := range subnets {
+		if subnet.Spec.ExternalIPAM == "" {
+			continue
+		}
+		reservations, err := c.externalIPAMClient.List(subnet.Spec.ExternalIPAM, subnet.Name, subnet.Spec.CIDRBlock)
+		if err != nil {
+			// an unavailable webhook should not block the gc of the other objects
+			klog.Errorf("failed to list reservations of subnet %s: %v", subnet.Name, err)
+			continue
+		}
+
+		var stale []request.ExternalIPAMReservation
+		var names []string
+		for _, r := range reservations {
+			if _, err = c.ipsLister.Get(r.Port); err == nil || !k8serrors.IsNotFound(err) {
+				continue
+			}
+			if len(c.ipam.GetPodAddress(fmt.Sprintf("%s/%s", r.Namespace, r.PodName))) != 0 {
+				continue
+			}
+			if pod, err := c.podsLister.Pods(r.Namespace).Get(r.PodName); err == nil && isPodAlive(pod) {
+				continue
+			}
+			stale = append(stale, r)
+			names = append(names, r.Port)
+		}
+		if !c.gcDeleteAllowed("external ipam reservations of subnet "+subnet.Name, names, len(reservations)) {
+			continue
+		}
+		for _, r := range stale {
+			klog.Infof("gc external ipam reservation %s of port %s in subnet %s", r.IPAddress, r.Port, subnet.Name)
+			c.releaseExternalAddress(subnet.Spec.ExternalIPAM, request.ExternalIPAMRequest{
+				Subnet:    subnet.Name,
+				CIDR:      subnet.Spec.CIDRBlock,
+				Namespace: r.Namespace,
+				PodName:   r.PodName,
+				Port:      r.Port,
+				IPAddress: r.IPAddress,
+			})
+		}
+	}
+	return nil
+}
+
+// checkExternalAddresses checks that the addresses have exactly one ip of each protocol of the subnet in its cidr
+func checkExternalAddresses(subnet *kubeovnv1.Subnet, ipStr string) error {
+	cidrs := make(map[string]string, 2)
+	for _, cidr := range strings.Split(subnet.Spec.CIDRBlock, ",") {
+		cidrs[util.CheckProtocol(cidr)] = cidr
+	}
+	ips := strings.Split(ipStr, ",")
+	if len(ips) != len(cidrs) {
+		return fmt.Errorf("%s does not have one address per protocol of subnet %s", ipStr, subnet.Name)
+	}
+	for _, ip := range ips {
+		cidr, ok := cidrs[util.CheckProtocol(ip)]
+		if !ok || !util.CIDRContainIP(cidr, ip) {
+			return fmt.Errorf("%s is not in the cidr of subnet %s", ip, subnet.Name)
+		}
+		delete(cidrs, util.CheckProtocol(ip))
+	}
+	return nil
+}
+
+func (c *Controller) enqueueReleaseExternalIP(ip *kubeovnv1.IP) {
+	if ip.DeletionTimestamp == nil || !util.ContainsString(ip.Finalizers, util.ExternalIPAMFinalizer) {
+		return
+	}
+	klog.V(3).Infof("enqueue release external ip %s", ip.Name)
+	c.releaseExternalIPQueue.Add(ip.Name)
+}
+
+func (c *Controller) runReleaseExternalIPWorker() {
+	for c.processNextWorkItem("releaseExternalIP", c.releaseExternalIPQueue, c.handleReleaseExternalIP) {
+	}
+}
+
+// handleReleaseExternalIP releases the addresses of the deleting ip CR to the external ipam recorded in the annotation,
+// and removes the finalizer after the release succeeds. The finalizer keeps the CR until then, so that the release is
+// retried after controller restarts
+func (c *Controller) handleReleaseExternalIP(key string) error {
+	cachedIP, err := c.ipsLister.Get(key)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		klog.Errorf("failed to get ip %s: %v", key, err)
+		return err
+	}
+	if cachedIP.DeletionTimestamp == nil || !util.ContainsString(cachedIP.Finalizers, util.ExternalIPAMFinalizer) {
+		return nil
+	}
+
+	if url := cachedIP.Annotations[util.ExternalIPAMAnnotation]; url != "" {
+		req := request.ExternalIPAMRequest{
+			Subnet:     cachedIP.Spec.Subnet,
+			Namespace:  cachedIP.Spec.Namespace,
+			PodName:    cachedIP.Spec.PodName,
+			Port:       cachedIP.Name,
+			IPAddress:  cachedIP.Spec.IPAddress,
+			MacAddress: cachedIP.Spec.MacAddress,
+		}
+		if subnet, err := c.subnetsLister.Get(cachedIP.Spec.Subnet); err == nil {
+			req.CIDR = subnet.Spec.CIDRBlock
+		}
+		if err = c.externalIPAMClient.Release(url, req); err != nil {
+			klog.Errorf("failed to release addresses of ip %s: %v", key, err)
+			c.recorder.Eventf(cachedIP, v1.EventTypeWarning, "ReleaseExternalIPFailed", err.Error())
+			return err
+		}
+		klog.Infof("external ipam %s released %s of ip %s", url, cachedIP.Spec.IPAddress, key)
+	}
+
+	newIP := cachedIP.DeepCopy()
+	controllerutil.RemoveFinalizer(newIP, util.ExternalIPAMFinalizer)
+	patch, err := util.GenerateMergePatchPayload(cachedIP, newIP)
+	if err != nil {
+		klog.Errorf("failed to generate patch payload for ip %s: %v", key, err)
+		return err
+	}
+	if _, err = c.config.KubeOvnClient.KubeovnV1().IPs().Patch(context.Background(), key,
+		types.MergePatchType, patch, metav1.PatchOptions{}, ""); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		klog.Errorf("failed to remove finalizer from ip %s: %v", key, err)
+		return err
+	}
+	return nil
+}
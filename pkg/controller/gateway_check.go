@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+// resolveGatewayCheckMode resolves the gateway check mode of the pod nic for kube-ovn-cni, in the order of
+// the pod annotation, the subnet, the provider network of the subnet and the cluster default.
+// An invalid mode is ignored with an event on the pod and the next one is used
+func (c *Controller) resolveGatewayCheckMode(pod *v1.Pod, provider string, subnet *kubeovnv1.Subnet) string {
+	type candidate struct {
+		source, mode string
+	}
+
+	subnetMode := subnet.Spec.GatewayCheckMode
+	if subnetMode == "" && subnet.Spec.DisableGatewayCheck {
+		subnetMode = kubeovnv1.GatewayCheckModeDisabled
+	}
+	candidates := []candidate{
+		{fmt.Sprintf("annotation %s", fmt.Sprintf(util.GatewayCheckModeAnnotationTemplate, provider)), pod.Annotations[fmt.Sprintf(util.GatewayCheckModeAnnotationTemplate, provider)]},
+		{fmt.Sprintf("subnet %s", subnet.Name), subnetMode},
+	}
+	if subnet.Spec.Vlan != "" {
+		if vlan, err := c.vlansLister.Get(subnet.Spec.Vlan); err != nil {
+			klog.Errorf("failed to get vlan %s, %v", subnet.Spec.Vlan, err)
+		} else if pn, err := c.providerNetworksLister.Get(vlan.Spec.Provider); err != nil {
+			klog.Errorf("failed to get provider network %s, %v", vlan.Spec.Provider, err)
+		} else {
+			candidates = append(candidates, candidate{fmt.Sprintf("provider network %s", pn.Name), pn.Spec.GatewayCheckMode})
+		}
+	}
+
+	for _, candidate := range candidates {
+		if candidate.mode == "" {
+			continue
+		}
+		if err := util.ValidateGatewayCheckMode(candidate.mode, subnet.Spec.GatewayCheckPort); err != nil {
+			klog.Warningf("ignore gateway check mode of %s for pod %s/%s: %v", candidate.source, pod.Namespace, pod.Name, err)
+			c.recorder.Eventf(pod, v1.EventTypeWarning, "InvalidGatewayCheckMode", "ignore gateway check mode of %s: %v", candidate.source, err)
+			continue
+		}
+		return candidate.mode
+	}
+	return c.config.DefaultGatewayCheckMode
+}
@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func (c *Controller) runRebalanceGatewayWorker() {
+	for c.processNextWorkItem("rebalanceGateway", c.rebalanceGatewayQueue, c.handleRebalanceSubnetGateways) {
+	}
+}
+
+func (c *Controller) handleRebalanceSubnetGateways(key string) error {
+	subnet, err := c.subnetsLister.Get(key)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if subnet.Annotations[util.GatewayRebalanceAnnotation] != "true" || !subnet.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	if isSubnetReconcilePaused(subnet) {
+		// rebalanced once the reconciliation is resumed
+		klog.Infof("skip rebalancing gateways of subnet %s as its reconciliation is paused", subnet.Name)
+		return nil
+	}
+
+	if !c.config.EnableEcmp || subnet.Spec.GatewayType != kubeovnv1.GWCentralizedType || subnet.Spec.GatewayNode == "" ||
+		(subnet.Spec.Vlan != "" && !subnet.Spec.LogicalGateway) {
+		msg := fmt.Sprintf("subnet %s has no ecmp centralized gateways to rebalance", subnet.Name)
+		klog.Warning(msg)
+		c.recorder.Event(subnet, v1.EventTypeWarning, "GatewayRebalanceSkipped", msg)
+		return c.removeGatewayRebalanceAnnotation(subnet.Name)
+	}
+
+	klog.Infof("rebalance gateways of subnet %s", subnet.Name)
+	var summaries []string
+	for _, cidrBlock := range strings.Split(subnet.Spec.CIDRBlock, ",") {
+		summary, err := c.rebalanceCentralizedGateways(subnet, cidrBlock)
+		if err != nil {
+			klog.Errorf("failed to rebalance gateways of subnet %s cidr %s, %v", subnet.Name, cidrBlock, err)
+			c.recorder.Eventf(subnet, v1.EventTypeWarning, "GatewayRebalanceFailed", "failed to rebalance gateways of cidr %s, %v", cidrBlock, err)
+			return err
+		}
+		summaries = append(summaries, summary)
+	}
+
+	msg := fmt.Sprintf("egress traffic is distributed as %s", strings.Join(summaries, "; "))
+	klog.Infof("rebalanced gateways of subnet %s, %s", subnet.Name, msg)
+	c.recorder.Event(subnet, v1.EventTypeNormal, "GatewayRebalanced", msg)
+	return c.removeGatewayRebalanceAnnotation(subnet.Name)
+}
+
+func (c *Controller) removeGatewayRebalanceAnnotation(name string) error {
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, util.GatewayRebalanceAnnotation)
+	if _, err := c.config.KubeOvnClient.KubeovnV1().Subnets().Patch(context.Background(), name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		klog.Errorf("failed to remove annotation %s of subnet %s, %v", util.GatewayRebalanceAnnotation, name, err)
+		return err
+	}
+	return nil
+}
+
+// getHealthyGateways returns the ovn0 ips of the ready gateway nodes of the subnet in the protocol of the cidr,
+// the latest results of the gateway check are reused, and the nodes not checked yet are pinged
+func (c *Controller) getHealthyGateways(subnet *kubeovnv1.Subnet, cidr string) (map[string]string, error) {
+	nodes, err := c.nodesLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list nodes, %v", err)
+		return nil, err
+	}
+
+	nameIpMap := make(map[string]string)
+	for _, node := range nodes {
+		if !util.GatewayContains(subnet.Spec.GatewayNode, node.Name) || !nodeReady(node) {
+			continue
+		}
+		for _, ip := range strings.Split(node.Annotations[util.IpAddressAnnotation], ",") {
+			if ip == "" || util.CheckProtocol(ip) != util.CheckProtocol(cidr) {
+				continue
+			}
+			ready, ok := c.gatewayNodesReady.Load(ip)
+			if !ok {
+				success, err := pingGateway(ip, 5)
+				if err != nil {
+					return nil, err
+				}
+				c.gatewayNodesReady.Store(ip, success)
+				ready = success
+			}
+			if ready.(bool) {
+				nameIpMap[node.Name] = ip
+			}
+		}
+	}
+	return nameIpMap, nil
+}
+
+// rebalanceCentralizedGateways recomputes the ecmp next hops and the weighted buckets of the cidr over the healthy
+// gateway nodes. The next hops are updated in place, the new gateways are added before the stale ones are removed,
+// so the egress traffic always has a path, and only the flows hashed to the changed gateways are moved.
+func (c *Controller) rebalanceCentralizedGateways(subnet *kubeovnv1.Subnet, cidr string) (string, error) {
+	desired, err := c.getHealthyGateways(subnet, cidr)
+	if err != nil {
+		return "", err
+	}
+	if len(desired) == 0 {
+		return "", fmt.Errorf("no healthy gateway node in %s", subnet.Spec.GatewayNode)
+	}
+
+	nodeNames := make([]string, 0, len(desired))
+	for name := range desired {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+	nextHops := make([]string, 0, len(nodeNames))
+	for _, name := range nodeNames {
+		nextHops = append(nextHops, desired[name])
+	}
+
+	ipSuffix := "ip4"
+	if util.CheckProtocol(cidr) == kubeovnv1.ProtocolIPv6 {
+		ipSuffix = "ip6"
+	}
+	match := fmt.Sprintf("%s.src == %s", ipSuffix, cidr)
+
+	currentHops, currentMap, err := c.getPolicyRouteParas(cidr)
+	if err != nil {
+		return "", err
+	}
+	added := make(map[string]string)
+	for name, ip := range desired {
+		if currentMap[name] != ip {
+			added[name] = ip
+		}
+	}
+	var stale []string
+	for name := range currentMap {
+		if name == "vendor" || name == "subnet" {
+			continue
+		}
+		if _, ok := desired[name]; !ok {
+			stale = append(stale, name)
+		}
+	}
+
+	// add the new gateways first
+	unionHops := append([]string{}, currentHops...)
+	for _, ip := range nextHops {
+		if !util.ContainsString(unionHops, ip) {
+			unionHops = append(unionHops, ip)
+		}
+	}
+	exist, err := c.ovnLegacyClient.SetPolicyRouteNextHops(util.GatewayRouterPolicyPriority, match, unionHops, added, nil)
+	if err != nil {
+		klog.Errorf("failed to add gateways %v for subnet %s, %v", added, subnet.Name, err)
+		return "", err
+	}
+	if !exist {
+		if err = c.updatePolicyRouteForCentralizedSubnet(subnet.Name, cidr, nextHops, desired); err != nil {
+			return "", err
+		}
+		return c.gatewayDistribution(subnet.Name, cidr, desired)
+	}
+
+	// move the weighted buckets while the stale gateways are still available
+	if err = c.reconcileWeightedPolicyRoute(subnet.Name, cidr, desired); err != nil {
+		return "", err
+	}
+
+	// remove the stale gateways at last
+	if len(stale) != 0 || len(unionHops) != len(nextHops) {
+		klog.Infof("remove gateways %v from subnet %s cidr %s", stale, subnet.Name, cidr)
+		if _, err = c.ovnLegacyClient.SetPolicyRouteNextHops(util.GatewayRouterPolicyPriority, match, nextHops, nil, stale); err != nil {
+			klog.Errorf("failed to remove gateways %v for subnet %s, %v", stale, subnet.Name, err)
+			return "", err
+		}
+	}
+	return c.gatewayDistribution(subnet.Name, cidr, desired)
+}
+
+// gatewayDistribution summarizes the share of egress traffic of each gateway node, the shares follow the weighted
+// buckets if any, otherwise the traffic is split evenly by ecmp
+func (c *Controller) gatewayDistribution(subnetName, cidr string, nameIpMap map[string]string) (string, error) {
+	ipSuffix := "ip4"
+	if util.CheckProtocol(cidr) == kubeovnv1.ProtocolIPv6 {
+		ipSuffix = "ip6"
+	}
+	policies, err := c.ovnClient.GetLogicalRouterPoliciesByExtID("subnet", subnetName)
+	if err != nil {
+		klog.Errorf("failed to list policy routes of subnet %s: %v", subnetName, err)
+		return "", err
+	}
+	buckets := make(map[string]int, len(nameIpMap))
+	var total int
+	prefix := fmt.Sprintf("%s.src == %s && ", ipSuffix, cidr)
+	for _, policy := range policies {
+		if policy.Priority != util.WeightedGatewayRouterPolicyPriority || !strings.HasPrefix(policy.Match, prefix) || len(policy.Nexthops) != 1 {
+			continue
+		}
+		buckets[policy.Nexthops[0]]++
+		total++
+	}
+
+	nodeNames := make([]string, 0, len(nameIpMap))
+	for name := range nameIpMap {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+	shares := make([]string, 0, len(nodeNames))
+	for _, name := range nodeNames {
+		share := 100 / float64(len(nodeNames))
+		if total != 0 {
+			share = 100 * float64(buckets[nameIpMap[name]]) / float64(total)
+		}
+		shares = append(shares, fmt.Sprintf("%s %.1f%%", name, share))
+	}
+	return fmt.Sprintf("%s: %s", cidr, strings.Join(shares, ", ")), nil
+}
@@ -3,23 +3,39 @@ package controller
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 
 	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
 	"github.com/kubeovn/kube-ovn/pkg/ovs"
+	"github.com/kubeovn/kube-ovn/pkg/ovsdb/ovnnb"
 	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
 var lastNoPodLSP map[string]bool
 
+// gcBlockedEventMaxNames limits the names of the objects listed in the event of a blocked gc
+const gcBlockedEventMaxNames = 20
+
+// gcConfirmation is the confirmation of the gc exceeding the delete threshold, it's read from the leader controller
+// pod once per gc round and confirms all the kinds of objects blocked in the round
+type gcConfirmation struct {
+	pod       *corev1.Pod
+	confirmed bool
+	used      bool
+}
+
 func (c *Controller) gc() error {
 	gcFunctions := []func() error{
 		c.gcNode,
@@ -29,13 +45,25 @@ func (c *Controller) gc() error {
 		c.gcLogicalSwitchPort,
 		c.gcLoadBalancer,
 		c.gcPortGroup,
+		c.gcAclSource,
 		c.gcStaticRoute,
 		c.gcVpcNatGateway,
 		c.gcLogicalRouterPort,
 		c.gcVip,
 		c.gcLbSvcPods,
 		c.gcVpcDns,
+		c.gcExternalIPAMReservations,
 	}
+	return c.runGC(gcFunctions...)
+}
+
+// runGC runs the gc functions as a gc round, the rounds are serialized so that they share no confirmation
+func (c *Controller) runGC(gcFunctions ...func() error) error {
+	c.gcMutex.Lock()
+	defer c.gcMutex.Unlock()
+
+	c.gcConfirm = c.getGCConfirmation()
+	defer c.consumeGCConfirmation()
 	for _, gcFunc := range gcFunctions {
 		if err := gcFunc(); err != nil {
 			return err
@@ -44,6 +72,66 @@ func (c *Controller) gc() error {
 	return nil
 }
 
+// getGCConfirmation reads the gc confirmation annotation of the leader controller pod
+func (c *Controller) getGCConfirmation() *gcConfirmation {
+	if c.config.GCMaxDeletePercent >= 100 {
+		return &gcConfirmation{}
+	}
+	pod, err := c.config.KubeClient.CoreV1().Pods(c.config.PodNamespace).Get(context.Background(), c.config.PodName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("failed to get controller pod %s/%s, %v", c.config.PodNamespace, c.config.PodName, err)
+		return &gcConfirmation{}
+	}
+	return &gcConfirmation{pod: pod, confirmed: pod.Annotations[util.GCConfirmAnnotation] == "true"}
+}
+
+// consumeGCConfirmation removes the gc confirmation annotation if it confirmed any blocked gc in the round
+func (c *Controller) consumeGCConfirmation() {
+	confirm := c.gcConfirm
+	c.gcConfirm = nil
+	if !confirm.used {
+		return
+	}
+	pod := confirm.pod
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, util.GCConfirmAnnotation)
+	if _, err := c.config.KubeClient.CoreV1().Pods(pod.Namespace).Patch(context.Background(), pod.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		klog.Errorf("failed to remove annotation %s of controller pod, %v", util.GCConfirmAnnotation, err)
+	}
+}
+
+// gcDeleteAllowed checks the objects to delete against the gc delete threshold, the gc exceeding the threshold is
+// blocked until it's confirmed by annotation on the leader controller pod, the confirmation is consumed at the end of
+// the gc round
+func (c *Controller) gcDeleteAllowed(kind string, toDelete []string, total int) bool {
+	if len(toDelete) == 0 || c.config.GCMaxDeletePercent >= 100 || len(toDelete)*100 <= c.config.GCMaxDeletePercent*total {
+		return true
+	}
+
+	// the gc functions run out of a gc round have no confirmation
+	confirm := c.gcConfirm
+	if confirm == nil {
+		confirm = &gcConfirmation{}
+	}
+	if confirm.confirmed {
+		confirm.used = true
+		klog.Infof("gc of %d of %d %s is confirmed", len(toDelete), total, kind)
+		c.recorder.Eventf(confirm.pod, corev1.EventTypeNormal, "GCConfirmed", "gc %d of %d %s", len(toDelete), total, kind)
+		return true
+	}
+
+	sort.Strings(toDelete)
+	klog.Warningf("refuse to gc %d of %d %s exceeding %d%%: %s", len(toDelete), total, kind, c.config.GCMaxDeletePercent, strings.Join(toDelete, ","))
+	if confirm.pod != nil {
+		names := toDelete
+		if len(names) > gcBlockedEventMaxNames {
+			names = append(names[:gcBlockedEventMaxNames:gcBlockedEventMaxNames], fmt.Sprintf("and %d more", len(toDelete)-gcBlockedEventMaxNames))
+		}
+		c.recorder.Eventf(confirm.pod, corev1.EventTypeWarning, "GCBlocked", "refuse to gc %d of %d %s exceeding %d%%, annotate this pod with %s=true to confirm: %s",
+			len(toDelete), total, kind, c.config.GCMaxDeletePercent, util.GCConfirmAnnotation, strings.Join(names, ","))
+	}
+	return false
+}
+
 func (c *Controller) gcLogicalRouterPort() error {
 	klog.Infof("start to gc logical router port")
 	vpcs, err := c.vpcsLister.List(labels.Everything())
@@ -63,13 +151,20 @@ func (c *Controller) gcLogicalRouterPort() error {
 		klog.Errorf("failed to list logical router port, %v", err)
 		return err
 	}
+	var lrpToDelete []string
 	for _, lrp := range lrps {
 		if !util.ContainsString(exceptPeerPorts, lrp) {
-			klog.Infof("gc logical router port %s", lrp)
-			if err = c.ovnLegacyClient.DeleteLogicalRouterPort(lrp); err != nil {
-				klog.Errorf("failed to delete logical router port %s, %v", lrp, err)
-				return err
-			}
+			lrpToDelete = append(lrpToDelete, lrp)
+		}
+	}
+	if !c.gcDeleteAllowed("logical router ports", lrpToDelete, len(lrps)) {
+		return nil
+	}
+	for _, lrp := range lrpToDelete {
+		klog.Infof("gc logical router port %s", lrp)
+		if err = c.ovnLegacyClient.DeleteLogicalRouterPort(lrp); err != nil {
+			klog.Errorf("failed to delete logical router port %s, %v", lrp, err)
+			return err
 		}
 	}
 	return nil
@@ -107,13 +202,20 @@ func (c *Controller) gcVpcNatGateway() error {
 		klog.Errorf("failed to list vpc nat gateway statefulset, %v", err)
 		return err
 	}
+	var stsToDelete []string
 	for _, sts := range stss.Items {
 		if !util.ContainsString(gwStsNames, sts.Name) {
-			err = c.config.KubeClient.AppsV1().StatefulSets(c.config.PodNamespace).Delete(context.Background(), sts.Name, metav1.DeleteOptions{})
-			if err != nil {
-				klog.Errorf("failed to delete vpc nat gateway statefulset, %v", err)
-				return err
-			}
+			stsToDelete = append(stsToDelete, sts.Name)
+		}
+	}
+	if !c.gcDeleteAllowed("vpc nat gateway statefulsets", stsToDelete, len(stss.Items)) {
+		return nil
+	}
+	for _, name := range stsToDelete {
+		klog.Infof("gc vpc nat gateway statefulset %s", name)
+		if err = c.config.KubeClient.AppsV1().StatefulSets(c.config.PodNamespace).Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil {
+			klog.Errorf("failed to delete vpc nat gateway statefulset, %v", err)
+			return err
 		}
 	}
 	return nil
@@ -139,6 +241,7 @@ func (c *Controller) gcLogicalSwitch() error {
 	}
 	klog.Infof("ls in ovn %v", lss)
 	klog.Infof("subnet in kubernetes %v", subnetNames)
+	var lsToDelete []string
 	for _, ls := range lss {
 		if ls == util.InterconnectionSwitch ||
 			ls == util.ExternalGatewaySwitch ||
@@ -148,7 +251,12 @@ func (c *Controller) gcLogicalSwitch() error {
 		if s := subnetMap[ls]; s != nil && isOvnSubnet(s) {
 			continue
 		}
-
+		lsToDelete = append(lsToDelete, ls)
+	}
+	if !c.gcDeleteAllowed("logical switches", lsToDelete, len(lss)) {
+		return nil
+	}
+	for _, ls := range lsToDelete {
 		klog.Infof("gc subnet %s", ls)
 		if err := c.handleDeleteLogicalSwitch(ls); err != nil {
 			klog.Errorf("failed to gc subnet %s, %v", ls, err)
@@ -169,6 +277,9 @@ func (c *Controller) gcLogicalSwitch() error {
 			uuidToDeleteList = append(uuidToDeleteList, item.UUID)
 		}
 	}
+	if !c.gcDeleteAllowed("dhcp options", uuidToDeleteList, len(dhcpOptions)) {
+		return nil
+	}
 	klog.Infof("gc dhcp options %v", uuidToDeleteList)
 	if len(uuidToDeleteList) > 0 {
 		if err = c.ovnLegacyClient.DeleteDHCPOptionsByUUIDs(uuidToDeleteList); err != nil {
@@ -197,16 +308,20 @@ func (c *Controller) gcCustomLogicalRouter() error {
 	}
 	klog.Infof("lr in ovn %v", lrs)
 	klog.Infof("vpc in kubernetes %v", vpcNames)
+	var lrToDelete []string
 	for _, lr := range lrs {
-		if lr == util.DefaultVpc {
-			continue
+		if lr != util.DefaultVpc && !util.IsStringIn(lr, vpcNames) {
+			lrToDelete = append(lrToDelete, lr)
 		}
-		if !util.IsStringIn(lr, vpcNames) {
-			klog.Infof("gc router %s", lr)
-			if err := c.deleteVpcRouter(lr); err != nil {
-				klog.Errorf("failed to delete router %s, %v", lr, err)
-				return err
-			}
+	}
+	if !c.gcDeleteAllowed("logical routers", lrToDelete, len(lrs)) {
+		return nil
+	}
+	for _, lr := range lrToDelete {
+		klog.Infof("gc router %s", lr)
+		if err := c.deleteVpcRouter(lr); err != nil {
+			klog.Errorf("failed to delete router %s, %v", lr, err)
+			return err
 		}
 	}
 	return nil
@@ -234,13 +349,20 @@ func (c *Controller) gcNode() error {
 			ipNodeNames = append(ipNodeNames, strings.TrimPrefix(ip.Name, "node-"))
 		}
 	}
+	var nodesToDelete []string
 	for _, no := range ipNodeNames {
 		if !util.IsStringIn(no, nodeNames) {
-			klog.Infof("gc node %s", no)
-			if err := c.handleDeleteNode(no); err != nil {
-				klog.Errorf("failed to gc node %s, %v", no, err)
-				return err
-			}
+			nodesToDelete = append(nodesToDelete, no)
+		}
+	}
+	if !c.gcDeleteAllowed("nodes", nodesToDelete, len(ipNodeNames)) {
+		return nil
+	}
+	for _, no := range nodesToDelete {
+		klog.Infof("gc node %s", no)
+		if err := c.handleDeleteNode(no); err != nil {
+			klog.Errorf("failed to gc node %s, %v", no, err)
+			return err
 		}
 	}
 	return nil
@@ -343,16 +465,33 @@ func (c *Controller) markAndCleanLSP() error {
 
 	noPodLSP := map[string]bool{}
 	lspMap := make(map[string]struct{}, len(lsps))
+	var lspToDelete []ovnnb.LogicalSwitchPort
+	var lspNamesToDelete []string
 	for _, lsp := range lsps {
 		lspMap[lsp.Name] = struct{}{}
 		if _, ok := ipMap[lsp.Name]; ok {
 			continue
 		}
+		// the lsps of deleted pods are kept until the end of the port removal grace period
+		if deadline, ok := c.lspRemovalDeadlines.Load(lsp.ExternalIDs["pod"]); ok && time.Now().Before(deadline.(time.Time)) {
+			continue
+		}
 		if !lastNoPodLSP[lsp.Name] {
 			noPodLSP[lsp.Name] = true
 			continue
 		}
+		lspToDelete = append(lspToDelete, lsp)
+		lspNamesToDelete = append(lspNamesToDelete, lsp.Name)
+	}
+	if !c.gcDeleteAllowed("logical switch ports", lspNamesToDelete, len(lsps)) {
+		// keep the ports marked so that they are checked again in the next round
+		for _, name := range lspNamesToDelete {
+			noPodLSP[name] = true
+		}
+		lspToDelete = nil
+	}
 
+	for _, lsp := range lspToDelete {
 		klog.Infof("gc logical switch port %s", lsp.Name)
 		if err := c.ovnLegacyClient.DeleteLogicalSwitchPort(lsp.Name); err != nil {
 			klog.Errorf("failed to delete lsp %s, %v", lsp, err)
@@ -377,9 +516,95 @@ func (c *Controller) markAndCleanLSP() error {
 		}
 	}
 
+	c.checkLspAddressDrift(lsps, ipMap)
+
 	return nil
 }
 
+// checkLspAddressDrift compares the addresses of the logical switch ports in use to the ip records,
+// a drifted port drops the packets of the pod by port security
+func (c *Controller) checkLspAddressDrift(lsps []ovnnb.LogicalSwitchPort, inUse map[string]struct{}) {
+	ips, err := c.ipsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list ip, %v", err)
+		return
+	}
+	ipCRs := make(map[string]*kubeovnv1.IP, len(ips))
+	for _, ip := range ips {
+		ipCRs[ip.Name] = ip
+	}
+
+	metricLspAddressDrift.Reset()
+	for _, lsp := range lsps {
+		if _, ok := inUse[lsp.Name]; !ok || lsp.ExternalIDs["liveMigration"] == "1" {
+			continue
+		}
+		ipCR := ipCRs[lsp.Name]
+		if ipCR == nil || ipCR.Spec.IPAddress == "" {
+			continue
+		}
+		mac, lspIPs := util.ParseLspAddresses(lsp.Addresses)
+		if lspAddressMatch(mac, lspIPs, ipCR.Spec.MacAddress, strings.Split(ipCR.Spec.IPAddress, ",")) {
+			continue
+		}
+
+		klog.Warningf("addresses %v of logical switch port %s drift from ip %s with mac %s and ip %s", lsp.Addresses, lsp.Name, ipCR.Name, ipCR.Spec.MacAddress, ipCR.Spec.IPAddress)
+		metricLspAddressDrift.WithLabelValues(lsp.Name, lsp.ExternalIDs["ls"]).Set(1)
+		c.recordLspAddressDrift(&lsp, ipCR)
+
+		if !c.config.AutoCorrectLspAddress {
+			continue
+		}
+		addresses := []string{strings.TrimSpace(strings.Join(append([]string{ipCR.Spec.MacAddress}, strings.Split(ipCR.Spec.IPAddress, ",")...), " "))}
+		for _, address := range lsp.Addresses {
+			if address == "unknown" {
+				addresses = append(addresses, address)
+			}
+		}
+		if err = c.ovnLegacyClient.SetPortAddresses(lsp.Name, addresses); err != nil {
+			klog.Errorf("failed to correct addresses of logical switch port %s, %v", lsp.Name, err)
+			continue
+		}
+		klog.Infof("addresses of logical switch port %s are corrected to %v", lsp.Name, addresses)
+		metricLspAddressDrift.WithLabelValues(lsp.Name, lsp.ExternalIDs["ls"]).Set(0)
+	}
+}
+
+// lspAddressMatch compares the addresses regardless of the order and the case of the mac
+func lspAddressMatch(lspMac string, lspIPs []string, mac string, ips []string) bool {
+	if mac != "" && !strings.EqualFold(lspMac, mac) {
+		return false
+	}
+	if len(lspIPs) != len(ips) {
+		return false
+	}
+	for _, ip := range ips {
+		if !util.ContainsString(lspIPs, strings.TrimSpace(ip)) {
+			return false
+		}
+	}
+	return true
+}
+
+// recordLspAddressDrift records the event on the pod or node owning the port
+func (c *Controller) recordLspAddressDrift(lsp *ovnnb.LogicalSwitchPort, ipCR *kubeovnv1.IP) {
+	var obj runtime.Object
+	if strings.HasPrefix(lsp.Name, "node-") {
+		node, err := c.nodesLister.Get(ipCR.Spec.PodName)
+		if err != nil {
+			return
+		}
+		obj = node
+	} else {
+		pod, err := c.podsLister.Pods(ipCR.Spec.Namespace).Get(ipCR.Spec.PodName)
+		if err != nil {
+			return
+		}
+		obj = pod
+	}
+	c.recorder.Eventf(obj, corev1.EventTypeWarning, "LspAddressDrift", "addresses %v of logical switch port %s drift from the allocated mac %s and ip %s", lsp.Addresses, lsp.Name, ipCR.Spec.MacAddress, ipCR.Spec.IPAddress)
+}
+
 func (c *Controller) gcLoadBalancer() error {
 	klog.Infof("start to gc loadbalancers")
 	if !c.config.EnableLb {
@@ -433,6 +658,9 @@ func (c *Controller) gcLoadBalancer() error {
 			klog.Errorf("failed to list load balancer, %v", err)
 			return err
 		}
+		if !c.gcDeleteAllowed("load balancers", ovnLbs, len(ovnLbs)) {
+			return nil
+		}
 		if err = c.ovnLegacyClient.DeleteLoadBalancer(ovnLbs...); err != nil {
 			klog.Errorf("failed to delete load balancer, %v", err)
 			return err
@@ -449,7 +677,14 @@ func (c *Controller) gcLoadBalancer() error {
 	udpVips := []string{}
 	tcpSessionVips := []string{}
 	udpSessionVips := []string{}
+	var serviceLbs []string
 	for _, svc := range svcs {
+		if hasDedicatedLoadBalancers(svc) {
+			// vips of the service are served by its dedicated loadbalancers
+			tcpDedicatedLb, udpDedicatedLb := dedicatedLbNames(svc)
+			serviceLbs = append(serviceLbs, tcpDedicatedLb, udpDedicatedLb)
+			continue
+		}
 		ip := svc.Spec.ClusterIP
 		if v, ok := svc.Annotations[util.SwitchLBRuleVipsAnnotation]; ok {
 			ip = v
@@ -482,90 +717,10 @@ func (c *Controller) gcLoadBalancer() error {
 		tcpSessLb, udpSessLb := vpc.Status.TcpSessionLoadBalancer, vpc.Status.UdpSessionLoadBalancer
 		vpcLbs = append(vpcLbs, tcpLb, udpLb, tcpSessLb, udpSessLb)
 
-		if tcpLb != "" {
-			lbUuid, err := c.ovnLegacyClient.FindLoadbalancer(tcpLb)
-			if err != nil {
-				klog.Errorf("failed to get lb %v", err)
-			}
-			vips, err := c.ovnLegacyClient.GetLoadBalancerVips(lbUuid)
-			if err != nil {
-				klog.Errorf("failed to get tcp lb vips %v", err)
+		for lb, svcVips := range map[string][]string{tcpLb: tcpVips, tcpSessLb: tcpSessionVips, udpLb: udpVips, udpSessLb: udpSessionVips} {
+			if err = c.gcLoadBalancerVips(lb, svcVips); err != nil {
 				return err
 			}
-			for vip := range vips {
-				if !util.IsStringIn(vip, tcpVips) {
-					err := c.ovnLegacyClient.DeleteLoadBalancerVip(vip, tcpLb)
-					if err != nil {
-						klog.Errorf("failed to delete vip %s from tcp lb %s, %v", vip, tcpLb, err)
-						return err
-					}
-				}
-			}
-		}
-
-		if tcpSessLb != "" {
-			lbUuid, err := c.ovnLegacyClient.FindLoadbalancer(tcpSessLb)
-			if err != nil {
-				klog.Errorf("failed to get lb %v", err)
-			}
-			vips, err := c.ovnLegacyClient.GetLoadBalancerVips(lbUuid)
-			if err != nil {
-				klog.Errorf("failed to get tcp session lb vips %v", err)
-				return err
-			}
-			for vip := range vips {
-				if !util.IsStringIn(vip, tcpSessionVips) {
-					err := c.ovnLegacyClient.DeleteLoadBalancerVip(vip, tcpSessLb)
-					if err != nil {
-						klog.Errorf("failed to delete vip %s from tcp session lb %s, %v", vip, tcpSessLb, err)
-						return err
-					}
-				}
-			}
-		}
-
-		if udpLb != "" {
-			lbUuid, err := c.ovnLegacyClient.FindLoadbalancer(udpLb)
-			if err != nil {
-				klog.Errorf("failed to get lb %v", err)
-				return err
-			}
-			vips, err := c.ovnLegacyClient.GetLoadBalancerVips(lbUuid)
-			if err != nil {
-				klog.Errorf("failed to get udp lb vips %v", err)
-				return err
-			}
-			for vip := range vips {
-				if !util.IsStringIn(vip, udpVips) {
-					err := c.ovnLegacyClient.DeleteLoadBalancerVip(vip, udpLb)
-					if err != nil {
-						klog.Errorf("failed to delete vip %s from tcp lb %s, %v", vip, udpLb, err)
-						return err
-					}
-				}
-			}
-		}
-
-		if udpSessLb != "" {
-			lbUuid, err := c.ovnLegacyClient.FindLoadbalancer(udpSessLb)
-			if err != nil {
-				klog.Errorf("failed to get lb %v", err)
-				return err
-			}
-			vips, err := c.ovnLegacyClient.GetLoadBalancerVips(lbUuid)
-			if err != nil {
-				klog.Errorf("failed to get udp session lb vips %v", err)
-				return err
-			}
-			for vip := range vips {
-				if !util.IsStringIn(vip, udpSessionVips) {
-					err := c.ovnLegacyClient.DeleteLoadBalancerVip(vip, udpSessLb)
-					if err != nil {
-						klog.Errorf("failed to delete vip %s from udp session lb %s, %v", vip, udpSessLb, err)
-						return err
-					}
-				}
-			}
 		}
 	}
 
@@ -575,12 +730,20 @@ func (c *Controller) gcLoadBalancer() error {
 		return err
 	}
 
+	vpcLbs = append(vpcLbs, serviceLbs...)
 	klog.Infof("vpcLbs: %v", vpcLbs)
 	klog.Infof("ovnLbs: %v", ovnLbs)
+	var lbToDelete []string
 	for _, lb := range ovnLbs {
-		if util.ContainsString(vpcLbs, lb) {
-			continue
+		// the loadbalancers of vpcs are kept even if all their vips are deleted
+		if !util.ContainsString(vpcLbs, lb) {
+			lbToDelete = append(lbToDelete, lb)
 		}
+	}
+	if !c.gcDeleteAllowed("load balancers", lbToDelete, len(ovnLbs)) {
+		return nil
+	}
+	for _, lb := range lbToDelete {
 		klog.Infof("start to destroy load balancer %s", lb)
 		if err := c.ovnLegacyClient.DeleteLoadBalancer(lb); err != nil {
 			return err
@@ -589,6 +752,39 @@ func (c *Controller) gcLoadBalancer() error {
 	return nil
 }
 
+// gcLoadBalancerVips deletes the vips of the loadbalancer which belong to no service
+func (c *Controller) gcLoadBalancerVips(lb string, svcVips []string) error {
+	if lb == "" {
+		return nil
+	}
+	lbUuid, err := c.ovnLegacyClient.FindLoadbalancer(lb)
+	if err != nil {
+		klog.Errorf("failed to get lb %s, %v", lb, err)
+		return err
+	}
+	vips, err := c.ovnLegacyClient.GetLoadBalancerVips(lbUuid)
+	if err != nil {
+		klog.Errorf("failed to get vips of lb %s, %v", lb, err)
+		return err
+	}
+	var vipsToDelete []string
+	for vip := range vips {
+		if !util.IsStringIn(vip, svcVips) {
+			vipsToDelete = append(vipsToDelete, vip)
+		}
+	}
+	if !c.gcDeleteAllowed("vips of load balancer "+lb, vipsToDelete, len(vips)) {
+		return nil
+	}
+	for _, vip := range vipsToDelete {
+		if err = c.ovnLegacyClient.DeleteLoadBalancerVip(vip, lb); err != nil {
+			klog.Errorf("failed to delete vip %s from lb %s, %v", vip, lb, err)
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *Controller) gcPortGroup() error {
 	klog.Infof("start to gc network policy")
 	var npNames []string
@@ -634,14 +830,58 @@ func (c *Controller) gcPortGroup() error {
 		klog.Errorf("failed to list port-group, %v", err)
 		return err
 	}
+	var pgToDelete, pgNamesToDelete []string
 	for _, pg := range pgs {
 		if !c.config.EnableNP || !util.IsStringIn(fmt.Sprintf("%s/%s", pg.NpNamespace, pg.NpName), npNames) {
-			klog.Infof("gc port group %s", pg.Name)
-			if err := c.handleDeleteNp(fmt.Sprintf("%s/%s", pg.NpNamespace, pg.NpName)); err != nil {
-				klog.Errorf("failed to gc np %s/%s, %v", pg.NpNamespace, pg.NpName, err)
+			pgToDelete = append(pgToDelete, fmt.Sprintf("%s/%s", pg.NpNamespace, pg.NpName))
+			pgNamesToDelete = append(pgNamesToDelete, pg.Name)
+		}
+	}
+	// the address sets of the network policies are deleted along with the port groups
+	if !c.gcDeleteAllowed("port groups and their address sets", pgNamesToDelete, len(pgs)) {
+		return nil
+	}
+	for i, np := range pgToDelete {
+		klog.Infof("gc port group %s", pgNamesToDelete[i])
+		if err := c.handleDeleteNp(np); err != nil {
+			klog.Errorf("failed to gc np %s, %v", np, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// gcAclSource removes the source tags of acls whose network policy or security group no longer exists
+func (c *Controller) gcAclSource() error {
+	klog.Infof("start to gc acl sources")
+	acls, err := c.ovnLegacyClient.ListAclSources()
+	if err != nil {
+		klog.Errorf("failed to list acl sources, %v", err)
+		return err
+	}
+	for _, acl := range acls {
+		switch acl.Kind {
+		case ovs.AclSourceNetworkPolicy:
+			if c.config.EnableNP {
+				if _, err = c.npsLister.NetworkPolicies(acl.Namespace).Get(acl.Name); err == nil {
+					continue
+				} else if !k8serrors.IsNotFound(err) {
+					klog.Errorf("failed to get np %s/%s, %v", acl.Namespace, acl.Name, err)
+					return err
+				}
+			}
+		case ovs.AclSourceSecurityGroup:
+			if _, err = c.sgsLister.Get(acl.Name); err == nil {
+				continue
+			} else if !k8serrors.IsNotFound(err) {
+				klog.Errorf("failed to get sg %s, %v", acl.Name, err)
 				return err
 			}
 		}
+		klog.Infof("gc source of acl %s, %s %s/%s", acl.UUID, acl.Kind, acl.Namespace, acl.Name)
+		if err = c.ovnLegacyClient.RemoveAclSource(acl.UUID); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -659,6 +899,8 @@ func (c *Controller) gcStaticRoute() error {
 		return err
 	}
 	var keepStaticRoute bool
+	var routesToDelete []*ovs.StaticRoute
+	var routeNamesToDelete []string
 	for _, route := range routes {
 		keepStaticRoute = false
 		for _, item := range defaultVpc.Spec.StaticRoutes {
@@ -676,10 +918,17 @@ func (c *Controller) gcStaticRoute() error {
 				klog.Errorf("failed to get NatRule by LogicalIP %s, %v", route.CIDR, err)
 				continue
 			}
-			klog.Infof("gc static route %s %s %s", route.Policy, route.CIDR, route.NextHop)
-			if err := c.ovnLegacyClient.DeleteStaticRoute(route.CIDR, c.config.ClusterRouter); err != nil {
-				klog.Errorf("failed to delete stale route %s, %v", route.NextHop, err)
-			}
+			routesToDelete = append(routesToDelete, route)
+			routeNamesToDelete = append(routeNamesToDelete, fmt.Sprintf("%s %s %s", route.Policy, route.CIDR, route.NextHop))
+		}
+	}
+	if !c.gcDeleteAllowed("static routes", routeNamesToDelete, len(routes)) {
+		return nil
+	}
+	for _, route := range routesToDelete {
+		klog.Infof("gc static route %s %s %s", route.Policy, route.CIDR, route.NextHop)
+		if err := c.ovnLegacyClient.DeleteStaticRoute(route.CIDR, c.config.ClusterRouter); err != nil {
+			klog.Errorf("failed to delete stale route %s, %v", route.NextHop, err)
 		}
 	}
 	return nil
@@ -687,28 +936,58 @@ func (c *Controller) gcStaticRoute() error {
 
 func (c *Controller) gcChassis() error {
 	klog.Infof("start to gc chassis")
-	chassises, err := c.ovnLegacyClient.GetAllChassis()
+	chassises, err := c.ovnLegacyClient.GetAllChassisNodes()
 	if err != nil {
 		klog.Errorf("failed to get all chassis, %v", err)
+		return err
 	}
 	nodes, err := c.nodesLister.List(labels.Everything())
 	if err != nil {
 		klog.Errorf("failed to list nodes, %v", err)
 		return err
 	}
-	for _, chassis := range chassises {
-		matched := true
-		for _, node := range nodes {
-			if chassis == node.Annotations[util.ChassisAnnotation] {
-				matched = false
+	if len(nodes) == 0 {
+		// the node cache may be not synced yet, deleting all chassis is never expected
+		klog.Warning("no node found, skip gc chassis")
+		return nil
+	}
+
+	// chassis of nodes still registered are kept regardless of the node readiness
+	chassisInUse := make(map[string]bool, len(nodes))
+	nodeExists := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		nodeExists[node.Name] = true
+		if chassis := node.Annotations[util.ChassisAnnotation]; chassis != "" {
+			chassisInUse[chassis] = true
+		}
+	}
+	var chassisToDelete []string
+	for chassis, nodeNames := range chassises {
+		if chassisInUse[chassis] {
+			continue
+		}
+		var owner string
+		for _, name := range nodeNames {
+			if nodeExists[name] {
+				owner = name
 				break
 			}
 		}
-		if matched {
-			if err := c.ovnLegacyClient.DeleteChassisByName(chassis); err != nil {
-				klog.Errorf("failed to delete chassis %s %v", chassis, err)
-				return err
-			}
+		if owner != "" {
+			klog.V(3).Infof("chassis %s is not annotated to node %s, skip gc", chassis, owner)
+			continue
+		}
+
+		chassisToDelete = append(chassisToDelete, chassis)
+	}
+	if !c.gcDeleteAllowed("chassis", chassisToDelete, len(chassises)) {
+		return nil
+	}
+	for _, chassis := range chassisToDelete {
+		klog.Infof("gc chassis %s of node %v which no longer exists", chassis, chassises[chassis])
+		if err := c.ovnLegacyClient.DeleteChassisByName(chassis); err != nil {
+			klog.Errorf("failed to delete chassis %s %v", chassis, err)
+			return err
 		}
 	}
 	return nil
@@ -766,6 +1045,8 @@ func (c *Controller) gcLbSvcPods() error {
 		return err
 	}
 
+	var total int
+	var dpsToDelete []string
 	for _, ns := range nss {
 		dps, err := c.config.KubeClient.AppsV1().Deployments(ns.Name).List(context.Background(), metav1.ListOptions{})
 		if err != nil {
@@ -783,15 +1064,23 @@ func (c *Controller) gcLbSvcPods() error {
 				continue
 			}
 
+			total++
 			svcName := strings.TrimPrefix(dp.Name, "lb-svc-")
 			_, err := c.servicesLister.Services(ns.Name).Get(svcName)
 			if err != nil && k8serrors.IsNotFound(err) {
-				klog.Infof("gc lb svc deployment %s in ns %s", dp.Name, ns.Name)
-				if err := c.config.KubeClient.AppsV1().Deployments(ns.Name).Delete(context.Background(), dp.Name, metav1.DeleteOptions{}); err != nil {
-					if !k8serrors.IsNotFound(err) {
-						klog.Errorf("failed to delete lb svc deployment in namespace %s, %v", ns.Name, err)
-					}
-				}
+				dpsToDelete = append(dpsToDelete, fmt.Sprintf("%s/%s", ns.Name, dp.Name))
+			}
+		}
+	}
+	if !c.gcDeleteAllowed("lb svc deployments", dpsToDelete, total) {
+		return nil
+	}
+	for _, key := range dpsToDelete {
+		namespace, name, _ := cache.SplitMetaNamespaceKey(key)
+		klog.Infof("gc lb svc deployment %s in ns %s", name, namespace)
+		if err := c.config.KubeClient.AppsV1().Deployments(namespace).Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil {
+			if !k8serrors.IsNotFound(err) {
+				klog.Errorf("failed to delete lb svc deployment in namespace %s, %v", namespace, err)
 			}
 		}
 	}
@@ -820,18 +1109,21 @@ func (c *Controller) gcVpcDns() error {
 		return err
 	}
 
+	vdNames := make(map[string]bool, len(vds))
+	for _, vd := range vds {
+		vdNames[genVpcDnsDpName(vd.Name)] = true
+	}
+
+	var depsToDelete []string
 	for _, dep := range deps.Items {
-		canFind := false
-		for _, vd := range vds {
-			name := genVpcDnsDpName(vd.Name)
-			if dep.Name == name {
-				canFind = true
-				break
-			}
+		if !vdNames[dep.Name] {
+			depsToDelete = append(depsToDelete, dep.Name)
 		}
-		if !canFind {
+	}
+	if c.gcDeleteAllowed("vpc-dns deployments", depsToDelete, len(deps.Items)) {
+		for _, name := range depsToDelete {
 			err := c.config.KubeClient.AppsV1().Deployments(c.config.PodNamespace).Delete(context.Background(),
-				dep.Name, metav1.DeleteOptions{})
+				name, metav1.DeleteOptions{})
 			if err != nil {
 				klog.Errorf("failed to delete vpc-dns deployment, %s", err)
 				return err
@@ -847,22 +1139,21 @@ func (c *Controller) gcVpcDns() error {
 		return err
 	}
 
+	var slrsToDelete []string
 	for _, slr := range slrs.Items {
-		canFind := false
-		for _, vd := range vds {
-			name := genVpcDnsDpName(vd.Name)
-			if slr.Name == name {
-				canFind = true
-				break
-			}
+		if !vdNames[slr.Name] {
+			slrsToDelete = append(slrsToDelete, slr.Name)
 		}
-		if !canFind {
-			err := c.config.KubeOvnClient.KubeovnV1().SwitchLBRules().Delete(context.Background(),
-				slr.Name, metav1.DeleteOptions{})
-			if err != nil {
-				klog.Errorf("failed to delete vpc-dns SwitchLBRule, %s", err)
-				return err
-			}
+	}
+	if !c.gcDeleteAllowed("vpc-dns SwitchLBRules", slrsToDelete, len(slrs.Items)) {
+		return nil
+	}
+	for _, name := range slrsToDelete {
+		err := c.config.KubeOvnClient.KubeovnV1().SwitchLBRules().Delete(context.Background(),
+			name, metav1.DeleteOptions{})
+		if err != nil {
+			klog.Errorf("failed to delete vpc-dns SwitchLBRule, %s", err)
+			return err
 		}
 	}
 	return nil
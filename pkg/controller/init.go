@@ -301,9 +301,10 @@ func (c *Controller) InitIPAM() error {
 		return err
 	}
 	for _, subnet := range subnets {
-		if err := c.ipam.AddOrUpdateSubnet(subnet.Name, subnet.Spec.CIDRBlock, subnet.Spec.Gateway, subnet.Spec.ExcludeIps); err != nil {
+		if err := c.ipam.AddOrUpdateSubnet(subnet.Name, subnet.Spec.CIDRBlock, subnet.Spec.Gateway, subnet.Spec.ExcludeIps, subnet.Spec.ExtraCIDRBlocks...); err != nil {
 			klog.Errorf("failed to init subnet %s: %v", subnet.Name, err)
 		}
+		c.ipam.SetSubnetMacOUI(subnet.Name, subnet.Spec.MacOUI)
 	}
 
 	result, err := c.ovnLegacyClient.CustomFindEntity("logical_switch_port", []string{"name"}, `external-ids:vendor{<}""`)
@@ -335,13 +336,7 @@ func (c *Controller) InitIPAM() error {
 			continue
 		}
 
-		var ipamKey string
-		if ip.Spec.Namespace != "" {
-			ipamKey = fmt.Sprintf("%s/%s", ip.Spec.Namespace, ip.Spec.PodName)
-		} else {
-			ipamKey = fmt.Sprintf("node-%s", ip.Spec.PodName)
-		}
-		if _, _, _, err = c.ipam.GetStaticAddress(ipamKey, ip.Name, ip.Spec.IPAddress, ip.Spec.MacAddress, ip.Spec.Subnet, true); err != nil {
+		if _, _, _, err = c.ipam.GetStaticAddress(ipCRIPAMKey(ip), ip.Name, ip.Spec.IPAddress, ip.Spec.MacAddress, ip.Spec.Subnet, true); err != nil {
 			klog.Errorf("failed to init IPAM from IP CR %s: %v", ip.Name, err)
 		}
 	}
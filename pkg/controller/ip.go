@@ -17,6 +17,9 @@ func (c *Controller) enqueueAddOrDelIP(obj interface{}) {
 		klog.V(3).Infof("enqueue update status subnet %s", as)
 		c.updateSubnetStatusQueue.Add(as)
 	}
+	c.enqueueVpcDnsReverse(ipObj.Spec.Subnet)
+	// the ip CRs being deleted are listed on startup, so the pending releases survive controller restarts
+	c.enqueueReleaseExternalIP(ipObj)
 }
 
 func (c *Controller) enqueueUpdateIP(old, new interface{}) {
@@ -29,4 +32,5 @@ func (c *Controller) enqueueUpdateIP(old, new interface{}) {
 		klog.V(3).Infof("enqueue update status subnet %s", as)
 		c.updateSubnetStatusQueue.Add(as)
 	}
+	c.enqueueReleaseExternalIP(ipObj)
 }
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+)
+
+const (
+	ipamDivergenceMissingInIPAM = "missing_in_ipam"
+	ipamDivergenceMissingCRD    = "missing_crd"
+	ipamDivergenceOwnerMismatch = "owner_mismatch"
+)
+
+// ipamDivergence is an address whose owner in the ipam mismatches the ip CR
+type ipamDivergence struct {
+	kind   string
+	subnet string
+	ip     string
+	// ipamOwner and ipamNic are the owner in the ipam, empty if the address is not allocated
+	ipamOwner string
+	ipamNic   string
+	// crOwner and crName are the owner recorded by the ip CR, empty if there is no ip CR
+	crOwner string
+	crName  string
+}
+
+// ipCRIPAMKey returns the owner of the addresses of the ip CR in the ipam
+func ipCRIPAMKey(ip *kubeovnv1.IP) string {
+	if ip.Spec.Namespace != "" {
+		return fmt.Sprintf("%s/%s", ip.Spec.Namespace, ip.Spec.PodName)
+	}
+	return fmt.Sprintf("node-%s", ip.Spec.PodName)
+}
+
+// checkIPAMDivergence compares the in-memory ipam with the ip CRs like initSyncCrdIPs and InitIPAM do on startup.
+// Both are read from memory, so the check is cheap. An address is only reported when the mismatch is found by two
+// consecutive checks, which filters out the pods being allocated or released in between
+func (c *Controller) checkIPAMDivergence() {
+	ips, err := c.ipsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list ips, %v", err)
+		return
+	}
+	subnets, err := c.subnetsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list subnets, %v", err)
+		return
+	}
+
+	subnetIPs := make(map[string][]*kubeovnv1.IP, len(subnets))
+	for _, ip := range ips {
+		if ip.DeletionTimestamp != nil {
+			continue
+		}
+		subnetIPs[ip.Spec.Subnet] = append(subnetIPs[ip.Spec.Subnet], ip)
+	}
+
+	found := make(map[ipamDivergence]bool)
+	for _, subnet := range subnets {
+		allocations, err := c.ipam.ListSubnetAllocations(subnet.Name)
+		if err != nil {
+			// the subnet is not initialized yet
+			continue
+		}
+		allocated := make(map[string]int, len(allocations))
+		for i, allocation := range allocations {
+			if allocation.Nic != "" {
+				allocated[allocation.IP] = i
+			}
+		}
+
+		recorded := make(map[string]bool, len(allocated))
+		for _, ip := range subnetIPs[subnet.Name] {
+			owner := ipCRIPAMKey(ip)
+			for _, addr := range strings.Split(ip.Spec.IPAddress, ",") {
+				if addr == "" {
+					continue
+				}
+				recorded[addr] = true
+				d := ipamDivergence{subnet: subnet.Name, ip: addr, crOwner: owner, crName: ip.Name}
+				i, ok := allocated[addr]
+				switch {
+				case !ok:
+					d.kind = ipamDivergenceMissingInIPAM
+				case allocations[i].Owner != owner:
+					d.kind = ipamDivergenceOwnerMismatch
+					d.ipamOwner, d.ipamNic = allocations[i].Owner, allocations[i].Nic
+				default:
+					continue
+				}
+				found[d] = true
+			}
+		}
+		for addr, i := range allocated {
+			// only pods are expected to have ip CRs, vips and eips are not recorded by them
+			if recorded[addr] || !strings.Contains(allocations[i].Owner, "/") {
+				continue
+			}
+			found[ipamDivergence{
+				kind:      ipamDivergenceMissingCRD,
+				subnet:    subnet.Name,
+				ip:        addr,
+				ipamOwner: allocations[i].Owner,
+				ipamNic:   allocations[i].Nic,
+			}] = true
+		}
+	}
+
+	metricIPAMCrdDivergence.Reset()
+	for _, subnet := range subnets {
+		for _, kind := range []string{ipamDivergenceMissingInIPAM, ipamDivergenceMissingCRD, ipamDivergenceOwnerMismatch} {
+			metricIPAMCrdDivergence.WithLabelValues(subnet.Name, kind).Set(0)
+		}
+	}
+	for d := range found {
+		if !c.ipamDivergences[d] {
+			continue
+		}
+		metricIPAMCrdDivergence.WithLabelValues(d.subnet, d.kind).Inc()
+		klog.Warningf("ipam diverges from ip CRs in subnet %s: address %s is owned by %q in ipam and %q by ip CR %q",
+			d.subnet, d.ip, d.ipamOwner, d.crOwner, d.crName)
+		if c.config.AutoCorrectIPAMDivergence {
+			c.correctIPAMDivergence(d)
+		}
+	}
+	c.ipamDivergences = found
+}
+
+// correctIPAMDivergence restores the addresses of the ip CRs missing in the ipam and releases the addresses of the
+// deleted pods without ip CRs, the owner mismatches are left to the administrators as it's unknown which one is right
+func (c *Controller) correctIPAMDivergence(d ipamDivergence) {
+	switch d.kind {
+	case ipamDivergenceMissingInIPAM:
+		ip, err := c.ipsLister.Get(d.crName)
+		if err != nil {
+			klog.Errorf("failed to get ip %s, %v", d.crName, err)
+			return
+		}
+		if _, _, _, err = c.ipam.GetStaticAddress(d.crOwner, ip.Name, ip.Spec.IPAddress, ip.Spec.MacAddress, ip.Spec.Subnet, true); err != nil {
+			klog.Errorf("failed to restore address %s of ip %s to ipam, %v", ip.Spec.IPAddress, ip.Name, err)
+			return
+		}
+		klog.Infof("restored address %s of ip %s to ipam", ip.Spec.IPAddress, ip.Name)
+	case ipamDivergenceMissingCRD:
+		exists, err := c.ipamOwnerPodExists(d.ipamOwner)
+		if err != nil || exists {
+			return
+		}
+		c.ipam.ReleaseAddressByNic(d.ipamOwner, d.ipamNic, d.subnet)
+		klog.Infof("released address %s of deleted pod %s without ip CR from ipam", d.ip, d.ipamOwner)
+	}
+}
+
+// ipamOwnerPodExists checks whether any pod is the owner in the ipam, which is the vm name for the pods of vms
+func (c *Controller) ipamOwnerPodExists(owner string) (bool, error) {
+	namespace, name, _ := strings.Cut(owner, "/")
+	pods, err := c.podsLister.Pods(namespace).List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list pods in namespace %s, %v", namespace, err)
+		return false, err
+	}
+	for _, pod := range pods {
+		if c.getNameByPod(pod) == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -18,10 +18,74 @@ import (
 	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
+// isNamespaceInScope checks whether the pods in the namespace are managed by the controller. A namespace not found
+// is regarded in scope, so that the pods deleted along with their namespace are always cleaned up
+func (c *Controller) isNamespaceInScope(name string) bool {
+	if c.config.NamespaceSelector == nil {
+		return true
+	}
+	ns, err := c.namespacesLister.Get(name)
+	if err != nil {
+		return true
+	}
+	return c.config.NamespaceSelector.Matches(labels.Set(ns.Labels))
+}
+
+// isPodInScope is the filter of the pod add and update events
+func (c *Controller) isPodInScope(obj interface{}) bool {
+	if c.config.NamespaceSelector == nil {
+		return true
+	}
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return true
+	}
+	return c.isNamespaceInScope(pod.Namespace)
+}
+
+// enqueueAddPodInScope drops the pod add events out of the namespace scope. The pods can't be selected by the
+// labels of their namespaces when listing and watching, so the pod informer still caches all the pods, which are
+// also needed by network policies and services. The delete events are never filtered, as the addresses, logical
+// switch ports and ip crs allocated to a pod before its namespace left the scope must be released when it's deleted.
+func (c *Controller) enqueueAddPodInScope(obj interface{}) {
+	if c.isPodInScope(obj) {
+		c.enqueueAddPod(obj)
+	}
+}
+
+func (c *Controller) enqueueUpdatePodInScope(oldObj, newObj interface{}) {
+	if c.isPodInScope(newObj) {
+		c.enqueueUpdatePod(oldObj, newObj)
+	}
+}
+
+// checkNamespaceScope validates the namespace selector against the existing namespaces at startup
+func (c *Controller) checkNamespaceScope() error {
+	if c.config.NamespaceSelector == nil {
+		return nil
+	}
+	namespaces, err := c.namespacesLister.List(c.config.NamespaceSelector)
+	if err != nil {
+		klog.Errorf("failed to list namespaces, %v", err)
+		return err
+	}
+	if len(namespaces) == 0 {
+		klog.Warningf("no namespace matches the namespace selector %s, no pod is managed by kube-ovn", c.config.NamespaceSelector)
+	}
+	if c.config.PodNamespace != "" && !c.isNamespaceInScope(c.config.PodNamespace) {
+		klog.Warningf("namespace %s of kube-ovn does not match the namespace selector %s, pods of kube-ovn like the pingers and vpc nat gateways are not managed", c.config.PodNamespace, c.config.NamespaceSelector)
+	}
+	klog.Infof("scoped mode is enabled, managing pods in %d namespaces matching %s", len(namespaces), c.config.NamespaceSelector)
+	return nil
+}
+
 func (c *Controller) enqueueAddNamespace(obj interface{}) {
 	if !c.isLeader() {
 		return
 	}
+	if !c.isNamespaceInScope(obj.(*v1.Namespace).Name) {
+		return
+	}
 	if c.config.EnableNP {
 		for _, np := range c.namespaceMatchNetworkPolicies(obj.(*v1.Namespace)) {
 			c.updateNpQueue.Add(np)
@@ -34,6 +98,9 @@ func (c *Controller) enqueueAddNamespace(obj interface{}) {
 		return
 	}
 	c.addNamespaceQueue.Add(key)
+	if obj.(*v1.Namespace).Annotations[util.AggregateEgressRateAnnotation] != "" {
+		c.syncNamespaceQosQueue.Add(key)
+	}
 }
 
 func (c *Controller) enqueueDeleteNamespace(obj interface{}) {
@@ -41,8 +108,26 @@ func (c *Controller) enqueueDeleteNamespace(obj interface{}) {
 		return
 	}
 
+	var ns *v1.Namespace
+	switch t := obj.(type) {
+	case *v1.Namespace:
+		ns = t
+	case cache.DeletedFinalStateUnknown:
+		n, ok := t.Obj.(*v1.Namespace)
+		if !ok {
+			klog.Warningf("unexpected object type: %T", t.Obj)
+			return
+		}
+		ns = n
+	default:
+		klog.Warningf("unexpected type: %T", obj)
+		return
+	}
+	// always clean up the aggregate egress rate in case the annotation was removed while the controller was down
+	c.syncNamespaceQosQueue.Add(ns.Name)
+
 	if c.config.EnableNP {
-		for _, np := range c.namespaceMatchNetworkPolicies(obj.(*v1.Namespace)) {
+		for _, np := range c.namespaceMatchNetworkPolicies(ns) {
 			c.updateNpQueue.Add(np)
 		}
 	}
@@ -57,6 +142,30 @@ func (c *Controller) enqueueUpdateNamespace(old, new interface{}) {
 	if oldNs.ResourceVersion == newNs.ResourceVersion {
 		return
 	}
+	if c.config.NamespaceSelector != nil {
+		if !c.config.NamespaceSelector.Matches(labels.Set(newNs.Labels)) {
+			if c.config.NamespaceSelector.Matches(labels.Set(oldNs.Labels)) {
+				// the existing pods keep their network, the updates of them are ignored from now on while
+				// their deletions are still handled to release the resources
+				klog.Infof("namespace %s leaves scope, the resources of its existing pods are released when they are deleted", newNs.Name)
+			}
+			return
+		}
+		if !c.config.NamespaceSelector.Matches(labels.Set(oldNs.Labels)) {
+			// the existing pods of the namespace are ignored until it comes into scope
+			klog.Infof("namespace %s comes into scope", newNs.Name)
+			c.enqueueAddNamespace(newNs)
+			pods, err := c.podsLister.Pods(newNs.Name).List(labels.Everything())
+			if err != nil {
+				klog.Errorf("failed to list pods in namespace %s, %v", newNs.Name, err)
+				return
+			}
+			for _, pod := range pods {
+				c.enqueueAddPod(pod)
+			}
+			return
+		}
+	}
 
 	if c.config.EnableNP && !reflect.DeepEqual(oldNs.Labels, newNs.Labels) {
 		oldNp := c.namespaceMatchNetworkPolicies(oldNs)
@@ -66,6 +175,10 @@ func (c *Controller) enqueueUpdateNamespace(old, new interface{}) {
 		}
 	}
 
+	if oldNs.Annotations[util.AggregateEgressRateAnnotation] != newNs.Annotations[util.AggregateEgressRateAnnotation] {
+		c.syncNamespaceQosQueue.Add(newNs.Name)
+	}
+
 	// in case annotations are removed by other controllers
 	if newNs.Annotations == nil || newNs.Annotations[util.LogicalSwitchAnnotation] == "" {
 		klog.Warningf("no logical switch annotation for ns %s", newNs.Name)
@@ -155,13 +268,18 @@ func (c *Controller) handleAddNamespace(key string) error {
 			klog.Errorf("failed to list vpc %v", err)
 			return err
 		}
+		var vpcBound bool
 		for _, v := range vpcs {
 			if util.ContainsString(v.Spec.Namespaces, key) {
-				vpc = v
+				vpc, vpcBound = v, true
 				break
 			}
 		}
 
+		if c.config.DisableNsDefaultSubnet && !vpcBound && !isSystemNamespace(key) {
+			return c.unbindNamespace(cachedNs)
+		}
+
 		if vpc.Status.DefaultLogicalSwitch != "" {
 			ls = vpc.Status.DefaultLogicalSwitch
 		} else {
@@ -200,3 +318,31 @@ func (c *Controller) handleAddNamespace(key string) error {
 	}
 	return err
 }
+
+func isSystemNamespace(name string) bool {
+	return name == metav1.NamespaceSystem || name == metav1.NamespacePublic || name == v1.NamespaceNodeLease
+}
+
+// unbindNamespace removes the subnet annotations of a namespace which is not bound to any subnet or vpc,
+// pods in the namespace can not get an address until the namespace is explicitly assigned
+func (c *Controller) unbindNamespace(cachedNs *v1.Namespace) error {
+	if cachedNs.Annotations[util.LogicalSwitchAnnotation] == "" {
+		return nil
+	}
+	namespace := cachedNs.DeepCopy()
+	delete(namespace.Annotations, util.LogicalSwitchAnnotation)
+	delete(namespace.Annotations, util.CidrAnnotation)
+	delete(namespace.Annotations, util.ExcludeIpsAnnotation)
+
+	patch, err := util.GenerateStrategicMergePatchPayload(cachedNs, namespace)
+	if err != nil {
+		return err
+	}
+	if _, err = c.config.KubeClient.CoreV1().Namespaces().Patch(context.Background(), namespace.Name,
+		types.StrategicMergePatchType, patch, metav1.PatchOptions{}, ""); err != nil {
+		klog.Errorf("patch namespace %s failed %v", namespace.Name, err)
+		return err
+	}
+	klog.Infof("namespace %s is not bound to any subnet", namespace.Name)
+	return nil
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeovn/kube-ovn/pkg/ovs"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+// enqueueNamespaceQos enqueues the namespace to sync its aggregate egress rate if the rate is set
+func (c *Controller) enqueueNamespaceQos(namespace string) {
+	ns, err := c.namespacesLister.Get(namespace)
+	if err != nil || ns.Annotations[util.AggregateEgressRateAnnotation] == "" {
+		return
+	}
+	klog.V(3).Infof("enqueue sync aggregate egress rate of namespace %s", namespace)
+	c.syncNamespaceQosQueue.Add(namespace)
+}
+
+func (c *Controller) runSyncNamespaceQosWorker() {
+	for c.processNextWorkItem("syncNamespaceQos", c.syncNamespaceQosQueue, c.handleSyncNamespaceQos) {
+	}
+}
+
+// handleSyncNamespaceQos caps the total egress traffic of the pods in the namespace with one qos rule matching
+// the port group of the pods, the qos and the port group are removed once the annotation or the namespace is gone
+func (c *Controller) handleSyncNamespaceQos(key string) error {
+	var rate int
+	ns, err := c.namespacesLister.Get(key)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		klog.Errorf("failed to get namespace %s, %v", key, err)
+		return err
+	}
+	if err == nil && ns.DeletionTimestamp == nil {
+		if rate, err = util.ParseBandwidth(ns.Annotations[util.AggregateEgressRateAnnotation], 0); err != nil {
+			msg := fmt.Sprintf("invalid annotation %s: %v", util.AggregateEgressRateAnnotation, err)
+			klog.Errorf("namespace %s: %s", key, msg)
+			c.recorder.Event(ns, v1.EventTypeWarning, "ValidateAggregateEgressRateFailed", msg)
+			return nil
+		}
+	}
+
+	var ports, switches []string
+	if rate != 0 {
+		pods, err := c.podsLister.Pods(key).List(labels.Everything())
+		if err != nil {
+			klog.Errorf("failed to list pods in namespace %s, %v", key, err)
+			return err
+		}
+		for _, pod := range pods {
+			if pod.Spec.HostNetwork || !isPodAlive(pod) ||
+				pod.Annotations[fmt.Sprintf(util.AllocatedAnnotationTemplate, util.OvnProvider)] != "true" {
+				continue
+			}
+			ports = append(ports, ovs.PodNameToPortName(c.getNameByPod(pod), pod.Namespace, util.OvnProvider))
+			if ls := pod.Annotations[util.LogicalSwitchAnnotation]; ls != "" && !util.ContainsString(switches, ls) {
+				switches = append(switches, ls)
+			}
+		}
+	}
+
+	// the rate of the annotation is in Mbit/s while the rate of ovn qos is in kbps
+	if err = c.ovnLegacyClient.SetNamespaceAggregateRate(key, ports, switches, rate*1000); err != nil {
+		klog.Errorf("failed to set aggregate egress rate of namespace %s, %v", key, err)
+		return err
+	}
+	return nil
+}
@@ -1,6 +1,22 @@
 package controller
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/kubeovn/kube-ovn/pkg/ipam"
+)
+
+// reasons of ipam allocation failures, keep the label values bounded
+const (
+	ipamFailureExhausted       = "exhausted"
+	ipamFailureConflict        = "conflict"
+	ipamFailureInvalidStaticIP = "invalid-static-ip"
+	ipamFailureOutOfRange      = "out-of-range"
+	ipamFailureMacExhausted    = "mac-exhausted"
+	ipamFailureOther           = "other"
+)
 
 var (
 	metricSubnetAvailableIPs = prometheus.NewGaugeVec(
@@ -24,9 +40,167 @@ var (
 			"protocol",
 			"subnet_cidr",
 		})
+
+	metricSubnetQuarantinedIPs = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "subnet_quarantined_ip_count",
+			Help: "The num of released ip address in subnet waiting for the release delay to elapse, which are available soon.",
+		},
+		[]string{
+			"subnet_name",
+			"protocol",
+			"subnet_cidr",
+		})
+
+	metricPreWorkerBlockedSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pre_worker_blocked_seconds",
+			Help: "The time in seconds the controller has been waiting for a resource to be ready before starting workers.",
+		},
+		[]string{
+			"stage",
+			"resource",
+		})
+
+	metricVpcNatGwHealthy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kube_ovn_vpc_natgw_healthy",
+			Help: "Whether the dataplane of the vpc nat gateway passed the health probe, 1 for healthy and 0 for unhealthy.",
+		},
+		[]string{
+			"vpc_nat_gateway",
+			"vpc",
+		})
+
+	metricIPAMAllocationFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kube_ovn_ipam_allocation_failures",
+			Help: "The num of ip address allocation failures in subnet by reason.",
+		},
+		[]string{
+			"subnet",
+			"reason",
+		})
+
+	metricLspAddressDrift = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kube_ovn_lsp_address_drift",
+			Help: "Whether the addresses of the logical switch port drift from the ip record allocated by ipam, 1 for drifted.",
+		},
+		[]string{
+			"logical_switch_port",
+			"logical_switch",
+		})
+
+	metricIPAMCrdDivergence = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kube_ovn_ipam_crd_divergence",
+			Help: "The num of addresses whose owners in the in-memory ipam mismatch the ip CRs, confirmed by two consecutive checks.",
+		},
+		[]string{
+			"subnet",
+			"type",
+		})
+
+	metricPodPortNotUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kube_ovn_pod_port_not_up",
+			Help: "Whether the logical switch port of the pod is not up in the timeout after the pod is created, 1 for not up.",
+		},
+		[]string{
+			"namespace",
+			"pod",
+			"node",
+		})
+
+	metricLbBackendCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kube_ovn_lb_backend_count",
+			Help: "The num of backends of the service vip in the ovn load balancer, 0 means the vip has no backend.",
+		},
+		[]string{
+			"namespace",
+			"service",
+			"protocol",
+			"vip",
+		})
+
+	metricNodeRouteRepairs = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kube_ovn_node_route_repairs",
+			Help: "The num of missing logical router policies of the node re-added by the controller.",
+		},
+		[]string{
+			"node",
+		})
+
+	metricLogicalRouterCount = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kube_ovn_logical_router_count",
+			Help: "The num of logical routers in the ovn northbound database.",
+		})
+
+	metricLogicalSwitchCount = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kube_ovn_logical_switch_count",
+			Help: "The num of logical switches in the ovn northbound database.",
+		})
+
+	metricLogicalSwitchPortCount = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kube_ovn_logical_switch_port_count",
+			Help: "The num of logical switch ports of all types in the ovn northbound database.",
+		})
+
+	metricSubnetReadySeconds = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "kube_ovn_subnet_ready_seconds",
+			Help:    "The seconds from the creation of the subnet to its logical switch and ipam being initialized.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+		})
 )
 
 func registerMetrics() {
 	prometheus.MustRegister(metricSubnetAvailableIPs)
 	prometheus.MustRegister(metricSubnetUsedIPs)
+	prometheus.MustRegister(metricSubnetQuarantinedIPs)
+	prometheus.MustRegister(metricPreWorkerBlockedSeconds)
+	prometheus.MustRegister(metricVpcNatGwHealthy)
+	prometheus.MustRegister(metricIPAMAllocationFailures)
+	prometheus.MustRegister(metricLspAddressDrift)
+	prometheus.MustRegister(metricIPAMCrdDivergence)
+	prometheus.MustRegister(metricPodPortNotUp)
+	prometheus.MustRegister(metricLbBackendCount)
+	prometheus.MustRegister(metricNodeRouteRepairs)
+	prometheus.MustRegister(metricSubnetReadySeconds)
+	prometheus.MustRegister(metricLogicalRouterCount)
+	prometheus.MustRegister(metricLogicalSwitchCount)
+	prometheus.MustRegister(metricLogicalSwitchPortCount)
+}
+
+func ipamFailureReason(err error) string {
+	switch {
+	case errors.Is(err, ipam.ErrNoAvailable):
+		return ipamFailureExhausted
+	case errors.Is(err, ipam.ErrConflict):
+		return ipamFailureConflict
+	case errors.Is(err, ipam.ErrOutOfRange):
+		return ipamFailureOutOfRange
+	case errors.Is(err, ipam.ErrNoAvailableMac):
+		return ipamFailureMacExhausted
+	default:
+		return ipamFailureOther
+	}
+}
+
+func recordIPAMFailure(subnet string, err error) {
+	metricIPAMAllocationFailures.WithLabelValues(subnet, ipamFailureReason(err)).Inc()
+}
+
+func recordInvalidStaticIP(subnet string) {
+	metricIPAMAllocationFailures.WithLabelValues(subnet, ipamFailureInvalidStaticIP).Inc()
+}
+
+func deleteLbBackendCount(namespace, name string) {
+	metricLbBackendCount.DeletePartialMatch(prometheus.Labels{"namespace": namespace, "service": name})
 }
@@ -12,6 +12,7 @@ import (
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
@@ -184,18 +185,11 @@ func (c *Controller) handleUpdateNp(key string) error {
 		logEnable = true
 	}
 
-	npName := np.Name
-	nameArray := []rune(np.Name)
-	if !unicode.IsLetter(nameArray[0]) {
-		npName = "np" + np.Name
-	}
-
-	// TODO: ovn acl doesn't support address_set name with '-', now we replace '-' by '.'.
-	// This may cause conflict if two np with name test-np and test.np. Maybe hash is a better solution,
-	// but we do not want to lost the readability now.
-	pgName := strings.Replace(fmt.Sprintf("%s.%s", npName, np.Namespace), "-", ".", -1)
+	npName := getNpName(np.Name)
+	pgName := getNpPortGroupName(np.Namespace, npName)
 	ingressAllowAsNamePrefix := strings.Replace(fmt.Sprintf("%s.%s.ingress.allow", npName, np.Namespace), "-", ".", -1)
 	ingressExceptAsNamePrefix := strings.Replace(fmt.Sprintf("%s.%s.ingress.except", npName, np.Namespace), "-", ".", -1)
+	ingressNamedAsNamePrefix := strings.Replace(fmt.Sprintf("%s.%s.ingress.named", npName, np.Namespace), "-", ".", -1)
 	egressAllowAsNamePrefix := strings.Replace(fmt.Sprintf("%s.%s.egress.allow", npName, np.Namespace), "-", ".", -1)
 	egressExceptAsNamePrefix := strings.Replace(fmt.Sprintf("%s.%s.egress.except", npName, np.Namespace), "-", ".", -1)
 
@@ -290,6 +284,30 @@ func (c *Controller) handleUpdateNp(key string) error {
 						excepts = append(excepts, except...)
 					}
 				}
+				var ingressPorts []netv1.NetworkPolicyPort
+				var namedPorts []npNamedPort
+				if ingressPorts, namedPorts, err = c.resolveNamedPorts(np, npr.Ports, protocol); err != nil {
+					klog.Errorf("failed to resolve named ports of np %s, %v", key, err)
+					return err
+				}
+				var ingressNamedPorts []ovs.NpNamedPort
+				for pidx, namedPort := range namedPorts {
+					asName := fmt.Sprintf("%s.%s.%d.%d", ingressNamedAsNamePrefix, protocol, idx, pidx)
+					if err = c.ovnLegacyClient.CreateNpAddressSet(asName, np.Namespace, npName, "ingress"); err != nil {
+						klog.Errorf("failed to create address_set %s, %v", asName, err)
+						return err
+					}
+					if err = c.ovnLegacyClient.SetAddressesToAddressSet(namedPort.addresses, asName); err != nil {
+						klog.Errorf("failed to set ingress named port address_set, %v", err)
+						return err
+					}
+					ingressNamedPorts = append(ingressNamedPorts, ovs.NpNamedPort{Protocol: string(namedPort.protocol), Port: namedPort.port, AddressSet: asName})
+				}
+				if len(npr.Ports) != 0 && len(ingressPorts) == 0 && len(ingressNamedPorts) == 0 {
+					// none of the selected pods exposes the named ports, the rule allows nothing
+					klog.Warningf("named ports of np %s ingress rule %d are not exposed by any selected pod", key, idx)
+					allows, excepts = nil, nil
+				}
 				klog.Infof("UpdateNp Ingress, allows is %v, excepts is %v, log %v", allows, excepts, logEnable)
 				if err = c.ovnLegacyClient.CreateNpAddressSet(ingressAllowAsName, np.Namespace, npName, "ingress"); err != nil {
 					klog.Errorf("failed to create address_set %s, %v", ingressAllowAsName, err)
@@ -310,9 +328,9 @@ func (c *Controller) handleUpdateNp(key string) error {
 				}
 
 				if len(allows) != 0 || len(excepts) != 0 {
-					ingressAclCmd = c.ovnLegacyClient.CombineIngressACLCmd(pgName, ingressAllowAsName, ingressExceptAsName, protocol, npr.Ports, logEnable, ingressAclCmd, idx)
+					ingressAclCmd = c.ovnLegacyClient.CombineIngressACLCmd(pgName, ingressAllowAsName, ingressExceptAsName, protocol, ingressPorts, ingressNamedPorts, logEnable, ingressAclCmd, idx)
 				} else {
-					ingressAclCmd = c.ovnLegacyClient.CombineIngressACLCmd(pgName, ingressAllowAsName, ingressExceptAsName, protocol, []netv1.NetworkPolicyPort{}, logEnable, ingressAclCmd, idx)
+					ingressAclCmd = c.ovnLegacyClient.CombineIngressACLCmd(pgName, ingressAllowAsName, ingressExceptAsName, protocol, []netv1.NetworkPolicyPort{}, nil, logEnable, ingressAclCmd, idx)
 				}
 			}
 			if len(np.Spec.Ingress) == 0 {
@@ -328,7 +346,7 @@ func (c *Controller) handleUpdateNp(key string) error {
 					return err
 				}
 				ingressPorts := []netv1.NetworkPolicyPort{}
-				ingressAclCmd = c.ovnLegacyClient.CombineIngressACLCmd(pgName, ingressAllowAsName, ingressExceptAsName, protocol, ingressPorts, logEnable, ingressAclCmd, 0)
+				ingressAclCmd = c.ovnLegacyClient.CombineIngressACLCmd(pgName, ingressAllowAsName, ingressExceptAsName, protocol, ingressPorts, nil, logEnable, ingressAclCmd, 0)
 			}
 
 			klog.Infof("create ingress acl cmd is: %v", ingressAclCmd)
@@ -348,8 +366,12 @@ func (c *Controller) handleUpdateNp(key string) error {
 			klog.Errorf("failed to list address_set, %v", err)
 			return err
 		}
-		// The format of asName is like "test.network.policy.test.ingress.except.0" or "test.network.policy.test.ingress.allow.0" for ingress
+		// The format of asName is like "test.network.policy.test.ingress.except.0" or "test.network.policy.test.ingress.allow.0" for ingress,
+		// the named port address sets like "test.network.policy.test.ingress.named.IPv4.0.1" end with the port index and are all created above
 		for _, asName := range asNames {
+			if strings.HasPrefix(asName, ingressNamedAsNamePrefix+".") {
+				continue
+			}
 			values := strings.Split(asName, ".")
 			if len(values) <= 1 {
 				continue
@@ -531,6 +553,10 @@ func (c *Controller) handleUpdateNp(key string) error {
 		klog.Errorf("failed to create gateway acl, %v", err)
 		return err
 	}
+	if err = c.ovnLegacyClient.SetPortGroupAclSource(pgName, ovs.AclSourceNetworkPolicy, np.Namespace, np.Name); err != nil {
+		klog.Errorf("failed to set source of np %s acls, %v", key, err)
+		return err
+	}
 	return nil
 }
 
@@ -541,12 +567,16 @@ func (c *Controller) handleDeleteNp(key string) error {
 		return nil
 	}
 
-	pgName := strings.Replace(fmt.Sprintf("%s.%s", name, namespace), "-", ".", -1)
+	// the name must be converted the same way as the one used to create the port group and address sets,
+	// otherwise they are leaked when the name of the policy does not start with a letter
+	npName := getNpName(name)
+	pgName := getNpPortGroupName(namespace, npName)
 	if err := c.ovnLegacyClient.DeletePortGroup(pgName); err != nil {
 		klog.Errorf("failed to delete np %s port group, %v", key, err)
+		return err
 	}
 
-	svcAsNames, err := c.ovnLegacyClient.ListNpAddressSet(namespace, name, "service")
+	svcAsNames, err := c.ovnLegacyClient.ListNpAddressSet(namespace, npName, "service")
 	if err != nil {
 		klog.Errorf("failed to list svc address_set, %v", err)
 		return err
@@ -558,7 +588,7 @@ func (c *Controller) handleDeleteNp(key string) error {
 		}
 	}
 
-	ingressAsNames, err := c.ovnLegacyClient.ListNpAddressSet(namespace, name, "ingress")
+	ingressAsNames, err := c.ovnLegacyClient.ListNpAddressSet(namespace, npName, "ingress")
 	if err != nil {
 		klog.Errorf("failed to list address_set, %v", err)
 		return err
@@ -570,7 +600,7 @@ func (c *Controller) handleDeleteNp(key string) error {
 		}
 	}
 
-	egressAsNames, err := c.ovnLegacyClient.ListNpAddressSet(namespace, name, "egress")
+	egressAsNames, err := c.ovnLegacyClient.ListNpAddressSet(namespace, npName, "egress")
 	if err != nil {
 		klog.Errorf("failed to list address_set, %v", err)
 		return err
@@ -584,6 +614,73 @@ func (c *Controller) handleDeleteNp(key string) error {
 	return nil
 }
 
+// getNpName returns the name used in the ovn resources of the network policy, which must start with a letter
+func getNpName(name string) string {
+	if !unicode.IsLetter([]rune(name)[0]) {
+		return "np" + name
+	}
+	return name
+}
+
+// getNpPortGroupName returns the name of the port group holding the ports selected by the network policy, the acls
+// of the policy are attached to the port group rather than the addresses of the selected pods
+func getNpPortGroupName(namespace, npName string) string {
+	// TODO: ovn acl doesn't support address_set name with '-', now we replace '-' by '.'.
+	// This may cause conflict if two np with name test-np and test.np. Maybe hash is a better solution,
+	// but we do not want to lost the readability now.
+	return strings.Replace(fmt.Sprintf("%s.%s", npName, namespace), "-", ".", -1)
+}
+
+// checkNpPortGroups validates the members of the port groups of network policies, the selected pods missed by
+// the event handlers, e.g. due to a failed reconcile, are added and the pods no longer selected are removed
+func (c *Controller) checkNpPortGroups(nameIdMap map[string]string, namePortsMap map[string][]string) error {
+	nps, err := c.npsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list network policies, %v", err)
+		return err
+	}
+	for _, np := range nps {
+		pgName := getNpPortGroupName(np.Namespace, getNpName(np.Name))
+		pgPorts, ok := namePortsMap[pgName]
+		if !ok {
+			// the port group is not created yet
+			continue
+		}
+		selected, err := c.fetchSelectedPorts(np.Namespace, &np.Spec.PodSelector)
+		if err != nil {
+			klog.Errorf("failed to fetch ports of np %s/%s, %v", np.Namespace, np.Name, err)
+			continue
+		}
+
+		ports := make([]string, 0, len(selected))
+		expected := make(map[string]struct{}, len(selected))
+		for _, port := range selected {
+			// ports without lsp are added once the lsp is created
+			if portId, ok := nameIdMap[port]; ok {
+				ports = append(ports, port)
+				expected[portId] = struct{}{}
+			}
+		}
+		changed := len(expected) != len(pgPorts)
+		for _, portId := range pgPorts {
+			if _, ok := expected[portId]; !ok {
+				changed = true
+				break
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		klog.Infof("members of port group %s mismatch the pods selected by np %s/%s, reset to %v", pgName, np.Namespace, np.Name, ports)
+		if err = c.ovnLegacyClient.SetPortsToPortGroup(pgName, ports); err != nil {
+			// the other network policies are still checked
+			klog.Errorf("failed to set ports of port group %s, %v", pgName, err)
+		}
+	}
+	return nil
+}
+
 func (c *Controller) fetchSelectedPorts(namespace string, selector *metav1.LabelSelector) ([]string, error) {
 	sel, err := metav1.LabelSelectorAsSelector(selector)
 	if err != nil {
@@ -618,6 +715,77 @@ func (c *Controller) fetchSelectedPorts(namespace string, selector *metav1.Label
 	return ports, nil
 }
 
+// npNamedPort is a named port resolved to the port number, which only applies to the pods with the addresses
+type npNamedPort struct {
+	protocol  corev1.Protocol
+	port      int32
+	addresses []string
+}
+
+// resolveNamedPorts translates named ports to the container port numbers of the pods selected by the network policy.
+// As the pods may map a name to different numbers, a named port is resolved per number to the addresses of the pods
+// exposing it, so that a pod is never reachable on the port number of another pod.
+func (c *Controller) resolveNamedPorts(np *netv1.NetworkPolicy, npps []netv1.NetworkPolicyPort, ipProtocol string) ([]netv1.NetworkPolicyPort, []npNamedPort, error) {
+	var pods []*corev1.Pod
+	result := make([]netv1.NetworkPolicyPort, 0, len(npps))
+	var namedPorts []npNamedPort
+	for _, npp := range npps {
+		if npp.Port == nil || npp.Port.Type != intstr.String {
+			result = append(result, npp)
+			continue
+		}
+
+		if pods == nil {
+			sel, err := metav1.LabelSelectorAsSelector(&np.Spec.PodSelector)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error creating label selector, %v", err)
+			}
+			if pods, err = c.podsLister.Pods(np.Namespace).List(sel); err != nil {
+				return nil, nil, fmt.Errorf("failed to list pods, %v", err)
+			}
+		}
+
+		protocol := corev1.ProtocolTCP
+		if npp.Protocol != nil {
+			protocol = *npp.Protocol
+		}
+		numberIndex := make(map[int32]int)
+		start := len(namedPorts)
+		for _, pod := range pods {
+			if !isPodAlive(pod) || pod.Spec.HostNetwork {
+				continue
+			}
+			var addresses []string
+			for _, podIP := range pod.Status.PodIPs {
+				if podIP.IP != "" && util.CheckProtocol(podIP.IP) == ipProtocol {
+					addresses = append(addresses, podIP.IP)
+				}
+			}
+			if len(addresses) == 0 {
+				continue
+			}
+			for _, container := range pod.Spec.Containers {
+				for _, port := range container.Ports {
+					if port.Name != npp.Port.StrVal || port.Protocol != protocol {
+						continue
+					}
+					i, ok := numberIndex[port.ContainerPort]
+					if !ok {
+						i = len(namedPorts)
+						numberIndex[port.ContainerPort] = i
+						namedPorts = append(namedPorts, npNamedPort{protocol: protocol, port: port.ContainerPort})
+					}
+					namedPorts[i].addresses = append(namedPorts[i].addresses, addresses...)
+				}
+			}
+		}
+		if len(namedPorts)-start > 1 {
+			klog.Infof("named port %s of np %s/%s is mapped to %d different numbers by selected pods", npp.Port.StrVal, np.Namespace, np.Name, len(namedPorts)-start)
+		}
+	}
+	return result, namedPorts, nil
+}
+
 func (c *Controller) fetchSelectedSvc(namespace string, selector *metav1.LabelSelector) ([]string, []string, error) {
 	sel, err := metav1.LabelSelectorAsSelector(selector)
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -19,9 +20,11 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
 	"github.com/kubeovn/kube-ovn/pkg/ovs"
+	"github.com/kubeovn/kube-ovn/pkg/ovsdb/ovnnb"
 	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
@@ -67,6 +70,79 @@ func (c *Controller) enqueueUpdateNode(oldObj, newObj interface{}) {
 		klog.V(3).Infof("enqueue update node %s", key)
 		c.updateNodeQueue.Add(key)
 	}
+
+	if !nodeReady(oldNode) && nodeReady(newNode) && newNode.Annotations[util.AllocatedAnnotation] != "true" {
+		// the node may have failed to be initialized before it was ready
+		klog.V(3).Infof("enqueue add node %s", newNode.Name)
+		c.addNodeQueue.Add(newNode.Name)
+	}
+	if providerNetworkBecameReady(oldNode, newNode) {
+		c.enqueuePodsWaitingProviderNetwork(newNode.Name)
+	}
+	if nodeReady(oldNode) != nodeReady(newNode) || providerNetworkReadinessChanged(oldNode, newNode) {
+		c.enqueueVpcNatGwsForPlacement()
+	}
+	if newNode.Labels[util.VpcNatGwDrainingLabel] == "true" && oldNode.Labels[util.VpcNatGwDrainingLabel] != "true" {
+		c.enqueueVpcNatGwsOnNode(newNode.Name)
+	}
+}
+
+func providerNetworkBecameReady(oldNode, newNode *v1.Node) bool {
+	for k, v := range newNode.Labels {
+		if v == "true" && oldNode.Labels[k] != "true" && strings.HasSuffix(k, ".provider-network.kubernetes.io/ready") {
+			return true
+		}
+	}
+	return false
+}
+
+func providerNetworkReadinessChanged(oldNode, newNode *v1.Node) bool {
+	for _, node := range []*v1.Node{oldNode, newNode} {
+		for k := range node.Labels {
+			if strings.HasSuffix(k, ".provider-network.kubernetes.io/ready") && oldNode.Labels[k] != newNode.Labels[k] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// enqueuePodsWaitingProviderNetwork enqueues the allocated but unrouted pods on the node,
+// which may be waiting for their provider networks to be ready
+func (c *Controller) enqueuePodsWaitingProviderNetwork(nodeName string) {
+	pods, err := c.podsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list pods, %v", err)
+		return
+	}
+	for _, pod := range pods {
+		if pod.Spec.NodeName != nodeName || pod.DeletionTimestamp != nil {
+			continue
+		}
+		for k, v := range pod.Annotations {
+			if v != "true" || !strings.HasSuffix(k, util.AllocatedAnnotationSuffix) {
+				continue
+			}
+			provider := strings.TrimSuffix(k, util.AllocatedAnnotationSuffix)
+			if pod.Annotations[fmt.Sprintf(util.RoutedAnnotationTemplate, provider)] == "true" {
+				continue
+			}
+			subnet, err := c.subnetsLister.Get(pod.Annotations[fmt.Sprintf(util.LogicalSwitchAnnotationTemplate, provider)])
+			if err != nil {
+				continue
+			}
+			if subnet.Spec.Vlan != "" {
+				key, err := cache.MetaNamespaceKeyFunc(pod)
+				if err != nil {
+					utilruntime.HandleError(err)
+					break
+				}
+				klog.V(3).Infof("enqueue update pod %s", key)
+				c.updatePodQueue.Add(key)
+				break
+			}
+		}
+	}
 }
 
 func (c *Controller) enqueueDeleteNode(obj interface{}) {
@@ -195,6 +271,9 @@ func nodeUnderlayAddressSetName(node string, af int) string {
 }
 
 func (c *Controller) handleAddNode(key string) error {
+	c.nodeKeyMutex.Lock(key)
+	defer c.nodeKeyMutex.Unlock(key)
+
 	cachedNode, err := c.nodesLister.Get(key)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
@@ -244,12 +323,14 @@ func (c *Controller) handleAddNode(key string) error {
 			node.Annotations[util.MacAddressAnnotation],
 			node.Annotations[util.LogicalSwitchAnnotation], true)
 		if err != nil {
+			recordIPAMFailure(node.Annotations[util.LogicalSwitchAnnotation], err)
 			klog.Errorf("failed to alloc static ip addrs for node %v: %v", node.Name, err)
 			return err
 		}
 	} else {
 		v4IP, v6IP, mac, err = c.ipam.GetRandomAddress(portName, portName, "", c.config.NodeSwitch, nil, true)
 		if err != nil {
+			recordIPAMFailure(c.config.NodeSwitch, err)
 			klog.Errorf("failed to alloc random ip addrs for node %v: %v", node.Name, err)
 			return err
 		}
@@ -356,6 +437,11 @@ func (c *Controller) handleAddNode(key string) error {
 		return err
 	}
 
+	if err := c.handleVpcNatGwDrain(node); err != nil {
+		klog.Errorf("failed to handle vpc nat gateway drain of node %s: %v", key, err)
+		return err
+	}
+
 	return nil
 }
 
@@ -446,6 +532,9 @@ func (c *Controller) handleNodeAnnotationsForProviderNetworks(node *v1.Node) err
 }
 
 func (c *Controller) handleDeleteNode(key string) error {
+	c.nodeKeyMutex.Lock(key)
+	defer c.nodeKeyMutex.Unlock(key)
+
 	portName := fmt.Sprintf("node-%s", key)
 	klog.Infof("delete logical switch port %s", portName)
 	if err := c.ovnLegacyClient.DeleteLogicalSwitchPort(portName); err != nil {
@@ -563,6 +652,9 @@ func (c *Controller) updateProviderNetworkForNodeDeletion(pn *kubeovnv1.Provider
 }
 
 func (c *Controller) handleUpdateNode(key string) error {
+	c.nodeKeyMutex.Lock(key)
+	defer c.nodeKeyMutex.Unlock(key)
+
 	node, err := c.nodesLister.Get(key)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
@@ -575,6 +667,10 @@ func (c *Controller) handleUpdateNode(key string) error {
 		klog.Errorf("failed to handle annotations of node %s for provider networks: %v", node.Name, err)
 		return err
 	}
+	if err = c.handleVpcNatGwDrain(node); err != nil {
+		klog.Errorf("failed to handle vpc nat gateway drain of node %s: %v", node.Name, err)
+		return err
+	}
 
 	subnets, err := c.subnetsLister.List(labels.Everything())
 	if err != nil {
@@ -594,6 +690,9 @@ func (c *Controller) handleUpdateNode(key string) error {
 
 	for _, cachedSubnet := range subnets {
 		subnet := cachedSubnet.DeepCopy()
+		if isSubnetReconcilePaused(subnet) {
+			continue
+		}
 		if util.GatewayContains(subnet.Spec.GatewayNode, node.Name) {
 			if err := c.reconcileOvnRoute(subnet); err != nil {
 				return err
@@ -631,9 +730,21 @@ func (c *Controller) createOrUpdateCrdIPs(podName, ip, mac, subnetName, ns, node
 		}
 	}
 
+	// the addresses reserved from external ipam are released by the finalizer of the ip CR,
+	// which is the url recorded in the annotation even if the subnet is changed later
+	var externalIPAM string
+	if subnet, err := c.subnetsLister.Get(subnetName); err == nil {
+		externalIPAM = subnet.Spec.ExternalIPAM
+	}
+	if externalIPAM != "" && ipCr != nil && ipCr.DeletionTimestamp != nil {
+		errMsg := fmt.Errorf("ip CR %s is being deleted, wait for the external addresses to be released", ipName)
+		klog.Error(errMsg)
+		return errMsg
+	}
+
 	v4IP, v6IP := util.SplitStringIP(ip)
 	if ipCr == nil {
-		_, err = c.config.KubeOvnClient.KubeovnV1().IPs().Create(context.Background(), &kubeovnv1.IP{
+		ipCr = &kubeovnv1.IP{
 			ObjectMeta: metav1.ObjectMeta{
 				Name: ipName,
 				Labels: map[string]string{
@@ -655,7 +766,12 @@ func (c *Controller) createOrUpdateCrdIPs(podName, ip, mac, subnetName, ns, node
 				AttachSubnets: []string{},
 				PodType:       podType,
 			},
-		}, metav1.CreateOptions{})
+		}
+		if externalIPAM != "" {
+			ipCr.Annotations = map[string]string{util.ExternalIPAMAnnotation: externalIPAM}
+			ipCr.Finalizers = []string{util.ExternalIPAMFinalizer}
+		}
+		_, err = c.config.KubeOvnClient.KubeovnV1().IPs().Create(context.Background(), ipCr, metav1.CreateOptions{})
 		if err != nil {
 			errMsg := fmt.Errorf("failed to create ip CR %s: %v", ipName, err)
 			klog.Error(errMsg)
@@ -682,7 +798,17 @@ func (c *Controller) createOrUpdateCrdIPs(podName, ip, mac, subnetName, ns, node
 		newIpCr.Spec.AttachMacs = []string{}
 		newIpCr.Spec.AttachSubnets = []string{}
 		newIpCr.Spec.PodType = podType
-		if reflect.DeepEqual(newIpCr.Labels, ipCr.Labels) && reflect.DeepEqual(newIpCr.Spec, ipCr.Spec) {
+		if externalIPAM != "" {
+			if newIpCr.Annotations == nil {
+				newIpCr.Annotations = make(map[string]string, 1)
+			}
+			if newIpCr.Annotations[util.ExternalIPAMAnnotation] == "" {
+				newIpCr.Annotations[util.ExternalIPAMAnnotation] = externalIPAM
+			}
+			controllerutil.AddFinalizer(newIpCr, util.ExternalIPAMFinalizer)
+		}
+		if reflect.DeepEqual(newIpCr.Labels, ipCr.Labels) && reflect.DeepEqual(newIpCr.Spec, ipCr.Spec) &&
+			reflect.DeepEqual(newIpCr.Annotations, ipCr.Annotations) && reflect.DeepEqual(newIpCr.Finalizers, ipCr.Finalizers) {
 			return nil
 		}
 
@@ -710,9 +836,360 @@ func (c *Controller) deleteCrdIPs(podName, ns, providerName string) error {
 }
 
 func (c *Controller) CheckGatewayReady() {
+	if err := c.checkNodeGwNextHops(); err != nil {
+		klog.Errorf("failed to check next hops of node gateway %v", err)
+	}
 	if err := c.checkGatewayReady(); err != nil {
 		klog.Errorf("failed to check gateway ready %v", err)
 	}
+	if err := c.syncSubnetGatewaysReady(); err != nil {
+		klog.Errorf("failed to sync gateway ready of subnets %v", err)
+	}
+	if err := c.syncSubnetGatewaysReachable(); err != nil {
+		klog.Errorf("failed to sync gateway reachable of subnets %v", err)
+	}
+}
+
+// subnetHasReadyGateway checks whether any gateway of the centralized subnet is ready,
+// by the ecmp policy routes maintained by checkGatewayReady or the active gateway
+func (c *Controller) subnetHasReadyGateway(subnet *kubeovnv1.Subnet) (bool, error) {
+	if c.config.EnableEcmp {
+		for _, cidrBlock := range strings.Split(subnet.Spec.CIDRBlock, ",") {
+			nextHops, _, err := c.getPolicyRouteParas(cidrBlock)
+			if err != nil {
+				klog.Errorf("failed to get ecmp policy route paras for subnet %s, %v", subnet.Name, err)
+				return false, err
+			}
+			if len(nextHops) == 0 {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	if subnet.Status.ActivateGateway == "" {
+		return false, nil
+	}
+	node, err := c.nodesLister.Get(subnet.Status.ActivateGateway)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		klog.Errorf("failed to get node %s, %v", subnet.Status.ActivateGateway, err)
+		return false, err
+	}
+	return nodeReady(node), nil
+}
+
+// syncSubnetGatewaysReady records whether the centralized subnets have any ready gateway,
+// and enqueues the unrouted pods of the subnets whose gateways recover
+func (c *Controller) syncSubnetGatewaysReady() error {
+	subnets, err := c.subnetsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list subnets %v", err)
+		return err
+	}
+
+	for _, subnet := range subnets {
+		if (subnet.Spec.Vlan != "" && !subnet.Spec.LogicalGateway) ||
+			subnet.Spec.GatewayNode == "" ||
+			subnet.Spec.GatewayType != kubeovnv1.GWCentralizedType {
+			if ready, ok := c.subnetGatewaysReady.LoadAndDelete(subnet.Name); ok && !ready.(bool) {
+				c.enqueueUnroutedPods(subnet.Name)
+			}
+			continue
+		}
+
+		ready, err := c.subnetHasReadyGateway(subnet)
+		if err != nil {
+			continue
+		}
+		lastReady, ok := c.subnetGatewaysReady.Load(subnet.Name)
+		c.subnetGatewaysReady.Store(subnet.Name, ready)
+		if ok && lastReady.(bool) == ready {
+			continue
+		}
+		if !ready {
+			klog.Warningf("all gateways %s of subnet %s are not ready", subnet.Spec.GatewayNode, subnet.Name)
+			c.recorder.Eventf(subnet, v1.EventTypeWarning, "GatewayNotReady", "all gateways %s are not ready", subnet.Spec.GatewayNode)
+		} else if ok {
+			klog.Infof("gateways of subnet %s recovered", subnet.Name)
+			c.recorder.Eventf(subnet, v1.EventTypeNormal, "GatewayRecovered", "gateways %s recovered", subnet.Spec.GatewayNode)
+			c.enqueueUnroutedPods(subnet.Name)
+		}
+	}
+	return nil
+}
+
+// gatewayReachableHysteresis is the number of consecutive checks contradicting the GatewayReachable condition of a
+// subnet required to flip it, so that transient failures do not flap the condition
+const gatewayReachableHysteresis = 3
+
+// syncSubnetGatewaysReachable reflects whether the gateways of the subnets are reachable into their GatewayReachable
+// conditions. The egress traffic of centralized subnets is forwarded by the gateway nodes, and that of distributed
+// subnets by the nodes of the pods, whose ovn0 addresses are pinged once per round for all the subnets
+func (c *Controller) syncSubnetGatewaysReachable() error {
+	subnets, err := c.subnetsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list subnets %v", err)
+		return err
+	}
+	subnetNextHops, err := c.distributedSubnetsNextHops(subnets)
+	if err != nil {
+		return err
+	}
+	var allNextHops []string
+	for _, nextHops := range subnetNextHops {
+		allNextHops = append(allNextHops, nextHops...)
+	}
+	reachableNextHops, _ := c.checkNextHopsReachable(util.UniqString(allNextHops))
+
+	for _, cachedSubnet := range subnets {
+		subnet := cachedSubnet.DeepCopy()
+		if subnet.Spec.Vpc != util.DefaultVpc || subnet.Name == c.config.NodeSwitch ||
+			(subnet.Spec.Vlan != "" && !subnet.Spec.LogicalGateway) {
+			// the egress traffic is not forwarded by the nodes
+			c.gwReachableFlips.Delete(subnet.Name)
+			if subnet.Status.GetCondition(kubeovnv1.GatewayReachable) == nil {
+				continue
+			}
+			subnet.Status.RemoveCondition(kubeovnv1.GatewayReachable)
+			subnet.Status.LastGatewayReachableTime = metav1.Time{}
+			if err = c.patchSubnetGatewayReachable(cachedSubnet, subnet); err != nil {
+				klog.Errorf("failed to remove gateway reachable condition of subnet %s, %v", subnet.Name, err)
+			}
+			continue
+		}
+
+		var reachable bool
+		var reason, message string
+		if subnet.Spec.GatewayType == kubeovnv1.GWCentralizedType && subnet.Spec.GatewayNode != "" {
+			if reachable, err = c.subnetHasReadyGateway(subnet); err != nil {
+				continue
+			}
+			reason = "Centralized"
+			if reachable {
+				message = fmt.Sprintf("gateways %s are reachable", subnet.Spec.GatewayNode)
+			} else {
+				message = fmt.Sprintf("all gateways %s are unreachable", subnet.Spec.GatewayNode)
+			}
+		} else {
+			var unreachable []string
+			nextHops := subnetNextHops[subnet.Name]
+			for _, nextHop := range nextHops {
+				if !util.ContainsString(reachableNextHops, nextHop) {
+					unreachable = append(unreachable, nextHop)
+				}
+			}
+			reason = "Distributed"
+			reachable = len(nextHops) != 0 && len(unreachable) == 0
+			switch {
+			case len(nextHops) == 0:
+				message = "no node is ready to forward the egress traffic"
+			case reachable:
+				message = fmt.Sprintf("gateways %s of the nodes are reachable", strings.Join(nextHops, ","))
+			default:
+				message = fmt.Sprintf("gateways %s of the nodes are unreachable", strings.Join(unreachable, ","))
+			}
+		}
+		if err = c.updateSubnetGatewayReachable(cachedSubnet, subnet, reachable, reason, message); err != nil {
+			klog.Errorf("failed to update gateway reachable condition of subnet %s, %v", subnet.Name, err)
+		}
+	}
+	return nil
+}
+
+// distributedSubnetsNextHops returns the ovn0 addresses of the nodes forwarding the egress traffic of the distributed
+// subnets, which are the ready nodes running the pods of the subnet, or all the ready nodes if there is no such pod
+func (c *Controller) distributedSubnetsNextHops(subnets []*kubeovnv1.Subnet) (map[string][]string, error) {
+	nodes, err := c.nodesLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list nodes, %v", err)
+		return nil, err
+	}
+	pods, err := c.podsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list pods, %v", err)
+		return nil, err
+	}
+
+	nodeNextHops := make(map[string][]string, len(nodes))
+	var allNextHops []string
+	for _, node := range nodes {
+		if !nodeReady(node) || node.Annotations[util.IpAddressAnnotation] == "" {
+			continue
+		}
+		nodeNextHops[node.Name] = strings.Split(node.Annotations[util.IpAddressAnnotation], ",")
+		allNextHops = append(allNextHops, nodeNextHops[node.Name]...)
+	}
+	subnetNodes := make(map[string]map[string]bool)
+	for _, pod := range pods {
+		if pod.Spec.HostNetwork || pod.Spec.NodeName == "" || !isPodAlive(pod) {
+			continue
+		}
+		for key, value := range pod.Annotations {
+			if !strings.HasSuffix(key, strings.TrimPrefix(util.LogicalSwitchAnnotationTemplate, "%s")) {
+				continue
+			}
+			if subnetNodes[value] == nil {
+				subnetNodes[value] = make(map[string]bool)
+			}
+			subnetNodes[value][pod.Spec.NodeName] = true
+		}
+	}
+
+	subnetNextHops := make(map[string][]string, len(subnets))
+	for _, subnet := range subnets {
+		if subnet.Spec.GatewayType == kubeovnv1.GWCentralizedType && subnet.Spec.GatewayNode != "" {
+			continue
+		}
+		if len(subnetNodes[subnet.Name]) == 0 {
+			subnetNextHops[subnet.Name] = allNextHops
+			continue
+		}
+		for nodeName := range subnetNodes[subnet.Name] {
+			subnetNextHops[subnet.Name] = append(subnetNextHops[subnet.Name], nodeNextHops[nodeName]...)
+		}
+		sort.Strings(subnetNextHops[subnet.Name])
+	}
+	return subnetNextHops, nil
+}
+
+// updateSubnetGatewayReachable sets the GatewayReachable condition of the subnet by the result of a check, which
+// flips the condition only after gatewayReachableHysteresis consecutive contradicting checks
+func (c *Controller) updateSubnetGatewayReachable(original, subnet *kubeovnv1.Subnet, reachable bool, reason, message string) error {
+	var changed bool
+	cond := subnet.Status.GetCondition(kubeovnv1.GatewayReachable)
+	if cond == nil || (cond.Status == v1.ConditionTrue) == reachable {
+		c.gwReachableFlips.Delete(subnet.Name)
+		changed = cond == nil || cond.Reason != reason || cond.Message != message
+	} else {
+		flips := 1
+		if v, ok := c.gwReachableFlips.Load(subnet.Name); ok {
+			flips += v.(int)
+		}
+		if flips < gatewayReachableHysteresis {
+			klog.V(3).Infof("gateway reachable of subnet %s is %v for %d checks, keep condition %s", subnet.Name, reachable, flips, cond.Status)
+			c.gwReachableFlips.Store(subnet.Name, flips)
+		} else {
+			klog.Infof("gateway reachable of subnet %s changes to %v: %s", subnet.Name, reachable, message)
+			c.gwReachableFlips.Delete(subnet.Name)
+			changed = true
+		}
+	}
+	if changed {
+		if reachable {
+			subnet.Status.SetCondition(kubeovnv1.GatewayReachable, reason, message)
+		} else {
+			subnet.Status.ClearCondition(kubeovnv1.GatewayReachable, reason, message)
+		}
+	}
+	if reachable && time.Since(subnet.Status.LastGatewayReachableTime.Time) >= time.Minute {
+		subnet.Status.LastGatewayReachableTime = metav1.Now()
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return c.patchSubnetGatewayReachable(original, subnet)
+}
+
+// patchSubnetGatewayReachable patches only the GatewayReachable condition and the last reachable time of the subnet,
+// the index of the condition in the cached subnet is tested so that the patch fails rather than overwrites another
+// condition if the conditions are changed concurrently, and it's retried by the next check
+func (c *Controller) patchSubnetGatewayReachable(original, subnet *kubeovnv1.Subnet) error {
+	var ops []map[string]interface{}
+	index := -1
+	for i, cond := range original.Status.Conditions {
+		if cond.Type == kubeovnv1.GatewayReachable {
+			index = i
+			break
+		}
+	}
+	path := fmt.Sprintf("/status/conditions/%d", index)
+	cond := subnet.Status.GetCondition(kubeovnv1.GatewayReachable)
+	if index != -1 {
+		ops = append(ops, map[string]interface{}{"op": "test", "path": path + "/type", "value": kubeovnv1.GatewayReachable})
+	}
+	switch {
+	case index != -1 && cond != nil:
+		ops = append(ops, map[string]interface{}{"op": "replace", "path": path, "value": cond})
+	case index != -1:
+		ops = append(ops, map[string]interface{}{"op": "remove", "path": path})
+	case cond != nil && len(original.Status.Conditions) == 0:
+		ops = append(ops, map[string]interface{}{"op": "add", "path": "/status/conditions", "value": []kubeovnv1.SubnetCondition{*cond}})
+	case cond != nil:
+		ops = append(ops, map[string]interface{}{"op": "add", "path": "/status/conditions/-", "value": cond})
+	}
+	if !original.Status.LastGatewayReachableTime.Equal(&subnet.Status.LastGatewayReachableTime) {
+		if subnet.Status.LastGatewayReachableTime.IsZero() {
+			ops = append(ops, map[string]interface{}{"op": "remove", "path": "/status/lastGatewayReachableTime"})
+		} else {
+			ops = append(ops, map[string]interface{}{"op": "add", "path": "/status/lastGatewayReachableTime", "value": subnet.Status.LastGatewayReachableTime})
+		}
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+
+	bytes, err := json.Marshal(ops)
+	if err != nil {
+		klog.Error(err)
+		return err
+	}
+	if _, err = c.config.KubeOvnClient.KubeovnV1().Subnets().Patch(context.Background(), subnet.Name, types.JSONPatchType, bytes, metav1.PatchOptions{}, "status"); err != nil {
+		klog.Errorf("failed to patch gateway reachable condition of subnet %s, %v", subnet.Name, err)
+		return err
+	}
+	return nil
+}
+
+// enqueueUnroutedPods enqueues the pods in the subnet which are allocated but not routed yet
+func (c *Controller) enqueueUnroutedPods(subnetName string) {
+	pods, err := c.podsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list pods, %v", err)
+		return
+	}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || !isPodAlive(pod) {
+			continue
+		}
+		podNets, err := c.getPodKubeovnNets(pod)
+		if err != nil {
+			continue
+		}
+		for _, podNet := range podNets {
+			if podNet.Subnet.Name == subnetName &&
+				pod.Annotations[fmt.Sprintf(util.AllocatedAnnotationTemplate, podNet.ProviderName)] == "true" &&
+				pod.Annotations[fmt.Sprintf(util.RoutedAnnotationTemplate, podNet.ProviderName)] != "true" {
+				key := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+				klog.V(3).Infof("enqueue update pod %s", key)
+				c.updatePodQueue.Add(key)
+				break
+			}
+		}
+	}
+}
+
+// pingGateway checks whether the gateway replies to icmp echo in count seconds
+func pingGateway(ip string, count int) (bool, error) {
+	pinger, err := goping.NewPinger(ip)
+	if err != nil {
+		return false, fmt.Errorf("failed to init pinger, %v", err)
+	}
+	pinger.SetPrivileged(true)
+	pinger.Count = count
+	pinger.Timeout = time.Duration(count) * time.Second
+	pinger.Interval = 1 * time.Second
+
+	success := false
+	pinger.OnRecv = func(p *goping.Packet) {
+		success = true
+		pinger.Stop()
+	}
+	pinger.Run()
+	return success, nil
 }
 
 func (c *Controller) checkGatewayReady() error {
@@ -737,6 +1214,8 @@ func (c *Controller) checkGatewayReady() error {
 			subnet.Spec.GatewayType != kubeovnv1.GWCentralizedType {
 			continue
 		}
+		// the readiness of the gateways is still probed for the subnets paused, but their routes are left alone
+		paused := isSubnetReconcilePaused(subnet)
 
 		for _, node := range nodes {
 			ipStr := node.Annotations[util.IpAddressAnnotation]
@@ -759,28 +1238,18 @@ func (c *Controller) checkGatewayReady() error {
 					}
 
 					if util.GatewayContains(subnet.Spec.GatewayNode, node.Name) {
-						pinger, err := goping.NewPinger(ip)
+						success, err := pingGateway(ip, 5)
 						if err != nil {
-							return fmt.Errorf("failed to init pinger, %v", err)
+							return err
 						}
-						pinger.SetPrivileged(true)
-
-						count := 5
-						pinger.Count = count
-						pinger.Timeout = time.Duration(count) * time.Second
-						pinger.Interval = 1 * time.Second
-
-						success := false
-
-						pinger.OnRecv = func(p *goping.Packet) {
-							success = true
-							pinger.Stop()
-						}
-						pinger.Run()
 
 						if !nodeReady(node) {
 							success = false
 						}
+						c.gatewayNodesReady.Store(ip, success)
+						if paused {
+							continue
+						}
 
 						if !success {
 							if exist {
@@ -806,7 +1275,7 @@ func (c *Controller) checkGatewayReady() error {
 								}
 							}
 						}
-					} else {
+					} else if !paused {
 						if exist {
 							klog.Infof("subnet %v gatewayNode does not contains node %v, delete policy route for node ip %s", subnet.Name, node.Name, ip)
 							nextHops = util.RemoveString(nextHops, ip)
@@ -820,6 +1289,22 @@ func (c *Controller) checkGatewayReady() error {
 				}
 			}
 		}
+
+		if paused {
+			continue
+		}
+		// gateway weights may be changed without any next hop change
+		for _, cidrBlock := range strings.Split(subnet.Spec.CIDRBlock, ",") {
+			_, nameIpMap, err := c.getPolicyRouteParas(cidrBlock)
+			if err != nil {
+				klog.Errorf("get ecmp policy route paras for subnet %v, error %v", subnet.Name, err)
+				continue
+			}
+			if err = c.reconcileWeightedPolicyRoute(subnet.Name, cidrBlock, nameIpMap); err != nil {
+				klog.Errorf("failed to reconcile weighted policy route for subnet %s, %v", subnet.Name, err)
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -966,6 +1451,10 @@ func (c *Controller) checkAndUpdateNodePortGroup() error {
 		return err
 	}
 
+	if err = c.checkNpPortGroups(nameIdMap, namePortsMap); err != nil {
+		klog.Errorf("failed to check port groups of network policies, %v", err)
+	}
+
 	for _, node := range nodes {
 		// The port-group should already created when add node
 		pgName := strings.Replace(node.Annotations[util.PortNameAnnotation], "-", ".", -1)
@@ -1031,7 +1520,7 @@ func (c *Controller) validateChassis(node *v1.Node) error {
 	}
 	if chassisAdd == "" {
 		// If no chassisID for this node is obtained, we need to perform GC in order for the chassis to be re-registered
-		if err = c.gcChassis(); err != nil {
+		if err = c.runGC(c.gcChassis); err != nil {
 			return fmt.Errorf("failed to gc chassis, %v", err)
 		}
 	} else {
@@ -1059,22 +1548,144 @@ func (c *Controller) addNodeGwStaticRoute() error {
 	}
 	dstCidr := "0.0.0.0/0,::/0"
 	for _, cidrBlock := range strings.Split(dstCidr, ",") {
-		for _, nextHop := range strings.Split(c.config.NodeSwitchGateway, ",") {
-			if util.CheckProtocol(cidrBlock) != util.CheckProtocol(nextHop) {
-				continue
+		nextHops := c.getNodeGwNextHops(cidrBlock)
+		if len(nextHops) == 0 {
+			continue
+		}
+
+		staleNextHops, err := c.getStaleNodeGwNextHops(cidrBlock, nextHops)
+		if err != nil {
+			return err
+		}
+		// add the new next hops into the ecmp group before removing the stale ones,
+		// so that the default route is never flushed
+		routeType := util.NormalRouteType
+		if len(nextHops) > 1 || len(staleNextHops) != 0 {
+			routeType = util.EcmpRouteType
+		}
+		for _, nextHop := range nextHops {
+			if err = c.addNodeGwNextHop(cidrBlock, nextHop, routeType); err != nil {
+				return err
 			}
-			exist, err := c.checkRouteExist(nextHop, cidrBlock, ovs.PolicyDstIP)
-			if err != nil {
-				klog.Errorf("get static route for node gw error %v", err)
+		}
+		for _, nextHop := range staleNextHops {
+			klog.Infof("delete stale next hop %s of static route %s for node gw", nextHop, cidrBlock)
+			if err = c.ovnLegacyClient.DeleteMatchedStaticRoute(cidrBlock, nextHop, c.config.ClusterRouter); err != nil {
+				klog.Errorf("failed to delete static route %s via %s for node gw: %v", cidrBlock, nextHop, err)
+				return err
+			}
+			if err = c.ovnClient.DeleteBFD(c.nodeGwLrpName(), nextHop); err != nil {
+				klog.Errorf("failed to delete bfd for next hop %s: %v", nextHop, err)
 				return err
 			}
+		}
+	}
+	return nil
+}
 
-			if !exist {
-				klog.Infof("add static route for node gw")
-				if err := c.ovnLegacyClient.AddStaticRoute("", cidrBlock, nextHop, c.config.ClusterRouter, util.NormalRouteType); err != nil {
-					klog.Errorf("failed to add static route for node gw: %v", err)
-					return err
-				}
+func (c *Controller) nodeGwLrpName() string {
+	return fmt.Sprintf("%s-%s", c.config.ClusterRouter, c.config.NodeSwitch)
+}
+
+func (c *Controller) getNodeGwNextHops(cidrBlock string) []string {
+	var nextHops []string
+	for _, nextHop := range strings.Split(c.config.NodeGwNextHops, ",") {
+		if util.CheckProtocol(cidrBlock) == util.CheckProtocol(nextHop) {
+			nextHops = append(nextHops, nextHop)
+		}
+	}
+	return nextHops
+}
+
+// getStaleNodeGwNextHops returns next hops in node switch of the node gw route which are no longer configured
+func (c *Controller) getStaleNodeGwNextHops(cidrBlock string, nextHops []string) ([]string, error) {
+	routes, err := c.ovnLegacyClient.GetStaticRouteList(c.config.ClusterRouter)
+	if err != nil {
+		klog.Errorf("failed to list static route %v", err)
+		return nil, err
+	}
+
+	var staleNextHops []string
+	for _, route := range routes {
+		if route.Policy != ovs.PolicyDstIP || route.CIDR != cidrBlock {
+			continue
+		}
+		if !util.ContainsString(nextHops, route.NextHop) && util.CIDRContainIP(c.config.NodeSwitchCIDR, route.NextHop) {
+			staleNextHops = append(staleNextHops, route.NextHop)
+		}
+	}
+	return staleNextHops, nil
+}
+
+func (c *Controller) addNodeGwNextHop(cidrBlock, nextHop, routeType string) error {
+	exist, err := c.checkRouteExist(nextHop, cidrBlock, ovs.PolicyDstIP)
+	if err != nil {
+		klog.Errorf("get static route for node gw error %v", err)
+		return err
+	}
+
+	if !exist {
+		klog.Infof("add static route %s via %s for node gw", cidrBlock, nextHop)
+		if err := c.ovnLegacyClient.AddStaticRoute("", cidrBlock, nextHop, c.config.ClusterRouter, routeType); err != nil {
+			klog.Errorf("failed to add static route for node gw: %v", err)
+			return err
+		}
+	}
+
+	// the node switch gateway is owned by the cluster router itself, there is nothing to detect
+	if !c.config.EnableNodeGwBfd || util.ContainsString(strings.Split(c.config.NodeSwitchGateway, ","), nextHop) {
+		if err = c.ovnClient.SetLogicalRouterStaticRouteBFD(c.config.ClusterRouter, cidrBlock, nextHop, ""); err != nil {
+			klog.Errorf("failed to unbind bfd of static route %s via %s: %v", cidrBlock, nextHop, err)
+			return err
+		}
+		return c.ovnClient.DeleteBFD(c.nodeGwLrpName(), nextHop)
+	}
+
+	bfd, err := c.ovnClient.CreateBFD(c.nodeGwLrpName(), nextHop, c.config.BfdMinRx, c.config.BfdMinTx, c.config.BfdDetectMult, nil)
+	if err != nil {
+		klog.Errorf("failed to create bfd for next hop %s: %v", nextHop, err)
+		return err
+	}
+	if err = c.ovnClient.SetLogicalRouterStaticRouteBFD(c.config.ClusterRouter, cidrBlock, nextHop, bfd.UUID); err != nil {
+		klog.Errorf("failed to bind bfd of static route %s via %s: %v", cidrBlock, nextHop, err)
+		return err
+	}
+	return nil
+}
+
+// checkNodeGwNextHops keeps every next hop of the node gw route in the ecmp group with its bfd session bound, ovn
+// skips the next hops whose bfd session is down by itself and uses them again once the session is up
+func (c *Controller) checkNodeGwNextHops() error {
+	if !c.config.EnableNodeGwBfd {
+		return nil
+	}
+	if vpc, err := c.vpcsLister.Get(util.DefaultVpc); err != nil || vpc.Spec.StaticRoutes != nil {
+		return nil
+	}
+
+	bfdList, err := c.ovnClient.ListBFD(c.nodeGwLrpName(), "")
+	if err != nil {
+		klog.Errorf("failed to list bfd of node gw: %v", err)
+		return err
+	}
+	down := make(map[string]bool, len(bfdList))
+	for _, bfd := range bfdList {
+		down[bfd.DstIP] = bfd.Status != nil && *bfd.Status == ovnnb.BFDStatusDown
+	}
+
+	for _, cidrBlock := range []string{"0.0.0.0/0", "::/0"} {
+		nextHops := c.getNodeGwNextHops(cidrBlock)
+		routeType := util.NormalRouteType
+		if len(nextHops) > 1 {
+			routeType = util.EcmpRouteType
+		}
+		for _, nextHop := range nextHops {
+			if down[nextHop] {
+				klog.Warningf("bfd session to next hop %s of static route %s for node gw is down", nextHop, cidrBlock)
+			}
+			// the route missing, e.g. deleted manually, is added back with the bfd session bound
+			if err = c.addNodeGwNextHop(cidrBlock, nextHop, routeType); err != nil {
+				return err
 			}
 		}
 	}
@@ -1183,7 +1794,8 @@ func (c *Controller) addPolicyRouteForCentralizedSubnetOnNode(nodeName, nodeIP s
 	}
 
 	for _, subnet := range subnets {
-		if subnet.Spec.Vlan != "" || subnet.Spec.Vpc != util.DefaultVpc || subnet.Name == c.config.NodeSwitch || subnet.Spec.GatewayType != kubeovnv1.GWCentralizedType {
+		if subnet.Spec.Vlan != "" || subnet.Spec.Vpc != util.DefaultVpc || subnet.Name == c.config.NodeSwitch || subnet.Spec.GatewayType != kubeovnv1.GWCentralizedType ||
+			isSubnetReconcilePaused(subnet) {
 			continue
 		}
 
@@ -1236,3 +1848,77 @@ func (c *Controller) addPolicyRouteForCentralizedSubnetOnNode(nodeName, nodeIP s
 	}
 	return nil
 }
+
+// checkNodeRoutes re-adds the missing logical router policies rerouting the traffic to the nodes through their join ips,
+// which may be lost when the ovn nb is edited or restored
+func (c *Controller) checkNodeRoutes() {
+	if !c.isLeader() {
+		return
+	}
+
+	policies, err := c.ovnClient.GetLogicalRouterPoliciesByExtID("vendor", util.CniTypeName)
+	if err != nil {
+		klog.Errorf("failed to list logical router policies: %v", err)
+		return
+	}
+	existingPolicies := make(map[string]string, len(policies))
+	for _, policy := range policies {
+		if policy.Priority == util.NodeRouterPolicyPriority {
+			existingPolicies[policy.Match] = strings.Join(policy.Nexthops, ",")
+		}
+	}
+
+	nodes, err := c.nodesLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list nodes: %v", err)
+		return
+	}
+	for _, node := range nodes {
+		c.checkNodeRoute(node.Name, existingPolicies)
+	}
+}
+
+func (c *Controller) checkNodeRoute(nodeName string, existingPolicies map[string]string) {
+	// wait for the node being added, updated or deleted
+	c.nodeKeyMutex.Lock(nodeName)
+	defer c.nodeKeyMutex.Unlock(nodeName)
+
+	node, err := c.nodesLister.Get(nodeName)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			klog.Errorf("failed to get node %s: %v", nodeName, err)
+		}
+		return
+	}
+	// the policies are added before the node is annotated as allocated
+	if node.DeletionTimestamp != nil || node.Annotations[util.AllocatedAnnotation] != "true" {
+		return
+	}
+
+	nodeIPv4, nodeIPv6 := util.GetNodeInternalIP(*node)
+	joinAddrV4, joinAddrV6 := util.SplitStringIP(node.Annotations[util.IpAddressAnnotation])
+	for _, route := range []struct {
+		af             int
+		nodeIP, joinIP string
+	}{{4, nodeIPv4, joinAddrV4}, {6, nodeIPv6, joinAddrV6}} {
+		if route.nodeIP == "" || route.joinIP == "" {
+			continue
+		}
+		match := fmt.Sprintf("ip%d.dst == %s", route.af, route.nodeIP)
+		if nextHop, ok := existingPolicies[match]; ok && nextHop == route.joinIP {
+			continue
+		}
+
+		klog.Warningf("repair logical router policy %q via %s for node %s", match, route.joinIP, node.Name)
+		externalIDs := map[string]string{
+			"vendor":         util.CniTypeName,
+			"node":           node.Name,
+			"address-family": strconv.Itoa(route.af),
+		}
+		if err = c.ovnLegacyClient.AddPolicyRoute(c.config.ClusterRouter, util.NodeRouterPolicyPriority, match, "reroute", route.joinIP, externalIDs); err != nil {
+			klog.Errorf("failed to repair logical router policy for node %s: %v", node.Name, err)
+			continue
+		}
+		metricNodeRouteRepairs.WithLabelValues(node.Name).Inc()
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"os"
 	"os/exec"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -21,8 +23,19 @@ import (
 )
 
 var (
-	icEnabled = "unknown"
-	lastIcCm  map[string]string
+	icEnabled         = "unknown"
+	lastIcCm          map[string]string
+	lastIcMtuValue    = -1
+	lastIcMtuMismatch string
+	lastIcMtuSync     time.Time
+)
+
+const (
+	// icMtuResyncInterval is the interval to sync the mtu probed by the ovn-ic gateway nodes
+	icMtuResyncInterval = time.Minute
+
+	icMinMtu = 1280
+	icMaxMtu = 9000
 )
 
 func (c *Controller) resyncInterConnection() {
@@ -93,6 +106,9 @@ func (c *Controller) resyncInterConnection() {
 
 		isCMEqual := reflect.DeepEqual(cm.Data, lastIcCm)
 		if icEnabled == "true" && lastIcCm != nil && isCMEqual {
+			if cm.Data["ic-mtu-probe"] == "true" && time.Since(lastIcMtuSync) >= icMtuResyncInterval {
+				c.syncICMtu(cm)
+			}
 			return
 		}
 		if icEnabled == "true" && lastIcCm != nil && !isCMEqual {
@@ -118,6 +134,7 @@ func (c *Controller) resyncInterConnection() {
 			}
 			icEnabled = "true"
 			lastIcCm = cm.Data
+			c.syncICMtu(cm)
 			klog.Info("finish reestablishing ovn-ic")
 			return
 		}
@@ -130,12 +147,14 @@ func (c *Controller) resyncInterConnection() {
 		}
 		icEnabled = "true"
 		lastIcCm = cm.Data
+		c.syncICMtu(cm)
 		klog.Info("finish establishing ovn-ic")
 		return
 	}
 }
 
 func (c *Controller) removeInterConnection(azName string) error {
+	lastIcMtuValue, lastIcMtuMismatch = -1, ""
 	sel, _ := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{MatchLabels: map[string]string{util.ICGatewayLabel: "true"}})
 	nodes, err := c.nodesLister.List(sel)
 	if err != nil {
@@ -262,6 +281,80 @@ func (c *Controller) establishInterConnection(config map[string]string) error {
 	return nil
 }
 
+// syncICMtu clamps the packets through the ovn-ic logical router port to the configured or probed mtu by the gateway
+// mtu of the port, which applies to the packets both leaving and entering the cluster through the port
+func (c *Controller) syncICMtu(cm *corev1.ConfigMap) {
+	lastIcMtuSync = time.Now()
+
+	var mtu int
+	if v := cm.Data["ic-mtu"]; v != "" {
+		var err error
+		if mtu, err = strconv.Atoi(v); err != nil || mtu < icMinMtu || mtu > icMaxMtu {
+			klog.Errorf("invalid ic-mtu %q, it should be an integer between %d and %d", v, icMinMtu, icMaxMtu)
+			c.recorder.Eventf(cm, corev1.EventTypeWarning, "InvalidICMtu", "invalid ic-mtu %q, it should be an integer between %d and %d", v, icMinMtu, icMaxMtu)
+			return
+		}
+	}
+
+	if cm.Data["ic-mtu-probe"] == "true" {
+		var mismatch string
+		if probedMtu := c.probedICMtu(); probedMtu != 0 {
+			if mtu != 0 && probedMtu < mtu {
+				mismatch = fmt.Sprintf("ic-mtu %d exceeds the probed ovn-ic path mtu %d", mtu, probedMtu)
+			}
+			if mtu == 0 || probedMtu < mtu {
+				mtu = probedMtu
+			}
+		}
+		if mismatch != "" && mismatch != lastIcMtuMismatch {
+			klog.Warning(mismatch)
+			c.recorder.Eventf(cm, corev1.EventTypeWarning, "ICMtuMismatch", "%s, clamp to %d", mismatch, mtu)
+		}
+		lastIcMtuMismatch = mismatch
+	}
+
+	if mtu == lastIcMtuValue {
+		return
+	}
+	if err := c.ovnLegacyClient.SetICLogicalRouterPortMtu(cm.Data["az-name"], mtu); err != nil {
+		klog.Errorf("failed to set ovn-ic mtu, %v", err)
+		return
+	}
+	lastIcMtuValue = mtu
+	if mtu != 0 {
+		klog.Infof("clamp ovn-ic traffic to mtu %d", mtu)
+		c.recorder.Eventf(cm, corev1.EventTypeNormal, "ICMtuUpdated", "clamp ovn-ic traffic to mtu %d", mtu)
+	}
+}
+
+// probedICMtu returns the minimum mtu probed by the ovn-ic gateway nodes to the gateways of other clusters,
+// 0 if no gateway node has probed it
+func (c *Controller) probedICMtu() int {
+	sel, _ := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{MatchLabels: map[string]string{util.ICGatewayLabel: "true"}})
+	nodes, err := c.nodesLister.List(sel)
+	if err != nil {
+		klog.Errorf("failed to list nodes, %v", err)
+		return 0
+	}
+
+	var mtu int
+	for _, node := range nodes {
+		v := node.Annotations[util.ICPathMtuAnnotation]
+		if v == "" {
+			continue
+		}
+		nodeMtu, err := strconv.Atoi(v)
+		if err != nil || nodeMtu <= 0 {
+			klog.Warningf("ignore invalid ovn-ic path mtu %q of node %s", v, node.Name)
+			continue
+		}
+		if mtu == 0 || nodeMtu < mtu {
+			mtu = nodeMtu
+		}
+	}
+	return mtu
+}
+
 func (c *Controller) acquireLrpAddress(ts string) (string, error) {
 	cidr, err := c.ovnLegacyClient.GetTsSubnet(ts)
 	if err != nil {
@@ -296,9 +389,9 @@ func (c *Controller) startOVNIC(icHost, icNbPort, icSbPort string) error {
 			fmt.Sprintf("--ovn-ic-sb-db=%s", genHostAddress(icHost, icSbPort)),
 			fmt.Sprintf("--ovn-northd-nb-db=%s", c.config.OvnNbAddr),
 			fmt.Sprintf("--ovn-northd-sb-db=%s", c.config.OvnSbAddr),
-			"--ovn-ic-ssl-key=/var/run/tls/key",
-			"--ovn-ic-ssl-cert=/var/run/tls/cert",
-			"--ovn-ic-ssl-ca-cert=/var/run/tls/cacert",
+			fmt.Sprintf("--ovn-ic-ssl-key=%s", c.config.OvnSSLKey),
+			fmt.Sprintf("--ovn-ic-ssl-cert=%s", c.config.OvnSSLCert),
+			fmt.Sprintf("--ovn-ic-ssl-ca-cert=%s", c.config.OvnSSLCACert),
 			"start_ic")
 	}
 	output, err := cmd.CombinedOutput()
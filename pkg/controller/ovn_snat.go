@@ -9,6 +9,7 @@ import (
 	"github.com/kubeovn/kube-ovn/pkg/util"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
@@ -232,6 +233,10 @@ func (c *Controller) handleAddOvnSnatRule(key string) error {
 		err = fmt.Errorf("failed to get v4 internal ip for snat %s", key)
 		return err
 	}
+	if err = c.claimOvnSnatEipVpc(key, cachedEip, vpcName); err != nil {
+		klog.Error(err)
+		return err
+	}
 	// create snat
 	if err = c.handleAddOvnSnatRuleFinalizer(cachedSnat); err != nil {
 		klog.Errorf("failed to add finalizer for ovn snat, %v", err)
@@ -338,6 +343,10 @@ func (c *Controller) handleUpdateOvnSnatRule(key string) error {
 		err = fmt.Errorf("failed to get v4 internal ip for snat %s", key)
 		return err
 	}
+	if err = c.claimOvnSnatEipVpc(key, cachedEip, vpcName); err != nil {
+		klog.Error(err)
+		return err
+	}
 	// snat change eip
 	if c.ovnSnatChangeEip(cachedSnat, cachedEip) {
 		klog.V(3).Infof("snat change ip, old ip %s, new ip %s", cachedEip.Status.V4Ip, cachedEip.Spec.V4Ip)
@@ -371,6 +380,104 @@ func (c *Controller) handleUpdateOvnSnatRule(key string) error {
 	return nil
 }
 
+// claimOvnSnatEipVpc binds the eip to the vpc of the snat rule, so that each vpc snats to its own pool of eips.
+// The vpcs sharing an external subnet may have overlapping cidrs, and the replies are only returned to the right
+// router when the snat addresses are distinct, the conntrack zones of the routers are separated by ovn. The eip is
+// updated with its resource version, so only one of the vpcs claiming the same eip concurrently succeeds
+func (c *Controller) claimOvnSnatEipVpc(key string, cachedEip *kubeovnv1.OvnEip, vpcName string) error {
+	if err := c.checkSnatAddressVpc(cachedEip.Spec.V4Ip, vpcName); err != nil {
+		return fmt.Errorf("failed to create snat %s, %v", key, err)
+	}
+
+	owner := cachedEip.Labels[util.VpcNameLabel]
+	if owner == vpcName {
+		return nil
+	}
+	if owner != "" {
+		snats, err := c.ovnSnatRulesLister.List(labels.Everything())
+		if err != nil {
+			klog.Errorf("failed to list ovn snats, %v", err)
+			return err
+		}
+		for _, snat := range snats {
+			if snat.Name == key || snat.Spec.OvnEip != cachedEip.Name || !snat.DeletionTimestamp.IsZero() {
+				continue
+			}
+			if c.getOvnSnatVpc(snat) == owner {
+				return fmt.Errorf("failed to create snat %s in vpc %s, eip '%s' is used by snat '%s' in vpc %s, vpcs must snat to distinct eips",
+					key, vpcName, cachedEip.Name, snat.Name, owner)
+			}
+		}
+	}
+
+	eip := cachedEip.DeepCopy()
+	if eip.Labels == nil {
+		eip.Labels = make(map[string]string, 1)
+	}
+	eip.Labels[util.VpcNameLabel] = vpcName
+	if _, err := c.config.KubeOvnClient.KubeovnV1().OvnEips().Update(context.Background(), eip, metav1.UpdateOptions{}); err != nil {
+		klog.Errorf("failed to bind ovn eip %s to vpc %s, %v", eip.Name, vpcName, err)
+		return err
+	}
+	return nil
+}
+
+// getOvnSnatVpc returns the vpc of the internal subnet or ip of the snat rule, empty if it's not found
+func (c *Controller) getOvnSnatVpc(snat *kubeovnv1.OvnSnatRule) string {
+	subnetName := snat.Spec.VpcSubnet
+	if snat.Spec.IpName != "" {
+		ip, err := c.ipsLister.Get(snat.Spec.IpName)
+		if err != nil {
+			return ""
+		}
+		subnetName = ip.Spec.Subnet
+	}
+	subnet, err := c.subnetsLister.Get(subnetName)
+	if err != nil {
+		return ""
+	}
+	return subnet.Spec.Vpc
+}
+
+// checkSnatAddressVpc ensures the snat address is not used by the eips of another vpc. The ovn eips and iptables
+// eips are allocated by separate ipams, so the same address may be allocated to both of them
+func (c *Controller) checkSnatAddressVpc(v4ip, vpcName string) error {
+	if v4ip == "" {
+		return nil
+	}
+	if c.ovnEipsLister != nil {
+		ovnEips, err := c.ovnEipsLister.List(labels.Everything())
+		if err != nil {
+			klog.Errorf("failed to list ovn eips, %v", err)
+			return err
+		}
+		for _, eip := range ovnEips {
+			if eip.Spec.V4Ip == v4ip && eip.Labels[util.VpcNameLabel] != "" && eip.Labels[util.VpcNameLabel] != vpcName {
+				return fmt.Errorf("address %s is used by ovn eip '%s' in vpc %s, vpcs must snat to distinct addresses", v4ip, eip.Name, eip.Labels[util.VpcNameLabel])
+			}
+		}
+	}
+
+	iptablesEips, err := c.iptablesEipsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list iptables eips, %v", err)
+		return err
+	}
+	for _, eip := range iptablesEips {
+		if eip.Spec.V4ip != v4ip || eip.Spec.NatGwDp == "" {
+			continue
+		}
+		gw, err := c.vpcNatGatewayLister.Get(eip.Spec.NatGwDp)
+		if err != nil {
+			continue
+		}
+		if gw.Spec.Vpc != vpcName {
+			return fmt.Errorf("address %s is used by iptables eip '%s' in vpc %s, vpcs must snat to distinct addresses", v4ip, eip.Name, gw.Spec.Vpc)
+		}
+	}
+	return nil
+}
+
 func (c *Controller) handleDelOvnSnatRule(key string) error {
 	klog.V(3).Infof("deleted ovn snat %s", key)
 	return nil
@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"reflect"
@@ -92,6 +93,12 @@ func (c *Controller) enqueueAddPod(obj interface{}) {
 		return
 	}
 
+	if p.Annotations[util.SubnetMigrationAnnotation] == util.SubnetMigrationRequested {
+		klog.V(3).Infof("enqueue migrate pod %s", key)
+		c.migratePodQueue.Add(key)
+		return
+	}
+
 	podNets, err := c.getPodKubeovnNets(p)
 	if err != nil {
 		klog.Errorf("pod not managed by ovn? failed to get pod nets %v", err)
@@ -138,6 +145,10 @@ func (c *Controller) enqueueDeletePod(obj interface{}) {
 			c.updateNpQueue.Add(np)
 		}
 	}
+	if c.config.TerminatingEndpointGrace > 0 {
+		// remove the pod kept as a terminating backend
+		c.enqueueServiceEndpointsOfPod(p)
+	}
 
 	if p.Spec.HostNetwork {
 		return
@@ -166,8 +177,51 @@ func (c *Controller) enqueueDeletePod(obj interface{}) {
 		}
 	} else {
 		klog.V(3).Infof("enqueue delete pod %s", key)
-		c.deletePodQueue.Add(obj)
+		c.enqueueDeletePodAfterGrace(key, p)
+	}
+}
+
+// enqueueDeletePodAfterGrace delays the deletion of the lsps of the pod by the port removal grace period, so that
+// the flows of the ovs ports kept by kube-ovn-cni for the same period are not removed while the in-flight connections
+// are drained. The addresses are kept allocated until the lsps are deleted, and gc skips the lsps until the deadline
+func (c *Controller) enqueueDeletePodAfterGrace(key string, pod *v1.Pod) {
+	delay := portRemovalDelay(pod, c.config.PortRemovalGracePeriod, time.Now())
+	if delay == 0 {
+		c.deletePodQueue.Add(pod)
+		return
+	}
+
+	podKey := fmt.Sprintf("%s/%s", pod.Namespace, c.getNameByPod(pod))
+	if _, loaded := c.lspRemovalDeadlines.LoadOrStore(podKey, time.Now().Add(delay)); loaded {
+		return
+	}
+	klog.Infof("delay the deletion of lsps of pod %s for %v", key, delay)
+	c.deletePodQueue.AddAfter(pod, delay)
+}
+
+// portRemovalDelay returns the remaining port removal grace period of the pod, which starts when the last container
+// of the pod exits. The pods observed without exited containers, e.g. force deleted ones, are counted from now, so the
+// delay never exceeds the grace period
+func portRemovalDelay(pod *v1.Pod, grace time.Duration, now time.Time) time.Duration {
+	if grace <= 0 {
+		return 0
+	}
+	var exitedAt time.Time
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated == nil {
+			return grace
+		}
+		if t := status.State.Terminated.FinishedAt.Time; t.After(exitedAt) {
+			exitedAt = t
+		}
+	}
+	if exitedAt.IsZero() || exitedAt.After(now) {
+		return grace
 	}
+	if delay := grace - now.Sub(exitedAt); delay > 0 {
+		return delay
+	}
+	return 0
 }
 
 func (c *Controller) enqueueUpdatePod(oldObj, newObj interface{}) {
@@ -189,13 +243,19 @@ func (c *Controller) enqueueUpdatePod(oldObj, newObj interface{}) {
 			}
 		}
 
-		if oldPod.Status.PodIP != newPod.Status.PodIP {
+		// named ports of network policies are resolved from the container ports
+		if oldPod.Status.PodIP != newPod.Status.PodIP || !reflect.DeepEqual(podContainerPorts(oldPod), podContainerPorts(newPod)) {
 			for _, np := range c.podMatchNetworkPolicies(newPod) {
 				c.updateNpQueue.Add(np)
 			}
 		}
 	}
 
+	if c.config.TerminatingEndpointGrace > 0 && newPod.DeletionTimestamp != nil && isPodReady(oldPod) != isPodReady(newPod) {
+		// the terminating pod is kept as a backend only while it is ready
+		c.enqueueServiceEndpointsOfPod(newPod)
+	}
+
 	if newPod.Spec.HostNetwork {
 		return
 	}
@@ -211,7 +271,7 @@ func (c *Controller) enqueueUpdatePod(oldObj, newObj interface{}) {
 	isVmPod, vmName := isVmPod(newPod)
 	if !isPodAlive(newPod) && !isStateful && !isVmPod {
 		klog.V(3).Infof("enqueue delete pod %s", key)
-		c.deletePodQueue.Add(newObj)
+		c.enqueueDeletePodAfterGrace(key, newPod)
 		return
 	}
 
@@ -220,7 +280,7 @@ func (c *Controller) enqueueUpdatePod(oldObj, newObj interface{}) {
 			// In case node get lost and pod can not be deleted,
 			// the ip address will not be recycled
 			time.Sleep(time.Duration(*newPod.Spec.TerminationGracePeriodSeconds) * time.Second)
-			c.deletePodQueue.Add(newObj)
+			c.enqueueDeletePodAfterGrace(key, newPod)
 		}()
 		return
 	}
@@ -243,6 +303,12 @@ func (c *Controller) enqueueUpdatePod(oldObj, newObj interface{}) {
 		return
 	}
 
+	if newPod.Annotations[util.SubnetMigrationAnnotation] == util.SubnetMigrationRequested {
+		klog.V(3).Infof("enqueue migrate pod %s", key)
+		c.migratePodQueue.Add(key)
+		return
+	}
+
 	podNets, err := c.getPodKubeovnNets(newPod)
 	if err != nil {
 		klog.Errorf("failed to get pod nets %v", err)
@@ -264,6 +330,23 @@ func (c *Controller) enqueueUpdatePod(oldObj, newObj interface{}) {
 		}
 	}
 
+	// interfaces of the pod may be added or removed
+	if newPod.Spec.NodeName != "" && newPod.Annotations[util.RoutedAnnotation] == "true" {
+		if interfaces, err := allocatedInterfaces(newPod, podNets); err == nil && interfaces != newPod.Annotations[util.AllocatedInterfacesAnnotation] {
+			klog.V(3).Infof("enqueue update pod %s", key)
+			c.updatePodQueue.Add(key)
+		}
+	}
+
+	// the matching egress ip pool may be changed
+	if c.config.EnableEipSnat && !reflect.DeepEqual(oldPod.Labels, newPod.Labels) &&
+		newPod.Annotations[util.RoutedAnnotation] == "true" {
+		if pools, err := c.egressIPPoolsLister.List(labels.Everything()); err == nil && len(pools) != 0 {
+			klog.V(3).Infof("enqueue update pod %s", key)
+			c.updatePodQueue.Add(key)
+		}
+	}
+
 	// security policy changed
 	for _, podNet := range podNets {
 		oldSecurity := oldPod.Annotations[fmt.Sprintf(util.PortSecurityAnnotationTemplate, podNet.ProviderName)]
@@ -436,7 +519,7 @@ func (c *Controller) processNextUpdatePodSecurityWorkItem() bool {
 }
 
 func (c *Controller) getPodKubeovnNets(pod *v1.Pod) ([]*kubeovnNet, error) {
-	defaultSubnet, err := c.getPodDefaultSubnet(pod)
+	defaultSubnet, subnetSource, err := c.resolvePodDefaultSubnet(pod)
 	if err != nil {
 		return nil, err
 	}
@@ -452,6 +535,7 @@ func (c *Controller) getPodKubeovnNets(pod *v1.Pod) ([]*kubeovnNet, error) {
 			Type:         providerTypeOriginal,
 			ProviderName: util.OvnProvider,
 			Subnet:       defaultSubnet,
+			SubnetSource: subnetSource,
 			IsDefault:    true,
 		})
 	}
@@ -465,6 +549,10 @@ func (c *Controller) handleAddPod(key string) error {
 		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
 		return nil
 	}
+	if !c.isNamespaceInScope(namespace) {
+		klog.V(3).Infof("skip pod %s out of the namespace scope", key)
+		return nil
+	}
 
 	c.podKeyMutex.Lock(key)
 	defer c.podKeyMutex.Unlock(key)
@@ -482,10 +570,16 @@ func (c *Controller) handleAddPod(key string) error {
 		c.recorder.Eventf(pod, v1.EventTypeWarning, "ValidatePodNetworkFailed", err.Error())
 		return err
 	}
+	if err := util.ValidatePodBandwidth(pod.Annotations, c.config.MaxPodBandwidth); err != nil {
+		klog.Errorf("validate bandwidth of pod %s/%s failed: %v", namespace, name, err)
+		c.recorder.Eventf(pod, v1.EventTypeWarning, "ValidatePodBandwidthFailed", err.Error())
+		return err
+	}
 
 	podNets, err := c.getPodKubeovnNets(pod)
 	if err != nil {
 		klog.Errorf("failed to get pod nets %v", err)
+		c.recorder.Eventf(pod, v1.EventTypeWarning, "GetPodNetworkFailed", err.Error())
 		return err
 	}
 
@@ -497,6 +591,18 @@ func (c *Controller) handleAddPod(key string) error {
 
 	// Avoid create lsp for already running pod in ovn-nb when controller restart
 	for _, podNet := range needAllocateSubnets(pod, podNets) {
+		if err := c.validatePodProviderNetwork(pod, podNet); err != nil {
+			klog.Errorf("validate pod %s/%s failed: %v", namespace, name, err)
+			c.recorder.Eventf(pod, v1.EventTypeWarning, "ProviderNetworkMismatch", err.Error())
+			return err
+		}
+		if family := pod.Annotations[fmt.Sprintf(util.PreferredIPFamilyAnnotationTemplate, podNet.ProviderName)]; family != "" {
+			if err := util.ValidatePreferredIPFamily(family, podNet.Subnet.Spec.Protocol); err != nil {
+				klog.Errorf("validate pod %s/%s failed: %v", namespace, name, err)
+				c.recorder.Eventf(pod, v1.EventTypeWarning, "ValidatePodNetworkFailed", err.Error())
+				return err
+			}
+		}
 		// the subnet may changed when alloc static ip from the latter subnet after ns supports multi subnets
 		v4IP, v6IP, mac, subnet, err := c.acquireAddress(pod, podNet)
 		if err != nil {
@@ -506,10 +612,13 @@ func (c *Controller) handleAddPod(key string) error {
 		ipStr := util.GetStringIP(v4IP, v6IP)
 		pod.Annotations[fmt.Sprintf(util.IpAddressAnnotationTemplate, podNet.ProviderName)] = ipStr
 		pod.Annotations[fmt.Sprintf(util.MacAddressAnnotationTemplate, podNet.ProviderName)] = mac
-		pod.Annotations[fmt.Sprintf(util.CidrAnnotationTemplate, podNet.ProviderName)] = subnet.Spec.CIDRBlock
+		pod.Annotations[fmt.Sprintf(util.CidrAnnotationTemplate, podNet.ProviderName)] = util.SubnetCIDRForIP(subnet, ipStr)
 		pod.Annotations[fmt.Sprintf(util.GatewayAnnotationTemplate, podNet.ProviderName)] = subnet.Spec.Gateway
 		pod.Annotations[fmt.Sprintf(util.LogicalSwitchAnnotationTemplate, podNet.ProviderName)] = subnet.Name
 		pod.Annotations[fmt.Sprintf(util.AllocatedAnnotationTemplate, podNet.ProviderName)] = "true"
+		if podNet.SubnetSource != "" {
+			c.recordPodSubnetSource(pod, subnet.Name, podNet.SubnetSource)
+		}
 		if pod.Annotations[fmt.Sprintf(util.PodNicAnnotationTemplate, podNet.ProviderName)] == "" {
 			pod.Annotations[fmt.Sprintf(util.PodNicAnnotationTemplate, podNet.ProviderName)] = c.config.PodNicType
 		}
@@ -517,7 +626,7 @@ func (c *Controller) handleAddPod(key string) error {
 			pod.Annotations[fmt.Sprintf(util.VmTemplate, podNet.ProviderName)] = vmName
 		}
 
-		if err := util.ValidatePodCidr(podNet.Subnet.Spec.CIDRBlock, ipStr); err != nil {
+		if err := util.ValidatePodCidr(util.SubnetCIDRForIP(podNet.Subnet, ipStr), ipStr); err != nil {
 			klog.Errorf("validate pod %s/%s failed: %v", namespace, name, err)
 			c.recorder.Eventf(pod, v1.EventTypeWarning, "ValidatePodNetworkFailed", err.Error())
 			return err
@@ -527,6 +636,11 @@ func (c *Controller) handleAddPod(key string) error {
 		podName := c.getNameByPod(pod)
 		if err := c.createOrUpdateCrdIPs(podName, ipStr, mac, subnet.Name, pod.Namespace, pod.Spec.NodeName, podNet.ProviderName, podType, nil); err != nil {
 			klog.Errorf("failed to create IP %s.%s: %v", podName, pod.Namespace, err)
+			if subnet.Spec.ExternalIPAM != "" {
+				// the ip CR is required to release the external addresses on pod deletion
+				c.releaseExternalAddressOfPod(pod, podName, podNet.ProviderName, ipStr, mac, subnet)
+				return err
+			}
 		}
 
 		if podNet.Type != providerTypeIPAM {
@@ -543,6 +657,7 @@ func (c *Controller) handleAddPod(key string) error {
 				pod.Annotations[fmt.Sprintf(util.VlanIdAnnotationTemplate, podNet.ProviderName)] = strconv.Itoa(vlan.Spec.ID)
 				pod.Annotations[fmt.Sprintf(util.ProviderNetworkTemplate, podNet.ProviderName)] = vlan.Spec.Provider
 			}
+			pod.Annotations[fmt.Sprintf(util.ResolvedGatewayCheckModeAnnotationTemplate, podNet.ProviderName)] = c.resolveGatewayCheckMode(pod, podNet.ProviderName, subnet)
 
 			portSecurity := false
 			if pod.Annotations[fmt.Sprintf(util.PortSecurityAnnotationTemplate, podNet.ProviderName)] == "true" {
@@ -560,16 +675,32 @@ func (c *Controller) handleAddPod(key string) error {
 			}
 
 			portName := ovs.PodNameToPortName(podName, namespace, podNet.ProviderName)
-			dhcpOptions := &ovs.DHCPOptionsUUIDs{
-				DHCPv4OptionsUUID: subnet.Status.DHCPv4OptionsUUID,
-				DHCPv6OptionsUUID: subnet.Status.DHCPv6OptionsUUID,
+			dhcpOptions := &ovs.DHCPOptionsUUIDs{}
+			enableDHCPv4, enableDHCPv6 := subnetDHCPEnabled(podNet.Subnet)
+			if enableDHCPv4 {
+				dhcpOptions.DHCPv4OptionsUUID = subnet.Status.DHCPv4OptionsUUID
+			}
+			if enableDHCPv6 {
+				dhcpOptions.DHCPv6OptionsUUID = subnet.Status.DHCPv6OptionsUUID
 			}
 
 			hasUnknown := pod.Annotations[fmt.Sprintf(util.Layer2ForwardAnnotationTemplate, podNet.ProviderName)] == "true"
-			if err := c.ovnLegacyClient.CreatePort(subnet.Name, portName, ipStr, mac, podName, pod.Namespace, portSecurity, securityGroupAnnotation, vips, podNet.AllowLiveMigration, podNet.Subnet.Spec.EnableDHCP, dhcpOptions, hasUnknown); err != nil {
+			if err := c.ovnLegacyClient.CreatePort(subnet.Name, portName, ipStr, mac, podName, pod.Namespace, portSecurity, securityGroupAnnotation, vips, podNet.AllowLiveMigration, enableDHCPv4 || enableDHCPv6, dhcpOptions, hasUnknown); err != nil {
 				c.recorder.Eventf(pod, v1.EventTypeWarning, "CreateOVNPortFailed", err.Error())
 				return err
 			}
+			if subnet.Spec.ArpResponder == kubeovnv1.ArpResponderFlood || subnet.Spec.ArpResponder == kubeovnv1.ArpResponderRouter {
+				if err := c.ovnLegacyClient.SetPortArpResponder(portName, false); err != nil {
+					c.recorder.Eventf(pod, v1.EventTypeWarning, "CreateOVNPortFailed", err.Error())
+					return err
+				}
+			}
+			if subnet.Spec.BroadcastRateLimit != 0 {
+				if err := c.ovnLegacyClient.SetPortBroadcastRateLimit(subnet.Name, portName, subnet.Spec.BroadcastRateLimit); err != nil {
+					c.recorder.Eventf(pod, v1.EventTypeWarning, "CreateOVNPortFailed", err.Error())
+					return err
+				}
+			}
 
 			if portSecurity {
 				sgNames := strings.Split(securityGroupAnnotation, ",")
@@ -587,6 +718,10 @@ func (c *Controller) handleAddPod(key string) error {
 		}
 	}
 
+	if err := setAllocatedInterfaces(pod, podNets); err != nil {
+		klog.Errorf("failed to generate allocated interfaces of pod %s/%s: %v", namespace, name, err)
+		return err
+	}
 	patch, err := util.GenerateStrategicMergePatchPayload(oriPod, pod)
 	if err != nil {
 		return err
@@ -606,6 +741,7 @@ func (c *Controller) handleAddPod(key string) error {
 	if vpcGwName, isVpcNatGw := pod.Annotations[util.VpcNatGatewayAnnotation]; isVpcNatGw {
 		c.initVpcNatGatewayQueue.Add(vpcGwName)
 	}
+	c.enqueueNamespaceQos(namespace)
 	return nil
 }
 
@@ -618,6 +754,12 @@ func (c *Controller) handleDeletePod(pod *v1.Pod) error {
 	c.podKeyMutex.Lock(key)
 	defer c.podKeyMutex.Unlock(key)
 
+	if deadline, ok := c.lspRemovalDeadlines.Load(key); ok && time.Now().Before(deadline.(time.Time)) {
+		// the pod is deleted again during the grace period, leave it to the delayed deletion
+		return nil
+	}
+	c.lspRemovalDeadlines.Delete(key)
+
 	p, _ := c.podsLister.Pods(pod.Namespace).Get(pod.Name)
 	if p != nil && p.UID != pod.UID {
 		// Pod with same name exists, just return here
@@ -679,6 +821,12 @@ func (c *Controller) handleDeletePod(pod *v1.Pod) error {
 			klog.Errorf("failed to delete lsp %s, %v", port.Name, err)
 			return err
 		}
+		if subnet, err := c.subnetsLister.Get(port.ExternalIDs["ls"]); err == nil && subnet.Spec.BroadcastRateLimit != 0 {
+			if err = c.ovnLegacyClient.DeletePortBroadcastRateLimit(port.Name); err != nil {
+				klog.Errorf("failed to delete broadcast rate limit of lsp %s, %v", port.Name, err)
+				return err
+			}
+		}
 		for _, sg := range sgs {
 			c.syncSgPortsQueue.Add(sg)
 		}
@@ -701,9 +849,15 @@ func (c *Controller) handleDeletePod(pod *v1.Pod) error {
 		}
 	}
 	c.ipam.ReleaseAddressByPod(key)
+	if c.config.EnableEipSnat {
+		if pool := c.egressIPAllocator.Release(key); pool != "" {
+			c.updateEgressIPPoolQueue.Add(pool)
+		}
+	}
 	for _, podNet := range podNets {
 		c.syncVirtualPortsQueue.Add(podNet.Subnet.Name)
 	}
+	c.enqueueNamespaceQos(pod.Namespace)
 	return nil
 }
 
@@ -769,6 +923,10 @@ func (c *Controller) handleUpdatePod(key string) error {
 		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
 		return nil
 	}
+	if !c.isNamespaceInScope(namespace) {
+		klog.V(3).Infof("skip pod %s out of the namespace scope", key)
+		return nil
+	}
 
 	c.podKeyMutex.Lock(key)
 	defer c.podKeyMutex.Unlock(key)
@@ -802,6 +960,41 @@ func (c *Controller) handleUpdatePod(key string) error {
 			return fmt.Errorf("no address has been allocated to %s/%s", namespace, name)
 		}
 
+		// keep the pod unrouted until the underlay provider network is ready on the node,
+		// the pod is enqueued again by the node handler when the provider network becomes ready
+		if podNet.Subnet.Spec.Vlan != "" {
+			vlan, err := c.vlansLister.Get(podNet.Subnet.Spec.Vlan)
+			if err != nil {
+				klog.Errorf("failed to get vlan %s of subnet %s: %v", podNet.Subnet.Spec.Vlan, podNet.Subnet.Name, err)
+				return err
+			}
+			pn := vlan.Spec.Provider
+			node, err := c.nodesLister.Get(pod.Spec.NodeName)
+			if err != nil {
+				klog.Errorf("failed to get node %s: %v", pod.Spec.NodeName, err)
+				return err
+			}
+			if node.Labels[fmt.Sprintf(util.ProviderNetworkReadyTemplate, pn)] != "true" {
+				klog.Warningf("provider network %s of pod %s/%s is not ready on node %s", pn, namespace, name, node.Name)
+				c.recorder.Eventf(pod, v1.EventTypeWarning, "ProviderNetworkNotReady", "provider network %s is not ready on node %s", pn, node.Name)
+				return nil
+			}
+		}
+
+		// keep the pod unrouted while all gateways of the centralized subnet are not ready if the policy is fail,
+		// the pod is enqueued again when the gateways recover
+		if pod.Annotations[fmt.Sprintf(util.RoutedAnnotationTemplate, podNet.ProviderName)] != "true" {
+			if ready, ok := c.subnetGatewaysReady.Load(podNet.Subnet.Name); ok && !ready.(bool) {
+				if podNet.Subnet.Spec.GatewayUnavailablePolicy == kubeovnv1.GatewayUnavailablePolicyFail {
+					klog.Warningf("all gateways of subnet %s are not ready, keep pod %s/%s pending", podNet.Subnet.Name, namespace, name)
+					c.recorder.Eventf(pod, v1.EventTypeWarning, "GatewayNotReady", "all gateways of subnet %s are not ready, wait for them to recover", podNet.Subnet.Name)
+					return nil
+				}
+				klog.Warningf("all gateways of subnet %s are not ready, pod %s/%s has no egress", podNet.Subnet.Name, namespace, name)
+				c.recorder.Eventf(pod, v1.EventTypeWarning, "GatewayNotReady", "all gateways of subnet %s are not ready, the pod has no egress until they recover", podNet.Subnet.Name)
+			}
+		}
+
 		podIP = pod.Annotations[fmt.Sprintf(util.IpAddressAnnotationTemplate, podNet.ProviderName)]
 		subnet = podNet.Subnet
 
@@ -812,8 +1005,24 @@ func (c *Controller) handleUpdatePod(key string) error {
 				return err
 			}
 
+			snatIP := pod.Annotations[util.SnatAnnotation]
+			var releasedEgressIP string
+			if c.config.EnableEipSnat && podNet.ProviderName == util.OvnProvider {
+				egressIP, err := c.acquirePodEgressIP(pod, podName)
+				if err != nil {
+					klog.Errorf("failed to acquire egress ip for pod %s/%s: %v", namespace, name, err)
+					return err
+				}
+				if egressIP != "" {
+					snatIP = egressIP
+				}
+				if egressIP == "" {
+					releasedEgressIP = oriPod.Annotations[util.EgressIPAnnotation]
+				}
+			}
+
 			pgName := getOverlaySubnetsPortGroupName(subnet.Name, node.Name)
-			if c.config.EnableEipSnat && (pod.Annotations[util.EipAnnotation] != "" || pod.Annotations[util.SnatAnnotation] != "") {
+			if c.config.EnableEipSnat && (pod.Annotations[util.EipAnnotation] != "" || snatIP != "") {
 				cm, err := c.configMapsLister.ConfigMaps(c.config.ExternalGatewayConfigNS).Get(util.ExternalGatewayConfig)
 				if err != nil {
 					klog.Errorf("failed to get ex-gateway config, %v", err)
@@ -878,6 +1087,14 @@ func (c *Controller) handleUpdatePod(key string) error {
 						klog.Errorf("failed to add static route, %v", err)
 						return err
 					}
+				} else if releasedEgressIP != "" {
+					// remove the route to the external gateway added for the released egress ip
+					for _, ipStr := range strings.Split(podIP, ",") {
+						if err := c.ovnLegacyClient.DeleteStaticRoute(ipStr, c.config.ClusterRouter); err != nil {
+							klog.Errorf("failed to delete static route of pod %s/%s, %v", namespace, name, err)
+							return err
+						}
+					}
 				}
 			}
 
@@ -888,7 +1105,14 @@ func (c *Controller) handleUpdatePod(key string) error {
 						return err
 					}
 
-					if err := c.ovnLegacyClient.UpdateNatRule("snat", ipStr, pod.Annotations[util.SnatAnnotation], c.config.ClusterRouter, "", ""); err != nil {
+					if snatIP == "" && releasedEgressIP != "" {
+						if err := c.ovnLegacyClient.DeleteSnatRule(c.config.ClusterRouter, releasedEgressIP, ipStr); err != nil {
+							klog.Errorf("failed to delete snat rule of pod %s/%s, %v", namespace, name, err)
+							return err
+						}
+						continue
+					}
+					if err := c.ovnLegacyClient.UpdateNatRule("snat", ipStr, snatIP, c.config.ClusterRouter, "", ""); err != nil {
 						klog.Errorf("failed to add nat rules, %v", err)
 						return err
 					}
@@ -898,6 +1122,10 @@ func (c *Controller) handleUpdatePod(key string) error {
 
 		pod.Annotations[fmt.Sprintf(util.RoutedAnnotationTemplate, podNet.ProviderName)] = "true"
 	}
+	if err := setAllocatedInterfaces(pod, podNets); err != nil {
+		klog.Errorf("failed to generate allocated interfaces of pod %s/%s: %v", namespace, name, err)
+		return err
+	}
 	patch, err := util.GenerateStrategicMergePatchPayload(oriPod, pod)
 	if err != nil {
 		return err
@@ -979,6 +1207,33 @@ func getNextHopByTunnelIP(gw []net.IP) string {
 	return nextHop
 }
 
+// allocatedInterfaces returns the allocated interfaces annotation generated from the interfaces of the pod
+func allocatedInterfaces(pod *v1.Pod, nets []*kubeovnNet) (string, error) {
+	providers := make([]string, 0, len(nets))
+	for _, n := range nets {
+		providers = append(providers, n.ProviderName)
+	}
+	return util.GenerateAllocatedInterfaces(pod.Annotations, providers)
+}
+
+// setAllocatedInterfaces keeps the allocated interfaces annotation in sync with the interfaces of the pod,
+// the per-provider annotations are left as they are for the existing consumers
+func setAllocatedInterfaces(pod *v1.Pod, nets []*kubeovnNet) error {
+	interfaces, err := allocatedInterfaces(pod, nets)
+	if err != nil {
+		return err
+	}
+	if interfaces == "" {
+		delete(pod.Annotations, util.AllocatedInterfacesAnnotation)
+		return nil
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[util.AllocatedInterfacesAnnotation] = interfaces
+	return nil
+}
+
 func needAllocateSubnets(pod *v1.Pod, nets []*kubeovnNet) []*kubeovnNet {
 	if pod.Status.Phase == v1.PodSucceeded ||
 		pod.Status.Phase == v1.PodFailed {
@@ -999,59 +1254,8 @@ func needAllocateSubnets(pod *v1.Pod, nets []*kubeovnNet) []*kubeovnNet {
 }
 
 func (c *Controller) getPodDefaultSubnet(pod *v1.Pod) (*kubeovnv1.Subnet, error) {
-	var subnet *kubeovnv1.Subnet
-	var err error
-	// 1. check annotation subnet
-	lsName, lsExist := pod.Annotations[util.LogicalSwitchAnnotation]
-	if lsExist {
-		subnet, err = c.subnetsLister.Get(lsName)
-		if err != nil {
-			klog.Errorf("failed to get subnet %v", err)
-			return nil, err
-		}
-	} else {
-		ns, err := c.namespacesLister.Get(pod.Namespace)
-		if err != nil {
-			klog.Errorf("failed to get namespace %s, %v", pod.Namespace, err)
-			return nil, err
-		}
-		if ns.Annotations == nil {
-			err = fmt.Errorf("namespace %s network annotations is nil", pod.Namespace)
-			klog.Error(err)
-			return nil, err
-		}
-
-		subnetNames := ns.Annotations[util.LogicalSwitchAnnotation]
-		for _, subnetName := range strings.Split(subnetNames, ",") {
-			if subnetName == "" {
-				err = fmt.Errorf("namespace %s default logical switch is not found", pod.Namespace)
-				klog.Error(err)
-				return nil, err
-			}
-			subnet, err = c.subnetsLister.Get(subnetName)
-			if err != nil {
-				klog.Errorf("failed to get subnet %v", err)
-				return nil, err
-			}
-
-			switch subnet.Spec.Protocol {
-			case kubeovnv1.ProtocolIPv4:
-				fallthrough
-			case kubeovnv1.ProtocolDual:
-				if subnet.Status.V4AvailableIPs == 0 {
-					klog.V(3).Infof("there's no available ips for subnet %v, try next subnet", subnet.Name)
-					continue
-				}
-			case kubeovnv1.ProtocolIPv6:
-				if subnet.Status.V6AvailableIPs == 0 {
-					klog.Infof("there's no available ips for subnet %v, try next subnet", subnet.Name)
-					continue
-				}
-			}
-			break
-		}
-	}
-	return subnet, nil
+	subnet, _, err := c.resolvePodDefaultSubnet(pod)
+	return subnet, err
 }
 
 func loadNetConf(bytes []byte) (*multustypes.DelegateNetConf, error) {
@@ -1076,9 +1280,11 @@ const (
 )
 
 type kubeovnNet struct {
-	Type               providerType
-	ProviderName       string
-	Subnet             *kubeovnv1.Subnet
+	Type         providerType
+	ProviderName string
+	Subnet       *kubeovnv1.Subnet
+	// SubnetSource is where the subnet of the default network is resolved from
+	SubnetSource       string
 	IsDefault          bool
 	AllowLiveMigration bool
 }
@@ -1239,6 +1445,12 @@ func (c *Controller) acquireAddress(pod *v1.Pod, podNet *kubeovnNet) (string, st
 		}
 	}
 
+	if podNet.Subnet.Spec.ExternalIPAM != "" {
+		portName := ovs.PodNameToPortName(podName, pod.Namespace, podNet.ProviderName)
+		v4IP, v6IP, mac, err := c.acquireExternalAddress(pod, podNet, key, portName, macStr)
+		return v4IP, v6IP, mac, podNet.Subnet, err
+	}
+
 	// Random allocate
 	if pod.Annotations[fmt.Sprintf(util.IpAddressAnnotationTemplate, podNet.ProviderName)] == "" &&
 		pod.Annotations[fmt.Sprintf(util.IpPoolAnnotationTemplate, podNet.ProviderName)] == "" {
@@ -1248,6 +1460,7 @@ func (c *Controller) acquireAddress(pod *v1.Pod, podNet *kubeovnNet) (string, st
 
 			ipv4, ipv6, mac, err := c.ipam.GetRandomAddress(key, portName, macStr, podNet.Subnet.Name, skippedAddrs, !podNet.AllowLiveMigration)
 			if err != nil {
+				recordIPAMFailure(podNet.Subnet.Name, err)
 				return "", "", "", podNet.Subnet, err
 			}
 			ipv4OK, ipv6OK, err := c.validatePodIP(pod.Name, podNet.Subnet.Name, ipv4, ipv6)
@@ -1283,6 +1496,16 @@ func (c *Controller) acquireAddress(pod *v1.Pod, podNet *kubeovnNet) (string, st
 			if err == nil {
 				return v4IP, v6IP, mac, net.Subnet, nil
 			}
+			if errors.Is(err, ipam.ErrConflict) {
+				if err = c.handleStaticIPConflict(pod, key, ipStr, net.Subnet.Name); err != nil {
+					return "", "", "", podNet.Subnet, err
+				}
+				// the holders have been reclaimed, try again
+				v4IP, v6IP, mac, err = c.acquireStaticAddress(key, portName, ipStr, macStr, net.Subnet.Name, net.AllowLiveMigration)
+				if err == nil {
+					return v4IP, v6IP, mac, net.Subnet, nil
+				}
+			}
 		}
 		return v4IP, v6IP, mac, podNet.Subnet, err
 	}
@@ -1333,11 +1556,13 @@ func (c *Controller) acquireStaticAddress(key, nicName, ip, mac, subnet string,
 	var err error
 	for _, ipStr := range strings.Split(ip, ",") {
 		if net.ParseIP(ipStr) == nil {
+			recordInvalidStaticIP(subnet)
 			return "", "", "", fmt.Errorf("failed to parse IP %s", ipStr)
 		}
 	}
 
 	if v4IP, v6IP, mac, err = c.ipam.GetStaticAddress(key, nicName, ip, mac, subnet, !liveMigration); err != nil {
+		recordIPAMFailure(subnet, err)
 		klog.Errorf("failed to get static ip %v, mac %v, subnet %v, err %v", ip, mac, subnet, err)
 		return "", "", "", err
 	}
@@ -1599,3 +1824,34 @@ func getPodType(pod *v1.Pod) string {
 	}
 	return ""
 }
+
+func podContainerPorts(pod *v1.Pod) []v1.ContainerPort {
+	var ports []v1.ContainerPort
+	for _, container := range pod.Spec.Containers {
+		ports = append(ports, container.Ports...)
+	}
+	return ports
+}
+
+// validatePodProviderNetwork checks that the subnet of the pod is on the provider network requested by the
+// provider_network annotation of the pod
+func (c *Controller) validatePodProviderNetwork(pod *v1.Pod, podNet *kubeovnNet) error {
+	requested := pod.Annotations[fmt.Sprintf(util.ProviderNetworkTemplate, podNet.ProviderName)]
+	if requested == "" {
+		return nil
+	}
+	if podNet.Subnet.Spec.Vlan == "" {
+		return fmt.Errorf("subnet %s is not an underlay subnet, but provider network %s is requested", podNet.Subnet.Name, requested)
+	}
+	vlan, err := c.vlansLister.Get(podNet.Subnet.Spec.Vlan)
+	if err != nil {
+		return err
+	}
+	if vlan.Spec.Provider != requested {
+		return fmt.Errorf("subnet %s is on provider network %s of vlan %s, but provider network %s is requested", podNet.Subnet.Name, vlan.Spec.Provider, vlan.Name, requested)
+	}
+	if _, err = c.providerNetworksLister.Get(requested); err != nil {
+		return err
+	}
+	return nil
+}
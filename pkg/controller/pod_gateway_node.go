@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/ovsdb/ovnnb"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+const (
+	// drop the egress traffic of the pods whose gateway node fails
+	podGatewayNodeFailurePolicyDrop = "drop"
+	// fall back to the subnet gateway for the pods whose gateway node fails
+	podGatewayNodeFailurePolicyFallback = "fallback"
+
+	podGatewayNodeKey = "pod-gateway-node"
+)
+
+// getPodGatewayNodeIPs returns the join ips of the node if it is a valid gateway
+func (c *Controller) getPodGatewayNodeIPs(nodeName string) (string, string, error) {
+	node, err := c.nodesLister.Get(nodeName)
+	if err != nil {
+		return "", "", err
+	}
+	if !nodeReady(node) {
+		return "", "", fmt.Errorf("node %s is not ready", nodeName)
+	}
+	if node.Annotations[util.AllocatedAnnotation] != "true" || node.Annotations[util.IpAddressAnnotation] == "" {
+		return "", "", fmt.Errorf("node %s has no join ip allocated", nodeName)
+	}
+	v4, v6 := util.SplitStringIP(node.Annotations[util.IpAddressAnnotation])
+	return v4, v6, nil
+}
+
+// syncPodGatewayNodePolicy reroutes the egress traffic of the pods with the gateway node annotation to the node,
+// overriding the gateway of the subnet. If the node is not a valid gateway, the traffic is dropped or falls back to
+// the subnet gateway according to the failure policy
+func (c *Controller) syncPodGatewayNodePolicy() {
+	lr, err := c.ovnClient.GetLogicalRouter(c.config.ClusterRouter, false)
+	if err != nil {
+		klog.Errorf("failed to get logical router %s, %v", c.config.ClusterRouter, err)
+		return
+	}
+	policies, err := c.ovnClient.GetLogicalRouterPoliciesByExtID(podGatewayNodeKey, "true")
+	if err != nil {
+		klog.Errorf("failed to list pod gateway node policies, %v", err)
+		return
+	}
+	existingPolicies := make(map[string]ovnnb.LogicalRouterPolicy, len(policies))
+	for _, policy := range policies {
+		existingPolicies[policy.Match] = policy
+	}
+
+	pods, err := c.podsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list pods, %v", err)
+		return
+	}
+	desiredMatches := make(map[string]bool)
+	for _, pod := range pods {
+		nodeName := pod.Annotations[util.GatewayNodeAnnotation]
+		if nodeName == "" || pod.Spec.HostNetwork || pod.Annotations[util.RoutedAnnotation] != "true" {
+			continue
+		}
+		subnet, err := c.subnetsLister.Get(pod.Annotations[util.LogicalSwitchAnnotation])
+		if err != nil {
+			klog.Errorf("failed to get subnet of pod %s/%s, %v", pod.Namespace, pod.Name, err)
+			continue
+		}
+		if subnet.Spec.Vpc != util.DefaultVpc || (subnet.Spec.Vlan != "" && !subnet.Spec.LogicalGateway) {
+			continue
+		}
+
+		action, reason := ovnnb.LogicalRouterPolicyActionReroute, ""
+		nodeIPv4, nodeIPv6, err := c.getPodGatewayNodeIPs(nodeName)
+		if err != nil {
+			if c.config.PodGatewayNodeFailurePolicy == podGatewayNodeFailurePolicyFallback {
+				continue
+			}
+			action, reason = ovnnb.LogicalRouterPolicyActionDrop, err.Error()
+		}
+
+		for _, ip := range strings.Split(pod.Annotations[util.IpAddressAnnotation], ",") {
+			ipSuffix, nextHop := "ip4", nodeIPv4
+			if util.CheckProtocol(ip) == kubeovnv1.ProtocolIPv6 {
+				ipSuffix, nextHop = "ip6", nodeIPv6
+			}
+			match := fmt.Sprintf("%s.src == %s", ipSuffix, ip)
+			policyAction := action
+			if action == ovnnb.LogicalRouterPolicyActionReroute && nextHop == "" {
+				if c.config.PodGatewayNodeFailurePolicy == podGatewayNodeFailurePolicyFallback {
+					continue
+				}
+				policyAction, reason = ovnnb.LogicalRouterPolicyActionDrop, fmt.Sprintf("node %s has no %s join ip", nodeName, ipSuffix)
+			}
+			if policyAction == ovnnb.LogicalRouterPolicyActionDrop {
+				nextHop = ""
+			}
+			desiredMatches[match] = true
+
+			if policy, ok := existingPolicies[match]; ok {
+				if policy.Action == policyAction && strings.Join(policy.Nexthops, ",") == nextHop {
+					continue
+				}
+				if err = c.ovnClient.DeleteRouterPolicy(lr, policy.UUID); err != nil {
+					klog.Errorf("failed to delete policy route of pod %s/%s, %v", pod.Namespace, pod.Name, err)
+					continue
+				}
+			}
+
+			externalIDs := map[string]string{
+				"vendor":          util.CniTypeName,
+				podGatewayNodeKey: "true",
+				"pod":             fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
+				"node":            nodeName,
+			}
+			if err = c.ovnLegacyClient.AddPolicyRoute(c.config.ClusterRouter, util.PodGatewayRouterPolicyPriority, match, policyAction, nextHop, externalIDs); err != nil {
+				klog.Errorf("failed to add policy route of pod %s/%s, %v", pod.Namespace, pod.Name, err)
+				continue
+			}
+			if policyAction == ovnnb.LogicalRouterPolicyActionDrop {
+				klog.Warningf("drop the egress traffic of pod %s/%s: %s", pod.Namespace, pod.Name, reason)
+				c.recorder.Eventf(pod, v1.EventTypeWarning, "GatewayNodeUnavailable", "drop the egress traffic: %s", reason)
+			} else {
+				klog.Infof("pin the egress traffic of pod %s/%s to gateway node %s", pod.Namespace, pod.Name, nodeName)
+				c.recorder.Eventf(pod, v1.EventTypeNormal, "GatewayNodePinned", "pin the egress traffic to gateway node %s", nodeName)
+			}
+		}
+	}
+
+	for match, policy := range existingPolicies {
+		if desiredMatches[match] {
+			continue
+		}
+		klog.Infof("delete stale pod gateway node policy %s", match)
+		if err = c.ovnClient.DeleteRouterPolicy(lr, policy.UUID); err != nil {
+			klog.Errorf("failed to delete policy route %s, %v", match, err)
+		}
+	}
+}
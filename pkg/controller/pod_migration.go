@@ -0,0 +1,342 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/ovs"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func (c *Controller) runMigratePodWorker() {
+	for c.processNextMigratePodWorkItem() {
+	}
+}
+
+func (c *Controller) processNextMigratePodWorkItem() bool {
+	obj, shutdown := c.migratePodQueue.Get()
+	if shutdown {
+		return false
+	}
+
+	err := func(obj interface{}) error {
+		defer c.migratePodQueue.Done(obj)
+		var key string
+		var ok bool
+		if key, ok = obj.(string); !ok {
+			c.migratePodQueue.Forget(obj)
+			utilruntime.HandleError(fmt.Errorf("expected string in workqueue but got %#v", obj))
+			return nil
+		}
+		klog.Infof("handle migrate pod %s", key)
+		if err := c.handleMigratePod(key); err != nil {
+			c.migratePodQueue.AddRateLimited(key)
+			return fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error())
+		}
+		c.migratePodQueue.Forget(obj)
+		return nil
+	}(obj)
+
+	if err != nil {
+		utilruntime.HandleError(err)
+	}
+	return true
+}
+
+// createPodPort creates the lsp of the primary nic of the pod in the subnet
+func (c *Controller) createPodPort(pod *v1.Pod, subnet *kubeovnv1.Subnet, podName, portName, ipStr, mac string) error {
+	provider := util.OvnProvider
+	portSecurity := pod.Annotations[fmt.Sprintf(util.PortSecurityAnnotationTemplate, provider)] == "true"
+	securityGroups := pod.Annotations[fmt.Sprintf(util.SecurityGroupAnnotationTemplate, provider)]
+	vips := pod.Annotations[fmt.Sprintf(util.PortVipAnnotationTemplate, provider)]
+	hasUnknown := pod.Annotations[fmt.Sprintf(util.Layer2ForwardAnnotationTemplate, provider)] == "true"
+
+	dhcpOptions := &ovs.DHCPOptionsUUIDs{}
+	enableDHCPv4, enableDHCPv6 := subnetDHCPEnabled(subnet)
+	if enableDHCPv4 {
+		dhcpOptions.DHCPv4OptionsUUID = subnet.Status.DHCPv4OptionsUUID
+	}
+	if enableDHCPv6 {
+		dhcpOptions.DHCPv6OptionsUUID = subnet.Status.DHCPv6OptionsUUID
+	}
+	if err := c.ovnLegacyClient.CreatePort(subnet.Name, portName, ipStr, mac, podName, pod.Namespace, portSecurity, securityGroups, vips, false, enableDHCPv4 || enableDHCPv6, dhcpOptions, hasUnknown); err != nil {
+		return err
+	}
+	if subnet.Spec.ArpResponder == kubeovnv1.ArpResponderFlood || subnet.Spec.ArpResponder == kubeovnv1.ArpResponderRouter {
+		if err := c.ovnLegacyClient.SetPortArpResponder(portName, false); err != nil {
+			return err
+		}
+	}
+	if subnet.Spec.BroadcastRateLimit != 0 {
+		return c.ovnLegacyClient.SetPortBroadcastRateLimit(subnet.Name, portName, subnet.Spec.BroadcastRateLimit)
+	}
+	return nil
+}
+
+// restorePodPortGroups adds the recreated lsp of the pod to the node, network policy and security group port groups,
+// the memberships are dropped with the deleted lsp
+func (c *Controller) restorePodPortGroups(pod *v1.Pod, subnet *kubeovnv1.Subnet, portName string) error {
+	var pgNames []string
+	if subnet.Spec.Vlan == "" && subnet.Spec.Vpc == util.DefaultVpc && subnet.Spec.GatewayType == kubeovnv1.GWDistributedType &&
+		pod.Annotations[util.NorthGatewayAnnotation] == "" {
+		pgNames = append(pgNames, getOverlaySubnetsPortGroupName(subnet.Name, pod.Spec.NodeName))
+	}
+	if c.config.EnableNP {
+		nps, err := c.npsLister.NetworkPolicies(pod.Namespace).List(labels.Everything())
+		if err != nil {
+			klog.Errorf("failed to list network policies in namespace %s: %v", pod.Namespace, err)
+			return err
+		}
+		for _, np := range nps {
+			sel, err := metav1.LabelSelectorAsSelector(&np.Spec.PodSelector)
+			if err != nil || !sel.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			pgNames = append(pgNames, getNpPortGroupName(np.Namespace, getNpName(np.Name)))
+		}
+	}
+	for _, pgName := range pgNames {
+		exists, err := c.ovnLegacyClient.PortGroupExists(pgName)
+		if err != nil {
+			klog.Errorf("failed to check port group %s: %v", pgName, err)
+			return err
+		}
+		if !exists {
+			// the port group is not created yet and its ports are set on creation
+			continue
+		}
+		c.ovnPgKeyMutex.Lock(pgName)
+		err = c.ovnClient.PortGroupAddPort(pgName, portName)
+		c.ovnPgKeyMutex.Unlock(pgName)
+		if err != nil {
+			klog.Errorf("failed to add port %s to port group %s: %v", portName, pgName, err)
+			return err
+		}
+	}
+
+	if pod.Annotations[fmt.Sprintf(util.PortSecurityAnnotationTemplate, util.OvnProvider)] == "true" {
+		for _, sg := range strings.Split(pod.Annotations[fmt.Sprintf(util.SecurityGroupAnnotationTemplate, util.OvnProvider)], ",") {
+			if sg == "" {
+				continue
+			}
+			if err := c.syncSgLogicalPort(sg); err != nil {
+				klog.Errorf("failed to sync ports of security group %s: %v", sg, err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// failPodMigration restores the logical switch annotation of the pod and removes the migration request
+func (c *Controller) failPodMigration(pod *v1.Pod, oldSubnet string, reason error) error {
+	klog.Errorf("failed to migrate pod %s/%s from subnet %s: %v", pod.Namespace, pod.Name, oldSubnet, reason)
+	c.recorder.Eventf(pod, v1.EventTypeWarning, "SubnetMigrationFailed", "failed to migrate from subnet %s: %v", oldSubnet, reason)
+
+	annotations := map[string]interface{}{util.SubnetMigrationAnnotation: nil}
+	if oldSubnet != "" {
+		annotations[util.LogicalSwitchAnnotation] = oldSubnet
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+	if _, err = c.config.KubeClient.CoreV1().Pods(pod.Namespace).Patch(context.Background(), pod.Name, types.MergePatchType, patch, metav1.PatchOptions{}, ""); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		klog.Errorf("failed to restore subnet of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		return err
+	}
+	return nil
+}
+
+// handleMigratePod moves the primary nic of the running pod to the subnet in the logical switch annotation.
+// The address is allocated in the new subnet and the lsp is moved to the new logical switch before the pod
+// is patched, each step is rolled back if a later one fails. The daemon reconfigures the nic in the pod
+// once the migration annotation is set to reconfiguring.
+func (c *Controller) handleMigratePod(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	c.podKeyMutex.Lock(key)
+	defer c.podKeyMutex.Unlock(key)
+
+	cachedPod, err := c.podsLister.Pods(namespace).Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if cachedPod.Annotations[util.SubnetMigrationAnnotation] != util.SubnetMigrationRequested {
+		return nil
+	}
+	pod := cachedPod.DeepCopy()
+	provider := util.OvnProvider
+	podName := c.getNameByPod(pod)
+	portName := ovs.PodNameToPortName(podName, namespace, provider)
+	ipamKey := fmt.Sprintf("%s/%s", namespace, podName)
+
+	ipCR, err := c.ipsLister.Get(portName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return c.failPodMigration(pod, "", fmt.Errorf("no address has been allocated to the pod"))
+		}
+		klog.Errorf("failed to get ip %s: %v", portName, err)
+		return err
+	}
+	oldSubnetName := ipCR.Spec.Subnet
+	newSubnetName := pod.Annotations[util.LogicalSwitchAnnotation]
+	if newSubnetName == oldSubnetName {
+		return c.failPodMigration(pod, oldSubnetName, fmt.Errorf("the pod is already in subnet %s", newSubnetName))
+	}
+	if pod.Annotations[fmt.Sprintf(util.AllocatedAnnotationTemplate, provider)] != "true" || pod.Spec.NodeName == "" || !isPodAlive(pod) {
+		return c.failPodMigration(pod, oldSubnetName, fmt.Errorf("the pod is not running"))
+	}
+	if isVmPod, _ := isVmPod(pod); isVmPod && c.config.EnableKeepVmIP {
+		return c.failPodMigration(pod, oldSubnetName, fmt.Errorf("vm pods keeping the ip can not be migrated"))
+	}
+	if pod.Annotations[util.EipAnnotation] != "" || pod.Annotations[util.SnatAnnotation] != "" {
+		return c.failPodMigration(pod, oldSubnetName, fmt.Errorf("pods with eip or snat can not be migrated"))
+	}
+
+	oldSubnet, err := c.subnetsLister.Get(oldSubnetName)
+	if err != nil {
+		klog.Errorf("failed to get subnet %s: %v", oldSubnetName, err)
+		return err
+	}
+	newSubnet, err := c.subnetsLister.Get(newSubnetName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return c.failPodMigration(pod, oldSubnetName, fmt.Errorf("subnet %s not found", newSubnetName))
+		}
+		klog.Errorf("failed to get subnet %s: %v", newSubnetName, err)
+		return err
+	}
+	if !isOvnSubnet(newSubnet) {
+		return c.failPodMigration(pod, oldSubnetName, fmt.Errorf("subnet %s is not an ovn subnet", newSubnetName))
+	}
+	if !newSubnet.Status.IsReady() {
+		return fmt.Errorf("subnet %s is not ready", newSubnetName)
+	}
+
+	oldIP := pod.Annotations[fmt.Sprintf(util.IpAddressAnnotationTemplate, provider)]
+	mac := pod.Annotations[fmt.Sprintf(util.MacAddressAnnotationTemplate, provider)]
+	klog.Infof("migrate pod %s from subnet %s to %s", key, oldSubnetName, newSubnetName)
+	c.recorder.Eventf(pod, v1.EventTypeNormal, "SubnetMigrationStarted", "migrate from subnet %s to %s", oldSubnetName, newSubnetName)
+
+	// allocate the address with the same mac in the new subnet
+	v4IP, v6IP, _, err := c.ipam.GetRandomAddress(ipamKey, portName, mac, newSubnetName, nil, true)
+	if err != nil {
+		return c.failPodMigration(pod, oldSubnetName, fmt.Errorf("failed to allocate address in subnet %s: %v", newSubnetName, err))
+	}
+	newIP := util.GetStringIP(v4IP, v6IP)
+	rollbackAddress := func() {
+		c.ipam.ReleaseAddressByNic(ipamKey, portName, newSubnetName)
+	}
+
+	// move the lsp to the new logical switch
+	rollbackPort := func() {
+		if err := c.ovnLegacyClient.DeleteLogicalSwitchPort(portName); err != nil {
+			klog.Errorf("failed to delete lsp %s: %v", portName, err)
+		}
+		if err := c.createPodPort(pod, oldSubnet, podName, portName, oldIP, mac); err != nil {
+			klog.Errorf("failed to restore lsp %s in logical switch %s: %v", portName, oldSubnetName, err)
+			return
+		}
+		if err := c.restorePodPortGroups(pod, oldSubnet, portName); err != nil {
+			klog.Errorf("failed to restore port groups of lsp %s: %v", portName, err)
+		}
+	}
+	if err = c.ovnLegacyClient.DeleteLogicalSwitchPort(portName); err != nil {
+		rollbackAddress()
+		return c.failPodMigration(pod, oldSubnetName, err)
+	}
+	if err = c.createPodPort(pod, newSubnet, podName, portName, newIP, mac); err != nil {
+		rollbackPort()
+		rollbackAddress()
+		return c.failPodMigration(pod, oldSubnetName, fmt.Errorf("failed to create lsp in logical switch %s: %v", newSubnetName, err))
+	}
+	if err = c.restorePodPortGroups(pod, newSubnet, portName); err != nil {
+		rollbackPort()
+		rollbackAddress()
+		return c.failPodMigration(pod, oldSubnetName, fmt.Errorf("failed to add lsp to port groups: %v", err))
+	}
+	c.recorder.Eventf(pod, v1.EventTypeNormal, "SubnetMigrationPortMoved", "address %s allocated and port moved to subnet %s", newIP, newSubnetName)
+
+	podType := getPodType(pod)
+	if err = c.createOrUpdateCrdIPs(podName, newIP, mac, newSubnetName, namespace, pod.Spec.NodeName, provider, podType, nil); err != nil {
+		klog.Errorf("failed to update IP %s.%s: %v", podName, namespace, err)
+	}
+
+	// signal the daemon to reconfigure the nic in the pod
+	annotations := map[string]interface{}{
+		fmt.Sprintf(util.IpAddressAnnotationTemplate, provider):     newIP,
+		fmt.Sprintf(util.CidrAnnotationTemplate, provider):          util.SubnetCIDRForIP(newSubnet, newIP),
+		fmt.Sprintf(util.GatewayAnnotationTemplate, provider):       newSubnet.Spec.Gateway,
+		fmt.Sprintf(util.LogicalSwitchAnnotationTemplate, provider): newSubnetName,
+		fmt.Sprintf(util.RoutedAnnotationTemplate, provider):        nil,
+		fmt.Sprintf(util.LogicalRouterAnnotationTemplate, provider): nil,
+		fmt.Sprintf(util.VlanIdAnnotationTemplate, provider):        nil,
+		fmt.Sprintf(util.ProviderNetworkTemplate, provider):         nil,
+		util.SubnetMigrationAnnotation:                              util.SubnetMigrationReconfiguring,
+	}
+	if (newSubnet.Spec.Vlan == "" || newSubnet.Spec.LogicalGateway) && newSubnet.Spec.Vpc != "" {
+		annotations[fmt.Sprintf(util.LogicalRouterAnnotationTemplate, provider)] = newSubnet.Spec.Vpc
+	}
+	if newSubnet.Spec.Vlan != "" {
+		vlan, err := c.vlansLister.Get(newSubnet.Spec.Vlan)
+		if err != nil {
+			rollbackPort()
+			rollbackAddress()
+			return c.failPodMigration(pod, oldSubnetName, fmt.Errorf("failed to get vlan %s: %v", newSubnet.Spec.Vlan, err))
+		}
+		annotations[fmt.Sprintf(util.VlanIdAnnotationTemplate, provider)] = strconv.Itoa(vlan.Spec.ID)
+		annotations[fmt.Sprintf(util.ProviderNetworkTemplate, provider)] = vlan.Spec.Provider
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+	if _, err = c.config.KubeClient.CoreV1().Pods(namespace).Patch(context.Background(), name, types.MergePatchType, patch, metav1.PatchOptions{}, ""); err != nil {
+		rollbackPort()
+		rollbackAddress()
+		if err := c.createOrUpdateCrdIPs(podName, oldIP, mac, oldSubnetName, namespace, pod.Spec.NodeName, provider, podType, nil); err != nil {
+			klog.Errorf("failed to restore IP %s.%s: %v", podName, namespace, err)
+		}
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return c.failPodMigration(pod, oldSubnetName, fmt.Errorf("failed to patch pod: %v", err))
+	}
+
+	c.ipam.ReleaseAddressByNic(ipamKey, portName, oldSubnetName)
+	if c.config.EnableNP {
+		// the address sets of the network policies selecting the pod as a peer are updated with the new address
+		for _, np := range c.podMatchNetworkPolicies(pod) {
+			c.updateNpQueue.Add(np)
+		}
+	}
+	klog.Infof("pod %s is migrated from subnet %s to %s with address %s, wait for the nic to be reconfigured", key, oldSubnetName, newSubnetName, newIP)
+	c.recorder.Eventf(pod, v1.EventTypeNormal, "SubnetMigrationReconfiguring", "wait for the nic to be reconfigured with address %s", newIP)
+	c.updateSubnetStatusQueue.Add(oldSubnetName)
+	c.updateSubnetStatusQueue.Add(newSubnetName)
+	return nil
+}
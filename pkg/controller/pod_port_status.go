@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeovn/kube-ovn/pkg/ovs"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+// the port is reported not up only after it's observed not up in the consecutive rounds,
+// so that the condition doesn't flap while ovn-controller is reconnecting to the southbound database
+const podPortDownThreshold = 2
+
+// resyncPodPortStatus reflects the up status of the logical switch ports in the southbound database to the pods
+func (c *Controller) resyncPodPortStatus() {
+	bindings, err := c.ovnLegacyClient.ListPortBindings()
+	if err != nil {
+		klog.Warningf("failed to list port bindings, skip syncing port status of pods: %v", err)
+		return
+	}
+	chassisNames, err := c.ovnLegacyClient.ListChassisNames()
+	if err != nil {
+		klog.Warningf("failed to list chassis, skip syncing port status of pods: %v", err)
+		return
+	}
+	registered := make(map[string]bool, len(chassisNames))
+	for _, name := range chassisNames {
+		registered[name] = true
+	}
+
+	pods, err := c.podsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list pods, %v", err)
+		return
+	}
+
+	metricPodPortNotUp.Reset()
+	seen := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		if pod.Spec.HostNetwork || pod.Spec.NodeName == "" || !isPodAlive(pod) || !c.isNamespaceInScope(pod.Namespace) ||
+			pod.Annotations[fmt.Sprintf(util.AllocatedAnnotationTemplate, util.OvnProvider)] != "true" {
+			continue
+		}
+		key := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+		seen[key] = true
+
+		portName := ovs.PodNameToPortName(c.getNameByPod(pod), pod.Namespace, util.OvnProvider)
+		status, reason, message := c.getPodPortStatus(pod, portName, bindings, registered)
+		switch status {
+		case v1.ConditionTrue, v1.ConditionUnknown:
+			delete(c.podPortDownCounts, key)
+		case v1.ConditionFalse:
+			c.podPortDownCounts[key]++
+			if c.podPortDownCounts[key] < podPortDownThreshold {
+				continue
+			}
+			if time.Since(pod.CreationTimestamp.Time) > c.config.PodPortUpTimeout {
+				metricPodPortNotUp.WithLabelValues(pod.Namespace, pod.Name, pod.Spec.NodeName).Set(1)
+			}
+		}
+		if err = c.patchPodPortUpCondition(pod, status, reason, message); err != nil {
+			klog.Errorf("failed to set port up condition of pod %s, %v", key, err)
+		}
+	}
+	for key := range c.podPortDownCounts {
+		if !seen[key] {
+			delete(c.podPortDownCounts, key)
+		}
+	}
+}
+
+// getPodPortStatus returns unknown rather than false if the chassis of the pod is down,
+// as the port status in the southbound database is stale until the chassis recovers
+func (c *Controller) getPodPortStatus(pod *v1.Pod, portName string, bindings map[string]ovs.PortBinding, registered map[string]bool) (v1.ConditionStatus, string, string) {
+	node, err := c.nodesLister.Get(pod.Spec.NodeName)
+	if err != nil {
+		return v1.ConditionUnknown, "ChassisDown", fmt.Sprintf("failed to get node %s: %v", pod.Spec.NodeName, err)
+	}
+	if !nodeReady(node) {
+		return v1.ConditionUnknown, "ChassisDown", fmt.Sprintf("node %s is not ready", node.Name)
+	}
+	if chassis := node.Annotations[util.ChassisAnnotation]; chassis == "" || !registered[chassis] {
+		return v1.ConditionUnknown, "ChassisDown", fmt.Sprintf("chassis of node %s is not registered", node.Name)
+	}
+
+	binding, ok := bindings[portName]
+	if !ok {
+		return v1.ConditionFalse, "PortNotFound", fmt.Sprintf("port %s is not found in the southbound database", portName)
+	}
+	if binding.Chassis == "" {
+		return v1.ConditionFalse, "PortNotBound", fmt.Sprintf("port %s is not bound to any chassis", portName)
+	}
+	if binding.Up != nil && !*binding.Up {
+		return v1.ConditionFalse, "PortNotUp", fmt.Sprintf("port %s is bound but not up", portName)
+	}
+	return v1.ConditionTrue, "PortUp", ""
+}
+
+func (c *Controller) patchPodPortUpCondition(pod *v1.Pod, status v1.ConditionStatus, reason, message string) error {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == util.PortUpConditionType && condition.Status == status && condition.Reason == reason {
+			return nil
+		}
+	}
+
+	patch := map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []v1.PodCondition{{
+				Type:               util.PortUpConditionType,
+				Status:             status,
+				Reason:             reason,
+				Message:            message,
+				LastTransitionTime: metav1.Now(),
+			}},
+		},
+	}
+	bytes, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	if _, err = c.config.KubeClient.CoreV1().Pods(pod.Namespace).Patch(context.Background(), pod.Name, types.StrategicMergePatchType, bytes, metav1.PatchOptions{}, "status"); err != nil {
+		return err
+	}
+	klog.Infof("port up condition of pod %s/%s is set to %s, reason %s", pod.Namespace, pod.Name, status, reason)
+	return nil
+}
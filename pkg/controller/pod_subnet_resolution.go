@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+const (
+	// the subnet is set by the logical switch annotation of the pod
+	podSubnetSourcePod = "pod"
+	// the subnets in the logical switch annotation of the namespace
+	podSubnetSourceNamespace = "namespace"
+	// the subnets selecting the namespace by the namespaces field
+	podSubnetSourceSubnet = "subnet"
+	// the default subnet of the vpc bound to the namespace or the cluster
+	podSubnetSourceDefault = "default"
+)
+
+var podSubnetSources = []string{podSubnetSourceNamespace, podSubnetSourceSubnet, podSubnetSourceDefault}
+
+// parsePodSubnetResolutionOrder parses the comma separated precedence of the subnet sources
+func parsePodSubnetResolutionOrder(order string) ([]string, error) {
+	var sources []string
+	for _, source := range strings.Split(order, ",") {
+		source = strings.TrimSpace(source)
+		if !util.ContainsString(podSubnetSources, source) {
+			return nil, fmt.Errorf("unknown subnet source %q, must be one of %s", source, strings.Join(podSubnetSources, ","))
+		}
+		if util.ContainsString(sources, source) {
+			return nil, fmt.Errorf("duplicate subnet source %q", source)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// resolvePodDefaultSubnet resolves the subnet of the default network of the pod without the logical switch annotation
+// by the sources in the configured order, the source the subnet is resolved from is returned as well
+func (c *Controller) resolvePodDefaultSubnet(pod *v1.Pod) (*kubeovnv1.Subnet, string, error) {
+	if lsName := pod.Annotations[util.LogicalSwitchAnnotation]; lsName != "" {
+		subnet, err := c.subnetsLister.Get(lsName)
+		if err != nil {
+			klog.Errorf("failed to get subnet %v", err)
+			return nil, "", err
+		}
+		return subnet, podSubnetSourcePod, nil
+	}
+
+	ns, err := c.namespacesLister.Get(pod.Namespace)
+	if err != nil {
+		klog.Errorf("failed to get namespace %s, %v", pod.Namespace, err)
+		return nil, "", err
+	}
+	for _, source := range c.config.PodSubnetResolutionOrder {
+		var names []string
+		switch source {
+		case podSubnetSourceNamespace:
+			if ns.Annotations[util.LogicalSwitchAnnotation] != "" {
+				names = strings.Split(ns.Annotations[util.LogicalSwitchAnnotation], ",")
+			}
+		case podSubnetSourceSubnet:
+			if names, err = c.getNamespaceBoundSubnets(ns.Name); err != nil {
+				return nil, "", err
+			}
+		case podSubnetSourceDefault:
+			var name string
+			if name, err = c.getNamespaceDefaultSubnet(ns.Name); err != nil {
+				return nil, "", err
+			}
+			if name != "" {
+				names = []string{name}
+			}
+		}
+		if len(names) == 0 {
+			klog.V(3).Infof("no subnet of pod %s/%s is found from %s", pod.Namespace, pod.Name, source)
+			continue
+		}
+		subnet, err := c.pickAvailableSubnet(names)
+		if err != nil {
+			return nil, "", err
+		}
+		return subnet, source, nil
+	}
+
+	err = fmt.Errorf("namespace %s is not bound to any subnet", pod.Namespace)
+	klog.Error(err)
+	return nil, "", err
+}
+
+// getNamespaceBoundSubnets returns the subnets with the namespace in the namespaces field, ordered by name
+func (c *Controller) getNamespaceBoundSubnets(namespace string) ([]string, error) {
+	subnets, err := c.subnetsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list subnets %v", err)
+		return nil, err
+	}
+	var names []string
+	for _, subnet := range subnets {
+		if subnet.DeletionTimestamp == nil && util.ContainsString(subnet.Spec.Namespaces, namespace) {
+			names = append(names, subnet.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// getNamespaceDefaultSubnet returns the default subnet of the vpc bound to the namespace or the default vpc,
+// it returns empty if the namespace is not allowed to use the default subnet
+func (c *Controller) getNamespaceDefaultSubnet(namespace string) (string, error) {
+	vpcs, err := c.vpcsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list vpc %v", err)
+		return "", err
+	}
+	sort.Slice(vpcs, func(i, j int) bool { return vpcs[i].Name < vpcs[j].Name })
+	for _, vpc := range vpcs {
+		if util.ContainsString(vpc.Spec.Namespaces, namespace) {
+			return vpc.Status.DefaultLogicalSwitch, nil
+		}
+	}
+
+	if c.config.DisableNsDefaultSubnet && !isSystemNamespace(namespace) {
+		return "", nil
+	}
+	vpc, err := c.vpcsLister.Get(c.config.ClusterRouter)
+	if err != nil {
+		klog.Errorf("failed to get default vpc %v", err)
+		return "", err
+	}
+	if vpc.Status.DefaultLogicalSwitch != "" {
+		return vpc.Status.DefaultLogicalSwitch, nil
+	}
+	return c.config.DefaultLogicalSwitch, nil
+}
+
+// pickAvailableSubnet returns the first subnet with available ips, or the last one if all of them are exhausted
+func (c *Controller) pickAvailableSubnet(names []string) (*kubeovnv1.Subnet, error) {
+	var subnet *kubeovnv1.Subnet
+	var err error
+	for _, name := range names {
+		if name == "" {
+			err = fmt.Errorf("subnet name must not be empty")
+			klog.Error(err)
+			return nil, err
+		}
+		subnet, err = c.subnetsLister.Get(name)
+		if err != nil {
+			klog.Errorf("failed to get subnet %v", err)
+			return nil, err
+		}
+
+		switch subnet.Spec.Protocol {
+		case kubeovnv1.ProtocolIPv4:
+			fallthrough
+		case kubeovnv1.ProtocolDual:
+			if subnet.Status.V4AvailableIPs == 0 {
+				klog.V(3).Infof("there's no available ips for subnet %v, try next subnet", subnet.Name)
+				continue
+			}
+		case kubeovnv1.ProtocolIPv6:
+			if subnet.Status.V6AvailableIPs == 0 {
+				klog.Infof("there's no available ips for subnet %v, try next subnet", subnet.Name)
+				continue
+			}
+		}
+		break
+	}
+	return subnet, nil
+}
+
+// recordPodSubnetSource records the source the subnet of the pod is resolved from,
+// and warns if the source is not the preferred one
+func (c *Controller) recordPodSubnetSource(pod *v1.Pod, subnet, source string) {
+	pod.Annotations[util.SubnetSourceAnnotation] = source
+	if source == podSubnetSourcePod || source == c.config.PodSubnetResolutionOrder[0] {
+		return
+	}
+	var preferred []string
+	for _, s := range c.config.PodSubnetResolutionOrder {
+		if s == source {
+			break
+		}
+		preferred = append(preferred, s)
+	}
+	msg := fmt.Sprintf("subnet %s is resolved from %s as no subnet is found from %s", subnet, source, strings.Join(preferred, ","))
+	klog.Warningf("pod %s/%s: %s", pod.Namespace, pod.Name, msg)
+	c.recorder.Event(pod, v1.EventTypeWarning, "SubnetResolutionFallback", msg)
+}
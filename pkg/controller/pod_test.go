@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPortRemovalDelay(t *testing.T) {
+	now := time.Now()
+	exitedAt := func(d ...time.Duration) *v1.Pod {
+		pod := &v1.Pod{}
+		for _, d := range d {
+			pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, v1.ContainerStatus{
+				State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{FinishedAt: metav1.NewTime(now.Add(d))}},
+			})
+		}
+		return pod
+	}
+	running := exitedAt(-10 * time.Second)
+	running.Status.ContainerStatuses = append(running.Status.ContainerStatuses, v1.ContainerStatus{
+		State: v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+	})
+	tests := []struct {
+		name  string
+		pod   *v1.Pod
+		grace time.Duration
+		exp   time.Duration
+	}{
+		{
+			name:  "disabled",
+			pod:   exitedAt(0),
+			grace: 0,
+			exp:   0,
+		},
+		{
+			name:  "noContainerStatus",
+			pod:   &v1.Pod{},
+			grace: 30 * time.Second,
+			exp:   30 * time.Second,
+		},
+		{
+			name:  "forceDeletedRunning",
+			pod:   running,
+			grace: 30 * time.Second,
+			exp:   30 * time.Second,
+		},
+		{
+			name:  "justExited",
+			pod:   exitedAt(0),
+			grace: 30 * time.Second,
+			exp:   30 * time.Second,
+		},
+		{
+			name:  "lastContainerExited",
+			pod:   exitedAt(-20*time.Second, -10*time.Second),
+			grace: 30 * time.Second,
+			exp:   20 * time.Second,
+		},
+		{
+			name:  "expired",
+			pod:   exitedAt(-time.Minute),
+			grace: 30 * time.Second,
+			exp:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if delay := portRemovalDelay(tt.pod, tt.grace, now); delay != tt.exp {
+				t.Errorf("expected delay %v, got %v", tt.exp, delay)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"reflect"
 
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -16,7 +17,7 @@ import (
 	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
-func (c *Controller) enqueueUpdateProviderNetwork(_, obj interface{}) {
+func (c *Controller) enqueueUpdateProviderNetwork(old, obj interface{}) {
 	if !c.isLeader() {
 		return
 	}
@@ -27,6 +28,21 @@ func (c *Controller) enqueueUpdateProviderNetwork(_, obj interface{}) {
 		return
 	}
 
+	oldPn, newPn := old.(*kubeovnv1.ProviderNetwork), obj.(*kubeovnv1.ProviderNetwork)
+	if !reflect.DeepEqual(oldPn.Spec.VlanRanges, newPn.Spec.VlanRanges) {
+		// revalidate the vlans against the new allowed ranges
+		vlans, err := c.vlansLister.List(labels.Everything())
+		if err != nil {
+			klog.Errorf("failed to list vlans: %v", err)
+		}
+		for _, vlan := range vlans {
+			if vlan.Spec.Provider == newPn.Name {
+				klog.V(3).Infof("enqueue add vlan %s", vlan.Name)
+				c.addVlanQueue.Add(vlan.Name)
+			}
+		}
+	}
+
 	klog.V(3).Infof("enqueue update provider network %s", key)
 	c.updateProviderNetworkQueue.Add(key)
 }
@@ -277,6 +277,10 @@ func (c *Controller) handleAddOrUpdateSg(key string) error {
 		c.patchSgStatus(sg)
 	}
 
+	if err = c.ovnLegacyClient.SetPortGroupAclSource(ovs.GetSgPortGroupName(sg.Name), ovs.AclSourceSecurityGroup, "", sg.Name); err != nil {
+		return fmt.Errorf("failed to set source of sg %s acls, %v", key, err)
+	}
+
 	// update status
 	sg.Status.PortGroup = ovs.GetSgPortGroupName(sg.Name)
 	sg.Status.AllowSameGroupTraffic = sg.Spec.AllowSameGroupTraffic
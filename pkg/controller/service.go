@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
@@ -53,6 +54,10 @@ func (c *Controller) enqueueAddService(obj interface{}) {
 		klog.V(3).Infof("enqueue add service %s", key)
 		c.addServiceQueue.Add(key)
 	}
+	if svc.Annotations[util.ServiceLbSelectionAnnotation] != "" {
+		// the lb selection algorithm is checked by the update worker
+		c.updateServiceQueue.Add(key)
+	}
 }
 
 func (c *Controller) enqueueDeleteService(obj interface{}) {
@@ -225,6 +230,15 @@ func (c *Controller) processNextUpdateServiceWorkItem() bool {
 }
 
 func (c *Controller) handleDeleteService(service *vpcService) error {
+	if service.Svc != nil {
+		deleteLbBackendCount(service.Svc.Namespace, service.Svc.Name)
+		c.svcLbSelections.Delete(fmt.Sprintf("%s/%s", service.Svc.Namespace, service.Svc.Name))
+		if err := c.deleteDedicatedLoadBalancers(service.Svc); err != nil {
+			klog.Errorf("failed to delete dedicated lb of service %s/%s, %v", service.Svc.Namespace, service.Svc.Name, err)
+			return err
+		}
+	}
+
 	svcs, err := c.servicesLister.Services(v1.NamespaceAll).List(labels.Everything())
 	if err != nil {
 		klog.Errorf("failed to list svc, %v", err)
@@ -282,6 +296,7 @@ func (c *Controller) handleUpdateService(key string) error {
 		}
 		return err
 	}
+	c.checkServiceLbSelection(key, svc)
 
 	ip := ""
 	if vip, ok := svc.Annotations[util.SwitchLBRuleVipsAnnotation]; ok {
@@ -327,6 +342,20 @@ func (c *Controller) handleUpdateService(key string) error {
 			}
 		}
 	}
+	if hasDedicatedLoadBalancers(svc) {
+		// vips of the service are served by its dedicated loadbalancers
+		if err = c.removeDedicatedServiceVips(svc, vpc); err != nil {
+			klog.Errorf("failed to remove vips of service %s from lb of vpc %s, %v", key, vpc.Name, err)
+			return err
+		}
+		c.updateEndpointQueue.Add(key)
+		return nil
+	}
+	if err = c.deleteDedicatedLoadBalancers(svc); err != nil {
+		klog.Errorf("failed to delete dedicated lb of service %s, %v", key, err)
+		return err
+	}
+
 	// for service update
 	lbUuid, err := c.ovnLegacyClient.FindLoadbalancer(tcpLb)
 	if err != nil {
@@ -398,6 +427,241 @@ func (c *Controller) handleUpdateService(key string) error {
 	return nil
 }
 
+func isHairpinSnatService(svc *v1.Service) bool {
+	return svc.Annotations[util.ServiceHairpinSnatAnnotation] != ""
+}
+
+// hairpinSnatIPs returns the ips the hairpin traffic of the service is snated to, which are taken from the hairpin
+// snat annotation as an ipv4 address, an ipv6 address or both of them separated by a comma
+func hairpinSnatIPs(svc *v1.Service) ([]string, error) {
+	value := svc.Annotations[util.ServiceHairpinSnatAnnotation]
+	var ips []string
+	var hasV4, hasV6 bool
+	for _, s := range strings.Split(value, ",") {
+		ip := net.ParseIP(strings.TrimSpace(s))
+		switch {
+		case ip == nil:
+			return nil, fmt.Errorf("invalid hairpin snat ip %q in %q", s, value)
+		case ip.To4() != nil:
+			if hasV4 {
+				return nil, fmt.Errorf("more than one ipv4 hairpin snat ip in %q", value)
+			}
+			hasV4 = true
+		default:
+			if hasV6 {
+				return nil, fmt.Errorf("more than one ipv6 hairpin snat ip in %q", value)
+			}
+			hasV6 = true
+		}
+		ips = append(ips, ip.String())
+	}
+	return ips, nil
+}
+
+// hasDedicatedLoadBalancers returns whether the vips of the service are served by its dedicated loadbalancers
+// instead of the shared ones of the vpc, which is required by hairpin snat and lb selection algorithms
+func hasDedicatedLoadBalancers(svc *v1.Service) bool {
+	return isHairpinSnatService(svc) || svc.Annotations[util.ServiceLbSelectionAnnotation] != ""
+}
+
+// lbSelectionAlgorithm is the selection fields of ovn loadbalancers implementing an algorithm,
+// and the min ovn version supporting them
+type lbSelectionAlgorithm struct {
+	selectFields string
+	minVersion   string
+}
+
+// lbSelectionAlgorithms are the algorithms supported by the lb selection annotation. Without selection fields ovn
+// selects the backends by dp_hash. Ovn has no round robin selection, which falls back like unknown algorithms
+var lbSelectionAlgorithms = map[string]lbSelectionAlgorithm{
+	"dp_hash":        {},
+	"source_ip":      {selectFields: "ip_src", minVersion: "20.06"},
+	"source_ip_port": {selectFields: "ip_src,tp_src", minVersion: "20.06"},
+	"five_tuple":     {selectFields: "ip_src,ip_dst,tp_src,tp_dst", minVersion: "20.06"},
+}
+
+// serviceLbSelectFields returns the selection fields of the dedicated loadbalancers of the service. Session affinity
+// takes precedence over the selection algorithm, and an algorithm not supported by the running ovn falls back to
+// dp_hash
+func (c *Controller) serviceLbSelectFields(svc *v1.Service) string {
+	if svc.Spec.SessionAffinity == v1.ServiceAffinityClientIP {
+		return "ip_src"
+	}
+	selectFields, err := c.lbSelectFields(svc.Annotations[util.ServiceLbSelectionAnnotation])
+	if err != nil {
+		klog.V(3).Infof("%v of service %s/%s, fall back to dp_hash", err, svc.Namespace, svc.Name)
+	}
+	return selectFields
+}
+
+// lbSelectFields returns the selection fields of the lb selection algorithm, or an error if the algorithm is not
+// supported by the running ovn
+func (c *Controller) lbSelectFields(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	algorithm, ok := lbSelectionAlgorithms[name]
+	if !ok {
+		return "", fmt.Errorf("lb selection algorithm %s is not supported by ovn", name)
+	}
+	if algorithm.minVersion == "" {
+		return algorithm.selectFields, nil
+	}
+	version, err := c.getOvnVersion()
+	if err != nil {
+		// the version check is best effort, the selection fields are rejected by ovn if not supported
+		klog.Errorf("failed to get ovn version, %v", err)
+		return algorithm.selectFields, nil
+	}
+	if util.CompareVersion(version, algorithm.minVersion) < 0 {
+		return "", fmt.Errorf("lb selection algorithm %s requires ovn %s or later, but the running ovn is %s", name, algorithm.minVersion, version)
+	}
+	return algorithm.selectFields, nil
+}
+
+// getOvnVersion returns the version of the running ovn, which is fetched once and cached
+func (c *Controller) getOvnVersion() (string, error) {
+	if version, ok := c.ovnVersion.Load().(string); ok {
+		return version, nil
+	}
+	version, err := c.ovnLegacyClient.GetVersion()
+	if err != nil {
+		return "", err
+	}
+	c.ovnVersion.Store(version)
+	return version, nil
+}
+
+// checkServiceLbSelection warns the unsupported lb selection algorithm of the service, it's called by the service
+// worker and warns once per annotation value rather than on each service or endpoint update
+func (c *Controller) checkServiceLbSelection(key string, svc *v1.Service) {
+	algorithm := svc.Annotations[util.ServiceLbSelectionAnnotation]
+	if last, ok := c.svcLbSelections.Load(key); ok && last.(string) == algorithm {
+		return
+	}
+	if algorithm == "" {
+		c.svcLbSelections.Delete(key)
+		return
+	}
+	c.svcLbSelections.Store(key, algorithm)
+	if _, err := c.lbSelectFields(algorithm); err != nil {
+		klog.Warningf("%v of service %s/%s, fall back to dp_hash", err, svc.Namespace, svc.Name)
+		c.recorder.Eventf(svc, v1.EventTypeWarning, "LbSelectionUnsupported", "%v, fall back to dp_hash", err)
+	}
+}
+
+// dedicatedLbNames returns the names of the dedicated tcp and udp loadbalancers of a service, which are prefixed
+// with hairpin as they were introduced for hairpin snat services
+func dedicatedLbNames(svc *v1.Service) (string, string) {
+	return fmt.Sprintf("hairpin-tcp-%s.%s", svc.Name, svc.Namespace), fmt.Sprintf("hairpin-udp-%s.%s", svc.Name, svc.Namespace)
+}
+
+// ensureDedicatedLoadBalancers creates the dedicated loadbalancers of a service, which select the backends with the
+// lb selection algorithm and snat the hairpin traffic of hairpin snat services to the annotated ips, and adds them to
+// the logical switches of the vpc. The vips are still served without hairpin snat if the annotated ips are invalid
+func (c *Controller) ensureDedicatedLoadBalancers(svc *v1.Service, vpcName string) (string, string, error) {
+	selectFields := c.serviceLbSelectFields(svc)
+	var snatIPs []string
+	if isHairpinSnatService(svc) {
+		var err error
+		if snatIPs, err = hairpinSnatIPs(svc); err != nil {
+			klog.Errorf("%v of service %s/%s, hairpin snat is disabled", err, svc.Namespace, svc.Name)
+			c.recorder.Eventf(svc, v1.EventTypeWarning, "InvalidHairpinSnatIP", "%v, hairpin snat is disabled", err)
+		}
+	}
+
+	tcpLb, udpLb := dedicatedLbNames(svc)
+	if err := c.ovnLegacyClient.CreateHairpinLoadBalancer(tcpLb, util.ProtocolTCP, selectFields, vpcName, snatIPs); err != nil {
+		klog.Errorf("failed to create dedicated tcp lb %s, %v", tcpLb, err)
+		return "", "", err
+	}
+	if err := c.ovnLegacyClient.CreateHairpinLoadBalancer(udpLb, util.ProtocolUDP, selectFields, vpcName, snatIPs); err != nil {
+		klog.Errorf("failed to create dedicated udp lb %s, %v", udpLb, err)
+		return "", "", err
+	}
+
+	if err := c.addLoadBalancersToVpcSwitches(vpcName, tcpLb, udpLb); err != nil {
+		return "", "", err
+	}
+	return tcpLb, udpLb, nil
+}
+
+func (c *Controller) deleteDedicatedLoadBalancers(svc *v1.Service) error {
+	tcpLb, udpLb := dedicatedLbNames(svc)
+	return c.deleteServiceLoadBalancers(tcpLb, udpLb)
+}
+
+func (c *Controller) addLoadBalancersToVpcSwitches(vpcName string, lbs ...string) error {
+	subnets, err := c.subnetsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list subnets, %v", err)
+		return err
+	}
+	for _, subnet := range subnets {
+		if subnet.Spec.Vpc != vpcName || subnet.Name == c.config.NodeSwitch {
+			continue
+		}
+		for _, lb := range lbs {
+			if err = c.ovnLegacyClient.AddLoadBalancerToLogicalSwitch(lb, subnet.Name); err != nil {
+				klog.Errorf("failed to add lb %s to logical switch %s, %v", lb, subnet.Name, err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Controller) deleteServiceLoadBalancers(lbs ...string) error {
+	for _, lb := range lbs {
+		lbUuid, err := c.ovnLegacyClient.FindLoadbalancer(lb)
+		if err != nil {
+			klog.Errorf("failed to get lb %s, %v", lb, err)
+			return err
+		}
+		if lbUuid == "" {
+			continue
+		}
+		klog.Infof("delete lb %s", lb)
+		if err = c.ovnLegacyClient.DeleteLoadBalancer(lb); err != nil {
+			klog.Errorf("failed to delete lb %s, %v", lb, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// removeDedicatedServiceVips removes the vips of a service with dedicated loadbalancers from the shared loadbalancers of the vpc
+func (c *Controller) removeDedicatedServiceVips(svc *v1.Service, vpc *kubeovnv1.Vpc) error {
+	ips := svc.Spec.ClusterIPs
+	if vip, ok := svc.Annotations[util.SwitchLBRuleVipsAnnotation]; ok {
+		ips = []string{vip}
+	} else if len(ips) == 0 {
+		ips = []string{svc.Spec.ClusterIP}
+	}
+
+	tcpLbs := []string{vpc.Status.TcpLoadBalancer, vpc.Status.TcpSessionLoadBalancer}
+	udpLbs := []string{vpc.Status.UdpLoadBalancer, vpc.Status.UdpSessionLoadBalancer}
+	for _, ip := range ips {
+		for _, port := range svc.Spec.Ports {
+			lbs := udpLbs
+			if port.Protocol == v1.ProtocolTCP {
+				lbs = tcpLbs
+			}
+			vip := util.JoinHostPort(ip, port.Port)
+			for _, lb := range lbs {
+				if lb == "" {
+					continue
+				}
+				if err := c.ovnLegacyClient.DeleteLoadBalancerVip(vip, lb); err != nil {
+					klog.Errorf("failed to delete vip %s from lb %s, %v", vip, lb, err)
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // Parse key of map, [fd00:10:96::11c9]:10665 for example
 func parseVipAddr(vipStr string) string {
 	vip := strings.Split(vipStr, ":")[0]
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func TestHairpinSnatIPs(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		ips     []string
+		wantErr bool
+	}{
+		{name: "ipv4", value: "169.254.0.5", ips: []string{"169.254.0.5"}},
+		{name: "ipv6", value: "fd00::5", ips: []string{"fd00::5"}},
+		{name: "dual", value: "169.254.0.5, fd00::5", ips: []string{"169.254.0.5", "fd00::5"}},
+		{name: "normalized", value: "fd00:0::05", ips: []string{"fd00::5"}},
+		{name: "legacy", value: "true", wantErr: true},
+		{name: "cidr", value: "169.254.0.5/32", wantErr: true},
+		{name: "twoIPv4", value: "169.254.0.5,169.254.0.6", wantErr: true},
+		{name: "twoIPv6", value: "fd00::5,fd00::6", wantErr: true},
+		{name: "trailingComma", value: "169.254.0.5,", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{
+				Name:        "svc",
+				Namespace:   "default",
+				Annotations: map[string]string{util.ServiceHairpinSnatAnnotation: tt.value},
+			}}
+			if !isHairpinSnatService(svc) {
+				t.Fatalf("service with annotation %q is not a hairpin snat service", tt.value)
+			}
+			ips, err := hairpinSnatIPs(svc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("hairpinSnatIPs(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(ips, tt.ips) {
+				t.Errorf("hairpinSnatIPs(%q) = %v, want %v", tt.value, ips, tt.ips)
+			}
+		})
+	}
+}
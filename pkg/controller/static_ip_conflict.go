@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+const (
+	// fail the pod whose static ip is used by another pod
+	staticIPConflictPolicyFail = "fail"
+	// reclaim the static ip after the holder is confirmed deleted and the grace period elapses
+	staticIPConflictPolicyReclaim = "reclaim"
+)
+
+type staticIPHolderState int
+
+const (
+	staticIPHolderAlive staticIPHolderState = iota
+	staticIPHolderTerminating
+	staticIPHolderDeleted
+)
+
+// getStaticIPHolders returns the nics of the pods other than the requester holding the ips in the subnet
+func (c *Controller) getStaticIPHolders(key, ipStr, subnet string) (map[string][]string, error) {
+	allocations, err := c.ipam.ListSubnetAllocations(subnet)
+	if err != nil {
+		return nil, err
+	}
+	ips := strings.Split(ipStr, ",")
+	holders := make(map[string][]string)
+	for _, allocation := range allocations {
+		if allocation.Reserved || !util.ContainsString(ips, allocation.IP) {
+			continue
+		}
+		for _, owner := range strings.Split(allocation.Owner, ",") {
+			if owner != "" && owner != key {
+				holders[owner] = append(holders[owner], allocation.Nic)
+			}
+		}
+	}
+	return holders, nil
+}
+
+// getStaticIPHolderState checks the holder against the apiserver rather than the cache,
+// so that the ip is only reclaimed when the holder is confirmed deleted
+func (c *Controller) getStaticIPHolderState(holder string) (staticIPHolderState, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(holder)
+	if err != nil {
+		return staticIPHolderAlive, err
+	}
+	pod, err := c.config.KubeClient.CoreV1().Pods(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return staticIPHolderAlive, err
+		}
+		if !c.config.EnableKeepVmIP {
+			return staticIPHolderDeleted, nil
+		}
+		// the holder may be a vm whose ip is kept across its pods
+		pods, err := c.podsLister.Pods(namespace).List(labels.Everything())
+		if err != nil {
+			return staticIPHolderAlive, err
+		}
+		pod = nil
+		for _, p := range pods {
+			if c.getNameByPod(p) == name {
+				pod = p
+				break
+			}
+		}
+		if pod == nil {
+			return staticIPHolderDeleted, nil
+		}
+	}
+	if pod.DeletionTimestamp != nil || !isPodAlive(pod) {
+		return staticIPHolderTerminating, nil
+	}
+	return staticIPHolderAlive, nil
+}
+
+// handleStaticIPConflict applies the conflict policy to the static ip requested by the pod and used by other pods,
+// it returns nil only if the holders have been reclaimed
+func (c *Controller) handleStaticIPConflict(pod *v1.Pod, key, ipStr, subnet string) error {
+	holders, err := c.getStaticIPHolders(key, ipStr, subnet)
+	if err != nil {
+		klog.Errorf("failed to get holders of static ip %s in subnet %s, %v", ipStr, subnet, err)
+		return err
+	}
+	if len(holders) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(holders))
+	for holder := range holders {
+		names = append(names, holder)
+	}
+	sort.Strings(names)
+
+	if c.config.StaticIPConflictPolicy != staticIPConflictPolicyReclaim {
+		err = fmt.Errorf("static ip %s in subnet %s is used by %s", ipStr, subnet, strings.Join(names, ","))
+		klog.Error(err)
+		c.recorder.Event(pod, v1.EventTypeWarning, "StaticIPConflictFailed", err.Error())
+		return err
+	}
+
+	for _, holder := range names {
+		conflictKey := fmt.Sprintf("%s/%s/%s", subnet, ipStr, holder)
+		state, err := c.getStaticIPHolderState(holder)
+		if err != nil {
+			klog.Errorf("failed to get state of static ip %s holder %s, %v", ipStr, holder, err)
+			return err
+		}
+		switch state {
+		case staticIPHolderAlive:
+			c.staticIPConflicts.Delete(conflictKey)
+			err = fmt.Errorf("static ip %s in subnet %s is used by running pod %s", ipStr, subnet, holder)
+			klog.Error(err)
+			c.recorder.Event(pod, v1.EventTypeWarning, "StaticIPConflictFailed", err.Error())
+			return err
+		case staticIPHolderTerminating:
+			c.staticIPConflicts.Delete(conflictKey)
+			err = fmt.Errorf("static ip %s in subnet %s is used by terminating pod %s, waiting for it to be deleted", ipStr, subnet, holder)
+			klog.Warning(err)
+			c.recorder.Event(pod, v1.EventTypeWarning, "StaticIPConflictWaiting", err.Error())
+			return err
+		case staticIPHolderDeleted:
+			deletedAt, _ := c.staticIPConflicts.LoadOrStore(conflictKey, time.Now())
+			if remaining := c.config.StaticIPReclaimGracePeriod - time.Since(deletedAt.(time.Time)); remaining > 0 {
+				err = fmt.Errorf("static ip %s in subnet %s is held by deleted pod %s, reclaim it in %s", ipStr, subnet, holder, remaining.Round(time.Second))
+				klog.Warning(err)
+				c.recorder.Event(pod, v1.EventTypeWarning, "StaticIPConflictWaiting", err.Error())
+				return err
+			}
+		}
+	}
+
+	for _, holder := range names {
+		klog.Infof("reclaim static ip %s in subnet %s from deleted pod %s for %s", ipStr, subnet, holder, key)
+		c.ipam.ReleaseAddressByPod(holder)
+		for _, nic := range holders[holder] {
+			if err = c.ovnLegacyClient.DeleteLogicalSwitchPort(nic); err != nil {
+				klog.Errorf("failed to delete lsp %s of deleted pod %s, %v", nic, holder, err)
+				return err
+			}
+		}
+		c.staticIPConflicts.Delete(fmt.Sprintf("%s/%s/%s", subnet, ipStr, holder))
+	}
+	c.recorder.Eventf(pod, v1.EventTypeNormal, "StaticIPReclaimed", "reclaim static ip %s in subnet %s from deleted pods %s", ipStr, subnet, strings.Join(names, ","))
+	return nil
+}
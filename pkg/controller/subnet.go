@@ -6,7 +6,9 @@ import (
 	"net"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -37,6 +39,9 @@ func (c *Controller) enqueueAddSubnet(obj interface{}) {
 	}
 	klog.V(3).Infof("enqueue add subnet %s", key)
 	c.addOrUpdateSubnetQueue.Add(key)
+	if obj.(*kubeovnv1.Subnet).Annotations[util.GatewayRebalanceAnnotation] == "true" {
+		c.rebalanceGatewayQueue.Add(key)
+	}
 }
 
 func (c *Controller) enqueueDeleteSubnet(obj interface{}) {
@@ -55,6 +60,9 @@ func (c *Controller) enqueueDeleteSubnet(obj interface{}) {
 	if subnet.Spec.GatewayType == kubeovnv1.GWCentralizedType {
 		c.deleteRouteQueue.Add(obj)
 	}
+	if subnet.Spec.EnableReverseDNS {
+		c.enqueueVpcDnsOfSubnet(subnet)
+	}
 }
 
 func (c *Controller) enqueueUpdateSubnet(old, new interface{}) {
@@ -82,27 +90,57 @@ func (c *Controller) enqueueUpdateSubnet(old, new interface{}) {
 		return
 	}
 
-	if oldSubnet.Spec.Private != newSubnet.Spec.Private ||
+	if oldSubnet.Annotations[util.ReconcileAnnotation] != newSubnet.Annotations[util.ReconcileAnnotation] ||
+		oldSubnet.Spec.Private != newSubnet.Spec.Private ||
 		oldSubnet.Spec.CIDRBlock != newSubnet.Spec.CIDRBlock ||
+		!reflect.DeepEqual(oldSubnet.Spec.ExtraCIDRBlocks, newSubnet.Spec.ExtraCIDRBlocks) ||
 		!reflect.DeepEqual(oldSubnet.Spec.AllowSubnets, newSubnet.Spec.AllowSubnets) ||
 		!reflect.DeepEqual(oldSubnet.Spec.Namespaces, newSubnet.Spec.Namespaces) ||
 		oldSubnet.Spec.GatewayType != newSubnet.Spec.GatewayType ||
 		oldSubnet.Spec.GatewayNode != newSubnet.Spec.GatewayNode ||
 		oldSubnet.Spec.LogicalGateway != newSubnet.Spec.LogicalGateway ||
+		oldSubnet.Spec.GatewayMode != newSubnet.Spec.GatewayMode ||
+		oldSubnet.Spec.MacOUI != newSubnet.Spec.MacOUI ||
+		oldSubnet.Spec.SuppressGatewayArp != newSubnet.Spec.SuppressGatewayArp ||
 		oldSubnet.Spec.Gateway != newSubnet.Spec.Gateway ||
 		!reflect.DeepEqual(oldSubnet.Spec.ExcludeIps, newSubnet.Spec.ExcludeIps) ||
 		!reflect.DeepEqual(oldSubnet.Spec.Vips, newSubnet.Spec.Vips) ||
 		oldSubnet.Spec.Vlan != newSubnet.Spec.Vlan ||
 		oldSubnet.Spec.EnableDHCP != newSubnet.Spec.EnableDHCP ||
+		oldSubnet.Spec.DisableDHCP != newSubnet.Spec.DisableDHCP ||
+		oldSubnet.Spec.DisableDHCPv4 != newSubnet.Spec.DisableDHCPv4 ||
+		oldSubnet.Spec.DisableDHCPv6 != newSubnet.Spec.DisableDHCPv6 ||
 		oldSubnet.Spec.DHCPv4Options != newSubnet.Spec.DHCPv4Options ||
 		oldSubnet.Spec.DHCPv6Options != newSubnet.Spec.DHCPv6Options ||
+		oldSubnet.Spec.DHCPLeaseTime != newSubnet.Spec.DHCPLeaseTime ||
 		oldSubnet.Spec.EnableIPv6RA != newSubnet.Spec.EnableIPv6RA ||
 		oldSubnet.Spec.IPv6RAConfigs != newSubnet.Spec.IPv6RAConfigs ||
 		oldSubnet.Spec.Protocol != newSubnet.Spec.Protocol ||
+		oldSubnet.Spec.AggregateEgressRate != newSubnet.Spec.AggregateEgressRate ||
+		!reflect.DeepEqual(oldSubnet.Spec.LogicalSwitchOptions, newSubnet.Spec.LogicalSwitchOptions) ||
+		oldSubnet.Spec.EnableMulticastSnoop != newSubnet.Spec.EnableMulticastSnoop ||
+		oldSubnet.Spec.EnableMulticastQuerier != newSubnet.Spec.EnableMulticastQuerier ||
+		oldSubnet.Spec.MulticastQuerierIP != newSubnet.Spec.MulticastQuerierIP ||
 		!reflect.DeepEqual(oldSubnet.Spec.Acls, newSubnet.Spec.Acls) {
 		klog.V(3).Infof("enqueue update subnet %s", key)
 		c.addOrUpdateSubnetQueue.Add(key)
 	}
+
+	if oldSubnet.Spec.GatewayUnavailablePolicy != newSubnet.Spec.GatewayUnavailablePolicy {
+		c.enqueueUnroutedPods(newSubnet.Name)
+	}
+
+	if oldSubnet.Spec.EnableReverseDNS != newSubnet.Spec.EnableReverseDNS ||
+		(newSubnet.Spec.EnableReverseDNS && oldSubnet.Spec.CIDRBlock != newSubnet.Spec.CIDRBlock) {
+		c.enqueueVpcDnsOfSubnet(newSubnet)
+	}
+
+	// the rebalance requested during maintenance is handled once the reconciliation is resumed
+	if newSubnet.Annotations[util.GatewayRebalanceAnnotation] == "true" &&
+		(oldSubnet.Annotations[util.GatewayRebalanceAnnotation] != "true" || (isSubnetReconcilePaused(oldSubnet) && !isSubnetReconcilePaused(newSubnet))) {
+		klog.V(3).Infof("enqueue rebalance gateways of subnet %s", key)
+		c.rebalanceGatewayQueue.Add(key)
+	}
 }
 
 func (c *Controller) runAddSubnetWorker() {
@@ -301,6 +339,11 @@ func formatSubnet(subnet *kubeovnv1.Subnet, c *Controller) error {
 		subnet.Spec.GatewayType = kubeovnv1.GWDistributedType
 		changed = true
 	}
+	if subnet.Spec.GatewayMode == kubeovnv1.GatewayModeL3 && subnet.Spec.Vlan != "" && !subnet.Spec.LogicalGateway {
+		// underlay subnets use the ovn gateway by the logical gateway
+		subnet.Spec.LogicalGateway = true
+		changed = true
+	}
 	if subnet.Spec.Vpc == "" {
 		changed = true
 		subnet.Spec.Vpc = util.DefaultVpc
@@ -399,6 +442,7 @@ func checkAndUpdateExcludeIps(subnet *kubeovnv1.Subnet) bool {
 	changed := false
 	var excludeIps []string
 	excludeIps = append(excludeIps, strings.Split(subnet.Spec.Gateway, ",")...)
+	excludeIps = append(excludeIps, util.ExtraCIDRRouterIPs(subnet)...)
 	sort.Strings(excludeIps)
 	if len(subnet.Spec.ExcludeIps) == 0 {
 		subnet.Spec.ExcludeIps = excludeIps
@@ -474,6 +518,98 @@ func (c Controller) patchSubnetStatus(subnet *kubeovnv1.Subnet, reason string, e
 	}
 }
 
+// isSubnetReconcilePaused checks whether the reconciliation of the ovn resources of the subnet is paused for maintenance
+func isSubnetReconcilePaused(subnet *kubeovnv1.Subnet) bool {
+	return subnet.Annotations[util.ReconcileAnnotation] == "false"
+}
+
+// patchSubnetMaintenance sets the maintenance condition of the subnet when the reconciliation is paused,
+// and clears it when the reconciliation is resumed
+func (c *Controller) patchSubnetMaintenance(subnet *kubeovnv1.Subnet, paused bool) error {
+	cond := subnet.Status.GetCondition(kubeovnv1.Maintenance)
+	if paused {
+		if cond != nil && cond.Status == v1.ConditionTrue {
+			return nil
+		}
+		subnet.Status.SetCondition(kubeovnv1.Maintenance, "ReconcilePaused", fmt.Sprintf("ovn resources are not reconciled until annotation %s is removed", util.ReconcileAnnotation))
+		c.recorder.Eventf(subnet, v1.EventTypeNormal, "ReconcilePaused", "reconciliation of ovn resources is paused")
+	} else {
+		if cond == nil || cond.Status != v1.ConditionTrue {
+			return nil
+		}
+		subnet.Status.ClearCondition(kubeovnv1.Maintenance, "ReconcileResumed", "")
+		c.recorder.Eventf(subnet, v1.EventTypeNormal, "ReconcileResumed", "reconciliation of ovn resources is resumed")
+	}
+
+	bytes, err := subnet.Status.Bytes()
+	if err != nil {
+		klog.Error(err)
+		return err
+	}
+	if _, err = c.config.KubeOvnClient.KubeovnV1().Subnets().Patch(context.Background(), subnet.Name, types.MergePatchType, bytes, metav1.PatchOptions{}, "status"); err != nil {
+		klog.Errorf("failed to patch status of subnet %s, %v", subnet.Name, err)
+		return err
+	}
+	return nil
+}
+
+// reconcileSubnetArpResponder applies the arp responder mode to the ports of the subnet and records the tradeoff
+// in the ArpResponder condition of the subnet
+func (c *Controller) reconcileSubnetArpResponder(subnet *kubeovnv1.Subnet, lr string, needRouter bool) error {
+	mode := subnet.Spec.ArpResponder
+	if mode == "" {
+		mode = kubeovnv1.ArpResponderDistributed
+	}
+
+	if err := c.ovnLegacyClient.SetLogicalSwitchPortsArpResponder(subnet.Name, mode == kubeovnv1.ArpResponderDistributed); err != nil {
+		c.patchSubnetStatus(subnet, "SetArpResponderFailed", err.Error())
+		return err
+	}
+	if needRouter {
+		var cidrs []string
+		if mode == kubeovnv1.ArpResponderRouter {
+			cidrs = strings.Split(util.SubnetCIDRs(subnet), ",")
+		}
+		if err := c.ovnLegacyClient.SetRouterPortArpProxy(subnet.Name, lr, cidrs); err != nil {
+			c.patchSubnetStatus(subnet, "SetArpResponderFailed", err.Error())
+			return err
+		}
+	}
+	// the acls are kept by the logical switch after the router port is removed, so they are cleaned anyway
+	var gateways []string
+	if needRouter && subnet.Spec.SuppressGatewayArp {
+		gateways = strings.Split(subnet.Spec.Gateway, ",")
+	}
+	if err := c.ovnLegacyClient.SetRouterPortGatewayArpSuppression(subnet.Name, lr, gateways); err != nil {
+		c.patchSubnetStatus(subnet, "SetGatewayArpSuppressionFailed", err.Error())
+		return err
+	}
+
+	var reason, message string
+	switch mode {
+	case kubeovnv1.ArpResponderFlood:
+		reason, message = "Flood", "arp/nd requests are flooded to all ports of the subnet, fewer flows at the cost of broadcast traffic"
+	case kubeovnv1.ArpResponderRouter:
+		reason, message = "RouterProxy", "arp/nd requests are answered by the router port, fewer flows but the traffic between pods of the subnet is routed"
+	default:
+		reason, message = "Distributed", "arp/nd requests are answered by the logical switch for every port, no broadcast traffic but one set of flows per port"
+	}
+	if cond := subnet.Status.GetCondition(kubeovnv1.ArpResponder); cond != nil && cond.Reason == reason {
+		return nil
+	}
+	subnet.Status.SetCondition(kubeovnv1.ArpResponder, reason, message)
+	bytes, err := subnet.Status.Bytes()
+	if err != nil {
+		klog.Error(err)
+		return err
+	}
+	if _, err = c.config.KubeOvnClient.KubeovnV1().Subnets().Patch(context.Background(), subnet.Name, types.MergePatchType, bytes, metav1.PatchOptions{}, "status"); err != nil {
+		klog.Errorf("failed to patch status of subnet %s, %v", subnet.Name, err)
+		return err
+	}
+	return nil
+}
+
 func (c *Controller) handleAddOrUpdateSubnet(key string) error {
 	var err error
 
@@ -552,21 +688,36 @@ func (c *Controller) handleAddOrUpdateSubnet(key string) error {
 		return err
 	}
 
-	if err := c.ipam.AddOrUpdateSubnet(subnet.Name, subnet.Spec.CIDRBlock, subnet.Spec.Gateway, subnet.Spec.ExcludeIps); err != nil {
+	if err := c.ipam.AddOrUpdateSubnet(subnet.Name, subnet.Spec.CIDRBlock, subnet.Spec.Gateway, subnet.Spec.ExcludeIps, subnet.Spec.ExtraCIDRBlocks...); err != nil {
 		return err
 	}
+	c.ipam.SetSubnetMacOUI(subnet.Name, subnet.Spec.MacOUI)
 
 	if !isOvnSubnet(subnet) {
 		return nil
 	}
 
+	// the ipam above is still tracked so that pods can be added and deleted during maintenance
+	paused := isSubnetReconcilePaused(subnet)
+	if err = c.patchSubnetMaintenance(subnet, paused); err != nil {
+		return err
+	}
+	if paused {
+		klog.Infof("reconciliation of subnet %s is paused by annotation %s", subnet.Name, util.ReconcileAnnotation)
+		return nil
+	}
+
 	if err = util.ValidateSubnet(*subnet); err != nil {
 		klog.Errorf("failed to validate subnet %s, %v", subnet.Name, err)
 		c.patchSubnetStatus(subnet, "ValidateLogicalSwitchFailed", err.Error())
 		return err
-	} else {
-		c.patchSubnetStatus(subnet, "ValidateLogicalSwitchSuccess", "")
 	}
+	if err = c.checkSubnetGatewayMode(subnet); err != nil {
+		klog.Errorf("failed to validate gateway mode of subnet %s, %v", subnet.Name, err)
+		c.patchSubnetStatus(subnet, "ValidateGatewayModeFailed", err.Error())
+		return err
+	}
+	c.patchSubnetStatus(subnet, "ValidateLogicalSwitchSuccess", "")
 
 	subnetList, err := c.subnetsLister.List(labels.Everything())
 	if err != nil {
@@ -574,13 +725,15 @@ func (c *Controller) handleAddOrUpdateSubnet(key string) error {
 		return err
 	}
 
+	// the extra cidr blocks are checked along with the cidr block
+	subnetCIDRs := util.SubnetCIDRs(subnet)
 	for _, sub := range subnetList {
 		if sub.Spec.Vpc != subnet.Spec.Vpc || sub.Spec.Vlan != subnet.Spec.Vlan || sub.Name == subnet.Name {
 			continue
 		}
 
-		if util.CIDROverlap(sub.Spec.CIDRBlock, subnet.Spec.CIDRBlock) {
-			err = fmt.Errorf("subnet %s cidr %s is conflict with subnet %s cidr %s", subnet.Name, subnet.Spec.CIDRBlock, sub.Name, sub.Spec.CIDRBlock)
+		if subCIDRs := util.SubnetCIDRs(sub); util.CIDROverlap(subCIDRs, subnetCIDRs) {
+			err = fmt.Errorf("subnet %s cidr %s is conflict with subnet %s cidr %s", subnet.Name, subnetCIDRs, sub.Name, subCIDRs)
 			klog.Error(err)
 			c.patchSubnetStatus(subnet, "ValidateLogicalSwitchFailed", err.Error())
 			return err
@@ -603,11 +756,16 @@ func (c *Controller) handleAddOrUpdateSubnet(key string) error {
 		}
 		for _, node := range nodes {
 			for _, addr := range node.Status.Addresses {
-				if addr.Type == v1.NodeInternalIP && util.CIDRContainIP(subnet.Spec.CIDRBlock, addr.Address) {
-					err = fmt.Errorf("subnet %s cidr %s conflict with node %s address %s", subnet.Name, subnet.Spec.CIDRBlock, node.Name, addr.Address)
-					klog.Error(err)
-					c.patchSubnetStatus(subnet, "ValidateLogicalSwitchFailed", err.Error())
-					return err
+				if addr.Type != v1.NodeInternalIP {
+					continue
+				}
+				for _, cidr := range strings.Split(subnetCIDRs, ",") {
+					if util.CIDRContainIP(cidr, addr.Address) {
+						err = fmt.Errorf("subnet %s cidr %s conflict with node %s address %s", subnet.Name, cidr, node.Name, addr.Address)
+						klog.Error(err)
+						c.patchSubnetStatus(subnet, "ValidateLogicalSwitchFailed", err.Error())
+						return err
+					}
 				}
 			}
 		}
@@ -635,6 +793,10 @@ func (c *Controller) handleAddOrUpdateSubnet(key string) error {
 			c.patchSubnetStatus(subnet, "CreateLogicalSwitchFailed", err.Error())
 			return err
 		}
+		// the logical switch may be recreated for a subnet which has been ready, only the initial readiness is recorded
+		if !subnet.Status.IsReady() {
+			c.subnetsPendingReady.Store(subnet.Name, true)
+		}
 		if needRouter {
 			if err := c.reconcileRouterPortBySubnet(vpc, subnet); err != nil {
 				klog.Errorf("failed to connect switch %s to router %s, %v", subnet.Name, vpc.Name, err)
@@ -646,10 +808,26 @@ func (c *Controller) handleAddOrUpdateSubnet(key string) error {
 			// do nothing if subnet is underlay vlan and use underlay gw
 			// TODO:// support update if spec changed
 			klog.Infof("skip reset external connection from vpc %s to switch %s", vpc.Status.Router, subnet.Name)
+			if err := c.reconcileSubnetArpResponder(subnet, vpc.Status.Router, needRouter); err != nil {
+				klog.Errorf("failed to reconcile arp responder of subnet %s, %v", subnet.Name, err)
+				return err
+			}
+			if err := c.setSubnetAggregateRate(subnet); err != nil {
+				return err
+			}
+			if err := c.ovnLegacyClient.SetLogicalSwitchBroadcastRateLimit(subnet.Name, subnet.Spec.BroadcastRateLimit); err != nil {
+				c.patchSubnetStatus(subnet, "SetLogicalSwitchBroadcastRateLimitFailed", err.Error())
+				return err
+			}
+			if err := c.ovnLegacyClient.SetLogicalSwitchOptions(subnet.Name, util.SubnetLogicalSwitchOptions(subnet)); err != nil {
+				c.patchSubnetStatus(subnet, "SetLogicalSwitchOptionsFailed", err.Error())
+				return err
+			}
+			c.recordSubnetReady(subnet)
 			return nil
 		}
 		// logical switch exists, only update other_config
-		if err := c.ovnLegacyClient.SetLogicalSwitchConfig(subnet.Name, vpc.Status.Router, subnet.Spec.Protocol, subnet.Spec.CIDRBlock, subnet.Spec.Gateway, subnet.Spec.ExcludeIps, needRouter); err != nil {
+		if err := c.ovnLegacyClient.SetLogicalSwitchConfig(subnet.Name, vpc.Status.Router, subnet.Spec.Protocol, subnet.Spec.CIDRBlock, subnet.Spec.Gateway, util.ExtraCIDRNetworks(subnet), subnet.Spec.ExcludeIps, needRouter); err != nil {
 			c.patchSubnetStatus(subnet, "SetLogicalSwitchConfigFailed", err.Error())
 			return err
 		}
@@ -663,7 +841,8 @@ func (c *Controller) handleAddOrUpdateSubnet(key string) error {
 	}
 
 	var dhcpOptionsUUIDs *ovs.DHCPOptionsUUIDs
-	dhcpOptionsUUIDs, err = c.ovnLegacyClient.UpdateDHCPOptions(subnet.Name, subnet.Spec.CIDRBlock, subnet.Spec.Gateway, subnet.Spec.DHCPv4Options, subnet.Spec.DHCPv6Options, subnet.Spec.EnableDHCP)
+	enableDHCPv4, enableDHCPv6 := subnetDHCPEnabled(subnet)
+	dhcpOptionsUUIDs, err = c.ovnLegacyClient.UpdateDHCPOptions(subnet.Name, subnet.Spec.CIDRBlock, subnet.Spec.Gateway, subnet.Spec.DHCPv4Options, subnet.Spec.DHCPv6Options, subnet.Spec.DHCPLeaseTime, enableDHCPv4, enableDHCPv6)
 	if err != nil {
 		klog.Errorf("failed to update dhcp options for switch %s, %v", subnet.Name, err)
 		return err
@@ -696,6 +875,17 @@ func (c *Controller) handleAddOrUpdateSubnet(key string) error {
 			c.patchSubnetStatus(subnet, "AddLbToLogicalSwitchFailed", err.Error())
 			return err
 		}
+		hairpinLbs, err := c.ovnLegacyClient.ListHairpinLoadBalancers(vpc.Name)
+		if err != nil {
+			klog.Errorf("failed to list hairpin lb of vpc %s, %v", vpc.Name, err)
+			return err
+		}
+		for _, lb := range hairpinLbs {
+			if err = c.ovnLegacyClient.AddLoadBalancerToLogicalSwitch(lb, subnet.Name); err != nil {
+				c.patchSubnetStatus(subnet, "AddLbToLogicalSwitchFailed", err.Error())
+				return err
+			}
+		}
 	}
 
 	if err := c.reconcileSubnet(subnet); err != nil {
@@ -722,10 +912,43 @@ func (c *Controller) handleAddOrUpdateSubnet(key string) error {
 		return err
 	}
 
+	if err := c.ovnLegacyClient.SetLogicalSwitchGatewayPing(subnet.Name, util.SubnetCIDRs(subnet), subnet.Spec.Gateway, subnet.Spec.AllowGatewayPing); err != nil {
+		c.patchSubnetStatus(subnet, "SetLogicalSwitchGatewayPingFailed", err.Error())
+		return err
+	}
+
+	if err := c.setSubnetAggregateRate(subnet); err != nil {
+		return err
+	}
+	if err := c.ovnLegacyClient.SetLogicalSwitchBroadcastRateLimit(subnet.Name, subnet.Spec.BroadcastRateLimit); err != nil {
+		c.patchSubnetStatus(subnet, "SetLogicalSwitchBroadcastRateLimitFailed", err.Error())
+		return err
+	}
+	if err := c.ovnLegacyClient.SetLogicalSwitchOptions(subnet.Name, util.SubnetLogicalSwitchOptions(subnet)); err != nil {
+		c.patchSubnetStatus(subnet, "SetLogicalSwitchOptionsFailed", err.Error())
+		return err
+	}
+
+	if err := c.reconcileSubnetArpResponder(subnet, vpc.Status.Router, needRouter); err != nil {
+		klog.Errorf("failed to reconcile arp responder of subnet %s, %v", subnet.Name, err)
+		return err
+	}
+
+	c.recordSubnetReady(subnet)
 	c.updateVpcStatusQueue.Add(subnet.Spec.Vpc)
 	return nil
 }
 
+// recordSubnetReady records the time from the creation of the subnet to its initial readiness
+func (c *Controller) recordSubnetReady(subnet *kubeovnv1.Subnet) {
+	if _, ok := c.subnetsPendingReady.LoadAndDelete(subnet.Name); !ok {
+		return
+	}
+	seconds := time.Since(subnet.CreationTimestamp.Time).Seconds()
+	klog.Infof("subnet %s is ready %.3f seconds after creation", subnet.Name, seconds)
+	metricSubnetReadySeconds.Observe(seconds)
+}
+
 func (c *Controller) handleUpdateSubnetStatus(key string) error {
 	c.subnetStatusKeyMutex.Lock(key)
 	defer c.subnetStatusKeyMutex.Unlock(key)
@@ -758,6 +981,8 @@ func (c *Controller) handleDeleteRoute(subnet *kubeovnv1.Subnet) error {
 
 func (c *Controller) handleDeleteLogicalSwitch(key string) (err error) {
 	c.ipam.DeleteSubnet(key)
+	c.subnetsPendingReady.Delete(key)
+	c.subnetGatewaysReady.Delete(key)
 
 	exist, err := c.ovnLegacyClient.LogicalSwitchExists(key, c.config.EnableExternalVpc)
 	if err != nil {
@@ -1084,6 +1309,7 @@ func (c *Controller) reconcileOvnRoute(subnet *kubeovnv1.Subnet) error {
 				klog.Errorf("failed to list nodes: %v", err)
 				return err
 			}
+			var gwNextHops []string
 			for _, node := range nodes {
 				if err = c.createPortGroupForDistributedSubnet(node, subnet); err != nil {
 					return err
@@ -1102,6 +1328,9 @@ func (c *Controller) reconcileOvnRoute(subnet *kubeovnv1.Subnet) error {
 					klog.Errorf("failed to add policy router for node %s and subnet %s: %v", node.Name, subnet.Name, err)
 					return err
 				}
+				if nodeReady(node) {
+					gwNextHops = append(gwNextHops, strings.Split(nextHop, ",")...)
+				}
 			}
 
 			nameIdMap, idNameMap, err := c.ovnLegacyClient.ListLspForNodePortgroup()
@@ -1200,7 +1429,19 @@ func (c *Controller) reconcileOvnRoute(subnet *kubeovnv1.Subnet) error {
 				}
 				c.ovnPgKeyMutex.Unlock(pgName)
 			}
-			return c.deletePolicyRouteForCentralizedSubnet(subnet)
+
+			// every node is the gateway of its own pods, all of them must be reachable
+			inTransition, err := c.stageGatewayTransition(subnet, kubeovnv1.GWDistributedType, kubeovnv1.GWCentralizedType, gwNextHops, true)
+			if err != nil {
+				return err
+			}
+			if err = c.deletePolicyRouteForCentralizedSubnet(subnet); err != nil {
+				return err
+			}
+			if inTransition {
+				c.recorder.Eventf(subnet, v1.EventTypeNormal, "GatewayTransitionCompleted", "switched to %s gateway", kubeovnv1.GWDistributedType)
+			}
+			return nil
 		} else {
 			if subnet.Spec.GatewayNode == "" {
 				klog.Errorf("subnet %s Spec.GatewayNode field must be specified for centralized gateway type", subnet.Name)
@@ -1219,6 +1460,8 @@ func (c *Controller) reconcileOvnRoute(subnet *kubeovnv1.Subnet) error {
 				return fmt.Errorf("failed to add ecmp policy route, no gateway node exists")
 			}
 
+			var gwNextHops []string
+
 			if c.config.EnableEcmp {
 				nodeIPs := make([]string, 0, len(strings.Split(subnet.Spec.GatewayNode, ",")))
 				ipNameMap := make(map[string]string, len(strings.Split(subnet.Spec.GatewayNode, ","))*2)
@@ -1256,13 +1499,15 @@ func (c *Controller) reconcileOvnRoute(subnet *kubeovnv1.Subnet) error {
 					klog.Errorf("failed to add ecmp policy route for centralized subnet %s: %v", subnet.Name, err)
 					return err
 				}
+				gwNextHops = nodeIPs
 			} else {
 				// check if activateGateway still ready
 				if subnet.Status.ActivateGateway != "" && util.GatewayContains(subnet.Spec.GatewayNode, subnet.Status.ActivateGateway) {
 					node, err := c.nodesLister.Get(subnet.Status.ActivateGateway)
 					if err == nil && nodeReady(node) {
 						klog.Infof("subnet %s uses the old activate gw %s", subnet.Name, node.Name)
-						return nil
+						// the distributed gateway path may be left by an interrupted transition
+						return c.completeCentralizedGatewayTransition(subnet, node)
 					}
 				}
 
@@ -1309,17 +1554,171 @@ func (c *Controller) reconcileOvnRoute(subnet *kubeovnv1.Subnet) error {
 
 				subnet.Status.ActivateGateway = newActivateNode
 				c.patchSubnetStatus(subnet, "ReconcileCentralizedGatewaySuccess", "")
+				gwNextHops = strings.Split(nextHop, ",")
 			}
 
+			// traffic keeps going through any reachable gateway node
+			inTransition, err := c.stageGatewayTransition(subnet, kubeovnv1.GWCentralizedType, kubeovnv1.GWDistributedType, gwNextHops, false)
+			if err != nil {
+				return err
+			}
 			if err := c.deletePolicyRouteByGatewayType(subnet, kubeovnv1.GWDistributedType, false); err != nil {
 				klog.Errorf("failed to delete policy route for overlay subnet %s, %v", subnet.Name, err)
 				return err
 			}
+			if inTransition {
+				c.recorder.Eventf(subnet, v1.EventTypeNormal, "GatewayTransitionCompleted", "switched to %s gateway", kubeovnv1.GWCentralizedType)
+			}
 		}
 	}
 	return nil
 }
 
+// completeCentralizedGatewayTransition removes the distributed gateway path once the active gateway is verified
+func (c *Controller) completeCentralizedGatewayTransition(subnet *kubeovnv1.Subnet, gwNode *v1.Node) error {
+	nodeTunlIPAddr, err := getNodeTunlIP(gwNode)
+	if err != nil {
+		return err
+	}
+	inTransition, err := c.stageGatewayTransition(subnet, kubeovnv1.GWCentralizedType, kubeovnv1.GWDistributedType, strings.Split(getNextHopByTunnelIP(nodeTunlIPAddr), ","), false)
+	if err != nil || !inTransition {
+		return err
+	}
+	if err = c.deletePolicyRouteByGatewayType(subnet, kubeovnv1.GWDistributedType, false); err != nil {
+		klog.Errorf("failed to delete policy route for overlay subnet %s, %v", subnet.Name, err)
+		return err
+	}
+	c.recorder.Eventf(subnet, v1.EventTypeNormal, "GatewayTransitionCompleted", "switched to %s gateway", kubeovnv1.GWCentralizedType)
+	return nil
+}
+
+// setSubnetAggregateRate sets the aggregate egress rate shared by all the pods of the subnet on its logical switch
+func (c *Controller) setSubnetAggregateRate(subnet *kubeovnv1.Subnet) error {
+	var aggregateRate int
+	if subnet.Spec.AggregateEgressRate != "" {
+		// validated by the webhook, the rate of ovn qos is in kbps
+		aggregateRate, _ = strconv.Atoi(subnet.Spec.AggregateEgressRate)
+		aggregateRate *= 1000
+	}
+	if err := c.ovnLegacyClient.SetLogicalSwitchAggregateRate(subnet.Name, util.SubnetCIDRs(subnet), aggregateRate); err != nil {
+		c.patchSubnetStatus(subnet, "SetLogicalSwitchAggregateRateFailed", err.Error())
+		return err
+	}
+	return nil
+}
+
+// hasGatewayPath checks whether the policy routes of the gateway type exist for the subnet
+func (c *Controller) hasGatewayPath(subnet *kubeovnv1.Subnet, gatewayType string) (bool, error) {
+	for _, cidr := range strings.Split(subnet.Spec.CIDRBlock, ",") {
+		ipSuffix := "ip4"
+		if util.CheckProtocol(cidr) == kubeovnv1.ProtocolIPv6 {
+			ipSuffix = "ip6"
+		}
+		var matches []string
+		if gatewayType == kubeovnv1.GWCentralizedType {
+			matches = append(matches, fmt.Sprintf("%s.src == %s", ipSuffix, cidr))
+		} else {
+			nodes, err := c.nodesLister.List(labels.Everything())
+			if err != nil {
+				klog.Errorf("failed to list nodes: %v", err)
+				return false, err
+			}
+			for _, node := range nodes {
+				pgName := getOverlaySubnetsPortGroupName(subnet.Name, node.Name)
+				matches = append(matches, fmt.Sprintf("%s.src == $%s_%s", ipSuffix, pgName, ipSuffix))
+			}
+		}
+		for _, match := range matches {
+			exist, err := c.ovnLegacyClient.PolicyRouteExists(util.GatewayRouterPolicyPriority, match)
+			if err != nil {
+				klog.Errorf("failed to check policy route %s: %v", match, err)
+				return false, err
+			}
+			if exist {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// gatewayTransitionTimeout is the duration after which a gateway transition requiring all the next hops proceeds
+// with the reachable ones, so that a broken node does not block the transition forever
+const gatewayTransitionTimeout = 5 * time.Minute
+
+// stageGatewayTransition is called after the gateway path of the new type is added. If the path of the old
+// type still exists, the subnet is switching the gateway type and the old path is kept until the next hops
+// of the new one are verified reachable, so that there is no window without a working egress. It's safe to
+// be interrupted at any stage as both paths are reconciled idempotently.
+func (c *Controller) stageGatewayTransition(subnet *kubeovnv1.Subnet, newType, oldType string, nextHops []string, requireAll bool) (bool, error) {
+	exist, err := c.hasGatewayPath(subnet, oldType)
+	if err != nil {
+		return false, err
+	}
+	if !exist {
+		c.gatewayTransitions.Delete(subnet.Name)
+		return false, nil
+	}
+
+	startedAt, loaded := c.gatewayTransitions.LoadOrStore(subnet.Name, time.Now())
+	if !loaded {
+		klog.Infof("subnet %s is switching from %s gateway to %s gateway, verify next hops %v", subnet.Name, oldType, newType, nextHops)
+		c.recorder.Eventf(subnet, v1.EventTypeNormal, "GatewayTransitionStarted", "%s gateway path added, verifying next hops %s before removing the %s gateway path", newType, strings.Join(nextHops, ","), oldType)
+	}
+	reachable, unreachable := c.checkNextHopsReachable(nextHops)
+	if len(reachable) != 0 && requireAll && len(unreachable) != 0 && time.Since(startedAt.(time.Time)) > gatewayTransitionTimeout {
+		klog.Warningf("next hops %s of %s gateway of subnet %s are still not reachable after %v, proceed with the reachable ones", strings.Join(unreachable, ","), newType, subnet.Name, gatewayTransitionTimeout)
+		c.recorder.Eventf(subnet, v1.EventTypeWarning, "GatewayTransitionTimeout", "next hops %s of %s gateway are not reachable after %v, removing the %s gateway path anyway", strings.Join(unreachable, ","), newType, gatewayTransitionTimeout, oldType)
+		requireAll = false
+	}
+	if len(reachable) == 0 || (requireAll && len(unreachable) != 0) {
+		err = fmt.Errorf("next hops %s of %s gateway are not reachable, keep the %s gateway path of subnet %s", strings.Join(unreachable, ","), newType, oldType, subnet.Name)
+		if len(nextHops) == 0 {
+			err = fmt.Errorf("no next hop of %s gateway is ready, keep the %s gateway path of subnet %s", newType, oldType, subnet.Name)
+		}
+		klog.Error(err)
+		c.recorder.Eventf(subnet, v1.EventTypeWarning, "GatewayTransitionPending", err.Error())
+		return false, err
+	}
+	c.gatewayTransitions.Delete(subnet.Name)
+	c.recorder.Eventf(subnet, v1.EventTypeNormal, "GatewayTransitionVerified", "next hops %s of %s gateway are reachable, removing the %s gateway path", strings.Join(reachable, ","), newType, oldType)
+	return true, nil
+}
+
+// checkNextHopsReachable splits the next hops by their reachability. The latest results of the gateway check are
+// reused for the centralized gateway nodes, and the other next hops are pinged in parallel, so the check takes a
+// few seconds at most
+func (c *Controller) checkNextHopsReachable(nextHops []string) (reachable, unreachable []string) {
+	results := make([]bool, len(nextHops))
+	var wg sync.WaitGroup
+	for i, nextHop := range nextHops {
+		if ready, ok := c.gatewayNodesReady.Load(nextHop); ok {
+			results[i] = ready.(bool)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, nextHop string) {
+			defer wg.Done()
+			ok, err := pingGateway(nextHop, 3)
+			if err != nil {
+				klog.Error(err)
+				return
+			}
+			results[i] = ok
+		}(i, nextHop)
+	}
+	wg.Wait()
+
+	for i, nextHop := range nextHops {
+		if results[i] {
+			reachable = append(reachable, nextHop)
+		} else {
+			unreachable = append(unreachable, nextHop)
+		}
+	}
+	return reachable, unreachable
+}
+
 func (c *Controller) deleteStaticRoute(ip, router string) error {
 	for _, ipStr := range strings.Split(ip, ",") {
 		if err := c.ovnLegacyClient.DeleteStaticRoute(ipStr, router); err != nil {
@@ -1331,6 +1730,34 @@ func (c *Controller) deleteStaticRoute(ip, router string) error {
 	return nil
 }
 
+// checkSubnetGatewayMode checks the vlan and the provider network of the underlay subnet,
+// and records the effective gateway mode in the status
+func (c *Controller) checkSubnetGatewayMode(subnet *kubeovnv1.Subnet) error {
+	mode := util.SubnetGatewayMode(*subnet)
+	if subnet.Spec.Vlan != "" {
+		vlan, err := c.vlansLister.Get(subnet.Spec.Vlan)
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				return fmt.Errorf("vlan %s of the underlay subnet with gateway mode %s is not found", subnet.Spec.Vlan, mode)
+			}
+			klog.Errorf("failed to get vlan %s: %v", subnet.Spec.Vlan, err)
+			return err
+		}
+		if _, err = c.providerNetworksLister.Get(vlan.Spec.Provider); err != nil {
+			if k8serrors.IsNotFound(err) {
+				return fmt.Errorf("provider network %s of vlan %s is not found", vlan.Spec.Provider, vlan.Name)
+			}
+			klog.Errorf("failed to get provider network %s: %v", vlan.Spec.Provider, err)
+			return err
+		}
+	}
+	if subnet.Status.GatewayMode != mode {
+		klog.Infof("gateway mode of subnet %s is %s", subnet.Name, mode)
+		subnet.Status.GatewayMode = mode
+	}
+	return nil
+}
+
 func (c *Controller) reconcileVlan(subnet *kubeovnv1.Subnet) error {
 	if subnet.Spec.Vlan == "" {
 		return nil
@@ -1343,6 +1770,12 @@ func (c *Controller) reconcileVlan(subnet *kubeovnv1.Subnet) error {
 		return err
 	}
 
+	if valid, err := c.checkVlan(vlan); err != nil {
+		return err
+	} else if !valid {
+		return fmt.Errorf("vlan %s of subnet %s is invalid", vlan.Name, subnet.Name)
+	}
+
 	localnetPort := ovs.GetLocalnetName(subnet.Name)
 	if err := c.ovnLegacyClient.CreateLocalnetPort(subnet.Name, localnetPort, vlan.Spec.Provider, vlan.Spec.ID); err != nil {
 		klog.Errorf("failed to create localnet port for subnet %s: %v", subnet.Name, err)
@@ -1378,11 +1811,11 @@ func calcDualSubnetStatusIP(subnet *kubeovnv1.Subnet, c *Controller) error {
 	v4ExcludeIps, v6ExcludeIps := util.SplitIpsByProtocol(subnet.Spec.ExcludeIps)
 	// gateway always in excludeIPs
 	cidrBlocks := strings.Split(subnet.Spec.CIDRBlock, ",")
-	v4toSubIPs := util.ExpandExcludeIPs(v4ExcludeIps, cidrBlocks[0])
+	v4toSubIPs := util.ExpandExcludeIPs(v4ExcludeIps, strings.Join(append([]string{cidrBlocks[0]}, subnet.Spec.ExtraCIDRBlocks...), ","))
 	v6toSubIPs := util.ExpandExcludeIPs(v6ExcludeIps, cidrBlocks[1])
 	_, v4CIDR, _ := net.ParseCIDR(cidrBlocks[0])
 	_, v6CIDR, _ := net.ParseCIDR(cidrBlocks[1])
-	v4availableIPs := util.AddressCount(v4CIDR) - util.CountIpNums(v4toSubIPs)
+	v4availableIPs := util.AddressCount(v4CIDR) + extraCIDRsAddressCount(subnet) - util.CountIpNums(v4toSubIPs)
 	v6availableIPs := util.AddressCount(v6CIDR) - util.CountIpNums(v6toSubIPs)
 
 	usingIPs := float64(len(podUsedIPs.Items))
@@ -1434,6 +1867,16 @@ func calcDualSubnetStatusIP(subnet *kubeovnv1.Subnet, c *Controller) error {
 	return err
 }
 
+func extraCIDRsAddressCount(subnet *kubeovnv1.Subnet) float64 {
+	var count float64
+	for _, cidrBlock := range subnet.Spec.ExtraCIDRBlocks {
+		if _, cidr, err := net.ParseCIDR(cidrBlock); err == nil {
+			count += util.AddressCount(cidr)
+		}
+	}
+	return count
+}
+
 func calcSubnetStatusIP(subnet *kubeovnv1.Subnet, c *Controller) error {
 	_, cidr, err := net.ParseCIDR(subnet.Spec.CIDRBlock)
 	if err != nil {
@@ -1446,8 +1889,8 @@ func calcSubnetStatusIP(subnet *kubeovnv1.Subnet, c *Controller) error {
 		return err
 	}
 	// gateway always in excludeIPs
-	toSubIPs := util.ExpandExcludeIPs(subnet.Spec.ExcludeIps, subnet.Spec.CIDRBlock)
-	availableIPs := util.AddressCount(cidr) - util.CountIpNums(toSubIPs)
+	toSubIPs := util.ExpandExcludeIPs(subnet.Spec.ExcludeIps, util.SubnetCIDRs(subnet))
+	availableIPs := util.AddressCount(cidr) + extraCIDRsAddressCount(subnet) - util.CountIpNums(toSubIPs)
 	usingIPs := float64(len(podUsedIPs.Items))
 	vipSelectors := fields.AndSelectors(fields.OneTermEqualSelector(util.SubnetNameLabel, subnet.Name),
 		fields.OneTermEqualSelector(util.IpReservedLabel, "")).String()
@@ -1507,6 +1950,14 @@ func calcSubnetStatusIP(subnet *kubeovnv1.Subnet, c *Controller) error {
 	return err
 }
 
+// subnetDHCPEnabled returns whether the ovn dhcp of ipv4 and ipv6 is enabled for the subnet
+func subnetDHCPEnabled(subnet *kubeovnv1.Subnet) (v4, v6 bool) {
+	if !subnet.Spec.EnableDHCP || subnet.Spec.DisableDHCP {
+		return false, false
+	}
+	return !subnet.Spec.DisableDHCPv4, !subnet.Spec.DisableDHCPv6
+}
+
 func isOvnSubnet(subnet *kubeovnv1.Subnet) bool {
 	return subnet.Spec.Provider == "" || subnet.Spec.Provider == util.OvnProvider || strings.HasSuffix(subnet.Spec.Provider, "ovn")
 }
@@ -1717,6 +2168,134 @@ func (c *Controller) updatePolicyRouteForCentralizedSubnet(subnetName, cidr stri
 		return err
 	}
 
+	return c.reconcileWeightedPolicyRoute(subnetName, cidr, nameIpMap)
+}
+
+// getGatewayWeight returns the weight of the gateway node, defaults to 1
+func getGatewayWeight(node *v1.Node) int {
+	weight := node.Annotations[util.GatewayWeightAnnotation]
+	if weight == "" {
+		return 1
+	}
+	w, err := strconv.Atoi(weight)
+	if err != nil || w < 0 {
+		klog.Warningf("invalid gateway weight %q of node %s, fall back to 1", weight, node.Name)
+		return 1
+	}
+	return w
+}
+
+// reconcileWeightedPolicyRoute biases egress traffic of centralized subnet towards gateway nodes with higher weight.
+// OVN reroutes to all next hops of a policy evenly, so when the weights differ, the source addresses are split into
+// buckets by the lowest bits, and each bucket is rerouted to a single gateway node with a higher priority policy.
+// Gateway nodes with weight 0 get no bucket, while they keep handling the return traffic of existing connections.
+func (c *Controller) reconcileWeightedPolicyRoute(subnetName, cidr string, nameIpMap map[string]string) error {
+	ipSuffix, bucketMask := "ip4", fmt.Sprintf("0.0.0.%d", util.GatewayWeightBuckets-1)
+	if util.CheckProtocol(cidr) == kubeovnv1.ProtocolIPv6 {
+		ipSuffix, bucketMask = "ip6", fmt.Sprintf("::%x", util.GatewayWeightBuckets-1)
+	}
+
+	nodeNames := make([]string, 0, len(nameIpMap))
+	weights := make(map[string]int, len(nameIpMap))
+	var total int
+	for name := range nameIpMap {
+		if name == "vendor" || name == "subnet" {
+			continue
+		}
+		node, err := c.nodesLister.Get(name)
+		if err != nil {
+			if !k8serrors.IsNotFound(err) {
+				klog.Errorf("failed to get node %s: %v", name, err)
+				return err
+			}
+			continue
+		}
+		nodeNames = append(nodeNames, name)
+		weights[name] = getGatewayWeight(node)
+		total += weights[name]
+	}
+	sort.Strings(nodeNames)
+
+	uneven := false
+	for _, name := range nodeNames {
+		if weights[name] != weights[nodeNames[0]] {
+			uneven = true
+			break
+		}
+	}
+
+	// distribute buckets with the largest remainder method
+	desired := make(map[string]string, util.GatewayWeightBuckets)
+	if uneven && total != 0 {
+		quota := make(map[string]int, len(nodeNames))
+		remainders := make([]string, 0, len(nodeNames))
+		assigned := 0
+		for _, name := range nodeNames {
+			quota[name] = weights[name] * util.GatewayWeightBuckets / total
+			assigned += quota[name]
+			if weights[name] != 0 {
+				remainders = append(remainders, name)
+			}
+		}
+		sort.SliceStable(remainders, func(i, j int) bool {
+			return weights[remainders[i]]*util.GatewayWeightBuckets%total > weights[remainders[j]]*util.GatewayWeightBuckets%total
+		})
+		for i := 0; assigned < util.GatewayWeightBuckets; i++ {
+			quota[remainders[i%len(remainders)]]++
+			assigned++
+		}
+
+		bucket := 0
+		for _, name := range nodeNames {
+			for i := 0; i < quota[name]; i++ {
+				var value string
+				if ipSuffix == "ip4" {
+					value = fmt.Sprintf("0.0.0.%d", bucket)
+				} else {
+					value = fmt.Sprintf("::%x", bucket)
+				}
+				match := fmt.Sprintf("%s.src == %s && %s.src == %s/%s", ipSuffix, cidr, ipSuffix, value, bucketMask)
+				desired[match] = nameIpMap[name]
+				bucket++
+			}
+		}
+	}
+
+	policies, err := c.ovnClient.GetLogicalRouterPoliciesByExtID("subnet", subnetName)
+	if err != nil {
+		klog.Errorf("failed to list policy routes of subnet %s: %v", subnetName, err)
+		return err
+	}
+	prefix := fmt.Sprintf("%s.src == %s && ", ipSuffix, cidr)
+	for _, policy := range policies {
+		if policy.Priority != util.WeightedGatewayRouterPolicyPriority || !strings.HasPrefix(policy.Match, prefix) {
+			continue
+		}
+		if nextHop, ok := desired[policy.Match]; ok && len(policy.Nexthops) == 1 && policy.Nexthops[0] == nextHop {
+			delete(desired, policy.Match)
+			continue
+		}
+		if _, ok := desired[policy.Match]; ok {
+			// the next hop is updated by AddPolicyRoute
+			continue
+		}
+		if err = c.ovnLegacyClient.DeletePolicyRoute(c.config.ClusterRouter, util.WeightedGatewayRouterPolicyPriority, policy.Match); err != nil {
+			klog.Errorf("failed to delete weighted policy route for subnet %s: %v", subnetName, err)
+			return err
+		}
+	}
+
+	externalIDs := map[string]string{
+		"vendor": util.CniTypeName,
+		"subnet": subnetName,
+	}
+	for match, nextHop := range desired {
+		klog.Infof("reroute %s to gateway %s for subnet %s", match, nextHop, subnetName)
+		if err = c.ovnLegacyClient.AddPolicyRoute(c.config.ClusterRouter, util.WeightedGatewayRouterPolicyPriority, match, "reroute", nextHop, externalIDs); err != nil {
+			klog.Errorf("failed to add weighted policy route for subnet %s: %v", subnetName, err)
+			return err
+		}
+	}
 	return nil
 }
 
@@ -1764,6 +2343,9 @@ func (c *Controller) deletePolicyRouteForCentralizedSubnet(subnet *kubeovnv1.Sub
 			klog.Errorf("failed to delete policy route for centralized subnet %s: %v", subnet.Name, err)
 			return err
 		}
+		if err := c.reconcileWeightedPolicyRoute(subnet.Name, cidr, nil); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -280,11 +280,13 @@ func (c *Controller) acquireStaticIpAddress(subnetName, name, nicName, ip string
 	var err error
 	for _, ipStr := range strings.Split(ip, ",") {
 		if net.ParseIP(ipStr) == nil {
+			recordInvalidStaticIP(subnetName)
 			return "", "", "", fmt.Errorf("failed to parse vip ip %s", ipStr)
 		}
 	}
 
 	if v4ip, v6ip, mac, err = c.ipam.GetStaticAddress(name, nicName, ip, mac, subnetName, checkConflict); err != nil {
+		recordIPAMFailure(subnetName, err)
 		klog.Errorf("failed to get static virtual ip '%s', mac '%s', subnet '%s', %v", ip, mac, subnetName, err)
 		return "", "", "", err
 	}
@@ -299,6 +301,7 @@ func (c *Controller) acquireIpAddress(subnetName, name, nicName string) (string,
 	for {
 		v4ip, v6ip, mac, err = c.ipam.GetRandomAddress(name, nicName, mac, subnetName, skippedAddrs, checkConflict)
 		if err != nil {
+			recordIPAMFailure(subnetName, err)
 			return "", "", "", err
 		}
 
@@ -3,7 +3,9 @@ package controller
 import (
 	"context"
 	"fmt"
+	"reflect"
 
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -223,6 +225,10 @@ func (c *Controller) handleAddVlan(key string) error {
 		return err
 	}
 
+	if valid, err := c.checkVlan(vlan); err != nil || !valid {
+		return err
+	}
+
 	if !util.ContainsString(pn.Status.Vlans, vlan.Name) {
 		newPn := pn.DeepCopy()
 		newPn.Status.Vlans = append(newPn.Status.Vlans, vlan.Name)
@@ -248,12 +254,16 @@ func (c *Controller) handleUpdateVlan(key string) error {
 	if vlan.Spec.Provider == "" {
 		newVlan := vlan.DeepCopy()
 		newVlan.Spec.Provider = c.config.DefaultProviderName
-		if _, err = c.config.KubeOvnClient.KubeovnV1().Vlans().Update(context.Background(), newVlan, metav1.UpdateOptions{}); err != nil {
-			klog.Errorf("failed to update vlan %s: %v", vlan.Name, err)
+		if vlan, err = c.config.KubeOvnClient.KubeovnV1().Vlans().Update(context.Background(), newVlan, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("failed to update vlan %s: %v", newVlan.Name, err)
 			return err
 		}
 	}
 
+	if valid, err := c.checkVlan(vlan); err != nil || !valid {
+		return err
+	}
+
 	subnets, err := c.subnetsLister.List(labels.Everything())
 	if err != nil {
 		klog.Errorf("failed to list subnets: %v", err)
@@ -314,6 +324,39 @@ func (c *Controller) updateProviderNetworkStatusForVlanDeletion(pn *kubeovnv1.Pr
 	return nil
 }
 
+// checkVlan validates the vlan against its provider network, an invalid vlan is
+// reported by event and status condition instead of being programmed into ovn
+func (c *Controller) checkVlan(vlan *kubeovnv1.Vlan) (bool, error) {
+	pn, err := c.providerNetworksLister.Get(vlan.Spec.Provider)
+	if err != nil {
+		klog.Errorf("failed to get provider network %s: %v", vlan.Spec.Provider, err)
+		return false, err
+	}
+	vlans, err := c.vlansLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list vlans: %v", err)
+		return false, err
+	}
+
+	newVlan := vlan.DeepCopy()
+	validateErr := util.ValidateVlan(vlan, pn, vlans)
+	if validateErr != nil {
+		klog.Errorf("failed to validate vlan %s: %v", vlan.Name, validateErr)
+		c.recorder.Eventf(vlan, corev1.EventTypeWarning, "ValidateVlanFailed", validateErr.Error())
+		newVlan.Status.SetVlanError("ValidateVlanFailed", validateErr.Error())
+	} else if cond := vlan.Status.GetVlanCondition(kubeovnv1.Error); cond != nil && cond.Status == corev1.ConditionTrue && cond.Reason == "ValidateVlanFailed" {
+		newVlan.Status.ClearVlanCondition(kubeovnv1.Error, "ValidateVlanSucceeded", "")
+	}
+
+	if !reflect.DeepEqual(newVlan.Status, vlan.Status) {
+		if _, err = c.config.KubeOvnClient.KubeovnV1().Vlans().UpdateStatus(context.Background(), newVlan, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("failed to update status of vlan %s: %v", vlan.Name, err)
+			return false, err
+		}
+	}
+	return validateErr == nil, nil
+}
+
 func (c *Controller) setLocalnetTag(subnet string, vlanID int) error {
 	localnetPort := ovs.GetLocalnetName(subnet)
 	if err := c.ovnLegacyClient.SetPortTag(localnetPort, vlanID); err != nil {
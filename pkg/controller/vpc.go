@@ -17,6 +17,7 @@ import (
 
 	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
 	"github.com/kubeovn/kube-ovn/pkg/ovs"
+	"github.com/kubeovn/kube-ovn/pkg/ovsdb/ovnnb"
 	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
@@ -106,8 +107,19 @@ func (c *Controller) handleDelVpc(vpc *kubeovnv1.Vpc) error {
 		return err
 	}
 
-	err := c.deleteVpcRouter(vpc.Status.Router)
+	bfdList, err := c.ovnClient.ListBFDByExtID("vpc", vpc.Name)
 	if err != nil {
+		klog.Errorf("failed to list bfd of vpc %s: %v", vpc.Name, err)
+		return err
+	}
+	for _, bfd := range bfdList {
+		if err = c.ovnClient.DeleteBFD(bfd.LogicalPort, bfd.DstIP); err != nil {
+			klog.Errorf("failed to delete bfd of vpc %s: %v", vpc.Name, err)
+			return err
+		}
+	}
+
+	if err = c.deleteVpcRouter(vpc.Status.Router); err != nil {
 		return err
 	}
 
@@ -199,7 +211,7 @@ func (c *Controller) reconcileRouterPorts(vpc *kubeovnv1.Vpc) error {
 				return nil
 			}
 
-			networks := util.GetIpAddrWithMask(subnet.Spec.Gateway, subnet.Spec.CIDRBlock)
+			networks := subnetRouterPortNetworks(subnet)
 			klog.Infof("add vpc lrp %s, networks %s", routerPortName, networks)
 			if err := c.ovnClient.AddLogicalRouterPort(router, routerPortName, "", networks); err != nil {
 				klog.ErrorS(err, "unable to create router port", "vpc", vpc.Name, "subnet", subnetName)
@@ -228,7 +240,7 @@ func (c *Controller) reconcileRouterPortBySubnet(vpc *kubeovnv1.Vpc, subnet *kub
 			return err
 		}
 
-		networks := util.GetIpAddrWithMask(subnet.Spec.Gateway, subnet.Spec.CIDRBlock)
+		networks := subnetRouterPortNetworks(subnet)
 		klog.Infof("router port does not exist, trying to create %s with ip %s", routerPortName, networks)
 
 		if err := c.ovnClient.AddLogicalRouterPort(router, routerPortName, "", networks); err != nil {
@@ -239,6 +251,11 @@ func (c *Controller) reconcileRouterPortBySubnet(vpc *kubeovnv1.Vpc, subnet *kub
 	return nil
 }
 
+// subnetRouterPortNetworks returns the networks of the router port connecting the subnet, including the extra CIDR blocks
+func subnetRouterPortNetworks(subnet *kubeovnv1.Subnet) string {
+	return strings.Join(append([]string{util.GetIpAddrWithMask(subnet.Spec.Gateway, subnet.Spec.CIDRBlock)}, util.ExtraCIDRNetworks(subnet)...), ",")
+}
+
 type VpcLoadBalancer struct {
 	TcpLoadBalancer     string
 	TcpSessLoadBalancer string
@@ -378,13 +395,39 @@ func (c *Controller) handleAddOrUpdateVpc(key string) error {
 	}
 
 	// handle static route
+	bfdList, err := c.ovnClient.ListBFDByExtID("vpc", vpc.Name)
+	if err != nil {
+		klog.Errorf("failed to list bfd of vpc %s: %v", vpc.Name, err)
+		return err
+	}
+	bfdStatus := make(map[string]string, len(bfdList))
+	for _, bfd := range bfdList {
+		if bfd.Status != nil {
+			bfdStatus[bfd.DstIP] = *bfd.Status
+		}
+	}
+	// static routes whose bfd session is down are kept with the bfd bound, ovn skips them until the session is up again
+	targetRoutes := vpc.Spec.StaticRoutes
+	routeStatus := make([]*kubeovnv1.StaticRouteStatus, 0, len(vpc.Spec.StaticRoutes))
+	for _, item := range vpc.Spec.StaticRoutes {
+		status := &kubeovnv1.StaticRouteStatus{Policy: item.Policy, CIDR: item.CIDR, NextHopIP: item.NextHopIP}
+		if item.BFD != nil {
+			status.BFDStatus = bfdStatus[item.NextHopIP]
+			status.Withdrawn = status.BFDStatus == ovnnb.BFDStatusDown
+		}
+		routeStatus = append(routeStatus, status)
+		if status.Withdrawn {
+			klog.Warningf("bfd session to next hop %s is down, static route %s of vpc %s is skipped by ovn", item.NextHopIP, item.CIDR, vpc.Name)
+		}
+	}
+
 	existRoute, err := c.ovnLegacyClient.GetStaticRouteList(vpc.Name)
 	if err != nil {
 		klog.Errorf("failed to get vpc %s static route list, %v", vpc.Name, err)
 		return err
 	}
 
-	routeNeedDel, routeNeedAdd, err := diffStaticRoute(existRoute, vpc.Spec.StaticRoutes)
+	routeNeedDel, routeNeedAdd, err := diffStaticRoute(existRoute, targetRoutes)
 	if err != nil {
 		klog.Errorf("failed to diff vpc %s static route, %v", vpc.Name, err)
 		return err
@@ -402,6 +445,10 @@ func (c *Controller) handleAddOrUpdateVpc(key string) error {
 			return err
 		}
 	}
+	if err = c.reconcileStaticRouteBFD(vpc, bfdList); err != nil {
+		klog.Errorf("failed to reconcile bfd of vpc %s static routes, %v", vpc.Name, err)
+		return err
+	}
 	// handle policy route
 	existPolicyRoute, err := c.ovnLegacyClient.GetPolicyRouteList(vpc.Name)
 	if err != nil {
@@ -431,6 +478,7 @@ func (c *Controller) handleAddOrUpdateVpc(key string) error {
 	vpc.Status.Router = key
 	vpc.Status.Standby = true
 	vpc.Status.VpcPeerings = newPeers
+	vpc.Status.StaticRoutes = routeStatus
 	if c.config.EnableLb {
 		vpcLb, err := c.addLoadBalancer(key)
 		if err != nil {
@@ -555,6 +603,111 @@ func diffStaticRoute(exist []*ovs.StaticRoute, target []*kubeovnv1.StaticRoute)
 	return
 }
 
+// reconcileStaticRouteBFD creates bfd sessions towards the next hops of static routes
+// with bfd enabled, binds them to the routes and removes the sessions no longer used
+func (c *Controller) reconcileStaticRouteBFD(vpc *kubeovnv1.Vpc, bfdList []ovnnb.BFD) error {
+	lrpList, err := c.ovnClient.ListLogicalRouterPorts(vpc.Name)
+	if err != nil {
+		return err
+	}
+
+	inUse := make(map[string]bool, len(bfdList))
+	for _, route := range vpc.Spec.StaticRoutes {
+		if route.BFD == nil {
+			if err = c.ovnClient.SetLogicalRouterStaticRouteBFD(vpc.Name, route.CIDR, route.NextHopIP, ""); err != nil {
+				return err
+			}
+			continue
+		}
+
+		lrpName := getRouterPortByNextHop(lrpList, route.NextHopIP)
+		if lrpName == "" {
+			klog.Warningf("no port of router %s is connected to next hop %s, skip bfd of static route %s", vpc.Name, route.NextHopIP, route.CIDR)
+			continue
+		}
+		minTx, minRx, detectMult := c.config.BfdMinTx, c.config.BfdMinRx, c.config.BfdDetectMult
+		if route.BFD.MinTx != 0 {
+			minTx = route.BFD.MinTx
+		}
+		if route.BFD.MinRx != 0 {
+			minRx = route.BFD.MinRx
+		}
+		if route.BFD.DetectMult != 0 {
+			detectMult = route.BFD.DetectMult
+		}
+		bfd, err := c.ovnClient.CreateBFD(lrpName, route.NextHopIP, minRx, minTx, detectMult, map[string]string{"vpc": vpc.Name})
+		if err != nil {
+			return err
+		}
+		inUse[bfd.UUID] = true
+		if err = c.ovnClient.SetLogicalRouterStaticRouteBFD(vpc.Name, route.CIDR, route.NextHopIP, bfd.UUID); err != nil {
+			return err
+		}
+	}
+
+	for _, bfd := range bfdList {
+		if !inUse[bfd.UUID] {
+			klog.Infof("delete bfd towards %s of vpc %s", bfd.DstIP, vpc.Name)
+			if err = c.ovnClient.DeleteBFD(bfd.LogicalPort, bfd.DstIP); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func getRouterPortByNextHop(lrpList []ovnnb.LogicalRouterPort, nextHop string) string {
+	for _, lrp := range lrpList {
+		for _, network := range lrp.Networks {
+			if util.CIDRContainIP(network, nextHop) {
+				return lrp.Name
+			}
+		}
+	}
+	return ""
+}
+
+// syncVpcStaticRouteBFD requeues the vpcs whose bfd state of static routes has changed
+func (c *Controller) syncVpcStaticRouteBFD() {
+	vpcs, err := c.vpcsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list vpc: %v", err)
+		return
+	}
+
+	for _, vpc := range vpcs {
+		recorded := make(map[string]string, len(vpc.Status.StaticRoutes))
+		for _, status := range vpc.Status.StaticRoutes {
+			recorded[status.NextHopIP] = status.BFDStatus
+		}
+
+		var bfdStatus map[string]string
+		for _, route := range vpc.Spec.StaticRoutes {
+			if route.BFD == nil {
+				continue
+			}
+			if bfdStatus == nil {
+				bfdList, err := c.ovnClient.ListBFDByExtID("vpc", vpc.Name)
+				if err != nil {
+					klog.Errorf("failed to list bfd of vpc %s: %v", vpc.Name, err)
+					break
+				}
+				bfdStatus = make(map[string]string, len(bfdList))
+				for _, bfd := range bfdList {
+					if bfd.Status != nil {
+						bfdStatus[bfd.DstIP] = *bfd.Status
+					}
+				}
+			}
+			if bfdStatus[route.NextHopIP] != recorded[route.NextHopIP] {
+				klog.Infof("bfd state of next hop %s in vpc %s changes to %q", route.NextHopIP, vpc.Name, bfdStatus[route.NextHopIP])
+				c.addOrUpdateVpcQueue.Add(vpc.Name)
+				break
+			}
+		}
+	}
+}
+
 func getStaticRouteItemKey(item *kubeovnv1.StaticRoute) (key string) {
 	if item.Policy == kubeovnv1.PolicyDst {
 		return fmt.Sprintf("dst:%s=>%s", item.CIDR, item.NextHopIP)
@@ -586,6 +739,10 @@ func formatVpc(vpc *kubeovnv1.Vpc, c *Controller) error {
 		if ip := net.ParseIP(item.NextHopIP); ip == nil {
 			return fmt.Errorf("invalid next hop IP %s", item.NextHopIP)
 		}
+		// check bfd
+		if item.BFD != nil && (item.BFD.MinTx < 0 || item.BFD.MinRx < 0 || item.BFD.DetectMult < 0) {
+			return fmt.Errorf("invalid bfd config of static route %s via %s", item.CIDR, item.NextHopIP)
+		}
 	}
 
 	for _, route := range vpc.Spec.PolicyRoutes {
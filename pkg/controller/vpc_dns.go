@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"reflect"
@@ -191,11 +192,18 @@ func (c *Controller) handleAddOrUpdateVpcDns(key string) error {
 		return err
 	}
 
-	if _, err := c.subnetsLister.Get(vpcDns.Spec.Subnet); err != nil {
+	subnet, err := c.subnetsLister.Get(vpcDns.Spec.Subnet)
+	if err != nil {
 		klog.Errorf("failed to get subnet '%s', err: %v", vpcDns.Spec.Subnet, err)
 		return err
 	}
 
+	if err := validateVpcDnsSubnet(vpcDns, subnet); err != nil {
+		klog.Errorf("failed to validate subnet of vpc-dns %s, %v", vpcDns.Name, err)
+		c.recorder.Eventf(vpcDns, corev1.EventTypeWarning, "InvalidSubnet", err.Error())
+		return err
+	}
+
 	if err := c.checkOvnNad(); err != nil {
 		klog.Errorf("failed to check nad, %v", err)
 		return err
@@ -211,7 +219,12 @@ func (c *Controller) handleAddOrUpdateVpcDns(key string) error {
 		return err
 	}
 
-	if err := c.createOrUpdateVpcDnsDep(vpcDns); err != nil {
+	reverseDNS, err := c.syncVpcDnsReverseConfigMap(vpcDns)
+	if err != nil {
+		return err
+	}
+
+	if err := c.createOrUpdateVpcDnsDep(vpcDns, reverseDNS); err != nil {
 		return err
 	}
 
@@ -225,7 +238,9 @@ func (c *Controller) handleAddOrUpdateVpcDns(key string) error {
 func (c *Controller) handleDelVpcDns(key string) error {
 	klog.V(3).Infof("handleDelVpcDns,%s", key)
 	name := genVpcDnsDpName(key)
-	err := c.config.KubeClient.AppsV1().Deployments(c.config.PodNamespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	// delete the pods before the deployment, so the static addresses are released once the deployment is gone
+	propagationPolicy := metav1.DeletePropagationForeground
+	err := c.config.KubeClient.AppsV1().Deployments(c.config.PodNamespace).Delete(context.Background(), name, metav1.DeleteOptions{PropagationPolicy: &propagationPolicy})
 	if err != nil && !k8serrors.IsNotFound(err) {
 		klog.Errorf("failed to delete Deployments: %v", err)
 		return err
@@ -236,6 +251,37 @@ func (c *Controller) handleDelVpcDns(key string) error {
 		klog.Errorf("failed to delete SwitchLBRule: %v", err)
 		return err
 	}
+	return c.deleteVpcDnsReverseConfigMap(key)
+}
+
+// validateVpcDnsSubnet checks the subnet belongs to the vpc of the vpc-dns and contains the static ips
+func validateVpcDnsSubnet(vpcDns *kubeovnv1.VpcDns, subnet *kubeovnv1.Subnet) error {
+	subnetVpc := subnet.Spec.Vpc
+	if subnetVpc == "" {
+		subnetVpc = util.DefaultVpc
+	}
+	if subnetVpc != vpcDns.Spec.Vpc {
+		return fmt.Errorf("subnet %s belongs to vpc %s rather than %s", subnet.Name, subnetVpc, vpcDns.Spec.Vpc)
+	}
+
+	ips := make(map[string]bool, len(vpcDns.Spec.IPs))
+	for _, ipStr := range vpcDns.Spec.IPs {
+		for _, ip := range strings.Split(ipStr, ",") {
+			if net.ParseIP(ip) == nil {
+				return fmt.Errorf("%s is not a valid ip", ip)
+			}
+			if !util.CIDRContainIP(subnet.Spec.CIDRBlock, ip) {
+				return fmt.Errorf("ip %s is not in the cidr %s of subnet %s", ip, subnet.Spec.CIDRBlock, subnet.Name)
+			}
+			if util.ContainsString(strings.Split(subnet.Spec.Gateway, ","), ip) {
+				return fmt.Errorf("ip %s is the gateway of subnet %s", ip, subnet.Name)
+			}
+			if ips[ip] {
+				return fmt.Errorf("ip %s is duplicated", ip)
+			}
+			ips[ip] = true
+		}
+	}
 	return nil
 }
 
@@ -259,7 +305,7 @@ func (c *Controller) checkVpcDnsDuplicated(vpcDns *kubeovnv1.VpcDns) error {
 	return nil
 }
 
-func (c *Controller) createOrUpdateVpcDnsDep(vpcDns *kubeovnv1.VpcDns) error {
+func (c *Controller) createOrUpdateVpcDnsDep(vpcDns *kubeovnv1.VpcDns, reverseDNS bool) error {
 	needToCreateDp := false
 	oldDp, err := c.config.KubeClient.AppsV1().Deployments(c.config.PodNamespace).
 		Get(context.Background(), genVpcDnsDpName(vpcDns.Name), metav1.GetOptions{})
@@ -270,9 +316,12 @@ func (c *Controller) createOrUpdateVpcDnsDep(vpcDns *kubeovnv1.VpcDns) error {
 		} else {
 			return err
 		}
+	} else if oldDp.DeletionTimestamp != nil {
+		// wait for the pods of the deleting deployment to release the addresses
+		return fmt.Errorf("deployment %s is being deleted", oldDp.Name)
 	}
 
-	newDp, err := c.genVpcDnsDeployment(vpcDns, oldDp)
+	newDp, err := c.genVpcDnsDeployment(vpcDns, oldDp, reverseDNS)
 	if err != nil {
 		klog.Errorf("failed to generate vpc-dns deployment, %v", err)
 		return err
@@ -337,7 +386,7 @@ func (c *Controller) createOrUpdateVpcDnsSlr(vpcDns *kubeovnv1.VpcDns) error {
 	return nil
 }
 
-func (c *Controller) genVpcDnsDeployment(vpcDns *kubeovnv1.VpcDns, oldDeploy *v1.Deployment) (*v1.Deployment, error) {
+func (c *Controller) genVpcDnsDeployment(vpcDns *kubeovnv1.VpcDns, oldDeploy *v1.Deployment, reverseDNS bool) (*v1.Deployment, error) {
 	if _, err := os.Stat(CorednsTemplateDep); errors.Is(err, os.ErrNotExist) {
 		if err := getCoreDnsTemplateFile(corednsYamlUrl); err != nil {
 			klog.Errorf("failed to get coredns template file, %v", err)
@@ -382,7 +431,13 @@ func (c *Controller) genVpcDnsDeployment(vpcDns *kubeovnv1.VpcDns, oldDeploy *v1
 	}
 
 	setCoreDnsEnv(dep)
-	setVpcDnsInterface(dep, vpcDns.Spec.Subnet)
+	setVpcDnsInterface(dep, vpcDns.Spec.Subnet, vpcDns.Spec.IPs)
+	if reverseDNS {
+		setVpcDnsReverseConfig(dep, genVpcDnsReverseCmName(vpcDns.Name))
+	}
+	if dep.Spec.Replicas != nil && len(vpcDns.Spec.IPs) != 0 && len(vpcDns.Spec.IPs) < int(*dep.Spec.Replicas) {
+		return nil, fmt.Errorf("%d ips of vpc-dns %s are not enough for %d replicas", len(vpcDns.Spec.IPs), vpcDns.Name, *dep.Spec.Replicas)
+	}
 
 	defaultSubnet, err := c.subnetsLister.Get(util.DefaultSubnet)
 	if err != nil {
@@ -423,9 +478,14 @@ func (c *Controller) genVpcDnsSlr(vpcName, namespace string) (*kubeovnv1.SwitchL
 	return slr, nil
 }
 
-func setVpcDnsInterface(dp *v1.Deployment, subnetName string) {
+func setVpcDnsInterface(dp *v1.Deployment, subnetName string, ips []string) {
 	annotations := dp.Spec.Template.Annotations
 	annotations[util.LogicalSwitchAnnotation] = subnetName
+	if len(ips) != 0 {
+		annotations[util.IpPoolAnnotation] = strings.Join(ips, ";")
+	} else {
+		delete(annotations, util.IpPoolAnnotation)
+	}
 	annotations[util.AttachmentNetworkAnnotation] = fmt.Sprintf("%s/%s", corev1.NamespaceDefault, nadName)
 	annotations[fmt.Sprintf(util.LogicalSwitchAnnotationTemplate, nadProvider)] = util.DefaultSubnet
 }
@@ -558,6 +618,19 @@ func (c *Controller) resyncVpcDnsConfig() {
 	k8sServiceHost = getValue("k8s-service-host")
 	k8sServicePort = getValue("k8s-service-port")
 
+	reverseDnsDomain = getValue("reverse-dns-domain")
+	if len(reverseDnsDomain) == 0 {
+		reverseDnsDomain = defaultReverseDnsDomain
+	}
+	reverseDnsMaxRecords = defaultReverseDnsMaxRecords
+	if v := getValue("reverse-dns-max-records"); len(v) != 0 {
+		if n, err := strconv.Atoi(v); err != nil || n <= 0 {
+			klog.Errorf("invalid reverse-dns-max-records %q, use the default %d", v, defaultReverseDnsMaxRecords)
+		} else {
+			reverseDnsMaxRecords = n
+		}
+	}
+
 	newEnableCoredns := true
 	if v, ok := cm.Data["enable-vpc-dns"]; ok {
 		raw, err := strconv.ParseBool(v)
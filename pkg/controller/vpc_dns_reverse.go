@@ -0,0 +1,264 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+const (
+	vpcDnsReverseVolume    = "reverse-config-volume"
+	vpcDnsReverseMountPath = "/etc/coredns-reverse"
+	vpcDnsReverseHostsFile = "reverse.hosts"
+
+	defaultReverseDnsDomain     = "cluster.local"
+	defaultReverseDnsMaxRecords = 10000
+)
+
+var (
+	reverseDnsDomain     = defaultReverseDnsDomain
+	reverseDnsMaxRecords = defaultReverseDnsMaxRecords
+)
+
+func genVpcDnsReverseCmName(name string) string {
+	return fmt.Sprintf("vpc-dns-%s-reverse", name)
+}
+
+// enqueueVpcDnsReverse enqueues the vpc-dns serving the reverse records of the subnet,
+// the records are synced with a delay to batch the changes of the pods
+func (c *Controller) enqueueVpcDnsReverse(subnetName string) {
+	if !c.config.EnableLb {
+		return
+	}
+	subnet, err := c.subnetsLister.Get(subnetName)
+	if err != nil || !subnet.Spec.EnableReverseDNS {
+		return
+	}
+	for _, name := range c.getVpcDnsNamesOfVpc(subnet.Spec.Vpc) {
+		klog.V(3).Infof("enqueue sync reverse records of vpc-dns %s", name)
+		c.syncVpcDnsReverseQueue.AddAfter(name, 2*time.Second)
+	}
+}
+
+func (c *Controller) getVpcDnsNamesOfVpc(vpc string) []string {
+	vpcDnsList, err := c.vpcDnsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list vpc-dns, %v", err)
+		return nil
+	}
+	var names []string
+	for _, vpcDns := range vpcDnsList {
+		if vpcDns.Spec.Vpc == vpc {
+			names = append(names, vpcDns.Name)
+		}
+	}
+	return names
+}
+
+func (c *Controller) runSyncVpcDnsReverseWorker() {
+	for c.processNextWorkItem("syncVpcDnsReverse", c.syncVpcDnsReverseQueue, c.handleSyncVpcDnsReverse) {
+	}
+}
+
+func (c *Controller) handleSyncVpcDnsReverse(key string) error {
+	vpcDns, err := c.vpcDnsLister.Get(key)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !vpcDns.Status.Active {
+		return nil
+	}
+	_, err = c.syncVpcDnsReverseConfigMap(vpcDns)
+	return err
+}
+
+// getVpcReverseDnsSubnets returns the subnets of the vpc with reverse dns enabled, ordered by name
+func (c *Controller) getVpcReverseDnsSubnets(vpc string) ([]*kubeovnv1.Subnet, error) {
+	subnets, err := c.subnetsLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var result []*kubeovnv1.Subnet
+	for _, subnet := range subnets {
+		if subnet.Spec.Vpc == vpc && subnet.Spec.EnableReverseDNS && subnet.DeletionTimestamp == nil {
+			result = append(result, subnet)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// genVpcDnsReverseData generates the Corefile and the hosts file serving the reverse records of the pods allocated
+// in the subnets. To keep the ConfigMap far below the size limit, the records are limited to reverseDnsMaxRecords,
+// the subnets exceeding the limit are skipped as a whole rather than served partially and returned to be reported
+func (c *Controller) genVpcDnsReverseData(subnets []*kubeovnv1.Subnet) (map[string]string, []string) {
+	var zones, skipped, records []string
+	for _, subnet := range subnets {
+		allocations, err := c.ipam.ListSubnetAllocations(subnet.Name)
+		if err != nil {
+			klog.Errorf("failed to list allocations of subnet %s, %v", subnet.Name, err)
+			continue
+		}
+		var subnetRecords []string
+		for _, allocation := range allocations {
+			if allocation.Reserved {
+				continue
+			}
+			namespace, name, err := cache.SplitMetaNamespaceKey(strings.Split(allocation.Owner, ",")[0])
+			if err != nil || namespace == "" {
+				continue
+			}
+			subnetRecords = append(subnetRecords, fmt.Sprintf("%s %s.%s.pod.%s", allocation.IP, name, namespace, reverseDnsDomain))
+		}
+		if len(records)+len(subnetRecords) > reverseDnsMaxRecords {
+			skipped = append(skipped, subnet.Name)
+			continue
+		}
+		records = append(records, subnetRecords...)
+		for _, cidr := range strings.Split(subnet.Spec.CIDRBlock, ",") {
+			zone, err := util.ReverseDNSZone(cidr)
+			if err != nil {
+				klog.Errorf("failed to get reverse zone of subnet %s, %v", subnet.Name, err)
+				continue
+			}
+			if !util.ContainsString(zones, zone) {
+				zones = append(zones, zone)
+			}
+		}
+	}
+	sort.Strings(records)
+
+	corefile := fmt.Sprintf("import %s\n", path.Join("/etc/coredns", "Corefile"))
+	if len(zones) != 0 {
+		corefile += fmt.Sprintf(`
+%s {
+    hosts %s {
+        ttl 30
+        reload 10s
+    }
+    errors
+}
+`, strings.Join(zones, " "), path.Join(vpcDnsReverseMountPath, vpcDnsReverseHostsFile))
+	}
+	return map[string]string{
+		"Corefile":             corefile,
+		vpcDnsReverseHostsFile: strings.Join(records, "\n") + "\n",
+	}, skipped
+}
+
+// syncVpcDnsReverseConfigMap creates or updates the ConfigMap serving the reverse records of the vpc-dns,
+// the ConfigMap is deleted and false is returned if no subnet in the vpc enables reverse dns
+func (c *Controller) syncVpcDnsReverseConfigMap(vpcDns *kubeovnv1.VpcDns) (bool, error) {
+	name := genVpcDnsReverseCmName(vpcDns.Name)
+	subnets, err := c.getVpcReverseDnsSubnets(vpcDns.Spec.Vpc)
+	if err != nil {
+		klog.Errorf("failed to get reverse dns subnets of vpc %s, %v", vpcDns.Spec.Vpc, err)
+		return false, err
+	}
+	if len(subnets) == 0 {
+		if err = c.deleteVpcDnsReverseConfigMap(vpcDns.Name); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	data, skipped := c.genVpcDnsReverseData(subnets)
+	if len(skipped) != 0 {
+		msg := fmt.Sprintf("reverse records of subnets %s are skipped as the records of vpc %s exceed the limit %d",
+			strings.Join(skipped, ","), vpcDns.Spec.Vpc, reverseDnsMaxRecords)
+		klog.Warning(msg)
+		c.recorder.Event(vpcDns, corev1.EventTypeWarning, "ReverseRecordsSkipped", msg)
+	}
+
+	cm, err := c.config.KubeClient.CoreV1().ConfigMaps(c.config.PodNamespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			klog.Errorf("failed to get configmap %s, %v", name, err)
+			return false, err
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{util.VpcDnsNameLabel: "true"},
+			},
+			Data: data,
+		}
+		if _, err = c.config.KubeClient.CoreV1().ConfigMaps(c.config.PodNamespace).Create(context.Background(), cm, metav1.CreateOptions{}); err != nil {
+			klog.Errorf("failed to create configmap %s, %v", name, err)
+			return false, err
+		}
+		return true, nil
+	}
+	if reflect.DeepEqual(cm.Data, data) {
+		return true, nil
+	}
+	cm = cm.DeepCopy()
+	cm.Data = data
+	if _, err = c.config.KubeClient.CoreV1().ConfigMaps(c.config.PodNamespace).Update(context.Background(), cm, metav1.UpdateOptions{}); err != nil {
+		klog.Errorf("failed to update configmap %s, %v", name, err)
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *Controller) deleteVpcDnsReverseConfigMap(vpcDnsName string) error {
+	name := genVpcDnsReverseCmName(vpcDnsName)
+	err := c.config.KubeClient.CoreV1().ConfigMaps(c.config.PodNamespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		klog.Errorf("failed to delete configmap %s, %v", name, err)
+		return err
+	}
+	return nil
+}
+
+// setVpcDnsReverseConfig runs coredns with the Corefile importing the original one and serving the reverse zones
+func setVpcDnsReverseConfig(dp *v1.Deployment, cmName string) {
+	dp.Spec.Template.Spec.Volumes = append(dp.Spec.Template.Spec.Volumes, corev1.Volume{
+		Name: vpcDnsReverseVolume,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: cmName},
+			},
+		},
+	})
+	for i, container := range dp.Spec.Template.Spec.Containers {
+		if container.Name == CorednsContainerName {
+			dp.Spec.Template.Spec.Containers[i].Args = []string{"-conf", path.Join(vpcDnsReverseMountPath, "Corefile")}
+			dp.Spec.Template.Spec.Containers[i].VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name:      vpcDnsReverseVolume,
+				MountPath: vpcDnsReverseMountPath,
+				ReadOnly:  true,
+			})
+			break
+		}
+	}
+}
+
+// enqueueVpcDnsOfSubnet enqueues the vpc-dns of the subnet to update the reverse zones and the deployment
+func (c *Controller) enqueueVpcDnsOfSubnet(subnet *kubeovnv1.Subnet) {
+	if !c.config.EnableLb {
+		return
+	}
+	for _, name := range c.getVpcDnsNamesOfVpc(subnet.Spec.Vpc) {
+		klog.V(3).Infof("enqueue update vpc-dns %s", name)
+		c.addOrUpdateVpcDnsQueue.Add(name)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 	"time"
@@ -45,8 +46,15 @@ const (
 	natGwSubnetRouteAdd    = "subnet-route-add"
 	natGwSubnetRouteDel    = "subnet-route-del"
 	natGwExtSubnetRouteAdd = "ext-subnet-route-add"
+	natGwHealthCheck       = "health-check"
+	natGwEipArp            = "eip-arp"
 
 	getIptablesVersion = "get-iptables-version"
+
+	// consecutive probe failures before a nat gateway is considered unhealthy
+	natGwUnhealthyThreshold = 3
+	// skip probing pods that started recently to avoid false negatives during restarts
+	natGwProbeGracePeriod = time.Minute
 )
 
 func genNatGwStsName(name string) string {
@@ -118,6 +126,11 @@ func (c *Controller) enqueueUpdateVpcNatGw(old, new interface{}) {
 	if !c.isLeader() {
 		return
 	}
+	oldGw, newGw := old.(*kubeovnv1.VpcNatGateway), new.(*kubeovnv1.VpcNatGateway)
+	if reflect.DeepEqual(oldGw.Spec, newGw.Spec) && !reflect.DeepEqual(oldGw.Status, newGw.Status) {
+		// status updated by the health probe
+		return
+	}
 	var key string
 	var err error
 	if key, err = cache.MetaNamespaceKeyFunc(new); err != nil {
@@ -215,6 +228,8 @@ func (c *Controller) handleDelVpcNatGw(key string) error {
 	defer c.vpcNatGwKeyMutex.Unlock(key)
 	name := genNatGwStsName(key)
 	klog.Infof("delete vpc nat gw %s", name)
+	metricVpcNatGwHealthy.DeletePartialMatch(map[string]string{"vpc_nat_gateway": key})
+	c.vpcNatGwIneligibleSince.Delete(key)
 	if err := c.config.KubeClient.AppsV1().StatefulSets(c.config.PodNamespace).Delete(context.Background(),
 		name, metav1.DeleteOptions{}); err != nil {
 		if k8serrors.IsNotFound(err) {
@@ -271,7 +286,7 @@ func (c *Controller) handleAddOrUpdateVpcNatGw(key string) error {
 			klog.Errorf("failed to create statefulset '%s', err: %v", newSts.Name, err)
 			return err
 		}
-		return nil
+		return c.checkNatGwPlacement(gw)
 	} else {
 		_, err := c.config.KubeClient.AppsV1().StatefulSets(c.config.PodNamespace).
 			Update(context.Background(), newSts, metav1.UpdateOptions{})
@@ -281,7 +296,7 @@ func (c *Controller) handleAddOrUpdateVpcNatGw(key string) error {
 			return err
 		}
 	}
-	return nil
+	return c.checkNatGwPlacement(gw)
 }
 
 func (c *Controller) handleInitVpcNatGw(key string) error {
@@ -344,7 +359,7 @@ func (c *Controller) handleInitVpcNatGw(key string) error {
 		klog.Errorf("patch pod %s/%s failed %v", pod.Name, pod.Namespace, err)
 		return err
 	}
-	return nil
+	return c.completeVpcNatGwDrain(gw, pod)
 }
 
 func (c *Controller) handleUpdateVpcFloatingIp(natGwKey string) error {
@@ -660,14 +675,7 @@ func (c *Controller) genNatGwStatefulSet(gw *kubeovnv1.VpcNatGateway, oldSts *v1
 		newPodAnnotations[key] = value
 	}
 
-	selectors := make(map[string]string)
-	for _, v := range gw.Spec.Selector {
-		parts := strings.Split(strings.TrimSpace(v), ":")
-		if len(parts) != 2 {
-			continue
-		}
-		selectors[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
-	}
+	selectors := parseNatGwSelector(gw)
 	klog.V(3).Infof("prepare for vpc nat gateway pod, node selector: %v", selectors)
 
 	var tolerations []corev1.Toleration
@@ -715,6 +723,7 @@ func (c *Controller) genNatGwStatefulSet(gw *kubeovnv1.VpcNatGateway, oldSts *v1
 						},
 					},
 					NodeSelector: selectors,
+					Affinity:     genNatGwAffinity(gw, c.natGwAvoidDrainingNodes(gw, oldSts)),
 					Tolerations:  tolerations,
 				},
 			},
@@ -726,6 +735,332 @@ func (c *Controller) genNatGwStatefulSet(gw *kubeovnv1.VpcNatGateway, oldSts *v1
 	return
 }
 
+func parseNatGwSelector(gw *kubeovnv1.VpcNatGateway) map[string]string {
+	selectors := make(map[string]string)
+	for _, v := range gw.Spec.Selector {
+		parts := strings.Split(strings.TrimSpace(v), ":")
+		if len(parts) != 2 {
+			continue
+		}
+		selectors[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return selectors
+}
+
+// genNatGwAffinity restricts the gateway pod to the candidate nodes with the external provider network ready,
+// and prefers the candidate nodes in order. The gateway pod is also kept away from the draining nodes if required
+func genNatGwAffinity(gw *kubeovnv1.VpcNatGateway, avoidDraining bool) *corev1.Affinity {
+	var requirements []corev1.NodeSelectorRequirement
+	if avoidDraining {
+		requirements = append(requirements, corev1.NodeSelectorRequirement{
+			Key:      util.VpcNatGwDrainingLabel,
+			Operator: corev1.NodeSelectorOpDoesNotExist,
+		})
+	}
+	if len(gw.Spec.Nodes) != 0 {
+		requirements = append(requirements, corev1.NodeSelectorRequirement{
+			Key:      corev1.LabelHostname,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   gw.Spec.Nodes,
+		})
+	}
+	if gw.Spec.ExternalProvider != "" {
+		requirements = append(requirements, corev1.NodeSelectorRequirement{
+			Key:      fmt.Sprintf(util.ProviderNetworkReadyTemplate, gw.Spec.ExternalProvider),
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{"true"},
+		})
+	}
+	if len(requirements) == 0 {
+		return nil
+	}
+
+	nodeAffinity := &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: requirements}},
+		},
+	}
+	for i, node := range gw.Spec.Nodes {
+		weight := int32(100 - i)
+		if weight < 1 {
+			weight = 1
+		}
+		nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+			corev1.PreferredSchedulingTerm{
+				Weight: weight,
+				Preference: corev1.NodeSelectorTerm{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{
+						Key:      corev1.LabelHostname,
+						Operator: corev1.NodeSelectorOpIn,
+						Values:   []string{node},
+					}},
+				},
+			})
+	}
+	return &corev1.Affinity{NodeAffinity: nodeAffinity}
+}
+
+// natGwAvoidDrainingNodes checks whether the gateway pod must be kept away from the draining nodes. It's only
+// required once the gateway is drained from a node and kept afterwards, so that the template of the statefulset
+// is unchanged and the gateways not involved in any drain are never restarted
+func (c *Controller) natGwAvoidDrainingNodes(gw *kubeovnv1.VpcNatGateway, oldSts *v1.StatefulSet) bool {
+	if oldSts != nil {
+		if affinity := oldSts.Spec.Template.Spec.Affinity; affinity != nil && affinity.NodeAffinity != nil &&
+			affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+			for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+				for _, requirement := range term.MatchExpressions {
+					if requirement.Key == util.VpcNatGwDrainingLabel {
+						return true
+					}
+				}
+			}
+		}
+	}
+
+	sel := labels.SelectorFromSet(labels.Set{"app": genNatGwStsName(gw.Name), util.VpcNatGatewayLabel: "true"})
+	pods, err := c.podsLister.Pods(c.config.PodNamespace).List(sel)
+	if err != nil {
+		klog.Errorf("failed to list pods of vpc nat gateway %s, %v", gw.Name, err)
+		return false
+	}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		if node, err := c.nodesLister.Get(pod.Spec.NodeName); err == nil && node.Labels[util.VpcNatGwDrainingLabel] == "true" {
+			// the template change restarts the pod, which must wait until another node is eligible
+			eligibleNodes, err := c.getNatGwEligibleNodes(gw)
+			return err == nil && len(eligibleNodes) != 0
+		}
+	}
+	return false
+}
+
+// getNatGwEligibleNodes returns names of the ready and not draining nodes which match the selector and candidate
+// nodes of the gateway and have the external provider network ready
+func (c *Controller) getNatGwEligibleNodes(gw *kubeovnv1.VpcNatGateway) ([]string, error) {
+	nodes, err := c.nodesLister.List(labels.SelectorFromSet(parseNatGwSelector(gw)))
+	if err != nil {
+		klog.Errorf("failed to list nodes, %v", err)
+		return nil, err
+	}
+
+	readyLabel := fmt.Sprintf(util.ProviderNetworkReadyTemplate, gw.Spec.ExternalProvider)
+	var eligibleNodes []string
+	for _, node := range nodes {
+		if !nodeReady(node) || node.Labels[util.VpcNatGwDrainingLabel] == "true" {
+			continue
+		}
+		if len(gw.Spec.Nodes) != 0 && !util.ContainsString(gw.Spec.Nodes, node.Labels[corev1.LabelHostname]) {
+			continue
+		}
+		if gw.Spec.ExternalProvider != "" && node.Labels[readyLabel] != "true" {
+			continue
+		}
+		eligibleNodes = append(eligibleNodes, node.Name)
+	}
+	return eligibleNodes, nil
+}
+
+// natGwIneligibility records since when the node of a vpc nat gateway pod is ineligible
+type natGwIneligibility struct {
+	uid   types.UID
+	since time.Time
+}
+
+// checkNatGwPlacement emits an event if no node is eligible for the gateway pod, and deletes the gateway pod
+// running on a draining node, or on a node ineligible for the failover grace period, to move it to an eligible one
+func (c *Controller) checkNatGwPlacement(gw *kubeovnv1.VpcNatGateway) error {
+	restricted := len(gw.Spec.Nodes) != 0 || gw.Spec.ExternalProvider != ""
+	eligibleNodes, err := c.getNatGwEligibleNodes(gw)
+	if err != nil {
+		return err
+	}
+	if len(eligibleNodes) == 0 && restricted {
+		klog.Warningf("no eligible node for vpc nat gateway %s", gw.Name)
+		c.recorder.Eventf(gw, corev1.EventTypeWarning, "NoEligibleNode",
+			"no ready node with external provider network %q ready in candidate nodes %v", gw.Spec.ExternalProvider, gw.Spec.Nodes)
+		return nil
+	}
+
+	sel, _ := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels: map[string]string{"app": genNatGwStsName(gw.Name), util.VpcNatGatewayLabel: "true"},
+	})
+	pods, err := c.podsLister.Pods(c.config.PodNamespace).List(sel)
+	if err != nil {
+		klog.Errorf("failed to list pods of vpc nat gateway %s, %v", gw.Name, err)
+		return err
+	}
+	var ineligible bool
+	defer func() {
+		if !ineligible {
+			c.vpcNatGwIneligibleSince.Delete(gw.Name)
+		}
+	}()
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.DeletionTimestamp != nil || util.ContainsString(eligibleNodes, pod.Spec.NodeName) {
+			continue
+		}
+		if node, err := c.nodesLister.Get(pod.Spec.NodeName); err == nil && node.Labels[util.VpcNatGwDrainingLabel] == "true" {
+			if err = c.drainVpcNatGwPod(gw, pod, eligibleNodes); err != nil {
+				return err
+			}
+			continue
+		}
+		if !restricted {
+			continue
+		}
+
+		// fail over only if the node stays ineligible, so that short outages of the kubelet don't flap the gateway
+		ineligible = true
+		value, _ := c.vpcNatGwIneligibleSince.LoadOrStore(gw.Name, natGwIneligibility{uid: pod.UID, since: time.Now()})
+		ineligibility := value.(natGwIneligibility)
+		if ineligibility.uid != pod.UID {
+			ineligibility = natGwIneligibility{uid: pod.UID, since: time.Now()}
+			c.vpcNatGwIneligibleSince.Store(gw.Name, ineligibility)
+		}
+		if remaining := c.config.NatGwFailoverGracePeriod - time.Since(ineligibility.since); remaining > 0 {
+			klog.Infof("node %s of vpc nat gateway pod %s is not eligible, fail over in %v unless it recovers", pod.Spec.NodeName, pod.Name, remaining.Round(time.Second))
+			c.addOrUpdateVpcNatGatewayQueue.AddAfter(gw.Name, remaining)
+			continue
+		}
+
+		klog.Infof("node %s of vpc nat gateway pod %s is no longer eligible, fail over to %v", pod.Spec.NodeName, pod.Name, eligibleNodes)
+		c.recorder.Eventf(gw, corev1.EventTypeNormal, "NatGwFailover", "fail over from node %s", pod.Spec.NodeName)
+		// never force delete the pod, the replacement is not created until the kubelet confirms the termination or
+		// the node is removed, so that no two pods run with the same lan ip and eips
+		if err = c.config.KubeClient.CoreV1().Pods(pod.Namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			klog.Errorf("failed to delete vpc nat gateway pod %s, %v", pod.Name, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueueVpcNatGwsForPlacement re-evaluates the placement of the gateways restricted to candidate nodes or
+// external provider networks
+func (c *Controller) enqueueVpcNatGwsForPlacement() {
+	if vpcNatEnabled != "true" {
+		return
+	}
+	gws, err := c.vpcNatGatewayLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list vpc nat gateway, %v", err)
+		return
+	}
+	for _, gw := range gws {
+		if len(gw.Spec.Nodes) != 0 || gw.Spec.ExternalProvider != "" {
+			c.addOrUpdateVpcNatGatewayQueue.Add(gw.Name)
+		}
+	}
+}
+
+// drainVpcNatGwPod deletes the gateway pod gracefully to reschedule it away from the draining node. The node is
+// recorded in the gateway so that the completion is reported after the rules are restored on the new pod
+func (c *Controller) drainVpcNatGwPod(gw *kubeovnv1.VpcNatGateway, pod *corev1.Pod, eligibleNodes []string) error {
+	if len(eligibleNodes) == 0 {
+		err := fmt.Errorf("no other eligible node to drain vpc nat gateway %s from node %s", gw.Name, pod.Spec.NodeName)
+		klog.Error(err)
+		c.recorder.Eventf(gw, corev1.EventTypeWarning, "NatGwDrainBlocked", "no other eligible node to drain from node %s", pod.Spec.NodeName)
+		return err
+	}
+
+	if gw.Annotations[util.VpcNatGwDrainedFromAnnotation] != pod.Spec.NodeName {
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, util.VpcNatGwDrainedFromAnnotation, pod.Spec.NodeName)
+		if _, err := c.config.KubeOvnClient.KubeovnV1().VpcNatGateways().Patch(context.Background(), gw.Name,
+			types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			klog.Errorf("failed to patch vpc nat gw %s: %v", gw.Name, err)
+			return err
+		}
+	}
+
+	klog.Infof("draining vpc nat gateway pod %s from node %s to %v", pod.Name, pod.Spec.NodeName, eligibleNodes)
+	c.recorder.Eventf(gw, corev1.EventTypeNormal, "NatGwDrainStarted", "draining from node %s", pod.Spec.NodeName)
+	if err := c.config.KubeClient.CoreV1().Pods(pod.Namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+		klog.Errorf("failed to delete vpc nat gateway pod %s, %v", pod.Name, err)
+		return err
+	}
+	return nil
+}
+
+// completeVpcNatGwDrain reports the completion of the drain after the new gateway pod is initialized, and reports
+// the node as drained when no gateway pod is left on it, so it's safe to cordon the node for maintenance
+func (c *Controller) completeVpcNatGwDrain(gw *kubeovnv1.VpcNatGateway, pod *corev1.Pod) error {
+	from := gw.Annotations[util.VpcNatGwDrainedFromAnnotation]
+	if from == "" || from == pod.Spec.NodeName {
+		return nil
+	}
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, util.VpcNatGwDrainedFromAnnotation)
+	if _, err := c.config.KubeOvnClient.KubeovnV1().VpcNatGateways().Patch(context.Background(), gw.Name,
+		types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		klog.Errorf("failed to patch vpc nat gw %s: %v", gw.Name, err)
+		return err
+	}
+	klog.Infof("vpc nat gateway %s drained from node %s to %s", gw.Name, from, pod.Spec.NodeName)
+	c.recorder.Eventf(gw, corev1.EventTypeNormal, "NatGwDrainCompleted",
+		"moved from node %s to %s, restoring eip, fip, dnat and snat rules", from, pod.Spec.NodeName)
+
+	node, err := c.nodesLister.Get(from)
+	if err != nil || node.Labels[util.VpcNatGwDrainingLabel] != "true" {
+		return nil
+	}
+	sel := labels.SelectorFromSet(labels.Set{util.VpcNatGatewayLabel: "true"})
+	pods, err := c.podsLister.Pods(c.config.PodNamespace).List(sel)
+	if err != nil {
+		klog.Errorf("failed to list vpc nat gateway pods, %v", err)
+		return err
+	}
+	for _, p := range pods {
+		if p.Spec.NodeName == from {
+			return nil
+		}
+	}
+	// node events are referenced by name, see kubectl describe node
+	ref := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: node.Name, UID: types.UID(node.Name)}}
+	c.recorder.Eventf(ref, corev1.EventTypeNormal, "VpcNatGwDrained", "all vpc nat gateways are drained from the node")
+	return nil
+}
+
+// handleVpcNatGwDrain syncs the draining label of the node with the drain annotation set by the administrators,
+// the gateway pods are kept away from the nodes with the label and moved out when the label is added
+func (c *Controller) handleVpcNatGwDrain(node *corev1.Node) error {
+	draining := node.Annotations[util.VpcNatGwDrainAnnotation] == "true"
+	if draining == (node.Labels[util.VpcNatGwDrainingLabel] == "true") {
+		return nil
+	}
+
+	patch := fmt.Sprintf(`{"metadata":{"labels":{%q:null}}}`, util.VpcNatGwDrainingLabel)
+	if draining {
+		patch = fmt.Sprintf(`{"metadata":{"labels":{%q:"true"}}}`, util.VpcNatGwDrainingLabel)
+		klog.Infof("start draining vpc nat gateways from node %s", node.Name)
+	}
+	if _, err := c.config.KubeClient.CoreV1().Nodes().Patch(context.Background(), node.Name,
+		types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		klog.Errorf("failed to patch node %s: %v", node.Name, err)
+		return err
+	}
+	return nil
+}
+
+// enqueueVpcNatGwsOnNode re-evaluates the placement of the gateways running on the node
+func (c *Controller) enqueueVpcNatGwsOnNode(nodeName string) {
+	if vpcNatEnabled != "true" {
+		return
+	}
+	sel := labels.SelectorFromSet(labels.Set{util.VpcNatGatewayLabel: "true"})
+	pods, err := c.podsLister.Pods(c.config.PodNamespace).List(sel)
+	if err != nil {
+		klog.Errorf("failed to list vpc nat gateway pods, %v", err)
+		return
+	}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == nodeName && pod.Annotations[util.VpcNatGatewayAnnotation] != "" {
+			c.addOrUpdateVpcNatGatewayQueue.Add(pod.Annotations[util.VpcNatGatewayAnnotation])
+		}
+	}
+}
+
 func (c *Controller) cleanUpVpcNatGw() error {
 	gws, err := c.vpcNatGatewayLister.List(labels.Everything())
 	if err != nil {
@@ -839,3 +1174,151 @@ func (c *Controller) getNatGw(router, subnet string) (string, error) {
 	}
 	return "", fmt.Errorf("too many nat gw")
 }
+
+// resyncVpcNatGwHealth probes the dataplane of the vpc nat gateways without changing it,
+// and re-inits the gateways which fail consecutive probes
+func (c *Controller) resyncVpcNatGwHealth() {
+	if vpcNatEnabled != "true" {
+		return
+	}
+	gws, err := c.vpcNatGatewayLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list vpc nat gateway, %v", err)
+		return
+	}
+
+	exists := make(map[string]bool, len(gws))
+	for _, gw := range gws {
+		exists[gw.Name] = true
+		if err = c.probeVpcNatGw(gw.DeepCopy()); err != nil {
+			klog.Errorf("failed to probe vpc nat gateway %s, %v", gw.Name, err)
+		}
+	}
+	for name := range c.vpcNatGwProbeFailures {
+		if !exists[name] {
+			delete(c.vpcNatGwProbeFailures, name)
+		}
+	}
+}
+
+func (c *Controller) probeVpcNatGw(gw *kubeovnv1.VpcNatGateway) error {
+	sel, _ := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels: map[string]string{"app": genNatGwStsName(gw.Name), util.VpcNatGatewayLabel: "true"},
+	})
+	pods, err := c.podsLister.Pods(c.config.PodNamespace).List(sel)
+	if err != nil {
+		return err
+	}
+
+	// the pod is being created, restarted or initialized, it will be probed in the next round
+	if len(pods) != 1 {
+		delete(c.vpcNatGwProbeFailures, gw.Name)
+		return nil
+	}
+	pod := pods[0]
+	if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning ||
+		pod.Annotations[util.VpcNatGatewayInitAnnotation] != "true" ||
+		pod.Status.StartTime == nil || time.Since(pod.Status.StartTime.Time) < natGwProbeGracePeriod {
+		delete(c.vpcNatGwProbeFailures, gw.Name)
+		return nil
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Running == nil || time.Since(status.State.Running.StartedAt.Time) < natGwProbeGracePeriod {
+			delete(c.vpcNatGwProbeFailures, gw.Name)
+			return nil
+		}
+	}
+
+	cmd := fmt.Sprintf("bash /kube-ovn/nat-gateway.sh %s", natGwHealthCheck)
+	_, errOutput, err := util.ExecuteCommandInContainer(c.config.KubeClient, c.config.KubeRestConfig, pod.Namespace, pod.Name, "vpc-nat-gw", []string{"/bin/bash", "-c", cmd}...)
+	if err != nil && strings.TrimSpace(errOutput) != "" {
+		err = errors.New(strings.TrimSpace(errOutput))
+	}
+	if err == nil {
+		delete(c.vpcNatGwProbeFailures, gw.Name)
+		metricVpcNatGwHealthy.WithLabelValues(gw.Name, gw.Spec.Vpc).Set(1)
+		return c.patchVpcNatGwHealthCondition(gw, corev1.ConditionTrue, "ProbeSucceeded", "")
+	}
+
+	c.vpcNatGwProbeFailures[gw.Name]++
+	if c.vpcNatGwProbeFailures[gw.Name] < natGwUnhealthyThreshold {
+		klog.Warningf("vpc nat gateway %s failed the health probe %d times: %v", gw.Name, c.vpcNatGwProbeFailures[gw.Name], err)
+		return nil
+	}
+	delete(c.vpcNatGwProbeFailures, gw.Name)
+
+	klog.Errorf("vpc nat gateway %s is unhealthy, reinit it: %v", gw.Name, err)
+	metricVpcNatGwHealthy.WithLabelValues(gw.Name, gw.Spec.Vpc).Set(0)
+	c.recorder.Eventf(gw, corev1.EventTypeWarning, "ProbeFailed", "vpc nat gateway dataplane is unhealthy: %v", err)
+	if err = c.patchVpcNatGwHealthCondition(gw, corev1.ConditionFalse, "ProbeFailed", err.Error()); err != nil {
+		return err
+	}
+
+	// clear the init annotation so that the init handler redoes the initialization and rules
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, util.VpcNatGatewayInitAnnotation)
+	if _, err = c.config.KubeClient.CoreV1().Pods(pod.Namespace).Patch(context.Background(), pod.Name,
+		types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		klog.Errorf("patch pod %s/%s failed %v", pod.Namespace, pod.Name, err)
+		return err
+	}
+	c.initVpcNatGatewayQueue.Add(gw.Name)
+	return nil
+}
+
+func (c *Controller) patchVpcNatGwHealthCondition(gw *kubeovnv1.VpcNatGateway, status corev1.ConditionStatus, reason, message string) error {
+	if cond := gw.Status.GetVpcNatCondition(kubeovnv1.Healthy); cond != nil &&
+		cond.Status == status && cond.Reason == reason && cond.Message == message {
+		return nil
+	}
+	gw.Status.SetVpcNatCondition(kubeovnv1.Healthy, status, reason, message)
+	bytes, err := gw.Status.Bytes()
+	if err != nil {
+		return err
+	}
+	if _, err = c.config.KubeOvnClient.KubeovnV1().VpcNatGateways().Patch(context.Background(), gw.Name,
+		types.MergePatchType, bytes, metav1.PatchOptions{}, "status"); err != nil {
+		klog.Errorf("failed to patch status of vpc nat gateway %s, %v", gw.Name, err)
+		return err
+	}
+	return nil
+}
+
+// resyncVpcNatGwEipArp announces the ready eips of each vpc nat gateway by gratuitous arp on its external interface,
+// so that the external gateway relearns the eips after it reboots or its arp cache expires
+func (c *Controller) resyncVpcNatGwEipArp() {
+	if vpcNatEnabled != "true" {
+		return
+	}
+	eips, err := c.iptablesEipsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list iptables eip, %v", err)
+		return
+	}
+
+	gwEips := make(map[string][]string)
+	for _, eip := range eips {
+		if !eip.Status.Ready || eip.DeletionTimestamp != nil || eip.Spec.NatGwDp == "" || eip.Spec.V4ip == "" {
+			continue
+		}
+		gwEips[eip.Spec.NatGwDp] = append(gwEips[eip.Spec.NatGwDp], eip.Spec.V4ip)
+	}
+
+	for gw, ips := range gwEips {
+		sel, _ := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+			MatchLabels: map[string]string{"app": genNatGwStsName(gw), util.VpcNatGatewayLabel: "true"},
+		})
+		pods, err := c.podsLister.Pods(c.config.PodNamespace).List(sel)
+		if err != nil {
+			klog.Errorf("failed to list pods of vpc nat gateway %s, %v", gw, err)
+			continue
+		}
+		// the eips are announced by the init process of a new pod, skip the pods not ready
+		if len(pods) != 1 || pods[0].DeletionTimestamp != nil || pods[0].Status.Phase != corev1.PodRunning ||
+			pods[0].Annotations[util.VpcNatGatewayInitAnnotation] != "true" {
+			continue
+		}
+		if err = c.execNatGwRules(pods[0], natGwEipArp, ips); err != nil {
+			klog.Errorf("failed to announce eips %v of vpc nat gateway %s, %v", ips, gw, err)
+		}
+	}
+}
@@ -568,11 +568,13 @@ func (c *Controller) acquireStaticEip(name, namespace, nicName, ip string) (stri
 	var err error
 	for _, ipStr := range strings.Split(ip, ",") {
 		if net.ParseIP(ipStr) == nil {
+			recordInvalidStaticIP(util.VpcExternalNet)
 			return "", "", "", fmt.Errorf("failed to parse eip ip %s", ipStr)
 		}
 	}
 
 	if v4ip, v6ip, mac, err = c.ipam.GetStaticAddress(name, nicName, ip, mac, util.VpcExternalNet, checkConflict); err != nil {
+		recordIPAMFailure(util.VpcExternalNet, err)
 		klog.Errorf("failed to get static ip %v, mac %v, subnet %v, err %v", ip, mac, util.VpcExternalNet, err)
 		return "", "", "", err
 	}
@@ -584,6 +586,7 @@ func (c *Controller) acquireEip(name, namespace, nicName string) (string, string
 	for {
 		ipv4, ipv6, mac, err := c.ipam.GetRandomAddress(name, nicName, "", util.VpcExternalNet, skippedAddrs, true)
 		if err != nil {
+			recordIPAMFailure(util.VpcExternalNet, err)
 			return "", "", "", err
 		}
 
@@ -893,6 +893,17 @@ func (c *Controller) handleAddIptablesSnatRule(key string) error {
 		err = fmt.Errorf("failed to create snat %s, eip '%s' is used by nat '%s'", key, eipName, eip.Status.Nat)
 		return err
 	}
+	// the iptables eip is bound to the vpc of its gateway, which must not share the address with the other vpcs
+	gw, err := c.vpcNatGatewayLister.Get(eip.Spec.NatGwDp)
+	if err != nil {
+		klog.Errorf("failed to get vpc nat gateway %s, %v", eip.Spec.NatGwDp, err)
+		return err
+	}
+	if err = c.checkSnatAddressVpc(eip.Spec.V4ip, gw.Spec.Vpc); err != nil {
+		err = fmt.Errorf("failed to create snat %s, %v", key, err)
+		klog.Error(err)
+		return err
+	}
 	// create snat
 	v4Cidr, _ := util.SplitStringIP(snat.Spec.InternalCIDR)
 	if v4Cidr == "" {
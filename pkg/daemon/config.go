@@ -10,12 +10,15 @@ import (
 	"os/exec"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -29,7 +32,10 @@ import (
 // Configuration is the daemon conf
 type Configuration struct {
 	// interface being used for tunnel
-	tunnelIface             string
+	tunnelIface string
+	// address being used as the tunnel endpoint
+	encapIP string
+
 	Iface                   string
 	DPDKTunnelIface         string
 	MTU                     int
@@ -56,6 +62,34 @@ type Configuration struct {
 	DefaultInterfaceName    string
 	ExternalGatewayConfigNS string
 	ExternalGatewaySwitch   string
+	GatewayCheckMaxRetry    int
+	GatewayCheckTimeout     time.Duration
+	EnableGatewayCheckMtu   bool
+	GatewayCheckMtuSize     int
+	HostPodMasquerade       bool
+	EnableNetworkReadyGate  bool
+	PortRemovalGracePeriod  time.Duration
+	PreservedHostRoutes     []*net.IPNet
+	EnableSflow             bool
+	SflowTarget             string
+	SflowSampling           int
+	MaxPodBandwidth         int
+	SnatPortRange           string
+	SnatReservedPorts       string
+	// SnatPortSegments are the segments of the snat port range excluding the reserved ports, nil to leave the source
+	// ports of the masqueraded traffic allocated by the kernel
+	SnatPortSegments []util.PortRange
+
+	EnableInternodeProbe       bool
+	InternodeProbeInterval     time.Duration
+	InternodeProbeMaxPeers     int
+	InternodeProbeSelector     string
+	InternodeProbeNodeSelector labels.Selector
+
+	Ovn0RecoveryPolicy    string
+	Ovn0RepairMaxFailures int
+
+	ForceProviderCleanup bool
 }
 
 // ParseFlags will parse cmd args then init kubeClient and configuration
@@ -86,6 +120,37 @@ func ParseFlags() *Configuration {
 		argsDefaultInterfaceName   = pflag.String("default-interface-name", "", "The default host interface name in the vlan/vxlan type")
 		argExternalGatewayConfigNS = pflag.String("external-gateway-config-ns", "kube-system", "The namespace of configmap external-gateway-config, default: kube-system")
 		argExternalGatewaySwitch   = pflag.String("external-gateway-switch", "external", "The name of the external gateway switch which is a ovs bridge to provide external network, default: external")
+		argGatewayCheckMaxRetry    = pflag.Int("gateway-check-max-retry", defaultGatewayCheckMaxRetry, "The max retry count of pod and ovn0 gateway check")
+		argGatewayCheckTimeout     = pflag.Duration("gateway-check-timeout", defaultGatewayCheckTimeout, "The timeout of each gateway check retry")
+		argEnableGatewayCheckMtu   = pflag.Bool("enable-gateway-check-mtu", false, "Ping the gateway with a large packet after the gateway check by ping succeeds to detect mtu issues")
+		argGatewayCheckMtuSize     = pflag.Int("gateway-check-mtu-size", 0, "The icmp payload size of the large packet of the gateway mtu check (default the pod iface MTU minus the ip and icmp headers)")
+
+		argHostPodMasquerade = pflag.Bool("host-pod-masquerade", false, "Masquerade the traffic from the host network to overlay pods by the ip of ovn0, so that the reply packets are routed back through the join subnet")
+
+		argEnableNetworkReadyGate = pflag.Bool("enable-network-ready-gate", false, "Set the pod condition "+util.NetworkReadyConditionType+" to true after the gateway check passes, or after the port is bound if the gateway check is disabled, for the pods declaring it as a readiness gate")
+
+		argPortRemovalGracePeriod = pflag.Duration("port-removal-grace-period", 0, "The period to keep the ovs port of a pod after its container exits so that in-flight connections can be drained, 0 to remove the port immediately")
+
+		argPreservedHostRoutes = pflag.String("preserved-host-routes", "", "Comma separated CIDRs of the routes to keep on the provider nic rather than migrating them to the OVS bridge, in addition to the link-local routes")
+
+		argEnableSflow   = pflag.Bool("enable-sflow", false, "Export sFlow samples of the traffic on br-int to the collector")
+		argSflowTarget   = pflag.String("sflow-target", "", "The sFlow collector address in the format of ip:port")
+		argSflowSampling = pflag.Int("sflow-sampling", 64, "The sFlow sampling rate, one packet out of the number of packets is sampled")
+
+		argSnatPortRange     = pflag.String("snat-port-range", "", "The source port range like 40000-60000 of the traffic masqueraded by the node for the nat outgoing subnets, which should not overlap the ephemeral ports of the host services (default allocated by the kernel)")
+		argSnatReservedPorts = pflag.String("snat-reserved-ports", "", "Comma separated ports and port ranges excluded from snat-port-range, like 45000,50000-50099")
+
+		argEnableInternodeProbe   = pflag.Bool("enable-internode-probe", false, "Periodically probe the rtt from ovn0 of the node to ovn0 of the peer nodes and export it as metrics")
+		argInternodeProbeInterval = pflag.Duration("internode-probe-interval", 30*time.Second, "The interval of the internode rtt probes")
+		argInternodeProbeMaxPeers = pflag.Int("internode-probe-max-peers", 32, "The max number of peer nodes probed by each node, a stable subset of the peers is sampled when there are more, 0 to probe all the peers")
+		argInternodeProbeSelector = pflag.String("internode-probe-node-selector", "", "The label selector of the peer nodes to probe (default all the nodes)")
+
+		argOvn0RecoveryPolicy    = pflag.String("ovn0-recovery-policy", ovn0RecoveryRestart, "The policy to recover ovn0 when it's down or the gateway is unreachable, restart to exit and restart kube-ovn-cni, repair to configure the ovn0 port and routes again in place")
+		argOvn0RepairMaxFailures = pflag.Int("ovn0-repair-max-failures", 3, "The number of consecutive failed repairs of ovn0 after which kube-ovn-cni falls back to restart")
+
+		argForceProviderCleanup = pflag.Bool("force-provider-network-cleanup", false, "Clean up the bridge of a deleted provider network and restore the host nic even if subnets still reference the provider network")
+
+		argMaxPodBandwidth = pflag.Int("max-pod-bandwidth", util.DefaultMaxPodBandwidth, "The max rate in Mbit/s accepted by the ingress and egress rate annotations of pods, the rates exceeding it are not applied, 0 to disable")
 	)
 
 	// mute info log for ipset lib
@@ -133,10 +198,52 @@ func ParseFlags() *Configuration {
 		DefaultInterfaceName:    *argsDefaultInterfaceName,
 		ExternalGatewayConfigNS: *argExternalGatewayConfigNS,
 		ExternalGatewaySwitch:   *argExternalGatewaySwitch,
+		GatewayCheckMaxRetry:    *argGatewayCheckMaxRetry,
+		GatewayCheckTimeout:     *argGatewayCheckTimeout,
+		EnableGatewayCheckMtu:   *argEnableGatewayCheckMtu,
+		GatewayCheckMtuSize:     *argGatewayCheckMtuSize,
+		HostPodMasquerade:       *argHostPodMasquerade,
+		EnableNetworkReadyGate:  *argEnableNetworkReadyGate,
+		PortRemovalGracePeriod:  *argPortRemovalGracePeriod,
+		EnableSflow:             *argEnableSflow,
+		SflowTarget:             *argSflowTarget,
+		SflowSampling:           *argSflowSampling,
+		MaxPodBandwidth:         *argMaxPodBandwidth,
+		SnatPortRange:           *argSnatPortRange,
+		SnatReservedPorts:       *argSnatReservedPorts,
+		EnableInternodeProbe:    *argEnableInternodeProbe,
+		InternodeProbeInterval:  *argInternodeProbeInterval,
+		InternodeProbeMaxPeers:  *argInternodeProbeMaxPeers,
+		InternodeProbeSelector:  *argInternodeProbeSelector,
+		Ovn0RecoveryPolicy:      *argOvn0RecoveryPolicy,
+		Ovn0RepairMaxFailures:   *argOvn0RepairMaxFailures,
+		ForceProviderCleanup:    *argForceProviderCleanup,
 	}
+
+	preservedHostRoutes, err := parsePreservedHostRoutes(*argPreservedHostRoutes)
+	if err != nil {
+		util.LogFatalAndExit(err, "failed to parse preserved host routes")
+	}
+	config.PreservedHostRoutes = preservedHostRoutes
 	return config
 }
 
+// parsePreservedHostRoutes parses the route prefixes which should not be migrated between the provider nic and the OVS bridge
+func parsePreservedHostRoutes(cidrs string) ([]*net.IPNet, error) {
+	var prefixes []*net.IPNet
+	for _, cidr := range strings.Split(cidrs, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, prefix, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid preserved host route %q: %v", cidr, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
 func (config *Configuration) Init(nicBridgeMappings map[string]string) error {
 	if config.NodeName == "" {
 		klog.Info("node name not specified in command line parameters, fall back to the environment variable")
@@ -150,6 +257,30 @@ func (config *Configuration) Init(nicBridgeMappings map[string]string) error {
 		}
 	}
 
+	if err := config.validateGatewayCheck(); err != nil {
+		return err
+	}
+	if config.PortRemovalGracePeriod < 0 {
+		return fmt.Errorf("port-removal-grace-period must not be negative, got %v", config.PortRemovalGracePeriod)
+	}
+	if err := config.validateSflow(); err != nil {
+		return err
+	}
+	if config.MaxPodBandwidth < 0 {
+		return fmt.Errorf("max-pod-bandwidth must not be negative, got %d", config.MaxPodBandwidth)
+	}
+	if err := config.validateSnatPorts(); err != nil {
+		return err
+	}
+	if err := config.validateInternodeProbe(); err != nil {
+		return err
+	}
+	if config.Ovn0RecoveryPolicy != ovn0RecoveryRestart && config.Ovn0RecoveryPolicy != ovn0RecoveryRepair {
+		return fmt.Errorf("ovn0-recovery-policy must be %s or %s, got %q", ovn0RecoveryRestart, ovn0RecoveryRepair, config.Ovn0RecoveryPolicy)
+	}
+	if config.Ovn0RepairMaxFailures <= 0 {
+		return fmt.Errorf("ovn0-repair-max-failures must be positive, got %d", config.Ovn0RepairMaxFailures)
+	}
 	if err := config.initKubeClient(); err != nil {
 		return err
 	}
@@ -161,6 +292,123 @@ func (config *Configuration) Init(nicBridgeMappings map[string]string) error {
 	return nil
 }
 
+func (config *Configuration) validateSflow() error {
+	if !config.EnableSflow {
+		return nil
+	}
+	if config.SflowSampling <= 0 {
+		return fmt.Errorf("sflow-sampling must be positive, got %d", config.SflowSampling)
+	}
+	host, port, err := net.SplitHostPort(config.SflowTarget)
+	if err != nil {
+		return fmt.Errorf("invalid sflow-target %q: %v", config.SflowTarget, err)
+	}
+	if net.ParseIP(host) == nil {
+		return fmt.Errorf("invalid sflow-target %q: %s is not a valid ip address", config.SflowTarget, host)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return fmt.Errorf("invalid sflow-target %q: %s is not a valid port", config.SflowTarget, port)
+	}
+	config.SflowTarget = net.JoinHostPort(net.ParseIP(host).String(), port)
+	return nil
+}
+
+func (config *Configuration) validateInternodeProbe() error {
+	if !config.EnableInternodeProbe {
+		return nil
+	}
+	if config.InternodeProbeInterval <= 0 {
+		return fmt.Errorf("internode-probe-interval must be positive, got %v", config.InternodeProbeInterval)
+	}
+	if config.InternodeProbeMaxPeers < 0 {
+		return fmt.Errorf("internode-probe-max-peers must not be negative, got %d", config.InternodeProbeMaxPeers)
+	}
+	selector, err := labels.Parse(config.InternodeProbeSelector)
+	if err != nil {
+		return fmt.Errorf("invalid internode-probe-node-selector %q: %v", config.InternodeProbeSelector, err)
+	}
+	config.InternodeProbeNodeSelector = selector
+	return nil
+}
+
+const (
+	// the snat port range must leave enough ports for the concurrent connections of the masqueraded traffic
+	minSnatPorts = 1024
+	// each segment of the snat port range is masqueraded by separate iptables rules
+	maxSnatPortSegments = 8
+)
+
+func (config *Configuration) validateSnatPorts() error {
+	if config.SnatPortRange == "" {
+		if config.SnatReservedPorts != "" {
+			return fmt.Errorf("snat-reserved-ports requires snat-port-range")
+		}
+		return nil
+	}
+	ranges, err := util.ParsePortRanges(config.SnatPortRange)
+	if err != nil {
+		return fmt.Errorf("invalid snat-port-range: %v", err)
+	}
+	if len(ranges) != 1 {
+		return fmt.Errorf("invalid snat-port-range %q, must be a single port range", config.SnatPortRange)
+	}
+	reserved, err := util.ParsePortRanges(config.SnatReservedPorts)
+	if err != nil {
+		return fmt.Errorf("invalid snat-reserved-ports: %v", err)
+	}
+
+	segments := util.ExcludePortRanges(ranges[0], reserved)
+	var size int
+	for _, segment := range segments {
+		size += segment.Size()
+	}
+	if size < minSnatPorts {
+		return fmt.Errorf("snat-port-range %s leaves %d ports after excluding snat-reserved-ports, at least %d ports are required", config.SnatPortRange, size, minSnatPorts)
+	}
+	if len(segments) > maxSnatPortSegments {
+		return fmt.Errorf("snat-reserved-ports split snat-port-range into %d segments, at most %d segments are supported", len(segments), maxSnatPortSegments)
+	}
+	config.SnatPortSegments = segments
+	return nil
+}
+
+// cniGatewayCheckBudget is the time the CNI plugin waits for the daemon, gateway check must finish within it
+const cniGatewayCheckBudget = 220 * time.Second
+
+func (config *Configuration) validateGatewayCheck() error {
+	if config.GatewayCheckMaxRetry <= 0 {
+		return fmt.Errorf("gateway-check-max-retry must be positive, got %d", config.GatewayCheckMaxRetry)
+	}
+	if config.GatewayCheckTimeout <= 0 {
+		return fmt.Errorf("gateway-check-timeout must be positive, got %v", config.GatewayCheckTimeout)
+	}
+	if total := time.Duration(config.GatewayCheckMaxRetry) * config.GatewayCheckTimeout; total > cniGatewayCheckBudget {
+		klog.Warningf("gateway check may take %v which exceeds the cni timeout %v, pod creation will fail before the check ends", total, cniGatewayCheckBudget)
+	}
+	if config.GatewayCheckMtuSize < 0 {
+		return fmt.Errorf("gateway-check-mtu-size must not be negative, got %d", config.GatewayCheckMtuSize)
+	}
+	if config.GatewayCheckTimeout < 100*time.Millisecond {
+		klog.Warningf("gateway check timeout %v is too short, the gateway may not reply in time", config.GatewayCheckTimeout)
+	}
+	return nil
+}
+
+// gatewayCheckMtuSize returns the icmp payload size of the gateway mtu check, -1 if the check is disabled
+func (config *Configuration) gatewayCheckMtuSize() int {
+	if !config.EnableGatewayCheckMtu {
+		return -1
+	}
+	return config.GatewayCheckMtuSize
+}
+
+// bondStatus is the status of the slaves of a bond
+type bondStatus struct {
+	Mode         string
+	Slaves       int
+	ActiveSlaves int
+}
+
 func (config *Configuration) initNicConfig(nicBridgeMappings map[string]string) error {
 	// Support to specify node network card separately
 	node, err := config.KubeClient.CoreV1().Nodes().Get(context.Background(), config.NodeName, metav1.GetOptions{})
@@ -180,12 +428,21 @@ func (config *Configuration) initNicConfig(nicBridgeMappings map[string]string)
 
 	var mtu int
 	var encapIP string
-	if config.Iface == "" {
+	if tunnelIP := node.GetAnnotations()[util.TunnelIPAnnotation]; tunnelIP != "" && !isDPDKNode {
+		if config.Iface, mtu, err = getIfaceByIP(tunnelIP); err != nil {
+			klog.Errorf("failed to find tunnel ip %s selected by annotation %s on the node: %v", tunnelIP, util.TunnelIPAnnotation, err)
+			return err
+		}
+		klog.Infof("use %s of %s selected by annotation as the tunnel ip", tunnelIP, config.Iface)
+		encapIP = tunnelIP
+		config.tunnelIface = config.Iface
+	} else if config.Iface == "" {
 		encapIP = config.getEncapIP(node)
 		if config.Iface, mtu, err = getIfaceByIP(encapIP); err != nil {
 			klog.Errorf("failed to get interface by IP %s: %v", encapIP, err)
 			return err
 		}
+		config.tunnelIface = config.Iface
 	} else {
 		tunnelNic := config.Iface
 		if brName := nicBridgeMappings[tunnelNic]; brName != "" {
@@ -219,6 +476,12 @@ func (config *Configuration) initNicConfig(nicBridgeMappings map[string]string)
 		config.tunnelIface = iface.Name
 	}
 
+	// the encap ip is set on a user provided bond as on any other nic, make sure it's usable
+	if err = validateTunnelBond(config.tunnelIface); err != nil {
+		klog.Error(err)
+		return err
+	}
+
 	encapIsIPv6 := util.CheckProtocol(encapIP) == kubeovnv1.ProtocolIPv6
 	if encapIsIPv6 && runtime.GOOS == "windows" {
 		// OVS windows datapath does not IPv6 tunnel in version v2.17
@@ -256,6 +519,7 @@ func (config *Configuration) initNicConfig(nicBridgeMappings map[string]string)
 		return err
 	}
 
+	config.encapIP = encapIP
 	return setEncapIP(encapIP)
 }
 
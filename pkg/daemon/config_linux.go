@@ -6,6 +6,7 @@ import (
 
 	"github.com/vishvananda/netlink"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
 )
 
 const defaultBindSocket = "/run/openvswitch/kube-ovn-daemon.sock"
@@ -35,3 +36,54 @@ func (config *Configuration) initRuntimeConfig(node *corev1.Node) error {
 	// nothing to do on Linux
 	return nil
 }
+
+// getBondStatus returns the slaves of the bond, nil if the link is not a bond
+func getBondStatus(name string) (*bondStatus, error) {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link %s: %v", name, err)
+	}
+	bond, ok := link.(*netlink.Bond)
+	if !ok {
+		return nil, nil
+	}
+
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %v", err)
+	}
+	status := &bondStatus{Mode: bond.Mode.String()}
+	for _, l := range links {
+		if l.Attrs().MasterIndex != bond.Index {
+			continue
+		}
+		slave, ok := l.Attrs().Slave.(*netlink.BondSlave)
+		if !ok {
+			continue
+		}
+		status.Slaves++
+		if slave.MiiStatus == netlink.BondLinkUp {
+			status.ActiveSlaves++
+		}
+	}
+	return status, nil
+}
+
+// validateTunnelBond checks that the tunnel interface has an active slave if it's a bond
+func validateTunnelBond(name string) error {
+	status, err := getBondStatus(name)
+	if err != nil || status == nil {
+		return err
+	}
+	if status.Slaves == 0 {
+		return fmt.Errorf("tunnel bond %s has no slave", name)
+	}
+	if status.ActiveSlaves == 0 {
+		return fmt.Errorf("none of the %d slaves of tunnel bond %s is active", status.Slaves, name)
+	}
+	if status.ActiveSlaves < status.Slaves {
+		klog.Warningf("tunnel bond %s is degraded, %d of %d slaves are active", name, status.ActiveSlaves, status.Slaves)
+	}
+	klog.Infof("use bond %s in mode %s with %d active slaves as tunnel interface", name, status.Mode, status.ActiveSlaves)
+	return nil
+}
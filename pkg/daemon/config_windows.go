@@ -136,3 +136,12 @@ func (config *Configuration) createHnsNetwork(name, adapter, protocol string) er
 	}
 	return nil
 }
+
+func getBondStatus(name string) (*bondStatus, error) {
+	// bond is not managed on Windows
+	return nil, nil
+}
+
+func validateTunnelBond(name string) error {
+	return nil
+}
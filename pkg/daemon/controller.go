@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -58,6 +60,17 @@ type Controller struct {
 
 	protocol string
 
+	// whether the bond used as tunnel interface was degraded at the last check
+	tunnelBondDegraded bool
+	// the number of egressing pods exceeding the capacity of the snat port range at the last check
+	snatPortsShortPods int
+	// the peers probed by the internode rtt probe at the last round
+	internodeProbePeers map[string]bool
+	// the tunnel ip selected by the node annotation which is not found on the node at the last check
+	encapIPMismatch string
+	// the number of consecutive failed repairs of ovn0
+	ovn0RepairFailures int
+
 	ControllerRuntime
 }
 
@@ -120,6 +133,7 @@ func NewController(config *Configuration, podInformerFactory informers.SharedInf
 		DeleteFunc: controller.enqueueDeleteSubnet,
 	})
 	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    controller.enqueueAddPod,
 		UpdateFunc: controller.enqueuePod,
 	})
 
@@ -261,9 +275,34 @@ func (c *Controller) initProviderNetwork(pn *kubeovnv1.ProviderNetwork, node *v1
 		}
 	}
 
-	var mtu int
-	var err error
-	if mtu, err = ovsInitProviderNetwork(pn.Name, nic, pn.Spec.ExchangeLinkName, c.config.MacLearningFallback); err != nil {
+	pns, err := c.providerNetworksLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list provider networks: %v", err)
+		return err
+	}
+	if err = util.ValidateProviderNetworkBridge(pn, pns); err != nil {
+		klog.Errorf("failed to validate bridge of provider network %s: %v", pn.Name, err)
+		pn.Status.SetNodeNotReady(node.Name, "BridgeNameConflict", err.Error())
+		if util.ContainsString(pn.Status.ReadyNodes, node.Name) {
+			pn.Status.ReadyNodes = util.RemoveString(pn.Status.ReadyNodes, node.Name)
+		}
+		if _, err1 := c.config.KubeOvnClient.KubeovnV1().ProviderNetworks().UpdateStatus(context.Background(), pn, metav1.UpdateOptions{}); err1 != nil {
+			klog.Errorf("failed to update status of provider network %s: %v", pn.Name, err1)
+		}
+		return err
+	}
+
+	mtu, failedStep, initErr := ovsInitProviderNetwork(pn.Name, util.ProviderNetworkBridgeName(pn), nic, pn.Spec.ExchangeLinkName, c.config.MacLearningFallback, c.config.PreservedHostRoutes)
+	setProviderNetworkNodeSteps(&pn.Status, node.Name, failedStep, initErr)
+	if _, err = c.config.KubeOvnClient.KubeovnV1().ProviderNetworks().UpdateStatus(context.Background(), pn, metav1.UpdateOptions{}); err != nil {
+		klog.Errorf("failed to update status of provider network %s: %v", pn.Name, err)
+		if initErr == nil {
+			return err
+		}
+	}
+
+	// the node is labeled ready only if all the steps are completed
+	if !pn.Status.NodeStepsReady(node.Name) {
 		if oldLen := len(node.Labels); oldLen != 0 {
 			delete(node.Labels, fmt.Sprintf(util.ProviderNetworkReadyTemplate, pn.Name))
 			delete(node.Labels, fmt.Sprintf(util.ProviderNetworkInterfaceTemplate, pn.Name))
@@ -277,27 +316,7 @@ func (c *Controller) initProviderNetwork(pn *kubeovnv1.ProviderNetwork, node *v1
 				}
 			}
 		}
-
-		pn.Status.SetNodeNotReady(node.Name, "InitOVSBridgeFailed", err.Error())
-		if util.ContainsString(pn.Status.ReadyNodes, node.Name) {
-			pn.Status.ReadyNodes = util.RemoveString(pn.Status.ReadyNodes, node.Name)
-		}
-		pn, err1 := c.config.KubeOvnClient.KubeovnV1().ProviderNetworks().UpdateStatus(context.Background(), pn, metav1.UpdateOptions{})
-		if err1 != nil {
-			klog.Errorf("failed to update status of provider network %s: %v", pn.Name, err1)
-		}
-
-		return err
-	}
-
-	pn.Status.SetNodeReady(node.Name, "InitOVSBridgeSucceeded", "")
-	if !util.ContainsString(pn.Status.ReadyNodes, node.Name) {
-		pn.Status.ReadyNodes = append(pn.Status.ReadyNodes, node.Name)
-	}
-	_, err = c.config.KubeOvnClient.KubeovnV1().ProviderNetworks().UpdateStatus(context.Background(), pn, metav1.UpdateOptions{})
-	if err != nil {
-		klog.Errorf("failed to update status of provider network %s: %v", pn.Name, err)
-		return err
+		return initErr
 	}
 
 	delete(node.Labels, fmt.Sprintf(util.ProviderNetworkExcludeTemplate, pn.Name))
@@ -322,6 +341,42 @@ func (c *Controller) initProviderNetwork(pn *kubeovnv1.ProviderNetwork, node *v1
 	return nil
 }
 
+var providerNetworkStepFailedReasons = map[kubeovnv1.ConditionType]string{
+	kubeovnv1.BridgeReady:    "InitOVSBridgeFailed",
+	kubeovnv1.RoutesMigrated: "MigrateRoutesFailed",
+	kubeovnv1.NicAttached:    "AttachNicFailed",
+}
+
+// setProviderNetworkNodeSteps sets the conditions of the steps initializing the provider network on the node,
+// the steps after the failed one are unknown as they're not reached, and the node is ready only if all the steps
+// are completed
+func setProviderNetworkNodeSteps(status *kubeovnv1.ProviderNetworkStatus, node string, failedStep kubeovnv1.ConditionType, err error) {
+	var failed bool
+	for _, step := range kubeovnv1.ProviderNetworkNodeSteps {
+		switch {
+		case failed:
+			status.SetNodeConditionUnknown(node, step, "Pending", fmt.Sprintf("waiting for step %s", failedStep))
+		case err != nil && step == failedStep:
+			failed = true
+			status.ClearNodeCondition(node, step, providerNetworkStepFailedReasons[step], err.Error())
+		default:
+			status.SetNodeCondition(node, step, "Completed", "")
+		}
+	}
+
+	if status.NodeStepsReady(node) {
+		status.SetNodeReady(node, "InitOVSBridgeSucceeded", "")
+		if !util.ContainsString(status.ReadyNodes, node) {
+			status.ReadyNodes = append(status.ReadyNodes, node)
+		}
+		return
+	}
+	status.SetNodeNotReady(node, providerNetworkStepFailedReasons[failedStep], err.Error())
+	if util.ContainsString(status.ReadyNodes, node) {
+		status.ReadyNodes = util.RemoveString(status.ReadyNodes, node)
+	}
+}
+
 func (c *Controller) updateProviderNetworkStatusForNodeDeletion(pn *kubeovnv1.ProviderNetwork, node string) error {
 	var needUpdate bool
 	if util.ContainsString(pn.Status.ReadyNodes, node) {
@@ -376,7 +431,7 @@ func (c *Controller) cleanProviderNetwork(pn *kubeovnv1.ProviderNetwork, node *v
 	if err = c.updateProviderNetworkStatusForNodeDeletion(pn.DeepCopy(), node.Name); err != nil {
 		return err
 	}
-	if err = ovsCleanProviderNetwork(pn.Name); err != nil {
+	if err = ovsCleanProviderNetwork(pn.Name, util.ProviderNetworkBridgeName(pn), c.config.PreservedHostRoutes); err != nil {
 		return err
 	}
 
@@ -384,7 +439,41 @@ func (c *Controller) cleanProviderNetwork(pn *kubeovnv1.ProviderNetwork, node *v
 }
 
 func (c *Controller) handleDeleteProviderNetwork(pn *kubeovnv1.ProviderNetwork) error {
-	if err := ovsCleanProviderNetwork(pn.Name); err != nil {
+	// the item may be a blocked delete retried after a provider network with the same name is recreated,
+	// the bridge and nic now belong to the new provider network and must not be cleaned up
+	newPn, err := c.providerNetworksLister.Get(pn.Name)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		klog.Errorf("failed to get provider network %s: %v", pn.Name, err)
+		return err
+	}
+	if err == nil && newPn.UID != pn.UID {
+		klog.Infof("provider network %s has been recreated with uid %s, skip cleaning up the deleted one with uid %s", pn.Name, newPn.UID, pn.UID)
+		return nil
+	}
+
+	subnets, err := c.providerNetworkSubnets(pn)
+	if err != nil {
+		klog.Errorf("failed to list subnets of provider network %s: %v", pn.Name, err)
+		return err
+	}
+	if len(subnets) != 0 {
+		node, err := c.nodesLister.Get(c.config.NodeName)
+		if err != nil {
+			klog.Errorf("failed to get node %s: %v", c.config.NodeName, err)
+			return err
+		}
+		names := strings.Join(subnets, ",")
+		if !c.config.ForceProviderCleanup {
+			klog.Warningf("provider network %s is still referenced by subnets %s, skip cleaning it up", pn.Name, names)
+			c.recorder.Eventf(node, v1.EventTypeWarning, "ProviderNetworkCleanupBlocked", "provider network %s is still referenced by subnets %s", pn.Name, names)
+			return fmt.Errorf("provider network %s is still referenced by subnets %s", pn.Name, names)
+		}
+		klog.Warningf("force cleaning up provider network %s referenced by subnets %s", pn.Name, names)
+		c.recorder.Eventf(node, v1.EventTypeWarning, "ProviderNetworkForceCleaned", "provider network %s is cleaned up while still referenced by subnets %s", pn.Name, names)
+	}
+
+	// the addresses and routes of the provider nic are moved back from the bridge by removeProviderNic
+	if err := ovsCleanProviderNetwork(pn.Name, util.ProviderNetworkBridgeName(pn), c.config.PreservedHostRoutes); err != nil {
 		return err
 	}
 
@@ -413,6 +502,27 @@ func (c *Controller) handleDeleteProviderNetwork(pn *kubeovnv1.ProviderNetwork)
 	return nil
 }
 
+// providerNetworkSubnets returns the sorted names of the subnets on the vlans of the provider network
+func (c *Controller) providerNetworkSubnets(pn *kubeovnv1.ProviderNetwork) ([]string, error) {
+	if len(pn.Status.Vlans) == 0 {
+		return nil, nil
+	}
+
+	subnets, err := c.subnetsLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, subnet := range subnets {
+		if subnet.Spec.Vlan != "" && util.ContainsString(pn.Status.Vlans, subnet.Spec.Vlan) {
+			names = append(names, subnet.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 type subnetEvent struct {
 	old, new interface{}
 }
@@ -423,6 +533,41 @@ func (c *Controller) enqueueAddSubnet(obj interface{}) {
 
 func (c *Controller) enqueueUpdateSubnet(old, new interface{}) {
 	c.subnetQueue.Add(subnetEvent{old: old, new: new})
+
+	oldSubnet := old.(*kubeovnv1.Subnet)
+	newSubnet := new.(*kubeovnv1.Subnet)
+	if oldSubnet.Spec.DefaultIngressRate != newSubnet.Spec.DefaultIngressRate ||
+		oldSubnet.Spec.DefaultEgressRate != newSubnet.Spec.DefaultEgressRate ||
+		oldSubnet.Spec.QosType != newSubnet.Spec.QosType {
+		c.enqueueSubnetPods(newSubnet)
+	}
+}
+
+// enqueueSubnetPods requeues the local pods in the subnet to reconcile their inherited rates and qos type
+func (c *Controller) enqueueSubnetPods(subnet *kubeovnv1.Subnet) {
+	pods, err := c.podsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list pods, %v", err)
+		return
+	}
+	for _, pod := range pods {
+		if pod.Spec.HostNetwork || pod.DeletionTimestamp != nil {
+			continue
+		}
+		inSubnet := pod.Annotations[util.LogicalSwitchAnnotation] == subnet.Name
+		if !inSubnet && subnet.Spec.Provider != "" && subnet.Spec.Provider != util.OvnProvider {
+			inSubnet = pod.Annotations[fmt.Sprintf(util.LogicalSwitchAnnotationTemplate, subnet.Spec.Provider)] == subnet.Name
+		}
+		if !inSubnet {
+			continue
+		}
+		key, err := cache.MetaNamespaceKeyFunc(pod)
+		if err != nil {
+			utilruntime.HandleError(err)
+			continue
+		}
+		c.podQueue.Add(key)
+	}
 }
 
 func (c *Controller) enqueueDeleteSubnet(obj interface{}) {
@@ -463,17 +608,43 @@ func (c *Controller) processNextSubnetWorkItem() bool {
 	return true
 }
 
+// enqueueAddPod enqueues the pods waiting for the network ready condition, which are listed again on daemon restarts
+func (c *Controller) enqueueAddPod(obj interface{}) {
+	if !c.podNetworkReadyPending(obj.(*v1.Pod)) {
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.podQueue.Add(key)
+}
+
 func (c *Controller) enqueuePod(old, new interface{}) {
 	oldPod := old.(*v1.Pod)
 	newPod := new.(*v1.Pod)
 
+	if c.podNetworkReadyPending(newPod) {
+		key, err := cache.MetaNamespaceKeyFunc(new)
+		if err != nil {
+			utilruntime.HandleError(err)
+			return
+		}
+		c.podQueue.Add(key)
+	}
+
 	if oldPod.Annotations[util.IngressRateAnnotation] != newPod.Annotations[util.IngressRateAnnotation] ||
 		oldPod.Annotations[util.EgressRateAnnotation] != newPod.Annotations[util.EgressRateAnnotation] ||
 		oldPod.Annotations[util.PriorityAnnotation] != newPod.Annotations[util.PriorityAnnotation] ||
+		oldPod.Annotations[util.QosTypeAnnotation] != newPod.Annotations[util.QosTypeAnnotation] ||
+		oldPod.Annotations[util.MinRateAnnotation] != newPod.Annotations[util.MinRateAnnotation] ||
+		oldPod.Annotations[util.EgressRateModeAnnotation] != newPod.Annotations[util.EgressRateModeAnnotation] ||
 		oldPod.Annotations[util.NetemQosLatencyAnnotation] != newPod.Annotations[util.NetemQosLatencyAnnotation] ||
 		oldPod.Annotations[util.NetemQosLimitAnnotation] != newPod.Annotations[util.NetemQosLimitAnnotation] ||
 		oldPod.Annotations[util.NetemQosLossAnnotation] != newPod.Annotations[util.NetemQosLossAnnotation] ||
-		oldPod.Annotations[util.MirrorControlAnnotation] != newPod.Annotations[util.MirrorControlAnnotation] {
+		oldPod.Annotations[util.MirrorControlAnnotation] != newPod.Annotations[util.MirrorControlAnnotation] ||
+		oldPod.Annotations[util.SubnetMigrationAnnotation] != newPod.Annotations[util.SubnetMigrationAnnotation] {
 		var key string
 		var err error
 		if key, err = cache.MetaNamespaceKeyFunc(new); err != nil {
@@ -493,6 +664,9 @@ func (c *Controller) enqueuePod(old, new interface{}) {
 			if oldPod.Annotations[fmt.Sprintf(util.IngressRateAnnotationTemplate, provider)] != newPod.Annotations[fmt.Sprintf(util.IngressRateAnnotationTemplate, provider)] ||
 				oldPod.Annotations[fmt.Sprintf(util.EgressRateAnnotationTemplate, provider)] != newPod.Annotations[fmt.Sprintf(util.EgressRateAnnotationTemplate, provider)] ||
 				oldPod.Annotations[fmt.Sprintf(util.PriorityAnnotationTemplate, provider)] != newPod.Annotations[fmt.Sprintf(util.PriorityAnnotationTemplate, provider)] ||
+				oldPod.Annotations[fmt.Sprintf(util.QosTypeAnnotationTemplate, provider)] != newPod.Annotations[fmt.Sprintf(util.QosTypeAnnotationTemplate, provider)] ||
+				oldPod.Annotations[fmt.Sprintf(util.MinRateAnnotationTemplate, provider)] != newPod.Annotations[fmt.Sprintf(util.MinRateAnnotationTemplate, provider)] ||
+				oldPod.Annotations[fmt.Sprintf(util.EgressRateModeAnnotationTemplate, provider)] != newPod.Annotations[fmt.Sprintf(util.EgressRateModeAnnotationTemplate, provider)] ||
 				oldPod.Annotations[fmt.Sprintf(util.NetemQosLatencyAnnotationTemplate, provider)] != newPod.Annotations[fmt.Sprintf(util.NetemQosLatencyAnnotationTemplate, provider)] ||
 				oldPod.Annotations[fmt.Sprintf(util.NetemQosLimitAnnotationTemplate, provider)] != newPod.Annotations[fmt.Sprintf(util.NetemQosLimitAnnotationTemplate, provider)] ||
 				oldPod.Annotations[fmt.Sprintf(util.NetemQosLossAnnotationTemplate, provider)] != newPod.Annotations[fmt.Sprintf(util.NetemQosLossAnnotationTemplate, provider)] ||
@@ -509,6 +683,89 @@ func (c *Controller) enqueuePod(old, new interface{}) {
 	}
 }
 
+// podNetworkReadyPending returns whether the pod declares the network ready gate whose condition is not true yet.
+// The pod ip is set by kubelet after the cni add succeeds, including the gateway check if it's enabled
+func (c *Controller) podNetworkReadyPending(pod *v1.Pod) bool {
+	if !c.config.EnableNetworkReadyGate || pod.Spec.HostNetwork || pod.DeletionTimestamp != nil || pod.Status.PodIP == "" ||
+		pod.Annotations[util.AllocatedAnnotation] != "true" {
+		return false
+	}
+	var hasGate bool
+	for _, gate := range pod.Spec.ReadinessGates {
+		if gate.ConditionType == util.NetworkReadyConditionType {
+			hasGate = true
+			break
+		}
+	}
+	if !hasGate {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == util.NetworkReadyConditionType {
+			return cond.Status != v1.ConditionTrue
+		}
+	}
+	return true
+}
+
+// syncPodNetworkReady sets the network ready condition of the pod to true after the port is bound by ovn-controller,
+// the pod is requeued until then
+func (c *Controller) syncPodNetworkReady(pod *v1.Pod) error {
+	if !c.podNetworkReadyPending(pod) {
+		return nil
+	}
+
+	podName := pod.Name
+	if pod.Annotations[fmt.Sprintf(util.VmTemplate, util.OvnProvider)] != "" {
+		podName = pod.Annotations[fmt.Sprintf(util.VmTemplate, util.OvnProvider)]
+	}
+	ifaceID := ovs.PodNameToPortName(podName, pod.Namespace, util.OvnProvider)
+	installed, err := ovs.InterfaceOvnInstalled(ifaceID)
+	if err != nil {
+		klog.Errorf("failed to check whether port %s is bound: %v", ifaceID, err)
+		return err
+	}
+	if !installed {
+		return fmt.Errorf("port %s of pod %s/%s is not bound yet", ifaceID, pod.Namespace, pod.Name)
+	}
+
+	// the gateway is not checked for live migrating pods and the pods with the check disabled
+	reason := "GatewayReachable"
+	mode := pod.Annotations[fmt.Sprintf(util.ResolvedGatewayCheckModeAnnotationTemplate, util.OvnProvider)]
+	if mode == "" {
+		if subnet, err := c.subnetsLister.Get(pod.Annotations[util.LogicalSwitchAnnotation]); err == nil && subnet.Spec.DisableGatewayCheck {
+			mode = kubeovnv1.GatewayCheckModeDisabled
+		}
+	}
+	if mode == kubeovnv1.GatewayCheckModeDisabled || pod.Annotations[fmt.Sprintf(util.LiveMigrationAnnotationTemplate, util.OvnProvider)] == "true" {
+		reason = "PortBound"
+	}
+	patch := map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []v1.PodCondition{{
+				Type:               util.NetworkReadyConditionType,
+				Status:             v1.ConditionTrue,
+				Reason:             reason,
+				LastTransitionTime: metav1.Now(),
+			}},
+		},
+	}
+	bytes, err := json.Marshal(patch)
+	if err != nil {
+		klog.Errorf("failed to marshal network ready condition patch: %v", err)
+		return err
+	}
+	if _, err = c.config.KubeClient.CoreV1().Pods(pod.Namespace).Patch(context.Background(), pod.Name, types.StrategicMergePatchType, bytes, metav1.PatchOptions{}, "status"); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		klog.Errorf("failed to set network ready condition of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		return err
+	}
+	klog.Infof("network of pod %s/%s is ready", pod.Namespace, pod.Name)
+	return nil
+}
+
 func (c *Controller) runPodWorker() {
 	for c.processNextPodWorkItem() {
 	}
@@ -644,6 +901,45 @@ func (c *Controller) getSubnetQosPriority(subnetName string) string {
 	return priority
 }
 
+// getSubnetDefaultRates returns the rate limits inherited by pods without rate annotations
+func (c *Controller) getSubnetDefaultRates(subnetName string) (ingress, egress string) {
+	subnet, err := c.subnetsLister.Get(subnetName)
+	if err != nil {
+		klog.Errorf("failed to get subnet %s: %v", subnetName, err)
+		return "", ""
+	}
+	return subnet.Spec.DefaultIngressRate, subnet.Spec.DefaultEgressRate
+}
+
+// podRates returns the ingress/egress rates of the pod nic, falling back to the subnet defaults
+func (c *Controller) podRates(pod *v1.Pod, ingressKey, egressKey, subnetName string) (ingress, egress string) {
+	ingress, egress = pod.Annotations[ingressKey], pod.Annotations[egressKey]
+	if ingress != "" && egress != "" {
+		return
+	}
+	defaultIngress, defaultEgress := c.getSubnetDefaultRates(subnetName)
+	if ingress == "" {
+		ingress = defaultIngress
+	}
+	if egress == "" {
+		egress = defaultEgress
+	}
+	return
+}
+
+// podQosType returns the ovs qos type of the pod nic, falling back to the qos type of the subnet
+func (c *Controller) podQosType(pod *v1.Pod, qosTypeKey, subnetName string) string {
+	if qosType := pod.Annotations[qosTypeKey]; qosType != "" {
+		return qosType
+	}
+	subnet, err := c.subnetsLister.Get(subnetName)
+	if err != nil {
+		klog.Errorf("failed to get subnet %s: %v", subnetName, err)
+		return ""
+	}
+	return subnet.Spec.QosType
+}
+
 // Run starts controller
 func (c *Controller) Run(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
@@ -653,9 +949,11 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 	defer c.podQueue.ShutDown()
 
 	go wait.Until(ovs.CleanLostInterface, time.Minute, stopCh)
+	go wait.Until(cleanExpiredNics, time.Minute, stopCh)
 	go wait.Until(recompute, 10*time.Minute, stopCh)
 	go wait.Until(rotateLog, 1*time.Hour, stopCh)
 	go wait.Until(c.operateMod, 10*time.Second, stopCh)
+	go wait.Until(c.syncSflow, 10*time.Second, stopCh)
 
 	if ok := cache.WaitForCacheSync(stopCh, c.providerNetworksSynced, c.subnetsSynced, c.podsSynced, c.nodesSynced, c.htbQosSynced); !ok {
 		util.LogFatalAndExit(nil, "failed to wait for caches to sync")
@@ -673,6 +971,12 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 	go wait.Until(c.runPodWorker, time.Second, stopCh)
 	go wait.Until(c.runGateway, 3*time.Second, stopCh)
 	go wait.Until(c.loopEncapIpCheck, 3*time.Second, stopCh)
+	go wait.Until(c.syncDpdkPmdCores, time.Minute, stopCh)
+	go wait.Until(c.syncTunnelBond, 10*time.Second, stopCh)
+	go wait.Until(c.probeICPathMtu, icPathMtuProbeInterval, stopCh)
+	if c.config.EnableInternodeProbe {
+		go wait.Until(c.probeInternodeRtt, c.config.InternodeProbeInterval, stopCh)
+	}
 	go wait.Until(func() {
 		if err := c.markAndCleanInternalPort(); err != nil {
 			klog.Errorf("gc ovs port error: %v", err)
@@ -683,6 +987,101 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 	klog.Info("Shutting down workers")
 }
 
+// syncDpdkPmdCores exposes the pmd cores of ovs-dpdk on userspace datapath nodes
+func (c *Controller) syncDpdkPmdCores() {
+	node, err := c.nodesLister.Get(c.config.NodeName)
+	if err != nil {
+		klog.Errorf("failed to get node %s: %v", c.config.NodeName, err)
+		return
+	}
+	if node.Labels[util.OvsDpTypeLabel] != "userspace" {
+		return
+	}
+
+	dpdkConfig, err := ovs.GetDpdkConfig()
+	if err != nil {
+		klog.Errorf("failed to get dpdk config of ovs: %v", err)
+		return
+	}
+	klog.V(3).Infof("ovs-dpdk dpdk-socket-mem %q, pmd-cpu-mask %q, %d pmd cores", dpdkConfig.SocketMem, dpdkConfig.PmdCPUMask, dpdkConfig.PmdCores)
+	dpdkPmdCores.WithLabelValues(c.config.NodeName).Set(float64(dpdkConfig.PmdCores))
+}
+
+// syncTunnelBond exposes the slaves of the tunnel interface if it's a bond and
+// records an event on the node when the bond becomes degraded or recovers
+func (c *Controller) syncTunnelBond() {
+	name := c.config.tunnelIface
+	if name == "" {
+		return
+	}
+	status, err := getBondStatus(name)
+	if err != nil {
+		klog.Errorf("failed to get status of tunnel interface %s: %v", name, err)
+		return
+	}
+	if status == nil {
+		return
+	}
+
+	tunnelBondSlaves.WithLabelValues(c.config.NodeName, name).Set(float64(status.Slaves))
+	tunnelBondActiveSlaves.WithLabelValues(c.config.NodeName, name).Set(float64(status.ActiveSlaves))
+
+	degraded := status.ActiveSlaves < status.Slaves
+	if degraded == c.tunnelBondDegraded {
+		return
+	}
+	node, err := c.nodesLister.Get(c.config.NodeName)
+	if err != nil {
+		klog.Errorf("failed to get node %s: %v", c.config.NodeName, err)
+		return
+	}
+	c.tunnelBondDegraded = degraded
+	if degraded {
+		klog.Warningf("tunnel bond %s is degraded, %d of %d slaves are active", name, status.ActiveSlaves, status.Slaves)
+		c.recorder.Eventf(node, v1.EventTypeWarning, "TunnelBondDegraded", "%d of %d slaves of tunnel bond %s are active", status.ActiveSlaves, status.Slaves, name)
+	} else {
+		klog.Infof("tunnel bond %s recovered, all %d slaves are active", name, status.Slaves)
+		c.recorder.Eventf(node, v1.EventTypeNormal, "TunnelBondRecovered", "all %d slaves of tunnel bond %s are active", status.Slaves, name)
+	}
+}
+
+// syncSflow reconciles the sFlow export of br-int with the configuration,
+// the sFlow created by kube-ovn is removed when it's disabled
+func (c *Controller) syncSflow() {
+	current, err := ovs.GetBridgeSflow("br-int")
+	if err != nil {
+		klog.Errorf("failed to get sflow of br-int: %v", err)
+		return
+	}
+
+	if !c.config.EnableSflow {
+		if current != nil && current.Vendor == util.CniTypeName {
+			klog.Info("sflow is disabled, remove sflow of br-int")
+			if err = ovs.ClearBridgeSflow("br-int"); err != nil {
+				klog.Errorf("failed to remove sflow of br-int: %v", err)
+				return
+			}
+			current = nil
+		}
+		if current != nil {
+			sflowActive.WithLabelValues(c.config.NodeName).Set(1)
+		} else {
+			sflowActive.WithLabelValues(c.config.NodeName).Set(0)
+		}
+		return
+	}
+
+	if current == nil || current.Target != c.config.SflowTarget || current.Sampling != c.config.SflowSampling {
+		klog.Infof("export sflow of br-int to %s with sampling rate %d", c.config.SflowTarget, c.config.SflowSampling)
+		if err = ovs.SetBridgeSflow("br-int", c.config.SflowTarget, c.config.SflowSampling); err != nil {
+			klog.Errorf("failed to set sflow of br-int: %v", err)
+			sflowActive.WithLabelValues(c.config.NodeName).Set(0)
+			return
+		}
+	}
+	sflowActive.WithLabelValues(c.config.NodeName).Set(1)
+}
+
 func recompute() {
 	output, err := exec.Command("ovn-appctl", "-t", "ovn-controller", "inc-engine/recompute").CombinedOutput()
 	if err != nil {
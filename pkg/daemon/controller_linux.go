@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -18,7 +19,9 @@ import (
 	"github.com/vishvananda/netlink"
 	v1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
@@ -210,7 +213,7 @@ func getNicExistRoutes(nic netlink.Link, gateway string) ([]netlink.Route, error
 
 func routeDiff(existRoutes []netlink.Route, cidrs []string) (toAdd []string, toDel []string) {
 	for _, route := range existRoutes {
-		if route.Scope == netlink.SCOPE_LINK {
+		if route.Scope == netlink.SCOPE_LINK || route.Protocol == nodeNicRouteProtocol {
 			continue
 		}
 
@@ -232,7 +235,7 @@ func routeDiff(existRoutes []netlink.Route, cidrs []string) (toAdd []string, toD
 	for _, c := range cidrs {
 		found := false
 		for _, r := range existRoutes {
-			if r.Dst.String() == c {
+			if r.Protocol != nodeNicRouteProtocol && r.Dst.String() == c {
 				found = true
 				break
 			}
@@ -404,11 +407,28 @@ func (c *Controller) handlePod(key string) error {
 		return err
 	}
 
+	if err = c.syncPodNetworkReady(pod); err != nil {
+		return err
+	}
+
 	if err := util.ValidatePodNetwork(pod.Annotations); err != nil {
 		klog.Errorf("validate pod %s/%s failed, %v", namespace, name, err)
 		c.recorder.Eventf(pod, v1.EventTypeWarning, "ValidatePodNetworkFailed", err.Error())
 		return err
 	}
+	if err := util.ValidatePodBandwidth(pod.Annotations, c.config.MaxPodBandwidth); err != nil {
+		klog.Errorf("validate bandwidth of pod %s/%s failed, %v", namespace, name, err)
+		c.recorder.Eventf(pod, v1.EventTypeWarning, "ValidatePodBandwidthFailed", err.Error())
+		return err
+	}
+
+	if pod.Annotations[util.SubnetMigrationAnnotation] == util.SubnetMigrationReconfiguring {
+		if err = c.reconfigurePodNic(pod); err != nil {
+			klog.Errorf("failed to reconfigure nic of pod %s/%s: %v", namespace, name, err)
+			c.recorder.Eventf(pod, v1.EventTypeWarning, "SubnetMigrationFailed", "failed to reconfigure nic: %v", err)
+			return err
+		}
+	}
 
 	podName := pod.Name
 	if pod.Annotations[fmt.Sprintf(util.VmTemplate, util.OvnProvider)] != "" {
@@ -424,7 +444,12 @@ func (c *Controller) handlePod(key string) error {
 
 	// set default nic bandwidth
 	ifaceID := ovs.PodNameToPortName(podName, pod.Namespace, util.OvnProvider)
-	err = ovs.SetInterfaceBandwidth(podName, pod.Namespace, ifaceID, pod.Annotations[util.EgressRateAnnotation], pod.Annotations[util.IngressRateAnnotation], priority)
+	ingress, egress := c.podRates(pod, util.IngressRateAnnotation, util.EgressRateAnnotation, subnetName)
+	qosType := c.podQosType(pod, util.QosTypeAnnotation, subnetName)
+	if err = setDedicatedQueue(podName, pod.Namespace, ifaceID, pod.Annotations[util.MinRateAnnotation], ingress, qosType, c.config.tunnelIface); err != nil {
+		return err
+	}
+	err = setPodBandwidth(podName, pod.Namespace, ifaceID, ingress, egress, priority, qosType, pod.Annotations[util.EgressRateModeAnnotation])
 	if err != nil {
 		return err
 	}
@@ -457,7 +482,12 @@ func (c *Controller) handlePod(key string) error {
 				priority = subnetPriority
 			}
 
-			err = ovs.SetInterfaceBandwidth(podName, pod.Namespace, ifaceID, pod.Annotations[fmt.Sprintf(util.EgressRateAnnotationTemplate, provider)], pod.Annotations[fmt.Sprintf(util.IngressRateAnnotationTemplate, provider)], priority)
+			ingress, egress := c.podRates(pod, fmt.Sprintf(util.IngressRateAnnotationTemplate, provider), fmt.Sprintf(util.EgressRateAnnotationTemplate, provider), subnetName)
+			qosType := c.podQosType(pod, fmt.Sprintf(util.QosTypeAnnotationTemplate, provider), subnetName)
+			if err = setDedicatedQueue(podName, pod.Namespace, ifaceID, pod.Annotations[fmt.Sprintf(util.MinRateAnnotationTemplate, provider)], ingress, qosType, c.config.tunnelIface); err != nil {
+				return err
+			}
+			err = setPodBandwidth(podName, pod.Namespace, ifaceID, ingress, egress, priority, qosType, pod.Annotations[fmt.Sprintf(util.EgressRateModeAnnotationTemplate, provider)])
 			if err != nil {
 				return err
 			}
@@ -474,6 +504,54 @@ func (c *Controller) handlePod(key string) error {
 	return nil
 }
 
+// reconfigurePodNic applies the address allocated in the new subnet to the primary nic of the pod migrated
+// between subnets, and removes the migration annotation to signal the completion
+func (c *Controller) reconfigurePodNic(pod *v1.Pod) error {
+	ifaceID := ovs.PodNameToPortName(pod.Name, pod.Namespace, util.OvnProvider)
+	ifaces, err := ovs.GetInterfacesByIfaceID(ifaceID)
+	if err != nil {
+		klog.Errorf("failed to get ovs interfaces of %s: %v", ifaceID, err)
+		return err
+	}
+	if len(ifaces) == 0 {
+		return fmt.Errorf("no ovs interface found for %s", ifaceID)
+	}
+	netns, err := ovs.GetInterfacePodNetns(ifaces[0])
+	if err != nil {
+		return err
+	}
+	if netns == "" {
+		return fmt.Errorf("no pod netns recorded in ovs interface %s", ifaces[0])
+	}
+
+	ip := pod.Annotations[util.IpAddressAnnotation]
+	mac, err := net.ParseMAC(pod.Annotations[util.MacAddressAnnotation])
+	if err != nil {
+		return fmt.Errorf("failed to parse mac %s: %v", pod.Annotations[util.MacAddressAnnotation], err)
+	}
+	ipAddr := util.GetIpAddrWithMask(ip, pod.Annotations[util.CidrAnnotation])
+	if err = reconfigureContainerNic(netns, mac, ipAddr, pod.Annotations[util.GatewayAnnotation]); err != nil {
+		return err
+	}
+	for _, iface := range ifaces {
+		if err = ovs.SetInterfaceIP(iface, ip); err != nil {
+			return err
+		}
+	}
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, util.SubnetMigrationAnnotation)
+	if _, err = c.config.KubeClient.CoreV1().Pods(pod.Namespace).Patch(context.Background(), pod.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		klog.Errorf("failed to remove annotation %s of pod %s/%s: %v", util.SubnetMigrationAnnotation, pod.Namespace, pod.Name, err)
+		return err
+	}
+	klog.Infof("nic of pod %s/%s is reconfigured with address %s", pod.Namespace, pod.Name, ipAddr)
+	c.recorder.Eventf(pod, v1.EventTypeNormal, "SubnetMigrationCompleted", "nic is reconfigured with address %s", ipAddr)
+	return nil
+}
+
 func (c *Controller) loopEncapIpCheck() {
 	node, err := c.nodesLister.Get(c.config.NodeName)
 	if err != nil {
@@ -481,6 +559,12 @@ func (c *Controller) loopEncapIpCheck() {
 		return
 	}
 
+	if tunnelIP := node.Annotations[util.TunnelIPAnnotation]; tunnelIP != "" {
+		c.checkSelectedEncapIP(node, tunnelIP)
+		return
+	}
+	c.encapIPMismatch = ""
+
 	if nodeTunnelName := node.GetAnnotations()[util.TunnelInterfaceAnnotation]; nodeTunnelName != "" {
 		iface, err := findInterface(nodeTunnelName)
 		if err != nil {
@@ -518,7 +602,37 @@ func (c *Controller) loopEncapIpCheck() {
 			klog.Errorf("failed to set encap ip %s for iface %s", encapIP, c.config.Iface)
 			return
 		}
+		c.config.encapIP = encapIP
+	}
+}
+
+// checkSelectedEncapIP validates the tunnel ip selected by the node annotation against the addresses of the node,
+// and updates the encap ip of the OVS chassis when the selection changes. ovn-controller updates the encap of the
+// chassis in the southbound database, and the tunnels from the other chassises are rebuilt to the new address
+func (c *Controller) checkSelectedEncapIP(node *v1.Node, tunnelIP string) {
+	iface, _, err := getIfaceByIP(tunnelIP)
+	if err != nil {
+		// report the mismatch once and keep the current encap ip until the annotation or the address is fixed
+		if c.encapIPMismatch != tunnelIP {
+			klog.Errorf("tunnel ip %s selected by annotation %s is not found on the node, keep using %s: %v", tunnelIP, util.TunnelIPAnnotation, c.config.encapIP, err)
+			c.recorder.Eventf(node, v1.EventTypeWarning, "TunnelIPMismatch", "tunnel ip %s is not found on the node, keep using %s", tunnelIP, c.config.encapIP)
+			c.encapIPMismatch = tunnelIP
+		}
+		return
+	}
+	c.encapIPMismatch = ""
+
+	if tunnelIP == c.config.encapIP {
+		return
+	}
+	if err = setEncapIP(tunnelIP); err != nil {
+		klog.Errorf("failed to set encap ip %s for iface %s: %v", tunnelIP, iface, err)
+		return
 	}
+	klog.Infof("encap ip is changed from %s to %s of %s", c.config.encapIP, tunnelIP, iface)
+	c.recorder.Eventf(node, v1.EventTypeNormal, "TunnelIPChanged", "encap ip is changed from %s to %s of %s", c.config.encapIP, tunnelIP, iface)
+	c.config.encapIP = tunnelIP
+	c.config.tunnelIface = iface
 }
 
 func (c *Controller) setSubnetQosPriority(subnet *kubeovnv1.Subnet) error {
@@ -599,7 +713,7 @@ func (c *Controller) setSubnetQosPriority(subnet *kubeovnv1.Subnet) error {
 }
 
 func (c *Controller) clearQos(podName, podNamespace, ifaceID string) error {
-	if htbQos, _ := ovs.IsHtbQos(ifaceID); !htbQos {
+	if queueQos, _ := ovs.IsQueueQos(ifaceID); !queueQos {
 		return nil
 	}
 
@@ -173,11 +173,20 @@ func (c *Controller) handlePod(key string) error {
 		return err
 	}
 
+	if err = c.syncPodNetworkReady(pod); err != nil {
+		return err
+	}
+
 	if err := util.ValidatePodNetwork(pod.Annotations); err != nil {
 		klog.Errorf("validate pod %s/%s failed, %v", namespace, name, err)
 		c.recorder.Eventf(pod, v1.EventTypeWarning, "ValidatePodNetworkFailed", err.Error())
 		return err
 	}
+	if err := util.ValidatePodBandwidth(pod.Annotations, c.config.MaxPodBandwidth); err != nil {
+		klog.Errorf("validate bandwidth of pod %s/%s failed, %v", namespace, name, err)
+		c.recorder.Eventf(pod, v1.EventTypeWarning, "ValidatePodBandwidthFailed", err.Error())
+		return err
+	}
 
 	podName := pod.Name
 	if pod.Annotations[fmt.Sprintf(util.VmTemplate, util.OvnProvider)] != "" {
@@ -186,7 +195,8 @@ func (c *Controller) handlePod(key string) error {
 
 	// set default nic bandwidth
 	ifaceID := ovs.PodNameToPortName(podName, pod.Namespace, util.OvnProvider)
-	err = ovs.SetInterfaceBandwidth(podName, pod.Namespace, ifaceID, pod.Annotations[util.EgressRateAnnotation], pod.Annotations[util.IngressRateAnnotation], pod.Annotations[util.PriorityAnnotation])
+	ingress, egress := c.podRates(pod, util.IngressRateAnnotation, util.EgressRateAnnotation, pod.Annotations[util.LogicalSwitchAnnotation])
+	err = ovs.SetInterfaceBandwidth(podName, pod.Namespace, ifaceID, egress, ingress, pod.Annotations[util.PriorityAnnotation], c.podQosType(pod, util.QosTypeAnnotation, pod.Annotations[util.LogicalSwitchAnnotation]))
 	if err != nil {
 		return err
 	}
@@ -207,7 +217,8 @@ func (c *Controller) handlePod(key string) error {
 		}
 		if pod.Annotations[fmt.Sprintf(util.AllocatedAnnotationTemplate, provider)] == "true" {
 			ifaceID = ovs.PodNameToPortName(podName, pod.Namespace, provider)
-			err = ovs.SetInterfaceBandwidth(podName, pod.Namespace, ifaceID, pod.Annotations[fmt.Sprintf(util.EgressRateAnnotationTemplate, provider)], pod.Annotations[fmt.Sprintf(util.IngressRateAnnotationTemplate, provider)], pod.Annotations[fmt.Sprintf(util.PriorityAnnotationTemplate, provider)])
+			ingress, egress := c.podRates(pod, fmt.Sprintf(util.IngressRateAnnotationTemplate, provider), fmt.Sprintf(util.EgressRateAnnotationTemplate, provider), pod.Annotations[fmt.Sprintf(util.LogicalSwitchAnnotationTemplate, provider)])
+			err = ovs.SetInterfaceBandwidth(podName, pod.Namespace, ifaceID, egress, ingress, pod.Annotations[fmt.Sprintf(util.PriorityAnnotationTemplate, provider)], c.podQosType(pod, fmt.Sprintf(util.QosTypeAnnotationTemplate, provider), pod.Annotations[fmt.Sprintf(util.LogicalSwitchAnnotationTemplate, provider)]))
 			if err != nil {
 				return err
 			}
@@ -1,13 +1,18 @@
 package daemon
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
 
 	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
@@ -15,6 +20,11 @@ import (
 	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
+const (
+	icPathMtuProbeInterval = 5 * time.Minute
+	icPathMtuProbeTimeout  = time.Second
+)
+
 func (c *Controller) runGateway() {
 	if err := c.setIPSet(); err != nil {
 		klog.Errorf("failed to set gw ipsets")
@@ -48,11 +58,11 @@ func (c *Controller) setGatewayBandwidth() error {
 	ingress, egress, priority := node.Annotations[util.IngressRateAnnotation], node.Annotations[util.EgressRateAnnotation], node.Annotations[util.PriorityAnnotation]
 	ifaceId := fmt.Sprintf("node-%s", c.config.NodeName)
 	if ingress == "" && egress == "" && priority == "" {
-		if htbQos, _ := ovs.IsHtbQos(ifaceId); !htbQos {
+		if queueQos, _ := ovs.IsQueueQos(ifaceId); !queueQos {
 			return nil
 		}
 	}
-	return ovs.SetInterfaceBandwidth("", "", ifaceId, egress, ingress, priority)
+	return ovs.SetInterfaceBandwidth("", "", ifaceId, egress, ingress, priority, "")
 }
 
 func (c *Controller) setICGateway() error {
@@ -84,6 +94,78 @@ func (c *Controller) setICGateway() error {
 	return nil
 }
 
+// probeICPathMtu probes the path mtu to the tunnel ips of the chassises of other clusters on the ovn-ic gateway
+// nodes, and records the mtu left for the cross-cluster traffic in the node annotation, which kube-ovn-controller
+// clamps the ovn-ic traffic to if ic-mtu-probe is enabled. It runs in its own loop as a probe may take seconds
+func (c *Controller) probeICPathMtu() {
+	node, err := c.nodesLister.Get(c.config.NodeName)
+	if err != nil {
+		klog.Errorf("failed to get node %s, %v", c.config.NodeName, err)
+		return
+	}
+
+	var value string
+	if node.Labels[util.ICGatewayLabel] == "true" {
+		mtu, err := c.probeRemoteChassisMtu()
+		if err != nil {
+			klog.Errorf("failed to probe ovn-ic path mtu, %v", err)
+			return
+		}
+		if mtu != 0 {
+			value = strconv.Itoa(mtu)
+		}
+	}
+	if node.Annotations[util.ICPathMtuAnnotation] == value {
+		return
+	}
+
+	var patch []byte
+	if value == "" {
+		patch = []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, util.ICPathMtuAnnotation))
+	} else {
+		klog.Infof("ovn-ic path mtu of node %s is %s", node.Name, value)
+		patch = []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, util.ICPathMtuAnnotation, value))
+	}
+	if _, err = c.config.KubeClient.CoreV1().Nodes().Patch(context.Background(), node.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		klog.Errorf("failed to patch node %s: %v", node.Name, err)
+	}
+}
+
+// probeRemoteChassisMtu returns the minimum path mtu to the tunnel ips of the chassises of other clusters minus the
+// geneve overhead, or 0 if no remote chassis is reachable. The chassises of the local nodes are skipped
+func (c *Controller) probeRemoteChassisMtu() (int, error) {
+	tunnels, err := ovs.ListTunnelRemoteIPs()
+	if err != nil {
+		return 0, err
+	}
+	nodes, err := c.nodesLister.List(labels.Everything())
+	if err != nil {
+		return 0, err
+	}
+	localChassises := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		if chassis := node.Annotations[util.ChassisAnnotation]; chassis != "" {
+			localChassises[chassis] = true
+		}
+	}
+
+	var mtu int
+	for chassis, ip := range tunnels {
+		if localChassises[chassis] {
+			continue
+		}
+		pathMtu, err := probePathMtu(ip, icPathMtuProbeTimeout)
+		if err != nil {
+			klog.Warningf("failed to probe path mtu to %s of chassis %s, %v", ip, chassis, err)
+			continue
+		}
+		if mtu == 0 || pathMtu-util.GeneveHeaderLength < mtu {
+			mtu = pathMtu - util.GeneveHeaderLength
+		}
+	}
+	return mtu, nil
+}
+
 func (c *Controller) getSubnetsNeedNAT(protocol string) ([]string, error) {
 	var subnetsNeedNat []string
 	subnets, err := c.subnetsLister.List(labels.Everything())
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net"
 	"os"
 	"reflect"
@@ -408,6 +409,7 @@ func (c *Controller) setIptables() error {
 		return err
 	}
 	klog.V(3).Infof("centralized subnets nat ips %v", centralGwNatIPs)
+	c.checkSnatPortCapacity()
 
 	var (
 		v4AbandonedRules = []util.IPTableRule{
@@ -561,6 +563,13 @@ func (c *Controller) setIptables() error {
 			}
 		}
 
+		if c.config.HostPodMasquerade {
+			// nat traffic from the host network to pods, the rule is removed from the chain once disabled.
+			// forwarded traffic like pod to pod does not match the local source address type
+			rule := fmt.Sprintf("-o ovn0 -m addrtype --src-type LOCAL -m set ! --match-set %s src -m set --match-set %s dst -j MASQUERADE", matchset, matchset)
+			iptablesRules = append([]util.IPTableRule{{Table: NAT, Chain: OvnPostrouting, Rule: strings.Fields(rule)}}, iptablesRules...)
+		}
+
 		var natPreroutingRules, natPostroutingRules []util.IPTableRule
 		for _, rule := range iptablesRules {
 			if rule.Table == NAT {
@@ -594,8 +603,12 @@ func (c *Controller) setIptables() error {
 			}
 			// insert the rule before the one for nat outgoing
 			n := len(natPostroutingRules)
-			natPostroutingRules = append(natPostroutingRules[:n-1], rule, natPostroutingRules[n-1])
+			natOutgoing := natPostroutingRules[n-1]
+			natPostroutingRules = append(append(natPostroutingRules[:n-1], c.snatPortRules(rule)...), natOutgoing)
 		}
+		// nat outgoing
+		n := len(natPostroutingRules)
+		natPostroutingRules = append(natPostroutingRules[:n-1], c.snatPortRules(natPostroutingRules[n-1])...)
 
 		if err = c.updateIptablesChain(protocol, NAT, OvnPrerouting, Prerouting, natPreroutingRules); err != nil {
 			klog.Errorf("failed to update chain %s/%s: %v", NAT, OvnPrerouting)
@@ -915,6 +928,116 @@ func (c *Controller) deleteLegacySnatRules(protocol, table, chain string) error
 	return nil
 }
 
+// snatPortRules expands the snat rule to the rules of tcp and udp allocating the source ports from the segments of
+// the snat port range. For each new connection, a segment is chosen randomly in proportion to its size. The original
+// rule is kept at last for the other protocols.
+func (c *Controller) snatPortRules(rule util.IPTableRule) []util.IPTableRule {
+	segments := c.config.SnatPortSegments
+	if len(segments) == 0 {
+		return []util.IPTableRule{rule}
+	}
+
+	var remaining int
+	for _, segment := range segments {
+		remaining += segment.Size()
+	}
+	var probabilities []string
+	for _, segment := range segments {
+		// iptables keeps the probability in 31 bits, round it in the same way to match the listed rules
+		p := math.Round(float64(segment.Size())/float64(remaining)*0x80000000) / 0x80000000
+		probabilities = append(probabilities, fmt.Sprintf("%.11f", p))
+		remaining -= segment.Size()
+	}
+
+	// the protocol is listed by iptables before the other matches
+	i := 0
+	for i < len(rule.Rule) && rule.Rule[i] != "-m" && rule.Rule[i] != "-j" {
+		i++
+	}
+	j := i
+	for j < len(rule.Rule) && rule.Rule[j] != "-j" {
+		j++
+	}
+
+	var rules []util.IPTableRule
+	for _, protocol := range [...]string{"tcp", "udp"} {
+		for k, segment := range segments {
+			spec := append([]string{}, rule.Rule[:i]...)
+			spec = append(spec, "-p", protocol)
+			spec = append(spec, rule.Rule[i:j]...)
+			if k != len(segments)-1 {
+				spec = append(spec, "-m", "statistic", "--mode", "random", "--probability", probabilities[k])
+			}
+			target := append([]string{}, rule.Rule[j:]...)
+			switch {
+			case len(target) == 2 && target[1] == "MASQUERADE":
+				target = append(target, "--to-ports", segment.String())
+			case len(target) == 4 && target[1] == "SNAT":
+				ip := target[3]
+				if util.CheckProtocol(ip) == kubeovnv1.ProtocolIPv6 {
+					ip = fmt.Sprintf("[%s]", ip)
+				}
+				target[3] = fmt.Sprintf("%s:%s", ip, segment.String())
+			}
+			rules = append(rules, util.IPTableRule{Table: rule.Table, Chain: rule.Chain, Rule: append(spec, target...)})
+		}
+	}
+	return append(rules, rule)
+}
+
+// snatPortsPerPod is the number of snat ports expected for the concurrent connections of an egressing pod
+const snatPortsPerPod = 64
+
+// checkSnatPortCapacity warns if the snat port range is too small for the pods masqueraded by the node
+func (c *Controller) checkSnatPortCapacity() {
+	if len(c.config.SnatPortSegments) == 0 {
+		return
+	}
+	subnets, err := c.subnetsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list subnets, %v", err)
+		return
+	}
+	pods, err := c.podsLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list pods, %v", err)
+		return
+	}
+
+	var egressPods int
+	for _, subnet := range subnets {
+		if !subnet.Spec.NatOutgoing || subnet.Spec.Vpc != util.DefaultVpc || (subnet.Spec.Vlan != "" && !subnet.Spec.LogicalGateway) {
+			continue
+		}
+		if subnet.Spec.GatewayType == kubeovnv1.GWCentralizedType {
+			// the pods of the subnet on all nodes may egress through the gateway node
+			if util.GatewayContains(subnet.Spec.GatewayNode, c.config.NodeName) {
+				egressPods += int(math.Max(subnet.Status.V4UsingIPs, subnet.Status.V6UsingIPs))
+			}
+			continue
+		}
+		for _, pod := range pods {
+			if !pod.Spec.HostNetwork && pod.Annotations[util.LogicalSwitchAnnotation] == subnet.Name {
+				egressPods++
+			}
+		}
+	}
+
+	var size int
+	for _, segment := range c.config.SnatPortSegments {
+		size += segment.Size()
+	}
+	if egressPods*snatPortsPerPod <= size {
+		c.snatPortsShortPods = 0
+		return
+	}
+	if c.snatPortsShortPods != egressPods {
+		klog.Warningf("snat port range %s excluding %q has %d ports, which may be exhausted by the concurrent connections of %d egressing pods, at least %d ports are recommended",
+			c.config.SnatPortRange, c.config.SnatReservedPorts, size, egressPods, egressPods*snatPortsPerPod)
+		c.snatPortsShortPods = egressPods
+	}
+}
+
 func ipsetExists(name string) (bool, error) {
 	sets, err := k8sipset.New(k8sexec.New()).ListSets()
 	if err != nil {
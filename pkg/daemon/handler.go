@@ -16,6 +16,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
 	clientset "github.com/kubeovn/kube-ovn/pkg/client/clientset/versioned"
 	"github.com/kubeovn/kube-ovn/pkg/ovs"
 	"github.com/kubeovn/kube-ovn/pkg/request"
@@ -26,6 +27,7 @@ const (
 	gatewayModeDisabled = iota
 	gatewayCheckModePing
 	gatewayCheckModeArping
+	gatewayCheckModeTCP
 )
 
 type cniServerHandler struct {
@@ -53,6 +55,16 @@ func (csh cniServerHandler) providerExists(provider string) bool {
 	return false
 }
 
+// providerNetworkReady returns whether the provider network has been initialized on this node
+func (csh cniServerHandler) providerNetworkReady(providerNetwork string) bool {
+	node, err := csh.Controller.nodesLister.Get(csh.Config.NodeName)
+	if err != nil {
+		klog.Errorf("failed to get node %s: %v", csh.Config.NodeName, err)
+		return false
+	}
+	return node.Labels[fmt.Sprintf(util.ProviderNetworkReadyTemplate, providerNetwork)] == "true"
+}
+
 func (csh cniServerHandler) handleAdd(req *restful.Request, resp *restful.Response) {
 	podRequest := request.CniRequest{}
 	if err := req.ReadEntity(&podRequest); err != nil {
@@ -82,9 +94,11 @@ func (csh cniServerHandler) handleAdd(req *restful.Request, resp *restful.Respon
 		return
 	}
 
-	var gatewayCheckMode int
-	var macAddr, ip, ipAddr, cidr, gw, subnet, ingress, egress, providerNetwork, ifName, nicType, podNicName, priority, vmName, latency, limit, loss string
-	var isDefaultRoute bool
+	var gatewayCheckMode, gatewayCheckPort int
+	var macAddr, ip, ipAddr, cidr, gw, subnet, ingress, egress, providerNetwork, ifName, podIfName, nicType, podNicName, hostNicName, priority, qosType, minRate, egressRateMode, vmName, latency, limit, loss, gatewayMac string
+	var isDefaultRoute, txChecksumOff bool
+	var gatewayRoutes []request.Route
+	var preferredIPFamily string
 	var pod *v1.Pod
 	var err error
 	for i := 0; i < 20; i++ {
@@ -119,6 +133,9 @@ func (csh cniServerHandler) handleAdd(req *restful.Request, resp *restful.Respon
 		ingress = pod.Annotations[fmt.Sprintf(util.IngressRateAnnotationTemplate, podRequest.Provider)]
 		egress = pod.Annotations[fmt.Sprintf(util.EgressRateAnnotationTemplate, podRequest.Provider)]
 		priority = pod.Annotations[fmt.Sprintf(util.PriorityAnnotationTemplate, podRequest.Provider)]
+		qosType = pod.Annotations[fmt.Sprintf(util.QosTypeAnnotationTemplate, podRequest.Provider)]
+		minRate = pod.Annotations[fmt.Sprintf(util.MinRateAnnotationTemplate, podRequest.Provider)]
+		egressRateMode = pod.Annotations[fmt.Sprintf(util.EgressRateModeAnnotationTemplate, podRequest.Provider)]
 		latency = pod.Annotations[fmt.Sprintf(util.NetemQosLatencyAnnotationTemplate, podRequest.Provider)]
 		limit = pod.Annotations[fmt.Sprintf(util.NetemQosLimitAnnotationTemplate, podRequest.Provider)]
 		loss = pod.Annotations[fmt.Sprintf(util.NetemQosLossAnnotationTemplate, podRequest.Provider)]
@@ -152,6 +169,12 @@ func (csh cniServerHandler) handleAdd(req *restful.Request, resp *restful.Respon
 			isDefaultRoute = ifName == "eth0"
 		}
 
+		if providerNetwork != "" && !csh.providerNetworkReady(providerNetwork) {
+			klog.Infof("wait provider network %s ready for pod %s/%s", providerNetwork, podRequest.PodNamespace, podRequest.PodName)
+			time.Sleep(1 * time.Second)
+			continue
+		}
+
 		if isDefaultRoute && pod.Annotations[fmt.Sprintf(util.RoutedAnnotationTemplate, podRequest.Provider)] != "true" && strings.HasSuffix(providerNetwork, util.OvnProvider) {
 			klog.Infof("wait route ready for pod %s/%s provider %s", podRequest.PodNamespace, podRequest.PodName, podRequest.Provider)
 			cniWaitRouteResult.WithLabelValues(nodeName).Inc()
@@ -191,6 +214,15 @@ func (csh cniServerHandler) handleAdd(req *restful.Request, resp *restful.Respon
 		return
 	}
 
+	if providerNetwork != "" && !csh.providerNetworkReady(providerNetwork) {
+		err := fmt.Errorf("provider network %s is not ready on node %s for pod %s/%s", providerNetwork, csh.Config.NodeName, pod.Namespace, pod.Name)
+		klog.Error(err)
+		if err := resp.WriteHeaderAndEntity(http.StatusInternalServerError, request.CniResponse{Err: err.Error()}); err != nil {
+			klog.Errorf("failed to write response, %v", err)
+		}
+		return
+	}
+
 	if strings.HasSuffix(podRequest.Provider, util.OvnProvider) && subnet != "" {
 		podSubnet, err := csh.Controller.subnetsLister.Get(subnet)
 		if err != nil {
@@ -202,15 +234,90 @@ func (csh cniServerHandler) handleAdd(req *restful.Request, resp *restful.Respon
 			return
 		}
 
+		// the host side nic names are always derived from the interface name passed by the runtime,
+		// only the interface inside the pod is renamed
+		podIfName = ifName
+		if podRequest.PodIfName != "" {
+			podIfName = podRequest.PodIfName
+		} else if podSubnet.Spec.PodIfName != "" && ifName == "eth0" {
+			podIfName = podSubnet.Spec.PodIfName
+		}
+
+		switch pod.Annotations[fmt.Sprintf(util.TxChecksumOffAnnotationTemplate, podRequest.Provider)] {
+		case "true":
+			txChecksumOff = true
+		case "false":
+			txChecksumOff = false
+		default:
+			txChecksumOff = podSubnet.Spec.DisableTxChecksum
+		}
+
+		if podSubnet.Spec.Vlan != "" && !podSubnet.Spec.LogicalGateway {
+			gatewayMac = podSubnet.Spec.GatewayMac
+		}
+
 		subnetPriority := csh.Controller.getSubnetQosPriority(subnet)
 		if priority == "" && subnetPriority != "" {
 			priority = subnetPriority
 		}
+		if ingress == "" {
+			ingress = podSubnet.Spec.DefaultIngressRate
+		}
+		if egress == "" {
+			egress = podSubnet.Spec.DefaultEgressRate
+		}
+		for _, rate := range []string{ingress, egress} {
+			if _, err = util.ParseBandwidth(rate, csh.Config.MaxPodBandwidth); err != nil {
+				errMsg := fmt.Errorf("invalid bandwidth %q of pod %s/%s: %v", rate, podRequest.PodNamespace, podRequest.PodName, err)
+				klog.Error(errMsg)
+				if err = resp.WriteHeaderAndEntity(http.StatusBadRequest, request.CniResponse{Err: errMsg.Error()}); err != nil {
+					klog.Errorf("failed to write response: %v", err)
+				}
+				return
+			}
+		}
+		if qosType == "" {
+			qosType = podSubnet.Spec.QosType
+		}
+
+		// the nic with gateway routes routes only the cidrs through the gateways instead of the default routes
+		if gatewayRoutes, err = util.ParseGatewayRoutes(pod.Annotations[fmt.Sprintf(util.GatewayRoutesAnnotationTemplate, podRequest.Provider)], gw); err == nil &&
+			len(gatewayRoutes) != 0 && pod.Annotations[fmt.Sprintf(util.DefaultRouteAnnotationTemplate, podRequest.Provider)] == "true" {
+			err = fmt.Errorf("conflicts with annotation %s", fmt.Sprintf(util.DefaultRouteAnnotationTemplate, podRequest.Provider))
+		}
+		if err != nil {
+			errMsg := fmt.Errorf("invalid gateway routes of pod %s/%s: %v", podRequest.PodNamespace, podRequest.PodName, err)
+			klog.Error(errMsg)
+			if err = resp.WriteHeaderAndEntity(http.StatusBadRequest, request.CniResponse{Err: errMsg.Error()}); err != nil {
+				klog.Errorf("failed to write response: %v", err)
+			}
+			return
+		}
+
+		// the pod annotation has been validated against the subnet by kube-ovn-controller
+		preferredIPFamily = pod.Annotations[fmt.Sprintf(util.PreferredIPFamilyAnnotationTemplate, podRequest.Provider)]
+		if preferredIPFamily == "" {
+			preferredIPFamily = podSubnet.Spec.PreferredIPFamily
+		}
 
 		//skip ping check gateway for pods during live migration
 		if pod.Annotations[fmt.Sprintf(util.LiveMigrationAnnotationTemplate, podRequest.Provider)] != "true" {
-			if !podSubnet.Spec.DisableGatewayCheck {
-				if podSubnet.Spec.Vlan != "" && !podSubnet.Spec.LogicalGateway {
+			// the mode is resolved by kube-ovn-controller, it is empty for the pods allocated by the former versions
+			switch mode := pod.Annotations[fmt.Sprintf(util.ResolvedGatewayCheckModeAnnotationTemplate, podRequest.Provider)]; mode {
+			case kubeovnv1.GatewayCheckModeDisabled:
+			case kubeovnv1.GatewayCheckModePing:
+				gatewayCheckMode = gatewayCheckModePing
+			case kubeovnv1.GatewayCheckModeArping:
+				gatewayCheckMode = gatewayCheckModeArping
+			case kubeovnv1.GatewayCheckModeTCP:
+				gatewayCheckMode, gatewayCheckPort = gatewayCheckModeTCP, podSubnet.Spec.GatewayCheckPort
+			default:
+				if mode == "" && podSubnet.Spec.DisableGatewayCheck {
+					break
+				}
+				if podSubnet.Spec.GatewayCheckPort != 0 {
+					gatewayCheckMode, gatewayCheckPort = gatewayCheckModeTCP, podSubnet.Spec.GatewayCheckPort
+				} else if podSubnet.Spec.Vlan != "" && !podSubnet.Spec.LogicalGateway && podSubnet.Spec.GatewayMac == "" {
 					gatewayCheckMode = gatewayCheckModeArping
 				} else {
 					gatewayCheckMode = gatewayCheckModePing
@@ -291,15 +398,31 @@ func (csh cniServerHandler) handleAdd(req *restful.Request, resp *restful.Respon
 			}
 		}
 
-		klog.Infof("create container interface %s mac %s, ip %s, cidr %s, gw %s, u2o routes %v, custom routes %v", ifName, macAddr, ipAddr, cidr, gw, u2oRoutes, podRequest.Routes)
-		allRoutes := append(u2oRoutes, podRequest.Routes...)
+		externalIDs, err := util.ParseOvsExternalIDs(pod.Annotations[util.OvsExternalIDsAnnotation])
+		if err != nil {
+			errMsg := fmt.Errorf("failed to parse annotation %s of pod %s/%s: %v", util.OvsExternalIDsAnnotation, pod.Namespace, pod.Name, err)
+			klog.Error(errMsg)
+			if err = resp.WriteHeaderAndEntity(http.StatusInternalServerError, request.CniResponse{Err: errMsg.Error()}); err != nil {
+				klog.Errorf("failed to write response: %v", err)
+			}
+			return
+		}
+
+		klog.Infof("create container interface %s mac %s, ip %s, cidr %s, gw %s, u2o routes %v, gateway routes %v, custom routes %v", podIfName, macAddr, ipAddr, cidr, gw, u2oRoutes, gatewayRoutes, podRequest.Routes)
+		allRoutes := append(append(u2oRoutes, gatewayRoutes...), podRequest.Routes...)
+		nicDefaultRoute := isDefaultRoute && len(gatewayRoutes) == 0
+		// the default route of the preferred family is configured and checked first
+		nicIPAddr, nicGateway := util.PreferIPFamily(ipAddr, preferredIPFamily), util.PreferIPFamily(gw, preferredIPFamily)
 		if nicType == util.InternalType {
-			podNicName, err = csh.configureNicWithInternalPort(podRequest.PodName, podRequest.PodNamespace, podRequest.Provider, podRequest.NetNs, podRequest.ContainerID, ifName, macAddr, mtu, ipAddr, gw, isDefaultRoute, allRoutes, podRequest.DNS.Nameservers, podRequest.DNS.Search, ingress, egress, priority, podRequest.DeviceID, nicType, latency, limit, loss, gatewayCheckMode)
+			podNicName, err = csh.configureNicWithInternalPort(podRequest.PodName, podRequest.PodNamespace, podRequest.Provider, podRequest.NetNs, podRequest.ContainerID, ifName, podIfName, macAddr, mtu, nicIPAddr, nicGateway, nicDefaultRoute, allRoutes, podRequest.DNS.Nameservers, podRequest.DNS.Search, ingress, egress, priority, qosType, minRate, egressRateMode, podRequest.DeviceID, nicType, latency, limit, loss, gatewayCheckMode, gatewayCheckPort, gatewayMac, externalIDs)
 		} else if nicType == util.DpdkType {
-			err = csh.configureDpdkNic(podRequest.PodName, podRequest.PodNamespace, podRequest.Provider, podRequest.NetNs, podRequest.ContainerID, ifName, macAddr, mtu, ipAddr, gw, ingress, egress, priority, getShortSharedDir(pod.UID, podRequest.VhostUserSocketVolumeName), podRequest.VhostUserSocketName)
+			err = csh.configureDpdkNic(podRequest.PodName, podRequest.PodNamespace, podRequest.Provider, podRequest.NetNs, podRequest.ContainerID, ifName, macAddr, mtu, ipAddr, gw, ingress, egress, priority, qosType, minRate, egressRateMode, getShortSharedDir(pod.UID, podRequest.VhostUserSocketVolumeName), podRequest.VhostUserSocketName, pod.Annotations[fmt.Sprintf(util.DpdkQueuesAnnotationTemplate, podRequest.Provider)], externalIDs)
 		} else {
-			podNicName = ifName
-			err = csh.configureNic(podRequest.PodName, podRequest.PodNamespace, podRequest.Provider, podRequest.NetNs, podRequest.ContainerID, podRequest.VfDriver, ifName, macAddr, mtu, ipAddr, gw, isDefaultRoute, allRoutes, podRequest.DNS.Nameservers, podRequest.DNS.Search, ingress, egress, priority, podRequest.DeviceID, nicType, latency, limit, loss, gatewayCheckMode)
+			podNicName = podIfName
+			if podRequest.DeviceID == "" {
+				hostNicName, _ = generateNicName(podRequest.ContainerID, ifName)
+			}
+			err = csh.configureNic(podRequest.PodName, podRequest.PodNamespace, podRequest.Provider, podRequest.NetNs, podRequest.ContainerID, podRequest.VfDriver, ifName, podIfName, macAddr, mtu, nicIPAddr, nicGateway, nicDefaultRoute, allRoutes, podRequest.DNS.Nameservers, podRequest.DNS.Search, ingress, egress, priority, qosType, minRate, egressRateMode, podRequest.DeviceID, nicType, latency, limit, loss, gatewayCheckMode, gatewayCheckPort, txChecksumOff, gatewayMac, externalIDs)
 		}
 		if err != nil {
 			errMsg := fmt.Errorf("configure nic failed %v", err)
@@ -327,15 +450,19 @@ func (csh cniServerHandler) handleAdd(req *restful.Request, resp *restful.Respon
 	}
 
 	response := &request.CniResponse{
-		Protocol:   util.CheckProtocol(cidr),
-		IpAddress:  ip,
-		MacAddress: macAddr,
-		CIDR:       cidr,
-		PodNicName: podNicName,
+		Protocol:    util.CheckProtocol(cidr),
+		IpAddress:   ip,
+		MacAddress:  macAddr,
+		CIDR:        cidr,
+		PodNicName:  podNicName,
+		HostNicName: hostNicName,
 	}
-	if isDefaultRoute {
+	if isDefaultRoute && len(gatewayRoutes) == 0 {
 		response.Gateway = gw
 	}
+	if response.Protocol == kubeovnv1.ProtocolDual {
+		response.PreferredIPFamily = preferredIPFamily
+	}
 	if err := resp.WriteHeaderAndEntity(http.StatusOK, response); err != nil {
 		klog.Errorf("failed to write response, %v", err)
 	}
@@ -18,7 +18,11 @@ import (
 )
 
 func (csh cniServerHandler) validatePodRequest(req *request.CniRequest) error {
-	// nothing to do on linux
+	if req.PodIfName != "" {
+		if err := util.ValidateInterfaceName(req.PodIfName); err != nil {
+			return fmt.Errorf("invalid pod interface name: %v", err)
+		}
+	}
 	return nil
 }
 
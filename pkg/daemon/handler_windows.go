@@ -15,6 +15,9 @@ func (csh cniServerHandler) validatePodRequest(req *request.CniRequest) error {
 	if req.VhostUserSocketVolumeName != "" {
 		return errors.New("DPDK is not supported on Windows")
 	}
+	if req.PodIfName != "" {
+		return errors.New("customizing pod interface name is not supported on Windows")
+	}
 
 	return nil
 }
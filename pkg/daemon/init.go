@@ -10,12 +10,14 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
 	"github.com/kubeovn/kube-ovn/pkg/ovs"
+	"github.com/kubeovn/kube-ovn/pkg/request"
 	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
 // InitOVSBridges initializes OVS bridges
-func InitOVSBridges() (map[string]string, error) {
+func InitOVSBridges(config *Configuration) (map[string]string, error) {
 	bridges, err := ovs.Bridges()
 	if err != nil {
 		return nil, err
@@ -40,7 +42,7 @@ func InitOVSBridges() (map[string]string, error) {
 					return nil, fmt.Errorf("failed to check vendor of port %s: %v", port, err)
 				}
 				if ok {
-					if _, err = configProviderNic(port, brName); err != nil {
+					if _, err = configProviderNic(port, brName, config.PreservedHostRoutes); err != nil {
 						return nil, err
 					}
 					mappings[port] = brName
@@ -55,6 +57,7 @@ func InitOVSBridges() (map[string]string, error) {
 // InitNodeGateway init ovn0
 func InitNodeGateway(config *Configuration) error {
 	var portName, ip, cidr, macAddr, gw, ipAddr string
+	var routes []request.Route
 	for {
 		nodeName := config.NodeName
 		node, err := config.KubeClient.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
@@ -77,6 +80,9 @@ func InitNodeGateway(config *Configuration) error {
 			cidr = node.Annotations[util.CidrAnnotation]
 			portName = node.Annotations[util.PortNameAnnotation]
 			gw = node.Annotations[util.GatewayAnnotation]
+			if routes, err = util.ParseNodeNicRoutes(node.Annotations[util.NodeNicRoutesAnnotation]); err != nil {
+				klog.Errorf("failed to parse annotation %s of node %s, ignore the custom routes: %v", util.NodeNicRoutesAnnotation, nodeName, err)
+			}
 			break
 		}
 	}
@@ -86,7 +92,7 @@ func InitNodeGateway(config *Configuration) error {
 	}
 
 	ipAddr = util.GetIpAddrWithMask(ip, cidr)
-	return configureNodeNic(portName, ipAddr, gw, mac, config.MTU)
+	return configureNodeNic(portName, ipAddr, gw, routes, mac, config.MTU, config.GatewayCheckMaxRetry, config.GatewayCheckTimeout)
 }
 
 func InitMirror(config *Configuration) error {
@@ -96,45 +102,93 @@ func InitMirror(config *Configuration) error {
 	return configureEmptyMirror(config.MirrorNic, config.MTU)
 }
 
-func ovsInitProviderNetwork(provider, nic string, exchangeLinkName, macLearningFallback bool) (int, error) {
+// ovsInitProviderNetwork initializes the provider network on the node in the steps of
+// kubeovnv1.ProviderNetworkNodeSteps. Every step only completes what's missing, so a retry after an interruption
+// continues from the failed step. The condition of the failed step is returned along with the error
+func ovsInitProviderNetwork(provider, brName, nic string, exchangeLinkName, macLearningFallback bool, preservedRoutes []*net.IPNet) (int, kubeovnv1.ConditionType, error) {
+	// clean the previous external bridge if the bridge name has been changed
+	oldBrName, err := getProviderBridgeMapping(provider)
+	if err != nil {
+		klog.Error(err)
+		return 0, kubeovnv1.BridgeReady, err
+	}
+	if oldBrName != "" && oldBrName != brName && !(exchangeLinkName && oldBrName == nic) {
+		klog.Infof("external bridge of provider %s is changed from %s to %s", provider, oldBrName, brName)
+		if err = ovsCleanProviderNetwork(provider, oldBrName, preservedRoutes); err != nil {
+			klog.Errorf("failed to clean external bridge %s: %v", oldBrName, err)
+			return 0, kubeovnv1.BridgeReady, err
+		}
+	}
+
 	// create and configure external bridge
-	brName := util.ExternalBridgeName(provider)
 	if exchangeLinkName {
 		exchanged, err := changeProvideNicName(nic, brName)
 		if err != nil {
 			klog.Errorf("failed to change provider nic name from %s to %s: %v", nic, brName, err)
-			return 0, err
+			return 0, kubeovnv1.BridgeReady, err
 		}
 		if exchanged {
 			nic, brName = brName, nic
 		}
 	}
 
-	if err := configExternalBridge(provider, brName, nic, exchangeLinkName, macLearningFallback); err != nil {
+	if err := configExternalBridge(provider, brName, nic, exchangeLinkName, macLearningFallback, preservedRoutes); err != nil {
 		errMsg := fmt.Errorf("failed to create and configure external bridge %s: %v", brName, err)
 		klog.Error(errMsg)
-		return 0, errMsg
+		return 0, kubeovnv1.BridgeReady, errMsg
 	}
 
 	// init provider chassis mac
 	if err := initProviderChassisMac(provider); err != nil {
 		errMsg := fmt.Errorf("failed to init chassis mac for provider %s, %v", provider, err)
 		klog.Error(errMsg)
-		return 0, errMsg
+		return 0, kubeovnv1.BridgeReady, errMsg
 	}
 
-	// add host nic to the external bridge
-	mtu, err := configProviderNic(nic, brName)
+	// keep the mac address and mtu of the external bridge the same with the host nic
+	mtu, err := configProviderBridge(nic, brName)
 	if err != nil {
+		errMsg := fmt.Errorf("failed to configure external bridge %s with nic %s: %v", brName, nic, err)
+		klog.Error(errMsg)
+		return 0, kubeovnv1.BridgeReady, errMsg
+	}
+
+	// transfer the addresses and routes of the host nic to the external bridge
+	if err = migrateProviderNicRoutes(nic, brName, preservedRoutes); err != nil {
+		errMsg := fmt.Errorf("failed to transfer addresses and routes of nic %s to external bridge %s: %v", nic, brName, err)
+		klog.Error(errMsg)
+		return 0, kubeovnv1.RoutesMigrated, errMsg
+	}
+
+	// add host nic to the external bridge
+	if err = attachProviderNic(nic, brName); err != nil {
 		errMsg := fmt.Errorf("failed to add nic %s to external bridge %s: %v", nic, brName, err)
 		klog.Error(errMsg)
-		return 0, errMsg
+		return 0, kubeovnv1.NicAttached, errMsg
+	}
+
+	return mtu, "", nil
+}
+
+// getProviderBridgeMapping returns the external bridge of the provider in ovn-bridge-mappings
+func getProviderBridgeMapping(provider string) (string, error) {
+	output, err := ovs.Exec(ovs.IfExists, "get", "open", ".", "external-ids:ovn-bridge-mappings")
+	if err != nil {
+		return "", fmt.Errorf("failed to get ovn-bridge-mappings, %v: %q", err, output)
 	}
 
-	return mtu, nil
+	mappingPrefix := provider + ":"
+	for _, m := range strings.Split(output, ",") {
+		if strings.HasPrefix(m, mappingPrefix) {
+			return m[len(mappingPrefix):], nil
+		}
+	}
+	return "", nil
 }
 
-func ovsCleanProviderNetwork(provider string) error {
+// ovsCleanProviderNetwork removes the external bridge of the provider,
+// bridgeName is the bridge name configured for the provider network
+func ovsCleanProviderNetwork(provider, bridgeName string, preservedRoutes []*net.IPNet) error {
 	output, err := ovs.Exec(ovs.IfExists, "get", "open", ".", "external-ids:ovn-bridge-mappings")
 	if err != nil {
 		return fmt.Errorf("failed to get ovn-bridge-mappings, %v: %q", err, output)
@@ -167,7 +221,7 @@ func ovsCleanProviderNetwork(provider string) error {
 	// remove host nic from the external bridge
 	if output != "" {
 		for _, port := range strings.Split(output, "\n") {
-			if err = removeProviderNic(port, brName); err != nil {
+			if err = removeProviderNic(port, brName, preservedRoutes); err != nil {
 				errMsg := fmt.Errorf("failed to remove port %s from external bridge %s: %v", port, brName, err)
 				klog.Error(errMsg)
 				return errMsg
@@ -214,7 +268,7 @@ func ovsCleanProviderNetwork(provider string) error {
 	// remove host nic from the external bridge
 	if output != "" {
 		for _, port := range strings.Split(output, "\n") {
-			if err = removeProviderNic(port, brName); err != nil {
+			if err = removeProviderNic(port, brName, preservedRoutes); err != nil {
 				errMsg := fmt.Errorf("failed to remove port %s from external bridge %s: %v", port, brName, err)
 				klog.Error(errMsg)
 				return errMsg
@@ -227,9 +281,9 @@ func ovsCleanProviderNetwork(provider string) error {
 		return fmt.Errorf("failed to remove OVS bridge %s, %v: %q", brName, err, output)
 	}
 
-	if br := util.ExternalBridgeName(provider); br != brName {
-		if _, err = changeProvideNicName(br, brName); err != nil {
-			klog.Errorf("failed to change provider nic name from %s to %s: %v", br, brName, err)
+	if bridgeName != brName {
+		if _, err = changeProvideNicName(bridgeName, brName); err != nil {
+			klog.Errorf("failed to change provider nic name from %s to %s: %v", bridgeName, brName, err)
 			return err
 		}
 	}
@@ -0,0 +1,134 @@
+package daemon
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	goping "github.com/oilbeater/go-ping"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+const (
+	internodeProbeCount   = 3
+	internodeProbeTimeout = 2 * time.Second
+)
+
+// probeInternodeRtt measures the rtt from ovn0 of this node to ovn0 of the peer nodes through the overlay network.
+// In large clusters only a subset of the peers is probed by each node, which is selected by rendezvous hashing,
+// so that the subset is stable across rounds and the peers are covered evenly by the nodes
+func (c *Controller) probeInternodeRtt() {
+	node, err := c.nodesLister.Get(c.config.NodeName)
+	if err != nil {
+		klog.Errorf("failed to get node %s, %v", c.config.NodeName, err)
+		return
+	}
+	sources := make(map[string]string)
+	for _, ip := range strings.Split(node.Annotations[util.IpAddressAnnotation], ",") {
+		if ip != "" {
+			sources[util.CheckProtocol(ip)] = ip
+		}
+	}
+	if len(sources) == 0 {
+		klog.V(3).Infof("ovn0 of node %s is not ready, skip probing peers", c.config.NodeName)
+		return
+	}
+
+	nodes, err := c.nodesLister.List(c.config.InternodeProbeNodeSelector)
+	if err != nil {
+		klog.Errorf("failed to list nodes, %v", err)
+		return
+	}
+	peers := make(map[string]*v1.Node, len(nodes))
+	names := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		if n.Name == c.config.NodeName || n.Annotations[util.IpAddressAnnotation] == "" || !isNodeReady(n) {
+			continue
+		}
+		peers[n.Name] = n
+		names = append(names, n.Name)
+	}
+	names = selectProbePeers(c.config.NodeName, names, c.config.InternodeProbeMaxPeers)
+
+	probed := make(map[string]bool, len(names))
+	var wg sync.WaitGroup
+	for _, name := range names {
+		probed[name] = true
+		for _, ip := range strings.Split(peers[name].Annotations[util.IpAddressAnnotation], ",") {
+			protocol := util.CheckProtocol(ip)
+			src, ok := sources[protocol]
+			if !ok {
+				continue
+			}
+			wg.Add(1)
+			go func(peer, src, dst, protocol string) {
+				defer wg.Done()
+				probeInternodePeer(peer, src, dst, protocol)
+			}(name, src, ip, protocol)
+		}
+	}
+	wg.Wait()
+
+	// remove the metrics of the peers no longer probed
+	for peer := range c.internodeProbePeers {
+		if !probed[peer] {
+			internodeRtt.DeletePartialMatch(prometheus.Labels{"node_name": nodeName, "peer": peer})
+		}
+	}
+	c.internodeProbePeers = probed
+}
+
+func probeInternodePeer(peer, src, dst, protocol string) {
+	pinger, err := goping.NewPinger(dst)
+	if err != nil {
+		klog.Errorf("failed to init pinger, %v", err)
+		return
+	}
+	pinger.SetPrivileged(true)
+	pinger.Source = src
+	pinger.Count = internodeProbeCount
+	pinger.Interval = 100 * time.Millisecond
+	pinger.Timeout = internodeProbeTimeout
+	pinger.Run()
+
+	stats := pinger.Statistics()
+	if stats.PacketsRecv == 0 {
+		klog.Warningf("failed to probe ovn0 %s of node %s from %s, %d packets lost", dst, peer, src, stats.PacketsSent)
+		internodeRtt.DeleteLabelValues(nodeName, peer, protocol)
+		internodeProbeFailures.WithLabelValues(nodeName, peer, protocol).Inc()
+		return
+	}
+	klog.V(5).Infof("rtt to ovn0 %s of node %s is %v", dst, peer, stats.AvgRtt)
+	internodeRtt.WithLabelValues(nodeName, peer, protocol).Set(stats.AvgRtt.Seconds())
+}
+
+func isNodeReady(node *v1.Node) bool {
+	for _, c := range node.Status.Conditions {
+		if c.Type == v1.NodeReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// selectProbePeers returns at most max peers with the highest rendezvous hash weights with the node,
+// all the peers are returned if max is zero
+func selectProbePeers(node string, peers []string, max int) []string {
+	if max == 0 || len(peers) <= max {
+		return peers
+	}
+	weights := make(map[string]uint64, len(peers))
+	for _, peer := range peers {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(node + "/" + peer))
+		weights[peer] = h.Sum64()
+	}
+	sort.Slice(peers, func(i, j int) bool { return weights[peers[i]] > weights[peers[j]] })
+	return peers[:max]
+}
@@ -45,6 +45,62 @@ var (
 			Name: "cni_wait_connectivity_seconds_total",
 			Help: "Latency that cni wait address ready in overlay network",
 		},
+		[]string{"node_name", "method"},
+	)
+
+	dpdkPmdCores = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dpdk_pmd_cores",
+			Help: "The number of pmd cores of ovs-dpdk",
+		},
+		[]string{"node_name"},
+	)
+
+	dpdkStaleSocketCleanups = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dpdk_stale_socket_cleanups_total",
+			Help: "The number of stale vhost-user sockets removed before creating dpdk ports",
+		},
+		[]string{"node_name"},
+	)
+
+	internodeRtt = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kube_ovn_internode_rtt_seconds",
+			Help: "The average rtt seconds from ovn0 of the node to ovn0 of the peer node",
+		},
+		[]string{"node_name", "peer", "protocol"},
+	)
+
+	internodeProbeFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kube_ovn_internode_probe_failed_total",
+			Help: "The number of internode probes with all the packets lost",
+		},
+		[]string{"node_name", "peer", "protocol"},
+	)
+
+	tunnelBondSlaves = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "tunnel_bond_slaves",
+			Help: "The number of slaves of the bond used as tunnel interface",
+		},
+		[]string{"node_name", "bond"},
+	)
+
+	tunnelBondActiveSlaves = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "tunnel_bond_active_slaves",
+			Help: "The number of slaves with link up of the bond used as tunnel interface",
+		},
+		[]string{"node_name", "bond"},
+	)
+
+	sflowActive = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sflow_active",
+			Help: "Whether sFlow sampling is active on br-int",
+		},
 		[]string{"node_name"},
 	)
 
@@ -128,6 +184,13 @@ func InitMetrics() {
 	prometheus.MustRegister(cniOperationHistogram)
 	prometheus.MustRegister(cniWaitAddressResult)
 	prometheus.MustRegister(cniConnectivityResult)
+	prometheus.MustRegister(dpdkPmdCores)
+	prometheus.MustRegister(dpdkStaleSocketCleanups)
+	prometheus.MustRegister(internodeRtt)
+	prometheus.MustRegister(internodeProbeFailures)
+	prometheus.MustRegister(tunnelBondSlaves)
+	prometheus.MustRegister(tunnelBondActiveSlaves)
+	prometheus.MustRegister(sflowActive)
 }
 
 // registerClientMetrics sets up the client latency metrics from client-go
@@ -1,8 +1,12 @@
 package daemon
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	goping "github.com/oilbeater/go-ping"
@@ -12,18 +16,36 @@ import (
 	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
-const gatewayCheckMaxRetry = 200
+const (
+	defaultGatewayCheckMaxRetry = 200
+	defaultGatewayCheckTimeout  = time.Second
+)
+
+// policies to recover ovn0 when it's found unhealthy, which happens after OVS restarts
+const (
+	// ovn0RecoveryRestart exits the process to let kube-ovn-cni restart and initialize ovn0 again
+	ovn0RecoveryRestart = "restart"
+	// ovn0RecoveryRepair configures ovn0 again in place, and falls back to restart if the repair keeps failing
+	ovn0RecoveryRepair = "repair"
+)
 
-func pingGateway(gw, src string, verbose bool) error {
+// methods of gateway check recorded in the connectivity metric
+const (
+	gatewayCheckMethodPing   = "ping"
+	gatewayCheckMethodArping = "arping"
+	gatewayCheckMethodTCP    = "tcp"
+)
+
+func pingGateway(gw, src string, verbose bool, maxRetry int, timeout time.Duration) error {
 	pinger, err := goping.NewPinger(gw)
 	if err != nil {
 		return fmt.Errorf("failed to init pinger: %v", err)
 	}
 	pinger.SetPrivileged(true)
 	// CNITimeoutSec = 220, cannot exceed
-	pinger.Count = gatewayCheckMaxRetry
-	pinger.Timeout = gatewayCheckMaxRetry * time.Second
-	pinger.Interval = time.Second
+	pinger.Count = maxRetry
+	pinger.Timeout = time.Duration(maxRetry) * timeout
+	pinger.Interval = timeout
 
 	var success bool
 	pinger.OnRecv = func(p *goping.Packet) {
@@ -32,9 +54,9 @@ func pingGateway(gw, src string, verbose bool) error {
 	}
 	pinger.Run()
 
-	cniConnectivityResult.WithLabelValues(nodeName).Add(float64(pinger.PacketsSent))
+	cniConnectivityResult.WithLabelValues(nodeName, gatewayCheckMethodPing).Add(float64(pinger.PacketsSent))
 	if !success {
-		return fmt.Errorf("%s network not ready after %d ping %s", src, gatewayCheckMaxRetry, gw)
+		return fmt.Errorf("%s network not ready after %d ping %s", src, maxRetry, gw)
 	}
 	if verbose {
 		klog.Infof("%s network ready after %d ping, gw %s", src, pinger.PacketsSent, gw)
@@ -43,6 +65,38 @@ func pingGateway(gw, src string, verbose bool) error {
 	return nil
 }
 
+// tcpConnectGateway checks the gateway by tcp connection to the port,
+// the network is ready once the gateway replies with either SYN-ACK or RST
+func tcpConnectGateway(gw, src string, port int, verbose bool, maxRetry int, timeout time.Duration) error {
+	dialer := net.Dialer{Timeout: timeout, LocalAddr: &net.TCPAddr{IP: net.ParseIP(src)}}
+	address := net.JoinHostPort(gw, strconv.Itoa(port))
+
+	var count int
+	var err error
+	defer func() {
+		cniConnectivityResult.WithLabelValues(nodeName, gatewayCheckMethodTCP).Add(float64(count))
+	}()
+	for count < maxRetry {
+		count++
+		start := time.Now()
+		var conn net.Conn
+		if conn, err = dialer.Dial("tcp", address); err == nil || errors.Is(err, syscall.ECONNREFUSED) {
+			if conn != nil {
+				_ = conn.Close()
+			}
+			if verbose {
+				klog.Infof("%s network ready after %d tcp connection to %s", src, count, address)
+			}
+			return nil
+		}
+		// wait for the rest of the timeout if the dial fails immediately, e.g. the neighbor is not resolved
+		if elapsed := time.Since(start); elapsed < timeout {
+			time.Sleep(timeout - elapsed)
+		}
+	}
+	return fmt.Errorf("%s network not ready after %d tcp connection to %s: %v", src, count, address, err)
+}
+
 func configureGlobalMirror(portName string, mtu int) error {
 	raw, err := ovs.Exec(ovs.MayExist, "add-port", "br-int", portName, "--",
 		"set", "interface", portName, "type=internal", "--",
@@ -71,7 +125,7 @@ func configureEmptyMirror(portName string, mtu int) error {
 	return configureMirrorLink(portName, mtu)
 }
 
-func configExternalBridge(provider, bridge, nic string, exchangeLinkName, macLearningFallback bool) error {
+func configExternalBridge(provider, bridge, nic string, exchangeLinkName, macLearningFallback bool, preservedRoutes []*net.IPNet) error {
 	brExists, err := ovs.BridgeExists(bridge)
 	if err != nil {
 		return fmt.Errorf("failed to check OVS bridge existence: %v", err)
@@ -102,7 +156,7 @@ func configExternalBridge(provider, bridge, nic string, exchangeLinkName, macLea
 					return fmt.Errorf("failed to check vendor of port %s: %v", port, err)
 				}
 				if ok {
-					if err = removeProviderNic(port, bridge); err != nil {
+					if err = removeProviderNic(port, bridge, preservedRoutes); err != nil {
 						return fmt.Errorf("failed to remove port %s from OVS bridge %s: %v", port, bridge, err)
 					}
 				}
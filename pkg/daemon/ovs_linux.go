@@ -1,6 +1,8 @@
 package daemon
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net"
@@ -9,7 +11,9 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -18,6 +22,10 @@ import (
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/containernetworking/plugins/pkg/utils/sysctl"
 	"github.com/vishvananda/netlink"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"golang.org/x/sys/unix"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
 
@@ -29,13 +37,35 @@ import (
 
 var pciAddrRegexp = regexp.MustCompile(`\b([0-9a-fA-F]{4}:[0-9a-fA-F]{2}:[0-9a-fA-F]{2}.\d{1}\S*)`)
 
-func (csh cniServerHandler) configureDpdkNic(podName, podNamespace, provider, netns, containerID, ifName, mac string, mtu int, ip, gateway, ingress, egress, priority, shortSharedDir, socketName string) error {
+func (csh cniServerHandler) configureDpdkNic(podName, podNamespace, provider, netns, containerID, ifName, mac string, mtu int, ip, gateway, ingress, egress, priority, qosType, minRate, egressRateMode, shortSharedDir, socketName, queues string, externalIDs map[string]string) error {
+	if err := flushNicRemovals(generateNicName(containerID, ifName)); err != nil {
+		return err
+	}
+	if queues != "" {
+		n, err := strconv.Atoi(queues)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid dpdk queues %q", queues)
+		}
+		dpdkConfig, err := ovs.GetDpdkConfig()
+		if err != nil {
+			return fmt.Errorf("failed to get dpdk config of ovs: %v", err)
+		}
+		dpdkPmdCores.WithLabelValues(nodeName).Set(float64(dpdkConfig.PmdCores))
+		if n > dpdkConfig.PmdCores {
+			return fmt.Errorf("pod requests %d dpdk queues but ovs-dpdk only has %d pmd cores (pmd-cpu-mask %q), adjust the queues or the pmd-cpu-mask", n, dpdkConfig.PmdCores, dpdkConfig.PmdCPUMask)
+		}
+	}
+
 	sharedDir := filepath.Join("/var", shortSharedDir)
+	if err := cleanStaleVhostUserSockets(sharedDir); err != nil {
+		klog.Error(err)
+		return err
+	}
 	hostNicName, _ := generateNicName(containerID, ifName)
 
 	ipStr := util.GetIpWithoutMask(ip)
 	ifaceID := ovs.PodNameToPortName(podName, podNamespace, provider)
-	ovs.CleanDuplicatePort(ifaceID, hostNicName)
+	ovs.CleanDuplicatePort(ifaceID, hostNicName, netns)
 	// Add veth pair host end to ovs port
 	output, err := ovs.Exec(ovs.MayExist, "add-port", "br-int", hostNicName, "--",
 		"set", "interface", hostNicName,
@@ -49,13 +79,23 @@ func (csh cniServerHandler) configureDpdkNic(podName, podNamespace, provider, ne
 	if err != nil {
 		return fmt.Errorf("add nic to ovs failed %v: %q", err, output)
 	}
-	if err = ovs.SetInterfaceBandwidth(podName, podNamespace, ifaceID, egress, ingress, priority); err != nil {
+	if err = ovs.SetInterfaceCustomExternalIds(hostNicName, externalIDs); err != nil {
+		return err
+	}
+	if err = setDedicatedQueue(podName, podNamespace, ifaceID, minRate, ingress, qosType, csh.Config.tunnelIface); err != nil {
+		return err
+	}
+	if err = setPodBandwidth(podName, podNamespace, ifaceID, ingress, egress, priority, qosType, egressRateMode); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (csh cniServerHandler) configureNic(podName, podNamespace, provider, netns, containerID, vfDriver, ifName, mac string, mtu int, ip, gateway string, isDefaultRoute bool, routes []request.Route, dnsServer, dnsSuffix []string, ingress, egress, priority, DeviceID, nicType, latency, limit, loss string, gwCheckMode int) error {
+func (csh cniServerHandler) configureNic(podName, podNamespace, provider, netns, containerID, vfDriver, ifName, podIfName, mac string, mtu int, ip, gateway string, isDefaultRoute bool, routes []request.Route, dnsServer, dnsSuffix []string, ingress, egress, priority, qosType, minRate, egressRateMode, DeviceID, nicType, latency, limit, loss string, gwCheckMode, gwCheckPort int, txChecksumOff bool, gatewayMac string, externalIDs map[string]string) error {
+	if err := flushNicRemovals(generateNicName(containerID, ifName)); err != nil {
+		return err
+	}
+
 	var err error
 	var hostNicName, containerNicName string
 	if DeviceID == "" {
@@ -72,9 +112,10 @@ func (csh cniServerHandler) configureNic(podName, podNamespace, provider, netns,
 		}
 	}
 
-	ipStr := util.GetIpWithoutMask(ip)
+	// the ips may be ordered by the preferred ip family, record them in the order of the annotation
+	ipStr := util.PreferIPFamily(util.GetIpWithoutMask(ip), kubeovnv1.ProtocolIPv4)
 	ifaceID := ovs.PodNameToPortName(podName, podNamespace, provider)
-	ovs.CleanDuplicatePort(ifaceID, hostNicName)
+	ovs.CleanDuplicatePort(ifaceID, hostNicName, netns)
 	// Add veth pair host end to ovs port
 	output, err := ovs.Exec(ovs.MayExist, "add-port", "br-int", hostNicName, "--",
 		"set", "interface", hostNicName, fmt.Sprintf("external_ids:iface-id=%s", ifaceID),
@@ -85,6 +126,9 @@ func (csh cniServerHandler) configureNic(podName, podNamespace, provider, netns,
 	if err != nil {
 		return fmt.Errorf("add nic to ovs failed %v: %q", err, output)
 	}
+	if err = ovs.SetInterfaceCustomExternalIds(hostNicName, externalIDs); err != nil {
+		return err
+	}
 
 	// lsp and container nic must use same mac address, otherwise ovn will reject these packets by default
 	macAddr, err := net.ParseMAC(mac)
@@ -94,7 +138,10 @@ func (csh cniServerHandler) configureNic(podName, podNamespace, provider, netns,
 	if err = configureHostNic(hostNicName); err != nil {
 		return err
 	}
-	if err = ovs.SetInterfaceBandwidth(podName, podNamespace, ifaceID, egress, ingress, priority); err != nil {
+	if err = setDedicatedQueue(podName, podNamespace, ifaceID, minRate, ingress, qosType, csh.Config.tunnelIface); err != nil {
+		return err
+	}
+	if err = setPodBandwidth(podName, podNamespace, ifaceID, ingress, egress, priority, qosType, egressRateMode); err != nil {
 		return err
 	}
 
@@ -114,20 +161,50 @@ func (csh cniServerHandler) configureNic(podName, podNamespace, provider, netns,
 		if err = turnOffNicTxChecksum(containerNicName); err != nil {
 			return err
 		}
+	} else if txChecksumOff {
+		klog.Infof("turn off tx checksum of nic %s for pod %s/%s", containerNicName, podNamespace, podName)
+		if err = turnOffNicTxChecksum(containerNicName); err != nil {
+			return err
+		}
 	}
 
 	podNS, err := ns.GetNS(netns)
 	if err != nil {
 		return fmt.Errorf("failed to open netns %q: %v", netns, err)
 	}
-	if err = configureContainerNic(containerNicName, ifName, ip, gateway, isDefaultRoute, routes, macAddr, podNS, mtu, nicType, gwCheckMode); err != nil {
+	if err = configureContainerNic(containerNicName, podIfName, ip, gateway, gatewayMac, isDefaultRoute, routes, macAddr, podNS, mtu, nicType, gwCheckMode, gwCheckPort, csh.Config.GatewayCheckMaxRetry, csh.Config.GatewayCheckTimeout, csh.Config.gatewayCheckMtuSize()); err != nil {
 		return err
 	}
 	return nil
 }
 
+// gatewayRouteFlags returns the flags of the default route via the gateway. The pods allocated from the extra CIDR
+// blocks of a subnet share the gateway of the subnet, which is not in their own CIDR and is reached on link
+func gatewayRouteFlags(ipAddr, gateway string) int {
+	for _, addr := range strings.Split(ipAddr, ",") {
+		if util.CheckProtocol(addr) == util.CheckProtocol(gateway) && util.CIDRContainIP(addr, gateway) {
+			return 0
+		}
+	}
+	return int(netlink.FLAG_ONLINK)
+}
+
+// pendingNicRemoval is a nic whose removal is delayed by the port removal grace period
+type pendingNicRemoval struct {
+	timer  *time.Timer
+	remove func() error
+}
+
+var (
+	// pendingNicRemovals holds the nics whose removal is delayed, keyed by the ovs port name
+	pendingNicRemovals     = make(map[string]*pendingNicRemoval)
+	pendingNicRemovalsLock sync.Mutex
+)
+
 func (csh cniServerHandler) deleteNic(podName, podNamespace, containerID, netns, deviceID, ifName, nicType string) error {
 	var nicName string
+	// ifName is the one passed by the runtime rather than the renamed interface inside the pod,
+	// which is exactly what the nic names are generated from when adding
 	hostNicName, containerNicName := generateNicName(containerID, ifName)
 
 	if nicType == util.InternalType {
@@ -136,17 +213,103 @@ func (csh cniServerHandler) deleteNic(podName, podNamespace, containerID, netns,
 		nicName = hostNicName
 	}
 
+	grace := csh.Config.PortRemovalGracePeriod
+	if grace <= 0 {
+		return removeNic(podName, podNamespace, nicName, deviceID)
+	}
+
+	pendingNicRemovalsLock.Lock()
+	defer pendingNicRemovalsLock.Unlock()
+	// the runtime may call del repeatedly, do not extend the grace period
+	if _, ok := pendingNicRemovals[nicName]; ok {
+		return nil
+	}
+	// keep the port and its flows during the grace period, the deadline makes sure the port
+	// is removed by cleanExpiredNics even if the daemon restarts before the timer fires
+	if err := ovs.SetPortRemovalDeadline(nicName, time.Now().Add(grace)); err != nil {
+		klog.Errorf("failed to set removal deadline of port %s: %v", nicName, err)
+		return err
+	}
+	klog.Infof("delay the removal of port %s of pod %s/%s for %v", nicName, podNamespace, podName, grace)
+
+	pending := &pendingNicRemoval{remove: func() error {
+		return removeNic(podName, podNamespace, nicName, deviceID)
+	}}
+	pending.timer = time.AfterFunc(grace, func() {
+		pendingNicRemovalsLock.Lock()
+		defer pendingNicRemovalsLock.Unlock()
+		if pendingNicRemovals[nicName] != pending {
+			return
+		}
+		delete(pendingNicRemovals, nicName)
+		if err := pending.remove(); err != nil {
+			// the port is still marked and will be removed by cleanExpiredNics
+			klog.Errorf("failed to remove port %s of pod %s/%s: %v", nicName, podNamespace, podName, err)
+		}
+	})
+	pendingNicRemovals[nicName] = pending
+	return nil
+}
+
+// flushNicRemovals removes the nics whose removal is delayed immediately, so that they can be added again by the runtime
+func flushNicRemovals(nicNames ...string) error {
+	pendingNicRemovalsLock.Lock()
+	defer pendingNicRemovalsLock.Unlock()
+	for _, nicName := range nicNames {
+		pending, ok := pendingNicRemovals[nicName]
+		if !ok {
+			continue
+		}
+		pending.timer.Stop()
+		delete(pendingNicRemovals, nicName)
+		klog.Infof("remove port %s pending removal since it is added again", nicName)
+		if err := pending.remove(); err != nil {
+			klog.Errorf("failed to remove port %s: %v", nicName, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanExpiredNics removes the nics whose removal deadline has passed but are not removed by the timers,
+// e.g. the daemon restarts during the grace period
+func cleanExpiredNics() {
+	deadlines, err := ovs.ListPortRemovalDeadlines()
+	if err != nil {
+		klog.Errorf("failed to list ports pending removal: %v", err)
+		return
+	}
+
+	pendingNicRemovalsLock.Lock()
+	defer pendingNicRemovalsLock.Unlock()
+	for nicName, deadline := range deadlines {
+		if _, ok := pendingNicRemovals[nicName]; ok || time.Now().Before(deadline) {
+			continue
+		}
+		podName, _ := ovs.Exec(ovs.IfExists, "get", "interface", nicName, "external_ids:pod_name")
+		podNamespace, _ := ovs.Exec(ovs.IfExists, "get", "interface", nicName, "external_ids:pod_namespace")
+		podName, podNamespace = strings.Trim(podName, `"`), strings.Trim(podNamespace, `"`)
+		klog.Infof("remove expired port %s of pod %s/%s", nicName, podNamespace, podName)
+		if err = removeNic(podName, podNamespace, nicName, ""); err != nil {
+			klog.Errorf("failed to remove port %s: %v", nicName, err)
+		}
+	}
+}
+
+func removeNic(podName, podNamespace, nicName, deviceID string) error {
 	// Remove ovs port
 	output, err := ovs.Exec(ovs.IfExists, "--with-iface", "del-port", "br-int", nicName)
 	if err != nil {
 		return fmt.Errorf("failed to delete ovs port %v, %q", err, output)
 	}
 
-	if err = ovs.ClearPodBandwidth(podName, podNamespace, ""); err != nil {
-		return err
-	}
-	if err = ovs.ClearHtbQosQueue(podName, podNamespace, ""); err != nil {
-		return err
+	if podName != "" && podNamespace != "" {
+		if err = ovs.ClearPodBandwidth(podName, podNamespace, ""); err != nil {
+			return err
+		}
+		if err = ovs.ClearHtbQosQueue(podName, podNamespace, ""); err != nil {
+			return err
+		}
 	}
 
 	if deviceID == "" {
@@ -163,6 +326,9 @@ func (csh cniServerHandler) deleteNic(podName, podNamespace, containerID, netns,
 		hostLinkType := hostLink.Type()
 		// Sometimes no deviceID input for vf nic, avoid delete vf nic.
 		if hostLinkType == "veth" {
+			if err = clearIfbShaping(hostLink); err != nil {
+				return err
+			}
 			if err = netlink.LinkDel(hostLink); err != nil {
 				return fmt.Errorf("delete host link %s failed %v", hostLink, err)
 			}
@@ -188,6 +354,196 @@ func generateNicName(containerID, ifname string) (string, string) {
 	return fmt.Sprintf("%s_%s_h", containerID[0:12-len(ifname)], ifname), fmt.Sprintf("%s_%s_c", containerID[0:12-len(ifname)], ifname)
 }
 
+// setDedicatedQueue sets the guaranteed rate in Mbit/s of the traffic to the pod on the queue of the pod port,
+// and warns if it exceeds the ingress rate of the pod or the guarantees of the dedicated queues oversubscribe the link
+func setDedicatedQueue(podName, podNamespace, ifaceID, minRate, maxRate, qosType, linkIface string) error {
+	if err := ovs.SetInterfaceMinRate(podName, podNamespace, ifaceID, minRate, qosType); err != nil {
+		klog.Errorf("failed to set min rate of pod %s/%s: %v", podNamespace, podName, err)
+		return err
+	}
+	if minRate == "" {
+		return nil
+	}
+
+	minRateMPS, _ := strconv.Atoi(minRate)
+	if maxRateMPS, _ := util.ParseBandwidth(maxRate, 0); maxRateMPS > 0 && minRateMPS > maxRateMPS {
+		klog.Warningf("min rate %dMbit/s of pod %s/%s exceeds its ingress rate %dMbit/s", minRateMPS, podNamespace, podName, maxRateMPS)
+	}
+
+	total, err := ovs.GetDedicatedQueueMinRate()
+	if err != nil {
+		klog.Errorf("failed to get min rate of dedicated queues: %v", err)
+		return nil
+	}
+	speed, err := os.ReadFile(fmt.Sprintf("/sys/class/net/%s/speed", linkIface))
+	if err != nil {
+		klog.V(3).Infof("failed to get speed of link %s: %v", linkIface, err)
+		return nil
+	}
+	if speedMPS, err := strconv.Atoi(strings.TrimSpace(string(speed))); err == nil && speedMPS > 0 && total > speedMPS*1000*1000 {
+		klog.Warningf("guaranteed rates %dMbit/s of dedicated queues oversubscribe link %s with speed %dMbit/s", total/1000/1000, linkIface, speedMPS)
+	}
+	return nil
+}
+
+// setPodBandwidth sets the bandwidth of the pod port. The egress traffic of the pod exceeding the egress rate is dropped
+// by ovs ingress policing by default, or buffered by an ifb device if the egress rate mode is shaping
+func setPodBandwidth(podName, podNamespace, ifaceID, ingress, egress, priority, qosType, egressRateMode string) error {
+	shaping := egressRateMode == util.EgressRateModeShaping
+	if egressRateMode != "" && egressRateMode != util.EgressRateModePolicing && !shaping {
+		klog.Warningf("egress rate mode %s of pod %s/%s is not supported, fall back to %s", egressRateMode, podNamespace, podName, util.EgressRateModePolicing)
+	}
+
+	ifNames, err := ovs.GetInterfacesByIfaceID(ifaceID)
+	if err != nil {
+		klog.Errorf("failed to get interfaces of port %s: %v", ifaceID, err)
+		return err
+	}
+	// shaping is only supported for the veth pairs whose host end is in the host netns
+	var links []netlink.Link
+	for _, ifName := range ifNames {
+		if link, err := netlink.LinkByName(ifName); err == nil && link.Type() == "veth" {
+			links = append(links, link)
+		}
+	}
+	if shaping && len(links) == 0 {
+		klog.Warningf("egress rate mode %s is not supported by the nic of pod %s/%s, fall back to %s", egressRateMode, podNamespace, podName, util.EgressRateModePolicing)
+		shaping = false
+	}
+
+	if !shaping {
+		// remove the ingress qdisc of the ifb device before ovs adds its own for policing
+		for _, link := range links {
+			if err = clearIfbShaping(link); err != nil {
+				return err
+			}
+		}
+		return ovs.SetInterfaceBandwidth(podName, podNamespace, ifaceID, egress, ingress, priority, qosType)
+	}
+
+	// disable the ingress policing so that ovs removes its ingress qdisc before the one of the ifb device is added
+	if err = ovs.SetInterfaceBandwidth(podName, podNamespace, ifaceID, "", ingress, priority, qosType); err != nil {
+		return err
+	}
+	egressMPS, err := util.ParseBandwidth(egress, 0)
+	if err != nil {
+		return fmt.Errorf("invalid egress rate %q of pod %s/%s: %v", egress, podNamespace, podName, err)
+	}
+	for _, link := range links {
+		if egressMPS <= 0 {
+			err = clearIfbShaping(link)
+		} else {
+			err = setIfbShaping(link, uint64(egressMPS)*1000*1000)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ifbName returns the name of the ifb device shaping the traffic from the host nic of the pod
+func ifbName(hostNicName string) string {
+	return strings.TrimSuffix(hostNicName, "_h") + "_f"
+}
+
+// setIfbShaping redirects the traffic from the pod to an ifb device and shapes it with tbf, the rate is in bit/s
+func setIfbShaping(link netlink.Link, rate uint64) error {
+	name := ifbName(link.Attrs().Name)
+	ifb, err := netlink.LinkByName(name)
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); !ok {
+			return fmt.Errorf("failed to get ifb device %s: %v", name, err)
+		}
+		ifb = &netlink.Ifb{LinkAttrs: netlink.LinkAttrs{Name: name, TxQLen: 1000, MTU: link.Attrs().MTU}}
+		if err = netlink.LinkAdd(ifb); err != nil {
+			return fmt.Errorf("failed to add ifb device %s: %v", name, err)
+		}
+		if ifb, err = netlink.LinkByName(name); err != nil {
+			return fmt.Errorf("failed to get ifb device %s: %v", name, err)
+		}
+	}
+	if err = netlink.LinkSetUp(ifb); err != nil {
+		return fmt.Errorf("failed to set ifb device %s up: %v", name, err)
+	}
+
+	// buffer 25ms of traffic, with a burst of at least 10 packets
+	rateBytes := rate / 8
+	burst := uint32(rateBytes / 40)
+	if minBurst := uint32(link.Attrs().MTU * 10); burst < minBurst {
+		burst = minBurst
+	}
+	tbf := &netlink.Tbf{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: ifb.Attrs().Index,
+			Handle:    netlink.MakeHandle(1, 0),
+			Parent:    netlink.HANDLE_ROOT,
+		},
+		Rate:   rateBytes,
+		Limit:  uint32(rateBytes/40) + burst,
+		Buffer: netlink.Xmittime(rateBytes, burst),
+	}
+	if err = netlink.QdiscReplace(tbf); err != nil {
+		return fmt.Errorf("failed to set tbf qdisc on ifb device %s: %v", name, err)
+	}
+
+	ingress := &netlink.Ingress{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_INGRESS,
+		},
+	}
+	if err = netlink.QdiscReplace(ingress); err != nil {
+		return fmt.Errorf("failed to set ingress qdisc on %s: %v", link.Attrs().Name, err)
+	}
+	filter := &netlink.U32{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    netlink.MakeHandle(0xffff, 0),
+			Priority:  1,
+			Protocol:  syscall.ETH_P_ALL,
+		},
+		Sel: &netlink.TcU32Sel{
+			Nkeys: 1,
+			Flags: netlink.TC_U32_TERMINAL,
+			Keys:  []netlink.TcU32Key{{}},
+		},
+		Actions: []netlink.Action{netlink.NewMirredAction(ifb.Attrs().Index)},
+	}
+	if err = netlink.FilterReplace(filter); err != nil {
+		return fmt.Errorf("failed to redirect traffic from %s to ifb device %s: %v", link.Attrs().Name, name, err)
+	}
+	return nil
+}
+
+// clearIfbShaping removes the ifb device of the host nic of the pod and the ingress qdisc redirecting traffic to it
+func clearIfbShaping(link netlink.Link) error {
+	name := ifbName(link.Attrs().Name)
+	ifb, err := netlink.LinkByName(name)
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			return nil
+		}
+		return fmt.Errorf("failed to get ifb device %s: %v", name, err)
+	}
+
+	ingress := &netlink.Ingress{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_INGRESS,
+		},
+	}
+	if err = netlink.QdiscDel(ingress); err != nil && !errors.Is(err, syscall.ENOENT) && !errors.Is(err, syscall.EINVAL) {
+		return fmt.Errorf("failed to delete ingress qdisc on %s: %v", link.Attrs().Name, err)
+	}
+	if err = netlink.LinkDel(ifb); err != nil {
+		return fmt.Errorf("failed to delete ifb device %s: %v", name, err)
+	}
+	return nil
+}
+
 func configureHostNic(nicName string) error {
 	hostLink, err := netlink.LinkByName(nicName)
 	if err != nil {
@@ -206,7 +562,7 @@ func configureHostNic(nicName string) error {
 	return nil
 }
 
-func configureContainerNic(nicName, ifName string, ipAddr, gateway string, isDefaultRoute bool, routes []request.Route, macAddr net.HardwareAddr, netns ns.NetNS, mtu int, nicType string, gwCheckMode int) error {
+func configureContainerNic(nicName, ifName string, ipAddr, gateway, gatewayMac string, isDefaultRoute bool, routes []request.Route, macAddr net.HardwareAddr, netns ns.NetNS, mtu int, nicType string, gwCheckMode, gwCheckPort, gwCheckMaxRetry int, gwCheckTimeout time.Duration, gwCheckMtuSize int) error {
 	containerLink, err := netlink.LinkByName(nicName)
 	if err != nil {
 		return fmt.Errorf("can not find container nic %s: %v", nicName, err)
@@ -225,6 +581,13 @@ func configureContainerNic(nicName, ifName string, ipAddr, gateway string, isDef
 	return ns.WithNetNSPath(netns.Path(), func(_ ns.NetNS) error {
 
 		if nicType != util.InternalType {
+			// the pod may already have an interface with the expected name, e.g. a secondary one
+			// configured by another plugin, renaming to it would fail with an obscure error
+			if _, err = netlink.LinkByName(ifName); err == nil {
+				return fmt.Errorf("interface %s already exists in the pod", ifName)
+			} else if _, ok := err.(netlink.LinkNotFoundError); !ok {
+				return fmt.Errorf("failed to get link %s: %v", ifName, err)
+			}
 			if err = netlink.LinkSetName(containerLink, ifName); err != nil {
 				return err
 			}
@@ -271,6 +634,7 @@ func configureContainerNic(nicName, ifName string, ipAddr, gateway string, isDef
 					Scope:     netlink.SCOPE_UNIVERSE,
 					Dst:       defaultNet,
 					Gw:        net.ParseIP(gateway),
+					Flags:     gatewayRouteFlags(ipAddr, gateway),
 				})
 			case kubeovnv1.ProtocolIPv6:
 				_, defaultNet, _ := net.ParseCIDR("::/0")
@@ -281,25 +645,22 @@ func configureContainerNic(nicName, ifName string, ipAddr, gateway string, isDef
 					Gw:        net.ParseIP(gateway),
 				})
 			case kubeovnv1.ProtocolDual:
-				gws := strings.Split(gateway, ",")
-				_, defaultNet, _ := net.ParseCIDR("0.0.0.0/0")
-				err = netlink.RouteReplace(&netlink.Route{
-					LinkIndex: containerLink.Attrs().Index,
-					Scope:     netlink.SCOPE_UNIVERSE,
-					Dst:       defaultNet,
-					Gw:        net.ParseIP(gws[0]),
-				})
-				if err != nil {
-					return fmt.Errorf("config v4 gateway failed: %v", err)
+				// the gateways are ordered by the preferred ip family
+				for _, gw := range strings.Split(gateway, ",") {
+					_, defaultNet, _ := net.ParseCIDR("0.0.0.0/0")
+					if util.CheckProtocol(gw) == kubeovnv1.ProtocolIPv6 {
+						_, defaultNet, _ = net.ParseCIDR("::/0")
+					}
+					if err = netlink.RouteReplace(&netlink.Route{
+						LinkIndex: containerLink.Attrs().Index,
+						Scope:     netlink.SCOPE_UNIVERSE,
+						Dst:       defaultNet,
+						Gw:        net.ParseIP(gw),
+						Flags:     gatewayRouteFlags(ipAddr, gw),
+					}); err != nil {
+						return fmt.Errorf("config %s gateway failed: %v", util.CheckProtocol(gw), err)
+					}
 				}
-
-				_, defaultNet, _ = net.ParseCIDR("::/0")
-				err = netlink.RouteReplace(&netlink.Route{
-					LinkIndex: containerLink.Attrs().Index,
-					Scope:     netlink.SCOPE_UNIVERSE,
-					Dst:       defaultNet,
-					Gw:        net.ParseIP(gws[1]),
-				})
 			}
 
 			if err != nil {
@@ -332,25 +693,128 @@ func configureContainerNic(nicName, ifName string, ipAddr, gateway string, isDef
 			}
 		}
 
+		if gatewayMac != "" && gateway != "" {
+			if err = addStaticGatewayNeigh(containerLink.Attrs().Index, gateway, gatewayMac); err != nil {
+				return err
+			}
+		}
+
 		if gwCheckMode != gatewayModeDisabled {
 			underlayGateway := gwCheckMode == gatewayCheckModeArping
+			if gwCheckMode != gatewayCheckModeTCP {
+				gwCheckPort = 0
+			}
 			if nicType != util.InternalType {
-				return waitNetworkReady(ifName, ipAddr, gateway, underlayGateway, true)
+				return waitNetworkReady(ifName, ipAddr, gateway, underlayGateway, true, gwCheckMaxRetry, gwCheckTimeout, gwCheckPort, gwCheckMtuSize)
 			}
-			return waitNetworkReady(nicName, ipAddr, gateway, underlayGateway, true)
+			return waitNetworkReady(nicName, ipAddr, gateway, underlayGateway, true, gwCheckMaxRetry, gwCheckTimeout, gwCheckPort, gwCheckMtuSize)
 		}
 
 		return nil
 	})
 }
 
-func waitNetworkReady(nic, ipAddr, gateway string, underlayGateway, verbose bool) error {
+// reconfigureContainerNic replaces the addresses of the nic with the mac in the netns,
+// and the default routes if the nic is the default route interface
+func reconfigureContainerNic(netns string, macAddr net.HardwareAddr, ipAddr, gateway string) error {
+	return ns.WithNetNSPath(netns, func(_ ns.NetNS) error {
+		links, err := netlink.LinkList()
+		if err != nil {
+			return fmt.Errorf("failed to list links: %v", err)
+		}
+		var link netlink.Link
+		for _, l := range links {
+			if l.Attrs().HardwareAddr.String() == macAddr.String() {
+				link = l
+				break
+			}
+		}
+		if link == nil {
+			return fmt.Errorf("no nic with mac %s found in netns %s", macAddr.String(), netns)
+		}
+
+		// the default routes are removed along with the old addresses
+		routes, err := netlink.RouteList(link, netlink.FAMILY_ALL)
+		if err != nil {
+			return fmt.Errorf("failed to list routes of nic %s: %v", link.Attrs().Name, err)
+		}
+		var isDefaultRoute bool
+		for _, route := range routes {
+			if route.Gw != nil && (route.Dst == nil || route.Dst.IP.IsUnspecified()) {
+				isDefaultRoute = true
+				break
+			}
+		}
+
+		if err = configureNic(link.Attrs().Name, ipAddr, macAddr, 0); err != nil {
+			return err
+		}
+		if !isDefaultRoute {
+			return nil
+		}
+		for _, gw := range strings.Split(gateway, ",") {
+			_, defaultNet, _ := net.ParseCIDR("0.0.0.0/0")
+			if util.CheckProtocol(gw) == kubeovnv1.ProtocolIPv6 {
+				_, defaultNet, _ = net.ParseCIDR("::/0")
+			}
+			if err = netlink.RouteReplace(&netlink.Route{
+				LinkIndex: link.Attrs().Index,
+				Scope:     netlink.SCOPE_UNIVERSE,
+				Dst:       defaultNet,
+				Gw:        net.ParseIP(gw),
+			}); err != nil {
+				return fmt.Errorf("failed to configure gateway %s: %v", gw, err)
+			}
+		}
+		return nil
+	})
+}
+
+// addStaticGatewayNeigh adds permanent neighbor entries of the gateways, so that the gateway mac
+// is not resolved by arp or ndp, which is required by appliances not replying to them
+func addStaticGatewayNeigh(linkIndex int, gateway, gatewayMac string) error {
+	mac, err := net.ParseMAC(gatewayMac)
+	if err != nil {
+		return fmt.Errorf("failed to parse gateway mac %s: %v", gatewayMac, err)
+	}
+	for _, gw := range strings.Split(gateway, ",") {
+		ip := net.ParseIP(gw)
+		if ip == nil {
+			return fmt.Errorf("invalid gateway %s", gw)
+		}
+		family := netlink.FAMILY_V4
+		if ip.To4() == nil {
+			family = netlink.FAMILY_V6
+		}
+		neigh := &netlink.Neigh{
+			LinkIndex:    linkIndex,
+			Family:       family,
+			State:        netlink.NUD_PERMANENT,
+			IP:           ip,
+			HardwareAddr: mac,
+		}
+		if err = netlink.NeighSet(neigh); err != nil {
+			return fmt.Errorf("failed to add static neighbor %s lladdr %s: %v", gw, gatewayMac, err)
+		}
+		klog.Infof("add static neighbor %s lladdr %s for gateway", gw, gatewayMac)
+	}
+	return nil
+}
+
+// waitNetworkReady checks the gateway by tcp connection if tcpPort is not zero, otherwise by arping or ping.
+// When the gateway is checked by ping and mtuCheckSize is not negative, the gateway is pinged by a large packet
+// as well, zero mtuCheckSize means the largest packet fitting in the MTU of the nic
+func waitNetworkReady(nic, ipAddr, gateway string, underlayGateway, verbose bool, maxRetry int, timeout time.Duration, tcpPort, mtuCheckSize int) error {
 	ips := strings.Split(ipAddr, ",")
 	for i, gw := range strings.Split(gateway, ",") {
 		src := strings.Split(ips[i], "/")[0]
-		if underlayGateway && util.CheckProtocol(gw) == kubeovnv1.ProtocolIPv4 {
-			mac, count, err := util.Arping(nic, src, gw, time.Second, gatewayCheckMaxRetry)
-			cniConnectivityResult.WithLabelValues(nodeName).Add(float64(count))
+		if tcpPort != 0 {
+			if err := tcpConnectGateway(gw, src, tcpPort, verbose, maxRetry, timeout); err != nil {
+				return err
+			}
+		} else if underlayGateway && util.CheckProtocol(gw) == kubeovnv1.ProtocolIPv4 {
+			mac, count, err := util.Arping(nic, src, gw, timeout, maxRetry)
+			cniConnectivityResult.WithLabelValues(nodeName, gatewayCheckMethodArping).Add(float64(count))
 			if err != nil {
 				err = fmt.Errorf("network %s with gateway %s is not ready for interface %s after %d checks: %v", ips[i], gw, nic, count, err)
 				klog.Warning(err)
@@ -361,15 +825,163 @@ func waitNetworkReady(nic, ipAddr, gateway string, underlayGateway, verbose bool
 				klog.Infof("network %s with gateway %s is ready for interface %s after %d checks", ips[i], gw, nic, count)
 			}
 		} else {
-			if err := pingGateway(gw, src, verbose); err != nil {
+			if err := pingGateway(gw, src, verbose, maxRetry, timeout); err != nil {
 				return err
 			}
+			if mtuCheckSize >= 0 {
+				if err := checkGatewayMtu(nic, gw, src, mtuCheckSize, timeout); err != nil {
+					return err
+				}
+			}
 		}
 	}
 	return nil
 }
 
-func configureNodeNic(portName, ip, gw string, macAddr net.HardwareAddr, mtu int) error {
+const (
+	// headers excluded from the icmp payload of the gateway mtu check
+	icmpHeaderLength = 8
+	ipv4HeaderLength = 20
+	ipv6HeaderLength = 40
+
+	gatewayMtuCheckMaxRetry = 3
+
+	// pathMtuProbeMin is the min MTU of ipv6 links, below which the path MTU is not probed
+	pathMtuProbeMin = 1280
+)
+
+// checkGatewayMtu pings the gateway by packets of the icmp payload size which must not be fragmented,
+// so the check fails rather than passes by fragments if the path MTU is smaller
+func checkGatewayMtu(nic, gw, src string, size int, timeout time.Duration) error {
+	if size == 0 {
+		link, err := netlink.LinkByName(nic)
+		if err != nil {
+			return fmt.Errorf("can not find nic %s: %v", nic, err)
+		}
+		size = link.Attrs().MTU - icmpHeaderLength
+		if util.CheckProtocol(gw) == kubeovnv1.ProtocolIPv4 {
+			size -= ipv4HeaderLength
+		} else {
+			size -= ipv6HeaderLength
+		}
+	}
+
+	// the gateway has replied to the small packets, so a few retries are enough
+	if err := pingGatewayNoFragment(gw, src, size, gatewayMtuCheckMaxRetry, timeout); err != nil {
+		err = fmt.Errorf("gateway %s replies to small packets but not to packets with %d bytes payload from %s, the path MTU may be smaller than the MTU of interface %s: %v", gw, size, src, nic, err)
+		klog.Error(err)
+		return err
+	}
+	return nil
+}
+
+// pingGatewayNoFragment pings the gateway by icmp echo requests of the payload size which must not be fragmented
+func pingGatewayNoFragment(gw, src string, size, maxRetry int, timeout time.Duration) error {
+	count, err := pingNoFragment(gw, src, size, maxRetry, timeout)
+	cniConnectivityResult.WithLabelValues(nodeName, gatewayCheckMethodPing).Add(float64(count))
+	return err
+}
+
+// pingNoFragment pings the ip by icmp echo requests of the payload size from a socket with path MTU discovery
+// enforced, which sets the DF bit of ipv4 packets and fails to send the packets exceeding the known path MTU.
+// It returns the number of the requests sent
+func pingNoFragment(ip, src string, size, maxRetry int, timeout time.Duration) (int, error) {
+	network, level, opt := "ip4:icmp", unix.IPPROTO_IP, unix.IP_MTU_DISCOVER
+	value, proto := unix.IP_PMTUDISC_DO, unix.IPPROTO_ICMP
+	var echoType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if util.CheckProtocol(ip) == kubeovnv1.ProtocolIPv6 {
+		network, level, opt = "ip6:ipv6-icmp", unix.IPPROTO_IPV6, unix.IPV6_MTU_DISCOVER
+		value, proto = unix.IPV6_PMTUDISC_DO, unix.IPPROTO_ICMPV6
+		echoType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	lc := net.ListenConfig{Control: func(_, _ string, c syscall.RawConn) error {
+		var err error
+		if cerr := c.Control(func(fd uintptr) {
+			err = unix.SetsockoptInt(int(fd), level, opt, value)
+		}); cerr != nil {
+			return cerr
+		}
+		return err
+	}}
+	conn, err := lc.ListenPacket(context.Background(), network, src)
+	if err != nil {
+		return 0, fmt.Errorf("failed to listen icmp on %s: %v", src, err)
+	}
+	defer conn.Close()
+
+	var count int
+	dst := &net.IPAddr{IP: net.ParseIP(ip)}
+	id, data, buf := os.Getpid()&0xffff, bytes.Repeat([]byte{1}, size), make([]byte, 65536)
+	for count < maxRetry {
+		count++
+		msg := icmp.Message{Type: echoType, Body: &icmp.Echo{ID: id, Seq: count, Data: data}}
+		// the checksum of icmpv6 is calculated by the kernel
+		b, err := msg.Marshal(nil)
+		if err != nil {
+			return count, fmt.Errorf("failed to marshal icmp echo request: %v", err)
+		}
+		if _, err = conn.WriteTo(b, dst); err != nil {
+			if errors.Is(err, unix.EMSGSIZE) {
+				return count, fmt.Errorf("packets with %d bytes payload exceed the path MTU to %s: %v", size, ip, err)
+			}
+			klog.Warningf("failed to send icmp echo request to %s: %v", ip, err)
+			time.Sleep(timeout)
+			continue
+		}
+
+		if err = conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return count, fmt.Errorf("failed to set read deadline: %v", err)
+		}
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				break
+			}
+			reply, err := icmp.ParseMessage(proto, buf[:n])
+			if err != nil || reply.Type != replyType || peer.String() != dst.String() {
+				continue
+			}
+			if echo, ok := reply.Body.(*icmp.Echo); ok && echo.ID == id && echo.Seq == count {
+				return count, nil
+			}
+		}
+	}
+	return count, fmt.Errorf("%s got no reply of %d icmp echo requests from %s", src, count, ip)
+}
+
+// probePathMtu finds the path MTU to the ip by binary search of the largest packets replied without fragmentation,
+// which is bounded by the MTU of the link the ip is routed through
+func probePathMtu(ip string, timeout time.Duration) (int, error) {
+	routes, err := netlink.RouteGet(net.ParseIP(ip))
+	if err != nil || len(routes) == 0 {
+		return 0, fmt.Errorf("failed to get the route to %s: %v", ip, err)
+	}
+	link, err := netlink.LinkByIndex(routes[0].LinkIndex)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the link of the route to %s: %v", ip, err)
+	}
+
+	headerLength := icmpHeaderLength + ipv4HeaderLength
+	if util.CheckProtocol(ip) == kubeovnv1.ProtocolIPv6 {
+		headerLength = icmpHeaderLength + ipv6HeaderLength
+	}
+	low, high := pathMtuProbeMin, link.Attrs().MTU
+	if _, err = pingNoFragment(ip, "", low-headerLength, 1, timeout); err != nil {
+		return 0, err
+	}
+	for low < high {
+		mid := (low + high + 1) / 2
+		if _, err = pingNoFragment(ip, "", mid-headerLength, 1, timeout); err == nil {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return low, nil
+}
+
+func configureNodeNic(portName, ip, gw string, routes []request.Route, macAddr net.HardwareAddr, mtu, gwCheckMaxRetry int, gwCheckTimeout time.Duration) error {
 	ipStr := util.GetIpWithoutMask(ip)
 	raw, err := ovs.Exec(ovs.MayExist, "add-port", "br-int", util.NodeNic, "--",
 		"set", "interface", util.NodeNic, "type=internal", "--",
@@ -393,9 +1005,14 @@ func configureNodeNic(portName, ip, gw string, macAddr net.HardwareAddr, mtu int
 		return fmt.Errorf("can not set host nic %s qlen: %v", util.NodeNic, err)
 	}
 
+	if err = setNodeNicRoutes(routes, gw); err != nil {
+		klog.Errorf("failed to set routes of %s: %v", util.NodeNic, err)
+		return err
+	}
+
 	// ping ovn0 gw to activate the flow
 	klog.Infof("wait ovn0 gw ready")
-	if err := waitNetworkReady(util.NodeNic, ip, gw, false, true); err != nil {
+	if err := waitNetworkReady(util.NodeNic, ip, gw, false, true, gwCheckMaxRetry, gwCheckTimeout, 0, -1); err != nil {
 		klog.Errorf("failed to init ovn0 check: %v", err)
 		return err
 	}
@@ -404,15 +1021,17 @@ func configureNodeNic(portName, ip, gw string, macAddr net.HardwareAddr, mtu int
 }
 
 // If OVS restart, the ovn0 port will down and prevent host to pod network,
-// Restart the kube-ovn-cni when this happens
+// Restart the kube-ovn-cni or repair ovn0 in place when this happens
 func (c *Controller) loopOvn0Check() {
 	link, err := netlink.LinkByName(util.NodeNic)
 	if err != nil {
-		util.LogFatalAndExit(err, "failed to get ovn0 nic")
+		c.recoverOvn0(fmt.Errorf("failed to get ovn0 nic: %v", err))
+		return
 	}
 
 	if link.Attrs().OperState == netlink.OperDown {
-		util.LogFatalAndExit(err, "ovn0 nic is down")
+		c.recoverOvn0(errors.New("ovn0 nic is down"))
+		return
 	}
 
 	node, err := c.nodesLister.Get(c.config.NodeName)
@@ -422,9 +1041,130 @@ func (c *Controller) loopOvn0Check() {
 	}
 	ip := node.Annotations[util.IpAddressAnnotation]
 	gw := node.Annotations[util.GatewayAnnotation]
-	if err := waitNetworkReady(util.NodeNic, ip, gw, false, false); err != nil {
-		util.LogFatalAndExit(err, "failed to ping ovn0 gateway %s", gw)
+	if err := waitNetworkReady(util.NodeNic, ip, gw, false, false, c.config.GatewayCheckMaxRetry, c.config.GatewayCheckTimeout, 0, -1); err != nil {
+		c.recoverOvn0(fmt.Errorf("failed to ping ovn0 gateway %s: %v", gw, err))
+		return
+	}
+	c.ovn0RepairFailures = 0
+
+	routes, err := util.ParseNodeNicRoutes(node.Annotations[util.NodeNicRoutesAnnotation])
+	if err != nil {
+		klog.Errorf("failed to parse annotation %s of node %s: %v", util.NodeNicRoutesAnnotation, node.Name, err)
+		return
+	}
+	if err = setNodeNicRoutes(routes, gw); err != nil {
+		klog.Errorf("failed to set routes of %s: %v", util.NodeNic, err)
+	}
+}
+
+// recoverOvn0 exits to restart kube-ovn-cni or repairs ovn0 in place according to the recovery policy, the process
+// still exits if the repair fails for ovn0-repair-max-failures consecutive times
+func (c *Controller) recoverOvn0(reason error) {
+	if c.config.Ovn0RecoveryPolicy != ovn0RecoveryRepair {
+		util.LogFatalAndExit(reason, "ovn0 is unhealthy")
+	}
+
+	klog.Warningf("repairing ovn0 in place: %v", reason)
+	if err := c.repairOvn0(); err != nil {
+		c.ovn0RepairFailures++
+		if c.ovn0RepairFailures >= c.config.Ovn0RepairMaxFailures {
+			util.LogFatalAndExit(err, "failed to repair ovn0 for %d times, restart to recover it", c.ovn0RepairFailures)
+		}
+		klog.Errorf("failed to repair ovn0 for %d times: %v", c.ovn0RepairFailures, err)
+		return
+	}
+	c.ovn0RepairFailures = 0
+	klog.Infof("ovn0 is repaired")
+	// restore the routes of subnets through ovn0
+	c.subnetQueue.Add(subnetEvent{})
+}
+
+// repairOvn0 configures the ovn0 port, addresses and routes again by the node annotations like InitNodeGateway,
+// each step of which keeps the existing configurations unchanged, so the repair can be retried safely
+func (c *Controller) repairOvn0() error {
+	node, err := c.nodesLister.Get(c.config.NodeName)
+	if err != nil {
+		klog.Errorf("failed to get node %s: %v", c.config.NodeName, err)
+		return err
+	}
+	if err = util.ValidatePodNetwork(node.Annotations); err != nil {
+		klog.Errorf("validate node %s address annotation failed, %v", node.Name, err)
+		return err
+	}
+	mac, err := net.ParseMAC(node.Annotations[util.MacAddressAnnotation])
+	if err != nil {
+		return fmt.Errorf("failed to parse mac %s %v", node.Annotations[util.MacAddressAnnotation], err)
+	}
+	routes, err := util.ParseNodeNicRoutes(node.Annotations[util.NodeNicRoutesAnnotation])
+	if err != nil {
+		klog.Errorf("failed to parse annotation %s of node %s, ignore the custom routes: %v", util.NodeNicRoutesAnnotation, node.Name, err)
+	}
+
+	ip := util.GetIpAddrWithMask(node.Annotations[util.IpAddressAnnotation], node.Annotations[util.CidrAnnotation])
+	return configureNodeNic(node.Annotations[util.PortNameAnnotation], ip, node.Annotations[util.GatewayAnnotation], routes, mac,
+		c.config.MTU, c.config.GatewayCheckMaxRetry, c.config.GatewayCheckTimeout)
+}
+
+// nodeNicRouteProtocol marks the custom routes on ovn0 to tell them from the routes of subnets
+const nodeNicRouteProtocol netlink.RouteProtocol = 0x4b
+
+// setNodeNicRoutes reconciles the custom routes on ovn0, the gateway of ovn0 is used for the routes without gateway
+func setNodeNicRoutes(routes []request.Route, gw string) error {
+	link, err := netlink.LinkByName(util.NodeNic)
+	if err != nil {
+		return fmt.Errorf("can not find nic %s: %v", util.NodeNic, err)
+	}
+
+	existingRoutes, err := netlink.RouteListFiltered(netlink.FAMILY_ALL, &netlink.Route{
+		LinkIndex: link.Attrs().Index,
+		Protocol:  nodeNicRouteProtocol,
+	}, netlink.RT_FILTER_OIF|netlink.RT_FILTER_PROTOCOL)
+	if err != nil {
+		return fmt.Errorf("failed to list routes of %s: %v", util.NodeNic, err)
+	}
+
+	desired := make(map[string]bool, len(routes))
+	for _, r := range routes {
+		_, dst, err := net.ParseCIDR(r.Destination)
+		if err != nil {
+			return fmt.Errorf("invalid route destination %s: %v", r.Destination, err)
+		}
+		nextHop := r.Gateway
+		if nextHop == "" {
+			protocol := util.CheckProtocol(r.Destination)
+			for _, g := range strings.Split(gw, ",") {
+				if util.CheckProtocol(g) == protocol {
+					nextHop = g
+					break
+				}
+			}
+			if nextHop == "" {
+				return fmt.Errorf("no %s gateway of %s for route %s", protocol, util.NodeNic, r.Destination)
+			}
+		}
+		route := netlink.Route{
+			LinkIndex: link.Attrs().Index,
+			Scope:     netlink.SCOPE_UNIVERSE,
+			Dst:       dst,
+			Gw:        net.ParseIP(nextHop),
+			Protocol:  nodeNicRouteProtocol,
+		}
+		desired[route.Dst.String()+"@"+route.Gw.String()] = true
+		if err = netlink.RouteReplace(&route); err != nil {
+			return fmt.Errorf("failed to replace route %s via %s on %s: %v", r.Destination, nextHop, util.NodeNic, err)
+		}
+	}
+
+	for _, route := range existingRoutes {
+		if route.Dst == nil || desired[route.Dst.String()+"@"+route.Gw.String()] {
+			continue
+		}
+		klog.Infof("delete route %s via %s on %s", route.Dst, route.Gw, util.NodeNic)
+		if err = netlink.RouteDel(&route); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return fmt.Errorf("failed to delete route %s via %s on %s: %v", route.Dst, route.Gw, util.NodeNic, err)
+		}
 	}
+	return nil
 }
 
 func configureMirrorLink(portName string, mtu int) error {
@@ -509,9 +1249,45 @@ func configureNic(link, ip string, macAddr net.HardwareAddr, mtu int) error {
 	return nil
 }
 
+// isPreservedRoute returns whether the route should be kept on the link rather than transferred
+// between the host nic and the external bridge
+func isPreservedRoute(route netlink.Route, preservedRoutes []*net.IPNet) bool {
+	if route.Gw == nil && route.Dst != nil && route.Dst.IP.IsLinkLocalUnicast() {
+		// skip 169.254.0.0/16 and fe80::/10
+		return true
+	}
+	if route.Dst == nil {
+		return false
+	}
+	ones, bits := route.Dst.Mask.Size()
+	for _, prefix := range preservedRoutes {
+		prefixOnes, prefixBits := prefix.Mask.Size()
+		if prefixBits == bits && prefixOnes <= ones && prefix.Contains(route.Dst.IP) {
+			return true
+		}
+	}
+	return false
+}
+
 // Add host nic to external bridge
-// Mac address, MTU, IP addresses & routes will be copied/transferred to the external bridge
-func configProviderNic(nicName, brName string) (int, error) {
+// Mac address, MTU, IP addresses & routes will be copied/transferred to the external bridge,
+// except the link-local routes and the routes within preservedRoutes
+func configProviderNic(nicName, brName string, preservedRoutes []*net.IPNet) (int, error) {
+	mtu, err := configProviderBridge(nicName, brName)
+	if err != nil {
+		return 0, err
+	}
+	if err = migrateProviderNicRoutes(nicName, brName, preservedRoutes); err != nil {
+		return 0, err
+	}
+	if err = attachProviderNic(nicName, brName); err != nil {
+		return 0, err
+	}
+	return mtu, nil
+}
+
+// configProviderBridge copies the mac address and MTU of the host nic to the external bridge and sets it up
+func configProviderBridge(nicName, brName string) (int, error) {
 	nic, err := netlink.LinkByName(nicName)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get nic by name %s: %v", nicName, err)
@@ -532,13 +1308,49 @@ func configProviderNic(nicName, brName string) (int, error) {
 		}
 	}
 
+	// keep mac address the same with the provider nic,
+	// unless the provider nic is a bond in mode 6, or a vlan interface of a bond in mode 6
+	albBond, err := linkIsAlbBond(nic)
+	if err != nil {
+		return 0, err
+	}
+	if !albBond {
+		if _, err = ovs.Exec("set", "bridge", brName, fmt.Sprintf(`other-config:hwaddr="%s"`, nic.Attrs().HardwareAddr.String())); err != nil {
+			return 0, fmt.Errorf("failed to set MAC address of OVS bridge %s: %v", brName, err)
+		}
+	}
+
+	if err = netlink.LinkSetMTU(bridge, nic.Attrs().MTU); err != nil {
+		return 0, fmt.Errorf("failed to set MTU of OVS bridge %s: %v", brName, err)
+	}
+	if err = netlink.LinkSetUp(bridge); err != nil {
+		return 0, fmt.Errorf("failed to set OVS bridge %s up: %v", brName, err)
+	}
+
+	return nic.Attrs().MTU, nil
+}
+
+// migrateProviderNicRoutes transfers the IP addresses & routes of the host nic to the external bridge,
+// except the link-local routes and the routes within preservedRoutes. An address is added to the bridge
+// before it's deleted from the nic, so it's never lost if the transfer is interrupted, and the addresses
+// and routes already transferred are skipped by the retry as they're no longer on the nic
+func migrateProviderNicRoutes(nicName, brName string, preservedRoutes []*net.IPNet) error {
+	nic, err := netlink.LinkByName(nicName)
+	if err != nil {
+		return fmt.Errorf("failed to get nic by name %s: %v", nicName, err)
+	}
+	bridge, err := netlink.LinkByName(brName)
+	if err != nil {
+		return fmt.Errorf("failed to get bridge by name %s: %v", brName, err)
+	}
+
 	addrs, err := netlink.AddrList(nic, netlink.FAMILY_ALL)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get addresses on nic %s: %v", nicName, err)
+		return fmt.Errorf("failed to get addresses on nic %s: %v", nicName, err)
 	}
 	routes, err := netlink.RouteList(nic, netlink.FAMILY_ALL)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get routes on nic %s: %v", nicName, err)
+		return fmt.Errorf("failed to get routes on nic %s: %v", nicName, err)
 	}
 
 	for _, addr := range addrs {
@@ -547,6 +1359,14 @@ func configProviderNic(nicName, brName string) (int, error) {
 			continue
 		}
 
+		brAddr := addr
+		if brAddr.Label != "" {
+			brAddr.Label = brName + strings.TrimPrefix(addr.Label, nicName)
+		}
+		if err = netlink.AddrReplace(bridge, &brAddr); err != nil {
+			return fmt.Errorf("failed to replace address %s on OVS bridge %s: %v", brAddr.String(), brName, err)
+		}
+
 		if err = netlink.AddrDel(nic, &addr); err != nil {
 			errMsg := fmt.Errorf("failed to delete address %s on nic %s: %v", addr.String(), nicName, err)
 			if errors.Is(err, syscall.EADDRNOTAVAIL) {
@@ -554,61 +1374,43 @@ func configProviderNic(nicName, brName string) (int, error) {
 				klog.Warning(errMsg)
 				continue
 			}
-			return 0, errMsg
-		}
-
-		if addr.Label != "" {
-			addr.Label = brName + strings.TrimPrefix(addr.Label, nicName)
-		}
-		if err = netlink.AddrReplace(bridge, &addr); err != nil {
-			return 0, fmt.Errorf("failed to replace address %s on OVS bridge %s: %v", addr.String(), brName, err)
-		}
-	}
-
-	// keep mac address the same with the provider nic,
-	// unless the provider nic is a bond in mode 6, or a vlan interface of a bond in mode 6
-	albBond, err := linkIsAlbBond(nic)
-	if err != nil {
-		return 0, err
-	}
-	if !albBond {
-		if _, err = ovs.Exec("set", "bridge", brName, fmt.Sprintf(`other-config:hwaddr="%s"`, nic.Attrs().HardwareAddr.String())); err != nil {
-			return 0, fmt.Errorf("failed to set MAC address of OVS bridge %s: %v", brName, err)
+			return errMsg
 		}
 	}
 
-	if err = netlink.LinkSetMTU(bridge, nic.Attrs().MTU); err != nil {
-		return 0, fmt.Errorf("failed to set MTU of OVS bridge %s: %v", brName, err)
-	}
-	if err = netlink.LinkSetUp(bridge); err != nil {
-		return 0, fmt.Errorf("failed to set OVS bridge %s up: %v", brName, err)
-	}
-
 	for _, scope := range routeScopeOrders {
 		for _, route := range routes {
-			if route.Gw == nil && route.Dst != nil && route.Dst.IP.IsLinkLocalUnicast() {
-				// skip 169.254.0.0/16 and fe80::/10
+			if isPreservedRoute(route, preservedRoutes) {
 				continue
 			}
 			if route.Scope == scope {
 				route.LinkIndex = bridge.Attrs().Index
 				if err = netlink.RouteReplace(&route); err != nil {
-					return 0, fmt.Errorf("failed to add/replace route %s: %v", route.String(), err)
+					return fmt.Errorf("failed to add/replace route %s: %v", route.String(), err)
 				}
 			}
 		}
 	}
 
-	if _, err = ovs.Exec(ovs.MayExist, "add-port", brName, nicName,
+	return nil
+}
+
+// attachProviderNic adds the host nic to the external bridge and sets it up
+func attachProviderNic(nicName, brName string) error {
+	if _, err := ovs.Exec(ovs.MayExist, "add-port", brName, nicName,
 		"--", "set", "port", nicName, "external_ids:vendor="+util.CniTypeName); err != nil {
-		return 0, fmt.Errorf("failed to add %s to OVS bridge %s: %v", nicName, brName, err)
+		return fmt.Errorf("failed to add %s to OVS bridge %s: %v", nicName, brName, err)
 	}
 
+	nic, err := netlink.LinkByName(nicName)
+	if err != nil {
+		return fmt.Errorf("failed to get nic by name %s: %v", nicName, err)
+	}
 	if err = netlink.LinkSetUp(nic); err != nil {
-		return 0, fmt.Errorf("failed to set link %s up: %v", nicName, err)
+		return fmt.Errorf("failed to set link %s up: %v", nicName, err)
 	}
 
-	return nic.Attrs().MTU, nil
+	return nil
 }
 
 func linkIsAlbBond(link netlink.Link) (bool, error) {
@@ -635,8 +1437,9 @@ func linkIsAlbBond(link netlink.Link) (bool, error) {
 }
 
 // Remove host nic from external bridge
-// IP addresses & routes will be transferred to the host nic
-func removeProviderNic(nicName, brName string) error {
+// IP addresses & routes will be transferred to the host nic,
+// except the link-local routes and the routes within preservedRoutes
+func removeProviderNic(nicName, brName string, preservedRoutes []*net.IPNet) error {
 	nic, err := netlink.LinkByName(nicName)
 	if err != nil {
 		if _, ok := err.(netlink.LinkNotFoundError); ok {
@@ -699,8 +1502,7 @@ func removeProviderNic(nicName, brName string) error {
 	}
 	for _, scope := range scopeOrders {
 		for _, route := range routes {
-			if route.Gw == nil && route.Dst != nil && route.Dst.IP.IsLinkLocalUnicast() {
-				// skip 169.254.0.0/16 and fe80::/10
+			if isPreservedRoute(route, preservedRoutes) {
 				continue
 			}
 			if route.Scope == scope {
@@ -843,11 +1645,16 @@ func renameLink(curName, newName string) error {
 	return nil
 }
 
-func (csh cniServerHandler) configureNicWithInternalPort(podName, podNamespace, provider, netns, containerID, ifName, mac string, mtu int, ip, gateway string, isDefaultRoute bool, routes []request.Route, dnsServer, dnsSuffix []string, ingress, egress, priority, DeviceID, nicType, latency, limit, loss string, gwCheckMode int) (string, error) {
+func (csh cniServerHandler) configureNicWithInternalPort(podName, podNamespace, provider, netns, containerID, ifName, podIfName, mac string, mtu int, ip, gateway string, isDefaultRoute bool, routes []request.Route, dnsServer, dnsSuffix []string, ingress, egress, priority, qosType, minRate, egressRateMode, DeviceID, nicType, latency, limit, loss string, gwCheckMode, gwCheckPort int, gatewayMac string, externalIDs map[string]string) (string, error) {
+	if err := flushNicRemovals(generateNicName(containerID, ifName)); err != nil {
+		return "", err
+	}
+
 	_, containerNicName := generateNicName(containerID, ifName)
-	ipStr := util.GetIpWithoutMask(ip)
+	// the ips may be ordered by the preferred ip family, record them in the order of the annotation
+	ipStr := util.PreferIPFamily(util.GetIpWithoutMask(ip), kubeovnv1.ProtocolIPv4)
 	ifaceID := ovs.PodNameToPortName(podName, podNamespace, provider)
-	ovs.CleanDuplicatePort(ifaceID, containerNicName)
+	ovs.CleanDuplicatePort(ifaceID, containerNicName, netns)
 
 	// Add container iface to ovs port as internal port
 	output, err := ovs.Exec(ovs.MayExist, "add-port", "br-int", containerNicName, "--",
@@ -860,6 +1667,9 @@ func (csh cniServerHandler) configureNicWithInternalPort(podName, podNamespace,
 	if err != nil {
 		return containerNicName, fmt.Errorf("add nic to ovs failed %v: %q", err, output)
 	}
+	if err = ovs.SetInterfaceCustomExternalIds(containerNicName, externalIDs); err != nil {
+		return containerNicName, err
+	}
 
 	// container nic must use same mac address from pod annotation, otherwise ovn will reject these packets by default
 	macAddr, err := net.ParseMAC(mac)
@@ -867,7 +1677,10 @@ func (csh cniServerHandler) configureNicWithInternalPort(podName, podNamespace,
 		return containerNicName, fmt.Errorf("failed to parse mac %s %v", macAddr, err)
 	}
 
-	if err = ovs.SetInterfaceBandwidth(podName, podNamespace, ifaceID, egress, ingress, priority); err != nil {
+	if err = setDedicatedQueue(podName, podNamespace, ifaceID, minRate, ingress, qosType, csh.Config.tunnelIface); err != nil {
+		return containerNicName, err
+	}
+	if err = setPodBandwidth(podName, podNamespace, ifaceID, ingress, egress, priority, qosType, egressRateMode); err != nil {
 		return containerNicName, err
 	}
 
@@ -879,7 +1692,7 @@ func (csh cniServerHandler) configureNicWithInternalPort(podName, podNamespace,
 	if err != nil {
 		return containerNicName, fmt.Errorf("failed to open netns %q: %v", netns, err)
 	}
-	if err = configureContainerNic(containerNicName, ifName, ip, gateway, isDefaultRoute, routes, macAddr, podNS, mtu, nicType, gwCheckMode); err != nil {
+	if err = configureContainerNic(containerNicName, podIfName, ip, gateway, gatewayMac, isDefaultRoute, routes, macAddr, podNS, mtu, nicType, gwCheckMode, gwCheckPort, csh.Config.GatewayCheckMaxRetry, csh.Config.GatewayCheckTimeout, csh.Config.gatewayCheckMtuSize()); err != nil {
 		return containerNicName, err
 	}
 	return containerNicName, nil
@@ -1010,3 +1823,37 @@ func turnOffNicTxChecksum(nicName string) (err error) {
 func getShortSharedDir(uid types.UID, volumeName string) string {
 	return filepath.Join(util.DefaultHostVhostuserBaseDir, string(uid), volumeName)
 }
+
+// cleanStaleVhostUserSockets removes the vhost-user sockets left in the shared dir by the crashed processes of
+// the pod, which block the new processes from creating the sockets to reattach. The sockets bound to live ports
+// of ovs-dpdk are kept
+func cleanStaleVhostUserSockets(sharedDir string) error {
+	entries, err := os.ReadDir(sharedDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read shared dir %s: %v", sharedDir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.Type()&os.ModeSocket == 0 {
+			continue
+		}
+		socket := filepath.Join(sharedDir, entry.Name())
+		inUse, err := ovs.VhostUserSocketInUse(socket)
+		if err != nil {
+			return fmt.Errorf("failed to check whether vhost-user socket %s is in use: %v", socket, err)
+		}
+		if inUse {
+			klog.V(3).Infof("vhost-user socket %s is bound to a live port", socket)
+			continue
+		}
+		if err = os.Remove(socket); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale vhost-user socket %s: %v", socket, err)
+		}
+		klog.Infof("removed stale vhost-user socket %s", socket)
+		dpdkStaleSocketCleanups.WithLabelValues(nodeName).Inc()
+	}
+	return nil
+}
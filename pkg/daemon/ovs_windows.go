@@ -18,15 +18,15 @@ import (
 	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
-func (csh cniServerHandler) configureDpdkNic(podName, podNamespace, provider, netns, containerID, ifName, mac string, mtu int, ip, gateway, ingress, egress, priority, sharedDir, socketName string) error {
+func (csh cniServerHandler) configureDpdkNic(podName, podNamespace, provider, netns, containerID, ifName, mac string, mtu int, ip, gateway, ingress, egress, priority, qosType, minRate, egressRateMode, sharedDir, socketName, queues string, externalIDs map[string]string) error {
 	return errors.New("DPDK is not supported on Windows")
 }
 
-func (csh cniServerHandler) configureNicWithInternalPort(podName, podNamespace, provider, netns, containerID, ifName, mac string, mtu int, ip, gateway string, isDefaultRoute bool, routes []request.Route, dnsServer, dnsSuffix []string, ingress, egress, priority, DeviceID, nicType, latency, limit, loss string, gwCheckMode int) (string, error) {
-	return ifName, csh.configureNic(podName, podNamespace, provider, netns, containerID, "", ifName, mac, mtu, ip, gateway, isDefaultRoute, routes, dnsServer, dnsSuffix, ingress, egress, priority, DeviceID, nicType, latency, limit, loss, gwCheckMode)
+func (csh cniServerHandler) configureNicWithInternalPort(podName, podNamespace, provider, netns, containerID, ifName, podIfName, mac string, mtu int, ip, gateway string, isDefaultRoute bool, routes []request.Route, dnsServer, dnsSuffix []string, ingress, egress, priority, qosType, minRate, egressRateMode, DeviceID, nicType, latency, limit, loss string, gwCheckMode, gwCheckPort int, gatewayMac string, externalIDs map[string]string) (string, error) {
+	return ifName, csh.configureNic(podName, podNamespace, provider, netns, containerID, "", ifName, podIfName, mac, mtu, ip, gateway, isDefaultRoute, routes, dnsServer, dnsSuffix, ingress, egress, priority, qosType, minRate, egressRateMode, DeviceID, nicType, latency, limit, loss, gwCheckMode, gwCheckPort, false, gatewayMac, externalIDs)
 }
 
-func (csh cniServerHandler) configureNic(podName, podNamespace, provider, netns, containerID, vfDriver, ifName, mac string, mtu int, ip, gateway string, isDefaultRoute bool, routes []request.Route, dnsServer, dnsSuffix []string, ingress, egress, priority, DeviceID, nicType, latency, limit, loss string, gwCheckMode int) error {
+func (csh cniServerHandler) configureNic(podName, podNamespace, provider, netns, containerID, vfDriver, ifName, podIfName, mac string, mtu int, ip, gateway string, isDefaultRoute bool, routes []request.Route, dnsServer, dnsSuffix []string, ingress, egress, priority, qosType, minRate, egressRateMode, DeviceID, nicType, latency, limit, loss string, gwCheckMode, gwCheckPort int, txChecksumOff bool, gatewayMac string, externalIDs map[string]string) error {
 	if DeviceID != "" {
 		return errors.New("SR-IOV is not supported on Windows")
 	}
@@ -113,7 +113,7 @@ func (csh cniServerHandler) configureNic(podName, podNamespace, provider, netns,
 		}
 
 		ifaceID := ovs.PodNameToPortName(podName, podNamespace, provider)
-		ovs.CleanDuplicatePort(ifaceID, epName)
+		ovs.CleanDuplicatePort(ifaceID, epName, netns)
 		output, err := ovs.Exec(ovs.MayExist, "add-port", "br-int", epName, "--",
 			"set", "interface", epName, "type=internal", "--",
 			"set", "interface", epName, fmt.Sprintf("external_ids:iface-id=%s", ifaceID),
@@ -124,7 +124,10 @@ func (csh cniServerHandler) configureNic(podName, podNamespace, provider, netns,
 			return fmt.Errorf("failed to add OVS port %s, %v: %q", epName, err, output)
 		}
 
-		if err = ovs.SetInterfaceBandwidth(podName, podNamespace, ifaceID, egress, ingress, priority); err != nil {
+		if err = ovs.SetInterfaceMinRate(podName, podNamespace, ifaceID, minRate, qosType); err != nil {
+			return err
+		}
+		if err = ovs.SetInterfaceBandwidth(podName, podNamespace, ifaceID, egress, ingress, priority, qosType); err != nil {
 			return err
 		}
 
@@ -219,6 +222,9 @@ func configureNic(name, ip string, mac net.HardwareAddr, mtu int) error {
 	return nil
 }
 
+// cleanExpiredNics removes the nics whose removal deadline has passed, the removal is never delayed on windows
+func cleanExpiredNics() {}
+
 func (csh cniServerHandler) deleteNic(podName, podNamespace, containerID, netns, deviceID, ifName, nicType string) error {
 	epName := hns.ConstructEndpointName(containerID, netns, util.HnsNetwork)[:12]
 	// remove ovs port
@@ -237,12 +243,16 @@ func generateNicName(containerID, ifname string) (string, string) {
 	return fmt.Sprintf("%s_%s_h", containerID[0:12-len(ifname)], ifname), fmt.Sprintf("%s_%s_c", containerID[0:12-len(ifname)], ifname)
 }
 
-func waitNetworkReady(nic, ipAddr, gateway string, underlayGateway, verbose bool) error {
+func waitNetworkReady(nic, ipAddr, gateway string, underlayGateway, verbose bool, maxRetry int, timeout time.Duration, tcpPort int) error {
 	ips := strings.Split(ipAddr, ",")
 	for i, gw := range strings.Split(gateway, ",") {
 		src := strings.Split(ips[i], "/")[0]
-		if !underlayGateway || util.CheckProtocol(gw) == kubeovnv1.ProtocolIPv6 {
-			if err := pingGateway(gw, src, verbose); err != nil {
+		if tcpPort != 0 {
+			if err := tcpConnectGateway(gw, src, tcpPort, verbose, maxRetry, timeout); err != nil {
+				return err
+			}
+		} else if !underlayGateway || util.CheckProtocol(gw) == kubeovnv1.ProtocolIPv6 {
+			if err := pingGateway(gw, src, verbose, maxRetry, timeout); err != nil {
 				return err
 			}
 		}
@@ -250,7 +260,11 @@ func waitNetworkReady(nic, ipAddr, gateway string, underlayGateway, verbose bool
 	return nil
 }
 
-func configureNodeNic(portName, ip, gw string, macAddr net.HardwareAddr, mtu int) error {
+func configureNodeNic(portName, ip, gw string, routes []request.Route, macAddr net.HardwareAddr, mtu, gwCheckMaxRetry int, gwCheckTimeout time.Duration) error {
+	if len(routes) != 0 {
+		klog.Warningf("custom routes of %s are not supported on Windows", util.NodeNic)
+	}
+
 	ipStr := util.GetIpWithoutMask(ip)
 	raw, err := ovs.Exec(ovs.MayExist, "add-port", "br-int", util.NodeNic, "--",
 		"set", "interface", util.NodeNic, "type=internal", "--",
@@ -267,7 +281,7 @@ func configureNodeNic(portName, ip, gw string, macAddr net.HardwareAddr, mtu int
 
 	// ping ovn0 gw to activate the flow
 	klog.Infof("wait ovn0 gw ready")
-	if err := waitNetworkReady(util.NodeNic, ip, gw, false, true); err != nil {
+	if err := waitNetworkReady(util.NodeNic, ip, gw, false, true, gwCheckMaxRetry, gwCheckTimeout, 0); err != nil {
 		klog.Errorf("failed to init ovn0 check: %v", err)
 		return err
 	}
@@ -314,16 +328,35 @@ func configureMirrorLink(portName string, mtu int) error {
 	return nil
 }
 
-func configProviderNic(nicName, brName string) (int, error) {
+func configProviderNic(nicName, brName string, preservedRoutes []*net.IPNet) (int, error) {
+	// nothing to do on Windows
+	return 0, nil
+}
+
+func configProviderBridge(nicName, brName string) (int, error) {
 	// nothing to do on Windows
 	return 0, nil
 }
 
-func removeProviderNic(nicName, brName string) error {
+func migrateProviderNicRoutes(nicName, brName string, preservedRoutes []*net.IPNet) error {
 	// nothing to do on Windows
 	return nil
 }
 
+func attachProviderNic(nicName, brName string) error {
+	// nothing to do on Windows
+	return nil
+}
+
+func removeProviderNic(nicName, brName string, preservedRoutes []*net.IPNet) error {
+	// nothing to do on Windows
+	return nil
+}
+
+func probePathMtu(ip string, timeout time.Duration) (int, error) {
+	return 0, errors.New("probing path MTU is not supported on Windows")
+}
+
 func turnOffNicTxChecksum(nicName string) error {
 	// TODO
 	return nil
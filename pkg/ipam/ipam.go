@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"k8s.io/klog/v2"
 
@@ -18,11 +19,15 @@ var (
 	ErrConflict    = errors.New("AddressConflict")
 	ErrNoAvailable = errors.New("NoAvailableAddress")
 	ErrInvalidCIDR = errors.New("CIDRInvalid")
+
+	ErrNoAvailableMac = errors.New("NoAvailableMac")
 )
 
 type IPAM struct {
 	mutex   sync.RWMutex
 	Subnets map[string]*Subnet
+	// ReleaseDelay is the quarantine period of released addresses before they are reallocated
+	ReleaseDelay time.Duration
 }
 
 type SubnetAddress struct {
@@ -119,8 +124,18 @@ func (ipam *IPAM) ReleaseAddressByPod(podName string) {
 	}
 }
 
-func (ipam *IPAM) AddOrUpdateSubnet(name, cidrStr, gw string, excludeIps []string) error {
-	excludeIps = util.ExpandExcludeIPs(excludeIps, cidrStr)
+// ReleaseAddressByNic releases the address of the nic of the pod in the subnet only
+func (ipam *IPAM) ReleaseAddressByNic(podName, nicName, subnetName string) {
+	ipam.mutex.RLock()
+	defer ipam.mutex.RUnlock()
+	if subnet, ok := ipam.Subnets[subnetName]; ok {
+		subnet.ReleaseNicAddress(podName, nicName)
+	}
+}
+
+// AddOrUpdateSubnet adds or updates the subnet, the extra IPv4 CIDRs share the same gateway and pool with the subnet CIDR
+func (ipam *IPAM) AddOrUpdateSubnet(name, cidrStr, gw string, excludeIps []string, extraV4CIDRs ...string) error {
+	excludeIps = util.ExpandExcludeIPs(excludeIps, strings.Join(append([]string{cidrStr}, extraV4CIDRs...), ","))
 
 	ipam.mutex.Lock()
 	defer ipam.mutex.Unlock()
@@ -150,20 +165,29 @@ func (ipam *IPAM) AddOrUpdateSubnet(name, cidrStr, gw string, excludeIps []strin
 		v6Gw = gw
 	}
 
+	extraCIDRs, err := parseExtraV4CIDRs(extraV4CIDRs)
+	if err != nil {
+		return err
+	}
+
 	// subnet.Spec.ExcludeIps contains both v4 and v6 addresses
 	v4ExcludeIps, v6ExcludeIps := util.SplitIpsByProtocol(excludeIps)
 
 	if subnet, ok := ipam.Subnets[name]; ok {
 		subnet.Protocol = protocol
+		subnet.ReleaseDelay = ipam.ReleaseDelay
 		if protocol == kubeovnv1.ProtocolDual || protocol == kubeovnv1.ProtocolIPv4 {
 			_, cidr, _ := net.ParseCIDR(v4cidrStr)
 			subnet.V4CIDR = cidr
+			subnet.V4ExtraCIDRs = extraCIDRs
 			subnet.V4ReservedIPList = convertExcludeIps(v4ExcludeIps)
 			firstIP, _ := util.FirstIP(v4cidrStr)
 			lastIP, _ := util.LastIP(v4cidrStr)
-			subnet.V4FreeIPList = IPRangeList{&IPRange{Start: IP(firstIP), End: IP(lastIP)}}
+			subnet.V4FreeIPList = append(IPRangeList{&IPRange{Start: IP(firstIP), End: IP(lastIP)}}, extraV4FreeIPList(extraCIDRs)...)
 			subnet.joinFreeWithReserve()
+			v4ReleasedAt := subnet.V4ReleasedAt
 			subnet.V4ReleasedIPList = IPRangeList{}
+			subnet.V4ReleasedAt = map[IP]time.Time{}
 			for nicName, ip := range subnet.V4NicToIP {
 				mac := subnet.NicToMac[nicName]
 				podName := subnet.V4IPToPod[ip]
@@ -171,6 +195,7 @@ func (ipam *IPAM) AddOrUpdateSubnet(name, cidrStr, gw string, excludeIps []strin
 					klog.Errorf("%s address not in subnet %s new cidr %s: %v", podName, name, cidrStr, err)
 				}
 			}
+			subnet.V4FreeIPList, subnet.V4ReleasedIPList = subnet.restoreQuarantinedIPs(subnet.V4FreeIPList, subnet.V4ReleasedIPList, subnet.V4ReleasedAt, v4ReleasedAt)
 		}
 		if protocol == kubeovnv1.ProtocolDual || protocol == kubeovnv1.ProtocolIPv6 {
 			_, cidr, _ := net.ParseCIDR(v6cidrStr)
@@ -180,7 +205,9 @@ func (ipam *IPAM) AddOrUpdateSubnet(name, cidrStr, gw string, excludeIps []strin
 			lastIP, _ := util.LastIP(v6cidrStr)
 			subnet.V6FreeIPList = IPRangeList{&IPRange{Start: IP(firstIP), End: IP(lastIP)}}
 			subnet.joinFreeWithReserve()
+			v6ReleasedAt := subnet.V6ReleasedAt
 			subnet.V6ReleasedIPList = IPRangeList{}
+			subnet.V6ReleasedAt = map[IP]time.Time{}
 			for nicName, ip := range subnet.V6NicToIP {
 				mac := subnet.NicToMac[nicName]
 				podName := subnet.V6IPToPod[ip]
@@ -188,21 +215,35 @@ func (ipam *IPAM) AddOrUpdateSubnet(name, cidrStr, gw string, excludeIps []strin
 					klog.Errorf("%s address not in subnet %s new cidr %s: %v", podName, name, cidrStr, err)
 				}
 			}
+			subnet.V6FreeIPList, subnet.V6ReleasedIPList = subnet.restoreQuarantinedIPs(subnet.V6FreeIPList, subnet.V6ReleasedIPList, subnet.V6ReleasedAt, v6ReleasedAt)
 		}
 		return nil
 	}
 
-	subnet, err := NewSubnet(name, cidrStr, excludeIps)
+	subnet, err := NewSubnet(name, cidrStr, excludeIps, extraV4CIDRs...)
 	if err != nil {
 		return err
 	}
 	subnet.V4Gw = v4Gw
 	subnet.V6Gw = v6Gw
+	subnet.ReleaseDelay = ipam.ReleaseDelay
 	klog.Infof("adding new subnet %s", name)
 	ipam.Subnets[name] = subnet
 	return nil
 }
 
+// SetSubnetMacOUI sets the prefix of the macs generated in the subnet, the allocated macs are not changed
+func (ipam *IPAM) SetSubnetMacOUI(subnetName, oui string) {
+	ipam.mutex.RLock()
+	defer ipam.mutex.RUnlock()
+
+	if subnet, ok := ipam.Subnets[subnetName]; ok {
+		subnet.mutex.Lock()
+		subnet.MacOUI = strings.ToUpper(oui)
+		subnet.mutex.Unlock()
+	}
+}
+
 func (ipam *IPAM) DeleteSubnet(subnetName string) {
 	ipam.mutex.Lock()
 	defer ipam.mutex.Unlock()
@@ -261,3 +302,26 @@ func (ipam *IPAM) GetSubnetV4Mask(subnetName string) (string, error) {
 		return "", ErrNoAvailable
 	}
 }
+
+// GetSubnetQuarantinedIPs returns the num of released addresses of the subnet still in quarantine
+func (ipam *IPAM) GetSubnetQuarantinedIPs(subnetName string) (int, int) {
+	ipam.mutex.RLock()
+	defer ipam.mutex.RUnlock()
+
+	subnet, ok := ipam.Subnets[subnetName]
+	if !ok {
+		return 0, 0
+	}
+	return subnet.QuarantinedIPCount()
+}
+
+func (ipam *IPAM) ListSubnetAllocations(subnetName string) ([]IPAllocation, error) {
+	ipam.mutex.RLock()
+	defer ipam.mutex.RUnlock()
+
+	subnet, ok := ipam.Subnets[subnetName]
+	if !ok {
+		return nil, ErrNoAvailable
+	}
+	return subnet.ListAllocations(), nil
+}
@@ -3,8 +3,10 @@ package ipam
 import (
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"k8s.io/klog/v2"
 
@@ -17,6 +19,7 @@ type Subnet struct {
 	mutex            sync.RWMutex
 	Protocol         string
 	V4CIDR           *net.IPNet
+	V4ExtraCIDRs     []*net.IPNet
 	V4FreeIPList     IPRangeList
 	V4ReleasedIPList IPRangeList
 	V4ReservedIPList IPRangeList
@@ -33,10 +36,23 @@ type Subnet struct {
 	PodToNicList     map[string][]string
 	V4Gw             string
 	V6Gw             string
+
+	// ReleaseDelay is the quarantine period of released addresses, they are not
+	// reallocated before the delay elapses unless the subnet is exhausted
+	ReleaseDelay time.Duration
+	V4ReleasedAt map[IP]time.Time
+	V6ReleasedAt map[IP]time.Time
+
+	// MacOUI is the prefix of the generated macs, util.DefaultMacOUI if empty
+	MacOUI string
 }
 
-func NewSubnet(name, cidrStr string, excludeIps []string) (*Subnet, error) {
-	excludeIps = util.ExpandExcludeIPs(excludeIps, cidrStr)
+// number of random hosts tried before scanning the host space of the oui for a free mac
+const randomMacAttempts = 64
+
+// NewSubnet creates the ipam subnet, addresses are also allocated from the extra IPv4 CIDRs if any
+func NewSubnet(name, cidrStr string, excludeIps []string, extraV4CIDRs ...string) (*Subnet, error) {
+	excludeIps = util.ExpandExcludeIPs(excludeIps, strings.Join(append([]string{cidrStr}, extraV4CIDRs...), ","))
 
 	var cidrs []*net.IPNet
 	for _, cidrBlock := range strings.Split(cidrStr, ",") {
@@ -46,6 +62,10 @@ func NewSubnet(name, cidrStr string, excludeIps []string) (*Subnet, error) {
 			cidrs = append(cidrs, cidr)
 		}
 	}
+	extraCIDRs, err := parseExtraV4CIDRs(extraV4CIDRs)
+	if err != nil {
+		return nil, err
+	}
 
 	// subnet.Spec.ExcludeIps contains both v4 and v6 addresses
 	v4ExcludeIps, v6ExcludeIps := util.SplitIpsByProtocol(excludeIps)
@@ -61,7 +81,8 @@ func NewSubnet(name, cidrStr string, excludeIps []string) (*Subnet, error) {
 			mutex:            sync.RWMutex{},
 			Protocol:         protocol,
 			V4CIDR:           cidrs[0],
-			V4FreeIPList:     IPRangeList{&IPRange{Start: IP(firstIP), End: IP(lastIP)}},
+			V4ExtraCIDRs:     extraCIDRs,
+			V4FreeIPList:     append(IPRangeList{&IPRange{Start: IP(firstIP), End: IP(lastIP)}}, extraV4FreeIPList(extraCIDRs)...),
 			V4ReleasedIPList: IPRangeList{},
 			V4ReservedIPList: convertExcludeIps(v4ExcludeIps),
 			V4NicToIP:        map[string]IP{},
@@ -71,6 +92,8 @@ func NewSubnet(name, cidrStr string, excludeIps []string) (*Subnet, error) {
 			MacToPod:         map[string]string{},
 			NicToMac:         map[string]string{},
 			PodToNicList:     map[string][]string{},
+			V4ReleasedAt:     map[IP]time.Time{},
+			V6ReleasedAt:     map[IP]time.Time{},
 		}
 		subnet.joinFreeWithReserve()
 	} else if protocol == kubeovnv1.ProtocolIPv6 {
@@ -92,6 +115,8 @@ func NewSubnet(name, cidrStr string, excludeIps []string) (*Subnet, error) {
 			MacToPod:         map[string]string{},
 			NicToMac:         map[string]string{},
 			PodToNicList:     map[string][]string{},
+			V4ReleasedAt:     map[IP]time.Time{},
+			V6ReleasedAt:     map[IP]time.Time{},
 		}
 		subnet.joinFreeWithReserve()
 	} else {
@@ -106,7 +131,8 @@ func NewSubnet(name, cidrStr string, excludeIps []string) (*Subnet, error) {
 			mutex:            sync.RWMutex{},
 			Protocol:         protocol,
 			V4CIDR:           cidrs[0],
-			V4FreeIPList:     IPRangeList{&IPRange{Start: IP(v4FirstIP), End: IP(v4LastIP)}},
+			V4ExtraCIDRs:     extraCIDRs,
+			V4FreeIPList:     append(IPRangeList{&IPRange{Start: IP(v4FirstIP), End: IP(v4LastIP)}}, extraV4FreeIPList(extraCIDRs)...),
 			V4ReleasedIPList: IPRangeList{},
 			V4ReservedIPList: convertExcludeIps(v4ExcludeIps),
 			V4NicToIP:        map[string]IP{},
@@ -120,24 +146,74 @@ func NewSubnet(name, cidrStr string, excludeIps []string) (*Subnet, error) {
 			MacToPod:         map[string]string{},
 			NicToMac:         map[string]string{},
 			PodToNicList:     map[string][]string{},
+			V4ReleasedAt:     map[IP]time.Time{},
+			V6ReleasedAt:     map[IP]time.Time{},
 		}
 		subnet.joinFreeWithReserve()
 	}
 	return &subnet, nil
 }
 
-func (subnet *Subnet) GetRandomMac(podName, nicName string) string {
+func parseExtraV4CIDRs(extraV4CIDRs []string) ([]*net.IPNet, error) {
+	var cidrs []*net.IPNet
+	for _, cidrBlock := range extraV4CIDRs {
+		_, cidr, err := net.ParseCIDR(cidrBlock)
+		if err != nil || cidr.IP.To4() == nil {
+			return nil, ErrInvalidCIDR
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, nil
+}
+
+func extraV4FreeIPList(cidrs []*net.IPNet) IPRangeList {
+	iprl := IPRangeList{}
+	for _, cidr := range cidrs {
+		firstIP, _ := util.FirstIP(cidr.String())
+		lastIP, _ := util.LastIP(cidr.String())
+		iprl = append(iprl, &IPRange{Start: IP(firstIP), End: IP(lastIP)})
+	}
+	return iprl
+}
+
+// v4Contains checks whether the address is in the IPv4 CIDR or the extra IPv4 CIDRs
+func (subnet *Subnet) v4Contains(ip net.IP) bool {
+	if subnet.V4CIDR.Contains(ip) {
+		return true
+	}
+	for _, cidr := range subnet.V4ExtraCIDRs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRandomMac allocates a mac with the oui of the subnet. The host part is random for the first attempts,
+// then the host space is scanned from a random position, so that the exhaustion is detected
+func (subnet *Subnet) GetRandomMac(podName, nicName string) (string, error) {
 	if mac, ok := subnet.NicToMac[nicName]; ok {
-		return mac
+		return mac, nil
 	}
-	for {
-		mac := util.GenerateMac()
+	oui := subnet.MacOUI
+	if oui == "" {
+		oui = util.DefaultMacOUI
+	}
+	start := util.RandomMacHost()
+	for i := uint32(0); i < util.MacHostSpace; i++ {
+		host := (start + i) % util.MacHostSpace
+		if i < randomMacAttempts {
+			host = util.RandomMacHost()
+		}
+		mac := util.MacWithOUI(oui, host)
 		if _, ok := subnet.MacToPod[mac]; !ok {
 			subnet.MacToPod[mac] = podName
 			subnet.NicToMac[nicName] = mac
-			return mac
+			return mac, nil
 		}
 	}
+	klog.Errorf("no available mac with oui %s in subnet %s", oui, subnet.Name)
+	return "", ErrNoAvailableMac
 }
 
 func (subnet *Subnet) GetStaticMac(podName, nicName, mac string, checkConflict bool) error {
@@ -151,6 +227,14 @@ func (subnet *Subnet) GetStaticMac(podName, nicName, mac string, checkConflict b
 	return nil
 }
 
+// releaseMac releases the mac allocated for the nic whose address allocation fails
+func (subnet *Subnet) releaseMac(nicName string) {
+	if mac, ok := subnet.NicToMac[nicName]; ok {
+		delete(subnet.NicToMac, nicName)
+		delete(subnet.MacToPod, mac)
+	}
+}
+
 func (subnet *Subnet) pushPodNic(podName, nicName string) {
 	if subnet.V4NicToIP[nicName] != "" || subnet.V6NicToIP[nicName] != "" || subnet.NicToMac[nicName] != "" {
 		subnet.PodToNicList[podName] = util.UniqString(append(subnet.PodToNicList[podName], nicName))
@@ -205,12 +289,23 @@ func (subnet *Subnet) getV4RandomAddress(podName, nicName string, mac string, sk
 		}
 		subnet.releaseAddr(podName, nicName)
 	}
+	var macAllocated bool
+	if mac == "" {
+		// allocate the mac first so that no address is left without mac when the macs are exhausted
+		_, exists := subnet.NicToMac[nicName]
+		if _, err := subnet.GetRandomMac(podName, nicName); err != nil {
+			return "", "", "", err
+		}
+		macAllocated = !exists
+	}
 	if len(subnet.V4FreeIPList) == 0 {
 		if len(subnet.V4ReleasedIPList) == 0 {
+			if macAllocated {
+				subnet.releaseMac(nicName)
+			}
 			return "", "", "", ErrNoAvailable
 		}
-		subnet.V4FreeIPList = subnet.V4ReleasedIPList
-		subnet.V4ReleasedIPList = IPRangeList{}
+		subnet.V4FreeIPList, subnet.V4ReleasedIPList = subnet.reclaimReleasedIPs(subnet.V4ReleasedIPList, subnet.V4ReleasedAt)
 	}
 
 	var ip IP
@@ -228,6 +323,9 @@ func (subnet *Subnet) getV4RandomAddress(podName, nicName string, mac string, sk
 		}
 	}
 	if ip == "" {
+		if macAllocated {
+			subnet.releaseMac(nicName)
+		}
 		return "", "", "", ErrConflict
 	}
 
@@ -246,7 +344,7 @@ func (subnet *Subnet) getV4RandomAddress(podName, nicName string, mac string, sk
 	subnet.V4IPToPod[ip] = podName
 	subnet.pushPodNic(podName, nicName)
 	if mac == "" {
-		return ip, "", subnet.GetRandomMac(podName, nicName), nil
+		return ip, "", subnet.NicToMac[nicName], nil
 	} else {
 		if err := subnet.GetStaticMac(podName, nicName, mac, checkConflict); err != nil {
 			return "", "", "", err
@@ -266,12 +364,23 @@ func (subnet *Subnet) getV6RandomAddress(podName, nicName string, mac string, sk
 		subnet.releaseAddr(podName, nicName)
 	}
 
+	var macAllocated bool
+	if mac == "" {
+		// allocate the mac first so that no address is left without mac when the macs are exhausted
+		_, exists := subnet.NicToMac[nicName]
+		if _, err := subnet.GetRandomMac(podName, nicName); err != nil {
+			return "", "", "", err
+		}
+		macAllocated = !exists
+	}
 	if len(subnet.V6FreeIPList) == 0 {
 		if len(subnet.V6ReleasedIPList) == 0 {
+			if macAllocated {
+				subnet.releaseMac(nicName)
+			}
 			return "", "", "", ErrNoAvailable
 		}
-		subnet.V6FreeIPList = subnet.V6ReleasedIPList
-		subnet.V6ReleasedIPList = IPRangeList{}
+		subnet.V6FreeIPList, subnet.V6ReleasedIPList = subnet.reclaimReleasedIPs(subnet.V6ReleasedIPList, subnet.V6ReleasedAt)
 	}
 
 	var ip IP
@@ -289,6 +398,9 @@ func (subnet *Subnet) getV6RandomAddress(podName, nicName string, mac string, sk
 		}
 	}
 	if ip == "" {
+		if macAllocated {
+			subnet.releaseMac(nicName)
+		}
 		return "", "", "", ErrConflict
 	}
 
@@ -307,7 +419,7 @@ func (subnet *Subnet) getV6RandomAddress(podName, nicName string, mac string, sk
 	subnet.V6IPToPod[ip] = podName
 	subnet.pushPodNic(podName, nicName)
 	if mac == "" {
-		return "", ip, subnet.GetRandomMac(podName, nicName), nil
+		return "", ip, subnet.NicToMac[nicName], nil
 	} else {
 		if err := subnet.GetStaticMac(podName, nicName, mac, checkConflict); err != nil {
 			return "", "", "", err
@@ -329,7 +441,7 @@ func (subnet *Subnet) GetStaticAddress(podName, nicName string, ip IP, mac strin
 	} else {
 		v6 = subnet.V6CIDR != nil
 	}
-	if v4 && !subnet.V4CIDR.Contains(net.ParseIP(string(ip))) {
+	if v4 && !subnet.v4Contains(net.ParseIP(string(ip))) {
 		return ip, mac, ErrOutOfRange
 	}
 	if v6 && !subnet.V6CIDR.Contains(net.ParseIP(string(ip))) {
@@ -337,10 +449,9 @@ func (subnet *Subnet) GetStaticAddress(podName, nicName string, ip IP, mac strin
 	}
 
 	if mac == "" {
-		if m, ok := subnet.NicToMac[nicName]; ok {
-			mac = m
-		} else {
-			mac = subnet.GetRandomMac(podName, nicName)
+		var err error
+		if mac, err = subnet.GetRandomMac(podName, nicName); err != nil {
+			return ip, mac, err
 		}
 	} else {
 		if err := subnet.GetStaticMac(podName, nicName, mac, checkConflict); err != nil {
@@ -378,6 +489,7 @@ func (subnet *Subnet) GetStaticAddress(podName, nicName string, ip IP, mac strin
 		} else {
 			if split, newReleasedList := splitIPRangeList(subnet.V4ReleasedIPList, ip); split {
 				subnet.V4ReleasedIPList = newReleasedList
+				delete(subnet.V4ReleasedAt, ip)
 				subnet.V4NicToIP[nicName] = ip
 				subnet.V4IPToPod[ip] = podName
 				return ip, mac, nil
@@ -413,6 +525,7 @@ func (subnet *Subnet) GetStaticAddress(podName, nicName string, ip IP, mac strin
 		} else {
 			if split, newReleasedList := splitIPRangeList(subnet.V6ReleasedIPList, ip); split {
 				subnet.V6ReleasedIPList = newReleasedList
+				delete(subnet.V6ReleasedAt, ip)
 				subnet.V6NicToIP[nicName] = ip
 				subnet.V6IPToPod[ip] = podName
 				return ip, mac, nil
@@ -439,7 +552,7 @@ func (subnet *Subnet) releaseAddr(podName, nicName string) {
 			}
 
 			// When CIDR changed, do not relocate ip to CIDR list
-			if !subnet.V4CIDR.Contains(net.ParseIP(string(ip))) {
+			if !subnet.v4Contains(net.ParseIP(string(ip))) {
 				// Continue to release IPv6 address
 				klog.Infof("release v4 %s mac %s for %s, ignore ip", ip, mac, podName)
 				changed = true
@@ -452,6 +565,9 @@ func (subnet *Subnet) releaseAddr(podName, nicName string) {
 
 			if merged, newReleasedList := mergeIPRangeList(subnet.V4ReleasedIPList, ip); !changed && merged {
 				subnet.V4ReleasedIPList = newReleasedList
+				if subnet.ReleaseDelay > 0 {
+					subnet.V4ReleasedAt[ip] = time.Now()
+				}
 				klog.Infof("release v4 %s mac %s for %s, add ip to released list", ip, mac, podName)
 			}
 		}
@@ -482,12 +598,87 @@ func (subnet *Subnet) releaseAddr(podName, nicName string) {
 
 			if merged, newReleasedList := mergeIPRangeList(subnet.V6ReleasedIPList, ip); !changed && merged {
 				subnet.V6ReleasedIPList = newReleasedList
+				if subnet.ReleaseDelay > 0 {
+					subnet.V6ReleasedAt[ip] = time.Now()
+				}
 				klog.Infof("release v6 %s mac %s for %s, add ip to released list", ip, mac, podName)
 			}
 		}
 	}
 }
 
+// reclaimReleasedIPs returns the released addresses which can be allocated again and
+// the ones still in quarantine. When all of them are in quarantine the subnet is
+// exhausted, so the release delay is bypassed to avoid failing the allocation.
+func (subnet *Subnet) reclaimReleasedIPs(released IPRangeList, releasedAt map[IP]time.Time) (IPRangeList, IPRangeList) {
+	if subnet.ReleaseDelay <= 0 || len(releasedAt) == 0 {
+		for ip := range releasedAt {
+			delete(releasedAt, ip)
+		}
+		return released, IPRangeList{}
+	}
+
+	free, quarantined := IPRangeList{}, IPRangeList{}
+	now := time.Now()
+	for _, ipr := range released {
+		for ip := ipr.Start; !ip.GreaterThan(ipr.End); ip = ip.Add(1) {
+			if t, ok := releasedAt[ip]; ok && now.Sub(t) < subnet.ReleaseDelay {
+				_, quarantined = mergeIPRangeList(quarantined, ip)
+				continue
+			}
+			delete(releasedAt, ip)
+			_, free = mergeIPRangeList(free, ip)
+		}
+	}
+	if len(free) == 0 {
+		klog.Warningf("subnet %s is exhausted, reuse released addresses before the release delay %v elapses", subnet.Name, subnet.ReleaseDelay)
+		for ip := range releasedAt {
+			delete(releasedAt, ip)
+		}
+		return released, IPRangeList{}
+	}
+	return free, quarantined
+}
+
+// restoreQuarantinedIPs moves the addresses still in quarantine before the address lists are rebuilt
+// from the free list back to the released list, so that subnet updates don't bypass the release delay
+func (subnet *Subnet) restoreQuarantinedIPs(free, released IPRangeList, releasedAt, oldReleasedAt map[IP]time.Time) (IPRangeList, IPRangeList) {
+	now := time.Now()
+	for ip, t := range oldReleasedAt {
+		if now.Sub(t) >= subnet.ReleaseDelay {
+			continue
+		}
+		// the addresses out of the new cidr, reserved or allocated are not in the free list
+		split, newFree := splitIPRangeList(free, ip)
+		if !split {
+			continue
+		}
+		free = newFree
+		_, released = mergeIPRangeList(released, ip)
+		releasedAt[ip] = t
+	}
+	return free, released
+}
+
+// QuarantinedIPCount returns the num of released addresses waiting for the release delay to elapse
+func (subnet *Subnet) QuarantinedIPCount() (v4, v6 int) {
+	subnet.mutex.RLock()
+	defer subnet.mutex.RUnlock()
+
+	now := time.Now()
+	for _, t := range subnet.V4ReleasedAt {
+		if now.Sub(t) < subnet.ReleaseDelay {
+			v4++
+		}
+	}
+	for _, t := range subnet.V6ReleasedAt {
+		if now.Sub(t) < subnet.ReleaseDelay {
+			v6++
+		}
+	}
+	return v4, v6
+}
+
 func (subnet *Subnet) ReleaseAddress(podName string) {
 	subnet.mutex.Lock()
 	defer subnet.mutex.Unlock()
@@ -497,6 +688,13 @@ func (subnet *Subnet) ReleaseAddress(podName string) {
 	}
 }
 
+func (subnet *Subnet) ReleaseNicAddress(podName, nicName string) {
+	subnet.mutex.Lock()
+	defer subnet.mutex.Unlock()
+	subnet.releaseAddr(podName, nicName)
+	subnet.popPodNic(podName, nicName)
+}
+
 func (subnet *Subnet) ContainAddress(address IP) bool {
 	subnet.mutex.RLock()
 	defer subnet.mutex.RUnlock()
@@ -568,3 +766,59 @@ func (subnet *Subnet) isIPAssignedToPod(ip, podName string) bool {
 	}
 	return false
 }
+
+// IPAllocation is an address held by the subnet, either assigned to a nic or
+// excluded from allocation. Excluded ranges are reported as "start..end".
+type IPAllocation struct {
+	IP       string
+	Owner    string
+	Nic      string
+	Reserved bool
+}
+
+func (subnet *Subnet) ListAllocations() []IPAllocation {
+	subnet.mutex.RLock()
+	defer subnet.mutex.RUnlock()
+
+	allocations := make([]IPAllocation, 0, len(subnet.V4NicToIP)+len(subnet.V6NicToIP))
+	for nicName, ip := range subnet.V4NicToIP {
+		allocations = append(allocations, IPAllocation{
+			IP:       string(ip),
+			Owner:    subnet.V4IPToPod[ip],
+			Nic:      nicName,
+			Reserved: subnet.V4ReservedIPList.Contains(ip),
+		})
+	}
+	for nicName, ip := range subnet.V6NicToIP {
+		allocations = append(allocations, IPAllocation{
+			IP:       string(ip),
+			Owner:    subnet.V6IPToPod[ip],
+			Nic:      nicName,
+			Reserved: subnet.V6ReservedIPList.Contains(ip),
+		})
+	}
+	for _, iprl := range []IPRangeList{subnet.V4ReservedIPList, subnet.V6ReservedIPList} {
+		for _, ipr := range iprl {
+			ip := string(ipr.Start)
+			if !ipr.Start.Equal(ipr.End) {
+				ip = fmt.Sprintf("%s..%s", ipr.Start, ipr.End)
+			}
+			allocations = append(allocations, IPAllocation{IP: ip, Reserved: true})
+		}
+	}
+
+	sort.Slice(allocations, func(i, j int) bool {
+		a := IP(strings.Split(allocations[i].IP, "..")[0])
+		b := IP(strings.Split(allocations[j].IP, "..")[0])
+		if a.Equal(b) {
+			// allocated addresses go before the reserved range they belong to
+			return allocations[i].Nic > allocations[j].Nic
+		}
+		aIsV4, bIsV4 := net.ParseIP(string(a)).To4() != nil, net.ParseIP(string(b)).To4() != nil
+		if aIsV4 != bIsV4 {
+			return aIsV4
+		}
+		return a.LessThan(b)
+	})
+	return allocations
+}
@@ -0,0 +1,184 @@
+package ovs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ovn-org/libovsdb/client"
+	"github.com/ovn-org/libovsdb/ovsdb"
+
+	ovsclient "github.com/kubeovn/kube-ovn/pkg/ovsdb/client"
+	"github.com/kubeovn/kube-ovn/pkg/ovsdb/ovnnb"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func (c OvnClient) ListBFD(lrpName, dstIP string) ([]ovnnb.BFD, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	api, err := c.ovnNbClient.WherePredict(ctx, func(bfd *ovnnb.BFD) bool {
+		if lrpName != "" && bfd.LogicalPort != lrpName {
+			return false
+		}
+		return dstIP == "" || bfd.DstIP == dstIP
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var bfdList []ovnnb.BFD
+	if err = api.List(context.TODO(), &bfdList); err != nil && err != client.ErrNotFound {
+		return nil, fmt.Errorf("failed to list bfd for lrp %s dst ip %s: %v", lrpName, dstIP, err)
+	}
+
+	return bfdList, nil
+}
+
+func (c OvnClient) ListBFDByExtID(key, value string) ([]ovnnb.BFD, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	api, err := c.ovnNbClient.WherePredict(ctx, func(bfd *ovnnb.BFD) bool {
+		return bfd.ExternalIDs[key] == value
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var bfdList []ovnnb.BFD
+	if err = api.List(context.TODO(), &bfdList); err != nil && err != client.ErrNotFound {
+		return nil, fmt.Errorf("failed to list bfd with external id %s=%s: %v", key, value, err)
+	}
+
+	return bfdList, nil
+}
+
+// CreateBFD creates a bfd session towards dstIP through the logical router port,
+// or updates the timers of the existing one
+func (c OvnClient) CreateBFD(lrpName, dstIP string, minRx, minTx, detectMult int, externalIDs map[string]string) (*ovnnb.BFD, error) {
+	bfdList, err := c.ListBFD(lrpName, dstIP)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bfdList) != 0 {
+		bfd := &bfdList[0]
+		if bfd.MinRx != nil && *bfd.MinRx == minRx && bfd.MinTx != nil && *bfd.MinTx == minTx &&
+			bfd.DetectMult != nil && *bfd.DetectMult == detectMult {
+			return bfd, nil
+		}
+		bfd.MinRx, bfd.MinTx, bfd.DetectMult = &minRx, &minTx, &detectMult
+		ops, err := c.ovnNbClient.Where(bfd).Update(bfd, &bfd.MinRx, &bfd.MinTx, &bfd.DetectMult)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate update operations for bfd %s: %v", bfd.UUID, err)
+		}
+		if err = Transact(c.ovnNbClient, "bfd-update", ops, c.ovnNbClient.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to update bfd for lrp %s dst ip %s: %v", lrpName, dstIP, err)
+		}
+		return bfd, nil
+	}
+
+	bfd := &ovnnb.BFD{
+		UUID:        ovsclient.NamedUUID(),
+		LogicalPort: lrpName,
+		DstIP:       dstIP,
+		MinRx:       &minRx,
+		MinTx:       &minTx,
+		DetectMult:  &detectMult,
+		ExternalIDs: map[string]string{"vendor": util.CniTypeName},
+	}
+	for k, v := range externalIDs {
+		bfd.ExternalIDs[k] = v
+	}
+	ops, err := c.ovnNbClient.Create(bfd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate create operations for bfd of lrp %s dst ip %s: %v", lrpName, dstIP, err)
+	}
+	if err = Transact(c.ovnNbClient, "bfd-add", ops, c.ovnNbClient.Timeout); err != nil {
+		return nil, fmt.Errorf("failed to create bfd for lrp %s dst ip %s: %v", lrpName, dstIP, err)
+	}
+
+	if bfdList, err = c.ListBFD(lrpName, dstIP); err != nil {
+		return nil, err
+	}
+	if len(bfdList) == 0 {
+		return nil, fmt.Errorf("bfd for lrp %s dst ip %s not found after creation", lrpName, dstIP)
+	}
+	return &bfdList[0], nil
+}
+
+func (c OvnClient) DeleteBFD(lrpName, dstIP string) error {
+	bfdList, err := c.ListBFD(lrpName, dstIP)
+	if err != nil {
+		return err
+	}
+	if len(bfdList) == 0 {
+		return nil
+	}
+
+	ops := make([]ovsdb.Operation, 0, len(bfdList))
+	for i := range bfdList {
+		deleteOps, err := c.ovnNbClient.Where(&bfdList[i]).Delete()
+		if err != nil {
+			return fmt.Errorf("failed to generate delete operations for bfd %s: %v", bfdList[i].UUID, err)
+		}
+		ops = append(ops, deleteOps...)
+	}
+	if err = Transact(c.ovnNbClient, "bfd-del", ops, c.ovnNbClient.Timeout); err != nil {
+		return fmt.Errorf("failed to delete bfd for lrp %s dst ip %s: %v", lrpName, dstIP, err)
+	}
+	return nil
+}
+
+// SetLogicalRouterStaticRouteBFD binds the bfd session to the static routes of the
+// router which match the prefix and nexthop, an empty bfdUUID unbinds the session
+func (c OvnClient) SetLogicalRouterStaticRouteBFD(lrName, prefix, nexthop, bfdUUID string) error {
+	lr, err := c.GetLogicalRouter(lrName, false)
+	if err != nil {
+		return err
+	}
+
+	routeUUIDs := make(map[string]struct{}, len(lr.StaticRoutes))
+	for _, uuid := range lr.StaticRoutes {
+		routeUUIDs[uuid] = struct{}{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	api, err := c.ovnNbClient.WherePredict(ctx, func(r *ovnnb.LogicalRouterStaticRoute) bool {
+		_, ok := routeUUIDs[r.UUID]
+		return ok && r.IPPrefix == prefix && r.Nexthop == nexthop
+	})
+	if err != nil {
+		return err
+	}
+	var routes []ovnnb.LogicalRouterStaticRoute
+	if err = api.List(context.TODO(), &routes); err != nil && err != client.ErrNotFound {
+		return fmt.Errorf("failed to list static routes of router %s: %v", lrName, err)
+	}
+
+	var bfd *string
+	if bfdUUID != "" {
+		bfd = &bfdUUID
+	}
+	var ops []ovsdb.Operation
+	for i := range routes {
+		route := &routes[i]
+		if (route.BFD == nil && bfd == nil) || (route.BFD != nil && bfd != nil && *route.BFD == *bfd) {
+			continue
+		}
+		route.BFD = bfd
+		updateOps, err := c.ovnNbClient.Where(route).Update(route, &route.BFD)
+		if err != nil {
+			return fmt.Errorf("failed to generate update operations for static route %s: %v", route.UUID, err)
+		}
+		ops = append(ops, updateOps...)
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	if err = Transact(c.ovnNbClient, "lr-route-set-bfd", ops, c.ovnNbClient.Timeout); err != nil {
+		return fmt.Errorf("failed to set bfd of static route %s via %s on router %s: %v", prefix, nexthop, lrName, err)
+	}
+	return nil
+}
@@ -30,6 +30,34 @@ func (c OvnClient) GetLogicalRouterPort(name string, ignoreNotFound bool) (*ovnn
 	return lrp, nil
 }
 
+func (c OvnClient) ListLogicalRouterPorts(lr string) ([]ovnnb.LogicalRouterPort, error) {
+	router, err := c.GetLogicalRouter(lr, false)
+	if err != nil {
+		return nil, err
+	}
+
+	portUUIDs := make(map[string]struct{}, len(router.Ports))
+	for _, uuid := range router.Ports {
+		portUUIDs[uuid] = struct{}{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	api, err := c.ovnNbClient.WherePredict(ctx, func(lrp *ovnnb.LogicalRouterPort) bool {
+		_, ok := portUUIDs[lrp.UUID]
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var lrpList []ovnnb.LogicalRouterPort
+	if err = api.List(context.TODO(), &lrpList); err != nil && err != client.ErrNotFound {
+		return nil, fmt.Errorf("failed to list ports of logical router %s: %v", lr, err)
+	}
+	return lrpList, nil
+}
+
 func (c OvnClient) AddLogicalRouterPort(lr, name, mac, networks string) error {
 	router, err := c.GetLogicalRouter(lr, false)
 	if err != nil {
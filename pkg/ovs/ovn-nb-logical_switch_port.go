@@ -45,6 +45,18 @@ func (c OvnClient) ListPodLogicalSwitchPorts(key string) ([]ovnnb.LogicalSwitchP
 	return lspList, nil
 }
 
+// CountLogicalSwitchPorts returns the num of logical switch ports of all types from the cache
+func (c OvnClient) CountLogicalSwitchPorts() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	var lspList []ovnnb.LogicalSwitchPort
+	if err := c.ovnNbClient.List(ctx, &lspList); err != nil {
+		return 0, fmt.Errorf("failed to list logical switch ports: %v", err)
+	}
+	return len(lspList), nil
+}
+
 func (c OvnClient) ListLogicalSwitchPorts(needVendorFilter bool, externalIDs map[string]string) ([]ovnnb.LogicalSwitchPort, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
 	defer cancel()
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -16,6 +17,7 @@ import (
 	"k8s.io/klog/v2"
 
 	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	ovsclient "github.com/kubeovn/kube-ovn/pkg/ovsdb/client"
 	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
@@ -28,10 +30,19 @@ const (
 
 var nbctlDaemonSocketRegexp = regexp.MustCompile(`^/var/run/ovn/ovn-nbctl\.[0-9]+\.ctl$`)
 
+// ovnNbCtlExec runs ovn-nbctl and returns the combined output, it's replaced by a fake nb in tests
+var ovnNbCtlExec = func(args ...string) ([]byte, error) {
+	return exec.Command(OvnNbCtl, args...).CombinedOutput()
+}
+
 func (c LegacyClient) ovnNbCommand(cmdArgs ...string) (string, error) {
 	start := time.Now()
 	cmdArgs = append([]string{fmt.Sprintf("--timeout=%d", c.OvnTimeout), "--no-wait"}, cmdArgs...)
-	raw, err := exec.Command(OvnNbCtl, cmdArgs...).CombinedOutput()
+	// the connection is made by the daemon if any, which is started with the inactivity probe
+	if c.OvnInactivityProbe != 0 && os.Getenv("OVN_NB_DAEMON") == "" {
+		cmdArgs = append([]string{fmt.Sprintf("--inactivity-probe=%d", c.OvnInactivityProbe)}, cmdArgs...)
+	}
+	raw, err := ovnNbCtlExec(cmdArgs...)
 	elapsed := float64((time.Since(start)) / time.Millisecond)
 	klog.V(4).Infof("command %s %s in %vms, output %q", OvnNbCtl, strings.Join(cmdArgs, " "), elapsed, raw)
 	method := ""
@@ -145,6 +156,21 @@ func (c LegacyClient) CreateICLogicalRouterPort(az, mac, subnet string, chassise
 	return nil
 }
 
+// SetICLogicalRouterPortMtu sets the gateway mtu of the ovn-ic logical router port, 0 to remove the limit
+func (c LegacyClient) SetICLogicalRouterPortMtu(az string, mtu int) error {
+	lrpName := fmt.Sprintf("%s-ts", az)
+	var err error
+	if mtu == 0 {
+		_, err = c.ovnNbCommand(IfExists, "remove", "logical_router_port", lrpName, "options", "gateway_mtu")
+	} else {
+		_, err = c.ovnNbCommand("set", "logical_router_port", lrpName, fmt.Sprintf("options:gateway_mtu=%d", mtu))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set gateway mtu of ovn-ic lrp %s, %v", lrpName, err)
+	}
+	return nil
+}
+
 func (c LegacyClient) DeleteICLogicalRouterPort(az string) error {
 	if err := c.DeleteLogicalRouterPort(fmt.Sprintf("%s-ts", az)); err != nil {
 		return fmt.Errorf("failed to delete ovn-ic logical router port: %v", err)
@@ -174,6 +200,15 @@ func (c LegacyClient) SetPortAddress(port, mac, ip string) error {
 	return nil
 }
 
+// SetPortAddresses replaces all the entries of the addresses column of the port
+func (c LegacyClient) SetPortAddresses(port string, addresses []string) error {
+	if _, err := c.ovnNbCommand(append([]string{"lsp-set-addresses", port}, addresses...)...); err != nil {
+		klog.Errorf("set port %s addresses failed, %v", port, err)
+		return err
+	}
+	return nil
+}
+
 func (c LegacyClient) SetPortExternalIds(port, key, value string) error {
 	rets, err := c.ListLogicalEntity("logical_switch_port", fmt.Sprintf("name=%s", port))
 	if err != nil {
@@ -408,7 +443,7 @@ func (c LegacyClient) ListPodLogicalSwitchPorts(pod, namespace string) ([]string
 	return result, nil
 }
 
-func (c LegacyClient) SetLogicalSwitchConfig(ls, lr, protocol, subnet, gateway string, excludeIps []string, needRouter bool) error {
+func (c LegacyClient) SetLogicalSwitchConfig(ls, lr, protocol, subnet, gateway string, extraNetworks, excludeIps []string, needRouter bool) error {
 	klog.Infof("set logical switch: ls %s, lr %s, protocol %s, subnet %s, gw %s", ls, lr, protocol, subnet, gateway)
 	var err error
 	cidrBlocks := strings.Split(subnet, ",")
@@ -437,6 +472,9 @@ func (c LegacyClient) SetLogicalSwitchConfig(ls, lr, protocol, subnet, gateway s
 
 		cmd = []string{MayExist, "ls-add", ls}
 	}
+	if len(extraNetworks) != 0 {
+		networks = strings.Join(append([]string{networks}, extraNetworks...), " ")
+	}
 	if needRouter {
 		cmd = append(cmd, []string{"--",
 			"set", "logical_router_port", fmt.Sprintf("%s-%s", lr, ls), fmt.Sprintf("networks=%s", networks)}...)
@@ -451,6 +489,158 @@ func (c LegacyClient) SetLogicalSwitchConfig(ls, lr, protocol, subnet, gateway s
 	return nil
 }
 
+// SetLogicalSwitchAggregateRate caps the traffic from the source cidrs in the logical switch with one shared qos rule,
+// the rate is in kbps and zero removes the cap
+func (c LegacyClient) SetLogicalSwitchAggregateRate(ls, cidrs string, rate int) error {
+	var matches []string
+	for _, cidr := range strings.Split(cidrs, ",") {
+		if util.CheckProtocol(cidr) == kubeovnv1.ProtocolIPv4 {
+			matches = append(matches, fmt.Sprintf("ip4.src == %s", cidr))
+		} else {
+			matches = append(matches, fmt.Sprintf("ip6.src == %s", cidr))
+		}
+	}
+	match := strings.Join(matches, " || ")
+
+	owned := fmt.Sprintf("external_ids:aggregate_rate=%s", ls)
+	output, err := c.ovnNbCommand("--data=bare", "--no-heading", "--columns=_uuid", "find", "qos", owned)
+	if err != nil {
+		klog.Errorf("failed to list aggregate rate qos of logical switch %s: %v", ls, err)
+		return err
+	}
+	qosList := strings.Fields(output)
+	if rate != 0 && len(qosList) == 1 {
+		output, err = c.ovnNbCommand("--data=bare", "--no-heading", "--columns=_uuid", "find", "qos", owned,
+			fmt.Sprintf("bandwidth:rate=%d", rate), fmt.Sprintf("match=\"%s\"", match))
+		if err != nil {
+			klog.Errorf("failed to find aggregate rate qos of logical switch %s: %v", ls, err)
+			return err
+		}
+		if len(strings.Fields(output)) == 1 {
+			return nil
+		}
+	}
+
+	var cmd []string
+	for _, qos := range qosList {
+		cmd = append(cmd, "--", "remove", "logical_switch", ls, "qos_rules", qos)
+	}
+	if rate != 0 {
+		cmd = append(cmd, "--", "--id=@qos", "create", "qos", "direction=from-lport", fmt.Sprintf("priority=%s", util.AggregateRateQosPriority),
+			fmt.Sprintf("match=\"%s\"", match), fmt.Sprintf("bandwidth:rate=%d", rate), owned,
+			"--", "add", "logical_switch", ls, "qos_rules", "@qos")
+	}
+	if len(cmd) == 0 {
+		return nil
+	}
+	if _, err = c.ovnNbCommand(cmd...); err != nil {
+		klog.Errorf("failed to set aggregate rate %d of logical switch %s: %v", rate, ls, err)
+		return err
+	}
+	return nil
+}
+
+func GetNamespaceQosPortGroupName(namespace string) string {
+	return strings.Replace(fmt.Sprintf("ovn.ns.%s.qos", namespace), "-", ".", -1)
+}
+
+// SetNamespaceAggregateRate caps the egress traffic of the ports in the namespace with one qos rule shared by the
+// logical switches of the ports, the rate is in kbps and zero removes the cap along with the port group of the ports
+func (c LegacyClient) SetNamespaceAggregateRate(namespace string, ports, switches []string, rate int) error {
+	pgName := GetNamespaceQosPortGroupName(namespace)
+	owned := fmt.Sprintf("external_ids:namespace_aggregate_rate=%s", namespace)
+	output, err := c.ovnNbCommand("--data=bare", "--no-heading", "--columns=_uuid", "find", "qos", owned)
+	if err != nil {
+		klog.Errorf("failed to list aggregate rate qos of namespace %s: %v", namespace, err)
+		return err
+	}
+	qosList := strings.Fields(output)
+
+	var cmd []string
+	for _, qos := range qosList {
+		output, err = c.ovnNbCommand("--data=bare", "--no-heading", "--columns=name", "find", "logical_switch", fmt.Sprintf("qos_rules{>=}%s", qos))
+		if err != nil {
+			klog.Errorf("failed to find logical switches of qos %s: %v", qos, err)
+			return err
+		}
+		existing := strings.Fields(output)
+		if rate == 0 || len(qosList) != 1 {
+			// the qos is deleted once it's removed from all logical switches
+			for _, ls := range existing {
+				cmd = append(cmd, "--", "remove", "logical_switch", ls, "qos_rules", qos)
+			}
+			continue
+		}
+
+		output, err = c.ovnNbCommand("--data=bare", "--no-heading", "--columns=_uuid", "find", "qos", owned, fmt.Sprintf("bandwidth:rate=%d", rate))
+		if err != nil {
+			klog.Errorf("failed to find aggregate rate qos of namespace %s: %v", namespace, err)
+			return err
+		}
+		if output == "" {
+			cmd = append(cmd, "--", "set", "qos", qos, fmt.Sprintf("bandwidth:rate=%d", rate))
+		}
+		// add the qos to the new logical switches before removing it from the stale ones,
+		// so that it's kept if the logical switches of the ports are changed completely
+		for _, ls := range switches {
+			if !util.ContainsString(existing, ls) {
+				cmd = append(cmd, "--", "add", "logical_switch", ls, "qos_rules", qos)
+			}
+		}
+		for _, ls := range existing {
+			if !util.ContainsString(switches, ls) {
+				cmd = append(cmd, "--", "remove", "logical_switch", ls, "qos_rules", qos)
+			}
+		}
+	}
+
+	if rate == 0 {
+		if len(cmd) != 0 {
+			if _, err = c.ovnNbCommand(cmd...); err != nil {
+				klog.Errorf("failed to remove aggregate rate of namespace %s: %v", namespace, err)
+				return err
+			}
+		}
+		if err = c.DeletePortGroup(pgName); err != nil {
+			klog.Errorf("failed to delete port group %s: %v", pgName, err)
+			return err
+		}
+		return nil
+	}
+
+	output, err = c.ovnNbCommand("--data=bare", "--no-heading", "--columns=_uuid", "find", "port_group", fmt.Sprintf("name=%s", pgName))
+	if err != nil {
+		klog.Errorf("failed to find port group %s: %v", pgName, err)
+		return err
+	}
+	if output == "" {
+		if _, err = c.ovnNbCommand("pg-add", pgName, "--", "set", "port_group", pgName, owned); err != nil {
+			klog.Errorf("failed to create port group %s: %v", pgName, err)
+			return err
+		}
+	}
+	if err = c.SetPortsToPortGroup(pgName, ports); err != nil {
+		klog.Errorf("failed to set ports of port group %s: %v", pgName, err)
+		return err
+	}
+
+	if len(qosList) != 1 && len(switches) != 0 {
+		cmd = append(cmd, "--", "--id=@qos", "create", "qos", "direction=from-lport", fmt.Sprintf("priority=%s", util.NamespaceAggregateRateQosPriority),
+			fmt.Sprintf("match=\"inport == @%s\"", pgName), fmt.Sprintf("bandwidth:rate=%d", rate), owned)
+		for _, ls := range switches {
+			cmd = append(cmd, "--", "add", "logical_switch", ls, "qos_rules", "@qos")
+		}
+	}
+	if len(cmd) == 0 {
+		return nil
+	}
+	if _, err = c.ovnNbCommand(cmd...); err != nil {
+		klog.Errorf("failed to set aggregate rate %d of namespace %s: %v", rate, namespace, err)
+		return err
+	}
+	return nil
+}
+
 // CreateLogicalSwitch create logical switch in ovn, connect it to router and apply tcp/udp lb rules
 func (c LegacyClient) CreateLogicalSwitch(ls, lr, subnet, gateway string, needRouter bool) error {
 	_, err := c.ovnNbCommand(MayExist, "ls-add", ls, "--",
@@ -1433,6 +1623,58 @@ func (c LegacyClient) CreateLoadBalancerRule(lb, vip, ips, protocol string) erro
 	return err
 }
 
+// hairpinVpcKey is the external id key of the vpc a hairpin loadbalancer belongs to
+const hairpinVpcKey = "hairpin_vpc"
+
+// CreateHairpinLoadBalancer create or update a dedicated loadbalancer in ovn which snats hairpin traffic to snatIPs,
+// the hairpin snat is disabled if snatIPs is empty
+func (c LegacyClient) CreateHairpinLoadBalancer(lb, protocol, selectFields, vpc string, snatIPs []string) error {
+	lbUuid, err := c.FindLoadbalancer(lb)
+	if err != nil {
+		klog.Errorf("failed to get lb %s: %v", lb, err)
+		return err
+	}
+	if lbUuid == "" {
+		if lbUuid, err = c.ovnNbCommand("create", "load_balancer", fmt.Sprintf("name=%s", lb), fmt.Sprintf("protocol=%s", protocol),
+			fmt.Sprintf("external_ids:%s=%s", hairpinVpcKey, vpc)); err != nil {
+			klog.Errorf("failed to create lb %s: %v", lb, err)
+			return err
+		}
+	}
+
+	if len(snatIPs) == 0 {
+		_, err = c.ovnNbCommand("set", "load_balancer", lbUuid, fmt.Sprintf("selection_fields=[%s]", selectFields),
+			"--", "remove", "load_balancer", lbUuid, "options", "hairpin_snat_ip")
+		return err
+	}
+	_, err = c.ovnNbCommand("set", "load_balancer", lbUuid, fmt.Sprintf("selection_fields=[%s]", selectFields),
+		fmt.Sprintf("options:hairpin_snat_ip=\"%s\"", strings.Join(snatIPs, " ")))
+	return err
+}
+
+// ListHairpinLoadBalancers list names of the hairpin loadbalancers in the vpc
+func (c LegacyClient) ListHairpinLoadBalancers(vpc string) ([]string, error) {
+	output, err := c.ovnNbCommand("--format=csv", "--data=bare", "--no-heading", "--columns=name", "find", "load_balancer",
+		fmt.Sprintf("external_ids:%s=%s", hairpinVpcKey, vpc))
+	if err != nil {
+		klog.Errorf("failed to list hairpin load balancer of vpc %s: %v", vpc, err)
+		return nil, err
+	}
+	lines := strings.Split(output, "\n")
+	result := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); len(l) > 0 {
+			result = append(result, l)
+		}
+	}
+	return result, nil
+}
+
+// AddLoadBalancerToLogicalSwitch add a loadbalancer to the logical switch
+func (c LegacyClient) AddLoadBalancerToLogicalSwitch(lb, ls string) error {
+	return c.addLoadBalancerToLogicalSwitch(lb, ls)
+}
+
 func (c LegacyClient) addLoadBalancerToLogicalSwitch(lb, ls string) error {
 	_, err := c.ovnNbCommand(MayExist, "ls-lb-add", ls, lb)
 	return err
@@ -1454,24 +1696,23 @@ func (c LegacyClient) removeLoadBalancerFromLogicalSwitch(lb, ls string) error {
 	return err
 }
 
-// DeleteLoadBalancerVip delete a vip rule from loadbalancer
+// DeleteLoadBalancerVip delete a vip rule from loadbalancer.
+// The vip is removed from the vips column rather than by lb-del, which destroys the loadbalancer with its last vip,
+// so the loadbalancer is kept empty and attached to the logical switches, and is reused when a vip is added again.
+// Each removal touches only its own key, so the vips of a loadbalancer can be deleted concurrently
 func (c LegacyClient) DeleteLoadBalancerVip(vip, lb string) error {
+	if vip == "" {
+		return nil
+	}
 	lbUuid, err := c.FindLoadbalancer(lb)
 	if err != nil {
 		klog.Errorf("failed to get lb: %v", err)
 		return err
 	}
-
-	existVips, err := c.GetLoadBalancerVips(lbUuid)
-	if err != nil {
-		klog.Errorf("failed to list lb %s vips: %v", lb, err)
-		return err
-	}
-	// vip is empty or delete last rule will destroy the loadbalancer
-	if vip == "" || len(existVips) == 1 {
+	if lbUuid == "" {
 		return nil
 	}
-	_, err = c.ovnNbCommand(IfExists, "lb-del", lb, vip)
+	_, err = c.ovnNbCommand("remove", "load_balancer", lbUuid, "vips", fmt.Sprintf(`"%s"`, vip))
 	return err
 }
 
@@ -1680,6 +1921,111 @@ func (c LegacyClient) ListNpPortGroup() ([]portGroup, error) {
 	return result, nil
 }
 
+// kinds of the crds acls are generated from
+const (
+	AclSourceNetworkPolicy = "NetworkPolicy"
+	AclSourceSecurityGroup = "SecurityGroup"
+)
+
+const (
+	aclSourceKindKey      = "source_kind"
+	aclSourceNamespaceKey = "source_namespace"
+	aclSourceNameKey      = "source_name"
+)
+
+// AclSource is an acl and the crd it is generated from
+type AclSource struct {
+	UUID      string
+	Direction string
+	Priority  string
+	Action    string
+	Match     string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// SetPortGroupAclSource tags all acls of the port group with the crd they are generated from
+func (c LegacyClient) SetPortGroupAclSource(pgName, kind, namespace, name string) error {
+	output, err := c.ovnNbCommand("--data=bare", "--no-heading", "--columns=acls", "find", "port_group", fmt.Sprintf("name=%s", pgName))
+	if err != nil {
+		klog.Errorf("failed to find acls of port_group %s: %v", pgName, err)
+		return err
+	}
+
+	var args []string
+	for _, uuid := range strings.Fields(output) {
+		args = append(args, "--", "set", "acl", uuid,
+			fmt.Sprintf("external_ids:%s=%s", aclSourceKindKey, kind),
+			fmt.Sprintf("external_ids:%s=%s", aclSourceNameKey, name))
+		if namespace != "" {
+			args = append(args, fmt.Sprintf("external_ids:%s=%s", aclSourceNamespaceKey, namespace))
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	if _, err = c.ovnNbCommand(args[1:]...); err != nil {
+		klog.Errorf("failed to set source of acls of port_group %s to %s %s: %v", pgName, kind, name, err)
+		return err
+	}
+	return nil
+}
+
+// ListAclSources lists the acls tagged with the crd they are generated from
+func (c LegacyClient) ListAclSources() ([]AclSource, error) {
+	output, err := c.ovnNbCommand("--data=bare", "--format=csv", "--no-heading", "--columns=_uuid,direction,priority,action,external_ids,match",
+		"find", "acl", fmt.Sprintf("external_ids:%s!=[]", aclSourceKindKey))
+	if err != nil {
+		klog.Errorf("failed to list acl sources, %v", err)
+		return nil, err
+	}
+	lines := strings.Split(output, "\n")
+	result := make([]AclSource, 0, len(lines))
+	for _, l := range lines {
+		if len(strings.TrimSpace(l)) == 0 {
+			continue
+		}
+		// match is the last column as it may contain commas
+		parts := strings.SplitN(strings.TrimSpace(l), ",", 6)
+		if len(parts) != 6 {
+			continue
+		}
+		acl := AclSource{
+			UUID:      parts[0],
+			Direction: parts[1],
+			Priority:  parts[2],
+			Action:    parts[3],
+			Match:     strings.ReplaceAll(strings.TrimSuffix(strings.TrimPrefix(parts[5], `"`), `"`), `""`, `"`),
+		}
+		for _, kv := range strings.Fields(parts[4]) {
+			idx := strings.Index(kv, "=")
+			if idx <= 0 {
+				continue
+			}
+			switch kv[:idx] {
+			case aclSourceKindKey:
+				acl.Kind = kv[idx+1:]
+			case aclSourceNamespaceKey:
+				acl.Namespace = kv[idx+1:]
+			case aclSourceNameKey:
+				acl.Name = kv[idx+1:]
+			}
+		}
+		result = append(result, acl)
+	}
+	return result, nil
+}
+
+// RemoveAclSource removes the source tags of the acl
+func (c LegacyClient) RemoveAclSource(uuid string) error {
+	if _, err := c.ovnNbCommand("remove", "acl", uuid, "external_ids", aclSourceKindKey, aclSourceNamespaceKey, aclSourceNameKey); err != nil {
+		klog.Errorf("failed to remove source of acl %s: %v", uuid, err)
+		return err
+	}
+	return nil
+}
+
 func (c LegacyClient) CreateAddressSet(name string) error {
 	output, err := c.ovnNbCommand("--data=bare", "--no-heading", "--columns=_uuid", "find", "address_set", fmt.Sprintf("name=%s", name))
 	if err != nil {
@@ -1781,7 +2127,15 @@ func (c LegacyClient) CreateNpAddressSet(asName, npNamespace, npName, direction
 	return err
 }
 
-func (c LegacyClient) CombineIngressACLCmd(pgName, asIngressName, asExceptName, protocol string, npp []netv1.NetworkPolicyPort, logEnable bool, aclCmds []string, index int) []string {
+// NpNamedPort is a named port of a network policy resolved to a port number, which only applies to the pods
+// in the address set mapping the name to the number
+type NpNamedPort struct {
+	Protocol   string
+	Port       int32
+	AddressSet string
+}
+
+func (c LegacyClient) CombineIngressACLCmd(pgName, asIngressName, asExceptName, protocol string, npp []netv1.NetworkPolicyPort, namedPorts []NpNamedPort, logEnable bool, aclCmds []string, index int) []string {
 	var allowArgs, ovnArgs []string
 
 	ipSuffix := "ip4"
@@ -1795,10 +2149,14 @@ func (c LegacyClient) CombineIngressACLCmd(pgName, asIngressName, asExceptName,
 		ovnArgs = []string{"--", fmt.Sprintf("--id=@%s.drop.%d", pgName, index), "create", "acl", "action=drop", "direction=to-lport", "log=false", fmt.Sprintf("priority=%s", util.IngressDefaultDrop), fmt.Sprintf("match=\"%s\"", fmt.Sprintf("outport==@%s && ip", pgName)), "--", "add", "port-group", pgName, "acls", fmt.Sprintf("@%s.drop.%d", pgName, index)}
 	}
 
-	if len(npp) == 0 {
+	if len(npp) == 0 && len(namedPorts) == 0 {
 		allowArgs = []string{"--", fmt.Sprintf("--id=@%s.noport.%d", pgName, index), "create", "acl", "action=allow-related", "direction=to-lport", fmt.Sprintf("priority=%s", util.IngressAllowPriority), fmt.Sprintf("match=\"%s\"", fmt.Sprintf("%s.src == $%s && %s.src != $%s && outport==@%s && ip", ipSuffix, asIngressName, ipSuffix, asExceptName, pgName)), "--", "add", "port-group", pgName, "acls", fmt.Sprintf("@%s.noport.%d", pgName, index)}
 		ovnArgs = append(ovnArgs, allowArgs...)
 	} else {
+		for pidx, port := range namedPorts {
+			allowArgs = []string{"--", fmt.Sprintf("--id=@%s.%d.named.%d", pgName, index, pidx), "create", "acl", "action=allow-related", "direction=to-lport", fmt.Sprintf("priority=%s", util.IngressAllowPriority), fmt.Sprintf("match=\"%s\"", fmt.Sprintf("%s.src == $%s && %s.src != $%s && %s.dst == $%s && %s.dst == %d && outport==@%s && ip", ipSuffix, asIngressName, ipSuffix, asExceptName, ipSuffix, port.AddressSet, strings.ToLower(port.Protocol), port.Port, pgName)), "--", "add", "port-group", pgName, "acls", fmt.Sprintf("@%s.%d.named.%d", pgName, index, pidx)}
+			ovnArgs = append(ovnArgs, allowArgs...)
+		}
 		for pidx, port := range npp {
 			if port.Port != nil {
 				if port.EndPort != nil {
@@ -2027,8 +2385,10 @@ func (c LegacyClient) SetAddressesToAddressSet(addresses []string, as string) er
 	return err
 }
 
-// StartOvnNbctlDaemon start a daemon and set OVN_NB_DAEMON env
-func StartOvnNbctlDaemon(ovnNbAddr string) error {
+// StartOvnNbctlDaemon start a daemon and set OVN_NB_DAEMON env,
+// the daemon re-reads the ssl files when they change on reconnection,
+// the inactivity probe is in milliseconds and zero keeps the default of ovn-nbctl
+func StartOvnNbctlDaemon(ovnNbAddr string, inactivityProbe int, sslFiles ovsclient.SSLFiles) error {
 	klog.Infof("start ovn-nbctl daemon")
 	output, err := exec.Command(
 		"pkill",
@@ -2047,15 +2407,18 @@ func StartOvnNbctlDaemon(ovnNbAddr string) error {
 	}
 	if os.Getenv("ENABLE_SSL") == "true" {
 		command = []string{
-			"-p", "/var/run/tls/key",
-			"-c", "/var/run/tls/cert",
-			"-C", "/var/run/tls/cacert",
+			"-p", sslFiles.Key,
+			"-c", sslFiles.Cert,
+			"-C", sslFiles.CACert,
 			fmt.Sprintf("--db=%s", ovnNbAddr),
 			"--pidfile",
 			"--detach",
 			"--overwrite-pidfile",
 		}
 	}
+	if inactivityProbe != 0 {
+		command = append(command, fmt.Sprintf("--inactivity-probe=%d", inactivityProbe))
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd := exec.Command("ovn-nbctl", command...)
@@ -2251,7 +2614,7 @@ func (c LegacyClient) ListSgRuleAddressSet(sgName string, direction AclDirection
 	return strings.Split(output, "\n"), nil
 }
 
-func (c LegacyClient) createSgRuleACL(sgName string, direction AclDirection, rule *kubeovnv1.SgRule, index int) error {
+func genSgRuleAcl(sgName string, direction AclDirection, rule *kubeovnv1.SgRule) (PortGroupAcl, error) {
 	ipSuffix := "ip4"
 	if rule.IPVersion == "ipv6" {
 		ipSuffix = "ip6"
@@ -2283,17 +2646,54 @@ func (c LegacyClient) createSgRuleACL(sgName string, direction AclDirection, rul
 		matchArgs = append(matchArgs, fmt.Sprintf("%d<=%s.dst<=%d", rule.PortRangeMin, rule.Protocol, rule.PortRangeMax))
 	}
 
-	matchStr := strings.Join(matchArgs, " && ")
 	action := "drop"
 	if rule.Policy == kubeovnv1.PolicyAllow {
 		action = "allow-related"
 	}
 	highestPriority, err := strconv.Atoi(util.SecurityGroupHighestPriority)
 	if err != nil {
-		return err
+		return PortGroupAcl{}, err
 	}
-	_, err = c.ovnNbCommand(MayExist, "--type=port-group", "acl-add", sgPortGroupName, string(direction), strconv.Itoa(highestPriority-rule.Priority), matchStr, action)
-	return err
+	return PortGroupAcl{
+		Direction: string(direction),
+		Priority:  strconv.Itoa(highestPriority - rule.Priority),
+		Match:     strings.Join(matchArgs, " && "),
+		Action:    action,
+	}, nil
+}
+
+// genSgAcls generates the acls of the security group in the direction
+func genSgAcls(sg *kubeovnv1.SecurityGroup, direction AclDirection) ([]PortGroupAcl, error) {
+	var acls []PortGroupAcl
+	sgPortGroupName := GetSgPortGroupName(sg.Name)
+	// port_group associated acl
+	if sg.Spec.AllowSameGroupTraffic {
+		v4AsName := GetSgV4AssociatedName(sg.Name)
+		v6AsName := GetSgV6AssociatedName(sg.Name)
+		if direction == SgAclIngressDirection {
+			acls = append(acls,
+				PortGroupAcl{string(direction), util.SecurityGroupAllowPriority, fmt.Sprintf("outport==@%s && ip4 && ip4.src==$%s", sgPortGroupName, v4AsName), "allow-related"},
+				PortGroupAcl{string(direction), util.SecurityGroupAllowPriority, fmt.Sprintf("outport==@%s && ip6 && ip6.src==$%s", sgPortGroupName, v6AsName), "allow-related"})
+		} else {
+			acls = append(acls,
+				PortGroupAcl{string(direction), util.SecurityGroupAllowPriority, fmt.Sprintf("inport==@%s && ip4 && ip4.dst==$%s", sgPortGroupName, v4AsName), "allow-related"},
+				PortGroupAcl{string(direction), util.SecurityGroupAllowPriority, fmt.Sprintf("inport==@%s && ip6 && ip6.dst==$%s", sgPortGroupName, v6AsName), "allow-related"})
+		}
+	}
+
+	// rule acl
+	sgRules := sg.Spec.EgressRules
+	if direction == SgAclIngressDirection {
+		sgRules = sg.Spec.IngressRules
+	}
+	for _, rule := range sgRules {
+		acl, err := genSgRuleAcl(sg.Name, direction, rule)
+		if err != nil {
+			return nil, err
+		}
+		acls = append(acls, acl)
+	}
+	return acls, nil
 }
 
 func (c LegacyClient) CreateSgDenyAllACL() error {
@@ -2321,13 +2721,40 @@ func (c LegacyClient) CreateSgDenyAllACL() error {
 	return nil
 }
 
+// UpdateSgACL applies the difference between the existing and desired acls of the security group, the unchanged
+// acls are kept in place so that there is no traffic disruption during the update
 func (c LegacyClient) UpdateSgACL(sg *kubeovnv1.SecurityGroup, direction AclDirection) error {
 	sgPortGroupName := GetSgPortGroupName(sg.Name)
-	// clear acl
-	if err := c.DeleteACL(sgPortGroupName, string(direction)); err != nil {
+	desired, err := genSgAcls(sg, direction)
+	if err != nil {
+		return err
+	}
+	existing, err := c.ListPortGroupAcls(sgPortGroupName, string(direction))
+	if err != nil {
 		return err
 	}
 
+	// add the new acls and update the changed actions before deleting the stale ones
+	added, updated, removed := diffPortGroupAcls(existing, desired)
+	for _, acl := range added {
+		if err = c.AddPortGroupAcl(sgPortGroupName, acl); err != nil {
+			return err
+		}
+	}
+	for _, acl := range updated {
+		if err = c.SetPortGroupAclAction(sgPortGroupName, acl); err != nil {
+			return err
+		}
+	}
+	for _, acl := range removed {
+		if err = c.DeletePortGroupAcl(sgPortGroupName, acl); err != nil {
+			return err
+		}
+	}
+	if len(added) != 0 || len(updated) != 0 || len(removed) != 0 {
+		klog.Infof("update %s acls of sg %s, %d added, %d updated, %d removed", direction, sg.Name, len(added), len(updated), len(removed))
+	}
+
 	// clear rule address_set
 	asList, err := c.ListSgRuleAddressSet(sg.Name, direction)
 	if err != nil {
@@ -2338,46 +2765,125 @@ func (c LegacyClient) UpdateSgACL(sg *kubeovnv1.SecurityGroup, direction AclDire
 			return err
 		}
 	}
+	return nil
+}
 
-	// create port_group associated acl
-	if sg.Spec.AllowSameGroupTraffic {
-		v4AsName := GetSgV4AssociatedName(sg.Name)
-		v6AsName := GetSgV6AssociatedName(sg.Name)
-		if direction == SgAclIngressDirection {
-			if _, err := c.ovnNbCommand(MayExist, "--type=port-group", "acl-add", sgPortGroupName, "to-lport", util.SecurityGroupAllowPriority,
-				fmt.Sprintf("outport==@%s && ip4 && ip4.src==$%s", sgPortGroupName, v4AsName), "allow-related"); err != nil {
-				return err
-			}
-			if _, err := c.ovnNbCommand(MayExist, "--type=port-group", "acl-add", sgPortGroupName, "to-lport", util.SecurityGroupAllowPriority,
-				fmt.Sprintf("outport==@%s && ip6 && ip6.src==$%s", sgPortGroupName, v6AsName), "allow-related"); err != nil {
-				return err
-			}
-		} else {
-			if _, err := c.ovnNbCommand(MayExist, "--type=port-group", "acl-add", sgPortGroupName, "from-lport", util.SecurityGroupAllowPriority,
-				fmt.Sprintf("inport==@%s && ip4 && ip4.dst==$%s", sgPortGroupName, v4AsName), "allow-related"); err != nil {
-				return err
-			}
-			if _, err := c.ovnNbCommand(MayExist, "--type=port-group", "acl-add", sgPortGroupName, "from-lport", util.SecurityGroupAllowPriority,
-				fmt.Sprintf("inport==@%s && ip6 && ip6.dst==$%s", sgPortGroupName, v6AsName), "allow-related"); err != nil {
-				return err
-			}
+// PortGroupAcl is an acl applied to a port group
+type PortGroupAcl struct {
+	Direction string
+	Priority  string
+	Match     string
+	Action    string
+}
+
+var aclListRegexp = regexp.MustCompile(`^\s*(from-lport|to-lport)\s+(\d+)\s+\((.*)\)\s+(\S+)`)
+
+// parseAclListOutput parses the output of acl-list, for example:
+//
+//	from-lport  2299 (inport==@ovn.sg.sg1 && ip4 && ip4.dst==10.0.0.0/8) allow-related
+func parseAclListOutput(output string) []PortGroupAcl {
+	var acls []PortGroupAcl
+	for _, line := range strings.Split(output, "\n") {
+		fields := aclListRegexp.FindStringSubmatch(line)
+		if fields == nil {
+			continue
 		}
+		acls = append(acls, PortGroupAcl{Direction: fields[1], Priority: fields[2], Match: fields[3], Action: fields[4]})
 	}
+	return acls
+}
 
-	// recreate rule ACL
-	var sgRules []*kubeovnv1.SgRule
-	if direction == SgAclIngressDirection {
-		sgRules = sg.Spec.IngressRules
-	} else {
-		sgRules = sg.Spec.EgressRules
+// portGroupAclKey identifies an acl of a port group, ovn-nbctl refuses to add two acls with the same key
+// and deletes the acl by the key regardless of the action
+type portGroupAclKey struct {
+	Direction string
+	Priority  string
+	Match     string
+}
+
+func (acl PortGroupAcl) key() portGroupAclKey {
+	return portGroupAclKey{Direction: acl.Direction, Priority: acl.Priority, Match: acl.Match}
+}
+
+// diffPortGroupAcls returns the desired acls not existing, the desired acls existing with another action
+// and the existing acls not desired
+func diffPortGroupAcls(existing, desired []PortGroupAcl) (added, updated, removed []PortGroupAcl) {
+	existingActions := make(map[portGroupAclKey]string, len(existing))
+	for _, acl := range existing {
+		existingActions[acl.key()] = acl.Action
 	}
-	for index, rule := range sgRules {
-		if err = c.createSgRuleACL(sg.Name, direction, rule, index); err != nil {
-			return err
+	desiredSet := make(map[portGroupAclKey]bool, len(desired))
+	for _, acl := range desired {
+		if desiredSet[acl.key()] {
+			continue
+		}
+		desiredSet[acl.key()] = true
+		action, ok := existingActions[acl.key()]
+		if !ok {
+			added = append(added, acl)
+		} else if action != acl.Action {
+			updated = append(updated, acl)
+		}
+	}
+	for _, acl := range existing {
+		if !desiredSet[acl.key()] {
+			removed = append(removed, acl)
 		}
 	}
+	return
+}
+
+// ListPortGroupAcls lists the acls of the port group in the direction, all directions if direction is empty
+func (c LegacyClient) ListPortGroupAcls(pgName, direction string) ([]PortGroupAcl, error) {
+	output, err := c.ovnNbCommand("--type=port-group", "acl-list", pgName)
+	if err != nil {
+		klog.Errorf("failed to list acls of port group %s: %v", pgName, err)
+		return nil, err
+	}
+	var acls []PortGroupAcl
+	for _, acl := range parseAclListOutput(output) {
+		if direction == "" || acl.Direction == direction {
+			acls = append(acls, acl)
+		}
+	}
+	return acls, nil
+}
+
+// AddPortGroupAcl adds the acl to the port group
+func (c LegacyClient) AddPortGroupAcl(pgName string, acl PortGroupAcl) error {
+	if _, err := c.ovnNbCommand(MayExist, "--type=port-group", "acl-add", pgName, acl.Direction, acl.Priority, acl.Match, acl.Action); err != nil {
+		klog.Errorf("failed to add acl %v to port group %s: %v", acl, pgName, err)
+		return err
+	}
+	return nil
+}
+
+// SetPortGroupAclAction updates the action of the existing acl of the port group in place
+func (c LegacyClient) SetPortGroupAclAction(pgName string, acl PortGroupAcl) error {
+	results, err := c.CustomFindEntity("acl", []string{"_uuid"}, fmt.Sprintf("priority=%s", acl.Priority), fmt.Sprintf("direction=%s", acl.Direction), fmt.Sprintf("match=\"%s\"", acl.Match))
+	if err != nil {
+		klog.Errorf("failed to find acl %v of port group %s: %v", acl, pgName, err)
+		return err
+	}
+	if len(results) == 0 || len(results[0]["_uuid"]) == 0 {
+		return c.AddPortGroupAcl(pgName, acl)
+	}
+	if _, err = c.ovnNbCommand("set", "acl", results[0]["_uuid"][0], fmt.Sprintf("action=%s", acl.Action)); err != nil {
+		klog.Errorf("failed to set action of acl %v of port group %s: %v", acl, pgName, err)
+		return err
+	}
 	return nil
 }
+
+// DeletePortGroupAcl deletes the acl from the port group
+func (c LegacyClient) DeletePortGroupAcl(pgName string, acl PortGroupAcl) error {
+	if _, err := c.ovnNbCommand("--type=port-group", "acl-del", pgName, acl.Direction, acl.Priority, acl.Match); err != nil {
+		klog.Errorf("failed to delete acl %v from port group %s: %v", acl, pgName, err)
+		return err
+	}
+	return nil
+}
+
 func (c LegacyClient) OvnGet(table, record, column, key string) (string, error) {
 	var columnVal string
 	if key == "" {
@@ -2499,6 +3005,37 @@ func (c LegacyClient) SetPolicyRouteExternalIds(priority int32, match string, na
 	return nil
 }
 
+// SetPolicyRouteNextHops updates the next hops of the policy route in place rather than deleting and re-adding it,
+// the external ids of the added and removed gateway nodes are updated in the same transaction.
+// It returns false if the policy route does not exist
+func (c LegacyClient) SetPolicyRouteNextHops(priority int32, match string, nextHops []string, addNameIpMap map[string]string, delNames []string) (bool, error) {
+	result, err := c.CustomFindEntity("Logical_Router_Policy", []string{"_uuid"}, fmt.Sprintf("priority=%d", priority), fmt.Sprintf("match=\"%s\"", match))
+	if err != nil {
+		klog.Errorf("customFindEntity failed, %v", err)
+		return false, err
+	}
+	if len(result) == 0 {
+		return false, nil
+	}
+
+	uuid := result[0]["_uuid"][0]
+	quoted := make([]string, 0, len(nextHops))
+	for _, nextHop := range nextHops {
+		quoted = append(quoted, fmt.Sprintf("%q", nextHop))
+	}
+	ovnCmd := []string{"set", "logical-router-policy", uuid, fmt.Sprintf("nexthops=[%s]", strings.Join(quoted, ","))}
+	for nodeName, nodeIP := range addNameIpMap {
+		ovnCmd = append(ovnCmd, fmt.Sprintf("external_ids:%s=\"%s\"", nodeName, nodeIP))
+	}
+	for _, nodeName := range delNames {
+		ovnCmd = append(ovnCmd, "--", "remove", "logical-router-policy", uuid, "external_ids", nodeName)
+	}
+	if _, err = c.ovnNbCommand(ovnCmd...); err != nil {
+		return false, fmt.Errorf("failed to set next hops of logical-router-policy %s, %v", uuid, err)
+	}
+	return true, nil
+}
+
 func (c LegacyClient) CheckPolicyRouteNexthopConsistent(router, match, nexthop string, priority int32) (bool, error) {
 	exist, err := c.PolicyRouteExists(priority, match)
 	if err != nil {
@@ -2596,8 +3133,14 @@ func (c *LegacyClient) createDHCPOptions(ls, cidr, optionsStr string) (dhcpOptio
 	return dhcpOptionsUuid, nil
 }
 
-func (c *LegacyClient) updateDHCPv4Options(ls, v4CIDR, v4Gateway, dhcpV4OptionsStr string) (dhcpV4OptionsUuid string, err error) {
+func (c *LegacyClient) updateDHCPv4Options(ls, v4CIDR, v4Gateway, dhcpV4OptionsStr string, leaseTime int) (dhcpV4OptionsUuid string, err error) {
 	dhcpV4OptionsStr = strings.ReplaceAll(dhcpV4OptionsStr, " ", "")
+	if leaseTime == 0 {
+		leaseTime = 3600
+	} else if len(dhcpV4OptionsStr) != 0 {
+		// the lease time is validated not to be specified by the custom options
+		dhcpV4OptionsStr = fmt.Sprintf("%s,lease_time=%d", dhcpV4OptionsStr, leaseTime)
+	}
 	dhcpV4Options, err := c.ListDHCPOptions(true, ls, kubeovnv1.ProtocolIPv4)
 	if err != nil {
 		klog.Errorf("list dhcp options for switch %s protocol %s failed: %v", ls, kubeovnv1.ProtocolIPv4, err)
@@ -2610,7 +3153,7 @@ func (c *LegacyClient) updateDHCPv4Options(ls, v4CIDR, v4Gateway, dhcpV4OptionsS
 			mac := util.GenerateMac()
 			if len(dhcpV4OptionsStr) == 0 {
 				// default dhcp v4 options
-				dhcpV4OptionsStr = fmt.Sprintf("lease_time=%d,router=%s,server_id=%s,server_mac=%s", 3600, v4Gateway, "169.254.0.254", mac)
+				dhcpV4OptionsStr = fmt.Sprintf("lease_time=%d,router=%s,server_id=%s,server_mac=%s", leaseTime, v4Gateway, "169.254.0.254", mac)
 			}
 			dhcpV4OptionsUuid, err = c.createDHCPOptions(ls, v4CIDR, dhcpV4OptionsStr)
 			if err != nil {
@@ -2618,14 +3161,14 @@ func (c *LegacyClient) updateDHCPv4Options(ls, v4CIDR, v4Gateway, dhcpV4OptionsS
 				return "", err
 			}
 		} else {
-			// update
+			// update in place, so that the logical switch ports keep referring to the same dhcp options
 			v4Options := dhcpV4Options[0]
 			if len(dhcpV4OptionsStr) == 0 {
 				mac := v4Options.options["server_mac"]
 				if len(mac) == 0 {
 					mac = util.GenerateMac()
 				}
-				dhcpV4OptionsStr = fmt.Sprintf("lease_time=%d,router=%s,server_id=%s,server_mac=%s", 3600, v4Gateway, "169.254.0.254", mac)
+				dhcpV4OptionsStr = fmt.Sprintf("lease_time=%d,router=%s,server_id=%s,server_mac=%s", leaseTime, v4Gateway, "169.254.0.254", mac)
 			}
 			_, err = c.ovnNbCommand("set", "dhcp_options", v4Options.UUID, fmt.Sprintf("cidr=%s", v4CIDR),
 				fmt.Sprintf("options=%s", strings.ReplaceAll(dhcpV4OptionsStr, ":", "\\:")))
@@ -2695,9 +3238,11 @@ func (c *LegacyClient) updateDHCPv6Options(ls, v6CIDR, dhcpV6OptionsStr string)
 	return
 }
 
-func (c *LegacyClient) UpdateDHCPOptions(ls, cidrBlock, gateway, dhcpV4OptionsStr, dhcpV6OptionsStr string, enableDHCP bool) (dhcpOptionsUUIDs *DHCPOptionsUUIDs, err error) {
+// UpdateDHCPOptions creates or updates the dhcp options of the enabled protocols of the logical switch,
+// and deletes the dhcp options of the disabled protocols. The lease time only applies to dhcpv4
+func (c *LegacyClient) UpdateDHCPOptions(ls, cidrBlock, gateway, dhcpV4OptionsStr, dhcpV6OptionsStr string, dhcpLeaseTime int, enableDHCPv4, enableDHCPv6 bool) (dhcpOptionsUUIDs *DHCPOptionsUUIDs, err error) {
 	dhcpOptionsUUIDs = &DHCPOptionsUUIDs{}
-	if enableDHCP {
+	if enableDHCPv4 || enableDHCPv6 {
 		var v4CIDR, v6CIDR string
 		var v4Gateway string
 		switch util.CheckProtocol(cidrBlock) {
@@ -2712,8 +3257,15 @@ func (c *LegacyClient) UpdateDHCPOptions(ls, cidrBlock, gateway, dhcpV4OptionsSt
 			v4CIDR, v6CIDR = cidrBlocks[0], cidrBlocks[1]
 			v4Gateway = gateways[0]
 		}
+		// the dhcp options of a protocol are deleted if its cidr is empty
+		if !enableDHCPv4 {
+			v4CIDR = ""
+		}
+		if !enableDHCPv6 {
+			v6CIDR = ""
+		}
 
-		dhcpOptionsUUIDs.DHCPv4OptionsUUID, err = c.updateDHCPv4Options(ls, v4CIDR, v4Gateway, dhcpV4OptionsStr)
+		dhcpOptionsUUIDs.DHCPv4OptionsUUID, err = c.updateDHCPv4Options(ls, v4CIDR, v4Gateway, dhcpV4OptionsStr, dhcpLeaseTime)
 		if err != nil {
 			klog.Errorf("update dhcp options for switch %s failed: %v", ls, err)
 			return nil, err
@@ -2861,6 +3413,95 @@ func (c LegacyClient) UpdateSubnetACL(ls string, acls []kubeovnv1.Acl) error {
 	return nil
 }
 
+// nbAcl is an acl listed by findAcls
+type nbAcl struct {
+	UUID      string
+	Direction string
+	Priority  string
+	Match     string
+}
+
+// findAcls lists the acls matching the conditions
+func (c LegacyClient) findAcls(conditions ...string) ([]nbAcl, error) {
+	args := append([]string{"--data=bare", "--format=csv", "--no-heading", "--columns=_uuid,direction,priority,match", "find", "acl"}, conditions...)
+	output, err := c.ovnNbCommand(args...)
+	if err != nil {
+		return nil, err
+	}
+	var acls []nbAcl
+	for _, l := range strings.Split(output, "\n") {
+		if len(strings.TrimSpace(l)) == 0 {
+			continue
+		}
+		// match is the last column as it may contain commas
+		parts := strings.SplitN(strings.TrimSpace(l), ",", 4)
+		if len(parts) != 4 {
+			continue
+		}
+		acls = append(acls, nbAcl{
+			UUID:      parts[0],
+			Direction: parts[1],
+			Priority:  parts[2],
+			Match:     strings.ReplaceAll(strings.TrimSuffix(strings.TrimPrefix(parts[3], `"`), `"`), `""`, `"`),
+		})
+	}
+	return acls, nil
+}
+
+// SetLogicalSwitchGatewayPing allows the icmp echo requests from the pods in the cidr to the gateway of the subnet,
+// which may be dropped by the acls of the subnet, network policies or security groups
+func (c LegacyClient) SetLogicalSwitchGatewayPing(ls, cidr, gateway string, enable bool) error {
+	var matches []string
+	if enable {
+		for _, cidrBlock := range strings.Split(cidr, ",") {
+			for _, gw := range strings.Split(gateway, ",") {
+				protocol := util.CheckProtocol(cidrBlock)
+				if protocol != util.CheckProtocol(gw) {
+					continue
+				}
+				match := fmt.Sprintf("ip4.src == %s && ip4.dst == %s && icmp4.type == 8", cidrBlock, gw)
+				if protocol == kubeovnv1.ProtocolIPv6 {
+					match = fmt.Sprintf("ip6.src == %s && ip6.dst == %s && icmp6.type == 128", cidrBlock, gw)
+				}
+				matches = append(matches, match)
+			}
+		}
+	}
+
+	acls, err := c.findAcls(fmt.Sprintf("external_ids:gateway-ping=\"%s\"", ls))
+	if err != nil {
+		klog.Errorf("failed to list gateway ping acls of logical switch %s, %v", ls, err)
+		return err
+	}
+	// only the changed acls are touched to avoid dropping the gateway pings in between
+	var ovnArgs []string
+	existing := make(map[string]bool, len(acls))
+	for _, acl := range acls {
+		if acl.Direction == "from-lport" && acl.Priority == util.GatewayPingAllowPriority && util.ContainsString(matches, acl.Match) && !existing[acl.Match] {
+			existing[acl.Match] = true
+			continue
+		}
+		ovnArgs = append(ovnArgs, "--", IfExists, "remove", "logical_switch", ls, "acls", acl.UUID)
+	}
+	for _, match := range matches {
+		if existing[match] {
+			continue
+		}
+		id := fmt.Sprintf("@gwping%d", len(ovnArgs))
+		ovnArgs = append(ovnArgs, "--", fmt.Sprintf("--id=%s", id), "create", "acl", "action=allow-related", "direction=from-lport",
+			fmt.Sprintf("priority=%s", util.GatewayPingAllowPriority), fmt.Sprintf("match=\"%s\"", match), fmt.Sprintf("external_ids:gateway-ping=\"%s\"", ls),
+			"--", "add", "logical_switch", ls, "acls", id)
+	}
+	if len(ovnArgs) == 0 {
+		return nil
+	}
+	if _, err = c.ovnNbCommand(ovnArgs...); err != nil {
+		klog.Errorf("failed to update gateway ping acls of logical switch %s, %v", ls, err)
+		return err
+	}
+	return nil
+}
+
 func (c *LegacyClient) GetLspExternalIds(lsp string) map[string]string {
 	result, err := c.CustomFindEntity("Logical_Switch_Port", []string{"external_ids"}, fmt.Sprintf("name=%s", lsp))
 	if err != nil {
@@ -2926,3 +3567,265 @@ func (c LegacyClient) SetAclLog(pgName string, logEnable, isIngress bool) error
 
 	return nil
 }
+
+// SetLogicalSwitchPortsArpResponder enables or disables the arp/nd responder of the pod ports in the logical switch,
+// the requests to the ports are flooded if the responder is disabled
+func (c LegacyClient) SetLogicalSwitchPortsArpResponder(ls string, enable bool) error {
+	ports, err := c.ListLogicalEntity("logical_switch_port", "type=\"\"", fmt.Sprintf("external_ids:ls=%s", ls))
+	if err != nil {
+		klog.Errorf("failed to list ports of logical switch %s, %v", ls, err)
+		return err
+	}
+	if len(ports) == 0 {
+		return nil
+	}
+
+	var ovnArgs []string
+	for _, port := range ports {
+		if enable {
+			ovnArgs = append(ovnArgs, "--", IfExists, "remove", "logical_switch_port", port, "options", "disable_arp_nd_rsp")
+		} else {
+			ovnArgs = append(ovnArgs, "--", IfExists, "set", "logical_switch_port", port, "options:disable_arp_nd_rsp=true")
+		}
+	}
+	if _, err = c.ovnNbCommand(ovnArgs...); err != nil {
+		klog.Errorf("failed to set arp responder of ports in logical switch %s, %v", ls, err)
+		return err
+	}
+	return nil
+}
+
+// SetPortArpResponder enables or disables the arp/nd responder of the logical switch port
+func (c LegacyClient) SetPortArpResponder(port string, enable bool) error {
+	var err error
+	if enable {
+		_, err = c.ovnNbCommand(IfExists, "remove", "logical_switch_port", port, "options", "disable_arp_nd_rsp")
+	} else {
+		_, err = c.ovnNbCommand(IfExists, "set", "logical_switch_port", port, "options:disable_arp_nd_rsp=true")
+	}
+	if err != nil {
+		klog.Errorf("failed to set arp responder of port %s, %v", port, err)
+		return err
+	}
+	return nil
+}
+
+// SetRouterPortArpProxy sets the cidrs answered by proxy arp/nd of the router type port between the logical switch
+// and the router, the proxy is removed if cidrs is empty
+func (c LegacyClient) SetRouterPortArpProxy(ls, lr string, cidrs []string) error {
+	lsTolr := fmt.Sprintf("%s-%s", ls, lr)
+	var err error
+	if len(cidrs) == 0 {
+		_, err = c.ovnNbCommand(IfExists, "remove", "logical_switch_port", lsTolr, "options", "arp_proxy")
+	} else {
+		_, err = c.ovnNbCommand(IfExists, "set", "logical_switch_port", lsTolr, fmt.Sprintf("options:arp_proxy=\"%s\"", strings.Join(cidrs, " ")))
+	}
+	if err != nil {
+		klog.Errorf("failed to set arp proxy of router port %s, %v", lsTolr, err)
+		return err
+	}
+	return nil
+}
+
+// SetRouterPortGatewayArpSuppression drops the arp replies and neighbor advertisements for the gateways sent by the
+// router port between the logical switch and the router, and disables the arp/nd responder of the router type port,
+// so that the requests for the gateways are only answered by the physical gateway. The suppression is removed if
+// gateways is empty
+func (c LegacyClient) SetRouterPortGatewayArpSuppression(ls, lr string, gateways []string) error {
+	lsTolr := fmt.Sprintf("%s-%s", ls, lr)
+	var match string
+	if len(gateways) != 0 {
+		matches := make([]string, 0, len(gateways))
+		for _, gw := range gateways {
+			if util.CheckProtocol(gw) == kubeovnv1.ProtocolIPv4 {
+				matches = append(matches, fmt.Sprintf("(arp.op == 2 && arp.spa == %s)", gw))
+			} else {
+				matches = append(matches, fmt.Sprintf("(nd_na && nd.target == %s)", gw))
+			}
+		}
+		match = fmt.Sprintf(`inport == "%s" && (%s)`, lsTolr, strings.Join(matches, " || "))
+	}
+
+	acls, err := c.findAcls(fmt.Sprintf("external_ids:gateway_arp_suppression=\"%s\"", ls))
+	if err != nil {
+		klog.Errorf("failed to list gateway arp suppression acls of logical switch %s, %v", ls, err)
+		return err
+	}
+	// the acl is created together with the port option, so both are in place if the acl is unchanged
+	if len(acls) == 0 && match == "" {
+		return nil
+	}
+	if len(acls) == 1 && acls[0].Priority == util.GatewayArpSuppressionPriority && acls[0].Match == match {
+		return nil
+	}
+
+	var ovnArgs []string
+	for _, acl := range acls {
+		ovnArgs = append(ovnArgs, "--", IfExists, "remove", "logical_switch", ls, "acls", acl.UUID)
+	}
+	if match == "" {
+		ovnArgs = append(ovnArgs, "--", IfExists, "remove", "logical_switch_port", lsTolr, "options", "disable_arp_nd_rsp")
+	} else {
+		ovnArgs = append(ovnArgs, "--", "--id=@acl", "create", "acl", "direction=from-lport", fmt.Sprintf("priority=%s", util.GatewayArpSuppressionPriority),
+			fmt.Sprintf("match=\"%s\"", strings.ReplaceAll(match, `"`, `\"`)), "action=drop", fmt.Sprintf("external_ids:gateway_arp_suppression=%s", ls),
+			"--", "add", "logical_switch", ls, "acls", "@acl",
+			"--", IfExists, "set", "logical_switch_port", lsTolr, "options:disable_arp_nd_rsp=true")
+	}
+	if len(ovnArgs) == 0 {
+		return nil
+	}
+	if _, err = c.ovnNbCommand(ovnArgs...); err != nil {
+		klog.Errorf("failed to set gateway arp suppression of router port %s, %v", lsTolr, err)
+		return err
+	}
+	return nil
+}
+
+// broadcastRateLimitQosCmd returns the command creating the qos limiting the arp/nd/broadcast rate of the port,
+// dhcp requests are excluded
+func broadcastRateLimitQosCmd(ls, port string, rate int) []string {
+	return []string{"--", "--id=@qos", "create", "qos", "direction=from-lport", fmt.Sprintf("priority=%s", util.BroadcastRateLimitQosPriority),
+		fmt.Sprintf("match=\"%s\"", strings.ReplaceAll(fmt.Sprintf(util.BroadcastRateLimitQosMatchTemplate, port), `"`, `\"`)), fmt.Sprintf("bandwidth:rate=%d", rate),
+		fmt.Sprintf("external_ids:broadcast_rate_limit=%s", ls), fmt.Sprintf("external_ids:port=%s", port), fmt.Sprintf("external_ids:rate=%d", rate),
+		"--", "add", "logical_switch", ls, "qos_rules", "@qos"}
+}
+
+// listBroadcastRateLimitQos returns the uuid, logical switch, port and rate of the broadcast rate limit qos
+func (c LegacyClient) listBroadcastRateLimitQos(args ...string) ([][4]string, error) {
+	results, err := c.CustomFindEntity("qos", []string{"_uuid", "external_ids"}, args...)
+	if err != nil {
+		klog.Errorf("failed to list broadcast rate limit qos, %v", err)
+		return nil, err
+	}
+	qosList := make([][4]string, 0, len(results))
+	for _, result := range results {
+		if len(result["_uuid"]) == 0 {
+			continue
+		}
+		qos := [4]string{result["_uuid"][0]}
+		for _, kv := range result["external_ids"] {
+			switch k, v, _ := strings.Cut(kv, "="); k {
+			case "broadcast_rate_limit":
+				qos[1] = v
+			case "port":
+				qos[2] = v
+			case "rate":
+				qos[3] = v
+			}
+		}
+		// the port key may be used by other qos
+		if qos[1] != "" {
+			qosList = append(qosList, qos)
+		}
+	}
+	return qosList, nil
+}
+
+// SetLogicalSwitchBroadcastRateLimit limits the arp/nd/broadcast rate in kbps of each pod port in the logical switch
+// by ovn qos meters dropping the excess packets, the limits are removed if rate is 0
+func (c LegacyClient) SetLogicalSwitchBroadcastRateLimit(ls string, rate int) error {
+	qosList, err := c.listBroadcastRateLimitQos(fmt.Sprintf("external_ids:broadcast_rate_limit=%s", ls))
+	if err != nil {
+		return err
+	}
+	desired := map[string]bool{}
+	if rate != 0 {
+		ports, err := c.ListLogicalEntity("logical_switch_port", "type=\"\"", fmt.Sprintf("external_ids:ls=%s", ls))
+		if err != nil {
+			klog.Errorf("failed to list ports of logical switch %s, %v", ls, err)
+			return err
+		}
+		for _, port := range ports {
+			desired[port] = true
+		}
+	}
+
+	var cmd []string
+	for _, qos := range qosList {
+		if desired[qos[2]] && qos[3] == strconv.Itoa(rate) {
+			delete(desired, qos[2])
+			continue
+		}
+		cmd = append(cmd, "--", "remove", "logical_switch", ls, "qos_rules", qos[0])
+	}
+	for port := range desired {
+		cmd = append(cmd, broadcastRateLimitQosCmd(ls, port, rate)...)
+	}
+	if len(cmd) == 0 {
+		return nil
+	}
+	if _, err = c.ovnNbCommand(cmd...); err != nil {
+		klog.Errorf("failed to set broadcast rate limit %d of logical switch %s: %v", rate, ls, err)
+		return err
+	}
+	return nil
+}
+
+// SetLogicalSwitchOptions sets the other_config options of the logical switch in place,
+// the supported options not in options are removed from the logical switch
+func (c LegacyClient) SetLogicalSwitchOptions(ls string, options map[string]string) error {
+	var set, remove []string
+	for key := range util.LogicalSwitchOptions {
+		if value, ok := options[key]; ok {
+			set = append(set, fmt.Sprintf(`other_config:%s="%s"`, key, value))
+		} else {
+			remove = append(remove, key)
+		}
+	}
+	sort.Strings(set)
+	sort.Strings(remove)
+
+	var cmd []string
+	if len(set) != 0 {
+		cmd = append([]string{"set", "logical_switch", ls}, set...)
+	}
+	if len(remove) != 0 {
+		cmd = append(append(cmd, "--", "remove", "logical_switch", ls, "other_config"), remove...)
+	}
+	if _, err := c.ovnNbCommand(cmd...); err != nil {
+		klog.Errorf("failed to set options %v of logical switch %s: %v", options, ls, err)
+		return err
+	}
+	return nil
+}
+
+// SetPortBroadcastRateLimit limits the arp/nd/broadcast rate in kbps of the port in the logical switch
+func (c LegacyClient) SetPortBroadcastRateLimit(ls, port string, rate int) error {
+	qosList, err := c.listBroadcastRateLimitQos(fmt.Sprintf("external_ids:port=%s", port))
+	if err != nil {
+		return err
+	}
+	var cmd []string
+	for _, qos := range qosList {
+		if qos[1] == ls && qos[3] == strconv.Itoa(rate) {
+			return nil
+		}
+		cmd = append(cmd, "--", IfExists, "remove", "logical_switch", qos[1], "qos_rules", qos[0])
+	}
+	cmd = append(cmd, broadcastRateLimitQosCmd(ls, port, rate)...)
+	if _, err = c.ovnNbCommand(cmd...); err != nil {
+		klog.Errorf("failed to set broadcast rate limit %d of port %s: %v", rate, port, err)
+		return err
+	}
+	return nil
+}
+
+// DeletePortBroadcastRateLimit removes the arp/nd/broadcast rate limit of the port
+func (c LegacyClient) DeletePortBroadcastRateLimit(port string) error {
+	qosList, err := c.listBroadcastRateLimitQos(fmt.Sprintf("external_ids:port=%s", port))
+	if err != nil {
+		return err
+	}
+	var cmd []string
+	for _, qos := range qosList {
+		cmd = append(cmd, "--", IfExists, "remove", "logical_switch", qos[1], "qos_rules", qos[0])
+	}
+	if len(cmd) == 0 {
+		return nil
+	}
+	if _, err = c.ovnNbCommand(cmd...); err != nil {
+		klog.Errorf("failed to delete broadcast rate limit of port %s: %v", port, err)
+		return err
+	}
+	return nil
+}
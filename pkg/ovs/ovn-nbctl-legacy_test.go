@@ -1,9 +1,15 @@
 package ovs
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
 )
 
 func Test_parseLrRouteListOutput(t *testing.T) {
@@ -41,3 +47,159 @@ func Test_parseLrPolicyRouteListOutput(t *testing.T) {
 	ast.Nil(err)
 	ast.Equal(6, len(routeList))
 }
+
+func Test_parseAclListOutput(t *testing.T) {
+	ast := assert.New(t)
+	output := `from-lport  2299 (inport==@ovn.sg.sg1 && ip4 && ip4.dst==10.0.0.0/8 && 80<=tcp.dst<=80) allow-related
+  to-lport  2299 (outport==@ovn.sg.sg1 && ip4 && ip4.src==$ovn.sg.sg2.associated.v4) drop
+  to-lport  2003 (outport==@ovn.sg.sg1 && ip6 && ip6.src==$ovn.sg.sg1.associated.v6) allow-related log(name=<unnamed>,severity=info)`
+	acls := parseAclListOutput(output)
+	ast.Equal([]PortGroupAcl{
+		{"from-lport", "2299", "inport==@ovn.sg.sg1 && ip4 && ip4.dst==10.0.0.0/8 && 80<=tcp.dst<=80", "allow-related"},
+		{"to-lport", "2299", "outport==@ovn.sg.sg1 && ip4 && ip4.src==$ovn.sg.sg2.associated.v4", "drop"},
+		{"to-lport", "2003", "outport==@ovn.sg.sg1 && ip6 && ip6.src==$ovn.sg.sg1.associated.v6", "allow-related"},
+	}, acls)
+	ast.Empty(parseAclListOutput(""))
+}
+
+func Test_UpdateSgAclDiff(t *testing.T) {
+	ast := assert.New(t)
+	sg := &kubeovnv1.SecurityGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "sg1"},
+		Spec: kubeovnv1.SecurityGroupSpec{
+			AllowSameGroupTraffic: true,
+			IngressRules: []*kubeovnv1.SgRule{
+				{IPVersion: "ipv4", RemoteType: kubeovnv1.SgRemoteTypeAddress, RemoteAddress: "10.0.0.0/8", Protocol: kubeovnv1.ProtocolTCP, PortRangeMin: 80, PortRangeMax: 80, Priority: 1, Policy: kubeovnv1.PolicyAllow},
+				{IPVersion: "ipv4", RemoteType: kubeovnv1.SgRemoteTypeAddress, RemoteAddress: "192.168.0.0/16", Protocol: kubeovnv1.ProtocolICMP, Priority: 2, Policy: kubeovnv1.PolicyDrop},
+			},
+		},
+	}
+	existing, err := genSgAcls(sg, SgAclIngressDirection)
+	ast.Nil(err)
+	ast.Len(existing, 4)
+
+	// nothing changes without rule updates
+	added, changed, removed := diffPortGroupAcls(existing, existing)
+	ast.Empty(added)
+	ast.Empty(changed)
+	ast.Empty(removed)
+
+	// update the second rule and add a new one
+	updated := sg.DeepCopy()
+	updated.Spec.IngressRules[1].RemoteAddress = "172.16.0.0/12"
+	updated.Spec.IngressRules = append(updated.Spec.IngressRules, &kubeovnv1.SgRule{IPVersion: "ipv6", RemoteType: kubeovnv1.SgRemoteTypeAddress, RemoteAddress: "fd00::/64", Protocol: kubeovnv1.ProtocolUDP, PortRangeMin: 53, PortRangeMax: 53, Priority: 3, Policy: kubeovnv1.PolicyAllow})
+	desired, err := genSgAcls(updated, SgAclIngressDirection)
+	ast.Nil(err)
+	added, changed, removed = diffPortGroupAcls(existing, desired)
+	ast.Equal([]PortGroupAcl{desired[3], desired[4]}, added)
+	ast.Empty(changed)
+	ast.Equal([]PortGroupAcl{existing[3]}, removed)
+
+	// the unchanged acls are neither added nor removed
+	for _, acl := range existing[:3] {
+		ast.NotContains(added, acl)
+		ast.NotContains(removed, acl)
+	}
+
+	// the acl of a rule with only the policy changed is updated in place instead of being added and removed
+	policyUpdated := sg.DeepCopy()
+	policyUpdated.Spec.IngressRules[0].Policy = kubeovnv1.PolicyDrop
+	desired, err = genSgAcls(policyUpdated, SgAclIngressDirection)
+	ast.Nil(err)
+	added, changed, removed = diffPortGroupAcls(existing, desired)
+	ast.Empty(added)
+	ast.Equal([]PortGroupAcl{desired[2]}, changed)
+	ast.Empty(removed)
+}
+
+// fakeNbAcls mimics the acl commands of ovn-nbctl on a single port group
+type fakeNbAcls struct {
+	acls []PortGroupAcl
+}
+
+func (f *fakeNbAcls) exec(args ...string) ([]byte, error) {
+	var mayExist bool
+	for len(args) != 0 && strings.HasPrefix(args[0], "--") {
+		mayExist = mayExist || args[0] == MayExist
+		args = args[1:]
+	}
+	switch args[0] {
+	case "acl-list":
+		var lines []string
+		for _, acl := range f.acls {
+			lines = append(lines, fmt.Sprintf("%10s %5s (%s) %s", acl.Direction, acl.Priority, acl.Match, acl.Action))
+		}
+		return []byte(strings.Join(lines, "\n")), nil
+	case "acl-add":
+		acl := PortGroupAcl{args[2], args[3], args[4], args[5]}
+		for _, existing := range f.acls {
+			if existing.key() == acl.key() {
+				if mayExist {
+					return nil, nil
+				}
+				return nil, fmt.Errorf("duplicate acl")
+			}
+		}
+		f.acls = append(f.acls, acl)
+	case "acl-del":
+		key := portGroupAclKey{args[2], args[3], args[4]}
+		for i, acl := range f.acls {
+			if acl.key() == key {
+				f.acls = append(f.acls[:i], f.acls[i+1:]...)
+				break
+			}
+		}
+	case "find":
+		if args[1] != "acl" {
+			return nil, nil
+		}
+		for i, acl := range f.acls {
+			key := portGroupAclKey{strings.TrimPrefix(args[3], "direction="), strings.TrimPrefix(args[2], "priority="), strings.Trim(strings.TrimPrefix(args[4], "match="), `"`)}
+			if acl.key() == key {
+				return []byte(strconv.Itoa(i)), nil
+			}
+		}
+	case "set":
+		i, _ := strconv.Atoi(args[2])
+		f.acls[i].Action = strings.TrimPrefix(args[3], "action=")
+	}
+	return nil, nil
+}
+
+func Test_UpdateSgACL(t *testing.T) {
+	ast := assert.New(t)
+	sg := &kubeovnv1.SecurityGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "sg1"},
+		Spec: kubeovnv1.SecurityGroupSpec{
+			IngressRules: []*kubeovnv1.SgRule{
+				{IPVersion: "ipv4", RemoteType: kubeovnv1.SgRemoteTypeAddress, RemoteAddress: "10.0.0.0/8", Protocol: kubeovnv1.ProtocolTCP, PortRangeMin: 80, PortRangeMax: 80, Priority: 1, Policy: kubeovnv1.PolicyAllow},
+				{IPVersion: "ipv4", RemoteType: kubeovnv1.SgRemoteTypeAddress, RemoteAddress: "192.168.0.0/16", Protocol: kubeovnv1.ProtocolICMP, Priority: 2, Policy: kubeovnv1.PolicyAllow},
+			},
+		},
+	}
+	nb := &fakeNbAcls{}
+	defer func(orig func(args ...string) ([]byte, error)) { ovnNbCtlExec = orig }(ovnNbCtlExec)
+	ovnNbCtlExec = nb.exec
+
+	client := LegacyClient{}
+	ast.Nil(client.UpdateSgACL(sg, SgAclIngressDirection))
+	desired, err := genSgAcls(sg, SgAclIngressDirection)
+	ast.Nil(err)
+	ast.Equal(desired, nb.acls)
+
+	// only the policy of the first rule changes, its acl must be kept with the new action
+	updated := sg.DeepCopy()
+	updated.Spec.IngressRules[0].Policy = kubeovnv1.PolicyDrop
+	ast.Nil(client.UpdateSgACL(updated, SgAclIngressDirection))
+	desired, err = genSgAcls(updated, SgAclIngressDirection)
+	ast.Nil(err)
+	ast.Equal(desired, nb.acls)
+	ast.Equal("drop", nb.acls[0].Action)
+
+	// the rule removed is deleted while the others are kept
+	updated.Spec.IngressRules = updated.Spec.IngressRules[:1]
+	ast.Nil(client.UpdateSgACL(updated, SgAclIngressDirection))
+	desired, err = genSgAcls(updated, SgAclIngressDirection)
+	ast.Nil(err)
+	ast.Equal(desired, nb.acls)
+}
@@ -18,14 +18,17 @@ func (c LegacyClient) ovnSbCommand(cmdArgs ...string) (string, error) {
 		cmdArgs = append([]string{
 			fmt.Sprintf("--timeout=%d", c.OvnTimeout),
 			fmt.Sprintf("--db=%s", c.OvnSbAddress),
-			"-p", "/var/run/tls/key",
-			"-c", "/var/run/tls/cert",
-			"-C", "/var/run/tls/cacert"}, cmdArgs...)
+			"-p", c.SSLFiles.Key,
+			"-c", c.SSLFiles.Cert,
+			"-C", c.SSLFiles.CACert}, cmdArgs...)
 	} else {
 		cmdArgs = append([]string{
 			fmt.Sprintf("--timeout=%d", c.OvnTimeout),
 			fmt.Sprintf("--db=%s", c.OvnSbAddress)}, cmdArgs...)
 	}
+	if c.OvnInactivityProbe != 0 {
+		cmdArgs = append([]string{fmt.Sprintf("--inactivity-probe=%d", c.OvnInactivityProbe)}, cmdArgs...)
+	}
 	raw, err := exec.Command(OvnSbCtl, cmdArgs...).CombinedOutput()
 	elapsed := float64((time.Since(start)) / time.Millisecond)
 	klog.V(4).Infof("command %s %s in %vms", OvnSbCtl, strings.Join(cmdArgs, " "), elapsed)
@@ -116,19 +119,85 @@ func (c LegacyClient) InitChassisNodeTag(chassisName string, nodeName string) er
 	return nil
 }
 
-// GetAllChassis get all chassis init by kube-ovn
-func (c LegacyClient) GetAllChassis() ([]string, error) {
-	output, err := c.ovnSbCommand("--format=csv", "--no-heading", "--data=bare", "--columns=name", "find", "chassis", fmt.Sprintf("external_ids:vendor=%s", util.CniTypeName))
+// GetAllChassisNodes returns the hostname and the node name tagged by kube-ovn of all chassis init by kube-ovn, indexed by chassis name
+func (c LegacyClient) GetAllChassisNodes() (map[string][]string, error) {
+	output, err := c.ovnSbCommand("--format=csv", "--no-heading", "--data=bare", "--columns=name,hostname,external_ids", "find", "chassis", fmt.Sprintf("external_ids:vendor=%s", util.CniTypeName))
 	if err != nil {
 		return nil, fmt.Errorf("failed to find node chassis, %v", err)
 	}
-	lines := strings.Split(output, "\n")
-	result := make([]string, 0, len(lines))
-	for _, l := range lines {
-		if len(strings.TrimSpace(l)) == 0 {
+	result := make(map[string][]string)
+	for _, l := range strings.Split(output, "\n") {
+		parts := strings.Split(strings.TrimSpace(l), ",")
+		if len(parts) != 3 || parts[0] == "" {
+			continue
+		}
+		var names []string
+		if parts[1] != "" {
+			names = append(names, parts[1])
+		}
+		for _, kv := range strings.Fields(parts[2]) {
+			if strings.HasPrefix(kv, "node=") {
+				names = append(names, strings.TrimPrefix(kv, "node="))
+			}
+		}
+		result[parts[0]] = names
+	}
+	return result, nil
+}
+
+// PortBinding is the binding state of a logical port in the southbound database
+type PortBinding struct {
+	// Chassis is the uuid of the chassis the port is bound to, empty if not bound
+	Chassis string
+	// Up is nil if the up column is not supported by the southbound database
+	Up *bool
+}
+
+// ListPortBindings lists the binding state of the vif ports indexed by the logical port name
+func (c LegacyClient) ListPortBindings() (map[string]PortBinding, error) {
+	withUp := true
+	output, err := c.ovnSbCommand("--format=csv", "--no-heading", "--data=bare", "--columns=logical_port,chassis,up", "find", "port_binding", `type=""`)
+	if err != nil && strings.Contains(err.Error(), "unknown column") {
+		// the up column is added in ovn 21.03
+		withUp = false
+		output, err = c.ovnSbCommand("--format=csv", "--no-heading", "--data=bare", "--columns=logical_port,chassis", "find", "port_binding", `type=""`)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find port bindings, %v", err)
+	}
+	return parsePortBindings(output, withUp), nil
+}
+
+func parsePortBindings(output string, withUp bool) map[string]PortBinding {
+	result := make(map[string]PortBinding)
+	for _, l := range strings.Split(output, "\n") {
+		parts := strings.Split(strings.TrimSpace(l), ",")
+		if len(parts) < 2 || parts[0] == "" {
+			continue
+		}
+		binding := PortBinding{Chassis: parts[1]}
+		if withUp && len(parts) == 3 && parts[2] != "" {
+			up := parts[2] == "true"
+			binding.Up = &up
+		}
+		result[parts[0]] = binding
+	}
+	return result
+}
+
+// ListChassisNames returns the names of all chassis indexed by the chassis uuid
+func (c LegacyClient) ListChassisNames() (map[string]string, error) {
+	output, err := c.ovnSbCommand("--format=csv", "--no-heading", "--data=bare", "--columns=_uuid,name", "list", "chassis")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chassis, %v", err)
+	}
+	result := make(map[string]string)
+	for _, l := range strings.Split(output, "\n") {
+		parts := strings.Split(strings.TrimSpace(l), ",")
+		if len(parts) != 2 || parts[0] == "" {
 			continue
 		}
-		result = append(result, strings.TrimSpace(l))
+		result[parts[0]] = parts[1]
 	}
 	return result, nil
 }
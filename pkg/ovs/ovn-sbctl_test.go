@@ -0,0 +1,31 @@
+package ovs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parsePortBindings(t *testing.T) {
+	ast := assert.New(t)
+	output := `pod1.default,8d8c5b5e-0000-0000-0000-000000000001,true
+pod2.default,8d8c5b5e-0000-0000-0000-000000000001,false
+pod3.default,,false
+pod4.default,,
+
+`
+	bindings := parsePortBindings(output, true)
+	ast.Equal(4, len(bindings))
+	ast.Equal("8d8c5b5e-0000-0000-0000-000000000001", bindings["pod1.default"].Chassis)
+	ast.True(*bindings["pod1.default"].Up)
+	ast.False(*bindings["pod2.default"].Up)
+	ast.Equal("", bindings["pod3.default"].Chassis)
+	ast.False(*bindings["pod3.default"].Up)
+	ast.Nil(bindings["pod4.default"].Up)
+
+	// the southbound database without the up column
+	bindings = parsePortBindings("pod1.default,8d8c5b5e-0000-0000-0000-000000000001\npod3.default,\n", false)
+	ast.Equal(2, len(bindings))
+	ast.Nil(bindings["pod1.default"].Up)
+	ast.Equal("", bindings["pod3.default"].Chassis)
+}
@@ -21,6 +21,7 @@ var (
 type LegacyClient struct {
 	OvnNbAddress                  string
 	OvnTimeout                    int
+	OvnInactivityProbe            int
 	OvnSbAddress                  string
 	OvnICNbAddress                string
 	OvnICSbAddress                string
@@ -33,6 +34,7 @@ type LegacyClient struct {
 	NodeSwitchCIDR                string
 	ExternalGatewayType           string
 	Version                       string
+	SSLFiles                      ovsclient.SSLFiles
 }
 
 type OvnClient struct {
@@ -59,12 +61,13 @@ const (
 	OVSDBWaitTimeout = 0
 )
 
-// NewLegacyClient init a legacy ovn client
-func NewLegacyClient(ovnNbAddr string, ovnNbTimeout int, ovnSbAddr, clusterRouter, clusterTcpLoadBalancer, clusterUdpLoadBalancer, clusterTcpSessionLoadBalancer, clusterUdpSessionLoadBalancer, nodeSwitch, nodeSwitchCIDR string) *LegacyClient {
+// NewLegacyClient init a legacy ovn client, the inactivity probe is in milliseconds and zero keeps the default of ovn-nbctl and ovn-sbctl
+func NewLegacyClient(ovnNbAddr string, ovnNbTimeout, ovnInactivityProbe int, ovnSbAddr, clusterRouter, clusterTcpLoadBalancer, clusterUdpLoadBalancer, clusterTcpSessionLoadBalancer, clusterUdpSessionLoadBalancer, nodeSwitch, nodeSwitchCIDR string, sslFiles ovsclient.SSLFiles) *LegacyClient {
 	return &LegacyClient{
 		OvnNbAddress:                  ovnNbAddr,
 		OvnSbAddress:                  ovnSbAddr,
 		OvnTimeout:                    ovnNbTimeout,
+		OvnInactivityProbe:            ovnInactivityProbe,
 		ClusterRouter:                 clusterRouter,
 		ClusterTcpLoadBalancer:        clusterTcpLoadBalancer,
 		ClusterUdpLoadBalancer:        clusterUdpLoadBalancer,
@@ -72,12 +75,15 @@ func NewLegacyClient(ovnNbAddr string, ovnNbTimeout int, ovnSbAddr, clusterRoute
 		ClusterUdpSessionLoadBalancer: clusterUdpSessionLoadBalancer,
 		NodeSwitch:                    nodeSwitch,
 		NodeSwitchCIDR:                nodeSwitchCIDR,
+		SSLFiles:                      sslFiles,
 	}
 }
 
+// NewOvnClient init an ovn client, the inactivity probe is in milliseconds and zero disables probing,
+// the reconnect timeout is in seconds
 // TODO: support sb/ic-nb client
-func NewOvnClient(ovnNbAddr string, ovnNbTimeout int) (*OvnClient, error) {
-	nbClient, err := ovsclient.NewNbClient(ovnNbAddr)
+func NewOvnClient(ovnNbAddr string, ovnNbTimeout, ovnInactivityProbe, ovnReconnectTimeout int, sslFiles ovsclient.SSLFiles) (*OvnClient, error) {
+	nbClient, err := ovsclient.NewNbClient(ovnNbAddr, time.Duration(ovnReconnectTimeout)*time.Second, time.Duration(ovnInactivityProbe)*time.Millisecond, sslFiles)
 	if err != nil {
 		klog.Errorf("failed to create OVN NB client: %v", err)
 		return nil, err
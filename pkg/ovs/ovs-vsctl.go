@@ -2,6 +2,7 @@ package ovs
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -184,6 +185,41 @@ func ClearPodBandwidth(podName, podNamespace, ifaceID string) error {
 	return nil
 }
 
+// PortRemovalDeadlineKey is the external id of the interfaces whose removal is delayed after the pod exits,
+// the value is the unix time after which the port is removed
+const PortRemovalDeadlineKey = "removal_deadline"
+
+// SetPortRemovalDeadline marks the interface to be removed after the deadline
+func SetPortRemovalDeadline(iface string, deadline time.Time) error {
+	_, err := Exec(IfExists, "set", "interface", iface, fmt.Sprintf("external_ids:%s=%d", PortRemovalDeadlineKey, deadline.Unix()))
+	return err
+}
+
+// ListPortRemovalDeadlines returns the removal deadlines of the interfaces whose removal is delayed
+func ListPortRemovalDeadlines() (map[string]time.Time, error) {
+	interfaceList, err := ovsFind("interface", "name", fmt.Sprintf("external_ids:%s!=[]", PortRemovalDeadlineKey))
+	if err != nil {
+		return nil, err
+	}
+	deadlines := make(map[string]time.Time, len(interfaceList))
+	for _, name := range interfaceList {
+		if name == "" {
+			continue
+		}
+		value, err := ovsGet("interface", name, "external_ids", PortRemovalDeadlineKey)
+		if err != nil {
+			return nil, err
+		}
+		deadline, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+		if err != nil {
+			// remove the interface as soon as possible if the deadline is corrupted
+			klog.Warningf("invalid removal deadline %s of interface %s", value, name)
+		}
+		deadlines[name] = time.Unix(deadline, 0)
+	}
+	return deadlines, nil
+}
+
 // CleanLostInterface will clean up related ovs port, interface and qos
 // When reboot node, the ovs internal interface will be deleted.
 func CleanLostInterface() {
@@ -196,9 +232,18 @@ func CleanLostInterface() {
 	if len(interfaceList) > 0 {
 		klog.Infof("error interfaces:\n %v", interfaceList)
 	}
+	// the ports whose removal is delayed are removed after their deadlines
+	pendingRemovals, err := ListPortRemovalDeadlines()
+	if err != nil {
+		klog.Errorf("failed to list interfaces pending removal, %v", err)
+		return
+	}
 
 	for _, intf := range interfaceList {
 		name, errText := strings.Trim(strings.Split(intf, "\n")[0], "\""), strings.Split(intf, "\n")[1]
+		if _, ok := pendingRemovals[name]; ok {
+			continue
+		}
 		if strings.Contains(errText, "No such device") {
 			qosList, err := ovsFind("port", "qos", fmt.Sprintf("name=%s", name))
 			if err != nil {
@@ -226,17 +271,187 @@ func CleanLostInterface() {
 	}
 }
 
+// duplicateInterface is an interface sharing the iface-id with the port being added
+type duplicateInterface struct {
+	UUID        string
+	Name        string
+	OfPort      string
+	ExternalIDs map[string]string
+	// Reason explains why the interface is considered stale
+	Reason string
+}
+
+// listDuplicateInterfaces lists the interfaces other than the port with the iface-id
+func listDuplicateInterfaces(ifaceID, portName string) ([]duplicateInterface, error) {
+	output, err := Exec("--data=bare", "--format=csv", "--no-heading", "--columns=_uuid,name,ofport,external_ids",
+		"find", "interface", "external-ids:iface-id="+ifaceID, "name!="+portName)
+	if err != nil {
+		return nil, err
+	}
+	return parseDuplicateInterfaces(output), nil
+}
+
+func parseDuplicateInterfaces(output string) []duplicateInterface {
+	var ifaces []duplicateInterface
+	for _, l := range strings.Split(output, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(l), ",", 4)
+		if len(parts) != 4 {
+			continue
+		}
+		iface := duplicateInterface{
+			UUID:        strings.TrimSpace(parts[0]),
+			Name:        strings.TrimSpace(parts[1]),
+			OfPort:      strings.TrimSpace(parts[2]),
+			ExternalIDs: make(map[string]string),
+		}
+		for _, field := range strings.Fields(strings.Trim(parts[3], `"`)) {
+			if kv := strings.SplitN(field, "=", 2); len(kv) == 2 {
+				iface.ExternalIDs[kv[0]] = kv[1]
+			}
+		}
+		ifaces = append(ifaces, iface)
+	}
+	return ifaces
+}
+
+// staleDuplicateInterfaces returns the duplicate interfaces which are verified to be stale. An interface is stale if it
+// belongs to the same pod netns, or it has no pod netns recorded, or its device or pod netns has gone. The interfaces
+// of another alive pod netns are kept, as a late cni request of an older sandbox must not clear the iface-id of the
+// port of a newer sandbox
+func staleDuplicateInterfaces(ifaceID, portName, podNetns string, ifaces []duplicateInterface, netnsExists func(string) bool) []duplicateInterface {
+	var stale []duplicateInterface
+	for _, iface := range ifaces {
+		if iface.Name == portName || iface.ExternalIDs["iface-id"] != ifaceID {
+			continue
+		}
+		netns := iface.ExternalIDs["pod_netns"]
+		switch {
+		case netns == "":
+			iface.Reason = "no pod netns is recorded"
+		case netns == podNetns:
+			iface.Reason = fmt.Sprintf("it belongs to the same pod netns %s", netns)
+		case iface.OfPort == "-1":
+			iface.Reason = "its device does not exist"
+		case !netnsExists(netns):
+			iface.Reason = fmt.Sprintf("its pod netns %s does not exist", netns)
+		default:
+			klog.Warningf("keep iface-id %s of OVS interface %s as its pod netns %s is still alive", ifaceID, iface.Name, netns)
+			continue
+		}
+		stale = append(stale, iface)
+	}
+	return stale
+}
+
 // Find and remove any existing OVS port with this iface-id. Pods can
 // have multiple sandboxes if some are waiting for garbage collection,
 // but only the latest one should have the iface-id set.
 // See: https://github.com/ovn-org/ovn-kubernetes/pull/869
-func CleanDuplicatePort(ifaceID, portName string) {
-	uuids, _ := ovsFind("Interface", "_uuid", "external-ids:iface-id="+ifaceID, "name!="+portName)
-	for _, uuid := range uuids {
-		if out, err := Exec("remove", "Interface", uuid, "external-ids", "iface-id"); err != nil {
-			klog.Errorf("failed to clear stale OVS port %q iface-id %q: %v\n  %q", uuid, ifaceID, err, out)
+func CleanDuplicatePort(ifaceID, portName, podNetns string) {
+	ifaces, err := listDuplicateInterfaces(ifaceID, portName)
+	if err != nil {
+		klog.Errorf("failed to list OVS interfaces with iface-id %s: %v", ifaceID, err)
+		return
+	}
+	for _, iface := range staleDuplicateInterfaces(ifaceID, portName, podNetns, ifaces, netnsExists) {
+		klog.Infof("clear iface-id %s of stale OVS interface %s (uuid %s, ofport %s, pod netns %q) for port %s: %s",
+			ifaceID, iface.Name, iface.UUID, iface.OfPort, iface.ExternalIDs["pod_netns"], portName, iface.Reason)
+		if out, err := Exec("remove", "Interface", iface.UUID, "external-ids", "iface-id"); err != nil {
+			klog.Errorf("failed to clear stale OVS port %q iface-id %q: %v\n  %q", iface.UUID, ifaceID, err, out)
+		}
+	}
+}
+
+// ListTunnelRemoteIPs lists the remote ips of the geneve tunnels created by ovn-controller, keyed by the chassis
+func ListTunnelRemoteIPs() (map[string]string, error) {
+	output, err := Exec("--data=bare", "--format=csv", "--no-heading", "--columns=external_ids,options", "find", "interface", "type=geneve")
+	if err != nil {
+		return nil, err
+	}
+	return parseTunnelRemoteIPs(output), nil
+}
+
+func parseTunnelRemoteIPs(output string) map[string]string {
+	remoteIPs := make(map[string]string)
+	for _, l := range strings.Split(output, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(l), ",", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		var chassis, remoteIP string
+		for _, field := range strings.Fields(strings.Trim(parts[0], `"`)) {
+			if kv := strings.SplitN(field, "=", 2); len(kv) == 2 && kv[0] == "ovn-chassis-id" {
+				// the chassis id is suffixed with the encap ip by the recent versions of ovn
+				chassis = strings.SplitN(strings.Trim(kv[1], `"`), "@", 2)[0]
+			}
+		}
+		for _, field := range strings.Fields(strings.Trim(parts[1], `"`)) {
+			if kv := strings.SplitN(field, "=", 2); len(kv) == 2 && kv[0] == "remote_ip" {
+				remoteIP = strings.Trim(kv[1], `"`)
+			}
 		}
+		if chassis != "" && remoteIP != "" && remoteIP != "flow" {
+			remoteIPs[chassis] = remoteIP
+		}
+	}
+	return remoteIPs
+}
+
+func netnsExists(netns string) bool {
+	_, err := os.Stat(netns)
+	return !os.IsNotExist(err)
+}
+
+// SetInterfaceCustomExternalIds sets the custom external ids of the interface, the stale ones are removed
+func SetInterfaceCustomExternalIds(iface string, ids map[string]string) error {
+	output, err := Exec("--data=bare", "--no-heading", "--columns=external_ids", "list", "interface", iface)
+	if err != nil {
+		klog.Errorf("failed to get external ids of interface %s: %v", iface, err)
+		return err
+	}
+
+	var args []string
+	for _, field := range strings.Fields(output) {
+		key := strings.SplitN(field, "=", 2)[0]
+		if strings.HasPrefix(key, util.OvsCustomExternalIDPrefix) {
+			if _, ok := ids[strings.TrimPrefix(key, util.OvsCustomExternalIDPrefix)]; !ok {
+				args = append(args, "--", "remove", "interface", iface, "external_ids", key)
+			}
+		}
+	}
+	if len(ids) != 0 {
+		args = append(args, "--", "set", "interface", iface)
+		for k, v := range ids {
+			args = append(args, fmt.Sprintf("external_ids:%s%s=%s", util.OvsCustomExternalIDPrefix, k, v))
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	if _, err = Exec(args...); err != nil {
+		klog.Errorf("failed to set custom external ids of interface %s: %v", iface, err)
+		return err
+	}
+	return nil
+}
+
+// InterfaceOvnInstalled returns whether the flows of the interface with the iface-id have been installed by ovn-controller
+func InterfaceOvnInstalled(ifaceID string) (bool, error) {
+	uuids, err := ovsFind("Interface", "_uuid", "external-ids:iface-id="+ifaceID, "external-ids:ovn-installed=true")
+	if err != nil {
+		return false, err
 	}
+	return len(uuids) != 0, nil
+}
+
+// VhostUserSocketInUse returns whether the vhost-user socket is bound to a live port,
+// which is a dpdkvhostuserclient interface connected through the socket
+func VhostUserSocketInUse(socket string) (bool, error) {
+	states, err := ovsFind("interface", "link_state", "type=dpdkvhostuserclient", fmt.Sprintf(`options:vhost-server-path="%s"`, socket))
+	if err != nil {
+		return false, err
+	}
+	return util.ContainsString(states, "up"), nil
 }
 
 func SetPortTag(port, tag string) error {
@@ -249,6 +464,40 @@ func ValidatePortVendor(port string) (bool, error) {
 	return util.ContainsString(output, port), err
 }
 
+// GetInterfacesByIfaceID returns the names of the interfaces of the port
+func GetInterfacesByIfaceID(iface string) ([]string, error) {
+	interfaceList, err := ovsFind("interface", "name", fmt.Sprintf("external-ids:iface-id=%s", iface))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(interfaceList))
+	for _, name := range interfaceList {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// GetInterfacePodNetns returns the netns of the pod attached to the interface
+func GetInterfacePodNetns(iface string) (string, error) {
+	output, err := Exec("--if-exists", "get", "interface", iface, "external_ids:pod_netns")
+	if err != nil {
+		klog.Errorf("failed to get pod netns of interface %s: %v", iface, err)
+		return "", err
+	}
+	return strings.Trim(output, `"`), nil
+}
+
+// SetInterfaceIP updates the ip of the pod recorded in the external ids of the interface
+func SetInterfaceIP(iface, ip string) error {
+	if err := ovsSet("interface", iface, fmt.Sprintf("external_ids:ip=%s", ip)); err != nil {
+		klog.Errorf("failed to set ip of interface %s: %v", iface, err)
+		return err
+	}
+	return nil
+}
+
 // config mirror for interface by pod annotations and install param
 func ConfigInterfaceMirror(globalMirror bool, open string, iface string) error {
 	if globalMirror {
@@ -396,3 +645,124 @@ func ListQosQueueIds() (map[string]string, error) {
 	}
 	return result, nil
 }
+
+// DpdkConfig is the dpdk related configuration of ovs-vswitchd
+type DpdkConfig struct {
+	SocketMem  string
+	PmdCPUMask string
+	PmdCores   int
+}
+
+func getOpenVSwitchOtherConfig(key string) (string, error) {
+	output, err := Exec("--if-exists", "get", "Open_vSwitch", ".", "other_config:"+key)
+	if err != nil {
+		return "", err
+	}
+	return strings.Trim(output, `"`), nil
+}
+
+// GetDpdkConfig reads the dpdk settings of ovs and counts the pmd cores, when pmd-cpu-mask
+// is not set, ovs-vswitchd creates pmd threads by itself and they are counted instead
+func GetDpdkConfig() (*DpdkConfig, error) {
+	socketMem, err := getOpenVSwitchOtherConfig("dpdk-socket-mem")
+	if err != nil {
+		klog.Errorf("failed to get dpdk-socket-mem: %v", err)
+		return nil, err
+	}
+	pmdCPUMask, err := getOpenVSwitchOtherConfig("pmd-cpu-mask")
+	if err != nil {
+		klog.Errorf("failed to get pmd-cpu-mask: %v", err)
+		return nil, err
+	}
+
+	config := &DpdkConfig{SocketMem: socketMem, PmdCPUMask: pmdCPUMask}
+	if pmdCPUMask != "" {
+		if config.PmdCores, err = util.CountCPUMask(pmdCPUMask); err != nil {
+			klog.Error(err)
+			return nil, err
+		}
+		return config, nil
+	}
+
+	output, err := exec.Command("ovs-appctl", "dpif-netdev/pmd-rxq-show").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to show pmd threads: %v, %q", err, output)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "pmd thread") {
+			config.PmdCores++
+		}
+	}
+	return config, nil
+}
+
+// SflowConfig is the sFlow configuration of an OVS bridge
+type SflowConfig struct {
+	Target   string
+	Sampling int
+	Vendor   string
+}
+
+// GetBridgeSflow returns the sFlow configuration of the bridge, nil if sFlow is not configured
+func GetBridgeSflow(bridge string) (*SflowConfig, error) {
+	record, err := ovsGet("bridge", bridge, "sflow", "")
+	if err != nil {
+		klog.Errorf("failed to get sflow of bridge %s: %v", bridge, err)
+		return nil, err
+	}
+	if record == "" || record == "[]" {
+		return nil, nil
+	}
+
+	targets, err := ovsGet("sflow", record, "targets", "")
+	if err != nil {
+		klog.Errorf("failed to get targets of sflow %s: %v", record, err)
+		return nil, err
+	}
+	sampling, err := ovsGet("sflow", record, "sampling", "")
+	if err != nil {
+		klog.Errorf("failed to get sampling of sflow %s: %v", record, err)
+		return nil, err
+	}
+	vendor, err := Exec("--if-exists", "get", "sflow", record, "external_ids:vendor")
+	if err != nil {
+		klog.Errorf("failed to get vendor of sflow %s: %v", record, err)
+		return nil, err
+	}
+
+	config := &SflowConfig{Vendor: strings.Trim(vendor, `"`)}
+	var targetList []string
+	for _, target := range strings.Split(strings.Trim(targets, "[]"), ",") {
+		if target = strings.Trim(strings.TrimSpace(target), `"`); target != "" {
+			targetList = append(targetList, target)
+		}
+	}
+	config.Target = strings.Join(targetList, ",")
+	if sampling = strings.Trim(sampling, "[]"); sampling != "" {
+		if config.Sampling, err = strconv.Atoi(sampling); err != nil {
+			klog.Errorf("failed to parse sampling %q of sflow %s: %v", sampling, record, err)
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// SetBridgeSflow replaces the sFlow configuration of the bridge, the previous sFlow record is garbage collected
+func SetBridgeSflow(bridge, target string, sampling int) error {
+	if _, err := Exec("--", "--id=@sflow", "create", "sflow", fmt.Sprintf(`targets="%s"`, target),
+		fmt.Sprintf("sampling=%d", sampling), "external_ids:vendor="+util.CniTypeName,
+		"--", "set", "bridge", bridge, "sflow=@sflow"); err != nil {
+		klog.Errorf("failed to set sflow of bridge %s: %v", bridge, err)
+		return err
+	}
+	return nil
+}
+
+// ClearBridgeSflow removes the sFlow configuration of the bridge
+func ClearBridgeSflow(bridge string) error {
+	if err := ovsClear("bridge", bridge, "sflow"); err != nil {
+		klog.Errorf("failed to clear sflow of bridge %s: %v", bridge, err)
+		return err
+	}
+	return nil
+}
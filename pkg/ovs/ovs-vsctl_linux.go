@@ -2,19 +2,74 @@ package ovs
 
 import (
 	"fmt"
+	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 
 	"k8s.io/klog/v2"
 
 	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
+// supportedQosTypes caches whether the queue based qos types are supported by the running ovs
+var supportedQosTypes sync.Map
+
+// isQueueQos returns whether the qos type limits the egress bandwidth by queues
+func isQueueQos(qosType string) bool {
+	return qosType == util.HtbQos || qosType == util.HfscQos
+}
+
+// qosTypeSupported checks the qos type against the types supported by the netdev of the interface
+func qosTypeSupported(ifName, qosType string) bool {
+	if supported, ok := supportedQosTypes.Load(qosType); ok {
+		return supported.(bool)
+	}
+	output, err := exec.Command("ovs-appctl", "qos/show-types", ifName).CombinedOutput()
+	if err != nil {
+		klog.Errorf("failed to show qos types of interface %s: %v, %q", ifName, err, output)
+		return false
+	}
+	supported := false
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) == qosType {
+			supported = true
+			break
+		}
+	}
+	supportedQosTypes.Store(qosType, supported)
+	return supported
+}
+
+// resolveQosType returns the queue based qos type used for the interface, falling back to linux-htb
+func resolveQosType(ifName, qosType string) string {
+	if qosType == "" || qosType == util.HtbQos {
+		return util.HtbQos
+	}
+	if qosType != util.HfscQos {
+		klog.Warningf("qos type %s of interface %s is not supported, fall back to %s", qosType, ifName, util.HtbQos)
+		return util.HtbQos
+	}
+	if !qosTypeSupported(ifName, qosType) {
+		klog.Warningf("qos type %s is not supported by the running ovs for interface %s, fall back to %s", qosType, ifName, util.HtbQos)
+		return util.HtbQos
+	}
+	return qosType
+}
+
 // SetInterfaceBandwidth set ingress/egress qos for given pod, annotation values are for node/pod
 // but ingress/egress parameters here are from the point of ovs port/interface view, so reverse input parameters when call func SetInterfaceBandwidth
-func SetInterfaceBandwidth(podName, podNamespace, iface, ingress, egress, podPriority string) error {
-	ingressMPS, _ := strconv.Atoi(ingress)
+func SetInterfaceBandwidth(podName, podNamespace, iface, ingress, egress, podPriority, qosType string) error {
+	ingressMPS, err := util.ParseBandwidth(ingress, 0)
+	if err != nil {
+		return fmt.Errorf("invalid ingress rate %q of interface %s: %v", ingress, iface, err)
+	}
+	egressMPS, err := util.ParseBandwidth(egress, 0)
+	if err != nil {
+		return fmt.Errorf("invalid egress rate %q of interface %s: %v", egress, iface, err)
+	}
 	ingressKPS := ingressMPS * 1000
+	egressBPS := egressMPS * 1000 * 1000
 	interfaceList, err := ovsFind("interface", "name", fmt.Sprintf("external-ids:iface-id=%s", iface))
 	if err != nil {
 		return err
@@ -31,22 +86,20 @@ func SetInterfaceBandwidth(podName, podNamespace, iface, ingress, egress, podPri
 	}
 
 	for _, ifName := range interfaceList {
+		ifQosType := resolveQosType(ifName, qosType)
 		// ingress_policing_rate is in Kbps
 		err := ovsSet("interface", ifName, fmt.Sprintf("ingress_policing_rate=%d", ingressKPS), fmt.Sprintf("ingress_policing_burst=%d", ingressKPS*8/10))
 		if err != nil {
 			return err
 		}
 
-		egressMPS, _ := strconv.Atoi(egress)
-		egressBPS := egressMPS * 1000 * 1000
-
 		if egressBPS > 0 {
 			queueUid, err := SetHtbQosQueueRecord(podName, podNamespace, iface, podPriority, egressBPS, queueIfaceUidMap)
 			if err != nil {
 				return err
 			}
 
-			if err = SetQosQueueBinding(podName, podNamespace, ifName, iface, queueUid, qosIfaceUidMap); err != nil {
+			if err = SetQosQueueBinding(podName, podNamespace, ifName, iface, queueUid, ifQosType, qosIfaceUidMap); err != nil {
 				return err
 			}
 		} else {
@@ -55,7 +108,7 @@ func SetInterfaceBandwidth(podName, podNamespace, iface, ingress, egress, podPri
 				if err != nil {
 					return err
 				}
-				if qosType != util.HtbQos {
+				if !isQueueQos(qosType) {
 					continue
 				}
 				queueId, err := ovsGet("qos", qosUid, "queues", "0")
@@ -69,7 +122,7 @@ func SetInterfaceBandwidth(podName, podNamespace, iface, ingress, egress, podPri
 			}
 		}
 
-		if err = SetHtbQosPriority(podName, podNamespace, iface, ifName, podPriority, qosIfaceUidMap, queueIfaceUidMap); err != nil {
+		if err = SetHtbQosPriority(podName, podNamespace, iface, ifName, podPriority, ifQosType, qosIfaceUidMap, queueIfaceUidMap); err != nil {
 			return err
 		}
 
@@ -82,6 +135,85 @@ func SetInterfaceBandwidth(podName, podNamespace, iface, ingress, egress, podPri
 	return nil
 }
 
+// SetInterfaceMinRate sets the guaranteed egress rate in Mbit/s of the dedicated queue of the pod port,
+// the queue is left to SetInterfaceBandwidth to clean up if the min rate is removed
+func SetInterfaceMinRate(podName, podNamespace, iface, minRate, qosType string) error {
+	minRateMPS, _ := strconv.Atoi(minRate)
+	minRateBPS := minRateMPS * 1000 * 1000
+	interfaceList, err := ovsFind("interface", "name", fmt.Sprintf("external-ids:iface-id=%s", iface))
+	if err != nil {
+		return err
+	}
+
+	qosIfaceUidMap, err := ListExternalIds("qos")
+	if err != nil {
+		return err
+	}
+
+	queueIfaceUidMap, err := ListExternalIds("queue")
+	if err != nil {
+		return err
+	}
+
+	for _, ifName := range interfaceList {
+		if minRateBPS <= 0 {
+			if queueUid, ok := queueIfaceUidMap[iface]; ok {
+				if _, err := Exec("remove", "queue", queueUid, "other_config", "min-rate", "--", "remove", "queue", queueUid, "external-ids", "dedicated"); err != nil {
+					return fmt.Errorf("failed to remove min rate for queue in pod %v/%v, %v", podNamespace, podName, err)
+				}
+			}
+			continue
+		}
+
+		queueCommandValues := []string{fmt.Sprintf("other_config:min-rate=%d", minRateBPS), "external-ids:dedicated=true"}
+		queueUid, ok := queueIfaceUidMap[iface]
+		if ok {
+			if err = ovsSet("queue", queueUid, queueCommandValues...); err != nil {
+				return err
+			}
+		} else {
+			queueCommandValues = append(queueCommandValues, fmt.Sprintf("external-ids:iface-id=%s", iface))
+			if podNamespace != "" && podName != "" {
+				queueCommandValues = append(queueCommandValues, fmt.Sprintf("external-ids:pod=%s/%s", podNamespace, podName))
+			}
+			if queueUid, err = ovsCreate("queue", queueCommandValues...); err != nil {
+				return err
+			}
+			queueIfaceUidMap[iface] = queueUid
+		}
+		if err = SetQosQueueBinding(podName, podNamespace, ifName, iface, queueUid, resolveQosType(ifName, qosType), qosIfaceUidMap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetDedicatedQueueMinRate returns the sum of the guaranteed rates in bit/s of the dedicated queues on the node
+func GetDedicatedQueueMinRate() (int, error) {
+	queueList, err := ovsFind("queue", "_uuid", "external-ids:dedicated=true")
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+	for _, queueUid := range queueList {
+		if queueUid == "" {
+			continue
+		}
+		minRate, err := ovsGet("queue", queueUid, "other_config", "min-rate")
+		if err != nil {
+			return 0, err
+		}
+		rate, err := strconv.Atoi(strings.Trim(minRate, `"`))
+		if err != nil {
+			klog.Warningf("invalid min rate %s of queue %s", minRate, queueUid)
+			continue
+		}
+		total += rate
+	}
+	return total, nil
+}
+
 func ClearHtbQosQueue(podName, podNamespace, iface string) error {
 	var queueList []string
 	var err error
@@ -122,13 +254,14 @@ func ClearHtbQosQueue(podName, podNamespace, iface string) error {
 	return nil
 }
 
-func IsHtbQos(iface string) (bool, error) {
+// IsQueueQos returns whether the qos of the interface is linux-htb or linux-hfsc
+func IsQueueQos(iface string) (bool, error) {
 	qosType, err := ovsFind("qos", "type", fmt.Sprintf(`external-ids:iface-id="%s"`, iface))
 	if err != nil {
 		return false, err
 	}
 
-	if len(qosType) != 0 && qosType[0] == util.HtbQos {
+	if len(qosType) != 0 && isQueueQos(qosType[0]) {
 		return true, nil
 	}
 	return false, nil
@@ -171,22 +304,37 @@ func SetPodQosPriority(podName, podNamespace, ifaceID, priority string, qosIface
 		return err
 	}
 
+	// keep the qos type of the interface
+	qosType := util.HtbQos
+	if qosUid, ok := qosIfaceUidMap[ifaceID]; ok {
+		if qosType, err = ovsGet("qos", qosUid, "type", ""); err != nil {
+			return err
+		}
+		if !isQueueQos(qosType) {
+			qosType = util.HtbQos
+		}
+	}
+
 	for _, ifName := range interfaceList {
-		if err = SetHtbQosPriority(podName, podNamespace, ifaceID, ifName, priority, qosIfaceUidMap, queueIfaceUidMap); err != nil {
+		if err = SetHtbQosPriority(podName, podNamespace, ifaceID, ifName, priority, qosType, qosIfaceUidMap, queueIfaceUidMap); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func SetHtbQosPriority(podName, podNamespace, iface, ifName, priority string, qosIfaceUidMap, queueIfaceUidMap map[string]string) error {
+func SetHtbQosPriority(podName, podNamespace, iface, ifName, priority, qosType string, qosIfaceUidMap, queueIfaceUidMap map[string]string) error {
+	if priority != "" && qosType != util.HtbQos {
+		klog.Warningf("priority %s of interface %s is ignored since it is not supported by %s", priority, iface, qosType)
+		priority = ""
+	}
 	if priority != "" {
 		queueUid, err := SetHtbQosQueueRecord(podName, podNamespace, iface, priority, 0, queueIfaceUidMap)
 		if err != nil {
 			return err
 		}
 
-		if err = SetQosQueueBinding(podName, podNamespace, ifName, iface, queueUid, qosIfaceUidMap); err != nil {
+		if err = SetQosQueueBinding(podName, podNamespace, ifName, iface, queueUid, qosType, qosIfaceUidMap); err != nil {
 			return err
 		}
 	} else {
@@ -200,7 +348,7 @@ func SetHtbQosPriority(podName, podNamespace, iface, ifName, priority string, qo
 		if err != nil {
 			return err
 		}
-		if qosType != util.HtbQos {
+		if !isQueueQos(qosType) {
 			return nil
 		}
 		queueId, err := ovsGet("qos", qosUid, "queues", "0")
@@ -217,12 +365,12 @@ func SetHtbQosPriority(podName, podNamespace, iface, ifName, priority string, qo
 }
 
 // SetQosQueueBinding set qos related to queue record.
-func SetQosQueueBinding(podName, podNamespace, ifName, iface, queueUid string, qosIfaceUidMap map[string]string) error {
+func SetQosQueueBinding(podName, podNamespace, ifName, iface, queueUid, qosType string, qosIfaceUidMap map[string]string) error {
 	var qosCommandValues []string
 	qosCommandValues = append(qosCommandValues, fmt.Sprintf("queues:0=%s", queueUid))
 
 	if qosUid, ok := qosIfaceUidMap[iface]; !ok {
-		qosCommandValues = append(qosCommandValues, "type=linux-htb", fmt.Sprintf(`external-ids:iface-id="%s"`, iface))
+		qosCommandValues = append(qosCommandValues, fmt.Sprintf("type=%s", qosType), fmt.Sprintf(`external-ids:iface-id="%s"`, iface))
 		if podNamespace != "" && podName != "" {
 			qosCommandValues = append(qosCommandValues, fmt.Sprintf("external-ids:pod=%s/%s", podNamespace, podName))
 		}
@@ -236,16 +384,18 @@ func SetQosQueueBinding(podName, podNamespace, ifName, iface, queueUid string, q
 		}
 		qosIfaceUidMap[iface] = qos
 	} else {
-		qosType, err := ovsGet("qos", qosUid, "type", "")
+		existingType, err := ovsGet("qos", qosUid, "type", "")
 		if err != nil {
 			return err
 		}
-		if qosType != util.HtbQos {
-			klog.Errorf("netem qos exists for pod %s/%s, conflict with current qos, will be changed to htb qos", podNamespace, podName)
-			qosCommandValues = append(qosCommandValues, "type=linux-htb")
-		}
-
-		if qosType == util.HtbQos {
+		if existingType != qosType {
+			if existingType == util.NetemQos {
+				klog.Errorf("netem qos exists for pod %s/%s, conflict with current qos, will be changed to %s qos", podNamespace, podName, qosType)
+			} else {
+				klog.Infof("change qos of pod %s/%s from %s to %s", podNamespace, podName, existingType, qosType)
+			}
+			qosCommandValues = append(qosCommandValues, fmt.Sprintf("type=%s", qosType))
+		} else {
 			queueId, err := ovsGet("qos", qosUid, "queues", "0")
 			if err != nil {
 				return err
@@ -433,7 +583,7 @@ func CheckAndUpdateHtbQos(podName, podNamespace, ifaceID string, queueIfaceUidMa
 	}
 
 	// recall clearQos
-	if htbQos, _ := IsHtbQos(ifaceID); !htbQos {
+	if queueQos, _ := IsQueueQos(ifaceID); !queueQos {
 		return nil
 	}
 
@@ -0,0 +1,63 @@
+package ovs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_staleDuplicateInterfaces(t *testing.T) {
+	ast := assert.New(t)
+	ifaceID := "pod1.default"
+	aliveNetns := map[string]bool{
+		"/var/run/netns/cni-fresh":   true,
+		"/var/run/netns/cni-current": true,
+	}
+	netnsExists := func(netns string) bool { return aliveNetns[netns] }
+
+	// the ports of the older sandboxes, whose netns may be deleted or still alive, briefly coexist
+	// with the port of the current sandbox while its cni request is being handled
+	output := `6a1f2c8e-0000-0000-0000-000000000001,a1b2c3d4e5f6_h,-1,"iface-id=pod1.default pod_name=pod1 pod_namespace=default pod_netns=/var/run/netns/cni-stale"
+6a1f2c8e-0000-0000-0000-000000000002,0f9e8d7c6b5a_h,12,"iface-id=pod1.default pod_name=pod1 pod_namespace=default pod_netns=/var/run/netns/cni-fresh"
+6a1f2c8e-0000-0000-0000-000000000003,1122334455aa_h,13,"iface-id=pod1.default pod_name=pod1 pod_namespace=default pod_netns=/var/run/netns/cni-gone"
+6a1f2c8e-0000-0000-0000-000000000004,5566778899bb_h,14,"iface-id=pod1.default pod_name=pod1 pod_namespace=default pod_netns=/var/run/netns/cni-current"
+6a1f2c8e-0000-0000-0000-000000000005,ccddeeff0011_h,15,"iface-id=pod1.default"
+6a1f2c8e-0000-0000-0000-000000000006,223344556677_h,16,"iface-id=pod2.default pod_netns=/var/run/netns/cni-gone"
+`
+	ifaces := parseDuplicateInterfaces(output)
+	ast.Equal(6, len(ifaces))
+	ast.Equal("a1b2c3d4e5f6_h", ifaces[0].Name)
+	ast.Equal("-1", ifaces[0].OfPort)
+	ast.Equal("/var/run/netns/cni-stale", ifaces[0].ExternalIDs["pod_netns"])
+
+	stale := staleDuplicateInterfaces(ifaceID, "current_h", "/var/run/netns/cni-current", ifaces, netnsExists)
+	names := make([]string, 0, len(stale))
+	for _, iface := range stale {
+		ast.NotEmpty(iface.Reason)
+		names = append(names, iface.Name)
+	}
+	// the port of another alive netns, which may be added by a newer sandbox, and the port with
+	// another iface-id must be kept
+	ast.Equal([]string{"a1b2c3d4e5f6_h", "1122334455aa_h", "5566778899bb_h", "ccddeeff0011_h"}, names)
+
+	// a late cni request of the older sandbox does not clear the iface-id of the newer live port
+	stale = staleDuplicateInterfaces(ifaceID, "0f9e8d7c6b5a_h", "/var/run/netns/cni-fresh", ifaces[3:4], netnsExists)
+	ast.Empty(stale)
+
+	// the port being added is never cleared
+	stale = staleDuplicateInterfaces(ifaceID, "0f9e8d7c6b5a_h", "/var/run/netns/cni-fresh", ifaces[1:2], netnsExists)
+	ast.Empty(stale)
+}
+
+func Test_parseTunnelRemoteIPs(t *testing.T) {
+	ast := assert.New(t)
+	output := `ovn-chassis-id=2b4bc2c8-1f1f-4c3e-9a0a-6cf0b3f3a1b1@172.18.0.2,"csum=true key=flow remote_ip=172.18.0.2"
+"ovn-chassis-id=az1-gw","csum=true key=flow remote_ip=172.19.0.3"
+"ovn-chassis-id=flow-based","key=flow remote_ip=flow"
+"","key=flow remote_ip=172.19.0.4"
+`
+	ast.Equal(map[string]string{
+		"2b4bc2c8-1f1f-4c3e-9a0a-6cf0b3f3a1b1": "172.18.0.2",
+		"az1-gw":                               "172.19.0.3",
+	}, parseTunnelRemoteIPs(output))
+}
@@ -2,17 +2,29 @@ package ovs
 
 // SetInterfaceBandwidth set ingress/egress qos for given pod, annotation values are for node/pod
 // but ingress/egress parameters here are from the point of ovs port/interface view, so reverse input parameters when call func SetInterfaceBandwidth
-func SetInterfaceBandwidth(podName, podNamespace, iface, ingress, egress, podPriority string) error {
+func SetInterfaceBandwidth(podName, podNamespace, iface, ingress, egress, podPriority, qosType string) error {
 	// TODO
 	return nil
 }
 
+// SetInterfaceMinRate sets the guaranteed egress rate in Mbit/s of the dedicated queue of the pod port
+func SetInterfaceMinRate(podName, podNamespace, iface, minRate, qosType string) error {
+	// TODO
+	return nil
+}
+
+// GetDedicatedQueueMinRate returns the sum of the guaranteed rates in bit/s of the dedicated queues on the node
+func GetDedicatedQueueMinRate() (int, error) {
+	// TODO
+	return 0, nil
+}
+
 func ClearHtbQosQueue(podName, podNamespace, iface string) error {
 	//TODO
 	return nil
 }
 
-func IsHtbQos(iface string) (bool, error) {
+func IsQueueQos(iface string) (bool, error) {
 	// TODO
 	return false, nil
 }
@@ -29,7 +41,7 @@ func SetPodQosPriority(podName, podNamespace, ifaceID, priority string, qosIface
 }
 
 // SetQosQueueBinding set qos related to queue record.
-func SetQosQueueBinding(podName, podNamespace, ifName, iface, queueUid string, qosIfaceUidMap map[string]string) error {
+func SetQosQueueBinding(podName, podNamespace, ifName, iface, queueUid, qosType string, qosIfaceUidMap map[string]string) error {
 	// TODO
 	return nil
 }
@@ -4,10 +4,8 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/tls"
-	"crypto/x509"
 	"encoding/binary"
 	"fmt"
-	"os"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -19,8 +17,6 @@ import (
 	"github.com/kubeovn/kube-ovn/pkg/ovsdb/ovnnb"
 )
 
-const timeout = 3 * time.Second
-
 var namedUUIDCounter uint32
 
 func init() {
@@ -35,8 +31,10 @@ func NamedUUID() string {
 	return fmt.Sprintf("u%010d", atomic.AddUint32(&namedUUIDCounter, 1))
 }
 
-// NewNbClient creates a new OVN NB client
-func NewNbClient(addr string) (client.Client, error) {
+// NewNbClient creates a new OVN NB client, the connection is probed by echo requests every inactivityProbe
+// and reconnected if the probe fails, zero inactivityProbe disables probing.
+// The client certificate of ssl connections is reloaded once the files change
+func NewNbClient(addr string, timeout, inactivityProbe time.Duration, sslFiles SSLFiles) (client.Client, error) {
 	dbModel, err := ovnnb.FullDatabaseModel()
 	if err != nil {
 		return nil, err
@@ -59,22 +57,18 @@ func NewNbClient(addr string) (client.Client, error) {
 	}
 
 	if ssl {
-		cert, err := tls.LoadX509KeyPair("/var/run/tls/cert", "/var/run/tls/key")
-		if err != nil {
-			return nil, fmt.Errorf("failed to load x509 cert key pair: %v", err)
-		}
-		caCert, err := os.ReadFile("/var/run/tls/cacert")
+		reloader, err := newCertReloader(sslFiles)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read ca cert: %v", err)
+			return nil, err
 		}
 
-		certPool := x509.NewCertPool()
-		certPool.AppendCertsFromPEM(caCert)
+		// the default verification is skipped for the host name, the server certificate is verified against the
+		// reloaded ca certificate by VerifyConnection instead
 		// #nosec
 		tlsConfig := &tls.Config{
-			Certificates:       []tls.Certificate{cert},
-			RootCAs:            certPool,
-			InsecureSkipVerify: true,
+			GetClientCertificate: reloader.GetClientCertificate,
+			VerifyConnection:     reloader.VerifyConnection,
+			InsecureSkipVerify:   true,
 		}
 
 		options = append(options, client.WithTLSConfig(tlsConfig))
@@ -99,11 +93,35 @@ func NewNbClient(addr string) (client.Client, error) {
 		client.WithTable(&ovnnb.LogicalRouterStaticRoute{}),
 		client.WithTable(&ovnnb.LogicalSwitchPort{}),
 		client.WithTable(&ovnnb.PortGroup{}),
+		client.WithTable(&ovnnb.BFD{}),
 	}
 	if _, err = c.Monitor(context.TODO(), c.NewMonitor(monitorOpts...)); err != nil {
 		klog.Errorf("failed to monitor database on OVN NB server %s: %v", addr, err)
 		return nil, err
 	}
 
+	if inactivityProbe != 0 {
+		go probeConnection(c, addr, inactivityProbe)
+	}
+
 	return c, nil
 }
+
+// probeConnection sends echo requests on the connection every interval,
+// and disconnects it on failure so that the client reconnects with the reconnect options
+func probeConnection(c client.Client, addr string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !c.Connected() {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := c.Echo(ctx)
+		cancel()
+		if err != nil {
+			klog.Errorf("inactivity probe to OVN NB server %s failed, reconnecting: %v", addr, err)
+			c.Disconnect()
+		}
+	}
+}
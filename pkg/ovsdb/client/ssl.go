@@ -0,0 +1,165 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// SSLFiles are the paths of the private key, certificate and ca certificate used to connect to the ovn databases over ssl
+type SSLFiles struct {
+	Key    string
+	Cert   string
+	CACert string
+}
+
+// Validate checks that the files are readable and contain valid certificates
+func (f SSLFiles) Validate() error {
+	if _, err := tls.LoadX509KeyPair(f.Cert, f.Key); err != nil {
+		return fmt.Errorf("failed to load x509 cert key pair from %s and %s: %v", f.Cert, f.Key, err)
+	}
+	if _, err := f.loadCACert(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (f SSLFiles) loadCACert() (*x509.CertPool, error) {
+	caCert, err := os.ReadFile(f.CACert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ca cert: %v", err)
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("no valid certificate found in ca cert %s", f.CACert)
+	}
+	return certPool, nil
+}
+
+// certReloader provides the client certificate of tls handshakes and verifies the server certificate against the ca
+// certificate. The files are re-read once they are modified, so that the rotated certificates are used by the
+// following connections
+type certReloader struct {
+	files SSLFiles
+
+	mutex       sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	caPool      *x509.CertPool
+	caModTime   time.Time
+}
+
+func newCertReloader(files SSLFiles) (*certReloader, error) {
+	r := &certReloader{files: files}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func latestModTime(files ...string) (time.Time, error) {
+	var modTime time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return modTime, fmt.Errorf("failed to stat %s: %v", file, err)
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime, nil
+}
+
+// reload loads the certificates if the files are modified since the last load, it must be called with the mutex held
+func (r *certReloader) reload() error {
+	certErr, caErr := r.reloadCert(), r.reloadCACert()
+	if certErr != nil {
+		return certErr
+	}
+	return caErr
+}
+
+func (r *certReloader) reloadCert() error {
+	modTime, err := latestModTime(r.files.Cert, r.files.Key)
+	if err != nil {
+		return err
+	}
+	if r.cert != nil && modTime.Equal(r.certModTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.files.Cert, r.files.Key)
+	if err != nil {
+		return fmt.Errorf("failed to load x509 cert key pair: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate %s: %v", r.files.Cert, err)
+	}
+	if r.cert != nil {
+		klog.Infof("reloaded ovn client certificate %s, subject %q, serial number %s, expires at %v",
+			r.files.Cert, leaf.Subject.String(), leaf.SerialNumber.String(), leaf.NotAfter)
+	}
+	r.cert, r.certModTime = &cert, modTime
+	return nil
+}
+
+func (r *certReloader) reloadCACert() error {
+	modTime, err := latestModTime(r.files.CACert)
+	if err != nil {
+		return err
+	}
+	if r.caPool != nil && modTime.Equal(r.caModTime) {
+		return nil
+	}
+
+	caPool, err := r.files.loadCACert()
+	if err != nil {
+		return err
+	}
+	if r.caPool != nil {
+		klog.Infof("reloaded ovn ca certificate %s", r.files.CACert)
+	}
+	r.caPool, r.caModTime = caPool, modTime
+	return nil
+}
+
+func (r *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.reload(); err != nil {
+		// the files may be in the middle of rotation, the reload is retried by the next handshake
+		klog.Errorf("failed to reload ovn ssl certificates, keep using the loaded ones: %v", err)
+	}
+	return r.cert, nil
+}
+
+// VerifyConnection verifies the certificate chain of the server against the ca certificate. The host name is not
+// verified as the certificates of the ovn databases are not issued for their addresses
+func (r *certReloader) VerifyConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("no certificate is presented by the ovn database server")
+	}
+
+	r.mutex.Lock()
+	if err := r.reload(); err != nil {
+		klog.Errorf("failed to reload ovn ssl certificates, keep using the loaded ones: %v", err)
+	}
+	roots := r.caPool
+	r.mutex.Unlock()
+
+	opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+		return fmt.Errorf("failed to verify the certificate of the ovn database server: %v", err)
+	}
+	return nil
+}
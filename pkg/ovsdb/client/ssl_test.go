@@ -0,0 +1,162 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, cn string, serial int64, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// writeFile writes the file and moves its modification time forward, so that the change is detected even if the
+// file system has a coarse timestamp resolution
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to change times of %s: %v", path, err)
+	}
+}
+
+func writeTestCert(t *testing.T, files SSLFiles, c *testCert, modTime time.Time) {
+	t.Helper()
+	keyDer, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	writeFile(t, files.Cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), modTime)
+	writeFile(t, files.Key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), modTime)
+}
+
+func writeTestCACert(t *testing.T, files SSLFiles, c *testCert, modTime time.Time) {
+	t.Helper()
+	writeFile(t, files.CACert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), modTime)
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	files := SSLFiles{
+		Key:    filepath.Join(dir, "key.pem"),
+		Cert:   filepath.Join(dir, "cert.pem"),
+		CACert: filepath.Join(dir, "cacert.pem"),
+	}
+	modTime := time.Now().Add(-time.Minute)
+
+	ca := newTestCert(t, "ca", 1, nil)
+	client := newTestCert(t, "client", 2, ca)
+	server := newTestCert(t, "server", 3, ca)
+	writeCACert := func(c *testCert) {
+		modTime = modTime.Add(time.Second)
+		writeTestCACert(t, files, c, modTime)
+	}
+	writeCert := func(c *testCert) {
+		modTime = modTime.Add(time.Second)
+		writeTestCert(t, files, c, modTime)
+	}
+	writeCACert(ca)
+	writeCert(client)
+
+	r, err := newCertReloader(files)
+	if err != nil {
+		t.Fatalf("failed to create cert reloader: %v", err)
+	}
+	assertClientCert := func(want *testCert) {
+		t.Helper()
+		cert, err := r.GetClientCertificate(nil)
+		if err != nil {
+			t.Fatalf("failed to get client certificate: %v", err)
+		}
+		if len(cert.Certificate) == 0 || string(cert.Certificate[0]) != string(want.der) {
+			t.Fatalf("got client certificate which is not %s", want.cert.Subject.CommonName)
+		}
+	}
+	verify := func(certs ...*testCert) error {
+		cs := tls.ConnectionState{}
+		for _, c := range certs {
+			cs.PeerCertificates = append(cs.PeerCertificates, c.cert)
+		}
+		return r.VerifyConnection(cs)
+	}
+
+	assertClientCert(client)
+	if err = verify(server); err != nil {
+		t.Errorf("server certificate issued by the ca is rejected: %v", err)
+	}
+	if err = verify(); err == nil {
+		t.Errorf("connection without server certificate is accepted")
+	}
+
+	// the rotated client certificate is used by the following handshakes
+	newClient := newTestCert(t, "client", 4, ca)
+	writeCert(newClient)
+	assertClientCert(newClient)
+
+	// the servers are verified against the rotated ca certificate
+	newCA := newTestCert(t, "ca", 5, nil)
+	newServer := newTestCert(t, "server", 6, newCA)
+	if err = verify(newServer); err == nil {
+		t.Errorf("server certificate issued by an unknown ca is accepted")
+	}
+	writeCACert(newCA)
+	if err = verify(newServer); err != nil {
+		t.Errorf("server certificate issued by the rotated ca is rejected: %v", err)
+	}
+	if err = verify(server); err == nil {
+		t.Errorf("server certificate issued by the replaced ca is accepted")
+	}
+
+	// the loaded certificates are kept if the files are broken in the middle of rotation
+	writeFile(t, files.CACert, []byte("invalid"), modTime.Add(time.Second))
+	writeFile(t, files.Cert, []byte("invalid"), modTime.Add(time.Second))
+	assertClientCert(newClient)
+	if err = verify(newServer); err != nil {
+		t.Errorf("server certificate is rejected after a broken ca certificate is written: %v", err)
+	}
+}
@@ -26,6 +26,7 @@ type CniRequest struct {
 	ContainerID  string    `json:"container_id"`
 	NetNs        string    `json:"net_ns"`
 	IfName       string    `json:"if_name"`
+	PodIfName    string    `json:"pod_if_name"`
 	Provider     string    `json:"provider"`
 	Routes       []Route   `json:"routes"`
 	DNS          types.DNS `json:"dns"`
@@ -40,15 +41,19 @@ type CniRequest struct {
 
 // CniResponse is the cniserver response format
 type CniResponse struct {
-	Protocol   string    `json:"protocol"`
-	IpAddress  string    `json:"address"`
-	MacAddress string    `json:"mac_address"`
-	CIDR       string    `json:"cidr"`
-	Gateway    string    `json:"gateway"`
-	Mtu        int       `json:"mtu"`
-	PodNicName string    `json:"nicname"`
-	DNS        types.DNS `json:"dns"`
-	Err        string    `json:"error"`
+	Protocol   string `json:"protocol"`
+	IpAddress  string `json:"address"`
+	MacAddress string `json:"mac_address"`
+	CIDR       string `json:"cidr"`
+	Gateway    string `json:"gateway"`
+	Mtu        int    `json:"mtu"`
+	PodNicName string `json:"nicname"`
+	// HostNicName is the host end of the veth pair of the pod nic, empty for the other types of nics
+	HostNicName string    `json:"host_nicname,omitempty"`
+	DNS         types.DNS `json:"dns"`
+	Err         string    `json:"error"`
+	// PreferredIPFamily is the family of the primary ip of the dual stack pod, IPv4 if empty
+	PreferredIPFamily string `json:"preferred_ip_family,omitempty"`
 }
 
 // Add pod request
@@ -0,0 +1,118 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// ExternalIPAMActionReserve reserves the addresses of a port, it must be idempotent for the same port
+	ExternalIPAMActionReserve = "reserve"
+	// ExternalIPAMActionRelease releases the addresses of a port, releasing unknown addresses must succeed
+	ExternalIPAMActionRelease = "release"
+	// ExternalIPAMActionList lists the reservations of a subnet
+	ExternalIPAMActionList = "list"
+)
+
+// ExternalIPAMRequest is the request posted to the external ipam webhook
+type ExternalIPAMRequest struct {
+	Action string `json:"action"`
+	Subnet string `json:"subnet"`
+	CIDR   string `json:"cidr"`
+	// Namespace and PodName are the owner of the addresses
+	Namespace string `json:"namespace,omitempty"`
+	PodName   string `json:"podName,omitempty"`
+	// Port is the name of the logical switch port, which identifies the reservation, empty for list
+	Port string `json:"port,omitempty"`
+	// IPAddress is the comma separated requested static ips for reserve or the ips to release
+	IPAddress  string `json:"ipAddress,omitempty"`
+	MacAddress string `json:"macAddress,omitempty"`
+}
+
+// ExternalIPAMResponse is the response of the external ipam webhook
+type ExternalIPAMResponse struct {
+	// IPAddress is the comma separated reserved ips, one per protocol of the subnet
+	IPAddress  string `json:"ipAddress,omitempty"`
+	MacAddress string `json:"macAddress,omitempty"`
+	// Reservations are the reservations of the subnet, only used by list
+	Reservations []ExternalIPAMReservation `json:"reservations,omitempty"`
+	Err          string                    `json:"error,omitempty"`
+}
+
+// ExternalIPAMReservation is a reservation held by the external ipam webhook
+type ExternalIPAMReservation struct {
+	Namespace string `json:"namespace"`
+	PodName   string `json:"podName"`
+	Port      string `json:"port"`
+	IPAddress string `json:"ipAddress"`
+}
+
+// ExternalIPAMClient is the client to visit the external ipam webhooks
+type ExternalIPAMClient struct {
+	client *http.Client
+}
+
+// NewExternalIPAMClient return a new external ipam client, requests not responded in timeout are failed
+func NewExternalIPAMClient(timeout time.Duration) *ExternalIPAMClient {
+	return &ExternalIPAMClient{client: &http.Client{Timeout: timeout}}
+}
+
+// Reserve posts the reserve request to the webhook and returns the reserved addresses
+func (c *ExternalIPAMClient) Reserve(url string, req ExternalIPAMRequest) (*ExternalIPAMResponse, error) {
+	req.Action = ExternalIPAMActionReserve
+	resp, err := c.do(url, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IPAddress == "" {
+		return nil, fmt.Errorf("no ip address reserved by external ipam %s for port %s", url, req.Port)
+	}
+	return resp, nil
+}
+
+// Release posts the release request to the webhook
+func (c *ExternalIPAMClient) Release(url string, req ExternalIPAMRequest) error {
+	req.Action = ExternalIPAMActionRelease
+	_, err := c.do(url, req)
+	return err
+}
+
+// List posts the list request to the webhook and returns the reservations of the subnet
+func (c *ExternalIPAMClient) List(url, subnet, cidr string) ([]ExternalIPAMReservation, error) {
+	resp, err := c.do(url, ExternalIPAMRequest{Action: ExternalIPAMActionList, Subnet: subnet, CIDR: cidr})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Reservations, nil
+}
+
+func (c *ExternalIPAMClient) do(url string, req ExternalIPAMRequest) (*ExternalIPAMResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s addresses of port %s of subnet %s by external ipam %s: %v", req.Action, req.Port, req.Subnet, url, err)
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response of port %s from external ipam %s: %v", req.Action, req.Port, url, err)
+	}
+	resp := &ExternalIPAMResponse{}
+	if len(data) != 0 {
+		if err = json.Unmarshal(data, resp); err != nil && res.StatusCode == http.StatusOK {
+			return nil, fmt.Errorf("failed to parse %s response of port %s from external ipam %s: %v", req.Action, req.Port, url, err)
+		}
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s addresses of port %s by external ipam %s return %d %s", req.Action, req.Port, url, res.StatusCode, resp.Err)
+	}
+	return resp, nil
+}
@@ -21,21 +21,30 @@ const (
 	FipFinalizer         = "ovn.kubernetes.io/fip"
 	VipAnnotation        = "ovn.kubernetes.io/vip"
 	ChassisAnnotation    = "ovn.kubernetes.io/chassis"
-
-	VpcNatGatewayAnnotation     = "ovn.kubernetes.io/vpc_nat_gw"
-	VpcNatGatewayInitAnnotation = "ovn.kubernetes.io/vpc_nat_gw_init"
-	VpcEipsAnnotation           = "ovn.kubernetes.io/vpc_eips"
-	VpcFloatingIpMd5Annotation  = "ovn.kubernetes.io/vpc_floating_ips"
-	VpcDnatMd5Annotation        = "ovn.kubernetes.io/vpc_dnat_md5"
-	VpcSnatMd5Annotation        = "ovn.kubernetes.io/vpc_snat_md5"
-	VpcCIDRsAnnotation          = "ovn.kubernetes.io/vpc_cidrs"
-	VpcLbAnnotation             = "ovn.kubernetes.io/vpc_lb"
-	VpcExternalLabel            = "ovn.kubernetes.io/vpc_external"
-	VpcEipLabel                 = "ovn.kubernetes.io/vpc_eip"
-	VpcDnatEPortLabel           = "ovn.kubernetes.io/vpc_dnat_eport"
-	VpcNatLabel                 = "ovn.kubernetes.io/vpc_nat"
-
-	SwitchLBRuleVipsAnnotation = "ovn.kubernetes.io/switch_lb_vip"
+	ICPathMtuAnnotation  = "ovn.kubernetes.io/ic_path_mtu"
+
+	VpcNatGatewayAnnotation       = "ovn.kubernetes.io/vpc_nat_gw"
+	VpcNatGatewayInitAnnotation   = "ovn.kubernetes.io/vpc_nat_gw_init"
+	VpcNatGwDrainAnnotation       = "ovn.kubernetes.io/vpc_nat_gw_drain"
+	VpcNatGwDrainedFromAnnotation = "ovn.kubernetes.io/vpc_nat_gw_drained_from"
+	VpcEipsAnnotation             = "ovn.kubernetes.io/vpc_eips"
+	VpcFloatingIpMd5Annotation    = "ovn.kubernetes.io/vpc_floating_ips"
+	VpcDnatMd5Annotation          = "ovn.kubernetes.io/vpc_dnat_md5"
+	VpcSnatMd5Annotation          = "ovn.kubernetes.io/vpc_snat_md5"
+	VpcCIDRsAnnotation            = "ovn.kubernetes.io/vpc_cidrs"
+	VpcLbAnnotation               = "ovn.kubernetes.io/vpc_lb"
+	VpcSkipRouteCheckAnnotation   = "ovn.kubernetes.io/skip_route_check"
+	VpcExternalLabel              = "ovn.kubernetes.io/vpc_external"
+	VpcEipLabel                   = "ovn.kubernetes.io/vpc_eip"
+	VpcDnatEPortLabel             = "ovn.kubernetes.io/vpc_dnat_eport"
+	VpcNatLabel                   = "ovn.kubernetes.io/vpc_nat"
+
+	SwitchLBRuleVipsAnnotation   = "ovn.kubernetes.io/switch_lb_vip"
+	ServiceHairpinSnatAnnotation = "ovn.kubernetes.io/hairpin_snat"
+	ServiceLbSelectionAnnotation = "ovn.kubernetes.io/lb_selection"
+
+	EgressIPPoolAnnotation = "ovn.kubernetes.io/egress_ip_pool"
+	EgressIPAnnotation     = "ovn.kubernetes.io/egress_ip"
 
 	LogicalRouterAnnotation = "ovn.kubernetes.io/logical_router"
 	VpcAnnotation           = "ovn.kubernetes.io/vpc"
@@ -62,6 +71,19 @@ const (
 	SecurityGroupAnnotationTemplate = "%s.kubernetes.io/security_groups"
 	LiveMigrationAnnotationTemplate = "%s.kubernetes.io/allow_live_migration"
 	DefaultRouteAnnotationTemplate  = "%s.kubernetes.io/default_route"
+	GatewayRoutesAnnotationTemplate = "%s.kubernetes.io/gateway_routes"
+	TxChecksumOffAnnotationTemplate = "%s.kubernetes.io/tx_checksum_off"
+	DpdkQueuesAnnotationTemplate    = "%s.kubernetes.io/dpdk_queues"
+
+	GatewayCheckModeAnnotation                 = "ovn.kubernetes.io/gateway_check_mode"
+	GatewayCheckModeAnnotationTemplate         = "%s.kubernetes.io/gateway_check_mode"
+	ResolvedGatewayCheckModeAnnotationTemplate = "%s.kubernetes.io/resolved_gateway_check_mode"
+
+	PreferredIPFamilyAnnotation         = "ovn.kubernetes.io/preferred_ip_family"
+	PreferredIPFamilyAnnotationTemplate = "%s.kubernetes.io/preferred_ip_family"
+
+	ExternalIPAMAnnotation = "ovn.kubernetes.io/external_ipam"
+	ExternalIPAMFinalizer  = "ovn.kubernetes.io/external_ipam"
 
 	ProviderNetworkTemplate          = "%s.kubernetes.io/provider_network"
 	ProviderNetworkReadyTemplate     = "%s.provider-network.kubernetes.io/ready"
@@ -74,6 +96,9 @@ const (
 
 	ExcludeIpsAnnotation = "ovn.kubernetes.io/exclude_ips"
 
+	// ReconcileAnnotation with value "false" pauses the reconciliation of ovn resources of a subnet
+	ReconcileAnnotation = "ovn.kubeovn.io/reconcile"
+
 	IngressRateAnnotation = "ovn.kubernetes.io/ingress_rate"
 	EgressRateAnnotation  = "ovn.kubernetes.io/egress_rate"
 
@@ -81,6 +106,15 @@ const (
 	LogicalSwitchAnnotation = "ovn.kubernetes.io/logical_switch"
 
 	TunnelInterfaceAnnotation = "ovn.kubernetes.io/tunnel_interface"
+	// TunnelIPAnnotation selects the address of the node used as the tunnel endpoint, which takes precedence over
+	// the tunnel interface
+	TunnelIPAnnotation = "ovn.kubernetes.io/tunnel_ip"
+
+	// NodeNicRoutesAnnotation is a json list of the additional routes on the overlay interface of the node
+	NodeNicRoutesAnnotation = "ovn.kubeovn.io/node_nic_routes"
+
+	// AllocatedInterfacesAnnotation is a read-only json array of the allocations of all interfaces of a pod
+	AllocatedInterfacesAnnotation = "ovn.kubeovn.io/allocated_interfaces"
 
 	OvsDpTypeLabel = "ovn.kubernetes.io/ovs_dp_type"
 
@@ -91,6 +125,7 @@ const (
 	VpcNatGatewayLabel         = "ovn.kubernetes.io/vpc-nat-gw"
 	IpReservedLabel            = "ovn.kubernetes.io/ip_reserved"
 	VpcNatGatewayNameLabel     = "ovn.kubernetes.io/vpc-nat-gw-name"
+	VpcNatGwDrainingLabel      = "ovn.kubernetes.io/vpc-nat-gw-draining"
 	VpcLbLabel                 = "ovn.kubernetes.io/vpc_lb"
 	VpcDnsNameLabel            = "ovn.kubernetes.io/vpc-dns"
 	NetworkPolicyLogAnnotation = "ovn.kubernetes.io/enable_log"
@@ -107,6 +142,8 @@ const (
 	NodeNic           = "ovn0"
 	NodeAllowPriority = "3000"
 
+	GatewayPingAllowPriority = "3100"
+
 	SecurityGroupHighestPriority = "2300"
 	SecurityGroupAllowPriority   = "2004"
 	SecurityGroupDropPriority    = "2003"
@@ -120,6 +157,13 @@ const (
 	SubnetAllowPriority = "1001"
 	DefaultDropPriority = "1000"
 
+	AggregateRateQosPriority           = "1000"
+	NamespaceAggregateRateQosPriority  = "1050"
+	BroadcastRateLimitQosPriority      = "1100"
+	BroadcastRateLimitQosMatchTemplate = `inport == "%s" && (arp || nd || eth.bcast) && !(udp.dst == 67 || udp.dst == 547)`
+
+	GatewayArpSuppressionPriority = "3200"
+
 	GeneveHeaderLength = 100
 	VxlanHeaderLength  = 50
 	SttHeaderLength    = 72
@@ -154,10 +198,18 @@ const (
 	OvnFip      = "ovn"
 	IptablesFip = "iptables"
 
-	GatewayRouterPolicyPriority = 29000
-	NodeRouterPolicyPriority    = 30000
-	SubnetRouterPolicyPriority  = 31000
-	OvnICPolicyPriority         = 29500
+	GatewayRouterPolicyPriority         = 29000
+	WeightedGatewayRouterPolicyPriority = 29100
+	NodeRouterPolicyPriority            = 30000
+	SubnetRouterPolicyPriority          = 31000
+	PodGatewayRouterPolicyPriority      = 29200
+	OvnICPolicyPriority                 = 29500
+
+	// GatewayWeightAnnotation is the relative share of egress traffic of centralized subnets
+	// a gateway node takes when ecmp is enabled, 0 means no new egress traffic
+	GatewayWeightAnnotation = "ovn.kubernetes.io/gateway_weight"
+	// GatewayWeightBuckets is the number of source address buckets distributed by gateway weights
+	GatewayWeightBuckets = 16
 
 	OffloadType  = "offload-port"
 	InternalType = "internal-port"
@@ -172,6 +224,40 @@ const (
 	MirrorControlAnnotation = "ovn.kubernetes.io/mirror"
 	MirrorDefaultName       = "m0"
 
+	// OvsExternalIDsAnnotation is a json map of the labels attached to the ovs interfaces of the pod,
+	// the keys are prefixed by OvsCustomExternalIDPrefix to avoid clobbering the external ids of kube-ovn
+	OvsExternalIDsAnnotation  = "ovn.kubeovn.io/ovs_external_ids"
+	OvsCustomExternalIDPrefix = "user_"
+
+	// GatewayNodeAnnotation pins the egress traffic of the pod to the gateway of the node
+	GatewayNodeAnnotation = "ovn.kubeovn.io/gateway_node"
+
+	// SubnetMigrationAnnotation moves the primary nic of a running pod to the subnet in the logical switch annotation,
+	// it's set to requested by the user, to reconfiguring by the controller once the address and the port are moved,
+	// and removed by the daemon once the nic in the pod is reconfigured
+	SubnetMigrationAnnotation    = "ovn.kubeovn.io/subnet_migration"
+	SubnetMigrationRequested     = "requested"
+	SubnetMigrationReconfiguring = "reconfiguring"
+
+	// GCConfirmAnnotation with value "true" on the leader kube-ovn-controller pod confirms the gc blocked by
+	// the delete threshold, it's removed by the controller once the blocked gc is performed
+	GCConfirmAnnotation = "ovn.kubeovn.io/gc_confirm"
+
+	// SubnetSourceAnnotation records where the subnet of the pod is resolved from, pod, namespace, subnet or default
+	SubnetSourceAnnotation = "ovn.kubeovn.io/subnet_source"
+
+	// GatewayRebalanceAnnotation with value "true" on a centralized subnet redistributes the ecmp egress traffic
+	// across the healthy gateway nodes, it's removed by the controller once the rebalance is done
+	GatewayRebalanceAnnotation = "ovn.kubeovn.io/gateway_rebalance"
+
+	// AggregateEgressRateAnnotation on a namespace limits the total egress traffic of the pods in the namespace
+	AggregateEgressRateAnnotation = "ovn.kubeovn.io/aggregate_egress_rate"
+
+	// NetworkReadyConditionType is the pod readiness gate set to true when the network of the pod is ready
+	NetworkReadyConditionType = "ovn.kubeovn.io/network-ready"
+	// PortUpConditionType is the pod condition reflecting whether the logical switch port of the pod is up in ovn
+	PortUpConditionType = "ovn.kubeovn.io/port-up"
+
 	DenyAllSecurityGroup = "kubeovn_deny_all"
 
 	HtbQosHigh   = "htbqos-high"
@@ -182,11 +268,25 @@ const (
 	NetemQosLatencyAnnotation = "ovn.kubernetes.io/latency"
 	NetemQosLimitAnnotation   = "ovn.kubernetes.io/limit"
 	NetemQosLossAnnotation    = "ovn.kubernetes.io/loss"
+	QosTypeAnnotation         = "ovn.kubernetes.io/qos_type"
+	MinRateAnnotation         = "ovn.kubernetes.io/min_rate"
+	EgressRateModeAnnotation  = "ovn.kubernetes.io/egress_rate_mode"
 
 	PriorityAnnotationTemplate        = "%s.kubernetes.io/priority"
 	NetemQosLatencyAnnotationTemplate = "%s.kubernetes.io/latency"
 	NetemQosLimitAnnotationTemplate   = "%s.kubernetes.io/limit"
 	NetemQosLossAnnotationTemplate    = "%s.kubernetes.io/loss"
+	QosTypeAnnotationTemplate         = "%s.kubernetes.io/qos_type"
+	MinRateAnnotationTemplate         = "%s.kubernetes.io/min_rate"
+	EgressRateModeAnnotationTemplate  = "%s.kubernetes.io/egress_rate_mode"
+
+	// the egress traffic of pods exceeding the egress rate is dropped by ovs ingress policing
+	EgressRateModePolicing = "policing"
+	// the egress traffic of pods exceeding the egress rate is buffered by an ifb device
+	EgressRateModeShaping = "shaping"
+
+	// the default max rate in Mbit/s accepted by the ingress and egress rate annotations
+	DefaultMaxPodBandwidth = 100000
 
 	POD_IP             = "POD_IP"
 	ContentType        = "application/vnd.kubernetes.protobuf"
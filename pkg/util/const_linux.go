@@ -9,6 +9,7 @@ const (
 	NetSysDir  = "/sys/class/net"
 
 	HtbQos   = "linux-htb"
+	HfscQos  = "linux-hfsc"
 	NetemQos = "linux-netem"
 
 	KoDir  = "/tmp/"
@@ -1,11 +1,17 @@
 package util
 
 import (
+	"encoding/json"
+	"fmt"
+	"regexp"
 	"strings"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
+var ovsExternalIDKeyRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
 func GetNodeInternalIP(node v1.Node) (ipv4, ipv6 string) {
 	var ips []string
 	for _, addr := range node.Status.Addresses {
@@ -16,3 +22,59 @@ func GetNodeInternalIP(node v1.Node) (ipv4, ipv6 string) {
 
 	return SplitStringIP(strings.Join(ips, ","))
 }
+
+// ParseOvsExternalIDs parses the annotation of the custom external ids of ovs interfaces, the values must be valid
+// label values so that they are safe to be passed to ovs-vsctl
+func ParseOvsExternalIDs(annotation string) (map[string]string, error) {
+	if annotation == "" {
+		return nil, nil
+	}
+	ids := make(map[string]string)
+	if err := json.Unmarshal([]byte(annotation), &ids); err != nil {
+		return nil, fmt.Errorf("invalid ovs external ids %s: %v", annotation, err)
+	}
+	for k, v := range ids {
+		if !ovsExternalIDKeyRegex.MatchString(k) || len(k) > validation.LabelValueMaxLength {
+			return nil, fmt.Errorf("invalid ovs external id key %q", k)
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) != 0 {
+			return nil, fmt.Errorf("invalid value %q of ovs external id %s: %s", v, k, strings.Join(errs, ", "))
+		}
+	}
+	return ids, nil
+}
+
+// AllocatedInterface is the allocation of a pod interface in the allocated interfaces annotation
+type AllocatedInterface struct {
+	Provider string `json:"provider"`
+	Subnet   string `json:"subnet"`
+	IP       string `json:"ip"`
+	MAC      string `json:"mac"`
+	Gateway  string `json:"gateway"`
+}
+
+// GenerateAllocatedInterfaces consolidates the per-provider allocation annotations of the providers into the
+// allocated interfaces annotation, it returns an empty string if none of the providers is allocated
+func GenerateAllocatedInterfaces(annotations map[string]string, providers []string) (string, error) {
+	var interfaces []AllocatedInterface
+	for _, provider := range providers {
+		if annotations[fmt.Sprintf(AllocatedAnnotationTemplate, provider)] != "true" {
+			continue
+		}
+		interfaces = append(interfaces, AllocatedInterface{
+			Provider: provider,
+			Subnet:   annotations[fmt.Sprintf(LogicalSwitchAnnotationTemplate, provider)],
+			IP:       annotations[fmt.Sprintf(IpAddressAnnotationTemplate, provider)],
+			MAC:      annotations[fmt.Sprintf(MacAddressAnnotationTemplate, provider)],
+			Gateway:  annotations[fmt.Sprintf(GatewayAnnotationTemplate, provider)],
+		})
+	}
+	if len(interfaces) == 0 {
+		return "", nil
+	}
+	buf, err := json.Marshal(interfaces)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
@@ -3,6 +3,7 @@ package util
 import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
 	"testing"
 )
 
@@ -74,3 +75,98 @@ func TestGetNodeInternalIP(t *testing.T) {
 		})
 	}
 }
+
+func TestParseOvsExternalIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		exp        map[string]string
+		err        string
+	}{
+		{
+			name:       "empty",
+			annotation: "",
+			exp:        nil,
+			err:        "",
+		},
+		{
+			name:       "correct",
+			annotation: `{"team":"net","app.tier":"web-1"}`,
+			exp:        map[string]string{"team": "net", "app.tier": "web-1"},
+			err:        "",
+		},
+		{
+			name:       "invalidJson",
+			annotation: `{"team":1}`,
+			exp:        nil,
+			err:        "invalid ovs external ids",
+		},
+		{
+			name:       "invalidKey",
+			annotation: `{"team=a":"net"}`,
+			exp:        nil,
+			err:        "invalid ovs external id key",
+		},
+		{
+			name:       "invalidValue",
+			annotation: `{"team":"net work"}`,
+			exp:        nil,
+			err:        "invalid value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, err := ParseOvsExternalIDs(tt.annotation)
+			if !ErrorContains(err, tt.err) {
+				t.Errorf("got error %v, want %s", err, tt.err)
+			}
+			if !reflect.DeepEqual(ids, tt.exp) {
+				t.Errorf("got %v, want %v", ids, tt.exp)
+			}
+		})
+	}
+}
+
+func TestGenerateAllocatedInterfaces(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		providers   []string
+		exp         string
+	}{
+		{
+			name:        "none",
+			annotations: map[string]string{},
+			providers:   []string{"ovn"},
+			exp:         "",
+		},
+		{
+			name: "multiple",
+			annotations: map[string]string{
+				"ovn.kubernetes.io/allocated":                   "true",
+				"ovn.kubernetes.io/logical_switch":              "ovn-default",
+				"ovn.kubernetes.io/ip_address":                  "10.16.0.2",
+				"ovn.kubernetes.io/mac_address":                 "00:00:00:54:17:2a",
+				"ovn.kubernetes.io/gateway":                     "10.16.0.1",
+				"net1.default.ovn.kubernetes.io/allocated":      "true",
+				"net1.default.ovn.kubernetes.io/logical_switch": "net1",
+				"net1.default.ovn.kubernetes.io/ip_address":     "172.17.0.2,fd00::2",
+				"net1.default.ovn.kubernetes.io/mac_address":    "00:00:00:54:17:2b",
+				"net1.default.ovn.kubernetes.io/gateway":        "172.17.0.1,fd00::1",
+			},
+			providers: []string{"ovn", "net1.default.ovn", "net2.default.ovn"},
+			exp:       `[{"provider":"ovn","subnet":"ovn-default","ip":"10.16.0.2","mac":"00:00:00:54:17:2a","gateway":"10.16.0.1"},{"provider":"net1.default.ovn","subnet":"net1","ip":"172.17.0.2,fd00::2","mac":"00:00:00:54:17:2b","gateway":"172.17.0.1,fd00::1"}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ret, err := GenerateAllocatedInterfaces(tt.annotations, tt.providers)
+			if err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if ret != tt.exp {
+				t.Errorf("got %v, want %v", ret, tt.exp)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"math"
 	"math/big"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -28,17 +29,31 @@ const (
 	IPv6LinkLocalUnicast = "FE80::/10"
 )
 
+const (
+	// DefaultMacOUI is the prefix of the generated macs
+	DefaultMacOUI = "00:00:00"
+	// MacHostSpace is the number of macs sharing an oui
+	MacHostSpace = 1 << 24
+)
+
 // GenerateMac generates mac address.
 func GenerateMac() string {
-	prefix := "00:00:00"
+	return MacWithOUI(DefaultMacOUI, RandomMacHost())
+}
+
+// RandomMacHost returns a random host part of macs
+func RandomMacHost() uint32 {
 	b := make([]byte, 3)
 	_, err := rand.Read(b)
 	if err != nil {
 		klog.Errorf("generate mac error: %v", err)
 	}
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+}
 
-	mac := fmt.Sprintf("%s:%02X:%02X:%02X", prefix, b[0], b[1], b[2])
-	return mac
+// MacWithOUI returns the mac of the oui and the lower 24 bits of host
+func MacWithOUI(oui string, host uint32) string {
+	return fmt.Sprintf("%s:%02X:%02X:%02X", oui, byte(host>>16), byte(host>>8), byte(host))
 }
 
 func Ip2BigInt(ipStr string) *big.Int {
@@ -135,6 +150,99 @@ func CIDRContainIP(cidrStr, ipStr string) bool {
 	return containFlag
 }
 
+// SubnetCIDRs returns the CIDR block of the subnet followed by the extra IPv4 CIDR blocks
+func SubnetCIDRs(subnet *kubeovnv1.Subnet) string {
+	if len(subnet.Spec.ExtraCIDRBlocks) == 0 {
+		return subnet.Spec.CIDRBlock
+	}
+	return subnet.Spec.CIDRBlock + "," + strings.Join(subnet.Spec.ExtraCIDRBlocks, ",")
+}
+
+// SubnetCIDRForIP returns the CIDR of the subnet which the address is allocated from,
+// in the same format as the CIDR block of the subnet
+func SubnetCIDRForIP(subnet *kubeovnv1.Subnet, ipStr string) string {
+	if len(subnet.Spec.ExtraCIDRBlocks) == 0 {
+		return subnet.Spec.CIDRBlock
+	}
+	v4IP, _ := SplitStringIP(ipStr)
+	v4CIDR, v6CIDR := SplitStringIP(subnet.Spec.CIDRBlock)
+	for _, cidr := range subnet.Spec.ExtraCIDRBlocks {
+		if CIDRContainIP(cidr, v4IP) {
+			v4CIDR = cidr
+			break
+		}
+	}
+	if v4CIDR != "" && v6CIDR != "" {
+		return v4CIDR + "," + v6CIDR
+	}
+	return v4CIDR + v6CIDR
+}
+
+// ExtraCIDRRouterIPs returns the first address of each extra CIDR block, which is configured on the router port
+// so that ovn routes the CIDR as directly connected. The pods of all the CIDRs use the gateway of the subnet
+func ExtraCIDRRouterIPs(subnet *kubeovnv1.Subnet) []string {
+	gws := make([]string, 0, len(subnet.Spec.ExtraCIDRBlocks))
+	for _, cidr := range subnet.Spec.ExtraCIDRBlocks {
+		if gw, err := FirstIP(cidr); err == nil {
+			gws = append(gws, gw)
+		}
+	}
+	return gws
+}
+
+// ExtraCIDRNetworks returns the router port networks of the extra CIDR blocks
+func ExtraCIDRNetworks(subnet *kubeovnv1.Subnet) []string {
+	networks := make([]string, 0, len(subnet.Spec.ExtraCIDRBlocks))
+	for _, cidr := range subnet.Spec.ExtraCIDRBlocks {
+		if gw, err := FirstIP(cidr); err == nil {
+			networks = append(networks, GetIpAddrWithMask(gw, cidr))
+		}
+	}
+	return networks
+}
+
+// SubnetLogicalSwitchOptions returns the other_config options of the logical switch of the subnet,
+// including the options of the multicast fields
+func SubnetLogicalSwitchOptions(subnet *kubeovnv1.Subnet) map[string]string {
+	options := make(map[string]string, len(subnet.Spec.LogicalSwitchOptions)+5)
+	for key, value := range subnet.Spec.LogicalSwitchOptions {
+		options[key] = value
+	}
+	if !subnet.Spec.EnableMulticastSnoop {
+		return options
+	}
+
+	options["mcast_snoop"] = "true"
+	options["mcast_querier"] = strconv.FormatBool(subnet.Spec.EnableMulticastQuerier)
+	if !subnet.Spec.EnableMulticastQuerier {
+		return options
+	}
+	querierIP := subnet.Spec.MulticastQuerierIP
+	if querierIP == "" {
+		querierIP = subnet.Spec.Gateway
+	}
+	v4IP, v6IP := SplitStringIP(querierIP)
+	if v4IP != "" {
+		options["mcast_ip4_src"] = v4IP
+	}
+	if v6IP != "" {
+		options["mcast_ip6_src"] = v6IP
+	}
+	if mac := multicastQuerierMac(querierIP); mac != "" {
+		options["mcast_eth_src"] = mac
+	}
+	return options
+}
+
+// multicastQuerierMac derives a stable locally administered mac from the last four bytes of the first querier ip
+func multicastQuerierMac(querierIP string) string {
+	ip := net.ParseIP(strings.Split(querierIP, ",")[0]).To16()
+	if ip == nil {
+		return ""
+	}
+	return fmt.Sprintf("0a:58:%02x:%02x:%02x:%02x", ip[12], ip[13], ip[14], ip[15])
+}
+
 func CheckProtocol(address string) string {
 	ips := strings.Split(address, ",")
 	if len(ips) == 2 {
@@ -319,6 +427,44 @@ func SplitStringIP(ipStr string) (string, string) {
 	return v4IP, v6IP
 }
 
+// PreferIPFamily moves the dual stack addresses of the preferred family to the front,
+// the addresses are returned as is if they are not dual stack or no family is preferred
+func PreferIPFamily(ipStr, family string) string {
+	if CheckProtocol(ipStr) != kubeovnv1.ProtocolDual {
+		return ipStr
+	}
+	v4IP, v6IP := SplitStringIP(ipStr)
+	if family == kubeovnv1.ProtocolIPv6 {
+		return v6IP + "," + v4IP
+	}
+	return v4IP + "," + v6IP
+}
+
+// ReverseDNSZone returns the in-addr.arpa or ip6.arpa zone covering the cidr,
+// the prefix is rounded down to the octet boundary for IPv4 and the nibble boundary for IPv6
+func ReverseDNSZone(cidr string) (string, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", err
+	}
+	ones, _ := ipNet.Mask.Size()
+	var labels []string
+	if ip := ipNet.IP.To4(); ip != nil {
+		for i := ones/8 - 1; i >= 0; i-- {
+			labels = append(labels, strconv.Itoa(int(ip[i])))
+		}
+		return strings.Join(append(labels, "in-addr.arpa"), "."), nil
+	}
+	for i := ones/4 - 1; i >= 0; i-- {
+		nibble := ipNet.IP[i/2] >> 4
+		if i%2 == 1 {
+			nibble = ipNet.IP[i/2] & 0x0f
+		}
+		labels = append(labels, strconv.FormatInt(int64(nibble), 16))
+	}
+	return strings.Join(append(labels, "ip6.arpa"), "."), nil
+}
+
 // ExpandExcludeIPs used to get exclude ips in range of subnet cidr, excludes cidr addr and broadcast addr
 func ExpandExcludeIPs(excludeIPs []string, cidr string) []string {
 	rv := []string{}
@@ -429,6 +575,75 @@ func JoinHostPort(host string, port int32) string {
 	return net.JoinHostPort(host, strconv.FormatInt(int64(port), 10))
 }
 
+// PortRange is an inclusive range of layer 4 ports
+type PortRange struct {
+	Min int
+	Max int
+}
+
+func (r PortRange) Size() int {
+	return r.Max - r.Min + 1
+}
+
+// String returns the range in the format of iptables, a single port is not formatted as a range
+func (r PortRange) String() string {
+	if r.Min == r.Max {
+		return strconv.Itoa(r.Min)
+	}
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// ParsePortRanges parses the comma separated ports and port ranges like 9100,30000-32767
+func ParsePortRanges(s string) ([]PortRange, error) {
+	var ranges []PortRange
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		bounds := strings.SplitN(field, "-", 2)
+		min, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid port range %q", field)
+		}
+		max := min
+		if len(bounds) == 2 {
+			if max, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil {
+				return nil, fmt.Errorf("invalid port range %q", field)
+			}
+		}
+		if min <= 0 || max > 65535 || min > max {
+			return nil, fmt.Errorf("invalid port range %q, ports must be in 1-65535 and in ascending order", field)
+		}
+		ranges = append(ranges, PortRange{Min: min, Max: max})
+	}
+	return ranges, nil
+}
+
+// ExcludePortRanges returns the segments of the range not in any of the excluded ranges, in ascending order
+func ExcludePortRanges(r PortRange, excluded []PortRange) []PortRange {
+	excluded = append([]PortRange{}, excluded...)
+	sort.Slice(excluded, func(i, j int) bool { return excluded[i].Min < excluded[j].Min })
+
+	var segments []PortRange
+	next := r.Min
+	for _, e := range excluded {
+		if e.Max < next {
+			continue
+		}
+		if e.Min > r.Max {
+			break
+		}
+		if e.Min > next {
+			segments = append(segments, PortRange{Min: next, Max: e.Min - 1})
+		}
+		next = e.Max + 1
+	}
+	if next <= r.Max {
+		segments = append(segments, PortRange{Min: next, Max: r.Max})
+	}
+	return segments
+}
+
 func CIDROverlap(a, b string) bool {
 	for _, cidrA := range strings.Split(a, ",") {
 		for _, cidrB := range strings.Split(b, ",") {
@@ -499,3 +714,25 @@ func CheckSystemCIDR(cidrs []string) error {
 	}
 	return nil
 }
+
+// ParseLspAddresses returns the mac and ip addresses in the addresses column of a logical switch port,
+// the special entries like unknown, router and dynamic are ignored
+func ParseLspAddresses(addresses []string) (string, []string) {
+	var mac string
+	var ips []string
+	for _, address := range addresses {
+		fields := strings.Fields(address)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "unknown", "router", "dynamic":
+			continue
+		}
+		if mac == "" {
+			mac = fields[0]
+		}
+		ips = append(ips, fields[1:]...)
+	}
+	return mac, ips
+}
@@ -115,6 +115,41 @@ func TestGenerateMac(t *testing.T) {
 	}
 }
 
+func TestMacWithOUI(t *testing.T) {
+	tests := []struct {
+		name   string
+		oui    string
+		host   uint32
+		expect string
+	}{
+		{
+			name:   "first",
+			oui:    "02:1A:2B",
+			host:   0,
+			expect: "02:1A:2B:00:00:00",
+		},
+		{
+			name:   "last",
+			oui:    "02:1A:2B",
+			host:   MacHostSpace - 1,
+			expect: "02:1A:2B:FF:FF:FF",
+		},
+		{
+			name:   "overflow",
+			oui:    DefaultMacOUI,
+			host:   MacHostSpace + 0x0102,
+			expect: "00:00:00:00:01:02",
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			if ans := MacWithOUI(c.oui, c.host); ans != c.expect {
+				t.Errorf("%v expected %v, but %v got", c.name, c.expect, ans)
+			}
+		})
+	}
+}
+
 func TestIp2BigInt(t *testing.T) {
 	tests := []struct {
 		expect *big.Int
@@ -335,6 +370,52 @@ func TestCIDRContainIP(t *testing.T) {
 	}
 }
 
+func TestSubnetCIDRForIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		cidr   string
+		extras []string
+		ip     string
+		want   string
+	}{
+		{
+			name: "noExtra",
+			cidr: "192.168.0.0/24",
+			ip:   "192.168.0.10",
+			want: "192.168.0.0/24",
+		},
+		{
+			name:   "primary",
+			cidr:   "192.168.0.0/24",
+			extras: []string{"10.0.1.0/24"},
+			ip:     "192.168.0.10",
+			want:   "192.168.0.0/24",
+		},
+		{
+			name:   "extra",
+			cidr:   "192.168.0.0/24",
+			extras: []string{"10.0.1.0/24", "10.0.3.0/24"},
+			ip:     "10.0.3.10",
+			want:   "10.0.3.0/24",
+		},
+		{
+			name:   "dual",
+			cidr:   "192.168.0.0/24,fd00::/120",
+			extras: []string{"10.0.1.0/24"},
+			ip:     "10.0.1.10,fd00::a",
+			want:   "10.0.1.0/24,fd00::/120",
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			subnet := &kubeovnv1.Subnet{Spec: kubeovnv1.SubnetSpec{CIDRBlock: c.cidr, ExtraCIDRBlocks: c.extras}}
+			if ans := SubnetCIDRForIP(subnet, c.ip); ans != c.want {
+				t.Errorf("%v expected %v, but %v got", c.ip, c.want, ans)
+			}
+		})
+	}
+}
+
 func TestCheckProtocol(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -993,3 +1074,190 @@ func TestJoinHostPort(t *testing.T) {
 		})
 	}
 }
+
+func TestPreferIPFamily(t *testing.T) {
+	tests := []struct {
+		name   string
+		ipStr  string
+		family string
+		want   string
+	}{
+		{"dualV6", "10.16.0.2,fd00::2", kubeovnv1.ProtocolIPv6, "fd00::2,10.16.0.2"},
+		{"dualV4", "fd00::2,10.16.0.2", kubeovnv1.ProtocolIPv4, "10.16.0.2,fd00::2"},
+		{"dualNone", "10.16.0.2,fd00::2", "", "10.16.0.2,fd00::2"},
+		{"cidrV6", "10.16.0.2/16,fd00::2/64", kubeovnv1.ProtocolIPv6, "fd00::2/64,10.16.0.2/16"},
+		{"v4", "10.16.0.2", kubeovnv1.ProtocolIPv6, "10.16.0.2"},
+		{"empty", "", kubeovnv1.ProtocolIPv6, ""},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			if ans := PreferIPFamily(c.ipStr, c.family); ans != c.want {
+				t.Errorf("%v, %v expected %v but %v got", c.ipStr, c.family, c.want, ans)
+			}
+		})
+	}
+}
+
+func TestReverseDNSZone(t *testing.T) {
+	tests := []struct {
+		name string
+		cidr string
+		want string
+		err  string
+	}{
+		{"v4Octet", "10.16.0.0/16", "16.10.in-addr.arpa", ""},
+		{"v4RoundDown", "10.16.16.0/20", "16.10.in-addr.arpa", ""},
+		{"v4Host", "192.168.1.10/32", "10.1.168.192.in-addr.arpa", ""},
+		{"v4All", "0.0.0.0/0", "in-addr.arpa", ""},
+		{"v6Nibble", "fd00:10:16::/64", "0.0.0.0.6.1.0.0.0.1.0.0.0.0.d.f.ip6.arpa", ""},
+		{"v6RoundDown", "fd00:10:16::/118", "0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.6.1.0.0.0.1.0.0.0.0.d.f.ip6.arpa", ""},
+		{"invalid", "10.16.0.0", "", "invalid CIDR address: 10.16.0.0"},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			ans, err := ReverseDNSZone(c.cidr)
+			if !ErrorContains(err, c.err) || ans != c.want {
+				t.Errorf("%v expected %v, %v but %v, %v got", c.cidr, c.want, c.err, ans, err)
+			}
+		})
+	}
+}
+
+func TestParsePortRanges(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []PortRange
+		err  string
+	}{
+		{"empty", "", nil, ""},
+		{"port", "9100", []PortRange{{9100, 9100}}, ""},
+		{"ranges", "9100, 30000-32767", []PortRange{{9100, 9100}, {30000, 32767}}, ""},
+		{"zero", "0-1024", nil, "ports must be in 1-65535"},
+		{"tooLarge", "60000-65536", nil, "ports must be in 1-65535"},
+		{"descending", "2000-1000", nil, "ascending order"},
+		{"invalid", "1000-", nil, `invalid port range "1000-"`},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			ans, err := ParsePortRanges(c.s)
+			if !ErrorContains(err, c.err) || !reflect.DeepEqual(ans, c.want) {
+				t.Errorf("%v expected %v, %v but %v, %v got", c.s, c.want, c.err, ans, err)
+			}
+		})
+	}
+}
+
+func TestExcludePortRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		r        PortRange
+		excluded []PortRange
+		want     []PortRange
+	}{
+		{"none", PortRange{40000, 60000}, nil, []PortRange{{40000, 60000}}},
+		{"outside", PortRange{40000, 60000}, []PortRange{{9100, 9100}, {61000, 62000}}, []PortRange{{40000, 60000}}},
+		{"middle", PortRange{40000, 60000}, []PortRange{{50000, 50099}, {45000, 45000}}, []PortRange{{40000, 44999}, {45001, 49999}, {50100, 60000}}},
+		{"edges", PortRange{40000, 60000}, []PortRange{{39000, 40999}, {59000, 61000}}, []PortRange{{41000, 58999}}},
+		{"overlapped", PortRange{40000, 60000}, []PortRange{{45000, 47000}, {46000, 48000}}, []PortRange{{40000, 44999}, {48001, 60000}}},
+		{"all", PortRange{40000, 60000}, []PortRange{{30000, 65535}}, nil},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			if ans := ExcludePortRanges(c.r, c.excluded); !reflect.DeepEqual(ans, c.want) {
+				t.Errorf("%v excluding %v expected %v but %v got", c.r, c.excluded, c.want, ans)
+			}
+		})
+	}
+}
+
+func TestParseLspAddresses(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []string
+		mac       string
+		ips       []string
+	}{
+		{
+			name:      "v4",
+			addresses: []string{"00:00:00:0a:0b:0c 10.16.0.5"},
+			mac:       "00:00:00:0a:0b:0c",
+			ips:       []string{"10.16.0.5"},
+		},
+		{
+			name:      "dual",
+			addresses: []string{"00:00:00:0a:0b:0c 10.16.0.5 fd00:10:16::5"},
+			mac:       "00:00:00:0a:0b:0c",
+			ips:       []string{"10.16.0.5", "fd00:10:16::5"},
+		},
+		{
+			name:      "unknown",
+			addresses: []string{"unknown", "00:00:00:0a:0b:0c 10.16.0.5"},
+			mac:       "00:00:00:0a:0b:0c",
+			ips:       []string{"10.16.0.5"},
+		},
+		{
+			name:      "secondary",
+			addresses: []string{"00:00:00:0a:0b:0c 10.16.0.5", "00:00:00:0a:0b:0c 10.16.0.6"},
+			mac:       "00:00:00:0a:0b:0c",
+			ips:       []string{"10.16.0.5", "10.16.0.6"},
+		},
+		{
+			name:      "macOnly",
+			addresses: []string{"00:00:00:0a:0b:0c"},
+			mac:       "00:00:00:0a:0b:0c",
+			ips:       nil,
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			mac, ips := ParseLspAddresses(c.addresses)
+			if mac != c.mac || !reflect.DeepEqual(ips, c.ips) {
+				t.Errorf("%v expected %v %v but %v %v got",
+					c.addresses, c.mac, c.ips, mac, ips)
+			}
+		})
+	}
+}
+
+func TestSubnetLogicalSwitchOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    kubeovnv1.SubnetSpec
+		options map[string]string
+	}{
+		{
+			name:    "none",
+			spec:    kubeovnv1.SubnetSpec{CIDRBlock: "10.16.0.0/16", Gateway: "10.16.0.1"},
+			options: map[string]string{},
+		},
+		{
+			name: "snoop",
+			spec: kubeovnv1.SubnetSpec{CIDRBlock: "10.16.0.0/16", Gateway: "10.16.0.1", EnableMulticastSnoop: true,
+				LogicalSwitchOptions: map[string]string{"mcast_table_size": "1024"}},
+			options: map[string]string{"mcast_snoop": "true", "mcast_querier": "false", "mcast_table_size": "1024"},
+		},
+		{
+			name: "querierGateway",
+			spec: kubeovnv1.SubnetSpec{CIDRBlock: "10.16.0.0/16,fd00:10:16::/64", Gateway: "10.16.0.1,fd00:10:16::1",
+				EnableMulticastSnoop: true, EnableMulticastQuerier: true},
+			options: map[string]string{"mcast_snoop": "true", "mcast_querier": "true", "mcast_ip4_src": "10.16.0.1",
+				"mcast_ip6_src": "fd00:10:16::1", "mcast_eth_src": "0a:58:0a:10:00:01"},
+		},
+		{
+			name: "querierIP",
+			spec: kubeovnv1.SubnetSpec{CIDRBlock: "fd00:10:16::/64", Gateway: "fd00:10:16::1",
+				EnableMulticastSnoop: true, EnableMulticastQuerier: true, MulticastQuerierIP: "fd00:10:16::fe"},
+			options: map[string]string{"mcast_snoop": "true", "mcast_querier": "true", "mcast_ip6_src": "fd00:10:16::fe",
+				"mcast_eth_src": "0a:58:00:00:00:fe"},
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			options := SubnetLogicalSwitchOptions(&kubeovnv1.Subnet{Spec: c.spec})
+			if !reflect.DeepEqual(options, c.options) {
+				t.Errorf("expected %v but %v got", c.options, options)
+			}
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package util
 
-import "strings"
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
 
 func DoubleQuotedFields(s string) []string {
 	var quoted bool
@@ -22,3 +27,20 @@ func DoubleQuotedFields(s string) []string {
 
 	return fields
 }
+
+// CountCPUMask returns the number of cores set in a hexadecimal cpu mask, e.g. pmd-cpu-mask of ovs-dpdk
+func CountCPUMask(mask string) (int, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(mask), "0x"), "0X")
+	if s == "" {
+		return 0, fmt.Errorf("empty cpu mask")
+	}
+	var count int
+	for _, c := range s {
+		v, err := strconv.ParseUint(string(c), 16, 8)
+		if err != nil {
+			return 0, fmt.Errorf("invalid cpu mask %q", mask)
+		}
+		count += bits.OnesCount8(uint8(v))
+	}
+	return count, nil
+}
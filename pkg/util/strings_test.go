@@ -48,3 +48,52 @@ func TestDoubleQuotedFields(t *testing.T) {
 		})
 	}
 }
+
+func TestCountCPUMask(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  string
+		want int
+		err  string
+	}{
+		{
+			name: "single core",
+			arg:  "0x4",
+			want: 1,
+		},
+		{
+			name: "without prefix",
+			arg:  "f0",
+			want: 4,
+		},
+		{
+			name: "wide mask",
+			arg:  "0x10000000000000003",
+			want: 3,
+		},
+		{
+			name: "empty",
+			arg:  "0x",
+			err:  "empty cpu mask",
+		},
+		{
+			name: "invalid",
+			arg:  "0xz1",
+			err:  `invalid cpu mask "0xz1"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ret, err := CountCPUMask(tt.arg)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("got error %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil || ret != tt.want {
+				t.Errorf("got %d, %v, want %d", ret, err, tt.want)
+			}
+		})
+	}
+}
@@ -1,21 +1,31 @@
 package util
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"net"
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 
 	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/request"
 )
 
 func ValidateSubnet(subnet kubeovnv1.Subnet) error {
 	if subnet.Spec.Gateway != "" && !CIDRContainIP(subnet.Spec.CIDRBlock, subnet.Spec.Gateway) {
 		return fmt.Errorf(" gateway %s is not in cidr %s", subnet.Spec.Gateway, subnet.Spec.CIDRBlock)
 	}
+	if len(subnet.Spec.ExtraCIDRBlocks) != 0 {
+		if err := validateExtraCIDRBlocks(subnet); err != nil {
+			return err
+		}
+	}
 	if err := CIDRGlobalUnicast(subnet.Spec.CIDRBlock); err != nil {
 		return err
 	}
@@ -54,6 +64,15 @@ func ValidateSubnet(subnet kubeovnv1.Subnet) error {
 		}
 	}
 
+	if subnet.Spec.GatewayMac != "" {
+		if subnet.Spec.Vlan == "" || subnet.Spec.LogicalGateway {
+			return fmt.Errorf("gatewayMac is only supported by underlay subnets with physical gateway")
+		}
+		if _, err := net.ParseMAC(subnet.Spec.GatewayMac); err != nil {
+			return fmt.Errorf("gatewayMac %s is not a valid mac address", subnet.Spec.GatewayMac)
+		}
+	}
+
 	gwType := subnet.Spec.GatewayType
 	if gwType != "" && gwType != kubeovnv1.GWDistributedType && gwType != kubeovnv1.GWCentralizedType {
 		return fmt.Errorf("%s is not a valid gateway type", gwType)
@@ -92,9 +111,430 @@ func ValidateSubnet(subnet kubeovnv1.Subnet) error {
 			}
 		}
 	}
+
+	if _, err := ParseBandwidth(subnet.Spec.DefaultIngressRate, 0); err != nil {
+		return fmt.Errorf("%s is not a valid defaultIngressRate: %v", subnet.Spec.DefaultIngressRate, err)
+	}
+	if _, err := ParseBandwidth(subnet.Spec.DefaultEgressRate, 0); err != nil {
+		return fmt.Errorf("%s is not a valid defaultEgressRate: %v", subnet.Spec.DefaultEgressRate, err)
+	}
+	if subnet.Spec.GatewayUnavailablePolicy != "" &&
+		subnet.Spec.GatewayUnavailablePolicy != kubeovnv1.GatewayUnavailablePolicyAllow &&
+		subnet.Spec.GatewayUnavailablePolicy != kubeovnv1.GatewayUnavailablePolicyFail {
+		return fmt.Errorf("%s is not a valid gatewayUnavailablePolicy, must be allow or fail", subnet.Spec.GatewayUnavailablePolicy)
+	}
+	if subnet.Spec.QosType != "" && subnet.Spec.QosType != "linux-htb" && subnet.Spec.QosType != "linux-hfsc" {
+		return fmt.Errorf("%s is not a valid qosType, must be linux-htb or linux-hfsc", subnet.Spec.QosType)
+	}
+	if subnet.Spec.AggregateEgressRate != "" {
+		if rate, err := strconv.Atoi(subnet.Spec.AggregateEgressRate); err != nil || rate < 0 {
+			return fmt.Errorf("%s is not a valid aggregateEgressRate", subnet.Spec.AggregateEgressRate)
+		}
+	}
+
+	if subnet.Spec.BroadcastRateLimit < 0 {
+		return fmt.Errorf("%d is not a valid broadcastRateLimit", subnet.Spec.BroadcastRateLimit)
+	}
+
+	if subnet.Spec.DHCPLeaseTime != 0 {
+		if err := ValidateDHCPLeaseTime(subnet.Spec.DHCPLeaseTime, subnet.Spec.DHCPv4Options); err != nil {
+			return err
+		}
+	}
+
+	if subnet.Spec.GatewayCheckPort < 0 || subnet.Spec.GatewayCheckPort > 65535 {
+		return fmt.Errorf("%d is not a valid gatewayCheckPort", subnet.Spec.GatewayCheckPort)
+	}
+
+	if subnet.Spec.GatewayCheckMode != "" {
+		if err := ValidateGatewayCheckMode(subnet.Spec.GatewayCheckMode, subnet.Spec.GatewayCheckPort); err != nil {
+			return err
+		}
+	}
+
+	if subnet.Spec.PreferredIPFamily != "" {
+		if err := ValidatePreferredIPFamily(subnet.Spec.PreferredIPFamily, CheckProtocol(subnet.Spec.CIDRBlock)); err != nil {
+			return err
+		}
+	}
+
+	switch subnet.Spec.ArpResponder {
+	case "", kubeovnv1.ArpResponderDistributed, kubeovnv1.ArpResponderFlood:
+	case kubeovnv1.ArpResponderRouter:
+		if subnet.Spec.Vlan != "" && !subnet.Spec.LogicalGateway {
+			return fmt.Errorf("arpResponder router requires a logical router port, which underlay subnets without logicalGateway do not have")
+		}
+	default:
+		return fmt.Errorf("%s is not a valid arpResponder, must be distributed, flood or router", subnet.Spec.ArpResponder)
+	}
+	if subnet.Spec.SuppressGatewayArp {
+		if subnet.Spec.Vlan == "" {
+			return fmt.Errorf("suppressGatewayArp is only supported by underlay subnets, which have a physical gateway")
+		}
+		if subnet.Spec.ArpResponder == kubeovnv1.ArpResponderRouter {
+			return fmt.Errorf("suppressGatewayArp conflicts with arpResponder router, which answers for the subnet by the router port")
+		}
+	}
+
+	if subnet.Spec.PodIfName != "" {
+		if err := ValidateInterfaceName(subnet.Spec.PodIfName); err != nil {
+			return fmt.Errorf("invalid podIfName: %v", err)
+		}
+	}
+
+	if err := validateGatewayMode(subnet); err != nil {
+		return err
+	}
+	if err := ValidateLogicalSwitchOptions(subnet.Spec.LogicalSwitchOptions); err != nil {
+		return err
+	}
+	if err := validateMulticast(subnet); err != nil {
+		return err
+	}
+	if err := validateExternalIPAM(subnet.Spec.ExternalIPAM); err != nil {
+		return err
+	}
+	if subnet.Spec.MacOUI != "" {
+		if err := ValidateMacOUI(subnet.Spec.MacOUI); err != nil {
+			return fmt.Errorf("invalid macOUI %s: %v", subnet.Spec.MacOUI, err)
+		}
+	}
+	return nil
+}
+
+// ValidateDHCPLeaseTime checks that the lease time fits in the 32-bit lease_time option of ovn,
+// and that it is not specified again by the custom dhcpv4 options
+func ValidateDHCPLeaseTime(leaseTime int, dhcpV4Options string) error {
+	if leaseTime < 0 || int64(leaseTime) > math.MaxUint32 {
+		return fmt.Errorf("%d is not a valid dhcpLeaseTime", leaseTime)
+	}
+	for _, option := range strings.Split(dhcpV4Options, ",") {
+		if strings.TrimSpace(strings.SplitN(option, "=", 2)[0]) == "lease_time" {
+			return fmt.Errorf("dhcpLeaseTime conflicts with the lease_time in dhcpV4Options")
+		}
+	}
+	return nil
+}
+
+// ValidateMacOUI checks that the oui is a 3-byte unicast prefix in the form of xx:xx:xx
+func ValidateMacOUI(oui string) error {
+	mac, err := net.ParseMAC(oui + ":00:00:00")
+	if err != nil {
+		return fmt.Errorf("must be 3 bytes in the form of xx:xx:xx")
+	}
+	if mac[0]&0x01 != 0 {
+		return fmt.Errorf("the multicast bit of the first byte must not be set")
+	}
+	return nil
+}
+
+func validateExternalIPAM(externalIPAM string) error {
+	if externalIPAM == "" {
+		return nil
+	}
+	u, err := url.Parse(externalIPAM)
+	if err != nil {
+		return fmt.Errorf("externalIPAM %s is not a valid url: %v", externalIPAM, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("externalIPAM %s must be an http or https url", externalIPAM)
+	}
+	return nil
+}
+
+// multicastOptions are the logical switch options managed by the multicast fields of subnets
+var multicastOptions = []string{"mcast_snoop", "mcast_querier", "mcast_eth_src", "mcast_ip4_src", "mcast_ip6_src"}
+
+func validateMulticast(subnet kubeovnv1.Subnet) error {
+	if !subnet.Spec.EnableMulticastSnoop {
+		if subnet.Spec.EnableMulticastQuerier {
+			return fmt.Errorf("enableMulticastQuerier requires enableMulticastSnoop")
+		}
+		if subnet.Spec.MulticastQuerierIP != "" {
+			return fmt.Errorf("multicastQuerierIP requires enableMulticastQuerier")
+		}
+		return nil
+	}
+	for _, key := range multicastOptions {
+		if _, ok := subnet.Spec.LogicalSwitchOptions[key]; ok {
+			return fmt.Errorf("logical switch option %s conflicts with enableMulticastSnoop", key)
+		}
+	}
+	if subnet.Spec.MulticastQuerierIP == "" {
+		return nil
+	}
+	if !subnet.Spec.EnableMulticastQuerier {
+		return fmt.Errorf("multicastQuerierIP requires enableMulticastQuerier")
+	}
+
+	cidrs := make(map[string]string, 2)
+	for _, cidr := range strings.Split(subnet.Spec.CIDRBlock, ",") {
+		cidrs[CheckProtocol(cidr)] = cidr
+	}
+	querierIPs := make(map[string]bool, 2)
+	for _, ip := range strings.Split(subnet.Spec.MulticastQuerierIP, ",") {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("multicastQuerierIP %s is not a valid ip", ip)
+		}
+		protocol := CheckProtocol(ip)
+		if querierIPs[protocol] {
+			return fmt.Errorf("multicastQuerierIP %s has more than one %s address", subnet.Spec.MulticastQuerierIP, protocol)
+		}
+		querierIPs[protocol] = true
+		if !CIDRContainIP(cidrs[protocol], ip) {
+			return fmt.Errorf("multicastQuerierIP %s is not in the cidr of subnet %s", ip, subnet.Name)
+		}
+	}
+	return nil
+}
+
+func validateBoolOption(value string) error {
+	if value != "true" && value != "false" {
+		return fmt.Errorf("must be true or false")
+	}
+	return nil
+}
+
+func validatePositiveIntOption(value string) error {
+	if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+		return fmt.Errorf("must be a positive integer")
+	}
+	return nil
+}
+
+func validateIPOption(protocol string) func(string) error {
+	return func(value string) error {
+		if net.ParseIP(value) == nil || CheckProtocol(value) != protocol {
+			return fmt.Errorf("must be an %s address", protocol)
+		}
+		return nil
+	}
+}
+
+// LogicalSwitchOptions are the other_config options of logical switches which can be set by subnets with their validators,
+// the options managed by kube-ovn such as subnet and exclude_ips are not included
+var LogicalSwitchOptions = map[string]func(string) error{
+	"mcast_snoop":              validateBoolOption,
+	"mcast_querier":            validateBoolOption,
+	"mcast_flood_unregistered": validateBoolOption,
+	"mcast_table_size":         validatePositiveIntOption,
+	"mcast_idle_timeout":       validatePositiveIntOption,
+	"mcast_query_interval":     validatePositiveIntOption,
+	"mcast_query_max_response": validatePositiveIntOption,
+	"mcast_eth_src": func(value string) error {
+		if _, err := net.ParseMAC(value); err != nil {
+			return fmt.Errorf("must be a mac address")
+		}
+		return nil
+	},
+	"mcast_ip4_src": validateIPOption(kubeovnv1.ProtocolIPv4),
+	"mcast_ip6_src": validateIPOption(kubeovnv1.ProtocolIPv6),
+}
+
+// ValidateLogicalSwitchOptions rejects the logical switch options out of the allowlist and the invalid values
+func ValidateLogicalSwitchOptions(options map[string]string) error {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		validate, ok := LogicalSwitchOptions[key]
+		if !ok {
+			return fmt.Errorf("logical switch option %s is not supported", key)
+		}
+		if err := validate(options[key]); err != nil {
+			return fmt.Errorf("invalid value %q of logical switch option %s: %v", options[key], key, err)
+		}
+	}
+	return nil
+}
+
+// SubnetGatewayMode returns the effective gateway mode of the subnet, the mode derived from vlan and logicalGateway
+// is returned if it's not set
+func SubnetGatewayMode(subnet kubeovnv1.Subnet) string {
+	if subnet.Spec.GatewayMode != "" {
+		return subnet.Spec.GatewayMode
+	}
+	if subnet.Spec.Vlan != "" && !subnet.Spec.LogicalGateway {
+		return kubeovnv1.GatewayModeL2
+	}
+	return kubeovnv1.GatewayModeL3
+}
+
+// validateGatewayMode rejects the configurations incompatible with the gateway mode set explicitly
+func validateGatewayMode(subnet kubeovnv1.Subnet) error {
+	if subnet.Spec.Gateway != "" && len(strings.Split(subnet.Spec.Gateway, ",")) > 1 && CheckProtocol(subnet.Spec.Gateway) != kubeovnv1.ProtocolDual {
+		return fmt.Errorf("gateway %s must be an IPv4 address and an IPv6 address", subnet.Spec.Gateway)
+	}
+
+	switch subnet.Spec.GatewayMode {
+	case "":
+	case kubeovnv1.GatewayModeL2:
+		if subnet.Spec.Vlan == "" {
+			return fmt.Errorf("gatewayMode L2 requires an underlay subnet with vlan, overlay subnets always use the ovn gateway")
+		}
+		if subnet.Spec.LogicalGateway {
+			return fmt.Errorf("conflict configuration: gatewayMode L2 and logicalGateway")
+		}
+		if subnet.Spec.NatOutgoing {
+			return fmt.Errorf("natOutgoing requires gatewayMode L3, the traffic of L2 subnets is forwarded by the external gateway")
+		}
+		if subnet.Spec.GatewayType == kubeovnv1.GWCentralizedType {
+			return fmt.Errorf("centralized gatewayType requires gatewayMode L3, the traffic of L2 subnets is forwarded by the external gateway")
+		}
+		if subnet.Spec.ExternalEgressGateway != "" {
+			return fmt.Errorf("externalEgressGateway requires gatewayMode L3, the traffic of L2 subnets is forwarded by the external gateway")
+		}
+		if subnet.Spec.Gateway != "" && CheckProtocol(subnet.Spec.CIDRBlock) == kubeovnv1.ProtocolDual && CheckProtocol(subnet.Spec.Gateway) != kubeovnv1.ProtocolDual {
+			return fmt.Errorf("dual stack subnet with gatewayMode L2 requires the IPv4 and IPv6 addresses of the external gateway, got %s", subnet.Spec.Gateway)
+		}
+	case kubeovnv1.GatewayModeL3:
+		if subnet.Spec.GatewayMac != "" {
+			return fmt.Errorf("gatewayMac requires gatewayMode L2, the gateway of L3 subnets is the ovn logical router")
+		}
+		if subnet.Spec.U2oRouting {
+			return fmt.Errorf("u2oRouting requires gatewayMode L2")
+		}
+	default:
+		return fmt.Errorf("%s is not a valid gatewayMode, must be L2 or L3", subnet.Spec.GatewayMode)
+	}
+	return nil
+}
+
+func validateExtraCIDRBlocks(subnet kubeovnv1.Subnet) error {
+	if CheckProtocol(subnet.Spec.CIDRBlock) == kubeovnv1.ProtocolIPv6 {
+		return fmt.Errorf("extraCIDRBlocks requires an IPv4 cidrBlock")
+	}
+	v4CIDR, _ := SplitStringIP(subnet.Spec.CIDRBlock)
+	cidrs := []string{v4CIDR}
+	for _, cidr := range subnet.Spec.ExtraCIDRBlocks {
+		if _, _, err := net.ParseCIDR(cidr); err != nil || CheckProtocol(cidr) != kubeovnv1.ProtocolIPv4 {
+			return fmt.Errorf("%s in extraCIDRBlocks is not a valid IPv4 cidr", cidr)
+		}
+		if err := CIDRGlobalUnicast(cidr); err != nil {
+			return err
+		}
+		for _, c := range cidrs {
+			if CIDROverlap(c, cidr) {
+				return fmt.Errorf("%s in extraCIDRBlocks is conflict with cidr %s", cidr, c)
+			}
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return nil
+}
+
+// ValidateInterfaceName checks whether the name can be used as a linux network interface name
+func ValidateInterfaceName(name string) error {
+	if name == "" {
+		return fmt.Errorf("interface name is empty")
+	}
+	if len(name) > 15 {
+		return fmt.Errorf("interface name %s is longer than 15 characters", name)
+	}
+	if name == "." || name == ".." {
+		return fmt.Errorf("interface name %s is not allowed", name)
+	}
+	if strings.ContainsAny(name, "/:% \t\n") {
+		return fmt.Errorf("interface name %s contains invalid characters", name)
+	}
+	return nil
+}
+
+// ValidatePreferredIPFamily checks that the preferred ip family is supported by the protocol of the subnet
+func ValidatePreferredIPFamily(family, protocol string) error {
+	if family != kubeovnv1.ProtocolIPv4 && family != kubeovnv1.ProtocolIPv6 {
+		return fmt.Errorf("%s is not a valid preferred ip family, must be IPv4 or IPv6", family)
+	}
+	if protocol != kubeovnv1.ProtocolDual && protocol != family {
+		return fmt.Errorf("preferred ip family %s is not supported by the %s subnet", family, protocol)
+	}
 	return nil
 }
 
+// ValidateGatewayCheckMode checks the gateway check mode, the tcp mode requires the gateway check port of the subnet
+func ValidateGatewayCheckMode(mode string, gatewayCheckPort int) error {
+	switch mode {
+	case kubeovnv1.GatewayCheckModeAuto, kubeovnv1.GatewayCheckModePing, kubeovnv1.GatewayCheckModeArping, kubeovnv1.GatewayCheckModeDisabled:
+		return nil
+	case kubeovnv1.GatewayCheckModeTCP:
+		if gatewayCheckPort == 0 {
+			return fmt.Errorf("gatewayCheckMode tcp requires gatewayCheckPort of the subnet")
+		}
+		return nil
+	}
+	return fmt.Errorf("%s is not a valid gatewayCheckMode, must be auto, ping, arping, tcp or disabled", mode)
+}
+
+// ParseBandwidth parses the rate of the bandwidth annotations into Mbit/s. A bare integer is in Mbit/s, and the units
+// Mbps and Gbps are accepted case-insensitively, e.g. 500Mbps or 1.5Gbps. An empty rate is 0 which means unlimited.
+// The rate must be a whole number of Mbit/s not exceeding maxRate, maxRate 0 means no limit
+func ParseBandwidth(rate string, maxRate int) (int, error) {
+	rate = strings.TrimSpace(rate)
+	if rate == "" {
+		return 0, nil
+	}
+
+	var mbps int
+	lower := strings.ToLower(rate)
+	if n, err := strconv.Atoi(rate); err == nil {
+		mbps = n
+	} else {
+		value, multiplier := lower, 1.0
+		switch {
+		case strings.HasSuffix(lower, "gbps"):
+			value, multiplier = strings.TrimSuffix(lower, "gbps"), 1000
+		case strings.HasSuffix(lower, "mbps"):
+			value = strings.TrimSuffix(lower, "mbps")
+		default:
+			return 0, fmt.Errorf("the rate must be an integer in Mbit/s or a number with unit Mbps or Gbps")
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, fmt.Errorf("the rate must be an integer in Mbit/s or a number with unit Mbps or Gbps")
+		}
+		f *= multiplier
+		if f != math.Trunc(f) {
+			return 0, fmt.Errorf("the rate must be a whole number of Mbit/s")
+		}
+		if f > math.MaxInt32 {
+			return 0, fmt.Errorf("the rate exceeds %d Mbit/s", math.MaxInt32)
+		}
+		mbps = int(f)
+	}
+
+	if mbps < 0 {
+		return 0, fmt.Errorf("the rate must not be negative")
+	}
+	if mbps > math.MaxInt32 {
+		return 0, fmt.Errorf("the rate exceeds %d Mbit/s", math.MaxInt32)
+	}
+	if maxRate > 0 && mbps > maxRate {
+		return 0, fmt.Errorf("the rate %d Mbit/s exceeds the max %d Mbit/s", mbps, maxRate)
+	}
+	return mbps, nil
+}
+
+// ValidatePodBandwidth checks the rates of the bandwidth annotations of all providers do not exceed maxRate in Mbit/s
+func ValidatePodBandwidth(annotations map[string]string, maxRate int) error {
+	keys := make([]string, 0, 2)
+	for key := range annotations {
+		if strings.HasSuffix(key, ".kubernetes.io/ingress_rate") || strings.HasSuffix(key, ".kubernetes.io/egress_rate") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	errors := []error{}
+	for _, key := range keys {
+		if _, err := ParseBandwidth(annotations[key], maxRate); err != nil {
+			errors = append(errors, fmt.Errorf("%s is not a valid %s: %v", annotations[key], key, err))
+		}
+	}
+	return utilerrors.NewAggregate(errors)
+}
+
 func ValidatePodNetwork(annotations map[string]string) error {
 	errors := []error{}
 
@@ -146,19 +586,27 @@ func ValidatePodNetwork(annotations map[string]string) error {
 	}
 
 	ingress := annotations[IngressRateAnnotation]
-	if ingress != "" {
-		if _, err := strconv.Atoi(ingress); err != nil {
-			errors = append(errors, fmt.Errorf("%s is not a valid %s", ingress, IngressRateAnnotation))
-		}
+	if _, err := ParseBandwidth(ingress, 0); err != nil {
+		errors = append(errors, fmt.Errorf("%s is not a valid %s: %v", ingress, IngressRateAnnotation, err))
 	}
 
 	egress := annotations[EgressRateAnnotation]
-	if egress != "" {
-		if _, err := strconv.Atoi(egress); err != nil {
-			errors = append(errors, fmt.Errorf("%s is not a valid %s", egress, EgressRateAnnotation))
+	if _, err := ParseBandwidth(egress, 0); err != nil {
+		errors = append(errors, fmt.Errorf("%s is not a valid %s: %v", egress, EgressRateAnnotation, err))
+	}
+
+	minRate := annotations[MinRateAnnotation]
+	if minRate != "" {
+		if rate, err := strconv.Atoi(minRate); err != nil || rate < 0 {
+			errors = append(errors, fmt.Errorf("%s is not a valid %s", minRate, MinRateAnnotation))
 		}
 	}
 
+	egressRateMode := annotations[EgressRateModeAnnotation]
+	if egressRateMode != "" && egressRateMode != EgressRateModePolicing && egressRateMode != EgressRateModeShaping {
+		errors = append(errors, fmt.Errorf("%s is not a valid %s", egressRateMode, EgressRateModeAnnotation))
+	}
+
 	return utilerrors.NewAggregate(errors)
 }
 
@@ -200,3 +648,206 @@ func ValidateCidrConflict(subnet kubeovnv1.Subnet, subnetList []kubeovnv1.Subnet
 	}
 	return nil
 }
+
+// ValidateProviderNetworkBridge checks that the external bridge of the provider network
+// is a valid interface name and is not used by br-int or another provider network
+func ValidateProviderNetworkBridge(pn *kubeovnv1.ProviderNetwork, pnList []*kubeovnv1.ProviderNetwork) error {
+	brName := ProviderNetworkBridgeName(pn)
+	if err := ValidateInterfaceName(brName); err != nil {
+		return err
+	}
+	if brName == "br-int" {
+		return fmt.Errorf("bridge name %s of provider network %s is reserved", brName, pn.Name)
+	}
+	for _, p := range pnList {
+		if p.Name == pn.Name {
+			continue
+		}
+		if ProviderNetworkBridgeName(p) == brName {
+			return fmt.Errorf("bridge name %s of provider network %s is conflict with provider network %s", brName, pn.Name, p.Name)
+		}
+	}
+	return nil
+}
+
+// ValidateVlan checks that the vlan id is in the allowed ranges of the provider network
+// and is not used by another vlan of the provider network created earlier.
+// Vlan 0 is untagged, it's not restricted by the allowed ranges but still unique.
+func ValidateVlan(vlan *kubeovnv1.Vlan, pn *kubeovnv1.ProviderNetwork, vlanList []*kubeovnv1.Vlan) error {
+	id := vlan.Spec.ID
+	if id < VlanIDUntagged || id > VlanIDMax {
+		return fmt.Errorf("vlan id %d of vlan %s is out of range, it must be from %d to %d", id, vlan.Name, VlanIDUntagged, VlanIDMax)
+	}
+
+	if id != VlanIDUntagged && len(pn.Spec.VlanRanges) != 0 {
+		var allowed bool
+		for _, r := range pn.Spec.VlanRanges {
+			start, end, err := ParseVlanRange(r)
+			if err != nil {
+				return fmt.Errorf("provider network %s: %v", pn.Name, err)
+			}
+			if id >= start && id <= end {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("vlan id %d of vlan %s is not in the allowed ranges %s of provider network %s", id, vlan.Name, strings.Join(pn.Spec.VlanRanges, ","), pn.Name)
+		}
+	}
+
+	for _, v := range vlanList {
+		if v.Name == vlan.Name || v.Spec.Provider != vlan.Spec.Provider || v.Spec.ID != id {
+			continue
+		}
+		// the vlan created earlier keeps the id
+		if v.CreationTimestamp.Before(&vlan.CreationTimestamp) ||
+			(v.CreationTimestamp.Equal(&vlan.CreationTimestamp) && v.Name < vlan.Name) {
+			return fmt.Errorf("vlan id %d of vlan %s is conflict with vlan %s on provider network %s", id, vlan.Name, v.Name, pn.Name)
+		}
+	}
+	return nil
+}
+
+// ValidateVpcStaticRoutes rejects static routes of the vpc which obviously loop
+// or whose next hop is not reachable through any subnet of the vpc
+func ValidateVpcStaticRoutes(vpc *kubeovnv1.Vpc, subnets []kubeovnv1.Subnet) error {
+	var networks []*net.IPNet
+	for i := range subnets {
+		subnet := &subnets[i]
+		vpcName := subnet.Spec.Vpc
+		if vpcName == "" {
+			vpcName = DefaultVpc
+		}
+		if vpcName != vpc.Name && !(vpc.Spec.EnableExternal && subnet.Name == VpcExternalNet) {
+			continue
+		}
+		for _, cidr := range strings.Split(SubnetCIDRs(subnet), ",") {
+			if _, network, err := net.ParseCIDR(cidr); err == nil {
+				networks = append(networks, network)
+			}
+		}
+	}
+	for _, peering := range vpc.Spec.VpcPeerings {
+		if _, network, err := net.ParseCIDR(peering.LocalConnectIP); err == nil {
+			networks = append(networks, network)
+		}
+	}
+
+	for _, route := range vpc.Spec.StaticRoutes {
+		if route == nil {
+			continue
+		}
+		nextHop := net.ParseIP(route.NextHopIP)
+		if nextHop == nil {
+			return fmt.Errorf("next hop %s of static route %s is not a valid ip", route.NextHopIP, route.CIDR)
+		}
+		cidr := route.CIDR
+		if !strings.Contains(cidr, "/") {
+			if CheckProtocol(cidr) == kubeovnv1.ProtocolIPv4 {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, dst, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("%s of static route is not a valid cidr", route.CIDR)
+		}
+		if CheckProtocol(cidr) != CheckProtocol(route.NextHopIP) {
+			return fmt.Errorf("next hop %s of static route %s is not the same protocol", route.NextHopIP, route.CIDR)
+		}
+
+		var connected *net.IPNet
+		for _, network := range networks {
+			if network.Contains(nextHop) && (connected == nil || maskSize(network) > maskSize(connected)) {
+				connected = network
+			}
+		}
+		if connected == nil {
+			return fmt.Errorf("next hop %s of static route %s is not in any subnet of vpc %s", route.NextHopIP, route.CIDR, vpc.Name)
+		}
+		// the next hop is resolved through the route itself if the route is at least as specific as the subnet
+		if route.Policy != kubeovnv1.PolicySrc && dst.Contains(nextHop) && maskSize(dst) >= maskSize(connected) {
+			return fmt.Errorf("next hop %s of static route %s is within the destination, which makes a routing loop", route.NextHopIP, route.CIDR)
+		}
+	}
+	return nil
+}
+
+// ParseNodeNicRoutes parses the additional routes of the node overlay interface from the annotation,
+// the destination without mask is a host route and the gateway is optional
+func ParseNodeNicRoutes(annotation string) ([]request.Route, error) {
+	if annotation == "" {
+		return nil, nil
+	}
+	var routes []request.Route
+	if err := json.Unmarshal([]byte(annotation), &routes); err != nil {
+		return nil, fmt.Errorf("invalid routes %q: %v", annotation, err)
+	}
+	for i, route := range routes {
+		dst := route.Destination
+		if !strings.Contains(dst, "/") {
+			if net.ParseIP(dst) == nil {
+				return nil, fmt.Errorf("destination %s of route is not a valid ip or cidr", route.Destination)
+			}
+			if CheckProtocol(dst) == kubeovnv1.ProtocolIPv4 {
+				dst += "/32"
+			} else {
+				dst += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(dst)
+		if err != nil {
+			return nil, fmt.Errorf("destination %s of route is not a valid ip or cidr", route.Destination)
+		}
+		routes[i].Destination = ipNet.String()
+		if route.Gateway == "" {
+			continue
+		}
+		if net.ParseIP(route.Gateway) == nil {
+			return nil, fmt.Errorf("gateway %s of route %s is not a valid ip", route.Gateway, route.Destination)
+		}
+		if CheckProtocol(route.Gateway) != CheckProtocol(ipNet.IP.String()) {
+			return nil, fmt.Errorf("gateway %s of route %s is not the same protocol", route.Gateway, route.Destination)
+		}
+	}
+	return routes, nil
+}
+
+// ParseGatewayRoutes parses the comma separated cidrs routed through the gateways of the nic instead of the default routes,
+// each cidr is routed through the gateway of the same protocol
+func ParseGatewayRoutes(annotation, gateway string) ([]request.Route, error) {
+	if annotation == "" {
+		return nil, nil
+	}
+	gateways := make(map[string]string, 2)
+	for _, gw := range strings.Split(gateway, ",") {
+		if gw != "" {
+			gateways[CheckProtocol(gw)] = gw
+		}
+	}
+
+	var routes []request.Route
+	for _, cidr := range strings.Split(annotation, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid cidr", cidr)
+		}
+		protocol := CheckProtocol(ipNet.IP.String())
+		gw, ok := gateways[protocol]
+		if !ok {
+			return nil, fmt.Errorf("no %s gateway for route %s", protocol, cidr)
+		}
+		routes = append(routes, request.Route{Destination: ipNet.String(), Gateway: gw})
+	}
+	return routes, nil
+}
+
+func maskSize(network *net.IPNet) int {
+	ones, _ := network.Mask.Size()
+	return ones
+}
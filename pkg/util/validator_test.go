@@ -2,9 +2,12 @@ package util
 
 import (
 	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/request"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestValidateSubnet(t *testing.T) {
@@ -478,6 +481,880 @@ func TestValidateSubnet(t *testing.T) {
 			},
 			err: "ip 10.16.1 in exclude_ips is not a valid address",
 		},
+		{
+			name: "DefaultIngressRateErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-defaultrateerr",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Default:            true,
+					Vpc:                "ovn-cluster",
+					Protocol:           "IPv4",
+					Namespaces:         nil,
+					CIDRBlock:          "10.16.0.0/16",
+					Gateway:            "10.16.0.1",
+					ExcludeIps:         []string{"10.16.0.1"},
+					Provider:           "ovn",
+					GatewayType:        "distributed",
+					DefaultIngressRate: "10M",
+				},
+				Status: kubeovnv1.SubnetStatus{},
+			},
+			err: "10M is not a valid defaultIngressRate",
+		},
+		{
+			name: "AggregateEgressRateErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-aggregaterate",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Default:             true,
+					Vpc:                 "ovn-cluster",
+					Protocol:            "IPv4",
+					CIDRBlock:           "10.16.0.0/16",
+					Gateway:             "10.16.0.1",
+					ExcludeIps:          []string{"10.16.0.1"},
+					Provider:            "ovn",
+					GatewayType:         "distributed",
+					AggregateEgressRate: "-1",
+				},
+			},
+			err: "-1 is not a valid aggregateEgressRate",
+		},
+		{
+			name: "GwUnavailPolicyErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwpolicy",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Default:                  true,
+					Vpc:                      "ovn-cluster",
+					Protocol:                 "IPv4",
+					CIDRBlock:                "10.16.0.0/16",
+					Gateway:                  "10.16.0.1",
+					ExcludeIps:               []string{"10.16.0.1"},
+					Provider:                 "ovn",
+					GatewayType:              "centralized",
+					GatewayNode:              "node1",
+					GatewayUnavailablePolicy: "drop",
+				},
+			},
+			err: "drop is not a valid gatewayUnavailablePolicy, must be allow or fail",
+		},
+		{
+			name: "QosTypeErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-qostype",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Default:     true,
+					Vpc:         "ovn-cluster",
+					Protocol:    "IPv4",
+					CIDRBlock:   "10.16.0.0/16",
+					Gateway:     "10.16.0.1",
+					ExcludeIps:  []string{"10.16.0.1"},
+					Provider:    "ovn",
+					GatewayType: "distributed",
+					QosType:     "linux-netem",
+				},
+			},
+			err: "linux-netem is not a valid qosType, must be linux-htb or linux-hfsc",
+		},
+		{
+			name: "GatewayCheckPortErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwcheckport",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Default:          true,
+					Vpc:              "ovn-cluster",
+					Protocol:         "IPv4",
+					CIDRBlock:        "10.16.0.0/16",
+					Gateway:          "10.16.0.1",
+					ExcludeIps:       []string{"10.16.0.1"},
+					Provider:         "ovn",
+					GatewayType:      "distributed",
+					GatewayCheckPort: 65536,
+				},
+			},
+			err: "65536 is not a valid gatewayCheckPort",
+		},
+		{
+			name: "GatewayCheckModeErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwcheckmode",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:              "ovn-cluster",
+					Protocol:         "IPv4",
+					CIDRBlock:        "10.16.0.0/16",
+					Gateway:          "10.16.0.1",
+					ExcludeIps:       []string{"10.16.0.1"},
+					Provider:         "ovn",
+					GatewayType:      "distributed",
+					GatewayCheckMode: "icmp",
+				},
+			},
+			err: "icmp is not a valid gatewayCheckMode, must be auto, ping, arping, tcp or disabled",
+		},
+		{
+			name: "GatewayCheckModeTCPErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwcheckmode",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:              "ovn-cluster",
+					Protocol:         "IPv4",
+					CIDRBlock:        "10.16.0.0/16",
+					Gateway:          "10.16.0.1",
+					ExcludeIps:       []string{"10.16.0.1"},
+					Provider:         "ovn",
+					GatewayType:      "distributed",
+					GatewayCheckMode: "tcp",
+				},
+			},
+			err: "gatewayCheckMode tcp requires gatewayCheckPort of the subnet",
+		},
+		{
+			name: "PreferredIPFamilyErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-preferredipfamily",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:               "ovn-cluster",
+					Protocol:          "IPv4",
+					CIDRBlock:         "10.16.0.0/16",
+					Gateway:           "10.16.0.1",
+					ExcludeIps:        []string{"10.16.0.1"},
+					Provider:          "ovn",
+					GatewayType:       "distributed",
+					PreferredIPFamily: "IPv8",
+				},
+			},
+			err: "IPv8 is not a valid preferred ip family, must be IPv4 or IPv6",
+		},
+		{
+			name: "PreferredIPFamilyUnsupportedErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-preferredipfamily",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:               "ovn-cluster",
+					Protocol:          "IPv4",
+					CIDRBlock:         "10.16.0.0/16",
+					Gateway:           "10.16.0.1",
+					ExcludeIps:        []string{"10.16.0.1"},
+					Provider:          "ovn",
+					GatewayType:       "distributed",
+					PreferredIPFamily: "IPv6",
+				},
+			},
+			err: "preferred ip family IPv6 is not supported by the IPv4 subnet",
+		},
+		{
+			name: "BroadcastRateLimitErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-broadcastratelimit",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:                "ovn-cluster",
+					Protocol:           "IPv4",
+					CIDRBlock:          "10.16.0.0/16",
+					Gateway:            "10.16.0.1",
+					ExcludeIps:         []string{"10.16.0.1"},
+					Provider:           "ovn",
+					GatewayType:        "distributed",
+					BroadcastRateLimit: -1,
+				},
+			},
+			err: "-1 is not a valid broadcastRateLimit",
+		},
+		{
+			name: "ArpResponderErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-arpresponder",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:          "ovn-cluster",
+					Protocol:     "IPv4",
+					CIDRBlock:    "10.16.0.0/16",
+					Gateway:      "10.16.0.1",
+					ExcludeIps:   []string{"10.16.0.1"},
+					Provider:     "ovn",
+					GatewayType:  "distributed",
+					ArpResponder: "proxy",
+				},
+			},
+			err: "proxy is not a valid arpResponder, must be distributed, flood or router",
+		},
+		{
+			name: "ArpResponderRouterUnderlayErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-arpresponder",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:          "ovn-cluster",
+					Protocol:     "IPv4",
+					CIDRBlock:    "10.16.0.0/16",
+					Gateway:      "10.16.0.1",
+					ExcludeIps:   []string{"10.16.0.1"},
+					Provider:     "ovn",
+					GatewayType:  "distributed",
+					Vlan:         "vlan1",
+					ArpResponder: "router",
+				},
+			},
+			err: "arpResponder router requires a logical router port, which underlay subnets without logicalGateway do not have",
+		},
+		{
+			name: "ExtraCIDRGateway",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-extracidr",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:             "ovn-cluster",
+					Protocol:        "IPv4",
+					CIDRBlock:       "10.16.0.0/24",
+					ExtraCIDRBlocks: []string{"10.18.0.0/24"},
+					Gateway:         "10.16.0.1",
+					ExcludeIps:      []string{"10.16.0.1", "10.18.0.1"},
+					Provider:        "ovn",
+					GatewayType:     "distributed",
+				},
+			},
+			err: "",
+		},
+		{
+			name: "ExtraCIDRGatewayErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-extracidr",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:             "ovn-cluster",
+					Protocol:        "IPv4",
+					CIDRBlock:       "10.16.0.0/24",
+					ExtraCIDRBlocks: []string{"10.18.0.0/24"},
+					Gateway:         "10.18.0.1",
+					ExcludeIps:      []string{"10.18.0.1"},
+					Provider:        "ovn",
+					GatewayType:     "distributed",
+				},
+			},
+			err: " gateway 10.18.0.1 is not in cidr 10.16.0.0/24",
+		},
+		{
+			name: "ExtraCIDROverlapErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-extracidr",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:             "ovn-cluster",
+					Protocol:        "IPv4",
+					CIDRBlock:       "10.16.0.0/24",
+					ExtraCIDRBlocks: []string{"10.16.0.128/25"},
+					Gateway:         "10.16.0.1",
+					ExcludeIps:      []string{"10.16.0.1"},
+					Provider:        "ovn",
+					GatewayType:     "distributed",
+				},
+			},
+			err: "10.16.0.128/25 in extraCIDRBlocks is conflict with cidr 10.16.0.0/24",
+		},
+		{
+			name: "GatewayMac",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwmac",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:        "ovn-cluster",
+					Protocol:   "Dual",
+					CIDRBlock:  "172.18.0.0/24,fc00:f853:ccd:e793::/64",
+					Gateway:    "172.18.0.1,fc00:f853:ccd:e793::1",
+					Provider:   "ovn",
+					Vlan:       "vlan1",
+					GatewayMac: "00:00:5e:00:01:01",
+				},
+			},
+			err: "",
+		},
+		{
+			name: "GatewayMacErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwmac",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:        "ovn-cluster",
+					Protocol:   "Dual",
+					CIDRBlock:  "172.18.0.0/24,fc00:f853:ccd:e793::/64",
+					Gateway:    "172.18.0.1,fc00:f853:ccd:e793::1",
+					Provider:   "ovn",
+					Vlan:       "vlan1",
+					GatewayMac: "00:00:5e:00:01",
+				},
+			},
+			err: "gatewayMac 00:00:5e:00:01 is not a valid mac address",
+		},
+		{
+			name: "GatewayMacOverlayErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwmac",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:        "ovn-cluster",
+					Protocol:   "Dual",
+					CIDRBlock:  "172.18.0.0/24,fc00:f853:ccd:e793::/64",
+					Gateway:    "172.18.0.1,fc00:f853:ccd:e793::1",
+					Provider:   "ovn",
+					GatewayMac: "00:00:5e:00:01:01",
+				},
+			},
+			err: "gatewayMac is only supported by underlay subnets with physical gateway",
+		},
+		{
+			name: "GatewayModeL2Underlay",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwmode",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:         "ovn-cluster",
+					Protocol:    "Dual",
+					CIDRBlock:   "172.18.0.0/24,fc00:f853:ccd:e793::/64",
+					Gateway:     "172.18.0.254,fc00:f853:ccd:e793::fe",
+					Provider:    "ovn",
+					Vlan:        "vlan1",
+					GatewayMode: "L2",
+				},
+			},
+			err: "",
+		},
+		{
+			name: "GatewayModeErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwmode",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:         "ovn-cluster",
+					Protocol:    "IPv4",
+					CIDRBlock:   "10.16.0.0/16",
+					Gateway:     "10.16.0.1",
+					Provider:    "ovn",
+					GatewayMode: "L4",
+				},
+			},
+			err: "L4 is not a valid gatewayMode, must be L2 or L3",
+		},
+		{
+			name: "GatewayModeL2OverlayErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwmode",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:         "ovn-cluster",
+					Protocol:    "IPv4",
+					CIDRBlock:   "10.16.0.0/16",
+					Gateway:     "10.16.0.1",
+					Provider:    "ovn",
+					GatewayMode: "L2",
+				},
+			},
+			err: "gatewayMode L2 requires an underlay subnet with vlan",
+		},
+		{
+			name: "GatewayModeL2LogicalGatewayErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwmode",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:            "ovn-cluster",
+					Protocol:       "IPv4",
+					CIDRBlock:      "10.16.0.0/16",
+					Gateway:        "10.16.0.1",
+					Provider:       "ovn",
+					Vlan:           "vlan1",
+					LogicalGateway: true,
+					GatewayMode:    "L2",
+				},
+			},
+			err: "conflict configuration: gatewayMode L2 and logicalGateway",
+		},
+		{
+			name: "GatewayModeL2NatOutgoingErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwmode",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:         "ovn-cluster",
+					Protocol:    "IPv4",
+					CIDRBlock:   "10.16.0.0/16",
+					Gateway:     "10.16.0.1",
+					Provider:    "ovn",
+					Vlan:        "vlan1",
+					NatOutgoing: true,
+					GatewayMode: "L2",
+				},
+			},
+			err: "natOutgoing requires gatewayMode L3",
+		},
+		{
+			name: "GatewayModeL2DualStackErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwmode",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:         "ovn-cluster",
+					Protocol:    "Dual",
+					CIDRBlock:   "172.18.0.0/24,fc00:f853:ccd:e793::/64",
+					Gateway:     "172.18.0.254",
+					Provider:    "ovn",
+					Vlan:        "vlan1",
+					GatewayMode: "L2",
+				},
+			},
+			err: "dual stack subnet with gatewayMode L2 requires the IPv4 and IPv6 addresses of the external gateway",
+		},
+		{
+			name: "GatewayModeL3GatewayMacErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwmode",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:         "ovn-cluster",
+					Protocol:    "IPv4",
+					CIDRBlock:   "10.16.0.0/16",
+					Gateway:     "10.16.0.1",
+					Provider:    "ovn",
+					Vlan:        "vlan1",
+					GatewayMac:  "00:00:5e:00:01:01",
+					GatewayMode: "L3",
+				},
+			},
+			err: "gatewayMac requires gatewayMode L2",
+		},
+		{
+			name: "DualStackGatewayErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-gwmode",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:       "ovn-cluster",
+					Protocol:  "Dual",
+					CIDRBlock: "172.18.0.0/24,fc00:f853:ccd:e793::/64",
+					Gateway:   "172.18.0.1,172.18.0.2",
+					Provider:  "ovn",
+				},
+			},
+			err: "gateway 172.18.0.1,172.18.0.2 must be an IPv4 address and an IPv6 address",
+		},
+		{
+			name: "LogicalSwitchOptions",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-lsopts",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:                  "ovn-cluster",
+					Protocol:             "IPv4",
+					CIDRBlock:            "10.16.0.0/16",
+					Gateway:              "10.16.0.1",
+					Provider:             "ovn",
+					LogicalSwitchOptions: map[string]string{"mcast_snoop": "true", "mcast_querier": "false", "mcast_ip4_src": "10.16.0.1"},
+				},
+			},
+			err: "",
+		},
+		{
+			name: "LogicalSwitchOptionsKeyErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-lsopts",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:                  "ovn-cluster",
+					Protocol:             "IPv4",
+					CIDRBlock:            "10.16.0.0/16",
+					Gateway:              "10.16.0.1",
+					Provider:             "ovn",
+					LogicalSwitchOptions: map[string]string{"exclude_ips": "10.16.0.2"},
+				},
+			},
+			err: "logical switch option exclude_ips is not supported",
+		},
+		{
+			name: "LogicalSwitchOptionsValueErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-lsopts",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:                  "ovn-cluster",
+					Protocol:             "IPv4",
+					CIDRBlock:            "10.16.0.0/16",
+					Gateway:              "10.16.0.1",
+					Provider:             "ovn",
+					LogicalSwitchOptions: map[string]string{"mcast_ip4_src": "fd00::1"},
+				},
+			},
+			err: "invalid value \"fd00::1\" of logical switch option mcast_ip4_src: must be an IPv4 address",
+		},
+		{
+			name: "MulticastQuerier",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-mcast",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:                    "ovn-cluster",
+					Protocol:               "Dual",
+					CIDRBlock:              "10.16.0.0/16,fd00:10:16::/64",
+					Gateway:                "10.16.0.1,fd00:10:16::1",
+					Provider:               "ovn",
+					EnableMulticastSnoop:   true,
+					EnableMulticastQuerier: true,
+					MulticastQuerierIP:     "10.16.0.254,fd00:10:16::fe",
+				},
+			},
+			err: "",
+		},
+		{
+			name: "MulticastQuerierSnoopErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-mcast",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:                    "ovn-cluster",
+					Protocol:               "Dual",
+					CIDRBlock:              "10.16.0.0/16,fd00:10:16::/64",
+					Gateway:                "10.16.0.1,fd00:10:16::1",
+					Provider:               "ovn",
+					EnableMulticastQuerier: true,
+				},
+			},
+			err: "enableMulticastQuerier requires enableMulticastSnoop",
+		},
+		{
+			name: "MulticastQuerierIPCIDRErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-mcast",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:                    "ovn-cluster",
+					Protocol:               "Dual",
+					CIDRBlock:              "10.16.0.0/16,fd00:10:16::/64",
+					Gateway:                "10.16.0.1,fd00:10:16::1",
+					Provider:               "ovn",
+					EnableMulticastSnoop:   true,
+					EnableMulticastQuerier: true,
+					MulticastQuerierIP:     "10.17.0.1",
+				},
+			},
+			err: "multicastQuerierIP 10.17.0.1 is not in the cidr of subnet utest-mcast",
+		},
+		{
+			name: "MulticastQuerierIPProtocolErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-mcast",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:                    "ovn-cluster",
+					Protocol:               "Dual",
+					CIDRBlock:              "10.16.0.0/16,fd00:10:16::/64",
+					Gateway:                "10.16.0.1,fd00:10:16::1",
+					Provider:               "ovn",
+					EnableMulticastSnoop:   true,
+					EnableMulticastQuerier: true,
+					MulticastQuerierIP:     "10.16.0.253,10.16.0.254",
+				},
+			},
+			err: "multicastQuerierIP 10.16.0.253,10.16.0.254 has more than one IPv4 address",
+		},
+		{
+			name: "MulticastOptionConflictErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-mcast",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:                  "ovn-cluster",
+					Protocol:             "Dual",
+					CIDRBlock:            "10.16.0.0/16,fd00:10:16::/64",
+					Gateway:              "10.16.0.1,fd00:10:16::1",
+					Provider:             "ovn",
+					EnableMulticastSnoop: true,
+					LogicalSwitchOptions: map[string]string{"mcast_querier": "true"},
+				},
+			},
+			err: "logical switch option mcast_querier conflicts with enableMulticastSnoop",
+		},
+		{
+			name: "ExternalIPAMCorrect",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-external-ipam",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:          "ovn-cluster",
+					Protocol:     "IPv4",
+					CIDRBlock:    "10.16.0.0/16",
+					Gateway:      "10.16.0.1",
+					Provider:     "ovn",
+					ExternalIPAM: "http://ipam.example.svc:8080/v1/ipam",
+				},
+			},
+			err: "",
+		},
+		{
+			name: "ExternalIPAMSchemeErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-external-ipam",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:          "ovn-cluster",
+					Protocol:     "IPv4",
+					CIDRBlock:    "10.16.0.0/16",
+					Gateway:      "10.16.0.1",
+					Provider:     "ovn",
+					ExternalIPAM: "unix:///run/ipam.sock",
+				},
+			},
+			err: "externalIPAM unix:///run/ipam.sock must be an http or https url",
+		},
+		{
+			name: "DHCPLeaseTimeCorrect",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-dhcp-lease-time",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:           "ovn-cluster",
+					Protocol:      "IPv4",
+					CIDRBlock:     "10.16.0.0/16",
+					Gateway:       "10.16.0.1",
+					Provider:      "ovn",
+					EnableDHCP:    true,
+					DHCPLeaseTime: 86400,
+				},
+			},
+			err: "",
+		},
+		{
+			name: "DHCPLeaseTimeErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-dhcp-lease-time",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:           "ovn-cluster",
+					Protocol:      "IPv4",
+					CIDRBlock:     "10.16.0.0/16",
+					Gateway:       "10.16.0.1",
+					Provider:      "ovn",
+					EnableDHCP:    true,
+					DHCPLeaseTime: -1,
+				},
+			},
+			err: "-1 is not a valid dhcpLeaseTime",
+		},
+		{
+			name: "DHCPLeaseTimeConflictErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-dhcp-lease-time",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:           "ovn-cluster",
+					Protocol:      "IPv4",
+					CIDRBlock:     "10.16.0.0/16",
+					Gateway:       "10.16.0.1",
+					Provider:      "ovn",
+					EnableDHCP:    true,
+					DHCPLeaseTime: 86400,
+					DHCPv4Options: "lease_time=3600,router=10.16.0.1,server_id=169.254.0.254,server_mac=00:00:00:2E:2F:B8",
+				},
+			},
+			err: "dhcpLeaseTime conflicts with the lease_time in dhcpV4Options",
+		},
+		{
+			name: "MacOUICorrect",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-mac-oui",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:       "ovn-cluster",
+					Protocol:  "IPv4",
+					CIDRBlock: "10.16.0.0/16",
+					Gateway:   "10.16.0.1",
+					Provider:  "ovn",
+					MacOUI:    "02:1a:2b",
+				},
+			},
+			err: "",
+		},
+		{
+			name: "MacOUIFormatErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-mac-oui",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:       "ovn-cluster",
+					Protocol:  "IPv4",
+					CIDRBlock: "10.16.0.0/16",
+					Gateway:   "10.16.0.1",
+					Provider:  "ovn",
+					MacOUI:    "02:1a",
+				},
+			},
+			err: "invalid macOUI 02:1a: must be 3 bytes in the form of xx:xx:xx",
+		},
+		{
+			name: "MacOUIMulticastErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-mac-oui",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:       "ovn-cluster",
+					Protocol:  "IPv4",
+					CIDRBlock: "10.16.0.0/16",
+					Gateway:   "10.16.0.1",
+					Provider:  "ovn",
+					MacOUI:    "01:00:5e",
+				},
+			},
+			err: "invalid macOUI 01:00:5e: the multicast bit of the first byte must not be set",
+		},
+		{
+			name: "SuppressGatewayArpCorrect",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-suppress-gw-arp",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:                "ovn-cluster",
+					Protocol:           "IPv4",
+					CIDRBlock:          "10.16.0.0/16",
+					Gateway:            "10.16.0.1",
+					Provider:           "ovn",
+					Vlan:               "vlan1",
+					LogicalGateway:     true,
+					SuppressGatewayArp: true,
+				},
+			},
+			err: "",
+		},
+		{
+			name: "SuppressGatewayArpOverlayErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-suppress-gw-arp",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:                "ovn-cluster",
+					Protocol:           "IPv4",
+					CIDRBlock:          "10.16.0.0/16",
+					Gateway:            "10.16.0.1",
+					Provider:           "ovn",
+					SuppressGatewayArp: true,
+				},
+			},
+			err: "suppressGatewayArp is only supported by underlay subnets, which have a physical gateway",
+		},
+		{
+			name: "SuppressGatewayArpRouterErr",
+			asubnet: kubeovnv1.Subnet{
+				TypeMeta: metav1.TypeMeta{Kind: "Subnet", APIVersion: "kubeovn.io/v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "utest-suppress-gw-arp",
+				},
+				Spec: kubeovnv1.SubnetSpec{
+					Vpc:                "ovn-cluster",
+					Protocol:           "IPv4",
+					CIDRBlock:          "10.16.0.0/16",
+					Gateway:            "10.16.0.1",
+					Provider:           "ovn",
+					Vlan:               "vlan1",
+					LogicalGateway:     true,
+					ArpResponder:       "router",
+					SuppressGatewayArp: true,
+				},
+			},
+			err: "suppressGatewayArp conflicts with arpResponder router, which answers for the subnet by the router port",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -612,6 +1489,28 @@ func TestValidatePodNetwork(t *testing.T) {
 			},
 			err: "a1 is not a valid ovn.kubernetes.io/egress_rate",
 		},
+		{
+			name: "MinRatErr",
+			annotations: map[string]string{
+				"ovn.kubernetes.io/ip_address":  "10.16.0.15",
+				"ovn.kubernetes.io/mac_address": "00:00:00:54:17:2A",
+				"ovn.kubernetes.io/egress_rate": "10",
+				"ovn.kubernetes.io/min_rate":    "-5",
+				"ovn.kubernetes.io/cidr":        "10.16.0.0/16",
+			},
+			err: "-5 is not a valid ovn.kubernetes.io/min_rate",
+		},
+		{
+			name: "EgRatModeErr",
+			annotations: map[string]string{
+				"ovn.kubernetes.io/ip_address":       "10.16.0.15",
+				"ovn.kubernetes.io/mac_address":      "00:00:00:54:17:2A",
+				"ovn.kubernetes.io/egress_rate":      "10",
+				"ovn.kubernetes.io/egress_rate_mode": "shape",
+				"ovn.kubernetes.io/cidr":             "10.16.0.0/16",
+			},
+			err: "shape is not a valid ovn.kubernetes.io/egress_rate_mode",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -623,6 +1522,72 @@ func TestValidatePodNetwork(t *testing.T) {
 	}
 }
 
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    string
+		maxRate int
+		want    int
+		err     string
+	}{
+		{"empty", "", 100, 0, ""},
+		{"bare", "10", 100, 10, ""},
+		{"mbps", "10Mbps", 100, 10, ""},
+		{"gbps", "1.5gbps", 0, 1500, ""},
+		{"space", " 2 Gbps ", 10000, 2000, ""},
+		{"exceed", "1Gbps", 100, 0, "the rate 1000 Mbit/s exceeds the max 100 Mbit/s"},
+		{"bareExceed", "101", 100, 0, "the rate 101 Mbit/s exceeds the max 100 Mbit/s"},
+		{"huge", "99999999999999999999Gbps", 0, 0, "the rate exceeds"},
+		{"negative", "-1", 0, 0, "the rate must not be negative"},
+		{"fraction", "1.5Mbps", 0, 0, "the rate must be a whole number of Mbit/s"},
+		{"bareFraction", "1.5", 0, 0, "the rate must be an integer in Mbit/s or a number with unit Mbps or Gbps"},
+		{"unknownUnit", "10M", 0, 0, "the rate must be an integer in Mbit/s or a number with unit Mbps or Gbps"},
+		{"nan", "NaNGbps", 0, 0, "the rate must be an integer in Mbit/s or a number with unit Mbps or Gbps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ret, err := ParseBandwidth(tt.rate, tt.maxRate)
+			if !ErrorContains(err, tt.err) || ret != tt.want {
+				t.Errorf("got %v, %v, want %v, %v", ret, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidatePodBandwidth(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		err         string
+	}{
+		{
+			name: "valid",
+			annotations: map[string]string{
+				"ovn.kubernetes.io/ingress_rate":  "1Gbps",
+				"ovn.kubernetes.io/egress_rate":   "500",
+				"net1.ns1.ovn.kubernetes.io/cidr": "10.17.0.0/16",
+			},
+			err: "",
+		},
+		{
+			name: "attachmentExceed",
+			annotations: map[string]string{
+				"ovn.kubernetes.io/ingress_rate":         "1Gbps",
+				"net1.ns1.ovn.kubernetes.io/egress_rate": "20Gbps",
+			},
+			err: "20Gbps is not a valid net1.ns1.ovn.kubernetes.io/egress_rate: the rate 20000 Mbit/s exceeds the max 10000 Mbit/s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ret := ValidatePodBandwidth(tt.annotations, 10000)
+			if !ErrorContains(ret, tt.err) {
+				t.Errorf("got %v, want a error %v", ret, tt.err)
+			}
+		})
+	}
+}
+
 func TestValidatePodCidr(t *testing.T) {
 	tests := []struct {
 		name string
@@ -818,3 +1783,365 @@ func TestValidateCidrConflict(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateInterfaceName(t *testing.T) {
+	tests := []struct {
+		name   string
+		ifName string
+		err    string
+	}{
+		{
+			name:   "correct",
+			ifName: "net0",
+			err:    "",
+		},
+		{
+			name:   "empty",
+			ifName: "",
+			err:    "interface name is empty",
+		},
+		{
+			name:   "tooLong",
+			ifName: "abcdefghijklmnop",
+			err:    "interface name abcdefghijklmnop is longer than 15 characters",
+		},
+		{
+			name:   "invalidChar",
+			ifName: "net/0",
+			err:    "interface name net/0 contains invalid characters",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ret := ValidateInterfaceName(tt.ifName)
+			if !ErrorContains(ret, tt.err) {
+				t.Errorf("got %v, want a error %v", ret, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateProviderNetworkBridge(t *testing.T) {
+	pnList := []*kubeovnv1.ProviderNetwork{
+		{ObjectMeta: metav1.ObjectMeta{Name: "net1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "net2"}, Spec: kubeovnv1.ProviderNetworkSpec{BridgeName: "br-phy"}},
+	}
+	tests := []struct {
+		name string
+		pn   *kubeovnv1.ProviderNetwork
+		err  string
+	}{
+		{
+			name: "default",
+			pn:   &kubeovnv1.ProviderNetwork{ObjectMeta: metav1.ObjectMeta{Name: "net3"}},
+			err:  "",
+		},
+		{
+			name: "custom",
+			pn:   &kubeovnv1.ProviderNetwork{ObjectMeta: metav1.ObjectMeta{Name: "net3"}, Spec: kubeovnv1.ProviderNetworkSpec{BridgeName: "br-ext"}},
+			err:  "",
+		},
+		{
+			name: "self",
+			pn:   &kubeovnv1.ProviderNetwork{ObjectMeta: metav1.ObjectMeta{Name: "net2"}, Spec: kubeovnv1.ProviderNetworkSpec{BridgeName: "br-phy"}},
+			err:  "",
+		},
+		{
+			name: "brInt",
+			pn:   &kubeovnv1.ProviderNetwork{ObjectMeta: metav1.ObjectMeta{Name: "net3"}, Spec: kubeovnv1.ProviderNetworkSpec{BridgeName: "br-int"}},
+			err:  "bridge name br-int of provider network net3 is reserved",
+		},
+		{
+			name: "conflictDefault",
+			pn:   &kubeovnv1.ProviderNetwork{ObjectMeta: metav1.ObjectMeta{Name: "net3"}, Spec: kubeovnv1.ProviderNetworkSpec{BridgeName: "br-net1"}},
+			err:  "bridge name br-net1 of provider network net3 is conflict with provider network net1",
+		},
+		{
+			name: "conflictCustom",
+			pn:   &kubeovnv1.ProviderNetwork{ObjectMeta: metav1.ObjectMeta{Name: "net3"}, Spec: kubeovnv1.ProviderNetworkSpec{BridgeName: "br-phy"}},
+			err:  "bridge name br-phy of provider network net3 is conflict with provider network net2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ret := ValidateProviderNetworkBridge(tt.pn, pnList)
+			if !ErrorContains(ret, tt.err) {
+				t.Errorf("got %v, want a error %v", ret, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateVlan(t *testing.T) {
+	now := metav1.Now()
+	earlier := metav1.NewTime(now.Add(-time.Minute))
+	pn := &kubeovnv1.ProviderNetwork{ObjectMeta: metav1.ObjectMeta{Name: "net1"}, Spec: kubeovnv1.ProviderNetworkSpec{VlanRanges: []string{"100..199", "300"}}}
+	vlanList := []*kubeovnv1.Vlan{
+		{ObjectMeta: metav1.ObjectMeta{Name: "vlan1", CreationTimestamp: earlier}, Spec: kubeovnv1.VlanSpec{ID: 100, Provider: "net1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "vlan2", CreationTimestamp: earlier}, Spec: kubeovnv1.VlanSpec{ID: 0, Provider: "net1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "vlan3", CreationTimestamp: earlier}, Spec: kubeovnv1.VlanSpec{ID: 101, Provider: "net2"}},
+	}
+	tests := []struct {
+		name string
+		vlan *kubeovnv1.Vlan
+		pn   *kubeovnv1.ProviderNetwork
+		err  string
+	}{
+		{
+			name: "inRange",
+			vlan: &kubeovnv1.Vlan{ObjectMeta: metav1.ObjectMeta{Name: "vlan4", CreationTimestamp: now}, Spec: kubeovnv1.VlanSpec{ID: 101, Provider: "net1"}},
+			pn:   pn,
+			err:  "",
+		},
+		{
+			name: "single",
+			vlan: &kubeovnv1.Vlan{ObjectMeta: metav1.ObjectMeta{Name: "vlan4", CreationTimestamp: now}, Spec: kubeovnv1.VlanSpec{ID: 300, Provider: "net1"}},
+			pn:   pn,
+			err:  "",
+		},
+		{
+			name: "noRanges",
+			vlan: &kubeovnv1.Vlan{ObjectMeta: metav1.ObjectMeta{Name: "vlan4", CreationTimestamp: now}, Spec: kubeovnv1.VlanSpec{ID: 4094, Provider: "net2"}},
+			pn:   &kubeovnv1.ProviderNetwork{ObjectMeta: metav1.ObjectMeta{Name: "net2"}},
+			err:  "",
+		},
+		{
+			name: "reserved",
+			vlan: &kubeovnv1.Vlan{ObjectMeta: metav1.ObjectMeta{Name: "vlan4", CreationTimestamp: now}, Spec: kubeovnv1.VlanSpec{ID: 4095, Provider: "net2"}},
+			pn:   &kubeovnv1.ProviderNetwork{ObjectMeta: metav1.ObjectMeta{Name: "net2"}},
+			err:  "vlan id 4095 of vlan vlan4 is out of range, it must be from 0 to 4094",
+		},
+		{
+			name: "outOfRanges",
+			vlan: &kubeovnv1.Vlan{ObjectMeta: metav1.ObjectMeta{Name: "vlan4", CreationTimestamp: now}, Spec: kubeovnv1.VlanSpec{ID: 200, Provider: "net1"}},
+			pn:   pn,
+			err:  "vlan id 200 of vlan vlan4 is not in the allowed ranges 100..199,300 of provider network net1",
+		},
+		{
+			name: "invalidRanges",
+			vlan: &kubeovnv1.Vlan{ObjectMeta: metav1.ObjectMeta{Name: "vlan4", CreationTimestamp: now}, Spec: kubeovnv1.VlanSpec{ID: 200, Provider: "net2"}},
+			pn:   &kubeovnv1.ProviderNetwork{ObjectMeta: metav1.ObjectMeta{Name: "net2"}, Spec: kubeovnv1.ProviderNetworkSpec{VlanRanges: []string{"300..200"}}},
+			err:  "provider network net2: invalid vlan range \"300..200\"",
+		},
+		{
+			name: "conflict",
+			vlan: &kubeovnv1.Vlan{ObjectMeta: metav1.ObjectMeta{Name: "vlan4", CreationTimestamp: now}, Spec: kubeovnv1.VlanSpec{ID: 100, Provider: "net1"}},
+			pn:   pn,
+			err:  "vlan id 100 of vlan vlan4 is conflict with vlan vlan1 on provider network net1",
+		},
+		{
+			name: "earliest",
+			vlan: vlanList[0],
+			pn:   pn,
+			err:  "",
+		},
+		{
+			name: "untagged",
+			vlan: &kubeovnv1.Vlan{ObjectMeta: metav1.ObjectMeta{Name: "vlan4", CreationTimestamp: now}, Spec: kubeovnv1.VlanSpec{ID: 0, Provider: "net2"}},
+			pn:   &kubeovnv1.ProviderNetwork{ObjectMeta: metav1.ObjectMeta{Name: "net2"}, Spec: kubeovnv1.ProviderNetworkSpec{VlanRanges: []string{"100"}}},
+			err:  "",
+		},
+		{
+			name: "untaggedConflict",
+			vlan: &kubeovnv1.Vlan{ObjectMeta: metav1.ObjectMeta{Name: "vlan4", CreationTimestamp: now}, Spec: kubeovnv1.VlanSpec{ID: 0, Provider: "net1"}},
+			pn:   pn,
+			err:  "vlan id 0 of vlan vlan4 is conflict with vlan vlan2 on provider network net1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ret := ValidateVlan(tt.vlan, tt.pn, vlanList)
+			if !ErrorContains(ret, tt.err) {
+				t.Errorf("got %v, want a error %v", ret, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateVpcStaticRoutes(t *testing.T) {
+	subnets := []kubeovnv1.Subnet{
+		{ObjectMeta: metav1.ObjectMeta{Name: "net1"}, Spec: kubeovnv1.SubnetSpec{Vpc: "vpc1", CIDRBlock: "10.0.1.0/24"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "net2"}, Spec: kubeovnv1.SubnetSpec{Vpc: "vpc2", CIDRBlock: "10.0.2.0/24"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: VpcExternalNet}, Spec: kubeovnv1.SubnetSpec{CIDRBlock: "172.18.0.0/16"}},
+	}
+	tests := []struct {
+		name   string
+		routes []*kubeovnv1.StaticRoute
+		err    string
+	}{
+		{
+			name:   "default",
+			routes: []*kubeovnv1.StaticRoute{{Policy: kubeovnv1.PolicyDst, CIDR: "0.0.0.0/0", NextHopIP: "10.0.1.254"}},
+			err:    "",
+		},
+		{
+			name:   "loop",
+			routes: []*kubeovnv1.StaticRoute{{Policy: kubeovnv1.PolicyDst, CIDR: "10.0.1.0/25", NextHopIP: "10.0.1.10"}},
+			err:    "next hop 10.0.1.10 of static route 10.0.1.0/25 is within the destination, which makes a routing loop",
+		},
+		{
+			name:   "srcPolicy",
+			routes: []*kubeovnv1.StaticRoute{{Policy: kubeovnv1.PolicySrc, CIDR: "10.0.1.0/25", NextHopIP: "10.0.1.200"}},
+			err:    "",
+		},
+		{
+			name:   "unreachable",
+			routes: []*kubeovnv1.StaticRoute{{Policy: kubeovnv1.PolicyDst, CIDR: "192.168.0.0/16", NextHopIP: "10.0.2.1"}},
+			err:    "next hop 10.0.2.1 of static route 192.168.0.0/16 is not in any subnet of vpc vpc1",
+		},
+		{
+			name:   "invalidNextHop",
+			routes: []*kubeovnv1.StaticRoute{{Policy: kubeovnv1.PolicyDst, CIDR: "192.168.0.0/16", NextHopIP: "10.0.1"}},
+			err:    "next hop 10.0.1 of static route 192.168.0.0/16 is not a valid ip",
+		},
+		{
+			name:   "protocol",
+			routes: []*kubeovnv1.StaticRoute{{Policy: kubeovnv1.PolicyDst, CIDR: "fd00::/64", NextHopIP: "10.0.1.1"}},
+			err:    "next hop 10.0.1.1 of static route fd00::/64 is not the same protocol",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vpc := &kubeovnv1.Vpc{ObjectMeta: metav1.ObjectMeta{Name: "vpc1"}, Spec: kubeovnv1.VpcSpec{StaticRoutes: tt.routes}}
+			ret := ValidateVpcStaticRoutes(vpc, subnets)
+			if !ErrorContains(ret, tt.err) {
+				t.Errorf("got %v, want a error %v", ret, tt.err)
+			}
+		})
+	}
+
+	vpc := &kubeovnv1.Vpc{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpc1"},
+		Spec: kubeovnv1.VpcSpec{
+			EnableExternal: true,
+			StaticRoutes:   []*kubeovnv1.StaticRoute{{Policy: kubeovnv1.PolicyDst, CIDR: "0.0.0.0/0", NextHopIP: "172.18.0.1"}},
+		},
+	}
+	if err := ValidateVpcStaticRoutes(vpc, subnets); err != nil {
+		t.Errorf("got %v, want no error for next hop in external subnet", err)
+	}
+}
+
+func TestParseNodeNicRoutes(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		routes     []request.Route
+		err        string
+	}{
+		{
+			name:       "empty",
+			annotation: "",
+			routes:     nil,
+			err:        "",
+		},
+		{
+			name:       "routes",
+			annotation: `[{"dst":"192.168.100.0/24","gw":"100.64.0.1"},{"dst":"192.168.200.10"},{"dst":"fd00:100::/64"}]`,
+			routes: []request.Route{
+				{Destination: "192.168.100.0/24", Gateway: "100.64.0.1"},
+				{Destination: "192.168.200.10/32"},
+				{Destination: "fd00:100::/64"},
+			},
+			err: "",
+		},
+		{
+			name:       "normalize",
+			annotation: `[{"dst":"192.168.100.1/24"}]`,
+			routes:     []request.Route{{Destination: "192.168.100.0/24"}},
+			err:        "",
+		},
+		{
+			name:       "json",
+			annotation: `{"dst":"192.168.100.0/24"}`,
+			err:        "invalid routes",
+		},
+		{
+			name:       "destination",
+			annotation: `[{"dst":"192.168.100.0/33"}]`,
+			err:        "destination 192.168.100.0/33 of route is not a valid ip or cidr",
+		},
+		{
+			name:       "gateway",
+			annotation: `[{"dst":"192.168.100.0/24","gw":"100.64.0"}]`,
+			err:        "gateway 100.64.0 of route 192.168.100.0/24 is not a valid ip",
+		},
+		{
+			name:       "protocol",
+			annotation: `[{"dst":"fd00:100::/64","gw":"100.64.0.1"}]`,
+			err:        "gateway 100.64.0.1 of route fd00:100::/64 is not the same protocol",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := ParseNodeNicRoutes(tt.annotation)
+			if !ErrorContains(err, tt.err) {
+				t.Errorf("got %v, want a error %v", err, tt.err)
+			}
+			if err == nil && !reflect.DeepEqual(routes, tt.routes) {
+				t.Errorf("got %v, want %v", routes, tt.routes)
+			}
+		})
+	}
+}
+
+func TestParseGatewayRoutes(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		gateway    string
+		routes     []request.Route
+		err        string
+	}{
+		{
+			name:       "empty",
+			annotation: "",
+			gateway:    "10.16.0.1",
+			routes:     nil,
+			err:        "",
+		},
+		{
+			name:       "ipv4",
+			annotation: "192.168.100.0/24, 10.0.0.1/8",
+			gateway:    "10.16.0.1",
+			routes: []request.Route{
+				{Destination: "192.168.100.0/24", Gateway: "10.16.0.1"},
+				{Destination: "10.0.0.0/8", Gateway: "10.16.0.1"},
+			},
+			err: "",
+		},
+		{
+			name:       "dual",
+			annotation: "fd00:100::/64,192.168.100.0/24",
+			gateway:    "10.16.0.1,fd00:10:16::1",
+			routes: []request.Route{
+				{Destination: "fd00:100::/64", Gateway: "fd00:10:16::1"},
+				{Destination: "192.168.100.0/24", Gateway: "10.16.0.1"},
+			},
+			err: "",
+		},
+		{
+			name:       "cidr",
+			annotation: "192.168.100.1",
+			gateway:    "10.16.0.1",
+			err:        "192.168.100.1 is not a valid cidr",
+		},
+		{
+			name:       "protocol",
+			annotation: "fd00:100::/64",
+			gateway:    "10.16.0.1",
+			err:        "no IPv6 gateway for route fd00:100::/64",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := ParseGatewayRoutes(tt.annotation, tt.gateway)
+			if !ErrorContains(err, tt.err) {
+				t.Errorf("got %v, want a error %v", err, tt.err)
+			}
+			if err == nil && !reflect.DeepEqual(routes, tt.routes) {
+				t.Errorf("got %v, want %v", routes, tt.routes)
+			}
+		})
+	}
+}
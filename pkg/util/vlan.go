@@ -1,6 +1,51 @@
 package util
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+)
+
+const (
+	// VlanIDUntagged is the vlan id of untagged traffic
+	VlanIDUntagged = 0
+	// VlanIDMax is the max vlan id could be used, 4095 is reserved
+	VlanIDMax = 4094
+)
+
 // ExternalBridgeName returns external bridge name of the provider network
 func ExternalBridgeName(provider string) string {
 	return "br-" + provider
 }
+
+// ProviderNetworkBridgeName returns the external bridge name configured in the provider network spec,
+// or the default one derived from the provider network name
+func ProviderNetworkBridgeName(pn *kubeovnv1.ProviderNetwork) string {
+	if pn.Spec.BridgeName != "" {
+		return pn.Spec.BridgeName
+	}
+	return ExternalBridgeName(pn.Name)
+}
+
+// ParseVlanRange parses a vlan range in the format of "id" or "start..end"
+func ParseVlanRange(vlanRange string) (int, int, error) {
+	parts := strings.Split(vlanRange, "..")
+	if len(parts) > 2 {
+		return 0, 0, fmt.Errorf("invalid vlan range %q", vlanRange)
+	}
+	ids := make([]int, 0, 2)
+	for _, part := range parts {
+		id, err := strconv.Atoi(part)
+		if err != nil || id <= VlanIDUntagged || id > VlanIDMax {
+			return 0, 0, fmt.Errorf("invalid vlan range %q, vlan id must be from 1 to %d", vlanRange, VlanIDMax)
+		}
+		ids = append(ids, id)
+	}
+	start, end := ids[0], ids[len(ids)-1]
+	if start > end {
+		return 0, 0, fmt.Errorf("invalid vlan range %q, start is greater than end", vlanRange)
+	}
+	return start, end, nil
+}
@@ -26,3 +26,31 @@ func TestExternalBridgeName(t *testing.T) {
 		})
 	}
 }
+
+func TestParseVlanRange(t *testing.T) {
+	tests := []struct {
+		arg   string
+		start int
+		end   int
+		err   string
+	}{
+		{arg: "100", start: 100, end: 100},
+		{arg: "100..200", start: 100, end: 200},
+		{arg: "0", err: "vlan id must be from 1 to 4094"},
+		{arg: "100..4095", err: "vlan id must be from 1 to 4094"},
+		{arg: "200..100", err: "start is greater than end"},
+		{arg: "1..2..3", err: "invalid vlan range"},
+		{arg: "abc", err: "invalid vlan range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			start, end, err := ParseVlanRange(tt.arg)
+			if !ErrorContains(err, tt.err) {
+				t.Errorf("got %v, want a error %v", err, tt.err)
+			}
+			if err == nil && (start != tt.start || end != tt.end) {
+				t.Errorf("got %d..%d, want %d..%d", start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
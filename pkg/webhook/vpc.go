@@ -4,13 +4,39 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"reflect"
 
+	"k8s.io/klog/v2"
 	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	ovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
+func (v *ValidatingHook) VpcCreateHook(ctx context.Context, req admission.Request) admission.Response {
+	vpc := ovnv1.Vpc{}
+	if err := v.decoder.Decode(req, &vpc); err != nil {
+		return ctrlwebhook.Errored(http.StatusBadRequest, err)
+	}
+	return v.validateVpcStaticRoutes(ctx, &vpc)
+}
+
+func (v *ValidatingHook) VpcUpdateHook(ctx context.Context, req admission.Request) admission.Response {
+	vpc := ovnv1.Vpc{}
+	if err := v.decoder.Decode(req, &vpc); err != nil {
+		return ctrlwebhook.Errored(http.StatusBadRequest, err)
+	}
+	oldVpc := ovnv1.Vpc{}
+	if err := v.decoder.DecodeRaw(req.OldObject, &oldVpc); err != nil {
+		return ctrlwebhook.Errored(http.StatusBadRequest, err)
+	}
+	if reflect.DeepEqual(vpc.Spec.StaticRoutes, oldVpc.Spec.StaticRoutes) {
+		return ctrlwebhook.Allowed("by pass")
+	}
+	return v.validateVpcStaticRoutes(ctx, &vpc)
+}
+
 func (v *ValidatingHook) VpcDeleteHook(ctx context.Context, req admission.Request) admission.Response {
 	vpc := ovnv1.Vpc{}
 	if err := v.decoder.DecodeRaw(req.OldObject, &vpc); err != nil {
@@ -22,3 +48,21 @@ func (v *ValidatingHook) VpcDeleteHook(ctx context.Context, req admission.Reques
 	}
 	return ctrlwebhook.Allowed("by pass")
 }
+
+func (v *ValidatingHook) validateVpcStaticRoutes(ctx context.Context, vpc *ovnv1.Vpc) admission.Response {
+	// advanced topologies may route through next hops unknown to kube-ovn
+	if vpc.Annotations[util.VpcSkipRouteCheckAnnotation] == "true" || len(vpc.Spec.StaticRoutes) == 0 {
+		return ctrlwebhook.Allowed("by pass")
+	}
+
+	subnetList := &ovnv1.SubnetList{}
+	if err := v.cache.List(ctx, subnetList); err != nil {
+		// fail open so that valid vpcs are not rejected on transient errors of the cache
+		klog.Errorf("failed to list subnets, skip checking static routes of vpc %s: %v", vpc.Name, err)
+		return ctrlwebhook.Allowed("by pass")
+	}
+	if err := util.ValidateVpcStaticRoutes(vpc, subnetList.Items); err != nil {
+		return ctrlwebhook.Denied(fmt.Sprintf("%v, add annotation %s=true to the vpc to skip the check", err, util.VpcSkipRouteCheckAnnotation))
+	}
+	return ctrlwebhook.Allowed("by pass")
+}
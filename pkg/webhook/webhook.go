@@ -44,8 +44,10 @@ func NewValidatingHook(c cache.Cache) (*ValidatingHook, error) {
 	createHooks[daemonSetGVK] = v.DaemonSetCreateHook
 	createHooks[podGVK] = v.PodCreateHook
 	createHooks[subnetGVK] = v.SubnetCreateHook
+	createHooks[vpcGVK] = v.VpcCreateHook
 
 	updateHooks[subnetGVK] = v.SubnetUpdateHook
+	updateHooks[vpcGVK] = v.VpcUpdateHook
 
 	deleteHooks[subnetGVK] = v.SubnetDeleteHook
 
@@ -2,6 +2,7 @@ package ipam
 
 import (
 	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -113,6 +114,61 @@ var _ = Describe("[IPAM]", func() {
 				Expect(ip).To(Equal("10.16.0.1"))
 			})
 
+			It("do not reuse released address before the release delay elapses", func() {
+				im := ipam.NewIPAM()
+				im.ReleaseDelay = time.Hour
+				err := im.AddOrUpdateSubnet(subnetName, "10.16.0.0/30", v4Gw, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				ip, _, _, err := im.GetRandomAddress("pod1.ns", "pod1.ns", "", subnetName, nil, true)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ip).To(Equal("10.16.0.1"))
+				ip, _, _, err = im.GetRandomAddress("pod2.ns", "pod2.ns", "", subnetName, nil, true)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ip).To(Equal("10.16.0.2"))
+
+				im.ReleaseAddressByPod("pod1.ns")
+				im.ReleaseAddressByPod("pod2.ns")
+				v4Quarantined, _ := im.GetSubnetQuarantinedIPs(subnetName)
+				Expect(v4Quarantined).To(Equal(2))
+
+				im.Subnets[subnetName].V4ReleasedAt["10.16.0.2"] = time.Now().Add(-2 * time.Hour)
+				ip, _, _, err = im.GetRandomAddress("pod3.ns", "pod3.ns", "", subnetName, nil, true)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ip).To(Equal("10.16.0.2"))
+				v4Quarantined, _ = im.GetSubnetQuarantinedIPs(subnetName)
+				Expect(v4Quarantined).To(Equal(1))
+
+				// the delay is bypassed when the subnet is exhausted
+				ip, _, _, err = im.GetRandomAddress("pod4.ns", "pod4.ns", "", subnetName, nil, true)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ip).To(Equal("10.16.0.1"))
+				v4Quarantined, _ = im.GetSubnetQuarantinedIPs(subnetName)
+				Expect(v4Quarantined).To(Equal(0))
+			})
+
+			It("release the address of a nic in one subnet", func() {
+				im := ipam.NewIPAM()
+				err := im.AddOrUpdateSubnet(subnetName, "10.16.0.0/30", v4Gw, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				err = im.AddOrUpdateSubnet("v4NewSubnet", "10.17.0.0/30", "10.17.0.1", nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				ip, _, mac, err := im.GetRandomAddress("pod1.ns", "pod1.ns", "", subnetName, nil, true)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ip).To(Equal("10.16.0.1"))
+				ip, _, newMac, err := im.GetRandomAddress("pod1.ns", "pod1.ns", mac, "v4NewSubnet", nil, true)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ip).To(Equal("10.17.0.1"))
+				Expect(newMac).To(Equal(mac))
+
+				im.ReleaseAddressByNic("pod1.ns", "pod1.ns", subnetName)
+				addresses := im.GetPodAddress("pod1.ns")
+				Expect(addresses).To(HaveLen(1))
+				Expect(addresses[0].Ip).To(Equal("10.17.0.1"))
+				Expect(addresses[0].Subnet.Name).To(Equal("v4NewSubnet"))
+			})
+
 			It("do not reuse released address after update subnet's excludedIps", func() {
 				im := ipam.NewIPAM()
 				err := im.AddOrUpdateSubnet(subnetName, "10.16.0.0/30", v4Gw, nil)
@@ -129,6 +185,30 @@ var _ = Describe("[IPAM]", func() {
 				_, _, _, err = im.GetRandomAddress("pod1.ns", "pod1.ns", "", subnetName, nil, true)
 				Expect(err).Should(MatchError(ipam.ErrNoAvailable))
 			})
+
+			It("allocate from extra cidrs", func() {
+				im := ipam.NewIPAM()
+				err := im.AddOrUpdateSubnet(subnetName, "10.16.0.0/30", v4Gw, []string{v4Gw, "10.18.0.1"}, "10.18.0.0/30")
+				Expect(err).ShouldNot(HaveOccurred())
+
+				ip, _, _, err := im.GetRandomAddress("pod1.ns", "pod1.ns", "", subnetName, nil, true)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ip).To(Equal("10.16.0.2"))
+
+				ip, _, _, err = im.GetRandomAddress("pod2.ns", "pod2.ns", "", subnetName, nil, true)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ip).To(Equal("10.18.0.2"))
+
+				_, _, _, err = im.GetRandomAddress("pod3.ns", "pod3.ns", "", subnetName, nil, true)
+				Expect(err).Should(MatchError(ipam.ErrNoAvailable))
+
+				_, _, _, err = im.GetStaticAddress("pod4.ns", "pod4.ns", "10.19.0.2", "", subnetName, true)
+				Expect(err).Should(MatchError(ipam.ErrOutOfRange))
+
+				im.ReleaseAddressByPod("pod2.ns")
+				_, _, _, err = im.GetStaticAddress("pod4.ns", "pod4.ns", "10.18.0.2", "", subnetName, true)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
 		})
 
 		Context("[IPv6]", func() {
@@ -451,6 +531,9 @@ var _ = Describe("[IPAM]", func() {
 				_, _, _, err = subnet.GetRandomAddress("pod3.ns", "pod3.ns", "", nil, true)
 				Expect(err).Should(MatchError(ipam.ErrNoAvailable))
 				Expect(subnet.V4FreeIPList).To(BeEmpty())
+				Expect(subnet.NicToMac).NotTo(HaveKey("pod3.ns"))
+				Expect(subnet.MacToPod).NotTo(ContainElement("pod3.ns"))
+				Expect(subnet.PodToNicList).NotTo(HaveKey("pod3.ns"))
 
 				Expect(subnet.V4IPToPod).To(HaveKeyWithValue(ipam.IP("10.16.0.1"), "pod1.ns"))
 				Expect(subnet.V4IPToPod).To(HaveKeyWithValue(ipam.IP("10.16.0.2"), "pod2.ns"))
@@ -539,6 +622,9 @@ var _ = Describe("[IPAM]", func() {
 				_, _, _, err = subnet.GetRandomAddress("pod3.ns", "pod3.ns", "", nil, true)
 				Expect(err).Should(MatchError(ipam.ErrNoAvailable))
 				Expect(subnet.V6FreeIPList).To(BeEmpty())
+				Expect(subnet.NicToMac).NotTo(HaveKey("pod3.ns"))
+				Expect(subnet.MacToPod).NotTo(ContainElement("pod3.ns"))
+				Expect(subnet.PodToNicList).NotTo(HaveKey("pod3.ns"))
 
 				Expect(subnet.V6IPToPod).To(HaveKeyWithValue(ipam.IP("fd00::1"), "pod1.ns"))
 				Expect(subnet.V6IPToPod).To(HaveKeyWithValue(ipam.IP("fd00::2"), "pod2.ns"))
@@ -667,6 +753,9 @@ var _ = Describe("[IPAM]", func() {
 				_, _, _, err = subnet.GetRandomAddress("pod3.ns", "pod3.ns", "", nil, true)
 				Expect(err).Should(MatchError(ipam.ErrNoAvailable))
 				Expect(subnet.V4FreeIPList).To(BeEmpty())
+				Expect(subnet.NicToMac).NotTo(HaveKey("pod3.ns"))
+				Expect(subnet.MacToPod).NotTo(ContainElement("pod3.ns"))
+				Expect(subnet.PodToNicList).NotTo(HaveKey("pod3.ns"))
 				Expect(subnet.V6FreeIPList).To(BeEmpty())
 
 				Expect(subnet.V4IPToPod).To(HaveKeyWithValue(ipam.IP("10.16.0.1"), "pod1.ns"))